@@ -0,0 +1,144 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+const (
+	accessRead     = "read"
+	accessSkipped  = "skipped"
+	accessExcluded = "excluded"
+	accessFailed   = "failed"
+	accessVanished = "vanished"
+)
+
+// accessLog is the recorder of the source objects accessed by the commands.
+// It is nil unless the "access-log" flag is given.
+var accessLog *accessLogWriter
+
+// accessRecord is an entry of the access log.
+type accessRecord struct {
+	Time      time.Time `json:"time"`
+	Operation string    `json:"operation"`
+	Source    string    `json:"source"`
+	VersionID string    `json:"version_id,omitempty"`
+	Size      int64     `json:"size"`
+	Action    string    `json:"action"`
+	Reason    string    `json:"reason,omitempty"`
+}
+
+// accessLogWriter writes a JSON line for each source object which is read,
+// skipped, excluded or failed to be read by cp, mv and sync. Unlike the
+// transfer statistics, the objects which are filtered out before their
+// transfers are recorded as well, so that the log tells all of the objects
+// which are touched by a run. Each record is written as soon as the decision
+// about its object is made.
+type accessLogWriter struct {
+	mu   sync.Mutex
+	file *os.File
+	err  error
+}
+
+// openAccessLog creates the access log file at path.
+func openAccessLog(path string) (*accessLogWriter, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &accessLogWriter{file: file}, nil
+}
+
+// record writes the action taken on the source object at srcurl. It is a no-op
+// if l is nil.
+func (l *accessLogWriter) record(op string, srcurl *url.URL, size int64, action, reason string) {
+	if l == nil {
+		return
+	}
+
+	line, err := json.Marshal(accessRecord{
+		Time:      time.Now().UTC(),
+		Operation: op,
+		Source:    srcurl.String(),
+		VersionID: srcurl.VersionID,
+		Size:      size,
+		Action:    action,
+		Reason:    reason,
+	})
+	if err != nil {
+		return
+	}
+	l.write(append(line, '\n'))
+}
+
+// start starts recording a transfer of the object at srcurl. It returns the
+// context which the transfer must be made with and the function to call with
+// the result of the transfer once it is completed. It is a no-op if l is nil.
+func (l *accessLogWriter) start(
+	ctx context.Context,
+	op string,
+	srcurl *url.URL,
+	size int64,
+) (context.Context, func(error)) {
+	if l == nil {
+		return ctx, func(error) {}
+	}
+
+	ctx, marked := withTransferStatus(ctx)
+	return ctx, func(err error) {
+		switch marked.result(err) {
+		case transferFailed:
+			l.record(op, srcurl, size, accessFailed, err.Error())
+		case transferSkipped:
+			l.record(op, srcurl, size, accessSkipped, marked.reason)
+		case transferVanished:
+			l.record(op, srcurl, size, accessVanished, marked.reason)
+		default:
+			// the partial transfers read the source as well.
+			l.record(op, srcurl, size, accessRead, "")
+		}
+	}
+}
+
+// write writes the line to the file. The first error is kept and the lines
+// after it are dropped.
+func (l *accessLogWriter) write(line []byte) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.err != nil {
+		return
+	}
+	_, l.err = l.file.Write(line)
+}
+
+// Close closes the access log file. It returns the first error the records
+// are written with, if any.
+func (l *accessLogWriter) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	err := l.file.Close()
+	if l.err != nil {
+		return fmt.Errorf("could not write access log: %w", l.err)
+	}
+	return err
+}
+
+// closeAccessLog closes the access log file, if any, and reports the error of
+// writing it.
+func closeAccessLog(command string) {
+	if accessLog == nil {
+		return
+	}
+	if err := accessLog.Close(); err != nil {
+		printError(command, "access-log", err)
+	}
+	accessLog = nil
+}
@@ -0,0 +1,96 @@
+package command
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gotest.tools/v3/assert"
+
+	errorpkg "github.com/peak/s5cmd/v2/error"
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+func TestAccessLogWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+
+	access, err := openAccessLog(path)
+	assert.NilError(t, err)
+
+	newURL := func(key string) *url.URL {
+		srcurl, err := url.New(key)
+		assert.NilError(t, err)
+		return srcurl
+	}
+
+	record := func(key string, result func(ctx context.Context) error) {
+		ctx, done := access.start(context.Background(), "cp", newURL(key), 10)
+		done(result(ctx))
+	}
+
+	record("s3://bucket/a.txt", func(context.Context) error { return nil })
+	record("s3://bucket/b.txt", func(ctx context.Context) error {
+		skipTransfer(ctx, errorpkg.ErrObjectExists)
+		return nil
+	})
+	record("s3://bucket/c.txt", func(context.Context) error { return fmt.Errorf("access denied") })
+	record("s3://bucket/d.txt", func(ctx context.Context) error {
+		partialTransfer(ctx)
+		return fmt.Errorf("upload failed")
+	})
+	access.record("sync", newURL("s3://bucket/e.txt"), 10, accessExcluded, "matches an exclude pattern")
+
+	assert.NilError(t, access.Close())
+
+	file, err := os.Open(path)
+	assert.NilError(t, err)
+	defer file.Close()
+
+	var records []accessRecord
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record accessRecord
+		assert.NilError(t, json.Unmarshal(scanner.Bytes(), &record))
+		records = append(records, record)
+	}
+	assert.NilError(t, scanner.Err())
+	assert.Equal(t, 5, len(records))
+
+	for i, expected := range []struct {
+		operation string
+		source    string
+		action    string
+		reason    string
+	}{
+		{operation: "cp", source: "s3://bucket/a.txt", action: accessRead},
+		{operation: "cp", source: "s3://bucket/b.txt", action: accessSkipped, reason: "object already exists"},
+		{operation: "cp", source: "s3://bucket/c.txt", action: accessFailed, reason: "access denied"},
+		// the source of a partial transfer is read.
+		{operation: "cp", source: "s3://bucket/d.txt", action: accessRead},
+		{operation: "sync", source: "s3://bucket/e.txt", action: accessExcluded, reason: "matches an exclude pattern"},
+	} {
+		got := records[i]
+		assert.Equal(t, expected.operation, got.Operation)
+		assert.Equal(t, expected.source, got.Source)
+		assert.Equal(t, int64(10), got.Size)
+		assert.Equal(t, expected.action, got.Action)
+		assert.Equal(t, expected.reason, got.Reason)
+		assert.Assert(t, !got.Time.IsZero())
+	}
+}
+
+func TestAccessLogWriterNil(t *testing.T) {
+	var access *accessLogWriter
+
+	srcurl, err := url.New("s3://bucket/key")
+	assert.NilError(t, err)
+
+	ctx, done := access.start(context.Background(), "cp", srcurl, 10)
+	skipTransfer(ctx, errorpkg.ErrObjectExists)
+	done(nil)
+	access.record("cp", srcurl, 10, accessExcluded, "")
+}
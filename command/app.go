@@ -12,6 +12,7 @@ import (
 	"github.com/peak/s5cmd/v2/log/stat"
 	"github.com/peak/s5cmd/v2/parallel"
 	"github.com/peak/s5cmd/v2/storage"
+	"github.com/peak/s5cmd/v2/strutil"
 )
 
 const (
@@ -25,11 +26,16 @@ var app = &cli.App{
 	Name:                 appName,
 	Usage:                "Blazing fast S3 and local filesystem execution tool",
 	EnableBashCompletion: true,
-	Flags: []cli.Flag{
+	Flags: append([]cli.Flag{
 		&cli.BoolFlag{
 			Name:  "json",
 			Usage: "enable JSON formatted output",
 		},
+		&cli.IntFlag{
+			Name:  "output-version",
+			Value: log.LatestOutputVersion,
+			Usage: "schema version of JSON formatted output: (1, 2)",
+		},
 		&cli.IntFlag{
 			Name:  "numworkers",
 			Value: defaultWorkerCount,
@@ -50,6 +56,26 @@ var app = &cli.App{
 			Name:  "no-verify-ssl",
 			Usage: "disable SSL certificate verification",
 		},
+		&cli.GenericFlag{
+			Name: "addressing-style",
+			Value: &EnumValue{
+				Enum:    []string{storage.AddressingStyleAuto, storage.AddressingStylePath, storage.AddressingStyleVirtual},
+				Default: storage.AddressingStyleAuto,
+			},
+			Usage: "bucket addressing style of the requests; auto uses path-style for bucket names with dots over HTTPS and for custom endpoints: (auto, path, virtual)",
+		},
+		&cli.GenericFlag{
+			Name: "ip-version",
+			Value: &EnumValue{
+				Enum:    []string{storage.IPVersionAuto, storage.IPVersion4, storage.IPVersion6},
+				Default: storage.IPVersionAuto,
+			},
+			Usage: "connect to the endpoints only over the given IP version; auto uses the addresses in the order they are resolved: (auto, 4, 6)",
+		},
+		&cli.StringSliceFlag{
+			Name:  "resolve",
+			Usage: "connect to the given address for the endpoint host and port in curl's host:port:addr format, e.g. s3.amazonaws.com:443:[2600:1f18::1], can be specified multiple times",
+		},
 		&cli.GenericFlag{
 			Name: "log",
 			Value: &EnumValue{
@@ -90,7 +116,55 @@ var app = &cli.App{
 			Name:  "credentials-file",
 			Usage: "use the specified credentials file instead of the default credentials file",
 		},
-	},
+		&cli.StringFlag{
+			Name:  "credentials-command",
+			Usage: "run the specified command to get the credentials in the credential_process JSON format, before they expire",
+		},
+		&cli.StringFlag{
+			Name:  "env-prefix",
+			Usage: "read the credentials from the environment variables with the specified prefix, e.g. TENANT1_ for TENANT1_AWS_ACCESS_KEY_ID",
+		},
+		&cli.IntFlag{
+			Name:        "max-concurrent-requests-per-host",
+			Usage:       "limit the number of in-flight requests to each S3 endpoint host, independent of the number of workers",
+			DefaultText: "unlimited",
+		},
+		&cli.BoolFlag{
+			Name:  "endpoint-resolver-cache",
+			Usage: "cache the resolved region and the client of each bucket for the whole run to avoid repeated region lookups",
+		},
+		&cli.IntFlag{
+			Name:        "read-concurrency",
+			Usage:       "limit the number of local files read at the same time, independent of the number of workers and upload concurrency",
+			DefaultText: "unlimited",
+		},
+		&cli.IntFlag{
+			Name:        "read-iops",
+			Usage:       "limit the number of local files opened per second",
+			DefaultText: "unlimited",
+		},
+		&cli.StringFlag{
+			Name:  "buffer-pool-size",
+			Value: "64KiB",
+			Usage: "size of the transfer buffers which are pooled and reused across the uploads and downloads, e.g. 1MiB; 0 disables the pool",
+		},
+		&cli.StringFlag{
+			Name:  "stats-csv",
+			Usage: "write the key, size, duration, throughput, retries and status of each transferred object to the given CSV file",
+		},
+		&cli.StringFlag{
+			Name:  "access-log",
+			Usage: "write a JSON line for each source object read, skipped or excluded by cp, mv and sync to the given file",
+		},
+		&cli.StringFlag{
+			Name:  "audit-log",
+			Usage: "append a hash-chained JSON line with the time, the user, the identity, the command and the result of each object or bucket created, overwritten or deleted to the given file",
+		},
+		&cli.BoolFlag{
+			Name:  "audit-syslog",
+			Usage: "send the audit log records to the system logger",
+		},
+	}, append(newSpoolFlags(), newCompletionHookFlags()...)...),
 	Before: func(c *cli.Context) error {
 		retryCount := c.Int("retry-count")
 		workerCount := c.Int("numworkers")
@@ -102,11 +176,45 @@ var app = &cli.App{
 		log.Init(logLevel, printJSON)
 		parallel.Init(workerCount)
 
+		// the hooks are created before the other flags are validated, so
+		// that they are run for the invalid ones too.
+		if err := validateCompletionHookFlags(c); err != nil {
+			printError(commandFromContext(c), c.Command.Name, err)
+			return err
+		}
+		completionHooks = newCompletionHook(c)
+		// the parts of the multipart copies share the workers, so that a few
+		// huge copies don't hold back the other transfers.
+		storage.LimitPartCopies(parallel.WorkerCount())
+
+		outputVersion := c.Int("output-version")
+		if outputVersion < log.OutputVersion1 || outputVersion > log.LatestOutputVersion {
+			err := fmt.Errorf("output version must be between %v and %v", log.OutputVersion1, log.LatestOutputVersion)
+			printError(commandFromContext(c), c.Command.Name, err)
+			return err
+		}
+		log.SetOutputVersion(outputVersion)
+
 		if retryCount < 0 {
 			err := fmt.Errorf("retry count cannot be a negative value")
 			printError(commandFromContext(c), c.Command.Name, err)
 			return err
 		}
+		if c.Int("max-concurrent-requests-per-host") < 0 {
+			err := fmt.Errorf("max concurrent requests per host cannot be a negative value")
+			printError(commandFromContext(c), c.Command.Name, err)
+			return err
+		}
+		if c.Int("read-concurrency") < 0 {
+			err := fmt.Errorf("read concurrency cannot be a negative value")
+			printError(commandFromContext(c), c.Command.Name, err)
+			return err
+		}
+		if c.Int("read-iops") < 0 {
+			err := fmt.Errorf("read iops cannot be a negative value")
+			printError(commandFromContext(c), c.Command.Name, err)
+			return err
+		}
 		if c.Bool("no-sign-request") && c.String("profile") != "" {
 			err := fmt.Errorf(`"no-sign-request" and "profile" flags cannot be used together`)
 			printError(commandFromContext(c), c.Command.Name, err)
@@ -117,11 +225,84 @@ var app = &cli.App{
 			printError(commandFromContext(c), c.Command.Name, err)
 			return err
 		}
+		if c.String("credentials-command") != "" {
+			for _, flag := range []string{"no-sign-request", "profile", "credentials-file"} {
+				if c.IsSet(flag) {
+					err := fmt.Errorf(`"credentials-command" and %q flags cannot be used together`, flag)
+					printError(commandFromContext(c), c.Command.Name, err)
+					return err
+				}
+			}
+		}
+		if c.String("env-prefix") != "" {
+			for _, flag := range []string{"no-sign-request", "profile", "credentials-file", "credentials-command"} {
+				if c.IsSet(flag) {
+					err := fmt.Errorf(`"env-prefix" and %q flags cannot be used together`, flag)
+					printError(commandFromContext(c), c.Command.Name, err)
+					return err
+				}
+			}
+		}
+
+		bufferPoolSize, err := strutil.ParseBytes(c.String("buffer-pool-size"))
+		if err != nil {
+			err = fmt.Errorf("invalid buffer-pool-size: %w", err)
+			printError(commandFromContext(c), c.Command.Name, err)
+			return err
+		}
+		storageBufferPoolSize = bufferPoolSize
+
+		dialer, err := storage.NewDialer(c.String("ip-version"), c.StringSlice("resolve"))
+		if err != nil {
+			printError(commandFromContext(c), c.Command.Name, err)
+			return err
+		}
+		storageDialer = dialer
 
 		if isStat {
 			stat.InitStat()
+			requestStatistics = newRequestStats()
+			storageHooks = requestStatistics.hooks()
+		}
+
+		if path := c.String("stats-csv"); path != "" {
+			stats, err := openTransferStats(path)
+			if err != nil {
+				err = fmt.Errorf("could not create stats file: %w", err)
+				printError(commandFromContext(c), c.Command.Name, err)
+				return err
+			}
+			transferStats = stats
+		}
+
+		if path := c.String("access-log"); path != "" {
+			access, err := openAccessLog(path)
+			if err != nil {
+				err = fmt.Errorf("could not create access log: %w", err)
+				printError(commandFromContext(c), c.Command.Name, err)
+				return err
+			}
+			accessLog = access
 		}
 
+		// nothing is changed with dry-run, so there is nothing to audit.
+		if path, useSyslog := c.String("audit-log"), c.Bool("audit-syslog"); (path != "" || useSyslog) && !c.Bool("dry-run") {
+			audit, err := openAuditLog(path, useSyslog, NewStorageOpts(c))
+			if err != nil {
+				err = fmt.Errorf("could not open audit log: %w", err)
+				printError(commandFromContext(c), c.Command.Name, err)
+				return err
+			}
+			auditLog = audit
+		}
+
+		spool, err := newSpool(c)
+		if err != nil {
+			printError(commandFromContext(c), c.Command.Name, err)
+			return err
+		}
+		tempSpool = spool
+
 		if endpointURL != "" {
 			if !strings.HasPrefix(endpointURL, "http") {
 				err := fmt.Errorf(`bad value for --endpoint-url %v: scheme is missing. Must be of the form http://<hostname>/ or https://<hostname>/`, endpointURL)
@@ -141,6 +322,10 @@ var app = &cli.App{
 
 		// After callback is not called if app exists with cli.Exit.
 		parallel.Close()
+		closeTransferStats(command)
+		closeAccessLog(command)
+		closeAuditLog(command)
+		completionHooks.run(c.Context, fmt.Errorf("command not found"))
 		log.Close()
 	},
 	OnUsageError: func(c *cli.Context, err error, isSubcommand bool) error {
@@ -169,32 +354,82 @@ var app = &cli.App{
 		if c.Bool("stat") && len(stat.Statistics()) > 0 {
 			log.Stat(stat.Statistics())
 		}
+		if requestStatistics != nil {
+			if stats := requestStatistics.Statistics(); len(stats) > 0 {
+				log.Stat(stats)
+			}
+		}
+		if c.Bool("stat") {
+			if stats, ok := storage.ReadStatistics(); ok {
+				log.Stat(stats)
+			}
+			if stats, ok := storage.UploadStatistics(); ok {
+				log.Stat(stats)
+			}
+		}
 
 		parallel.Close()
-		log.Close()
+		printAlternateDataStreamsSummary()
+		printErrorSummary()
+		closeTransferStats(commandFromContext(c))
+		closeAccessLog(commandFromContext(c))
+		closeAuditLog(commandFromContext(c))
 		return nil
 	},
 }
 
+// storageDialer dials the connections of the S3 clients created by the
+// commands. It is nil unless the "ip-version" or "resolve" flags are given.
+var storageDialer *storage.Dialer
+
+// storageBufferPoolSize is the size of the pooled transfer buffers of the S3
+// clients created by the commands. It is set by the "buffer-pool-size" flag.
+var storageBufferPoolSize int64
+
+// isDryRun reports whether the global "dry-run" flag is set, or the flag of
+// the same name of a command, e.g. "sync --dry-run". The flag of a command
+// hides the global one from the lookups of the context.
+func isDryRun(c *cli.Context) bool {
+	for _, ctx := range c.Lineage() {
+		if ctx.Bool("dry-run") {
+			return true
+		}
+	}
+	return false
+}
+
 // NewStorageOpts creates storage.Options object from the given context.
 func NewStorageOpts(c *cli.Context) storage.Options {
 	return storage.Options{
-		DryRun:                 c.Bool("dry-run"),
+		DryRun:                 isDryRun(c),
 		Endpoint:               c.String("endpoint-url"),
 		MaxRetries:             c.Int("retry-count"),
 		NoSignRequest:          c.Bool("no-sign-request"),
 		NoVerifySSL:            c.Bool("no-verify-ssl"),
 		RequestPayer:           c.String("request-payer"),
 		UseListObjectsV1:       c.Bool("use-list-objects-v1"),
+		FetchOwner:             c.Bool("fetch-owner") || c.String("owner") != "",
 		Profile:                c.String("profile"),
 		CredentialFile:         c.String("credentials-file"),
+		CredentialsCommand:     c.String("credentials-command"),
+		EnvPrefix:              c.String("env-prefix"),
+		EndpointResolverCache:  c.Bool("endpoint-resolver-cache"),
+		MaxRequestsPerHost:     c.Int("max-concurrent-requests-per-host"),
+		ReadConcurrency:        c.Int("read-concurrency"),
+		AddressingStyle:        c.String("addressing-style"),
+		DeleteBatchSize:        c.Int("delete-batch-size"),
+		BufferPoolSize:         storageBufferPoolSize,
+		ReadIOPS:               c.Int("read-iops"),
+		OnBrokenSymlink:        c.String("on-broken-symlink"),
 		LogLevel:               log.LevelFromString(c.String("log")),
 		NoSuchUploadRetryCount: c.Int("no-such-upload-retry-count"),
+		Hooks:                  storageHooks,
+		Dialer:                 storageDialer,
 	}
 }
 
 func Commands() []*cli.Command {
-	return []*cli.Command{
+	return withExamplesHint([]*cli.Command{
 		NewListCommand(),
 		NewCopyCommand(),
 		NewDeleteCommand(),
@@ -209,7 +444,12 @@ func Commands() []*cli.Command {
 		NewSyncCommand(),
 		NewVersionCommand(),
 		NewBucketVersionCommand(),
-	}
+		NewCheckCommand(),
+		NewStatCommand(),
+		NewExistsCommand(),
+		NewMergeCommand(),
+		NewExamplesCommand(),
+	})
 }
 
 func AppCommand(name string) *cli.Command {
@@ -226,5 +466,15 @@ func AppCommand(name string) *cli.Command {
 func Main(ctx context.Context, args []string) error {
 	app.Commands = Commands()
 
-	return app.RunContext(ctx, args)
+	err := app.RunContext(ctx, args)
+	// the temporary files are removed once the commands are completed or
+	// interrupted by a signal, which cancels ctx.
+	if cerr := tempSpool.Cleanup(); cerr != nil {
+		printDebug(appName, fmt.Errorf("remove temporary directory: %w", cerr))
+	}
+	// the completion hooks are run once the summaries of the command are
+	// printed, and the logger is closed after them to print their failures.
+	completionHooks.run(ctx, err)
+	log.Close()
+	return err
 }
@@ -0,0 +1,366 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+
+	errorpkg "github.com/peak/s5cmd/v2/error"
+	"github.com/peak/s5cmd/v2/storage"
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+const (
+	auditSucceeded = "succeeded"
+	auditFailed    = "failed"
+)
+
+// auditLog is the recorder of the mutations made by the commands. It is nil
+// unless the "audit-log" or the "audit-syslog" flag is given.
+var auditLog *auditLogWriter
+
+// auditRecord is an entry of the audit log.
+type auditRecord struct {
+	Time        time.Time `json:"time"`
+	User        string    `json:"user"`
+	Identity    string    `json:"identity,omitempty"`
+	Command     string    `json:"command"`
+	Operation   string    `json:"operation"`
+	Source      string    `json:"source,omitempty"`
+	Destination string    `json:"destination,omitempty"`
+	Size        int64     `json:"size"`
+	Result      string    `json:"result"`
+	Error       string    `json:"error,omitempty"`
+	// PrevHash is the hash of the previous record, and Hash is the SHA-256
+	// hash of the previous hash and the record without its hash. The records
+	// form a chain, so that a removed or modified record breaks the hashes of
+	// the records after it.
+	PrevHash string `json:"prev_hash"`
+	Hash     string `json:"hash,omitempty"`
+}
+
+// auditLogWriter writes a JSON line for each object or bucket which is
+// created, overwritten or deleted by the commands, regardless of the log
+// level. The records are written as soon as the mutations are completed, and
+// the records of the deletions are flushed to the disk before the deletions
+// are reported.
+type auditLogWriter struct {
+	mu     sync.Mutex
+	file   *os.File
+	syslog io.WriteCloser
+	err    error
+	// prev is the hash of the last record.
+	prev string
+
+	user    string
+	command string
+
+	// identity is the ARN of the caller, which is looked up once, on the
+	// first record.
+	identityOnce sync.Once
+	identity     string
+	storageOpts  storage.Options
+}
+
+// openAuditLog opens the audit log file at path, if not empty, and the
+// syslog, if useSyslog is set. The records are appended to the file, and the
+// hash chain is continued from its last record.
+func openAuditLog(path string, useSyslog bool, storageOpts storage.Options) (*auditLogWriter, error) {
+	l := &auditLogWriter{
+		user:        currentUser(),
+		command:     strings.Join(os.Args, " "),
+		storageOpts: storageOpts,
+	}
+
+	if path != "" {
+		file, err := os.OpenFile(path, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0o600)
+		if err != nil {
+			return nil, err
+		}
+		prev, err := lastAuditHash(file)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("invalid audit log %q: %w", path, err)
+		}
+		l.file, l.prev = file, prev
+	}
+
+	if useSyslog {
+		w, err := openAuditSyslog()
+		if err != nil {
+			if l.file != nil {
+				l.file.Close()
+			}
+			return nil, err
+		}
+		l.syslog = w
+	}
+	return l, nil
+}
+
+// currentUser returns the name of the operating system user.
+func currentUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
+
+// lastAuditHash returns the hash of the last record of the audit log file.
+func lastAuditHash(file *os.File) (string, error) {
+	line, err := lastLine(file)
+	if err != nil || len(line) == 0 {
+		return "", err
+	}
+
+	var record auditRecord
+	if err := json.Unmarshal(line, &record); err != nil {
+		return "", err
+	}
+	if record.Hash == "" {
+		return "", errors.New("last record has no hash")
+	}
+	return record.Hash, nil
+}
+
+// lastLine returns the last non-empty line of the file, reading it backwards
+// so that only the end of a large file is read.
+func lastLine(file *os.File) ([]byte, error) {
+	const chunkSize = 4096
+
+	st, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	var tail []byte
+	for offset := st.Size(); offset > 0; {
+		n := int64(chunkSize)
+		if offset < n {
+			n = offset
+		}
+		offset -= n
+
+		chunk := make([]byte, n)
+		if _, err := file.ReadAt(chunk, offset); err != nil {
+			return nil, err
+		}
+		tail = append(chunk, tail...)
+
+		trimmed := bytes.TrimRight(tail, "\n")
+		if i := bytes.LastIndexByte(trimmed, '\n'); i >= 0 {
+			return trimmed[i+1:], nil
+		}
+	}
+	return bytes.TrimRight(tail, "\n"), nil
+}
+
+// start starts recording a transfer of the object at srcurl, whose
+// destinations are marked with transferDestination during the transfer. It
+// returns the context which the transfer must be made with and the function to
+// call with the result of the transfer once it is completed. The skipped
+// transfers are not recorded since they don't change anything. It is a no-op
+// if l is nil.
+func (l *auditLogWriter) start(
+	ctx context.Context,
+	op string,
+	srcurl *url.URL,
+	size int64,
+) (context.Context, func(error)) {
+	if l == nil {
+		return ctx, func(error) {}
+	}
+
+	ctx, marked := withTransferStatus(ctx)
+	return ctx, func(err error) {
+		switch marked.result(err) {
+		case transferSkipped, transferVanished:
+			return
+		}
+		// the transfers which fail before their destinations are known
+		// are recorded without destinations.
+		if len(marked.destinations) == 0 {
+			l.record(ctx, op, srcurl, nil, size, err, false)
+			return
+		}
+		for _, dsturl := range marked.destinations {
+			l.record(ctx, op, srcurl, dsturl, size, destinationError(err, dsturl), false)
+		}
+	}
+}
+
+// destinationError returns the error of the upload to dsturl, if the upload
+// to multiple destinations failed with err.
+func destinationError(err error, dsturl *url.URL) error {
+	merr, ok := err.(*multierror.Error)
+	if !ok {
+		return err
+	}
+	for _, err := range merr.Errors {
+		var oerr *errorpkg.Error
+		if errors.As(err, &oerr) && oerr.Dst != nil && oerr.Dst.String() == dsturl.String() {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleted records the deletion of the object or the bucket at u. The record is
+// flushed to the disk before it returns. It is a no-op if l is nil.
+func (l *auditLogWriter) deleted(ctx context.Context, op string, u *url.URL, err error) {
+	if l == nil {
+		return
+	}
+	l.record(ctx, op, u, nil, 0, err, true)
+}
+
+// created records the creation of the object or the bucket at dsturl from
+// srcurl, which is nil for the objects and the buckets which are not copied.
+// It is a no-op if l is nil.
+func (l *auditLogWriter) created(ctx context.Context, op string, srcurl, dsturl *url.URL, size int64, err error) {
+	if l == nil {
+		return
+	}
+	l.record(ctx, op, srcurl, dsturl, size, err, false)
+}
+
+func (l *auditLogWriter) record(
+	ctx context.Context,
+	op string,
+	srcurl *url.URL,
+	dsturl *url.URL,
+	size int64,
+	err error,
+	flush bool,
+) {
+	if errorpkg.IsCancelation(err) {
+		return
+	}
+
+	record := auditRecord{
+		Time:      time.Now().UTC(),
+		User:      l.user,
+		Identity:  l.callerIdentity(ctx),
+		Command:   l.command,
+		Operation: op,
+		Size:      size,
+		Result:    auditSucceeded,
+	}
+	if srcurl != nil {
+		record.Source = srcurl.String()
+	}
+	if dsturl != nil {
+		record.Destination = dsturl.String()
+	}
+	if err != nil {
+		record.Result, record.Error = auditFailed, err.Error()
+	}
+
+	l.write(record, flush)
+}
+
+// callerIdentity returns the ARN of the caller, looking it up on the first
+// call. The identity is left empty if it can't be looked up.
+func (l *auditLogWriter) callerIdentity(ctx context.Context) string {
+	l.identityOnce.Do(func() {
+		// the lookup is not canceled along with the operation which
+		// triggers it, since the later records use it as well.
+		l.identity, _ = storage.CallerIdentity(context.Background(), l.storageOpts)
+	})
+	return l.identity
+}
+
+// write chains the record to the previous one and writes it. The first error
+// is kept and the records after it are dropped.
+func (l *auditLogWriter) write(record auditRecord, flush bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.err != nil {
+		return
+	}
+
+	record.PrevHash = l.prev
+	line, err := chainAuditRecord(&record)
+	if err != nil {
+		l.err = err
+		return
+	}
+	l.prev = record.Hash
+
+	if l.file != nil {
+		if _, l.err = l.file.Write(line); l.err != nil {
+			return
+		}
+		if flush {
+			if l.err = l.file.Sync(); l.err != nil {
+				return
+			}
+		}
+	}
+	if l.syslog != nil {
+		_, l.err = l.syslog.Write(line)
+	}
+}
+
+// chainAuditRecord sets the hash of the record and returns its JSON line.
+func chainAuditRecord(record *auditRecord) ([]byte, error) {
+	record.Hash = ""
+	unsigned, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(append([]byte(record.PrevHash), unsigned...))
+	record.Hash = hex.EncodeToString(sum[:])
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+	return append(line, '\n'), nil
+}
+
+// Close closes the audit log file and the syslog. It returns the first error
+// the records are written with, if any.
+func (l *auditLogWriter) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var err error
+	if l.file != nil {
+		err = l.file.Close()
+	}
+	if l.syslog != nil {
+		if serr := l.syslog.Close(); err == nil {
+			err = serr
+		}
+	}
+	if l.err != nil {
+		return fmt.Errorf("could not write audit log: %w", l.err)
+	}
+	return err
+}
+
+// closeAuditLog closes the audit log, if any, and reports the error of writing
+// it.
+func closeAuditLog(command string) {
+	if auditLog == nil {
+		return
+	}
+	if err := auditLog.Close(); err != nil {
+		printError(command, "audit-log", err)
+	}
+	auditLog = nil
+}
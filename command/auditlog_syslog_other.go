@@ -0,0 +1,14 @@
+//go:build windows || plan9
+// +build windows plan9
+
+package command
+
+import (
+	"errors"
+	"io"
+)
+
+// openAuditSyslog fails since there is no system logger on this platform.
+func openAuditSyslog() (io.WriteCloser, error) {
+	return nil, errors.New("syslog is not supported on this platform")
+}
@@ -0,0 +1,15 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package command
+
+import (
+	"io"
+	"log/syslog"
+)
+
+// openAuditSyslog connects to the system logger, which the audit records are
+// sent to.
+func openAuditSyslog() (io.WriteCloser, error) {
+	return syslog.New(syslog.LOG_NOTICE|syslog.LOG_AUTH, appName)
+}
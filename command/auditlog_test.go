@@ -0,0 +1,139 @@
+package command
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/go-multierror"
+	"gotest.tools/v3/assert"
+
+	errorpkg "github.com/peak/s5cmd/v2/error"
+	"github.com/peak/s5cmd/v2/storage"
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+// readAuditLog reads the records of the audit log at path and verifies their
+// hash chain.
+func readAuditLog(t *testing.T, path string) []auditRecord {
+	t.Helper()
+
+	file, err := os.Open(path)
+	assert.NilError(t, err)
+	defer file.Close()
+
+	var (
+		records []auditRecord
+		prev    string
+	)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record auditRecord
+		assert.NilError(t, json.Unmarshal(scanner.Bytes(), &record))
+		assert.Equal(t, record.PrevHash, prev)
+
+		unsigned := record
+		unsigned.Hash = ""
+		line, err := json.Marshal(unsigned)
+		assert.NilError(t, err)
+		sum := sha256.Sum256(append([]byte(prev), line...))
+		assert.Equal(t, record.Hash, hex.EncodeToString(sum[:]))
+
+		prev = record.Hash
+		records = append(records, record)
+	}
+	assert.NilError(t, scanner.Err())
+	return records
+}
+
+func TestAuditLogWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	opts := storage.Options{NoSignRequest: true}
+
+	newURL := func(key string) *url.URL {
+		u, err := url.New(key)
+		assert.NilError(t, err)
+		return u
+	}
+
+	audit, err := openAuditLog(path, false, opts)
+	assert.NilError(t, err)
+
+	transfer := func(key string, dsturls []*url.URL, result func(ctx context.Context) error) {
+		ctx, done := audit.start(context.Background(), "cp", newURL(key), 10)
+		transferDestination(ctx, dsturls...)
+		done(result(ctx))
+	}
+
+	a, b := newURL("s3://bucket/a.txt"), newURL("s3://backup/a.txt")
+	transfer("file/a.txt", []*url.URL{a}, func(context.Context) error { return nil })
+	transfer("file/b.txt", []*url.URL{newURL("s3://bucket/b.txt")}, func(ctx context.Context) error {
+		skipTransfer(ctx, errorpkg.ErrObjectExists)
+		return nil
+	})
+	transfer("file/a.txt", []*url.URL{a, b}, func(ctx context.Context) error {
+		partialTransfer(ctx)
+		return multierror.Append(nil, &errorpkg.Error{Op: "cp", Dst: b, Err: fmt.Errorf("access denied")})
+	})
+	audit.deleted(context.Background(), "rm", newURL("s3://bucket/c.txt"), nil)
+	assert.NilError(t, audit.Close())
+
+	// the records of the next runs are chained to the previous ones.
+	audit, err = openAuditLog(path, false, opts)
+	assert.NilError(t, err)
+	audit.created(context.Background(), "mb", nil, newURL("s3://newbucket"), 0, nil)
+	assert.NilError(t, audit.Close())
+
+	records := readAuditLog(t, path)
+	assert.Equal(t, 5, len(records))
+
+	for i, expected := range []struct {
+		operation   string
+		source      string
+		destination string
+		result      string
+		err         string
+	}{
+		{operation: "cp", source: "file/a.txt", destination: "s3://bucket/a.txt", result: auditSucceeded},
+		{operation: "cp", source: "file/a.txt", destination: "s3://bucket/a.txt", result: auditSucceeded},
+		{operation: "cp", source: "file/a.txt", destination: "s3://backup/a.txt", result: auditFailed, err: "access denied"},
+		{operation: "rm", source: "s3://bucket/c.txt", result: auditSucceeded},
+		{operation: "mb", destination: "s3://newbucket", result: auditSucceeded},
+	} {
+		got := records[i]
+		assert.Equal(t, expected.operation, got.Operation)
+		assert.Equal(t, expected.source, got.Source)
+		assert.Equal(t, expected.destination, got.Destination)
+		assert.Equal(t, expected.result, got.Result)
+		assert.Assert(t, (expected.err == "") == (got.Error == ""))
+		assert.Assert(t, got.User != "")
+		assert.Assert(t, !got.Time.IsZero())
+	}
+}
+
+func TestAuditLogWriterInvalidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	assert.NilError(t, os.WriteFile(path, []byte("{\"operation\":\"cp\"}\n"), 0o600))
+
+	_, err := openAuditLog(path, false, storage.Options{NoSignRequest: true})
+	assert.ErrorContains(t, err, "invalid audit log")
+}
+
+func TestAuditLogWriterNil(t *testing.T) {
+	var audit *auditLogWriter
+
+	u, err := url.New("s3://bucket/key")
+	assert.NilError(t, err)
+
+	ctx, done := audit.start(context.Background(), "cp", u, 10)
+	assert.Equal(t, ctx, context.Background())
+	done(nil)
+	audit.created(ctx, "mb", nil, u, 0, nil)
+	audit.deleted(ctx, "rm", u, nil)
+}
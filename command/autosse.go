@@ -0,0 +1,96 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/peak/s5cmd/v2/storage"
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+const autoSSEFlagName = "auto-sse"
+
+func newAutoSSEFlag() cli.Flag {
+	return &cli.BoolFlag{
+		Name:  autoSSEFlagName,
+		Usage: "apply the default encryption method and KMS key of the destination bucket to the uploaded and copied objects explicitly, for the bucket policies requiring the encryption headers; --sse flag takes precedence",
+	}
+}
+
+// bucketEncryptions is the cache of the default encryption configurations of
+// the destination buckets, which are requested once for all objects of the
+// process.
+var bucketEncryptions = &bucketEncryptionCache{
+	encryptions: map[string]*bucketEncryptionEntry{},
+}
+
+// bucketEncryptionEntry is the default encryption configuration of a bucket,
+// which is requested once.
+type bucketEncryptionEntry struct {
+	once       sync.Once
+	encryption storage.BucketEncryption
+}
+
+// bucketEncryptionCache holds the default encryption configurations of the
+// buckets by their names.
+type bucketEncryptionCache struct {
+	mu          sync.Mutex
+	encryptions map[string]*bucketEncryptionEntry
+}
+
+// get returns the default encryption configuration of the bucket of dsturl.
+// The configuration is requested with the first call for the bucket, and the
+// concurrent calls wait for it. The buckets whose configuration can't be read,
+// e.g. due to missing permissions, are reported once and treated as the
+// buckets without default encryption.
+func (bc *bucketEncryptionCache) get(
+	ctx context.Context,
+	op string,
+	dsturl *url.URL,
+	opts storage.Options,
+) storage.BucketEncryption {
+	bc.mu.Lock()
+	entry, ok := bc.encryptions[dsturl.Bucket]
+	if !ok {
+		entry = &bucketEncryptionEntry{}
+		bc.encryptions[dsturl.Bucket] = entry
+	}
+	bc.mu.Unlock()
+
+	entry.once.Do(func() {
+		client, err := storage.NewRemoteClient(ctx, dsturl, opts)
+		if err == nil {
+			entry.encryption, err = client.GetBucketEncryption(ctx, dsturl.Bucket)
+		}
+		if err != nil {
+			printDebug(op, fmt.Errorf("default encryption of the bucket is not applied: %w", err), dsturl)
+		}
+	})
+	return entry.encryption
+}
+
+// withAutoSSE returns the metadata of the object uploaded or copied to dsturl
+// with the default encryption of its bucket set explicitly, if the
+// "auto-sse" flag is given. The metadata is returned as is if the encryption
+// is given with --sse flag, or the bucket has no default encryption. It is
+// copied otherwise, since it may be shared by the destinations in different
+// buckets.
+func (c Copy) withAutoSSE(ctx context.Context, metadata storage.Metadata, dsturl *url.URL) storage.Metadata {
+	if !c.autoSSE || c.encryptionMethod != "" || !dsturl.IsRemote() {
+		return metadata
+	}
+
+	encryption := bucketEncryptions.get(ctx, c.op, dsturl, c.storageOpts)
+	if encryption.Algorithm == "" {
+		return metadata
+	}
+
+	result := storage.NewMetadata()
+	for key, value := range metadata {
+		result[key] = value
+	}
+	return result.SetSSE(encryption.Algorithm).SetSSEKeyID(encryption.KMSKeyID)
+}
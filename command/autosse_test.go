@@ -0,0 +1,106 @@
+package command
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/peak/s5cmd/v2/storage"
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+func TestCopyWithAutoSSE(t *testing.T) {
+	// the default encryptions of the buckets are cached, they are not
+	// requested in the test.
+	for bucket, encryption := range map[string]storage.BucketEncryption{
+		"kms-bucket":         {Algorithm: "aws:kms", KMSKeyID: "arn:aws:kms:us-east-1:111122223333:key/key-id"},
+		"aes-bucket":         {Algorithm: "AES256"},
+		"unencrypted-bucket": {},
+	} {
+		entry := &bucketEncryptionEntry{encryption: encryption}
+		entry.once.Do(func() {})
+		bucketEncryptions.encryptions[bucket] = entry
+	}
+
+	testcases := []struct {
+		name     string
+		copy     Copy
+		dst      string
+		expected storage.Metadata
+	}{
+		{
+			name: "kms",
+			copy: Copy{autoSSE: true},
+			dst:  "s3://kms-bucket/key",
+			expected: storage.Metadata{
+				"StorageClass":     "STANDARD",
+				"EncryptionMethod": "aws:kms",
+				"EncryptionKeyID":  "arn:aws:kms:us-east-1:111122223333:key/key-id",
+			},
+		},
+		{
+			name: "aes",
+			copy: Copy{autoSSE: true},
+			dst:  "s3://aes-bucket/key",
+			expected: storage.Metadata{
+				"StorageClass":     "STANDARD",
+				"EncryptionMethod": "AES256",
+				"EncryptionKeyID":  "",
+			},
+		},
+		{
+			name:     "no default encryption",
+			copy:     Copy{autoSSE: true},
+			dst:      "s3://unencrypted-bucket/key",
+			expected: storage.Metadata{"StorageClass": "STANDARD"},
+		},
+		{
+			name:     "explicit sse",
+			copy:     Copy{autoSSE: true, encryptionMethod: "AES256"},
+			dst:      "s3://kms-bucket/key",
+			expected: storage.Metadata{"StorageClass": "STANDARD", "EncryptionMethod": "AES256"},
+		},
+		{
+			name:     "not enabled",
+			copy:     Copy{},
+			dst:      "s3://kms-bucket/key",
+			expected: storage.Metadata{"StorageClass": "STANDARD"},
+		},
+		{
+			name:     "local destination",
+			copy:     Copy{autoSSE: true},
+			dst:      "dir/key",
+			expected: storage.Metadata{"StorageClass": "STANDARD"},
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			dsturl, err := url.New(tc.dst)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			metadata := storage.NewMetadata().SetStorageClass("STANDARD")
+			if tc.copy.encryptionMethod != "" {
+				metadata.SetSSE(tc.copy.encryptionMethod)
+			}
+			original := storage.NewMetadata()
+			for key, value := range metadata {
+				original[key] = value
+			}
+
+			got := tc.copy.withAutoSSE(context.Background(), metadata, dsturl)
+			if diff := cmp.Diff(tc.expected, got); diff != "" {
+				t.Errorf("(-want +got):\n%v", diff)
+			}
+			// the metadata may be shared by the destinations in other
+			// buckets.
+			if diff := cmp.Diff(original, metadata); diff != "" {
+				t.Errorf("metadata is modified (-want +got):\n%v", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,229 @@
+package command
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/peak/s5cmd/v2/log"
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+const (
+	recursiveFlagName    = "recursive"
+	awscliCompatFlagName = "awscli-compat"
+	includeFlagName      = "include"
+)
+
+// newAWSCLIFlags returns the flags of the aws-cli style invocations of the
+// given operation, e.g. "copy" or "remove".
+func newAWSCLIFlags(op string) []cli.Flag {
+	return []cli.Flag{
+		&cli.BoolFlag{
+			Name:  recursiveFlagName,
+			Usage: fmt.Sprintf("%v all objects under the source prefixes as \"aws s3 --recursive\" does, by rewriting the sources to the \"/*\" wildcard form", op),
+		},
+		&cli.BoolFlag{
+			Name:  awscliCompatFlagName,
+			Usage: "evaluate --exclude and --include flags in the order they are given as aws-cli does: an object is included unless the last pattern it matches is an --exclude",
+		},
+		&cli.StringSliceFlag{
+			Name:  includeFlagName,
+			Usage: "include only the objects with given pattern which match no --exclude pattern; with --awscli-compat, include objects with given pattern after an --exclude",
+		},
+	}
+}
+
+var (
+	recursiveNotice    sync.Once
+	awscliCompatNotice sync.Once
+)
+
+// recursiveSources returns the given sources in the "/*" wildcard form if
+// --recursive flag is set, and as they are otherwise.
+func recursiveSources(c *cli.Context, sources ...string) []string {
+	if !c.Bool(recursiveFlagName) {
+		return sources
+	}
+
+	result := make([]string, 0, len(sources))
+	for _, src := range sources {
+		if !strings.HasSuffix(src, "/") {
+			src += "/"
+		}
+		result = append(result, src+"*")
+	}
+	return result
+}
+
+// printAWSCLINotices explains the native idioms of the aws-cli style flags,
+// once for all commands of the process.
+func printAWSCLINotices(c *cli.Context, sources ...string) {
+	if c.Bool(recursiveFlagName) {
+		recursiveNotice.Do(func() {
+			log.Warning(log.WarningMessage{
+				Operation: c.Command.Name,
+				Warning: fmt.Sprintf(
+					"%q flag is mapped to the wildcard source %q, which matches all objects under %q without the flag",
+					recursiveFlagName, strings.Join(recursiveSources(c, sources...), " "), strings.Join(sources, " "),
+				),
+			})
+		})
+	}
+
+	if c.Bool(awscliCompatFlagName) {
+		awscliCompatNotice.Do(func() {
+			log.Warning(log.WarningMessage{
+				Operation: c.Command.Name,
+				Warning: fmt.Sprintf(
+					"%q flag evaluates the patterns in the order they are given, the last matching pattern wins; without the flag, the objects matching any %q pattern or no %q pattern are excluded",
+					awscliCompatFlagName, "exclude", includeFlagName,
+				),
+			})
+		})
+	}
+}
+
+// awscliFilter is an --exclude or --include pattern of --awscli-compat mode.
+type awscliFilter struct {
+	include bool
+	pattern *regexp.Regexp
+}
+
+// parseFilters returns the exclude and include patterns of the command, or
+// the ordered exclude and include patterns with --awscli-compat flag.
+func parseFilters(c *cli.Context) ([]string, []string, []awscliFilter, error) {
+	if !c.Bool(awscliCompatFlagName) {
+		return c.StringSlice("exclude"), c.StringSlice(includeFlagName), nil, nil
+	}
+
+	args, err := orderedFilterArgs(c)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	filters := make([]awscliFilter, 0, len(args))
+	for _, arg := range args {
+		patterns, err := createExcludesFromWildcard([]string{arg.pattern})
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		for _, pattern := range patterns {
+			filters = append(filters, awscliFilter{include: arg.include, pattern: pattern})
+		}
+	}
+	return nil, nil, filters, nil
+}
+
+type filterArg struct {
+	include bool
+	pattern string
+}
+
+// orderedFilterArgs returns the values of --exclude and --include flags in the
+// order they are given. The values of different flags are not ordered by the
+// cli package, so they are read from the raw arguments of the command, which
+// are the arguments of its parent context.
+func orderedFilterArgs(c *cli.Context) ([]filterArg, error) {
+	var raw []string
+	if lineage := c.Lineage(); len(lineage) > 1 && lineage[1] != nil {
+		raw = lineage[1].Args().Tail()
+	}
+
+	var args []filterArg
+	for i := 0; i < len(raw); i++ {
+		if raw[i] == "--" {
+			break
+		}
+		if !strings.HasPrefix(raw[i], "-") {
+			continue
+		}
+
+		name, value, hasValue := strings.Cut(strings.TrimLeft(raw[i], "-"), "=")
+		if name != "exclude" && name != includeFlagName {
+			continue
+		}
+		if !hasValue {
+			if i+1 >= len(raw) {
+				break
+			}
+			i++
+			value = raw[i]
+		}
+
+		for _, pattern := range strings.Split(value, ",") {
+			args = append(args, filterArg{include: name == includeFlagName, pattern: strings.TrimSpace(pattern)})
+		}
+	}
+
+	var excludes, includes []string
+	for _, arg := range args {
+		if arg.include {
+			includes = append(includes, arg.pattern)
+		} else {
+			excludes = append(excludes, arg.pattern)
+		}
+	}
+	if !equalPatterns(excludes, c.StringSlice("exclude")) || !equalPatterns(includes, c.StringSlice(includeFlagName)) {
+		return nil, fmt.Errorf("the order of %q and %q flags can't be determined", "exclude", includeFlagName)
+	}
+	return args, nil
+}
+
+func equalPatterns(a, b []string) bool {
+	if len(a) == 0 && len(b) == 0 {
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// isURLExcludedByFilters reports whether the last of the filters which
+// urlPath matches is an --exclude. The paths which match no filters are
+// included.
+func isURLExcludedByFilters(filters []awscliFilter, urlPath, sourcePrefix string) bool {
+	if len(filters) == 0 {
+		return false
+	}
+	if !strings.HasSuffix(sourcePrefix, "/") {
+		sourcePrefix += "/"
+	}
+	sourcePrefix = filepath.ToSlash(sourcePrefix)
+	path := strings.TrimPrefix(urlPath, sourcePrefix)
+
+	excluded := false
+	for _, filter := range filters {
+		if filter.pattern.MatchString(path) {
+			excluded = !filter.include
+		}
+	}
+	return excluded
+}
+
+// validateAWSCLIFlags validates the aws-cli style flags of the given sources.
+func validateAWSCLIFlags(c *cli.Context, sources ...string) error {
+	if _, _, _, err := parseFilters(c); err != nil {
+		return err
+	}
+
+	if !c.Bool(recursiveFlagName) {
+		return nil
+	}
+	if c.Bool("raw") {
+		return fmt.Errorf("it is not allowed to combine %q and %q flags", recursiveFlagName, "raw")
+	}
+	for _, src := range sources {
+		srcurl, err := url.New(src)
+		if err != nil {
+			return err
+		}
+		if srcurl.IsWildcard() {
+			return fmt.Errorf("%q flag cannot be used with wildcards", recursiveFlagName)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,250 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/urfave/cli/v2"
+)
+
+// runFilterCommand parses the given arguments of a command with the exclude
+// and the aws-cli style flags, and returns the parsed filters.
+func runFilterCommand(t *testing.T, args ...string) ([]string, []string, []awscliFilter, error) {
+	t.Helper()
+
+	var (
+		exclude []string
+		include []string
+		filters []awscliFilter
+		err     error
+	)
+	app := &cli.App{
+		Name: "s5cmd",
+		Commands: []*cli.Command{
+			{
+				Name: "cp",
+				Flags: append([]cli.Flag{
+					&cli.StringSliceFlag{Name: "exclude"},
+				}, newAWSCLIFlags("copy")...),
+				Action: func(c *cli.Context) error {
+					exclude, include, filters, err = parseFilters(c)
+					return nil
+				},
+			},
+		},
+	}
+	if err := app.Run(append([]string{"s5cmd", "cp"}, args...)); err != nil {
+		t.Fatal(err)
+	}
+	return exclude, include, filters, err
+}
+
+func TestRecursiveSources(t *testing.T) {
+	testcases := []struct {
+		name      string
+		recursive bool
+		sources   []string
+		expected  []string
+	}{
+		{
+			name:     "not recursive",
+			sources:  []string{"s3://bucket/prefix"},
+			expected: []string{"s3://bucket/prefix"},
+		},
+		{
+			name:      "prefix",
+			recursive: true,
+			sources:   []string{"s3://bucket/prefix"},
+			expected:  []string{"s3://bucket/prefix/*"},
+		},
+		{
+			name:      "prefix with slash",
+			recursive: true,
+			sources:   []string{"s3://bucket/prefix/", "s3://bucket"},
+			expected:  []string{"s3://bucket/prefix/*", "s3://bucket/*"},
+		},
+		{
+			name:      "directory",
+			recursive: true,
+			sources:   []string{"dir"},
+			expected:  []string{"dir/*"},
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			var got []string
+			app := &cli.App{
+				Name: "s5cmd",
+				Commands: []*cli.Command{
+					{
+						Name:  "rm",
+						Flags: newAWSCLIFlags("remove"),
+						Action: func(c *cli.Context) error {
+							got = recursiveSources(c, tc.sources...)
+							return nil
+						},
+					},
+				},
+			}
+			args := []string{"s5cmd", "rm"}
+			if tc.recursive {
+				args = append(args, "--recursive")
+			}
+			if err := app.Run(append(args, tc.sources...)); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.expected, got); diff != "" {
+				t.Errorf("(-want +got):\n%v", diff)
+			}
+		})
+	}
+}
+
+func TestAWSCLIFilterPrecedence(t *testing.T) {
+	const prefix = "s3://bucket/prefix/"
+
+	testcases := []struct {
+		name     string
+		args     []string
+		included []string
+		excluded []string
+	}{
+		{
+			name:     "native excludes ignore the order",
+			args:     []string{"--exclude", "*.txt", "--exclude", "*.log"},
+			included: []string{"a.csv"},
+			excluded: []string{"a.txt", "a.log"},
+		},
+		{
+			name:     "native includes",
+			args:     []string{"--include", "*.txt", "--include", "*.log", "--exclude", "secret*"},
+			included: []string{"a.txt", "dir/b.log"},
+			excluded: []string{"a.csv", "secret.txt"},
+		},
+		{
+			name:     "include after exclude",
+			args:     []string{"--awscli-compat", "--exclude", "*", "--include", "*.txt"},
+			included: []string{"a.txt", "dir/b.txt"},
+			excluded: []string{"a.log", "dir/b.csv"},
+		},
+		{
+			name:     "exclude after include",
+			args:     []string{"--awscli-compat", "--include", "*.txt", "--exclude", "*"},
+			excluded: []string{"a.txt", "a.log"},
+		},
+		{
+			name:     "exclude after include with equal sign",
+			args:     []string{"--awscli-compat", "--exclude=*", "--include=*.txt", "--exclude=secret*"},
+			included: []string{"a.txt"},
+			excluded: []string{"a.log", "secret.txt"},
+		},
+		{
+			name:     "comma separated patterns",
+			args:     []string{"--awscli-compat", "--exclude", "*", "--include", "*.txt,*.log"},
+			included: []string{"a.txt", "a.log"},
+			excluded: []string{"a.csv"},
+		},
+		{
+			name:     "no filters",
+			args:     []string{"--awscli-compat"},
+			included: []string{"a.txt"},
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			exclude, include, filters, err := runFilterCommand(t, append(tc.args, prefix+"*", "dir/")...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			excludePatterns, err := createExcludesFromWildcard(exclude)
+			if err != nil {
+				t.Fatal(err)
+			}
+			includePatterns, err := createExcludesFromWildcard(include)
+			if err != nil {
+				t.Fatal(err)
+			}
+			isExcluded := func(key string) bool {
+				return isURLExcluded(excludePatterns, prefix+key, prefix) ||
+					!isURLIncluded(includePatterns, prefix+key, prefix) ||
+					isURLExcludedByFilters(filters, prefix+key, prefix)
+			}
+
+			for _, key := range tc.included {
+				if isExcluded(key) {
+					t.Errorf("expected %q to be included", key)
+				}
+			}
+			for _, key := range tc.excluded {
+				if !isExcluded(key) {
+					t.Errorf("expected %q to be excluded", key)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateAWSCLIFlags(t *testing.T) {
+	testcases := []struct {
+		name        string
+		args        []string
+		expectedErr string
+	}{
+		{
+			name: "recursive",
+			args: []string{"--recursive", "s3://bucket/prefix"},
+		},
+		{
+			name: "include without awscli-compat",
+			args: []string{"--include", "*.txt", "s3://bucket/prefix/*"},
+		},
+		{
+			name:        "recursive with wildcard",
+			args:        []string{"--recursive", "s3://bucket/prefix/*"},
+			expectedErr: `"recursive" flag cannot be used with wildcards`,
+		},
+		{
+			name:        "recursive with raw",
+			args:        []string{"--recursive", "--raw", "s3://bucket/prefix"},
+			expectedErr: `it is not allowed to combine "recursive" and "raw" flags`,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			var err error
+			app := &cli.App{
+				Name: "s5cmd",
+				Commands: []*cli.Command{
+					{
+						Name: "cp",
+						Flags: append([]cli.Flag{
+							&cli.BoolFlag{Name: "raw"},
+						}, newAWSCLIFlags("copy")...),
+						Action: func(c *cli.Context) error {
+							err = validateAWSCLIFlags(c, c.Args().First())
+							return nil
+						},
+					},
+				},
+			}
+			if runErr := app.Run(append([]string{"s5cmd", "cp"}, tc.args...)); runErr != nil {
+				t.Fatal(runErr)
+			}
+
+			var got string
+			if err != nil {
+				got = err.Error()
+			}
+			if got != tc.expectedErr {
+				t.Errorf("expected error %q, got %q", tc.expectedErr, got)
+			}
+		})
+	}
+}
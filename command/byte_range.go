@@ -0,0 +1,135 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/peak/s5cmd/v2/storage"
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+const (
+	offsetFlagName = "offset"
+	lengthFlagName = "length"
+)
+
+func newByteRangeFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.Int64Flag{
+			Name:  offsetFlagName,
+			Usage: "read the object from the given byte offset; it is an offset of the decompressed content for the objects in zstd seekable format",
+		},
+		&cli.Int64Flag{
+			Name:  lengthFlagName,
+			Usage: "read only the given number of bytes of the object; it is a length of the decompressed content for the objects in zstd seekable format",
+		},
+	}
+}
+
+// byteRange is the range of the content of an object read with --offset and
+// --length flags.
+type byteRange struct {
+	offset int64
+	// length is negative if the object is read up to its end.
+	length int64
+}
+
+// parseByteRange returns the range given with --offset and --length flags, or
+// nil if none of them is set.
+func parseByteRange(c *cli.Context) *byteRange {
+	if !c.IsSet(offsetFlagName) && !c.IsSet(lengthFlagName) {
+		return nil
+	}
+
+	length := int64(-1)
+	if c.IsSet(lengthFlagName) {
+		length = c.Int64(lengthFlagName)
+	}
+	return &byteRange{
+		offset: c.Int64(offsetFlagName),
+		length: length,
+	}
+}
+
+func validateByteRangeFlags(c *cli.Context) error {
+	if c.IsSet(offsetFlagName) && c.Int64(offsetFlagName) < 0 {
+		return fmt.Errorf("%q flag must not be negative", offsetFlagName)
+	}
+	if c.IsSet(lengthFlagName) && c.Int64(lengthFlagName) <= 0 {
+		return fmt.Errorf("%q flag must be a positive number", lengthFlagName)
+	}
+	return nil
+}
+
+// readObjectRange writes the given range of the object to w. The range of the
+// objects in zstd seekable format is a range of their decompressed content,
+// and only the frames holding it are read.
+func readObjectRange(
+	ctx context.Context,
+	client *storage.S3,
+	src *url.URL,
+	obj *storage.Object,
+	r byteRange,
+	w io.Writer,
+) (int64, error) {
+	read := func(offset, length int64) (io.ReadCloser, error) {
+		return client.ReadRange(ctx, src, offset, length)
+	}
+
+	if isZstdSeekableCandidate(obj) {
+		return readZstdSeekableRange(read, obj.Size, r.offset, r.length, w)
+	}
+
+	if r.offset > obj.Size {
+		return 0, fmt.Errorf("offset %d is beyond the size %d of the object", r.offset, obj.Size)
+	}
+	// a range starting at the end of the object is not satisfiable.
+	if r.offset == obj.Size {
+		return 0, nil
+	}
+
+	body, err := read(r.offset, r.length)
+	if err != nil {
+		return 0, err
+	}
+	defer body.Close()
+	return io.Copy(w, body)
+}
+
+// validateCopyByteRange validates --offset and --length flags of cp, which
+// can only be used to download a single object.
+func validateCopyByteRange(c *cli.Context, srcurl, dsturl *url.URL) error {
+	if err := validateByteRangeFlags(c); err != nil {
+		return err
+	}
+	if parseByteRange(c) == nil {
+		return nil
+	}
+
+	if c.Command.Name != "cp" {
+		return fmt.Errorf("%q and %q flags can only be used with cp command", offsetFlagName, lengthFlagName)
+	}
+	if !srcurl.IsRemote() || srcurl.IsWildcard() || dsturl.IsRemote() {
+		return fmt.Errorf("%q and %q flags can only be used to download a single object", offsetFlagName, lengthFlagName)
+	}
+	return nil
+}
+
+// downloadRange downloads the range of the object given with --offset and
+// --length flags.
+func (c Copy) downloadRange(ctx context.Context, client *storage.S3, srcurl *url.URL, w io.Writer) (int64, error) {
+	if c.storageOpts.DryRun {
+		return 0, nil
+	}
+
+	// the metadata marking the objects in zstd seekable format is not
+	// returned with the listings.
+	obj, err := client.Stat(ctx, srcurl)
+	if err != nil {
+		return 0, err
+	}
+	return readObjectRange(ctx, client, srcurl, obj, *c.byteRange, w)
+}
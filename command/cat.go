@@ -28,6 +28,21 @@ Examples:
 
 	2. Print specific version of a remote object's content to stdout
 		 > s5cmd {{.HelpName}} --version-id VERSION_ID s3://bucket/prefix/object
+
+	3. Print 1024 bytes of a remote object starting from its 4096th byte
+		 > s5cmd {{.HelpName}} --offset 4096 --length 1024 s3://bucket/prefix/object
+
+	4. Print 1024 bytes of the decompressed content of an object in zstd seekable format, fetching only the frames holding them
+		 > s5cmd {{.HelpName}} --offset 4096 --length 1024 s3://bucket/prefix/object.zst
+
+	5. Print the first 100 lines of a remote object without downloading the rest
+		 > s5cmd {{.HelpName}} --head 100 s3://bucket/prefix/object.log
+
+	6. Print the last 50 lines of a remote object, reading only its end
+		 > s5cmd {{.HelpName}} --tail 50 s3://bucket/prefix/object.log
+
+	7. Print the first 10 lines of a gzip compressed remote object
+		 > s5cmd {{.HelpName}} --decompress --head 10 s3://bucket/prefix/object.log.gz
 `
 
 func NewCatCommand() *cli.Command {
@@ -35,7 +50,7 @@ func NewCatCommand() *cli.Command {
 		Name:     "cat",
 		HelpName: "cat",
 		Usage:    "print remote object content",
-		Flags: []cli.Flag{
+		Flags: append([]cli.Flag{
 			&cli.BoolFlag{
 				Name:  "raw",
 				Usage: "disable the wildcard operations, useful with filenames that contains glob characters",
@@ -56,7 +71,7 @@ func NewCatCommand() *cli.Command {
 				Value:   defaultPartSize,
 				Usage:   "size of each part transferred between host and remote server, in MiB",
 			},
-		},
+		}, append(newCatLinesFlags(), newByteRangeFlags()...)...),
 		CustomHelpTemplate: catHelpTemplate,
 		Before: func(c *cli.Context) error {
 			err := validateCatCommand(c)
@@ -86,6 +101,10 @@ func NewCatCommand() *cli.Command {
 				storageOpts: NewStorageOpts(c),
 				concurrency: c.Int("concurrency"),
 				partSize:    c.Int64("part-size") * megabytes,
+				head:        c.Int64(headFlagName),
+				tail:        c.Int64(tailFlagName),
+				decompress:  c.Bool(decompressFlagName),
+				byteRange:   parseByteRange(c),
 			}.Run(c.Context)
 		},
 	}
@@ -102,6 +121,12 @@ type Cat struct {
 	storageOpts storage.Options
 	concurrency int
 	partSize    int64
+	head        int64
+	tail        int64
+	decompress  bool
+	// byteRange is the range of the content printed with --offset and
+	// --length flags, if set.
+	byteRange *byteRange
 }
 
 // Run prints content of given source to standard output.
@@ -111,13 +136,25 @@ func (c Cat) Run(ctx context.Context) error {
 		printError(c.fullCommand, c.op, err)
 		return err
 	}
-	_, err = client.Stat(ctx, c.src)
+	obj, err := client.Stat(ctx, c.src)
 	if err != nil {
 		printError(c.fullCommand, c.op, err)
 		return err
 	}
-	buf := orderedwriter.New(os.Stdout)
-	_, err = client.Get(ctx, c.src, buf, c.concurrency, c.partSize)
+
+	switch {
+	case c.byteRange != nil:
+		_, err = readObjectRange(ctx, client, c.src, obj, *c.byteRange, os.Stdout)
+	case c.head > 0:
+		err = c.printHead(ctx, client, obj)
+	case c.tail > 0:
+		err = c.printTail(ctx, client, obj)
+	case c.decompress:
+		err = c.printContent(ctx, client, obj)
+	default:
+		buf := orderedwriter.New(os.Stdout)
+		_, err = client.Get(ctx, c.src, buf, c.concurrency, c.partSize)
+	}
 	if err != nil {
 		printError(c.fullCommand, c.op, err)
 		return err
@@ -152,5 +189,19 @@ func validateCatCommand(c *cli.Context) error {
 		return err
 	}
 
+	if err := validateCatLinesFlags(c); err != nil {
+		return err
+	}
+	if err := validateByteRangeFlags(c); err != nil {
+		return err
+	}
+
+	if parseByteRange(c) != nil {
+		for _, flagname := range []string{headFlagName, tailFlagName, decompressFlagName} {
+			if c.IsSet(flagname) {
+				return fmt.Errorf("it is not allowed to combine %q and %q flags with %q flag", offsetFlagName, lengthFlagName, flagname)
+			}
+		}
+	}
 	return nil
 }
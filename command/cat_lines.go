@@ -0,0 +1,274 @@
+package command
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/peak/s5cmd/v2/storage"
+)
+
+const (
+	headFlagName       = "head"
+	tailFlagName       = "tail"
+	decompressFlagName = "decompress"
+)
+
+// catTailChunkSize is the size of the first range read from the end of an
+// object to find its last lines. The ranges are doubled until they hold all
+// of the lines.
+var catTailChunkSize int64 = 64 * 1024
+
+func newCatLinesFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.Int64Flag{
+			Name:  headFlagName,
+			Usage: "print only the first N lines of the object, the download stops once they are printed",
+		},
+		&cli.Int64Flag{
+			Name:  tailFlagName,
+			Usage: "print only the last N lines of the object, which are read from its end in growing ranges",
+		},
+		&cli.BoolFlag{
+			Name:  decompressFlagName,
+			Usage: "decompress the gzip compressed content of the object; --tail reads the whole object with it",
+		},
+	}
+}
+
+// errHeadDone is returned by headWriter once the lines are written.
+var errHeadDone = errors.New("head lines are written")
+
+// headWriter writes the given number of the first lines of the content
+// written to it. It fails with errHeadDone once they are written, so that the
+// copy of the content stops.
+type headWriter struct {
+	w     io.Writer
+	lines int64
+}
+
+func (h *headWriter) Write(p []byte) (int, error) {
+	if h.lines <= 0 {
+		return 0, errHeadDone
+	}
+
+	n := len(p)
+	for i := 0; i < len(p); {
+		j := bytes.IndexByte(p[i:], '\n')
+		if j < 0 {
+			break
+		}
+		i += j + 1
+		h.lines--
+		if h.lines == 0 {
+			n = i
+			break
+		}
+	}
+
+	written, err := h.w.Write(p[:n])
+	if err != nil {
+		return written, err
+	}
+	if h.lines == 0 {
+		return written, errHeadDone
+	}
+	return written, nil
+}
+
+// lastLines returns the suffix of data holding its last n lines. The newline
+// at the end of data doesn't start a new line. It reports whether the first
+// of the lines starts in data, which is not known if data has n lines or
+// less.
+func lastLines(data []byte, n int64) ([]byte, bool) {
+	end := len(data)
+	if end > 0 && data[end-1] == '\n' {
+		end--
+	}
+	for i := end - 1; i >= 0; i-- {
+		if data[i] != '\n' {
+			continue
+		}
+		n--
+		if n == 0 {
+			return data[i+1:], true
+		}
+	}
+	return data, false
+}
+
+// gzipReadCloser is the decompressed content of an object, which closes the
+// object content as well.
+type gzipReadCloser struct {
+	*gzip.Reader
+	body io.Closer
+}
+
+func (r gzipReadCloser) Close() error {
+	r.Reader.Close()
+	return r.body.Close()
+}
+
+// openContent returns the content of the object from its beginning, which is
+// decompressed with --decompress flag. Closing it before it is read to its end
+// aborts the download.
+func (c Cat) openContent(ctx context.Context, client *storage.S3) (io.ReadCloser, error) {
+	body, err := client.ReadRange(ctx, c.src, 0, -1)
+	if err != nil {
+		return nil, err
+	}
+	if !c.decompress {
+		return body, nil
+	}
+
+	gz, err := gzip.NewReader(body)
+	if err != nil {
+		body.Close()
+		return nil, fmt.Errorf("could not decompress the object: %w", err)
+	}
+	return gzipReadCloser{Reader: gz, body: body}, nil
+}
+
+// printContent prints the whole content of the object with --decompress flag.
+func (c Cat) printContent(ctx context.Context, client *storage.S3, obj *storage.Object) error {
+	if obj.Size == 0 {
+		return nil
+	}
+
+	content, err := c.openContent(ctx, client)
+	if err != nil {
+		return err
+	}
+	defer content.Close()
+
+	_, err = io.Copy(os.Stdout, content)
+	return err
+}
+
+// printHead prints the first lines of the object. The download is aborted
+// once they are printed.
+func (c Cat) printHead(ctx context.Context, client *storage.S3, obj *storage.Object) error {
+	if obj.Size == 0 {
+		return nil
+	}
+
+	content, err := c.openContent(ctx, client)
+	if err != nil {
+		return err
+	}
+	defer content.Close()
+
+	_, err = io.Copy(&headWriter{w: os.Stdout, lines: c.head}, content)
+	if err == errHeadDone {
+		return nil
+	}
+	return err
+}
+
+// printTail prints the last lines of the object. They are read from the end
+// of the object in ranges doubled in size, until the ranges hold all of the
+// lines or the object is read to its beginning.
+func (c Cat) printTail(ctx context.Context, client *storage.S3, obj *storage.Object) error {
+	if c.decompress {
+		return c.printDecompressedTail(ctx, client, obj)
+	}
+
+	var data []byte
+	offset, chunk := obj.Size, catTailChunkSize
+	for offset > 0 {
+		start := offset - chunk
+		if start < 0 {
+			start = 0
+		}
+
+		part, err := c.readRange(ctx, client, start, offset-start)
+		if err != nil {
+			return err
+		}
+		data = append(part, data...)
+		offset = start
+
+		if lines, ok := lastLines(data, c.tail); ok || offset == 0 {
+			_, err := os.Stdout.Write(lines)
+			return err
+		}
+		chunk *= 2
+	}
+	return nil
+}
+
+// readRange reads the given range of the object.
+func (c Cat) readRange(ctx context.Context, client *storage.S3, offset, length int64) ([]byte, error) {
+	body, err := client.ReadRange(ctx, c.src, offset, length)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return io.ReadAll(body)
+}
+
+// printDecompressedTail prints the last lines of the decompressed content of
+// the object. The offsets of the lines in the compressed content are not
+// known, so the whole object is read and only its last lines are kept.
+func (c Cat) printDecompressedTail(ctx context.Context, client *storage.S3, obj *storage.Object) error {
+	if obj.Size == 0 {
+		return nil
+	}
+
+	content, err := c.openContent(ctx, client)
+	if err != nil {
+		return err
+	}
+	defer content.Close()
+
+	// lines is a ring of the last lines, whose oldest line is at next.
+	var (
+		lines  [][]byte
+		next   int64
+		reader = bufio.NewReader(content)
+	)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			if int64(len(lines)) < c.tail {
+				lines = append(lines, line)
+			} else {
+				lines[next] = line
+				next = (next + 1) % c.tail
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	for i := range lines {
+		if _, err := w.Write(lines[(next+int64(i))%int64(len(lines))]); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+func validateCatLinesFlags(c *cli.Context) error {
+	if c.IsSet(headFlagName) && c.IsSet(tailFlagName) {
+		return fmt.Errorf("it is not allowed to combine %q and %q flags", headFlagName, tailFlagName)
+	}
+	for _, flagname := range []string{headFlagName, tailFlagName} {
+		if c.IsSet(flagname) && c.Int64(flagname) <= 0 {
+			return fmt.Errorf("%q flag must be a positive number", flagname)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package command
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestHeadWriter(t *testing.T) {
+	t.Parallel()
+
+	content := "first\nsecond line\n" + strings.Repeat("x", 100) + "\nlast"
+
+	testcases := []struct {
+		name     string
+		lines    int64
+		expected string
+		done     bool
+	}{
+		{name: "first line", lines: 1, expected: "first\n", done: true},
+		{name: "long line", lines: 3, expected: "first\nsecond line\n" + strings.Repeat("x", 100) + "\n", done: true},
+		{name: "line without newline", lines: 4, expected: content},
+		{name: "more lines than content", lines: 10, expected: content},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			// the content is written in chunks which split the lines.
+			for _, chunkSize := range []int{1, 7, len(content)} {
+				var buf bytes.Buffer
+				w := &headWriter{w: &buf, lines: tc.lines}
+
+				var err error
+				for i := 0; i < len(content) && err == nil; i += chunkSize {
+					end := i + chunkSize
+					if end > len(content) {
+						end = len(content)
+					}
+					_, err = w.Write([]byte(content[i:end]))
+				}
+
+				assert.Equal(t, buf.String(), tc.expected, "chunk size %v", chunkSize)
+				assert.Equal(t, err == errHeadDone, tc.done, "chunk size %v", chunkSize)
+			}
+		})
+	}
+}
+
+func TestLastLines(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		data     string
+		lines    int64
+		expected string
+		found    bool
+	}{
+		{name: "last line", data: "a\nb\nc\n", lines: 1, expected: "c\n", found: true},
+		{name: "last lines", data: "a\nb\nc\n", lines: 2, expected: "b\nc\n", found: true},
+		{name: "all lines", data: "a\nb\nc\n", lines: 3, expected: "a\nb\nc\n"},
+		{name: "more lines than data", data: "a\nb\nc\n", lines: 10, expected: "a\nb\nc\n"},
+		{name: "without trailing newline", data: "a\nb\nc", lines: 1, expected: "c", found: true},
+		{name: "empty last line", data: "a\n\n", lines: 1, expected: "\n", found: true},
+		{name: "partial first line", data: "ong line\nb\n", lines: 2, expected: "ong line\nb\n"},
+		{name: "empty", data: "", lines: 1, expected: ""},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, found := lastLines([]byte(tc.data), tc.lines)
+			assert.Equal(t, string(got), tc.expected)
+			assert.Equal(t, found, tc.found)
+		})
+	}
+}
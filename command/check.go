@@ -0,0 +1,378 @@
+package command
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+
+	errorpkg "github.com/peak/s5cmd/v2/error"
+	"github.com/peak/s5cmd/v2/log"
+	"github.com/peak/s5cmd/v2/log/stat"
+	"github.com/peak/s5cmd/v2/storage"
+	"github.com/peak/s5cmd/v2/storage/url"
+	"github.com/peak/s5cmd/v2/strutil"
+)
+
+var checkHelpTemplate = `Name:
+	{{.HelpName}} - {{.Usage}}
+
+Usage:
+	{{.HelpName}} [options] s3://bucket[/prefix/]
+
+Options:
+	{{range .VisibleFlags}}{{.}}
+	{{end}}
+Examples:
+	1. Check that the credentials, the endpoint and the bucket are usable for reading objects under a prefix
+		 > s5cmd {{.HelpName}} s3://bucket/prefix/
+
+	2. Check that objects can also be written and deleted under a prefix, using a probe object
+		 > s5cmd {{.HelpName}} --write s3://bucket/prefix/
+
+	3. Check a bucket and print the results as JSON
+		 > s5cmd --json {{.HelpName}} s3://bucket
+`
+
+func NewCheckCommand() *cli.Command {
+	cmd := &cli.Command{
+		Name:               "check",
+		HelpName:           "check",
+		Usage:              "check credentials, endpoint and permissions on a bucket",
+		CustomHelpTemplate: checkHelpTemplate,
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "write",
+				Usage: "also check that a probe object can be written to and deleted from the prefix",
+			},
+		},
+		Before: func(c *cli.Context) error {
+			err := validateCheckCommand(c)
+			if err != nil {
+				printError(commandFromContext(c), c.Command.Name, err)
+			}
+			return err
+		},
+		Action: func(c *cli.Context) (err error) {
+			defer stat.Collect(c.Command.FullName(), &err)()
+
+			fullCommand := commandFromContext(c)
+
+			srcurl, err := url.New(c.Args().First(), url.WithRaw(true))
+			if err != nil {
+				printError(fullCommand, c.Command.Name, err)
+				return err
+			}
+
+			return Check{
+				src:         srcurl,
+				op:          c.Command.Name,
+				fullCommand: fullCommand,
+				write:       c.Bool("write"),
+				storageOpts: NewStorageOpts(c),
+			}.Run(c.Context)
+		},
+	}
+
+	cmd.BashComplete = getBashCompleteFn(cmd, true, false)
+	return cmd
+}
+
+// Check holds check operation flags and states.
+type Check struct {
+	src         *url.URL
+	op          string
+	fullCommand string
+
+	// flags
+	write bool
+
+	storageOpts storage.Options
+}
+
+// CheckStatus is the result of a single check.
+type CheckStatus string
+
+const (
+	CheckPass CheckStatus = "pass"
+	CheckFail CheckStatus = "fail"
+	CheckSkip CheckStatus = "skip"
+)
+
+// CheckResult is the result and the explanation of a single check.
+type CheckResult struct {
+	Name    string      `json:"check"`
+	Status  CheckStatus `json:"status"`
+	Message string      `json:"message,omitempty"`
+}
+
+// checker runs the checks in order. Once a check that the following checks
+// depend on fails, the following checks are skipped.
+type checker struct {
+	results []CheckResult
+	skip    string
+}
+
+func (c *checker) run(name string, check func() (string, error)) bool {
+	if c.skip != "" {
+		c.results = append(c.results, CheckResult{Name: name, Status: CheckSkip, Message: c.skip})
+		return false
+	}
+
+	msg, err := check()
+	if err != nil {
+		c.results = append(c.results, CheckResult{Name: name, Status: CheckFail, Message: err.Error()})
+		return false
+	}
+
+	c.results = append(c.results, CheckResult{Name: name, Status: CheckPass, Message: msg})
+	return true
+}
+
+// skipRest skips the remaining checks with the given reason, unless they are
+// already skipped.
+func (c *checker) skipRest(reason string) {
+	if c.skip == "" {
+		c.skip = reason
+	}
+}
+
+func (c *checker) failed() bool {
+	for _, r := range c.results {
+		if r.Status == CheckFail {
+			return true
+		}
+	}
+	return false
+}
+
+// Run checks the credentials, the endpoint, the bucket and the permissions
+// on the prefix of the source.
+func (ch Check) Run(ctx context.Context) error {
+	bucket := ch.src.Bucket
+	c := &checker{}
+
+	// the client without a bucket does not make any requests to resolve the
+	// region of the bucket, so the credentials and the endpoint are checked
+	// before the bucket.
+	client, err := storage.NewRemoteClient(ctx, &url.URL{}, ch.storageOpts)
+	if err != nil {
+		printError(ch.fullCommand, ch.op, err)
+		return err
+	}
+
+	ok := c.run("credentials", func() (string, error) {
+		provider, err := client.ResolveCredentials(ctx)
+		if err != nil {
+			return "", fmt.Errorf("credentials could not be resolved: %v; configure them with environment variables, a shared credentials file or an instance profile", cleanupError(err))
+		}
+		if provider == "" {
+			return "anonymous requests are sent", nil
+		}
+		return fmt.Sprintf("resolved by %v", provider), nil
+	})
+	if !ok {
+		c.skipRest("credentials check failed")
+	}
+
+	ok = c.run("endpoint", func() (string, error) {
+		// any response, even an error response, shows that the endpoint is
+		// reachable.
+		err := client.HeadBucket(ctx, bucket)
+		if err != nil && storage.ResponseStatusCode(err) == 0 {
+			return "", fmt.Errorf("endpoint is not reachable: %v; check the endpoint URL and the network connection", cleanupError(err))
+		}
+		return "endpoint is reachable", nil
+	})
+	if !ok {
+		c.skipRest("endpoint check failed")
+	}
+
+	var bucketClient *storage.S3
+	ok = c.run("bucket", func() (string, error) {
+		// the region of the bucket is resolved while creating the client.
+		bucketClient, err = storage.NewRemoteClient(ctx, ch.src, ch.storageOpts)
+		if err == nil {
+			err = bucketClient.HeadBucket(ctx, bucket)
+		}
+
+		switch {
+		case err == nil:
+			return fmt.Sprintf("bucket %q exists and is accessible", bucket), nil
+		case storage.IsNoSuchBucketError(err):
+			return "", fmt.Errorf("bucket %q does not exist; check the bucket name", bucket)
+		case storage.IsAccessDeniedError(err):
+			return "", fmt.Errorf("access to bucket %q is denied; s3:ListBucket permission is required", bucket)
+		default:
+			return "", fmt.Errorf("bucket %q could not be accessed: %v", bucket, cleanupError(err))
+		}
+	})
+	if !ok {
+		c.skipRest("bucket check failed")
+	}
+
+	ok = c.run("region", func() (string, error) {
+		region, err := bucketClient.BucketRegion(ctx, bucket)
+		if err != nil {
+			return "", fmt.Errorf("region of bucket %q could not be resolved: %v", bucket, cleanupError(err))
+		}
+		if region != bucketClient.Region() {
+			return "", fmt.Errorf("bucket %q is in %v, but requests are sent to %v; set AWS_REGION to %v or remove the configured region", bucket, region, bucketClient.Region(), region)
+		}
+		return fmt.Sprintf("bucket %q is in %v", bucket, region), nil
+	})
+	if !ok {
+		c.skipRest("region check failed")
+	}
+
+	listurl, err := url.New(fmt.Sprintf("s3://%v/%v*", bucket, ch.src.Path))
+	if err != nil {
+		printError(ch.fullCommand, ch.op, err)
+		return err
+	}
+
+	var object *storage.Object
+	listed := c.run("list", func() (string, error) {
+		object, err = firstObject(ctx, bucketClient, listurl)
+		if err != nil {
+			return "", fmt.Errorf("objects under %v could not be listed: %v; s3:ListBucket permission is required", listurl, cleanupError(err))
+		}
+		if object == nil {
+			return fmt.Sprintf("no objects found under %v", listurl), nil
+		}
+		return fmt.Sprintf("objects under %v are listed", listurl), nil
+	})
+
+	switch {
+	case !listed && c.skip == "":
+		c.results = append(c.results, CheckResult{Name: "head", Status: CheckSkip, Message: "list check failed"})
+	case listed && object == nil:
+		c.results = append(c.results, CheckResult{Name: "head", Status: CheckSkip, Message: "no objects to check"})
+	default:
+		c.run("head", func() (string, error) {
+			if _, err := bucketClient.Stat(ctx, object.URL); err != nil {
+				return "", fmt.Errorf("metadata of %v could not be read: %v; s3:GetObject permission is required", object.URL, cleanupError(err))
+			}
+			return fmt.Sprintf("metadata of %v is read", object.URL), nil
+		})
+	}
+
+	if !ch.write {
+		c.skipRest("use --write flag to check")
+	}
+
+	probe, err := probeURL(bucket, ch.src.Path)
+	if err != nil {
+		printError(ch.fullCommand, ch.op, err)
+		return err
+	}
+
+	ok = c.run("write", func() (string, error) {
+		err := bucketClient.Put(ctx, strings.NewReader("s5cmd check probe\n"), probe, storage.NewMetadata(), 1, storage.MinPartSize)
+		if err != nil {
+			return "", fmt.Errorf("probe object %v could not be written: %v; s3:PutObject permission is required", probe, cleanupError(err))
+		}
+		return fmt.Sprintf("probe object %v is written", probe), nil
+	})
+	if !ok {
+		c.skipRest("write check failed")
+	}
+
+	c.run("delete", func() (string, error) {
+		// the probe object must not be left behind even if the operation is
+		// canceled.
+		if err := bucketClient.Delete(context.Background(), probe); err != nil {
+			return "", fmt.Errorf("probe object %v could not be deleted: %v; s3:DeleteObject permission is required, delete the probe object manually", probe, cleanupError(err))
+		}
+		return fmt.Sprintf("probe object %v is deleted", probe), nil
+	})
+
+	log.Info(CheckMessage{
+		Source:  ch.src.String(),
+		Success: !c.failed(),
+		Checks:  c.results,
+	})
+
+	if c.failed() {
+		return fmt.Errorf("check failed")
+	}
+	return nil
+}
+
+// firstObject returns the first object listed by the given URL, or nil if no
+// objects are found.
+func firstObject(ctx context.Context, client *storage.S3, src *url.URL) (*storage.Object, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	for object := range client.List(ctx, src, false) {
+		if err := object.Err; err != nil {
+			if err == storage.ErrNoObjectFound {
+				return nil, nil
+			}
+			if errorpkg.IsCancelation(err) {
+				continue
+			}
+			return nil, err
+		}
+		if object.Type.IsDir() {
+			continue
+		}
+		// stop listing once an object is found.
+		cancel()
+		return object, nil
+	}
+	return nil, nil
+}
+
+// probeURL returns a unique URL for the probe object under the given prefix.
+func probeURL(bucket, prefix string) (*url.URL, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	return url.New(fmt.Sprintf("s3://%v/%v.s5cmd-check-%v", bucket, prefix, hex.EncodeToString(b)), url.WithRaw(true))
+}
+
+// CheckMessage is the structure for logging the results of the checks.
+type CheckMessage struct {
+	Source  string        `json:"source"`
+	Success bool          `json:"success"`
+	Checks  []CheckResult `json:"checks"`
+}
+
+// String returns the string representation of CheckMessage as a table.
+func (m CheckMessage) String() string {
+	const rowFormat = "%-12s %-6s %s"
+
+	lines := []string{fmt.Sprintf(rowFormat, "CHECK", "STATUS", "MESSAGE")}
+	for _, r := range m.Checks {
+		lines = append(lines, strings.TrimSpace(fmt.Sprintf(rowFormat, r.Name, r.Status, r.Message)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// JSON returns the JSON representation of CheckMessage.
+func (m CheckMessage) JSON() string {
+	return strutil.JSON(m)
+}
+
+func validateCheckCommand(c *cli.Context) error {
+	if c.Args().Len() != 1 {
+		return fmt.Errorf("expected only 1 argument")
+	}
+
+	srcurl, err := url.New(c.Args().First(), url.WithRaw(true))
+	if err != nil {
+		return err
+	}
+
+	if !srcurl.IsRemote() {
+		return fmt.Errorf("source must be a remote bucket or prefix")
+	}
+
+	return nil
+}
@@ -0,0 +1,283 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kballard/go-shellquote"
+	"github.com/urfave/cli/v2"
+
+	errorpkg "github.com/peak/s5cmd/v2/error"
+	"github.com/peak/s5cmd/v2/log"
+	"github.com/peak/s5cmd/v2/strutil"
+)
+
+const (
+	onCompleteExecFlagName    = "on-complete-exec"
+	onCompleteWebhookFlagName = "on-complete-webhook"
+	onCompleteTimeoutFlagName = "on-complete-timeout"
+
+	defaultOnCompleteTimeout = 30 * time.Second
+
+	// completionExitCodeEnv is the environment variable the exit code of
+	// s5cmd is given to the completion command in.
+	completionExitCodeEnv = "S5CMD_EXIT_CODE"
+
+	// completionWebhookAttempts is the number of times the summary is posted
+	// to the webhook until it succeeds, within the timeout of the hook.
+	completionWebhookAttempts = 3
+	// completionWebhookBackoff is the delay before the first retry of the
+	// webhook, which is doubled for the next ones.
+	completionWebhookBackoff = time.Second
+)
+
+func newCompletionHookFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:  onCompleteExecFlagName,
+			Usage: "run the given command once s5cmd completes, successfully or not, with the JSON summary of the run on its standard input and the exit code in " + completionExitCodeEnv + " environment variable",
+		},
+		&cli.StringFlag{
+			Name:  onCompleteWebhookFlagName,
+			Usage: "POST the JSON summary of the run to the given URL once s5cmd completes, successfully or not, retrying the failed requests",
+		},
+		&cli.DurationFlag{
+			Name:  onCompleteTimeoutFlagName,
+			Value: defaultOnCompleteTimeout,
+			Usage: "time limit of each of the completion hooks, including the retries of the webhook",
+		},
+	}
+}
+
+// completionHooks are the hooks given with the "on-complete-*" flags. They are
+// nil unless any of them is given.
+var completionHooks *completionHook
+
+// CompletionSummary is the summary of a run of s5cmd which is given to the
+// completion hooks.
+type CompletionSummary struct {
+	Command   string    `json:"command"`
+	Success   bool      `json:"success"`
+	ExitCode  int       `json:"exit_code"`
+	Error     string    `json:"error,omitempty"`
+	Canceled  bool      `json:"canceled,omitempty"`
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+	// Elapsed is the duration of the run in seconds.
+	Elapsed     float64      `json:"elapsed"`
+	Errors      int64        `json:"errors"`
+	ErrorGroups []ErrorGroup `json:"error_groups,omitempty"`
+}
+
+// JSON is the JSON representation of CompletionSummary.
+func (s CompletionSummary) JSON() string {
+	return strutil.JSON(s)
+}
+
+// completionHook notifies the completion of s5cmd with the command given with
+// --on-complete-exec and the webhook given with --on-complete-webhook.
+type completionHook struct {
+	command   string
+	startTime time.Time
+
+	args    []string
+	webhook string
+	timeout time.Duration
+	client  *http.Client
+	backoff time.Duration
+
+	once sync.Once
+}
+
+// newCompletionHook creates the completion hooks from the flags. It returns
+// nil if none of them is given.
+func newCompletionHook(c *cli.Context) *completionHook {
+	command, webhook := c.String(onCompleteExecFlagName), c.String(onCompleteWebhookFlagName)
+	if command == "" && webhook == "" {
+		return nil
+	}
+	// the command is validated by validateCompletionHookFlags.
+	args, _ := shellquote.Split(command)
+	return &completionHook{
+		command:   strings.Join(c.Args().Slice(), " "),
+		startTime: time.Now(),
+		args:      args,
+		webhook:   webhook,
+		timeout:   c.Duration(onCompleteTimeoutFlagName),
+		client:    http.DefaultClient,
+		backoff:   completionWebhookBackoff,
+	}
+}
+
+// run runs the hooks with the summary of the run which ended with err. The
+// hooks are run only once even if it is called again, e.g. by a shutdown
+// path. They are not run with ctx, which is canceled on interrupt, so that
+// they are also run for the runs interrupted by a signal. Their failures are
+// printed, but they don't change the exit code of s5cmd.
+func (h *completionHook) run(ctx context.Context, err error) {
+	if h == nil {
+		return
+	}
+	h.once.Do(func() {
+		summary := h.summary(ctx, err)
+		body := []byte(summary.JSON())
+
+		// the output of the command is flushed before the hooks, so that the
+		// output of the completion command follows it.
+		log.Flush()
+
+		if len(h.args) > 0 {
+			if err := h.exec(body, summary.ExitCode); err != nil {
+				h.printError(onCompleteExecFlagName, fmt.Errorf("completion command failed: %w", err))
+			}
+		}
+		if h.webhook != "" {
+			if err := h.post(body); err != nil {
+				h.printError(onCompleteWebhookFlagName, fmt.Errorf("completion webhook failed: %w", err))
+			}
+		}
+	})
+}
+
+// summary returns the summary of the run which ended with err.
+func (h *completionHook) summary(ctx context.Context, err error) CompletionSummary {
+	now := time.Now()
+	failed := failures.message()
+
+	summary := CompletionSummary{
+		Command:     h.command,
+		Success:     err == nil || errors.Is(err, errSyncChanged) || errors.Is(err, errSyncPlanned),
+		ExitCode:    errorpkg.ExitCode(err),
+		Canceled:    ctx.Err() != nil,
+		StartTime:   h.startTime,
+		EndTime:     now,
+		Elapsed:     now.Sub(h.startTime).Seconds(),
+		Errors:      failed.Errors,
+		ErrorGroups: failed.Groups,
+	}
+	if !summary.Success {
+		summary.Error = cleanupError(err)
+	}
+	return summary
+}
+
+// exec runs the completion command with the summary on its standard input.
+func (h *completionHook) exec(summary []byte, exitCode int) error {
+	ctx, cancel := h.context()
+	defer cancel()
+
+	// the arguments are not passed through a shell, as the ones of the
+	// compare command.
+	cmd := exec.CommandContext(ctx, h.args[0], h.args[1:]...)
+	cmd.Env = append(os.Environ(), completionExitCodeEnv+"="+strconv.Itoa(exitCode))
+	cmd.Stdin = bytes.NewReader(summary)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	err := cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("timed out after %v", h.timeout)
+	}
+	return err
+}
+
+// post posts the summary to the webhook. The requests which fail with a
+// network error, a server error or too many requests are retried with
+// exponential backoff.
+func (h *completionHook) post(summary []byte) error {
+	ctx, cancel := h.context()
+	defer cancel()
+
+	backoff := h.backoff
+	var err error
+	for attempt := 1; ; attempt++ {
+		var retryable bool
+		retryable, err = h.postOnce(ctx, summary)
+		if err == nil || !retryable || attempt == completionWebhookAttempts {
+			break
+		}
+
+		select {
+		case <-time.After(backoff):
+			backoff *= 2
+		case <-ctx.Done():
+			return fmt.Errorf("timed out after %v: %w", h.timeout, err)
+		}
+	}
+	return err
+}
+
+// postOnce posts the summary to the webhook, and reports whether the request
+// is retried if it fails.
+func (h *completionHook) postOnce(ctx context.Context, summary []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.webhook, bytes.NewReader(summary))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retryable := resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests
+	return retryable, fmt.Errorf("%v returned %v", h.webhook, resp.Status)
+}
+
+// context returns the context of a hook, which is canceled after the timeout
+// of the hooks, if any.
+func (h *completionHook) context() (context.Context, context.CancelFunc) {
+	if h.timeout > 0 {
+		return context.WithTimeout(context.Background(), h.timeout)
+	}
+	return context.WithCancel(context.Background())
+}
+
+// printError prints the failure of a hook. It is not added to the failures of
+// the command, since they are summarized already.
+func (h *completionHook) printError(op string, err error) {
+	log.Error(log.ErrorMessage{
+		Operation: op,
+		Command:   h.command,
+		Err:       cleanupError(err),
+	})
+}
+
+func validateCompletionHookFlags(c *cli.Context) error {
+	if c.Duration(onCompleteTimeoutFlagName) < 0 {
+		return fmt.Errorf("%q flag must not be negative", onCompleteTimeoutFlagName)
+	}
+	if c.IsSet(onCompleteExecFlagName) {
+		args, err := shellquote.Split(c.String(onCompleteExecFlagName))
+		if err != nil {
+			return fmt.Errorf("invalid %q flag: %w", onCompleteExecFlagName, err)
+		}
+		if len(args) == 0 {
+			return fmt.Errorf("%q flag requires a command", onCompleteExecFlagName)
+		}
+	}
+	if webhook := c.String(onCompleteWebhookFlagName); c.IsSet(onCompleteWebhookFlagName) {
+		u, err := neturl.Parse(webhook)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("%q flag must be an http or https URL", onCompleteWebhookFlagName)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,227 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/urfave/cli/v2"
+	"gotest.tools/v3/assert"
+
+	errorpkg "github.com/peak/s5cmd/v2/error"
+)
+
+func TestCompletionHookPost(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name             string
+		statuses         []int
+		expectedRequests int64
+		expectedErr      string
+	}{
+		{
+			name:             "success",
+			statuses:         []int{http.StatusOK},
+			expectedRequests: 1,
+		},
+		{
+			name:             "server error is retried",
+			statuses:         []int{http.StatusInternalServerError, http.StatusTooManyRequests, http.StatusNoContent},
+			expectedRequests: 3,
+		},
+		{
+			name:             "retries are exhausted",
+			statuses:         []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway, http.StatusOK},
+			expectedRequests: completionWebhookAttempts,
+			expectedErr:      "502 Bad Gateway",
+		},
+		{
+			name:             "client error is not retried",
+			statuses:         []int{http.StatusNotFound, http.StatusOK},
+			expectedRequests: 1,
+			expectedErr:      "404 Not Found",
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var requests int64
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := atomic.AddInt64(&requests, 1)
+				assert.Equal(t, r.Method, http.MethodPost)
+				assert.Equal(t, r.Header.Get("Content-Type"), "application/json")
+				body, err := io.ReadAll(r.Body)
+				assert.NilError(t, err)
+				assert.Equal(t, string(body), `{"command":"sync"}`)
+				w.WriteHeader(tc.statuses[n-1])
+			}))
+			defer server.Close()
+
+			hook := &completionHook{
+				webhook: server.URL,
+				timeout: time.Minute,
+				client:  server.Client(),
+				backoff: time.Millisecond,
+			}
+			err := hook.post([]byte(`{"command":"sync"}`))
+			if tc.expectedErr != "" {
+				assert.ErrorContains(t, err, tc.expectedErr)
+			} else {
+				assert.NilError(t, err)
+			}
+			assert.Equal(t, atomic.LoadInt64(&requests), tc.expectedRequests)
+		})
+	}
+}
+
+func TestCompletionHookSummary(t *testing.T) {
+	t.Parallel()
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	testcases := []struct {
+		name             string
+		ctx              context.Context
+		err              error
+		expectedSuccess  bool
+		expectedCode     int
+		expectedError    string
+		expectedCanceled bool
+	}{
+		{
+			name:            "success",
+			ctx:             context.Background(),
+			expectedSuccess: true,
+		},
+		{
+			name:            "changes made",
+			ctx:             context.Background(),
+			err:             errSyncChanged,
+			expectedSuccess: true,
+			expectedCode:    syncExitChanged,
+		},
+		{
+			name:            "changes planned",
+			ctx:             context.Background(),
+			err:             errSyncPlanned,
+			expectedSuccess: true,
+			expectedCode:    syncExitPlanned,
+		},
+		{
+			name:          "failure",
+			ctx:           context.Background(),
+			err:           &errorpkg.ExitError{Code: 2, Err: errors.New("failed")},
+			expectedCode:  2,
+			expectedError: "failed",
+		},
+		{
+			name:             "interrupted",
+			ctx:              canceled,
+			err:              fmt.Errorf("sync: %w", context.Canceled),
+			expectedCode:     1,
+			expectedError:    "sync: context canceled",
+			expectedCanceled: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			hook := &completionHook{command: "sync dir/ s3://bucket/", startTime: time.Now()}
+			summary := hook.summary(tc.ctx, tc.err)
+
+			assert.Equal(t, summary.Command, "sync dir/ s3://bucket/")
+			assert.Equal(t, summary.Success, tc.expectedSuccess)
+			assert.Equal(t, summary.ExitCode, tc.expectedCode)
+			assert.Equal(t, summary.Error, tc.expectedError)
+			assert.Equal(t, summary.Canceled, tc.expectedCanceled)
+			assert.Assert(t, !summary.EndTime.Before(summary.StartTime))
+
+			var decoded map[string]interface{}
+			assert.NilError(t, json.Unmarshal([]byte(summary.JSON()), &decoded))
+			assert.Equal(t, decoded["exit_code"], float64(tc.expectedCode))
+		})
+	}
+}
+
+// newAppContext returns the context of the app with the given global flags.
+func newAppContext(t *testing.T, flags map[string]string) *cli.Context {
+	t.Helper()
+
+	set := flag.NewFlagSet(app.Name, flag.ContinueOnError)
+	for _, f := range app.Flags {
+		assert.NilError(t, f.Apply(set))
+	}
+	ctx := cli.NewContext(app, set, nil)
+	for name, value := range flags {
+		assert.NilError(t, ctx.Set(name, value))
+	}
+	return ctx
+}
+
+func TestCompletionHookQuotedCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("completion command requires a POSIX shell")
+	}
+
+	output := filepath.Join(t.TempDir(), "summary file.json")
+	c := newAppContext(t, map[string]string{
+		onCompleteExecFlagName: fmt.Sprintf(`sh -c 'cat > "$0"' %q`, output),
+	})
+	assert.NilError(t, validateCompletionHookFlags(c))
+
+	hook := newCompletionHook(c)
+	assert.DeepEqual(t, hook.args, []string{"sh", "-c", `cat > "$0"`, output})
+
+	assert.NilError(t, hook.exec([]byte(`{"success":true}`), 0))
+	data, err := os.ReadFile(output)
+	assert.NilError(t, err)
+	assert.Equal(t, string(data), `{"success":true}`)
+}
+
+func TestValidateCompletionHookCommand(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		command  string
+		expected string
+	}{
+		{name: "quoted arguments", command: `notify-send "sync completed"`},
+		{name: "unterminated quote", command: `notify-send "sync completed`, expected: `invalid "on-complete-exec" flag: Unterminated double-quoted string`},
+		{name: "blank command", command: "  ", expected: `"on-complete-exec" flag requires a command`},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			c := newAppContext(t, map[string]string{onCompleteExecFlagName: tc.command})
+			err := validateCompletionHookFlags(c)
+			if tc.expected == "" {
+				assert.NilError(t, err)
+				return
+			}
+			assert.Error(t, err, tc.expected)
+		})
+	}
+}
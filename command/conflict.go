@@ -0,0 +1,141 @@
+package command
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+const (
+	// conflictOverwrite lets the objects copied to the same destination key
+	// overwrite each other.
+	conflictOverwrite = "overwrite"
+	// conflictRename appends a suffix to the destination keys which are
+	// already written by the run.
+	conflictRename = "rename"
+
+	defaultConflictSuffixFormat = "-{{.N}}"
+)
+
+// destinationKeys holds the destination keys of the objects copied with
+// "--on-conflict rename" by the process, so that the commands of a run don't
+// overwrite each other either.
+var destinationKeys = &keySet{keys: map[string]struct{}{}}
+
+type keySet struct {
+	mu   sync.Mutex
+	keys map[string]struct{}
+}
+
+// conflictSuffix is the suffix appended to the names of the conflicting
+// destination keys.
+type conflictSuffix struct {
+	tmpl      *template.Template
+	timestamp string
+}
+
+// conflictSuffixData is the data the conflict suffix format is executed with.
+type conflictSuffixData struct {
+	// N is the number of the attempt to find a free key, starting from 1.
+	N int
+	// Timestamp is the start time of the command in UTC.
+	Timestamp string
+}
+
+func newConflictSuffix(format string, now time.Time) (*conflictSuffix, error) {
+	tmpl, err := template.New("conflict-suffix").Option("missingkey=error").Parse(format)
+	if err != nil {
+		return nil, err
+	}
+
+	suffix := &conflictSuffix{
+		tmpl:      tmpl,
+		timestamp: now.UTC().Format("20060102T150405Z"),
+	}
+
+	// fail early with the errors of the fields which don't exist.
+	s, err := suffix.format(1)
+	if err != nil {
+		return nil, err
+	}
+	if s == "" {
+		return nil, fmt.Errorf("suffix is empty")
+	}
+	return suffix, nil
+}
+
+func (s *conflictSuffix) format(n int) (string, error) {
+	var b strings.Builder
+	if err := s.tmpl.Execute(&b, conflictSuffixData{N: n, Timestamp: s.timestamp}); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// reserve reserves the key of dsturl and returns it. If the key is already
+// reserved, the suffix is appended to the name of the key, before its
+// extension, until a key which is not reserved is found.
+func (k *keySet) reserve(dsturl *url.URL, suffix *conflictSuffix) (*url.URL, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if _, ok := k.keys[dsturl.String()]; !ok {
+		k.keys[dsturl.String()] = struct{}{}
+		return dsturl, nil
+	}
+
+	dir, name := path.Split(dsturl.Path)
+	ext := path.Ext(name)
+	// the names which start with a dot, e.g. ".bashrc", have no extension.
+	if ext == name {
+		ext = ""
+	}
+	stem := strings.TrimSuffix(name, ext)
+
+	var previous string
+	for n := 1; ; n++ {
+		s, err := suffix.format(n)
+		if err != nil {
+			return nil, err
+		}
+
+		renamed := dsturl.Clone()
+		renamed.Path = dir + stem + s + ext
+		key := renamed.String()
+		if key == previous {
+			return nil, fmt.Errorf("conflict suffix format %q doesn't give a new key for %q", suffix.tmpl.Root.String(), dsturl)
+		}
+
+		if _, ok := k.keys[key]; !ok {
+			k.keys[key] = struct{}{}
+			return renamed, nil
+		}
+		previous = key
+	}
+}
+
+func validateOnConflict(c *cli.Context, dsturl *url.URL) error {
+	if c.IsSet("conflict-suffix-format") && c.String("on-conflict") != conflictRename {
+		return fmt.Errorf(`"conflict-suffix-format" flag requires "--on-conflict %v"`, conflictRename)
+	}
+
+	if c.String("on-conflict") != conflictRename {
+		return nil
+	}
+
+	if !dsturl.IsRemote() {
+		return fmt.Errorf(`"--on-conflict %v" can only be used with remote destination`, conflictRename)
+	}
+
+	if _, err := newConflictSuffix(c.String("conflict-suffix-format"), time.Now()); err != nil {
+		return fmt.Errorf("invalid conflict-suffix-format: %w", err)
+	}
+	return nil
+}
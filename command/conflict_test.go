@@ -0,0 +1,89 @@
+package command
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+func TestKeySetReserve(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+
+	testcases := []struct {
+		name     string
+		format   string
+		keys     []string
+		expected []string
+	}{
+		{
+			name:     "counter before extension",
+			format:   defaultConflictSuffixFormat,
+			keys:     []string{"s3://bucket/a.txt", "s3://bucket/a.txt", "s3://bucket/a.txt", "s3://bucket/b.txt"},
+			expected: []string{"s3://bucket/a.txt", "s3://bucket/a-1.txt", "s3://bucket/a-2.txt", "s3://bucket/b.txt"},
+		},
+		{
+			name:     "renamed key is taken",
+			format:   defaultConflictSuffixFormat,
+			keys:     []string{"s3://bucket/a-1.txt", "s3://bucket/a.txt", "s3://bucket/a.txt"},
+			expected: []string{"s3://bucket/a-1.txt", "s3://bucket/a.txt", "s3://bucket/a-2.txt"},
+		},
+		{
+			name:     "no extension",
+			format:   defaultConflictSuffixFormat,
+			keys:     []string{"s3://bucket/dir/.bashrc", "s3://bucket/dir/.bashrc", "s3://bucket/dir/Makefile", "s3://bucket/dir/Makefile"},
+			expected: []string{"s3://bucket/dir/.bashrc", "s3://bucket/dir/.bashrc-1", "s3://bucket/dir/Makefile", "s3://bucket/dir/Makefile-1"},
+		},
+		{
+			name:     "timestamp",
+			format:   "-{{.Timestamp}}-{{.N}}",
+			keys:     []string{"s3://bucket/a.txt", "s3://bucket/a.txt"},
+			expected: []string{"s3://bucket/a.txt", "s3://bucket/a-20240301T123000Z-1.txt"},
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			suffix, err := newConflictSuffix(tc.format, now)
+			assert.NilError(t, err)
+
+			keys := &keySet{keys: map[string]struct{}{}}
+			for i, key := range tc.keys {
+				u, err := url.New(key)
+				assert.NilError(t, err)
+
+				got, err := keys.reserve(u, suffix)
+				assert.NilError(t, err)
+				assert.Equal(t, tc.expected[i], got.String())
+			}
+		})
+	}
+}
+
+func TestKeySetReserveWithoutCounter(t *testing.T) {
+	suffix, err := newConflictSuffix("-{{.Timestamp}}", time.Now())
+	assert.NilError(t, err)
+
+	keys := &keySet{keys: map[string]struct{}{}}
+	u, err := url.New("s3://bucket/a.txt")
+	assert.NilError(t, err)
+
+	for i := 0; i < 2; i++ {
+		_, err := keys.reserve(u, suffix)
+		assert.NilError(t, err)
+	}
+
+	// the suffix doesn't change between the attempts.
+	_, err = keys.reserve(u, suffix)
+	assert.ErrorContains(t, err, `doesn't give a new key for "s3://bucket/a.txt"`)
+}
+
+func TestNewConflictSuffixInvalid(t *testing.T) {
+	for _, format := range []string{"-{{.N", "-{{.Name}}", ""} {
+		_, err := newConflictSuffix(format, time.Now())
+		assert.Assert(t, err != nil, "format: %q", format)
+	}
+}
@@ -1,7 +1,6 @@
 package command
 
 import (
-	"flag"
 	"fmt"
 	"sort"
 	"strconv"
@@ -25,14 +24,24 @@ func commandFromContext(c *cli.Context) string {
 		cmd = fmt.Sprintf("%v %v", cmd, strings.Join(c.Args().Slice(), " "))
 	}
 
+	// the commands of run and sync are prefixed with their positions in the
+	// input, so that their errors can be traced back to it.
+	if pos := commandPosition(c); pos != "" {
+		cmd = fmt.Sprintf("%v: %v", pos, cmd)
+	}
+
 	return cmd
 }
 
 // contextValue traverses context and its ancestor contexts to find
-// the flag value and returns string slice.
+// the flag value and returns string slice. The flags of a command hide the
+// ones of its ancestors with the same name, e.g. "dry-run" flag of sync.
 func contextValue(c *cli.Context, flagname string) []string {
 	for _, c := range c.Lineage() {
 		if !c.IsSet(flagname) {
+			if c.Command != nil && hasFlag(c.Command, flagname) {
+				return nil
+			}
 			continue
 		}
 
@@ -40,6 +49,8 @@ func contextValue(c *cli.Context, flagname string) []string {
 		switch val.(type) {
 		case cli.StringSlice:
 			return c.StringSlice(flagname)
+		case HeaderValue:
+			return val.(HeaderValue).Values()
 		case cli.Int64Slice, cli.IntSlice:
 			values := c.Int64Slice(flagname)
 			var result []string
@@ -61,46 +72,114 @@ func contextValue(c *cli.Context, flagname string) []string {
 	return nil
 }
 
-// generateCommand generates command string from given context, app command, default flags and urls.
-func generateCommand(c *cli.Context, cmd string, defaultFlags map[string]interface{}, urls ...*url.URL) (string, error) {
-	command := AppCommand(cmd)
-	flagset := flag.NewFlagSet(command.Name, flag.ContinueOnError)
-
-	var args []string
-	for _, url := range urls {
-		args = append(args, fmt.Sprintf("%q", url.String()))
+// quoteFlagValue quotes the flag value if it has spaces or quotes, so that the
+// generated commands are split into the same flags when they are run, e.g.
+// "--transform-exec=gzip -c".
+func quoteFlagValue(value string) string {
+	if strings.ContainsAny(value, " \t\n'\"") {
+		return strconv.Quote(value)
 	}
+	return value
+}
 
-	flags := []string{}
-	for flagname, flagvalue := range defaultFlags {
-		flags = append(flags, fmt.Sprintf("--%s=%v", flagname, flagvalue))
-	}
+// unforwardedFlags are the flags which are applied by the command generating
+// the commands, so that they are not applied once more by the generated ones.
+var unforwardedFlags = map[string]struct{}{
+	destDatePrefixFlagName:     {},
+	"exclude":                  {},
+	includeFlagName:            {},
+	storageClassFilterFlagName: {},
+}
 
-	isDefaultFlag := func(flagname string) bool {
-		_, ok := defaultFlags[flagname]
-		return ok
+// commandGenerator generates the commands of an app command with the same
+// flags for different urls, e.g. the copy commands of a sync. The flags are
+// resolved and encoded once, since they don't change between the commands.
+type commandGenerator struct {
+	name         string
+	defaultFlags map[string]interface{}
+	// forwarded are the flags of the context forwarded to the generated
+	// commands, in "--name=value" form by their names.
+	forwarded map[string][]string
+	// prefix is the name of the command followed by its sorted flags.
+	prefix string
+}
+
+// newCommandGenerator returns the generator of the commands of the app command
+// cmd with the default flags and the flags of the command which are set on c.
+// The default flags take precedence over the ones of the context.
+func newCommandGenerator(c *cli.Context, cmd string, defaultFlags map[string]interface{}) *commandGenerator {
+	command := AppCommand(cmd)
+	g := &commandGenerator{
+		name:         command.Name,
+		defaultFlags: defaultFlags,
+		forwarded:    map[string][]string{},
 	}
 
 	for _, f := range command.Flags {
 		flagname := f.Names()[0]
-		if isDefaultFlag(flagname) || !c.IsSet(flagname) {
+		if _, ok := defaultFlags[flagname]; ok || !c.IsSet(flagname) {
+			continue
+		}
+		if _, ok := unforwardedFlags[flagname]; ok {
 			continue
 		}
 
 		for _, flagvalue := range contextValue(c, flagname) {
-			flags = append(flags, fmt.Sprintf("--%s=%s", flagname, flagvalue))
+			g.forwarded[flagname] = append(g.forwarded[flagname], fmt.Sprintf("--%s=%s", flagname, quoteFlagValue(flagvalue)))
 		}
 	}
 
+	g.prefix = strings.Join(append([]string{g.name}, g.flags(nil)...), " ")
+	return g
+}
+
+// flags returns the sorted flags of a command generated with the given
+// extra flags, which take precedence over the others. The extra flags given
+// multiple times have string slice values.
+func (g *commandGenerator) flags(extraFlags map[string]interface{}) []string {
+	flags := []string{}
+	for flagname, flagvalue := range g.defaultFlags {
+		if _, ok := extraFlags[flagname]; ok {
+			continue
+		}
+		flags = append(flags, fmt.Sprintf("--%s=%v", flagname, flagvalue))
+	}
+	for flagname, flagvalue := range extraFlags {
+		if values, ok := flagvalue.([]string); ok {
+			for _, value := range values {
+				flags = append(flags, fmt.Sprintf("--%s=%s", flagname, quoteFlagValue(value)))
+			}
+			continue
+		}
+		flags = append(flags, fmt.Sprintf("--%s=%v", flagname, flagvalue))
+	}
+	for flagname, values := range g.forwarded {
+		if _, ok := extraFlags[flagname]; ok {
+			continue
+		}
+		flags = append(flags, values...)
+	}
 	sort.Strings(flags)
-	flags = append(flags, args...)
-	flags = append([]string{command.Name}, flags...)
+	return flags
+}
 
-	err := flagset.Parse(flags)
-	if err != nil {
-		return "", err
+// generate returns the command of the given urls. The extra flags, e.g. the
+// version of the source of a copy, are added to the flags of the generator.
+func (g *commandGenerator) generate(extraFlags map[string]interface{}, urls ...*url.URL) string {
+	var b strings.Builder
+	if len(extraFlags) == 0 {
+		b.WriteString(g.prefix)
+	} else {
+		b.WriteString(strings.Join(append([]string{g.name}, g.flags(extraFlags)...), " "))
 	}
+	for _, url := range urls {
+		b.WriteByte(' ')
+		b.WriteString(strconv.Quote(url.String()))
+	}
+	return b.String()
+}
 
-	cmdCtx := cli.NewContext(c.App, flagset, c)
-	return strings.TrimSpace(commandFromContext(cmdCtx)), nil
+// generateCommand generates command string from given context, app command, default flags and urls.
+func generateCommand(c *cli.Context, cmd string, defaultFlags map[string]interface{}, urls ...*url.URL) string {
+	return newCommandGenerator(c, cmd, defaultFlags).generate(nil, urls...)
 }
@@ -2,6 +2,8 @@ package command
 
 import (
 	"flag"
+	"fmt"
+	"sort"
 	"strings"
 	"testing"
 
@@ -106,17 +108,36 @@ func TestGenerateCommand(t *testing.T) {
 		{
 			name: "string-slice-flag",
 			cmd:  "cp",
+			flags: []cli.Flag{
+				&cli.StringSliceFlag{
+					Name:  "also-to",
+					Value: cli.NewStringSlice("s3://b/", "s3://a/"),
+				},
+			},
+			urls: []*url.URL{
+				mustNewURL(t, "/source/dir"),
+				mustNewURL(t, "s3://bucket/prefix/"),
+			},
+			expectedCommand: `cp --also-to=s3://a/ --also-to=s3://b/ "/source/dir" "s3://bucket/prefix/"`,
+		},
+		{
+			name: "filters-are-not-forwarded",
+			cmd:  "cp",
 			flags: []cli.Flag{
 				&cli.StringSliceFlag{
 					Name:  "exclude",
-					Value: cli.NewStringSlice("*.txt", "*.log"),
+					Value: cli.NewStringSlice("*.txt"),
+				},
+				&cli.StringSliceFlag{
+					Name:  "include",
+					Value: cli.NewStringSlice("*.log"),
 				},
 			},
 			urls: []*url.URL{
 				mustNewURL(t, "/source/dir"),
 				mustNewURL(t, "s3://bucket/prefix/"),
 			},
-			expectedCommand: `cp --exclude=*.log --exclude=*.txt "/source/dir" "s3://bucket/prefix/"`,
+			expectedCommand: `cp "/source/dir" "s3://bucket/prefix/"`,
 		},
 		{
 			name:  "command-with-multiple-args",
@@ -162,11 +183,7 @@ func TestGenerateCommand(t *testing.T) {
 				}
 			})
 
-			got, err := generateCommand(ctx, command.Name, tc.defaultFlags, tc.urls...)
-			if err != nil {
-				t.Fatal(err)
-			}
-
+			got := generateCommand(ctx, command.Name, tc.defaultFlags, tc.urls...)
 			if diff := cmp.Diff(tc.expectedCommand, got); diff != "" {
 				t.Errorf("(-want +got):\n%v", diff)
 			}
@@ -174,7 +191,7 @@ func TestGenerateCommand(t *testing.T) {
 	}
 }
 
-func mustNewURL(t *testing.T, path string) *url.URL {
+func mustNewURL(t testing.TB, path string) *url.URL {
 	t.Helper()
 
 	u, err := url.New(path)
@@ -195,3 +212,176 @@ func flagSet(t *testing.T, name string, flags []cli.Flag) *flag.FlagSet {
 	}
 	return set
 }
+
+func TestQuoteFlagValue(t *testing.T) {
+	t.Parallel()
+
+	for value, expected := range map[string]string{
+		"STANDARD":                  "STANDARD",
+		"*.txt":                     "*.txt",
+		"gzip -c":                   `"gzip -c"`,
+		"text/plain; charset=utf-8": `"text/plain; charset=utf-8"`,
+		`say "hi"`:                  `"say \"hi\""`,
+	} {
+		if got := quoteFlagValue(value); got != expected {
+			t.Errorf("quoteFlagValue(%q): expected %v, got %v", value, expected, got)
+		}
+	}
+}
+
+// legacyGenerateCommand is the former implementation of generateCommand, which
+// resolved and encoded the flags for each command. The commands must stay the
+// same, since they are piped to the run command as text.
+func legacyGenerateCommand(c *cli.Context, cmd string, defaultFlags map[string]interface{}, urls ...*url.URL) (string, error) {
+	command := AppCommand(cmd)
+	flagset := flag.NewFlagSet(command.Name, flag.ContinueOnError)
+
+	var args []string
+	for _, url := range urls {
+		args = append(args, fmt.Sprintf("%q", url.String()))
+	}
+
+	flags := []string{}
+	for flagname, flagvalue := range defaultFlags {
+		flags = append(flags, fmt.Sprintf("--%s=%v", flagname, flagvalue))
+	}
+
+	for _, f := range command.Flags {
+		flagname := f.Names()[0]
+		if _, ok := defaultFlags[flagname]; ok || !c.IsSet(flagname) {
+			continue
+		}
+		if _, ok := unforwardedFlags[flagname]; ok {
+			continue
+		}
+		for _, flagvalue := range contextValue(c, flagname) {
+			flags = append(flags, fmt.Sprintf("--%s=%s", flagname, quoteFlagValue(flagvalue)))
+		}
+	}
+
+	sort.Strings(flags)
+	flags = append(flags, args...)
+	flags = append([]string{command.Name}, flags...)
+
+	if err := flagset.Parse(flags); err != nil {
+		return "", err
+	}
+
+	cmdCtx := cli.NewContext(c.App, flagset, c)
+	return strings.TrimSpace(commandFromContext(cmdCtx)), nil
+}
+
+// newSyncContext returns the context of a sync command with the given flags.
+func newSyncContext(t testing.TB, flags map[string][]string) *cli.Context {
+	t.Helper()
+	return newCommandContext(t, "sync", flags)
+}
+
+// newCommandContext returns the context of the named command with the given
+// flags.
+func newCommandContext(t testing.TB, name string, flags map[string][]string) *cli.Context {
+	t.Helper()
+
+	set := flag.NewFlagSet(name, flag.ContinueOnError)
+	for _, f := range AppCommand(name).Flags {
+		if err := f.Apply(set); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctx := cli.NewContext(cli.NewApp(), set, nil)
+	for name, values := range flags {
+		for _, value := range values {
+			if err := ctx.Set(name, value); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	return ctx
+}
+
+func TestCommandGeneratorMatchesLegacy(t *testing.T) {
+	t.Parallel()
+
+	defaultFlags := map[string]interface{}{"raw": true}
+
+	versioned := mustNewURL(t, "s3://bucket/prefix/versioned.txt")
+	versioned.VersionID = "v1"
+
+	contexts := map[string]map[string][]string{
+		"no-flags": nil,
+		"flags": {
+			"exclude":       {"*.log", "*.txt"},
+			"storage-class": {"STANDARD_IA"},
+			"content-type":  {"text/plain; charset=utf-8"},
+			"cache-control": {`max-age="60"`},
+			"concurrency":   {"8"},
+			"delete":        {"true"},
+			"size-only":     {"true"},
+		},
+		"overridden-flag": {
+			"raw": {"false"},
+		},
+	}
+
+	urls := [][]*url.URL{
+		{mustNewURL(t, "s3://bucket/prefix/key"), mustNewURL(t, "dir/key")},
+		{mustNewURL(t, "dir/file with space"), mustNewURL(t, `s3://bucket/"quoted"`)},
+		{versioned, mustNewURL(t, "dir/versioned.txt")},
+	}
+
+	for name, flags := range contexts {
+		ctx := newSyncContext(t, flags)
+		for _, cmd := range []string{"cp", "rm"} {
+			generator := newCommandGenerator(ctx, cmd, defaultFlags)
+			for _, urls := range urls {
+				want, err := legacyGenerateCommand(ctx, cmd, versionFlags(defaultFlags, urls[0]), urls...)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got := generator.generate(versionFlags(nil, urls[0]), urls...)
+				if diff := cmp.Diff(want, got); diff != "" {
+					t.Errorf("%v: %v: (-want +got):\n%v", name, cmd, diff)
+				}
+			}
+		}
+	}
+}
+
+// BenchmarkPlanCopyCommands generates the copy commands of b.N objects of a
+// sync, e.g. 1M objects with "-benchtime 1000000x", with the former and the
+// current implementation.
+func BenchmarkPlanCopyCommands(b *testing.B) {
+	ctx := newSyncContext(b, map[string][]string{
+		"exclude":       {"*.log", "*.tmp"},
+		"storage-class": {"STANDARD_IA"},
+		"concurrency":   {"8"},
+		"delete":        {"true"},
+	})
+	defaultFlags := map[string]interface{}{"raw": true}
+
+	srcurls := make([]*url.URL, 1000)
+	dsturls := make([]*url.URL, len(srcurls))
+	for i := range srcurls {
+		srcurls[i] = mustNewURL(b, fmt.Sprintf("s3://bucket/prefix/%08d.txt", i))
+		dsturls[i] = mustNewURL(b, fmt.Sprintf("dir/%08d.txt", i))
+	}
+
+	b.Run("legacy", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			src, dst := srcurls[i%len(srcurls)], dsturls[i%len(dsturls)]
+			if _, err := legacyGenerateCommand(ctx, "cp", versionFlags(defaultFlags, src), src, dst); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("generator", func(b *testing.B) {
+		b.ReportAllocs()
+		generator := newCommandGenerator(ctx, "cp", defaultFlags)
+		for i := 0; i < b.N; i++ {
+			src, dst := srcurls[i%len(srcurls)], dsturls[i%len(dsturls)]
+			generator.generate(versionFlags(nil, src), src, dst)
+		}
+	})
+}
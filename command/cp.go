@@ -11,6 +11,7 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/hashicorp/go-multierror"
 	"github.com/urfave/cli/v2"
@@ -22,12 +23,15 @@ import (
 	"github.com/peak/s5cmd/v2/progressbar"
 	"github.com/peak/s5cmd/v2/storage"
 	"github.com/peak/s5cmd/v2/storage/url"
+	"github.com/peak/s5cmd/v2/strutil"
 )
 
 const (
 	defaultCopyConcurrency = 5
-	defaultPartSize        = 50 // MiB
-	megabytes              = 1024 * 1024
+
+	multipartCopyConcurrencyFlagName = "multipart-copy-concurrency"
+	defaultPartSize                  = 50 // MiB
+	megabytes                        = 1024 * 1024
 )
 
 var copyHelpTemplate = `Name:
@@ -105,6 +109,84 @@ Examples:
 		 
 	22. Download the specific version of a remote object to working directory
 		 > s5cmd {{.HelpName}} --version-id VERSION_ID s3://bucket/prefix/object .
+
+	23. Download all S3 objects giving each object 2 seconds per MiB, at least 30 seconds and at most 2 hours
+		 > s5cmd {{.HelpName}} --timeout-per-mb 2s --timeout-min 30s --timeout-max 2h "s3://bucket/*" target-directory/
+
+	24. Download large S3 objects reserving their disk space up front
+		 > s5cmd {{.HelpName}} --preallocate "s3://bucket/prefix/*" target-directory/
+
+	25. Download S3 objects asking before overwriting each existing local file
+		 > s5cmd {{.HelpName}} -i "s3://bucket/prefix/*" target-directory/
+
+	26. Upload a growing log file, sending only the bytes appended since the last upload
+		 > s5cmd {{.HelpName}} --append-detect app.log s3://bucket/logs/app.log
+
+	27. Copy objects to another S3 bucket, failing early if any of the buckets does not exist or is not accessible
+		 > s5cmd {{.HelpName}} --verify-bucket "s3://bucket/prefix/*" s3://target-bucket/prefix/
+
+	28. Download objects, hard linking the objects with identical content instead of storing multiple copies
+		 > s5cmd {{.HelpName}} --hardlink-identical "s3://bucket/prefix/*" target-directory/
+
+	29. Download objects, using at most 400GB of the disk and pausing until space is freed when the limit is reached
+		 > s5cmd {{.HelpName}} --max-disk-usage 400GB "s3://bucket/prefix/*" target-directory/
+
+	30. Download objects, replacing the local files and directories which have the paths of directories and files to be downloaded
+		 > s5cmd {{.HelpName}} --on-type-conflict replace --force "s3://bucket/prefix/*" target-directory/
+
+	31. Upload a directory, uploading the symbolic links whose targets don't exist as objects holding their targets
+		 > s5cmd {{.HelpName}} --on-broken-symlink upload-as-link dir/ s3://bucket/prefix/
+
+	32. Upload a file written by another process through a named pipe, verifying that the uploaded object is 1GiB
+		 > s5cmd {{.HelpName}} --expected-size 1GiB --delete-on-size-mismatch /tmp/export.fifo s3://bucket/prefix/export.csv
+
+	33. Upload all files of nested directories into a single prefix, renaming the later files with the same name, e.g. report.csv, to report-1.csv, report-2.csv and so on
+		 > s5cmd {{.HelpName}} --flatten --on-conflict rename "dir/*" s3://bucket/prefix/
+
+	34. Upload all files of nested directories into a single prefix, appending the time of the upload to the names of the conflicting files
+		 > s5cmd {{.HelpName}} --flatten --on-conflict rename --conflict-suffix-format "-{{"{{"}}.Timestamp{{"}}"}}-{{"{{"}}.N{{"}}"}}" "dir/*" s3://bucket/prefix/
+
+	35. Export the latest versions of the objects in a versioned bucket at listing time, even if they change during the copy
+		 > s5cmd --json --stats-csv export.csv {{.HelpName}} --pin-versions "s3://bucket/prefix/*" s3://target-bucket/export/
+
+	36. Upload files to buckets in two regions, reading each file only once and uploading it to either both of the buckets or none of them
+		 > s5cmd {{.HelpName}} --also-to s3://dr-bucket/prefix/ --fanout-require-all "dir/*" s3://bucket/prefix/
+
+	37. Copy objects larger than 5GiB on the server side, copying 20 parts of 512MiB of each object at the same time
+		 > s5cmd {{.HelpName}} --multipart-copy-concurrency 20 --part-size 512 "s3://bucket/backups/*" s3://target-bucket/backups/
+
+	38. Upload a redirect stub of a static website, redirecting the requests for the old page to the new one
+		 > s5cmd {{.HelpName}} --website-redirect /docs/new.html old.html s3://bucket/docs/old.html
+
+	39. Upload log files under prefixes of the days they were last modified on, e.g. s3://bucket/logs/2024/01/02/app.log
+		 > s5cmd {{.HelpName}} --dest-date-prefix 2006/01/02 "logs/*.log" s3://bucket/logs/
+
+	40. Upload text files compressed with gzip, running at most 8 compressions at the same time
+		 > s5cmd {{.HelpName}} --transform-exec "gzip -c" --transform-concurrency 8 --content-encoding gzip "dir/*.txt" s3://bucket/prefix/
+
+	41. Upload files on Windows keeping their read-only, hidden, system and archive attributes, to restore them on download
+		 > s5cmd {{.HelpName}} --preserve-windows-attrs "Documents/*" s3://bucket/documents/
+
+	42. Upload files up to 100MB with a single request and the larger files in parts of 64MiB
+		 > s5cmd {{.HelpName}} --part-size 64 --multipart-threshold 100MB "dir/*" s3://bucket/prefix/
+
+	43. Download all objects under a prefix as "aws s3 cp --recursive" does, only the text files
+		 > s5cmd {{.HelpName}} --recursive --awscli-compat --exclude "*" --include "*.txt" s3://bucket/prefix dir/
+
+	44. Upload files with the default encryption of the destination bucket, for a bucket policy requiring the encryption headers
+		 > s5cmd {{.HelpName}} --auto-sse "dir/*" s3://bucket/prefix/
+
+	45. Upload a file to S3 with user-defined metadata
+		 > s5cmd {{.HelpName}} --metadata owner=alice --metadata team=data users.csv s3://bucket/
+
+	46. Upload files and print the https URLs of the uploaded objects, collecting them in a file as well
+		 > s5cmd {{.HelpName}} --print-urls https --print-urls-file urls.txt "reports/*" s3://bucket/reports/
+
+	47. Upload files keeping their modification times in the object metadata, to set them on download with --preserve-mtime
+		 > s5cmd {{.HelpName}} --preserve-mtime "dir/*" s3://bucket/prefix/
+
+	48. Upload files of a directory which is written to at the same time, skipping the files removed after they are listed
+		 > s5cmd {{.HelpName}} --skip-vanished "logs/*" s3://bucket/logs/
 `
 
 func NewSharedFlags() []cli.Flag {
@@ -129,6 +211,12 @@ func NewSharedFlags() []cli.Flag {
 			Value:   defaultPartSize,
 			Usage:   "size of each part transferred between host and remote server, in MiB",
 		},
+		&cli.IntFlag{
+			Name:  multipartCopyConcurrencyFlagName,
+			Value: defaultCopyConcurrency,
+			Usage: "number of concurrent parts copied on the server side for each object larger than 5GiB",
+		},
+		newMultipartThresholdFlag(),
 		&cli.StringFlag{
 			Name:  "sse",
 			Usage: "perform server side encryption of the data at its destination, e.g. aws:kms",
@@ -137,6 +225,7 @@ func NewSharedFlags() []cli.Flag {
 			Name:  "sse-kms-key-id",
 			Usage: "customer master key (CMK) id for SSE-KMS encryption; leave it out if server-side generated key is desired",
 		},
+		newAutoSSEFlag(),
 		&cli.StringFlag{
 			Name:  "acl",
 			Usage: "set acl for target: defines granted accesses and their types on different accounts/groups, e.g. cp --acl 'public-read'",
@@ -185,6 +274,85 @@ func NewSharedFlags() []cli.Flag {
 			Name:  "content-disposition",
 			Usage: "set content disposition for target: defines content disposition header for object, e.g. --content-disposition 'attachment; filename=\"filename.jpg\"'",
 		},
+		&cli.StringFlag{
+			Name:  "content-language",
+			Usage: "set content language for target: defines content language header for object, e.g. --content-language en-US",
+		},
+		&cli.StringFlag{
+			Name:  "website-redirect",
+			Usage: "set website redirect location for target: redirects the requests for the object to the given key or URL if the bucket is configured as a website, e.g. --website-redirect /new/page.html",
+		},
+		&cli.GenericFlag{
+			Name:  "system-header",
+			Value: &HeaderValue{Allowed: storage.SystemHeaders()},
+			Usage: "set a system-defined metadata header for target, overriding its dedicated flag, can be specified multiple times, e.g. --system-header 'Content-Language=de'",
+		},
+		&cli.GenericFlag{
+			Name:  "metadata",
+			Value: &HeaderValue{},
+			Usage: "set a user-defined metadata for target, stored in x-amz-meta-<name> header, can be specified multiple times, e.g. --metadata 'camera=Nikon D750'",
+		},
+		&cli.DurationFlag{
+			Name:  "timeout-per-mb",
+			Usage: "time allowed per MiB of an object for a single object operation, e.g. 2s; 0 disables per-object timeouts",
+		},
+		&cli.DurationFlag{
+			Name:  "timeout-min",
+			Usage: "minimum time allowed for a single object operation when per-object timeouts are enabled, e.g. 30s",
+		},
+		&cli.DurationFlag{
+			Name:  "timeout-max",
+			Usage: "maximum time allowed for a single object operation when per-object timeouts are enabled, e.g. 2h",
+		},
+		&cli.BoolFlag{
+			Name:  "preallocate",
+			Usage: "reserve the full size of an object on local disk before downloading it, where supported by the filesystem",
+		},
+		&cli.BoolFlag{
+			Name:  "append-detect",
+			Usage: "upload only the new tail of a local file if the existing destination object is the unchanged beginning of it, e.g. append-only log files",
+		},
+		&cli.BoolFlag{
+			Name:  "hardlink-identical",
+			Usage: "hard link downloaded objects with the same ETag and size to the first downloaded file instead of storing multiple copies",
+		},
+		newPreserveWindowsAttrsFlag(),
+		&cli.BoolFlag{
+			Name:  "verify-bucket",
+			Usage: "check that the source and destination buckets exist and are accessible before listing begins",
+		},
+		&cli.BoolFlag{
+			Name:  "space-check",
+			Usage: "check that the destination filesystem has enough free space for the downloads before starting them, which holds them until the source is listed",
+		},
+		&cli.StringFlag{
+			Name:  "max-disk-usage",
+			Usage: "pause downloads while they would use more than the given disk space in total on the destination filesystem, e.g. 400GB",
+		},
+		&cli.GenericFlag{
+			Name: "on-type-conflict",
+			Value: &EnumValue{
+				Enum:    []string{typeConflictFail, typeConflictSkip, typeConflictReplace},
+				Default: typeConflictSkip,
+			},
+			Usage: "action when a download destination exists as a directory or one of its parents exists as a file: (fail, skip, replace)",
+		},
+		&cli.BoolFlag{
+			Name:  "force",
+			Usage: "remove non-empty directories recursively with --on-type-conflict replace",
+		},
+		&cli.GenericFlag{
+			Name: "on-broken-symlink",
+			Value: &EnumValue{
+				Enum:    []string{storage.BrokenSymlinkSkip, storage.BrokenSymlinkError, storage.BrokenSymlinkUploadAsLink},
+				Default: storage.BrokenSymlinkSkip,
+			},
+			Usage: "action when an upload source is a symbolic link whose target doesn't exist: (skip, error, upload-as-link)",
+		},
+		&cli.BoolFlag{
+			Name:  "pin-versions",
+			Usage: "copy the version of each source object which is the latest at listing time, even if the object changes during the run",
+		},
 		&cli.IntFlag{
 			Name:        "no-such-upload-retry-count",
 			Usage:       "number of times that a request will be retried on NoSuchUpload error; you should not use this unless you really know what you're doing",
@@ -201,11 +369,29 @@ func NewCopyCommandFlags() []cli.Flag {
 			Aliases: []string{"f"},
 			Usage:   "flatten directory structure of source, starting from the first wildcard",
 		},
+		&cli.GenericFlag{
+			Name: "on-conflict",
+			Value: &EnumValue{
+				Enum:    []string{conflictOverwrite, conflictRename},
+				Default: conflictOverwrite,
+			},
+			Usage: "action when more than one object is copied to the same remote destination key by the run: (overwrite, rename)",
+		},
+		&cli.StringFlag{
+			Name:  "conflict-suffix-format",
+			Value: defaultConflictSuffixFormat,
+			Usage: "template of the suffix appended to the names of the conflicting keys with --on-conflict rename, with {{.N}} and {{.Timestamp}} fields",
+		},
 		&cli.BoolFlag{
 			Name:    "no-clobber",
 			Aliases: []string{"n"},
 			Usage:   "do not overwrite destination if already exists",
 		},
+		&cli.BoolFlag{
+			Name:    "interactive",
+			Aliases: []string{"i"},
+			Usage:   "prompt before overwriting an existing destination; ignored if standard input is not a terminal",
+		},
 		&cli.BoolFlag{
 			Name:    "if-size-differ",
 			Aliases: []string{"s"},
@@ -225,7 +411,30 @@ func NewCopyCommandFlags() []cli.Flag {
 			Aliases: []string{"sp"},
 			Usage:   "show a progress bar",
 		},
-	}
+		&cli.StringFlag{
+			Name:  "expected-size",
+			Usage: "verify that the size of the uploaded object is the given size, e.g. 1048576 or 1MiB",
+		},
+		&cli.BoolFlag{
+			Name:  "delete-on-size-mismatch",
+			Usage: "delete the uploaded object if its size doesn't match --expected-size",
+		},
+		&cli.StringSliceFlag{
+			Name:  alsoToFlagName,
+			Usage: "upload the files to the given bucket or prefix as well, reading them only once, can be specified multiple times",
+		},
+		&cli.BoolFlag{
+			Name:  fanoutRequireAllFlagName,
+			Usage: "upload the files to either all of the destinations or none of them with --also-to, deleting the uploaded objects if an upload fails",
+		},
+		newDestDatePrefixFlag(),
+		newPreserveMtimeFlag(),
+		newSkipVanishedFlag(),
+	}
+	copyFlags = append(copyFlags, newTransformFlags()...)
+	copyFlags = append(copyFlags, newPrintURLsFlags()...)
+	copyFlags = append(copyFlags, newByteRangeFlags()...)
+	copyFlags = append(copyFlags, newAWSCLIFlags("copy")...)
 	sharedFlags := NewSharedFlags()
 	return append(copyFlags, sharedFlags...)
 }
@@ -247,6 +456,8 @@ func NewCopyCommand() *cli.Command {
 		Action: func(c *cli.Context) (err error) {
 			defer stat.Collect(c.Command.FullName(), &err)()
 
+			printAWSCLINotices(c, c.Args().Get(0))
+
 			// don't delete source
 			copy, err := NewCopy(c, false)
 			if err != nil {
@@ -266,6 +477,9 @@ type Copy struct {
 	dst         *url.URL
 	op          string
 	fullCommand string
+	// position is the position of the command in the input of run or sync,
+	// if any.
+	position string
 
 	deleteSource bool
 
@@ -278,39 +492,87 @@ type Copy struct {
 	storageClass          storage.StorageClass
 	encryptionMethod      string
 	encryptionKeyID       string
+	autoSSE               bool
 	acl                   string
 	forceGlacierTransfer  bool
 	ignoreGlacierWarnings bool
 	exclude               []string
+	include               []string
+	filters               []awscliFilter
 	cacheControl          string
 	expires               string
 	contentType           string
 	contentEncoding       string
 	contentDisposition    string
+	contentLanguage       string
+	websiteRedirect       string
+	destDatePrefix        string
+	systemHeaders         map[string]string
+	userMetadata          map[string]string
 	showProgress          bool
 	progressbar           progressbar.ProgressBar
+	timeoutPerMB          time.Duration
+	timeoutMin            time.Duration
+	timeoutMax            time.Duration
+	preallocate           bool
+	appendDetect          bool
+	verifyBucket          bool
+	hardlinkIdentical     bool
+	preserveWindowsAttrs  bool
+	preserveMtime         bool
+	skipVanished          bool
+	spaceCheck            bool
+	maxDiskUsage          int64
+	expectedSize          int64
+	deleteOnSizeMismatch  bool
+	onTypeConflict        string
+	force                 bool
+	onBrokenSymlink       string
+	pinVersions           bool
+	alsoTo                []*url.URL
+	fanoutRequireAll      bool
+	prompter              *overwritePrompter
+	// byteRange is the range of the object downloaded with --offset and
+	// --length flags, if set.
+	byteRange *byteRange
+	// conflictSuffix is set if the conflicting destination keys are renamed.
+	conflictSuffix *conflictSuffix
+	// transform pipes the content of the objects through the command given
+	// with --transform-exec, if set.
+	transform *transformExec
+	// urls builds the URLs of the uploaded objects printed with --print-urls,
+	// if set.
+	urls *urlPrinter
 
 	// region settings
 	srcRegion string
 	dstRegion string
 
 	// s3 options
-	concurrency int
-	partSize    int64
-	storageOpts storage.Options
+	concurrency              int
+	partSize                 int64
+	multipartCopyConcurrency int
+	multipartThreshold       int64
+	storageOpts              storage.Options
 }
 
 // NewCopy creates Copy from cli.Context.
 func NewCopy(c *cli.Context, deleteSource bool) (*Copy, error) {
 	fullCommand := commandFromContext(c)
 
-	src, err := url.New(c.Args().Get(0), url.WithVersion(c.String("version-id")),
+	src, err := url.New(recursiveSources(c, c.Args().Get(0))[0], url.WithVersion(c.String("version-id")),
 		url.WithRaw(c.Bool("raw")))
 	if err != nil {
 		printError(fullCommand, c.Command.Name, err)
 		return nil, err
 	}
 
+	exclude, include, filters, err := parseFilters(c)
+	if err != nil {
+		printError(fullCommand, c.Command.Name, err)
+		return nil, err
+	}
+
 	dst, err := url.New(c.Args().Get(1), url.WithRaw(c.Bool("raw")))
 	if err != nil {
 		printError(fullCommand, c.Command.Name, err)
@@ -325,34 +587,115 @@ func NewCopy(c *cli.Context, deleteSource bool) (*Copy, error) {
 		commandProgressBar = &progressbar.NoOp{}
 	}
 
+	var maxDiskUsage int64
+	if c.IsSet("max-disk-usage") {
+		maxDiskUsage, err = strutil.ParseBytes(c.String("max-disk-usage"))
+		if err != nil {
+			printError(fullCommand, c.Command.Name, err)
+			return nil, err
+		}
+	}
+
+	expectedSize, err := parseExpectedSize(c)
+	if err != nil {
+		printError(fullCommand, c.Command.Name, err)
+		return nil, err
+	}
+
+	alsoTo, err := parseAlsoTo(c)
+	if err != nil {
+		printError(fullCommand, c.Command.Name, err)
+		return nil, err
+	}
+
+	var suffix *conflictSuffix
+	if c.String("on-conflict") == conflictRename {
+		suffix, err = newConflictSuffix(c.String("conflict-suffix-format"), time.Now())
+		if err != nil {
+			printError(fullCommand, c.Command.Name, err)
+			return nil, err
+		}
+	}
+
+	urls, err := newURLPrinter(c)
+	if err != nil {
+		printError(fullCommand, c.Command.Name, err)
+		return nil, err
+	}
+
+	var prompter *overwritePrompter
+	if c.Bool("interactive") {
+		if isTerminal(os.Stdin) {
+			prompter = newOverwritePrompter(os.Stdin, os.Stderr)
+		} else {
+			fmt.Fprintf(os.Stderr, "WARNING %q: standard input is not a terminal, ignoring --interactive flag\n", fullCommand)
+		}
+	}
+
 	return &Copy{
 		src:          src,
 		dst:          dst,
 		op:           c.Command.Name,
 		fullCommand:  fullCommand,
+		position:     commandPosition(c),
 		deleteSource: deleteSource,
 		// flags
-		noClobber:             c.Bool("no-clobber"),
-		ifSizeDiffer:          c.Bool("if-size-differ"),
-		ifSourceNewer:         c.Bool("if-source-newer"),
-		flatten:               c.Bool("flatten"),
-		followSymlinks:        !c.Bool("no-follow-symlinks"),
-		storageClass:          storage.StorageClass(c.String("storage-class")),
-		concurrency:           c.Int("concurrency"),
-		partSize:              c.Int64("part-size") * megabytes,
-		encryptionMethod:      c.String("sse"),
-		encryptionKeyID:       c.String("sse-kms-key-id"),
-		acl:                   c.String("acl"),
-		forceGlacierTransfer:  c.Bool("force-glacier-transfer"),
-		ignoreGlacierWarnings: c.Bool("ignore-glacier-warnings"),
-		exclude:               c.StringSlice("exclude"),
-		cacheControl:          c.String("cache-control"),
-		expires:               c.String("expires"),
-		contentType:           c.String("content-type"),
-		contentEncoding:       c.String("content-encoding"),
-		contentDisposition:    c.String("content-disposition"),
-		showProgress:          c.Bool("show-progress"),
-		progressbar:           commandProgressBar,
+		noClobber:                c.Bool("no-clobber"),
+		ifSizeDiffer:             c.Bool("if-size-differ"),
+		ifSourceNewer:            c.Bool("if-source-newer"),
+		flatten:                  c.Bool("flatten"),
+		followSymlinks:           !c.Bool("no-follow-symlinks"),
+		storageClass:             storage.StorageClass(c.String("storage-class")),
+		concurrency:              c.Int("concurrency"),
+		partSize:                 c.Int64("part-size") * megabytes,
+		multipartCopyConcurrency: c.Int(multipartCopyConcurrencyFlagName),
+		multipartThreshold:       parseMultipartThreshold(c),
+		encryptionMethod:         c.String("sse"),
+		encryptionKeyID:          c.String("sse-kms-key-id"),
+		autoSSE:                  c.Bool(autoSSEFlagName),
+		acl:                      c.String("acl"),
+		forceGlacierTransfer:     c.Bool("force-glacier-transfer"),
+		ignoreGlacierWarnings:    c.Bool("ignore-glacier-warnings"),
+		exclude:                  exclude,
+		include:                  include,
+		filters:                  filters,
+		cacheControl:             c.String("cache-control"),
+		expires:                  c.String("expires"),
+		contentType:              c.String("content-type"),
+		contentEncoding:          c.String("content-encoding"),
+		contentDisposition:       c.String("content-disposition"),
+		contentLanguage:          c.String("content-language"),
+		websiteRedirect:          c.String("website-redirect"),
+		destDatePrefix:           c.String(destDatePrefixFlagName),
+		transform:                newTransformExec(c),
+		systemHeaders:            systemHeaders(c),
+		userMetadata:             userMetadata(c),
+		showProgress:             c.Bool("show-progress"),
+		progressbar:              commandProgressBar,
+		timeoutPerMB:             c.Duration("timeout-per-mb"),
+		timeoutMin:               c.Duration("timeout-min"),
+		timeoutMax:               c.Duration("timeout-max"),
+		preallocate:              c.Bool("preallocate"),
+		appendDetect:             c.Bool("append-detect"),
+		verifyBucket:             c.Bool("verify-bucket"),
+		hardlinkIdentical:        c.Bool("hardlink-identical"),
+		preserveWindowsAttrs:     c.Bool(preserveWindowsAttrsFlagName),
+		preserveMtime:            c.Bool(preserveMtimeFlagName),
+		skipVanished:             c.Bool(skipVanishedFlagName),
+		spaceCheck:               c.Bool("space-check"),
+		maxDiskUsage:             maxDiskUsage,
+		expectedSize:             expectedSize,
+		deleteOnSizeMismatch:     c.Bool("delete-on-size-mismatch"),
+		onTypeConflict:           c.String("on-type-conflict"),
+		force:                    c.Bool("force"),
+		onBrokenSymlink:          c.String("on-broken-symlink"),
+		pinVersions:              c.Bool("pin-versions"),
+		alsoTo:                   alsoTo,
+		fanoutRequireAll:         c.Bool(fanoutRequireAllFlagName),
+		prompter:                 prompter,
+		conflictSuffix:           suffix,
+		urls:                     urls,
+		byteRange:                parseByteRange(c),
 
 		// region settings
 		srcRegion: c.String("source-region"),
@@ -370,6 +713,27 @@ increase the open file limit or try to decrease the number of workers with
 
 // Run starts copying given source objects to destination.
 func (c Copy) Run(ctx context.Context) error {
+	defer func() {
+		if err := c.urls.Close(); err != nil {
+			printError(c.fullCommand, c.op, err)
+		}
+	}()
+
+	if c.verifyBucket {
+		err := verifyBuckets(ctx, c.storageOpts, c.src, c.srcRegion, c.dst, c.dstRegion)
+		if err != nil {
+			printError(c.fullCommand, c.op, err)
+			return err
+		}
+		for _, dsturl := range c.alsoTo {
+			if err := verifiedBuckets.verify(ctx, dsturl, c.storageOpts); err != nil {
+				err = fmt.Errorf("destination %w", err)
+				printError(c.fullCommand, c.op, err)
+				return err
+			}
+		}
+	}
+
 	// override source region if set
 	if c.srcRegion != "" {
 		c.storageOpts.SetRegion(c.srcRegion)
@@ -381,7 +745,26 @@ func (c Copy) Run(ctx context.Context) error {
 		return err
 	}
 
-	objch, err := expandSource(ctx, client, c.followSymlinks, c.src)
+	// answering "quit" to an overwrite prompt stops the whole operation.
+	if c.prompter != nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(ctx)
+		defer cancel()
+		c.prompter.cancel = cancel
+	}
+
+	// the versions of the source objects are fixed at listing time, so that
+	// the objects which change during the run are copied as listed.
+	src := c.src
+	if c.pinVersions {
+		src = pinnedSourceURL(c.src)
+	}
+
+	objch, err := expandSource(ctx, client, c.followSymlinks, src)
+	if c.skipVanished && isVanishedFile(src, err) {
+		c.vanishFile(ctx, src)
+		return nil
+	}
 	if err != nil {
 		printError(c.fullCommand, c.op, err)
 		return err
@@ -392,9 +775,8 @@ func (c Copy) Run(ctx context.Context) error {
 	waiter := parallel.NewWaiter()
 
 	var (
-		merrorWaiter  error
-		merrorObjects error
-		errDoneCh     = make(chan bool)
+		errs      = newErrorSummary()
+		errDoneCh = make(chan bool)
 	)
 
 	go func() {
@@ -407,13 +789,17 @@ func (c Copy) Run(ctx context.Context) error {
 				os.Exit(1)
 			}
 			printError(c.fullCommand, c.op, err)
-			merrorWaiter = multierror.Append(merrorWaiter, err)
+			errs.add(err, "")
 		}
 	}()
 
 	isBatch := c.src.IsWildcard()
 	if !isBatch && !c.src.IsRemote() {
 		obj, err := client.Stat(ctx, c.src)
+		if c.skipVanished && isVanishedFile(c.src, err) {
+			c.vanishFile(ctx, c.src)
+			return nil
+		}
 		if err != nil {
 			printError(c.fullCommand, c.op, err)
 			return err
@@ -428,33 +814,76 @@ func (c Copy) Run(ctx context.Context) error {
 		return err
 	}
 
+	includePatterns, err := createExcludesFromWildcard(c.include)
+	if err != nil {
+		printError(c.fullCommand, c.op, err)
+		return err
+	}
+
+	// with --space-check, downloads are started once all of them are
+	// planned, so that their total size is checked against the free space of
+	// the destination.
+	var (
+		checkSpace   = c.src.IsRemote() && !c.dst.IsRemote() && c.spaceCheck && !c.storageOpts.DryRun
+		pending      []parallel.Task
+		plannedBytes int64
+	)
+
 	for object := range objch {
 		if object.Type.IsDir() || errorpkg.IsCancelation(object.Err) {
 			continue
 		}
 
 		if err := object.Err; err != nil {
-			merrorObjects = multierror.Append(merrorObjects, err)
+			errs.add(err, "")
 			printError(c.fullCommand, c.op, err)
 			continue
 		}
 
+		if src.AllVersions && !isPinnedVersion(object) {
+			continue
+		}
+
 		if object.StorageClass.IsGlacier() && !c.forceGlacierTransfer {
+			accessLog.record(c.op, object.URL, object.Size, accessSkipped, "object is on Glacier storage")
 			if !c.ignoreGlacierWarnings {
 				err := fmt.Errorf("object '%v' is on Glacier storage", object)
-				merrorObjects = multierror.Append(merrorObjects, err)
+				errs.add(err, "")
 				printError(c.fullCommand, c.op, err)
 			}
 			continue
 		}
 
-		if isURLExcluded(excludePatterns, object.URL.Path, c.src.Prefix) {
+		if isURLExcluded(excludePatterns, object.URL.Path, c.src.Prefix) ||
+			isURLExcludedByFilters(c.filters, object.URL.Path, c.src.Prefix) {
+			accessLog.record(c.op, object.URL, object.Size, accessExcluded, "matches an exclude pattern")
+			continue
+		}
+
+		if !isURLIncluded(includePatterns, object.URL.Path, c.src.Prefix) {
+			accessLog.record(c.op, object.URL, object.Size, accessExcluded, "matches no include pattern")
 			continue
 		}
 
 		srcurl := object.URL
 		var task parallel.Task
 
+		dsturl := c.dst
+		if c.destDatePrefix != "" {
+			dsturl = datePrefixedURL(c.dst, datePrefix(c.op, object, c.destDatePrefix))
+		}
+		if c.conflictSuffix != nil && c.dst.IsRemote() {
+			dsturl, err = destinationKeys.reserve(
+				prepareRemoteDestination(srcurl, dsturl, c.flatten, isBatch),
+				c.conflictSuffix,
+			)
+			if err != nil {
+				errs.add(err, "")
+				printError(c.fullCommand, c.op, err)
+				continue
+			}
+		}
+
 		if object.Size == 0 && !(srcurl.Type == c.dst.Type) {
 			obj, err := client.Stat(ctx, srcurl)
 			if err == nil {
@@ -466,20 +895,38 @@ func (c Copy) Run(ctx context.Context) error {
 
 		switch {
 		case srcurl.Type == c.dst.Type: // local->local or remote->remote
-			task = c.prepareCopyTask(ctx, srcurl, c.dst, isBatch)
+			task = c.prepareCopyTask(ctx, srcurl, dsturl, isBatch, object.Size)
 		case srcurl.IsRemote(): // remote->local
-			task = c.prepareDownloadTask(ctx, srcurl, c.dst, isBatch)
+			task = c.prepareDownloadTask(ctx, srcurl, dsturl, isBatch, object.Size, object.Etag)
 		case c.dst.IsRemote(): // local->remote
-			task = c.prepareUploadTask(ctx, srcurl, c.dst, isBatch)
+			task = c.prepareUploadTask(ctx, srcurl, dsturl, isBatch, object.Size)
 		default:
 			panic("unexpected src-dst pair")
 		}
+
+		if checkSpace {
+			pending = append(pending, task)
+			plannedBytes += object.Size
+			continue
+		}
 		parallel.Run(task, waiter)
 	}
+
+	if len(pending) > 0 {
+		err := checkDiskSpace(storage.NewLocalClient(c.storageOpts), c.dst.Absolute(), plannedBytes)
+		if err != nil {
+			errs.add(err, "")
+			printError(c.fullCommand, c.op, err)
+			pending = nil
+		}
+		for _, task := range pending {
+			parallel.Run(task, waiter)
+		}
+	}
 	waiter.Wait()
 	<-errDoneCh
 
-	return multierror.Append(merrorWaiter, merrorObjects).ErrorOrNil()
+	return errs.err()
 }
 
 func (c Copy) prepareCopyTask(
@@ -487,16 +934,29 @@ func (c Copy) prepareCopyTask(
 	srcurl *url.URL,
 	dsturl *url.URL,
 	isBatch bool,
+	size int64,
 ) func() error {
-	return func() error {
+	return func() (err error) {
+		ctx, done := startTransfer(ctx, c.op, srcurl, size)
+		defer func() { done(err) }()
+
 		dsturl = prepareRemoteDestination(srcurl, dsturl, c.flatten, isBatch)
-		err := c.doCopy(ctx, srcurl, dsturl)
+		transferDestination(ctx, dsturl)
+		err = c.runWithObjectTimeout(ctx, size, func(ctx context.Context, timeout time.Duration) error {
+			return objectTimeoutError(ctx, c.doCopy(ctx, srcurl, dsturl, size), timeout)
+		})
+		if c.pinVersions && isVanished(srcurl, err) {
+			vanishTransfer(ctx, srcurl)
+			log.Info(VanishedMessage{Source: srcurl, Destination: dsturl})
+			return nil
+		}
 		if err != nil {
 			return &errorpkg.Error{
-				Op:  c.op,
-				Src: srcurl,
-				Dst: dsturl,
-				Err: err,
+				Op:       c.op,
+				Src:      srcurl,
+				Dst:      dsturl,
+				Err:      err,
+				Position: c.position,
 			}
 		}
 		c.progressbar.IncrementCompletedObjects()
@@ -509,19 +969,51 @@ func (c Copy) prepareDownloadTask(
 	srcurl *url.URL,
 	dsturl *url.URL,
 	isBatch bool,
+	size int64,
+	etag string,
 ) func() error {
-	return func() error {
-		dsturl, err := prepareLocalDestination(ctx, srcurl, dsturl, c.flatten, isBatch, c.storageOpts)
+	return func() (err error) {
+		ctx, done := startTransfer(ctx, c.op, srcurl, size)
+		defer func() { done(err) }()
+
+		dsturl, err := c.prepareLocalDestination(ctx, srcurl, dsturl, isBatch)
+		if errorpkg.IsTypeConflict(err) {
+			if c.onTypeConflict == typeConflictSkip {
+				skipTransfer(ctx, err)
+				log.Info(TypeConflictMessage{
+					Source:      srcurl,
+					Destination: dsturl,
+					Err:         err,
+				})
+				return nil
+			}
+			return &errorpkg.Error{
+				Op:       c.op,
+				Src:      srcurl,
+				Dst:      dsturl,
+				Err:      err,
+				Position: c.position,
+			}
+		}
 		if err != nil {
 			return err
 		}
-		err = c.doDownload(ctx, srcurl, dsturl)
+		transferDestination(ctx, dsturl)
+		err = c.runWithObjectTimeout(ctx, size, func(ctx context.Context, timeout time.Duration) error {
+			return objectTimeoutError(ctx, c.doDownload(ctx, srcurl, dsturl, size, etag), timeout)
+		})
+		if c.pinVersions && isVanished(srcurl, err) {
+			vanishTransfer(ctx, srcurl)
+			log.Info(VanishedMessage{Source: srcurl, Destination: dsturl})
+			return nil
+		}
 		if err != nil {
 			return &errorpkg.Error{
-				Op:  c.op,
-				Src: srcurl,
-				Dst: dsturl,
-				Err: err,
+				Op:       c.op,
+				Src:      srcurl,
+				Dst:      dsturl,
+				Err:      err,
+				Position: c.position,
 			}
 		}
 		c.progressbar.IncrementCompletedObjects()
@@ -534,16 +1026,58 @@ func (c Copy) prepareUploadTask(
 	srcurl *url.URL,
 	dsturl *url.URL,
 	isBatch bool,
+	size int64,
 ) func() error {
-	return func() error {
+	return func() (err error) {
+		ctx, done := startTransfer(ctx, c.op, srcurl, size)
+		defer func() { done(err) }()
+
+		if len(c.alsoTo) > 0 {
+			dsturls := c.fanoutDestinations(srcurl, dsturl, isBatch)
+			transferDestination(ctx, dsturls...)
+			err = c.runWithObjectTimeout(ctx, size, func(ctx context.Context, timeout time.Duration) error {
+				wrapErr := func(err error) error {
+					return objectTimeoutError(ctx, err, timeout)
+				}
+				err := c.doFanoutUpload(ctx, srcurl, dsturls, wrapErr)
+				if _, ok := err.(*multierror.Error); ok {
+					return err
+				}
+				return wrapErr(err)
+			})
+			if err != nil {
+				if _, ok := err.(*multierror.Error); !ok {
+					err = &errorpkg.Error{
+						Op:       c.op,
+						Src:      srcurl,
+						Dst:      dsturls[0],
+						Err:      err,
+						Position: c.position,
+					}
+				}
+				return err
+			}
+			c.progressbar.IncrementCompletedObjects()
+			return nil
+		}
+
 		dsturl = prepareRemoteDestination(srcurl, dsturl, c.flatten, isBatch)
-		err := c.doUpload(ctx, srcurl, dsturl)
+		transferDestination(ctx, dsturl)
+		err = c.runWithObjectTimeout(ctx, size, func(ctx context.Context, timeout time.Duration) error {
+			return objectTimeoutError(ctx, c.doUpload(ctx, srcurl, dsturl), timeout)
+		})
+		if c.skipVanished && isVanishedFile(srcurl, err) {
+			vanishTransfer(ctx, srcurl)
+			log.Info(VanishedMessage{Source: srcurl, Destination: dsturl})
+			return nil
+		}
 		if err != nil {
 			return &errorpkg.Error{
-				Op:  c.op,
-				Src: srcurl,
-				Dst: dsturl,
-				Err: err,
+				Op:       c.op,
+				Src:      srcurl,
+				Dst:      dsturl,
+				Err:      err,
+				Position: c.position,
 			}
 		}
 		c.progressbar.IncrementCompletedObjects()
@@ -551,8 +1085,82 @@ func (c Copy) prepareUploadTask(
 	}
 }
 
+// withObjectTimeout returns a context whose deadline is proportional to the
+// given object size, along with the computed timeout. If per-object timeouts
+// are not enabled, the returned context has no deadline and the timeout is 0.
+func (c Copy) withObjectTimeout(ctx context.Context, size int64) (context.Context, context.CancelFunc, time.Duration) {
+	timeout := objectTimeout(size, c.timeoutPerMB, c.timeoutMin, c.timeoutMax)
+	if timeout == 0 {
+		ctx, cancel := context.WithCancel(ctx)
+		return ctx, cancel, 0
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	return ctx, cancel, timeout
+}
+
+// runWithObjectTimeout runs the object operation with the per-object deadline
+// of the given object size, which is passed to it along with the timeout. An
+// operation which times out is run again with a fresh deadline, up to the
+// retry count, and the error of its last attempt is returned.
+func (c Copy) runWithObjectTimeout(
+	ctx context.Context,
+	size int64,
+	op func(ctx context.Context, timeout time.Duration) error,
+) error {
+	for attempt := 1; ; attempt++ {
+		attemptCtx, cancel, timeout := c.withObjectTimeout(ctx, size)
+		err := op(attemptCtx, timeout)
+		// the operations canceled with ctx are not retried.
+		timedOut := err != nil && timeout > 0 && ctx.Err() == nil &&
+			errors.Is(attemptCtx.Err(), context.DeadlineExceeded)
+		cancel()
+
+		if !timedOut || attempt > c.storageOpts.MaxRetries {
+			return err
+		}
+		log.Debug(log.DebugMessage{
+			Operation: c.op,
+			Err:       fmt.Sprintf("retrying the operation timed out after %v, attempt %d of %d", timeout, attempt, c.storageOpts.MaxRetries),
+		})
+	}
+}
+
+// objectTimeout computes the deadline of a single object operation. The
+// timeout grows by perMB for every started MiB of the object and it is then
+// clamped to the [min, max] range. Zero values of min and max mean no bound.
+// It returns 0 if none of the timeout flags are set.
+func objectTimeout(size int64, perMB, min, max time.Duration) time.Duration {
+	if perMB == 0 && min == 0 && max == 0 {
+		return 0
+	}
+
+	mb := (size + megabytes - 1) / megabytes
+	timeout := perMB * time.Duration(mb)
+
+	if timeout < min {
+		timeout = min
+	}
+	if max > 0 && (timeout > max || timeout == 0) {
+		timeout = max
+	}
+	return timeout
+}
+
+// objectTimeoutError reports the given error as a timeout if the per-object
+// deadline of ctx is exceeded. The SDK reports an exceeded deadline as a
+// cancelation, which would otherwise be silently dropped.
+func objectTimeoutError(ctx context.Context, err error, timeout time.Duration) error {
+	if err != nil && timeout > 0 && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%w after %v", errorpkg.ErrObjectTimeout, timeout)
+	}
+	return err
+}
+
 // doDownload is used to fetch a remote object and save as a local object.
-func (c Copy) doDownload(ctx context.Context, srcurl *url.URL, dsturl *url.URL) error {
+// The size of the object is only used as a hint and it can be 0 if unknown.
+// The ETag of the object is used to find the identical objects which are
+// already downloaded, if hardlink-identical flag is set.
+func (c Copy) doDownload(ctx context.Context, srcurl *url.URL, dsturl *url.URL, size int64, etag string) error {
 	srcClient, err := storage.NewRemoteClient(ctx, srcurl, c.storageOpts)
 	if err != nil {
 		return err
@@ -564,40 +1172,59 @@ func (c Copy) doDownload(ctx context.Context, srcurl *url.URL, dsturl *url.URL)
 	if err != nil {
 		// FIXME(ig): rename
 		if errorpkg.IsWarning(err) {
+			skipTransfer(ctx, err)
 			printDebug(c.op, err, srcurl, dsturl)
 			return nil
 		}
 		return err
 	}
 
-	dstPath := filepath.Dir(dsturl.Absolute())
-	dstFile := filepath.Base(dsturl.Absolute())
-	file, err := dstClient.CreateTemp(dstPath, dstFile)
-	if err != nil {
-		return err
+	if c.hardlinkIdentical && etag != "" && size > 0 && c.byteRange == nil {
+		key := contentKey{etag: etag, size: size}
+		file, owner := downloadedFiles.acquire(key)
+		if owner {
+			err := c.download(ctx, srcClient, dstClient, srcurl, dsturl, size)
+			downloadedFiles.finish(key, file, dsturl.Absolute(), err == nil)
+			return err
+		}
+
+		if path, ok := file.wait(ctx); ok {
+			err := c.link(ctx, srcClient, dstClient, srcurl, dsturl, path, size)
+			if err == nil {
+				return nil
+			}
+			// hard links are not supported across filesystems and on some
+			// filesystems, download the object instead.
+			printDebug(c.op, err, srcurl, dsturl)
+		}
 	}
 
-	writer := newCountingReaderWriter(file, c.progressbar)
-	size, err := srcClient.Get(ctx, srcurl, writer, c.concurrency, c.partSize)
-	file.Close()
+	return c.download(ctx, srcClient, dstClient, srcurl, dsturl, size)
+}
 
-	if err != nil {
-		dErr := dstClient.Delete(ctx, &url.URL{Path: file.Name(), Type: dsturl.Type})
-		if dErr != nil {
-			printDebug(c.op, dErr, srcurl, dsturl)
+// link hard links the local file at path, which holds the content of srcurl,
+// to dsturl.
+func (c Copy) link(
+	ctx context.Context,
+	srcClient *storage.S3,
+	dstClient *storage.Filesystem,
+	srcurl *url.URL,
+	dsturl *url.URL,
+	path string,
+	size int64,
+) error {
+	// the same object may be listed more than once.
+	if path != dsturl.Absolute() {
+		if err := dstClient.Link(path, dsturl.Absolute()); err != nil {
+			return err
 		}
-		return err
 	}
 
 	if c.deleteSource {
 		_ = srcClient.Delete(ctx, srcurl)
 	}
 
-	err = dstClient.Rename(file, dsturl.Absolute())
-	if err != nil {
-		return err
-	}
-
+	c.progressbar.AddCompletedBytes(size)
 	if !c.showProgress {
 		msg := log.InfoMessage{
 			Operation:   c.op,
@@ -613,63 +1240,205 @@ func (c Copy) doDownload(ctx context.Context, srcurl *url.URL, dsturl *url.URL)
 	return nil
 }
 
-func (c Copy) doUpload(ctx context.Context, srcurl *url.URL, dsturl *url.URL) error {
-	srcClient := storage.NewLocalClient(c.storageOpts)
+// download fetches the remote object at srcurl and saves it to dsturl.
+func (c Copy) download(
+	ctx context.Context,
+	srcClient *storage.S3,
+	dstClient *storage.Filesystem,
+	srcurl *url.URL,
+	dsturl *url.URL,
+	size int64,
+) error {
+	if c.maxDiskUsage > 0 && !c.storageOpts.DryRun {
+		release, err := waitDiskSpace(ctx, dstClient, dsturl.Absolute(), size, c.maxDiskUsage)
+		if err != nil {
+			return err
+		}
+		defer release()
+	}
 
-	file, err := srcClient.Open(srcurl.Absolute())
+	dstPath := filepath.Dir(dsturl.Absolute())
+	dstFile := filepath.Base(dsturl.Absolute())
+	file, err := dstClient.CreateTemp(dstPath, dstFile)
 	if err != nil {
 		return err
 	}
-	defer file.Close()
 
-	err = c.shouldOverride(ctx, srcurl, dsturl)
-	if err != nil {
-		if errorpkg.IsWarning(err) {
-			printDebug(c.op, err, srcurl, dsturl)
-			return nil
+	preallocated := c.preallocate && size > 0 && c.byteRange == nil
+	if preallocated {
+		err = dstClient.Preallocate(file, size)
+	}
+
+	if err == nil {
+		writer := newCountingReaderWriter(file, c.progressbar)
+		if c.byteRange != nil {
+			size, err = c.downloadRange(ctx, srcClient, srcurl, writer)
+		} else {
+			size, err = srcClient.Get(ctx, srcurl, writer, c.concurrency, c.partSize)
 		}
-		return err
 	}
 
-	// override destination region if set
-	if c.dstRegion != "" {
-		c.storageOpts.SetRegion(c.dstRegion)
+	// the object may have changed since it is listed, drop the excess of the
+	// preallocated space.
+	if err == nil && preallocated {
+		err = dstClient.Truncate(file, size)
 	}
-	dstClient, err := storage.NewRemoteClient(ctx, dsturl, c.storageOpts)
+	file.Close()
+
 	if err != nil {
+		dErr := dstClient.Delete(ctx, &url.URL{Path: file.Name(), Type: dsturl.Type})
+		if dErr != nil {
+			printDebug(c.op, dErr, srcurl, dsturl)
+		}
 		return err
 	}
 
-	metadata := storage.NewMetadata().
-		SetStorageClass(string(c.storageClass)).
-		SetSSE(c.encryptionMethod).
-		SetSSEKeyID(c.encryptionKeyID).
-		SetACL(c.acl).
-		SetCacheControl(c.cacheControl).
-		SetExpires(c.expires)
+	if c.deleteSource {
+		_ = srcClient.Delete(ctx, srcurl)
+	}
 
-	if c.contentType != "" {
-		metadata.SetContentType(c.contentType)
-	} else {
-		metadata.SetContentType(guessContentType(file))
+	err = dstClient.Rename(file, dsturl.Absolute())
+	if err != nil {
+		return err
 	}
 
-	if c.contentEncoding != "" {
-		metadata.SetContentEncoding(c.contentEncoding)
+	if (c.preserveMtime || c.preserveWindowsAttrs) && !c.storageOpts.DryRun {
+		// the metadata of the object is not returned with its content.
+		obj, err := srcClient.Stat(ctx, srcurl)
+		if err != nil {
+			return err
+		}
+		// the modification time is set before the attributes, which may
+		// make the file read-only.
+		if c.preserveMtime {
+			if err := restoreModTime(dstClient, obj, dsturl); err != nil {
+				return err
+			}
+		}
+		if c.preserveWindowsAttrs {
+			if err := restoreWindowsAttributes(dstClient, obj, dsturl); err != nil {
+				return err
+			}
+		}
 	}
-	if c.contentDisposition != "" {
-		metadata.SetContentDisposition(c.contentDisposition)
+
+	if !c.showProgress {
+		msg := log.InfoMessage{
+			Operation:   c.op,
+			Source:      srcurl,
+			Destination: dsturl,
+			Object: &storage.Object{
+				Size: size,
+			},
+		}
+		log.Info(msg)
 	}
-	reader := newCountingReaderWriter(file, c.progressbar)
-	err = dstClient.Put(ctx, reader, dsturl, metadata, c.concurrency, c.partSize)
+
+	return nil
+}
+
+func (c Copy) doUpload(ctx context.Context, srcurl *url.URL, dsturl *url.URL) error {
+	srcClient := storage.NewLocalClient(c.storageOpts)
+
+	if c.onBrokenSymlink == storage.BrokenSymlinkUploadAsLink {
+		if target, ok := srcClient.BrokenSymlinkTarget(srcurl.Absolute()); ok {
+			return c.doUploadSymlink(ctx, srcClient, srcurl, dsturl, target)
+		}
+	}
+
+	file, release, err := srcClient.Open(ctx, srcurl.Absolute())
+	if err != nil {
+		return err
+	}
+	defer release()
+	defer file.Close()
+
+	err = c.shouldOverride(ctx, srcurl, dsturl)
+	if err != nil {
+		if errorpkg.IsWarning(err) {
+			skipTransfer(ctx, err)
+			printDebug(c.op, err, srcurl, dsturl)
+			return nil
+		}
+		return err
+	}
+
+	// override destination region if set
+	if c.dstRegion != "" {
+		c.storageOpts.SetRegion(c.dstRegion)
+	}
+	dstClient, err := storage.NewRemoteClient(ctx, dsturl, c.storageOpts)
 	if err != nil {
 		return err
 	}
 
+	transform := c.transform != nil && !c.storageOpts.DryRun
+
+	metadata := c.withAutoSSE(ctx, c.uploadMetadata(), dsturl)
+	if metadata.ContentType() == "" {
+		if transform {
+			// the content of the file is not the content of the object.
+			metadata.SetContentType(guessContentTypeByExtension(dsturl))
+		} else {
+			metadata.SetContentType(guessContentType(file))
+		}
+	}
+	if c.preserveWindowsAttrs {
+		attrs, err := windowsAttributesMetadata(srcClient, srcurl)
+		if err != nil {
+			return err
+		}
+		metadata.SetWindowsAttributes(attrs)
+	}
+	if c.preserveMtime {
+		metadata, err = modTimeMetadata(metadata, file)
+		if err != nil {
+			return err
+		}
+	}
+	warnAlternateDataStreams(c.op, srcClient, srcurl)
+
+	reader := newCountingReaderWriter(file, c.progressbar)
+
+	var appended bool
+	if c.appendDetect {
+		appended, err = c.doAppend(ctx, dstClient, file, reader, srcurl, dsturl, metadata)
+		if err != nil {
+			return err
+		}
+	}
+
+	transformedSize := int64(-1)
+	switch {
+	case appended:
+	case transform:
+		transformedSize, err = c.transform.pipe(ctx, reader, func(r io.Reader) error {
+			return dstClient.Put(ctx, r, dsturl, metadata, c.concurrency, c.partSize)
+		})
+		if err != nil {
+			return err
+		}
+	default:
+		err = dstClient.Put(ctx, reader, dsturl, metadata, c.concurrency, c.filePartSize(file))
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.expectedSize >= 0 && !c.storageOpts.DryRun {
+		err := verifyUploadSize(ctx, dstClient, dsturl, c.expectedSize, c.deleteOnSizeMismatch)
+		if err != nil {
+			return err
+		}
+	}
+
 	obj, err := srcClient.Stat(ctx, srcurl)
 	if err != nil {
 		return err
 	}
+	if transformedSize >= 0 {
+		obj.Size = transformedSize
+	}
 
 	if c.deleteSource {
 		// close the file before deleting
@@ -679,6 +1448,11 @@ func (c Copy) doUpload(ctx context.Context, srcurl *url.URL, dsturl *url.URL) er
 		}
 	}
 
+	objurl, err := c.printURL(ctx, dsturl)
+	if err != nil {
+		return err
+	}
+
 	if !c.showProgress {
 		msg := log.InfoMessage{
 			Operation:   c.op,
@@ -688,6 +1462,7 @@ func (c Copy) doUpload(ctx context.Context, srcurl *url.URL, dsturl *url.URL) er
 				Size:         obj.Size,
 				StorageClass: c.storageClass,
 			},
+			URL: objurl,
 		}
 		log.Info(msg)
 	}
@@ -695,16 +1470,76 @@ func (c Copy) doUpload(ctx context.Context, srcurl *url.URL, dsturl *url.URL) er
 	return nil
 }
 
-func (c Copy) doCopy(ctx context.Context, srcurl, dsturl *url.URL) error {
+// doUploadSymlink uploads a symbolic link whose target doesn't exist as an
+// object holding the target of the link.
+func (c Copy) doUploadSymlink(
+	ctx context.Context,
+	srcClient *storage.Filesystem,
+	srcurl *url.URL,
+	dsturl *url.URL,
+	target string,
+) error {
+	err := c.shouldOverride(ctx, srcurl, dsturl)
+	if err != nil {
+		if errorpkg.IsWarning(err) {
+			skipTransfer(ctx, err)
+			printDebug(c.op, err, srcurl, dsturl)
+			return nil
+		}
+		return err
+	}
+
 	// override destination region if set
 	if c.dstRegion != "" {
 		c.storageOpts.SetRegion(c.dstRegion)
 	}
-	dstClient, err := storage.NewClient(ctx, dsturl, c.storageOpts)
+	dstClient, err := storage.NewRemoteClient(ctx, dsturl, c.storageOpts)
+	if err != nil {
+		return err
+	}
+
+	metadata := c.withAutoSSE(ctx, c.uploadMetadata(), dsturl).SetSymlinkTarget(target)
+	if metadata.ContentType() == "" {
+		metadata.SetContentType("text/plain")
+	}
+
+	err = dstClient.Put(ctx, strings.NewReader(target), dsturl, metadata, c.concurrency, c.partSize)
 	if err != nil {
 		return err
 	}
+	c.progressbar.AddCompletedBytes(int64(len(target)))
+
+	if c.deleteSource {
+		if err := srcClient.Delete(ctx, srcurl); err != nil {
+			return err
+		}
+	}
+
+	objurl, err := c.printURL(ctx, dsturl)
+	if err != nil {
+		return err
+	}
+
+	if !c.showProgress {
+		msg := log.InfoMessage{
+			Operation:   c.op,
+			Source:      srcurl,
+			Destination: dsturl,
+			Object: &storage.Object{
+				Size:         int64(len(target)),
+				StorageClass: c.storageClass,
+			},
+			URL: objurl,
+		}
+		log.Info(msg)
+	}
+
+	return nil
+}
 
+// uploadMetadata returns the metadata of the uploaded and copied objects set
+// by the flags.
+func (c Copy) uploadMetadata() storage.Metadata {
 	metadata := storage.NewMetadata().
 		SetStorageClass(string(c.storageClass)).
 		SetSSE(c.encryptionMethod).
@@ -722,17 +1557,97 @@ func (c Copy) doCopy(ctx context.Context, srcurl, dsturl *url.URL) error {
 	if c.contentDisposition != "" {
 		metadata.SetContentDisposition(c.contentDisposition)
 	}
+	if c.contentLanguage != "" {
+		metadata.SetContentLanguage(c.contentLanguage)
+	}
+	if c.websiteRedirect != "" {
+		metadata.SetWebsiteRedirect(c.websiteRedirect)
+	}
+	for name, value := range c.systemHeaders {
+		metadata.SetSystemHeader(name, value)
+	}
+	for name, value := range c.userMetadata {
+		metadata.SetUserMetadata(name, value)
+	}
+	return metadata
+}
+
+// doAppend uploads only the new tail of a local file if the destination object
+// is the unchanged beginning of it, which is typical for append-only files such
+// as logs. It returns false if the file should be uploaded in full instead.
+func (c Copy) doAppend(
+	ctx context.Context,
+	dstClient *storage.S3,
+	file *os.File,
+	reader *countingReaderWriter,
+	srcurl *url.URL,
+	dsturl *url.URL,
+	metadata storage.Metadata,
+) (bool, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return false, err
+	}
+
+	dstObj, err := statObject(ctx, dsturl, dstClient)
+	if err != nil {
+		return false, err
+	}
+
+	if dstObj == nil || dstObj.Size >= info.Size() || dstObj.Size < storage.MinPartSize {
+		return false, nil
+	}
+
+	ok, err := dstClient.HasPrefix(ctx, dstObj, file)
+	if err != nil {
+		return false, err
+	}
+	if !ok {
+		err := fmt.Errorf("destination is not the beginning of the source, uploading the whole file")
+		printDebug(c.op, err, srcurl, dsturl)
+		return false, nil
+	}
+
+	err = dstClient.Append(ctx, reader, info.Size(), dstObj, metadata, c.partSize)
+	if err != nil {
+		return false, err
+	}
+	c.progressbar.AddCompletedBytes(dstObj.Size)
+
+	return true, nil
+}
+
+func (c Copy) doCopy(ctx context.Context, srcurl, dsturl *url.URL, size int64) error {
+	if c.transform != nil && !c.storageOpts.DryRun {
+		return c.doTransformedCopy(ctx, srcurl, dsturl)
+	}
+
+	// override destination region if set
+	if c.dstRegion != "" {
+		c.storageOpts.SetRegion(c.dstRegion)
+	}
+	dstClient, err := storage.NewClient(ctx, dsturl, c.storageOpts)
+	if err != nil {
+		return err
+	}
+
+	metadata := c.withAutoSSE(ctx, c.uploadMetadata(), dsturl)
 
 	err = c.shouldOverride(ctx, srcurl, dsturl)
 	if err != nil {
 		if errorpkg.IsWarning(err) {
+			skipTransfer(ctx, err)
 			printDebug(c.op, err, srcurl, dsturl)
 			return nil
 		}
 		return err
 	}
 
-	err = dstClient.Copy(ctx, srcurl, dsturl, metadata)
+	if s3client, ok := dstClient.(*storage.S3); ok && c.isMultipartCopy(size) {
+		err = s3client.MultipartCopy(ctx, srcurl, dsturl, metadata, c.multipartCopyConcurrency, c.partSize)
+	} else {
+		err = dstClient.Copy(ctx, srcurl, dsturl, metadata)
+	}
 	if err != nil {
 		return err
 	}
@@ -747,6 +1662,11 @@ func (c Copy) doCopy(ctx context.Context, srcurl, dsturl *url.URL) error {
 		}
 	}
 
+	objurl, err := c.printURL(ctx, dsturl)
+	if err != nil {
+		return err
+	}
+
 	msg := log.InfoMessage{
 		Operation:   c.op,
 		Source:      srcurl,
@@ -755,6 +1675,79 @@ func (c Copy) doCopy(ctx context.Context, srcurl, dsturl *url.URL) error {
 			URL:          dsturl,
 			StorageClass: c.storageClass,
 		},
+		URL: objurl,
+	}
+	log.Info(msg)
+
+	return nil
+}
+
+// doTransformedCopy copies the remote object at srcurl to dsturl through the
+// transform command. The object is downloaded and uploaded as a stream, since
+// the size of the transformed object is not known in advance.
+func (c Copy) doTransformedCopy(ctx context.Context, srcurl, dsturl *url.URL) error {
+	srcClient, err := storage.NewRemoteClient(ctx, srcurl, c.storageOpts)
+	if err != nil {
+		return err
+	}
+
+	err = c.shouldOverride(ctx, srcurl, dsturl)
+	if err != nil {
+		if errorpkg.IsWarning(err) {
+			skipTransfer(ctx, err)
+			printDebug(c.op, err, srcurl, dsturl)
+			return nil
+		}
+		return err
+	}
+
+	// override destination region if set
+	if c.dstRegion != "" {
+		c.storageOpts.SetRegion(c.dstRegion)
+	}
+	dstClient, err := storage.NewRemoteClient(ctx, dsturl, c.storageOpts)
+	if err != nil {
+		return err
+	}
+
+	src, err := srcClient.Read(ctx, srcurl)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	metadata := c.withAutoSSE(ctx, c.uploadMetadata(), dsturl)
+	if metadata.ContentType() == "" {
+		metadata.SetContentType(guessContentTypeByExtension(dsturl))
+	}
+
+	size, err := c.transform.pipe(ctx, src, func(r io.Reader) error {
+		return dstClient.Put(ctx, r, dsturl, metadata, c.concurrency, c.partSize)
+	})
+	if err != nil {
+		return err
+	}
+
+	if c.deleteSource {
+		if err := srcClient.Delete(ctx, srcurl); err != nil {
+			return err
+		}
+	}
+
+	objurl, err := c.printURL(ctx, dsturl)
+	if err != nil {
+		return err
+	}
+
+	msg := log.InfoMessage{
+		Operation:   c.op,
+		Source:      srcurl,
+		Destination: dsturl,
+		Object: &storage.Object{
+			Size:         size,
+			StorageClass: c.storageClass,
+		},
+		URL: objurl,
 	}
 	log.Info(msg)
 
@@ -765,10 +1758,11 @@ func (c Copy) doCopy(ctx context.Context, srcurl, dsturl *url.URL) error {
 // the source-destination pair and given copy flags conform to the
 // override criteria. For example; "cp -n -s <src> <dst>" should not override
 // the <dst> if <src> and <dst> filenames are the same, except if the size
-// differs.
+// differs. In interactive mode, the user is asked before overriding the
+// destination if none of the criteria prevents it.
 func (c Copy) shouldOverride(ctx context.Context, srcurl *url.URL, dsturl *url.URL) error {
 	// if not asked to override, ignore.
-	if !c.noClobber && !c.ifSizeDiffer && !c.ifSourceNewer {
+	if !c.noClobber && !c.ifSizeDiffer && !c.ifSourceNewer && c.prompter == nil {
 		return nil
 	}
 
@@ -820,6 +1814,16 @@ func (c Copy) shouldOverride(ctx context.Context, srcurl *url.URL, dsturl *url.U
 		}
 	}
 
+	if stickyErr == nil && c.prompter != nil {
+		ok, err := c.prompter.Confirm(dsturl)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			stickyErr = errorpkg.ErrObjectExists
+		}
+	}
+
 	return stickyErr
 }
 
@@ -842,22 +1846,22 @@ func prepareRemoteDestination(
 	return dsturl
 }
 
-// prepareDownloadDestination will return a new destination URL for
-// remote->local copy operations.
-func prepareLocalDestination(
+// prepareLocalDestination will return a new destination URL for
+// remote->local copy operations. The type conflicts on the destination are
+// resolved with respect to the on-type-conflict flag, the destination URL is
+// returned along with the error if they can't be.
+func (c Copy) prepareLocalDestination(
 	ctx context.Context,
 	srcurl *url.URL,
 	dsturl *url.URL,
-	flatten bool,
 	isBatch bool,
-	storageOpts storage.Options,
 ) (*url.URL, error) {
 	objname := srcurl.Base()
-	if isBatch && !flatten {
+	if isBatch && !c.flatten {
 		objname = srcurl.Relative()
 	}
 
-	client := storage.NewLocalClient(storageOpts)
+	client := storage.NewLocalClient(c.storageOpts)
 
 	if isBatch {
 		err := client.MkdirAll(dsturl.Absolute())
@@ -874,8 +1878,11 @@ func prepareLocalDestination(
 		}
 	}
 
-	if isBatch && !flatten {
+	if isBatch && !c.flatten {
 		dsturl = dsturl.Join(objname)
+		if err := c.resolveTypeConflict(ctx, client, dsturl); err != nil {
+			return dsturl, err
+		}
 		err := client.MkdirAll(dsturl.Dir())
 		if err != nil {
 			return nil, err
@@ -883,16 +1890,22 @@ func prepareLocalDestination(
 	}
 	var objNotFound *storage.ErrGivenObjectNotFound
 	if errors.As(err, &objNotFound) {
+		if strings.HasSuffix(dsturl.Absolute(), "/") {
+			dsturl = dsturl.Join(objname)
+		}
+		if err := c.resolveTypeConflict(ctx, client, dsturl); err != nil {
+			return dsturl, err
+		}
 		err := client.MkdirAll(dsturl.Dir())
 		if err != nil {
 			return nil, err
 		}
-		if strings.HasSuffix(dsturl.Absolute(), "/") {
-			dsturl = dsturl.Join(objname)
-		}
 	} else {
 		if obj.Type.IsDir() {
 			dsturl = obj.URL.Join(objname)
+			if err := c.resolveTypeConflict(ctx, client, dsturl); err != nil {
+				return dsturl, err
+			}
 		}
 	}
 
@@ -911,13 +1924,71 @@ func statObject(ctx context.Context, url *url.URL, client storage.Storage) (*sto
 	return obj, err
 }
 
+// parseExpectedSize returns the size given with the "expected-size" flag, or
+// -1 if the flag is not set.
+func parseExpectedSize(c *cli.Context) (int64, error) {
+	if !c.IsSet("expected-size") {
+		return -1, nil
+	}
+	size, err := strutil.ParseBytes(c.String("expected-size"))
+	if err != nil {
+		return 0, fmt.Errorf("invalid expected-size: %w", err)
+	}
+	return size, nil
+}
+
+// verifyUploadSize checks that the size of the uploaded object matches the
+// expected size, which catches the uploads of truncated streams. The object
+// is deleted on mismatch if deleteOnMismatch is set.
+func verifyUploadSize(
+	ctx context.Context,
+	client *storage.S3,
+	dsturl *url.URL,
+	expected int64,
+	deleteOnMismatch bool,
+) error {
+	obj, err := client.Stat(ctx, dsturl)
+	if err != nil {
+		return err
+	}
+
+	if obj.Size == expected {
+		return nil
+	}
+
+	err = fmt.Errorf("uploaded object size %v does not match expected size %v", obj.Size, expected)
+	if !deleteOnMismatch {
+		return err
+	}
+
+	if derr := client.Delete(ctx, dsturl); derr != nil {
+		return fmt.Errorf("%w, and the object could not be deleted: %v", err, derr)
+	}
+	return fmt.Errorf("%w, the object is deleted", err)
+}
+
+func validateExpectedSize(c *cli.Context) error {
+	if !c.IsSet("expected-size") {
+		if c.Bool("delete-on-size-mismatch") {
+			return fmt.Errorf("%q flag requires %q flag", "delete-on-size-mismatch", "expected-size")
+		}
+		return nil
+	}
+	_, err := parseExpectedSize(c)
+	return err
+}
+
 func validateCopyCommand(c *cli.Context) error {
 	if c.Args().Len() != 2 {
 		return fmt.Errorf("expected source and destination arguments")
 	}
 
+	if err := validateAWSCLIFlags(c, c.Args().Get(0)); err != nil {
+		return err
+	}
+
 	ctx := c.Context
-	src := c.Args().Get(0)
+	src := recursiveSources(c, c.Args().Get(0))[0]
 	dst := c.Args().Get(1)
 
 	srcurl, err := url.New(src, url.WithVersion(c.String("version-id")),
@@ -955,16 +2026,138 @@ func validateCopyCommand(c *cli.Context) error {
 		return err
 	}
 
+	if err := validateObjectTimeout(c); err != nil {
+		return err
+	}
+
+	if err := validateCopyByteRange(c, srcurl, dsturl); err != nil {
+		return err
+	}
+
+	if c.IsSet("max-disk-usage") {
+		if _, err := strutil.ParseBytes(c.String("max-disk-usage")); err != nil {
+			return fmt.Errorf("invalid max-disk-usage: %w", err)
+		}
+	}
+
+	if err := validateExpectedSize(c); err != nil {
+		return err
+	}
+
+	if err := validateWebsiteRedirect(c); err != nil {
+		return err
+	}
+
+	if err := validateOnConflict(c, dsturl); err != nil {
+		return err
+	}
+
+	if err := validatePinVersions(c, srcurl); err != nil {
+		return err
+	}
+
+	if err := validateFanout(c, srcurl, dsturl); err != nil {
+		return err
+	}
+
+	if err := validateDestDatePrefix(c, dsturl); err != nil {
+		return err
+	}
+
+	if err := validateTransformExec(c, dsturl); err != nil {
+		return err
+	}
+
+	if err := validatePrintURLs(c, dsturl); err != nil {
+		return err
+	}
+
+	if err := validatePreserveWindowsAttrs(c); err != nil {
+		return err
+	}
+
+	if err := validateMultipartThreshold(c); err != nil {
+		return err
+	}
+
+	if c.IsSet(multipartCopyConcurrencyFlagName) && c.Int(multipartCopyConcurrencyFlagName) < 1 {
+		return fmt.Errorf("%q flag must be greater than zero", multipartCopyConcurrencyFlagName)
+	}
+
+	if c.IsSet("expected-size") {
+		if srcurl.IsRemote() || srcurl.IsWildcard() || !dsturl.IsRemote() {
+			return fmt.Errorf("%q flag can only be used to upload a single file", "expected-size")
+		}
+		obj, err := storage.NewLocalClient(NewStorageOpts(c)).Stat(ctx, srcurl)
+		if err != nil {
+			return err
+		}
+		if obj.Type.IsDir() {
+			return fmt.Errorf("%q flag can only be used to upload a single file", "expected-size")
+		}
+	}
+
+	if c.Bool("force") && c.String("on-type-conflict") != typeConflictReplace {
+		return fmt.Errorf(`"force" flag can only be used with "--on-type-conflict %v"`, typeConflictReplace)
+	}
+
 	switch {
 	case srcurl.Type == dsturl.Type:
 		return validateCopy(srcurl, dsturl)
 	case dsturl.IsRemote():
-		return validateUpload(ctx, srcurl, dsturl, NewStorageOpts(c))
+		return validateUpload(ctx, srcurl, dsturl, NewStorageOpts(c), c.Bool(skipVanishedFlagName))
 	default:
 		return nil
 	}
 }
 
+// systemHeaders returns the headers given with the "system-header" flag by
+// their canonical names.
+func systemHeaders(c *cli.Context) map[string]string {
+	if headers, ok := c.Value("system-header").(HeaderValue); ok {
+		return headers.Headers()
+	}
+	return nil
+}
+
+// userMetadata returns the user-defined metadata given with the "metadata"
+// flag by their names.
+func userMetadata(c *cli.Context) map[string]string {
+	if metadata, ok := c.Value("metadata").(HeaderValue); ok {
+		return metadata.Headers()
+	}
+	return nil
+}
+
+// validateWebsiteRedirect checks that the website redirect location is a key
+// of the bucket or a URL, as S3 requires.
+func validateWebsiteRedirect(c *cli.Context) error {
+	location := c.String("website-redirect")
+	if headers := systemHeaders(c); headers["X-Amz-Website-Redirect-Location"] != "" {
+		location = headers["X-Amz-Website-Redirect-Location"]
+	}
+	if location == "" {
+		return nil
+	}
+	for _, prefix := range []string{"/", "http://", "https://"} {
+		if strings.HasPrefix(location, prefix) {
+			return nil
+		}
+	}
+	return fmt.Errorf("website redirect location %q must start with \"/\", \"http://\" or \"https://\"", location)
+}
+
+func validateObjectTimeout(c *cli.Context) error {
+	perMB, min, max := c.Duration("timeout-per-mb"), c.Duration("timeout-min"), c.Duration("timeout-max")
+	if perMB < 0 || min < 0 || max < 0 {
+		return fmt.Errorf("per-object timeout values cannot be negative")
+	}
+	if max > 0 && min > max {
+		return fmt.Errorf("timeout-min (%v) cannot be greater than timeout-max (%v)", min, max)
+	}
+	return nil
+}
+
 func validateCopy(srcurl, dsturl *url.URL) error {
 	if srcurl.IsRemote() || dsturl.IsRemote() {
 		return nil
@@ -974,7 +2167,7 @@ func validateCopy(srcurl, dsturl *url.URL) error {
 	return fmt.Errorf("local->local copy operations are not permitted")
 }
 
-func validateUpload(ctx context.Context, srcurl, dsturl *url.URL, storageOpts storage.Options) error {
+func validateUpload(ctx context.Context, srcurl, dsturl *url.URL, storageOpts storage.Options, skipVanished bool) error {
 	srcclient := storage.NewLocalClient(storageOpts)
 
 	if srcurl.IsWildcard() {
@@ -982,6 +2175,10 @@ func validateUpload(ctx context.Context, srcurl, dsturl *url.URL, storageOpts st
 	}
 
 	obj, err := srcclient.Stat(ctx, srcurl)
+	// the vanished file is reported as such when the copy runs.
+	if skipVanished && isVanishedFile(srcurl, err) {
+		return nil
+	}
 	if err != nil {
 		return err
 	}
@@ -1033,6 +2230,12 @@ func (r *countingReaderWriter) WriteAt(p []byte, off int64) (int, error) {
 	return n, err
 }
 
+func (r *countingReaderWriter) Write(p []byte) (int, error) {
+	n, err := r.fp.Write(p)
+	r.pb.AddCompletedBytes(int64(n))
+	return n, err
+}
+
 func (r *countingReaderWriter) Read(p []byte) (int, error) {
 	n, err := r.fp.Read(p)
 	r.pb.AddCompletedBytes(int64(n))
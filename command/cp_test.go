@@ -1,11 +1,18 @@
 package command
 
 import (
+	"context"
+	"errors"
 	"io"
 	"os"
 	"testing"
+	"time"
 
 	"gotest.tools/v3/assert"
+
+	errorpkg "github.com/peak/s5cmd/v2/error"
+	"github.com/peak/s5cmd/v2/log"
+	"github.com/peak/s5cmd/v2/storage"
 )
 
 func TestGuessContentType(t *testing.T) {
@@ -77,3 +84,206 @@ func TestGuessContentType(t *testing.T) {
 		os.Remove(f.Name())
 	}
 }
+
+func TestObjectTimeout(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name  string
+		size  int64
+		perMB time.Duration
+		min   time.Duration
+		max   time.Duration
+
+		expected time.Duration
+	}{
+		{
+			name:     "disabled",
+			size:     10 * megabytes,
+			expected: 0,
+		},
+		{
+			name:     "proportional to size",
+			size:     10 * megabytes,
+			perMB:    2 * time.Second,
+			expected: 20 * time.Second,
+		},
+		{
+			name:     "partial megabyte is rounded up",
+			size:     megabytes + 1,
+			perMB:    2 * time.Second,
+			expected: 4 * time.Second,
+		},
+		{
+			name:     "small object gets the minimum",
+			size:     4 * 1024,
+			perMB:    2 * time.Second,
+			min:      30 * time.Second,
+			max:      2 * time.Hour,
+			expected: 30 * time.Second,
+		},
+		{
+			name:     "large object gets the maximum",
+			size:     10 * 1024 * megabytes,
+			perMB:    2 * time.Second,
+			min:      30 * time.Second,
+			max:      2 * time.Hour,
+			expected: 2 * time.Hour,
+		},
+		{
+			name:     "empty object with only maximum set",
+			size:     0,
+			max:      time.Minute,
+			expected: time.Minute,
+		},
+		{
+			name:     "only minimum set",
+			size:     10 * megabytes,
+			min:      time.Minute,
+			expected: time.Minute,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := objectTimeout(tc.size, tc.perMB, tc.min, tc.max)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
+
+func TestRunWithObjectTimeoutRetriesTimedOutOperation(t *testing.T) {
+	log.Init("error", false)
+
+	c := Copy{
+		op:          "cp",
+		timeoutMin:  50 * time.Millisecond,
+		storageOpts: storage.Options{MaxRetries: 2},
+	}
+
+	var deadlines []time.Time
+	err := c.runWithObjectTimeout(context.Background(), 1, func(ctx context.Context, timeout time.Duration) error {
+		deadline, ok := ctx.Deadline()
+		assert.Assert(t, ok)
+		deadlines = append(deadlines, deadline)
+
+		// the first attempt is stuck until it times out.
+		if len(deadlines) == 1 {
+			<-ctx.Done()
+			return objectTimeoutError(ctx, ctx.Err(), timeout)
+		}
+		return nil
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, len(deadlines), 2)
+	// the second attempt is given a fresh deadline.
+	assert.Assert(t, deadlines[1].After(deadlines[0]))
+}
+
+func TestRunWithObjectTimeout(t *testing.T) {
+	log.Init("error", false)
+
+	errFailed := errors.New("failed")
+
+	testcases := []struct {
+		name       string
+		timeoutMin time.Duration
+		retries    int
+		// timeouts is the number of the attempts which time out before the
+		// operation completes with err.
+		timeouts int
+		err      error
+
+		expectedAttempts int
+		expectedTimeout  bool
+		expectedErr      error
+	}{
+		{
+			name:             "succeeded",
+			timeoutMin:       time.Minute,
+			retries:          2,
+			expectedAttempts: 1,
+		},
+		{
+			name:             "failed without timeout",
+			timeoutMin:       time.Minute,
+			retries:          2,
+			err:              errFailed,
+			expectedAttempts: 1,
+			expectedErr:      errFailed,
+		},
+		{
+			name:             "timeouts disabled",
+			retries:          2,
+			err:              errFailed,
+			expectedAttempts: 1,
+			expectedErr:      errFailed,
+		},
+		{
+			name:             "timed out every time",
+			timeoutMin:       10 * time.Millisecond,
+			retries:          2,
+			timeouts:         10,
+			expectedAttempts: 3,
+			expectedTimeout:  true,
+		},
+		{
+			name:             "no retries",
+			timeoutMin:       10 * time.Millisecond,
+			timeouts:         10,
+			expectedAttempts: 1,
+			expectedTimeout:  true,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			c := Copy{
+				op:          "cp",
+				timeoutMin:  tc.timeoutMin,
+				storageOpts: storage.Options{MaxRetries: tc.retries},
+			}
+
+			attempts := 0
+			err := c.runWithObjectTimeout(context.Background(), 1, func(ctx context.Context, timeout time.Duration) error {
+				attempts++
+				if attempts <= tc.timeouts {
+					<-ctx.Done()
+					return objectTimeoutError(ctx, ctx.Err(), timeout)
+				}
+				return tc.err
+			})
+
+			assert.Equal(t, attempts, tc.expectedAttempts)
+			if tc.expectedTimeout {
+				assert.Assert(t, errors.Is(err, errorpkg.ErrObjectTimeout), "unexpected error: %v", err)
+				return
+			}
+			assert.Equal(t, err, tc.expectedErr)
+		})
+	}
+}
+
+func TestRunWithObjectTimeoutNotRetriedIfCanceled(t *testing.T) {
+	log.Init("error", false)
+
+	c := Copy{
+		op:          "cp",
+		timeoutMin:  time.Minute,
+		storageOpts: storage.Options{MaxRetries: 2},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	attempts := 0
+	err := c.runWithObjectTimeout(ctx, 1, func(ctx context.Context, timeout time.Duration) error {
+		attempts++
+		cancel()
+		return ctx.Err()
+	})
+	assert.Equal(t, attempts, 1)
+	assert.Assert(t, errors.Is(err, context.Canceled))
+}
@@ -0,0 +1,71 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/peak/s5cmd/v2/log"
+	"github.com/peak/s5cmd/v2/storage"
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+const destDatePrefixFlagName = "dest-date-prefix"
+
+func newDestDatePrefixFlag() cli.Flag {
+	return &cli.StringFlag{
+		Name:  destDatePrefixFlagName,
+		Usage: "copy the objects under a prefix formed of their modification time in UTC with the given Go time layout, e.g. 2006/01/02",
+	}
+}
+
+// datePrefix returns the destination prefix of the object with --dest-date-prefix
+// layout. The objects without a modification time are put under the prefix
+// of the current time.
+func datePrefix(op string, object *storage.Object, layout string) string {
+	modTime := time.Now()
+	if object.ModTime != nil && !object.ModTime.IsZero() {
+		modTime = *object.ModTime
+	} else {
+		log.Warning(log.WarningMessage{
+			Operation: op,
+			Warning:   fmt.Sprintf("%v has no modification time, the current time is used for its %v", object.URL, destDatePrefixFlagName),
+		})
+	}
+	return strings.Trim(modTime.UTC().Format(layout), "/")
+}
+
+// datePrefixedURL returns the directory of the destination dsturl under the
+// given date prefix.
+func datePrefixedURL(dsturl *url.URL, prefix string) *url.URL {
+	u := dsturl.Join(prefix)
+	if !strings.HasSuffix(u.Path, "/") {
+		u.Path += "/"
+	}
+	return u
+}
+
+func validateDestDatePrefix(c *cli.Context, dsturl *url.URL) error {
+	if !c.IsSet(destDatePrefixFlagName) {
+		return nil
+	}
+
+	layout := strings.Trim(c.String(destDatePrefixFlagName), "/")
+	// a layout without any element of the time is formatted as is.
+	if layout == "" || (time.Time{}).Format(layout) == layout {
+		return fmt.Errorf("%q flag must be a Go time layout, e.g. 2006/01/02", destDatePrefixFlagName)
+	}
+
+	if dsturl.IsRemote() && !dsturl.IsPrefix() && !dsturl.IsBucket() {
+		return fmt.Errorf("%q flag requires the destination to be a bucket or a prefix", destDatePrefixFlagName)
+	}
+	if c.Bool("partition-by-prefix") {
+		return fmt.Errorf("it is not allowed to combine %q and %q flags", destDatePrefixFlagName, "partition-by-prefix")
+	}
+	if c.IsSet(alsoToFlagName) {
+		return fmt.Errorf("%q flag cannot be used with %q flag", destDatePrefixFlagName, alsoToFlagName)
+	}
+	return nil
+}
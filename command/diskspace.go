@@ -0,0 +1,149 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/peak/s5cmd/v2/log"
+	"github.com/peak/s5cmd/v2/storage"
+	"github.com/peak/s5cmd/v2/strutil"
+)
+
+// diskSpacePollInterval is the interval to query the free space of a
+// filesystem while downloads are paused.
+var diskSpacePollInterval = time.Second
+
+// checkDiskSpace returns an error if the filesystem of the given destination
+// doesn't have enough free space for the planned downloads.
+func checkDiskSpace(fs *storage.Filesystem, dst string, planned int64) error {
+	free, _, err := fs.DiskSpace(dst)
+	if err == storage.ErrDiskSpaceNotSupported {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if planned > free {
+		return fmt.Errorf(
+			"not enough disk space on the destination: %v required, %v available",
+			strutil.HumanizeBytes(planned), strutil.HumanizeBytes(free),
+		)
+	}
+	return nil
+}
+
+// diskReserves holds the reserves of the filesystems downloaded to by the
+// commands of the process, so that the commands generated by "run" and "sync"
+// share them.
+var diskReserves = &diskReserveRegistry{
+	reserves: map[diskReserveKey]*diskReserve{},
+}
+
+type diskReserveKey struct {
+	device   uint64
+	maxUsage int64
+}
+
+type diskReserveRegistry struct {
+	mu       sync.Mutex
+	reserves map[diskReserveKey]*diskReserve
+}
+
+// get returns the reserve of the filesystem with the given device. The
+// reserve is the free space of the filesystem at the time of the first call,
+// less the maximum disk usage.
+func (r *diskReserveRegistry) get(device uint64, free, maxUsage int64) *diskReserve {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := diskReserveKey{device: device, maxUsage: maxUsage}
+	if reserve, ok := r.reserves[key]; ok {
+		return reserve
+	}
+
+	reserve := &diskReserve{reserve: free - maxUsage, maxUsage: maxUsage}
+	r.reserves[key] = reserve
+	return reserve
+}
+
+// diskReserve limits the disk usage of the downloads to a filesystem. A
+// download starts only if the free space of the filesystem stays above the
+// reserve after the download, including the downloads in progress.
+type diskReserve struct {
+	mu       sync.Mutex
+	reserve  int64
+	maxUsage int64
+
+	// inflight is the total size of the downloads in progress. It is an
+	// overestimate of the space they will use in addition to the current free
+	// space, since they have written some of their content already.
+	inflight int64
+}
+
+// waitDiskSpace waits until the filesystem of the given destination has enough
+// free space to download an object of the given size without using more than
+// maxUsage bytes in total. The downloads are resumed once other tenants of the
+// filesystem, or the consumers of the downloaded files, free some space. The
+// returned function must be called once the download is finished.
+func waitDiskSpace(ctx context.Context, fs *storage.Filesystem, dst string, size, maxUsage int64) (func(), error) {
+	free, device, err := fs.DiskSpace(dst)
+	if err == storage.ErrDiskSpaceNotSupported {
+		return func() {}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	reserve := diskReserves.get(device, free, maxUsage)
+	if size > reserve.maxUsage {
+		return nil, fmt.Errorf(
+			"object size %v exceeds the maximum disk usage %v",
+			strutil.HumanizeBytes(size), strutil.HumanizeBytes(reserve.maxUsage),
+		)
+	}
+
+	for paused := false; ; paused = true {
+		if reserve.tryAcquire(free, size) {
+			return func() { reserve.release(size) }, nil
+		}
+
+		if !paused {
+			log.Debug(log.DebugMessage{
+				Err: fmt.Sprintf("pausing download to %v until disk space is freed", dst),
+			})
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(diskSpacePollInterval):
+		}
+
+		free, _, err = fs.DiskSpace(dst)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (r *diskReserve) tryAcquire(free, size int64) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if free-r.inflight-size < r.reserve {
+		return false
+	}
+
+	r.inflight += size
+	return true
+}
+
+func (r *diskReserve) release(size int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.inflight -= size
+}
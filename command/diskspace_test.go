@@ -0,0 +1,54 @@
+package command
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/peak/s5cmd/v2/storage"
+)
+
+func TestDiskReserveTryAcquire(t *testing.T) {
+	// 1000 bytes are free at the start and the downloads can use 600 bytes.
+	r := &diskReserve{reserve: 400, maxUsage: 600}
+
+	assert.Assert(t, r.tryAcquire(1000, 500))
+
+	// the download in progress is taken into account even if it hasn't
+	// written anything yet.
+	assert.Assert(t, !r.tryAcquire(1000, 200))
+	assert.Assert(t, r.tryAcquire(1000, 100))
+
+	// the downloads are finished and used the space.
+	r.release(500)
+	r.release(100)
+	assert.Assert(t, !r.tryAcquire(400, 1))
+
+	// another tenant freed some space.
+	assert.Assert(t, r.tryAcquire(700, 300))
+}
+
+func TestWaitDiskSpaceObjectLargerThanMaxUsage(t *testing.T) {
+	fs := storage.NewLocalClient(storage.Options{})
+
+	_, err := waitDiskSpace(context.Background(), fs, t.TempDir(), 2048, 1024)
+	if err == nil {
+		t.Skip("querying free disk space is not supported")
+	}
+	assert.ErrorContains(t, err, "exceeds the maximum disk usage")
+}
+
+func TestCheckDiskSpace(t *testing.T) {
+	fs := storage.NewLocalClient(storage.Options{})
+	dir := t.TempDir()
+
+	assert.NilError(t, checkDiskSpace(fs, dir, 1))
+
+	err := checkDiskSpace(fs, dir, 1<<50)
+	if err == nil {
+		t.Skip("querying free disk space is not supported")
+	}
+	assert.Assert(t, strings.HasPrefix(err.Error(), "not enough disk space on the destination: 1024.0T required"), err)
+}
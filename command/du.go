@@ -3,6 +3,11 @@ package command
 import (
 	"context"
 	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
 
 	urlpkg "net/url"
 
@@ -47,8 +52,31 @@ Examples:
 	
 	7. Show disk usage of a specific version of an object in the bucket
 		 > s5cmd {{.HelpName}} --version-id VERSION_ID s3://bucket/object
+
+	8. Report the distribution of objects and bytes across storage classes with percentages
+		 > s5cmd {{.HelpName}} --storage-class-report "s3://bucket/prefix/*"
+
+	9. Report the number of objects and bytes in the default size ranges (<1KB, 1KB-1MB, 1MB-100MB, >=100MB)
+		 > s5cmd {{.HelpName}} --object-size-histogram "s3://bucket/*"
+
+	10. Report the number of objects and bytes in custom size ranges (<64KB, 64KB-8MB, >=8MB)
+		 > s5cmd {{.HelpName}} --object-size-histogram --buckets 64KB,8MB "s3://bucket/*"
+
+	11. Show disk usage of all objects in a bucket including the parts of the incomplete multipart uploads
+		 > s5cmd {{.HelpName}} --include-mpu "s3://bucket/*"
+
+	12. Show disk usage of the objects in a bucket which are not in STANDARD storage class
+		 > s5cmd {{.HelpName}} --storage-class-filter '!STANDARD' "s3://bucket/*"
 `
 
+// defaultHistogramBuckets are the upper bounds of the size ranges of the
+// object size histogram.
+const defaultHistogramBuckets = "1KB,1MB,100MB"
+
+// listPartsConcurrency is the number of the multipart uploads whose parts are
+// listed at the same time with --include-mpu.
+const listPartsConcurrency = 16
+
 func NewSizeCommand() *cli.Command {
 	cmd := &cli.Command{
 		Name:               "du",
@@ -61,6 +89,19 @@ func NewSizeCommand() *cli.Command {
 				Aliases: []string{"g"},
 				Usage:   "group sizes by storage class",
 			},
+			&cli.BoolFlag{
+				Name:  "storage-class-report",
+				Usage: "report the number of objects and bytes in each storage class along with their percentages, sorted by size",
+			},
+			&cli.BoolFlag{
+				Name:  "object-size-histogram",
+				Usage: "report the number of objects and bytes in each size range along with their percentages",
+			},
+			&cli.StringFlag{
+				Name:  "buckets",
+				Usage: "comma-separated upper bounds of the size ranges of the object size histogram",
+				Value: defaultHistogramBuckets,
+			},
 			&cli.BoolFlag{
 				Name:    "humanize",
 				Aliases: []string{"H"},
@@ -78,6 +119,11 @@ func NewSizeCommand() *cli.Command {
 				Name:  "version-id",
 				Usage: "use the specified version of an object",
 			},
+			&cli.BoolFlag{
+				Name:  "include-mpu",
+				Usage: "include the parts of the incomplete multipart uploads as a separate line and in the total",
+			},
+			newStorageClassFilterFlag(),
 		},
 		Before: func(c *cli.Context) error {
 			err := validateDUCommand(c)
@@ -99,14 +145,22 @@ func NewSizeCommand() *cli.Command {
 				return err
 			}
 
+			// the bounds are validated before.
+			histogramBounds, _ := parseHistogramBuckets(c.String("buckets"))
+
 			return Size{
 				src:         srcurl,
 				op:          c.Command.Name,
 				fullCommand: fullCommand,
 				// flags
-				groupByClass: c.Bool("group"),
-				humanize:     c.Bool("humanize"),
-				exclude:      c.StringSlice("exclude"),
+				groupByClass:       c.Bool("group"),
+				storageClassReport: c.Bool("storage-class-report"),
+				sizeHistogram:      c.Bool("object-size-histogram"),
+				histogramBounds:    histogramBounds,
+				humanize:           c.Bool("humanize"),
+				exclude:            c.StringSlice("exclude"),
+				includeMPU:         c.Bool("include-mpu"),
+				storageClasses:     newStorageClassFilter(c),
 
 				storageOpts: NewStorageOpts(c),
 			}.Run(c.Context)
@@ -124,9 +178,14 @@ type Size struct {
 	fullCommand string
 
 	// flags
-	groupByClass bool
-	humanize     bool
-	exclude      []string
+	groupByClass       bool
+	storageClassReport bool
+	sizeHistogram      bool
+	histogramBounds    []histogramBound
+	humanize           bool
+	exclude            []string
+	includeMPU         bool
+	storageClasses     *storageClassFilter
 
 	storageOpts storage.Options
 }
@@ -141,6 +200,8 @@ func (sz Size) Run(ctx context.Context) error {
 
 	storageTotal := map[string]sizeAndCount{}
 	total := sizeAndCount{}
+	// the last range of the histogram has no upper bound.
+	histogram := make([]sizeAndCount, len(sz.histogramBounds)+1)
 
 	var merror error
 
@@ -165,20 +226,51 @@ func (sz Size) Run(ctx context.Context) error {
 			continue
 		}
 
+		if !sz.storageClasses.match(object.URL, object.StorageClass) {
+			continue
+		}
+
 		storageClass := string(object.StorageClass)
 		s := storageTotal[storageClass]
 		s.addObject(object)
 		storageTotal[storageClass] = s
 
 		total.addObject(object)
+
+		if sz.sizeHistogram {
+			histogram[histogramRange(sz.histogramBounds, object.Size)].addObject(object)
+		}
+	}
+
+	if sz.sizeHistogram {
+		log.Info(newObjectSizeHistogramMessage(sz.src, sz.histogramBounds, histogram, total, sz.humanize))
+		return merror
+	}
+
+	if sz.storageClassReport {
+		log.Info(newStorageClassReportMessage(sz.src, storageTotal, total, sz.humanize))
+		return merror
+	}
+
+	// the parts of the incomplete multipart uploads are billed, even though
+	// they are not listed as objects.
+	var uploads MultipartUploadSizeMessage
+	if sz.includeMPU {
+		var err error
+		uploads, err = sz.multipartUploadSize(ctx, excludePatterns)
+		if err != nil {
+			merror = multierror.Append(merror, err)
+		}
+		log.Info(uploads)
 	}
 
 	if !sz.groupByClass {
 		msg := SizeMessage{
-			Source:        sz.src.String(),
-			Count:         total.count,
-			Size:          total.size,
-			showHumanized: sz.humanize,
+			Source:           sz.src.String(),
+			Count:            total.count,
+			Size:             total.size + uploads.Size,
+			MultipartUploads: uploads.Uploads,
+			showHumanized:    sz.humanize,
 		}
 		log.Info(msg)
 		return nil
@@ -197,12 +289,88 @@ func (sz Size) Run(ctx context.Context) error {
 	return merror
 }
 
+// multipartUploadSize returns the number and the total size of the parts of
+// the incomplete multipart uploads whose keys match the source. The parts of
+// the uploads are listed concurrently.
+func (sz Size) multipartUploadSize(
+	ctx context.Context,
+	excludePatterns []*regexp.Regexp,
+) (MultipartUploadSizeMessage, error) {
+	msg := MultipartUploadSizeMessage{
+		Source:        sz.src.String(),
+		showHumanized: sz.humanize,
+	}
+
+	client, err := storage.NewRemoteClient(ctx, sz.src, sz.storageOpts)
+	if err != nil {
+		printError(sz.fullCommand, sz.op, err)
+		return msg, err
+	}
+
+	var (
+		mu        sync.Mutex
+		wg        sync.WaitGroup
+		merror    error
+		semaphore = make(chan struct{}, listPartsConcurrency)
+	)
+
+	fail := func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		merror = multierror.Append(merror, err)
+		printError(sz.fullCommand, sz.op, err)
+	}
+
+	for upload := range client.ListMultipartUploads(ctx, sz.src) {
+		if errorpkg.IsCancelation(upload.Err) {
+			continue
+		}
+
+		if err := upload.Err; err != nil {
+			fail(err)
+			continue
+		}
+
+		if isURLExcluded(excludePatterns, upload.URL.Path, sz.src.Prefix) ||
+			!sz.storageClasses.match(upload.URL, upload.StorageClass) {
+			continue
+		}
+
+		semaphore <- struct{}{}
+		wg.Add(1)
+		go func(upload *storage.MultipartUpload) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			parts, size, err := client.UploadedParts(ctx, upload)
+			if err != nil {
+				if !errorpkg.IsCancelation(err) {
+					fail(err)
+				}
+				return
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			msg.Uploads++
+			msg.Parts += parts
+			msg.Size += size
+		}(upload)
+	}
+	wg.Wait()
+
+	return msg, merror
+}
+
 // SizeMessage is the structure for logging disk usage.
 type SizeMessage struct {
 	Source       string `json:"source"`
 	StorageClass string `json:"storage_class,omitempty"`
 	Count        int64  `json:"count"`
 	Size         int64  `json:"size"`
+	// MultipartUploads is the number of the incomplete multipart uploads
+	// whose parts are included in Size.
+	MultipartUploads int64 `json:"multipart_uploads,omitempty"`
 
 	showHumanized bool
 }
@@ -221,10 +389,15 @@ func (s SizeMessage) String() string {
 	if s.StorageClass != "" {
 		storageCls = fmt.Sprintf(" [%s]", s.StorageClass)
 	}
+	var uploads string
+	if s.MultipartUploads > 0 {
+		uploads = fmt.Sprintf(" and %d incomplete multipart uploads", s.MultipartUploads)
+	}
 	return fmt.Sprintf(
-		"%s bytes in %d objects: %s%s",
+		"%s bytes in %d objects%s: %s%s",
 		s.humanize(),
 		s.Count,
+		uploads,
 		s.Source,
 		storageCls,
 	)
@@ -235,6 +408,313 @@ func (s SizeMessage) JSON() string {
 	return strutil.JSON(s)
 }
 
+// MultipartUploadSizeMessage is the structure for logging the size of the
+// parts of the incomplete multipart uploads.
+type MultipartUploadSizeMessage struct {
+	Source  string `json:"source"`
+	Uploads int64  `json:"multipart_uploads"`
+	Parts   int64  `json:"parts"`
+	Size    int64  `json:"size"`
+
+	showHumanized bool
+}
+
+// String returns the string representation of MultipartUploadSizeMessage.
+func (s MultipartUploadSizeMessage) String() string {
+	size := fmt.Sprintf("%d", s.Size)
+	if s.showHumanized {
+		size = strutil.HumanizeBytes(s.Size)
+	}
+	return fmt.Sprintf(
+		"%s bytes in %d parts of %d incomplete multipart uploads: %s",
+		size,
+		s.Parts,
+		s.Uploads,
+		s.Source,
+	)
+}
+
+// JSON returns the JSON representation of MultipartUploadSizeMessage.
+func (s MultipartUploadSizeMessage) JSON() string {
+	return strutil.JSON(s)
+}
+
+// StorageClassReportMessage is the structure for logging the distribution of
+// objects and bytes across storage classes.
+type StorageClassReportMessage struct {
+	Source         string              `json:"source"`
+	Count          int64               `json:"count"`
+	Size           int64               `json:"size"`
+	StorageClasses []StorageClassUsage `json:"storage_classes"`
+
+	showHumanized bool
+}
+
+// StorageClassUsage is the usage of a single storage class in a storage class
+// report.
+type StorageClassUsage struct {
+	StorageClass    string  `json:"storage_class"`
+	Count           int64   `json:"count"`
+	Size            int64   `json:"size"`
+	CountPercentage float64 `json:"count_percentage"`
+	SizePercentage  float64 `json:"size_percentage"`
+}
+
+func newStorageClassReportMessage(
+	src *url.URL,
+	storageTotal map[string]sizeAndCount,
+	total sizeAndCount,
+	humanize bool,
+) StorageClassReportMessage {
+	// S3 omits the storage class of STANDARD objects in some responses.
+	if v, ok := storageTotal[""]; ok && src.IsRemote() {
+		standard := storageTotal["STANDARD"]
+		standard.size += v.size
+		standard.count += v.count
+		storageTotal["STANDARD"] = standard
+		delete(storageTotal, "")
+	}
+
+	usages := make([]StorageClassUsage, 0, len(storageTotal))
+	for class, v := range storageTotal {
+		usages = append(usages, StorageClassUsage{
+			StorageClass:    class,
+			Count:           v.count,
+			Size:            v.size,
+			CountPercentage: percentage(v.count, total.count),
+			SizePercentage:  percentage(v.size, total.size),
+		})
+	}
+
+	// the storage classes that hold the most bytes come first.
+	sort.Slice(usages, func(i, j int) bool {
+		if usages[i].Size != usages[j].Size {
+			return usages[i].Size > usages[j].Size
+		}
+		return usages[i].StorageClass < usages[j].StorageClass
+	})
+
+	return StorageClassReportMessage{
+		Source:         src.String(),
+		Count:          total.count,
+		Size:           total.size,
+		StorageClasses: usages,
+		showHumanized:  humanize,
+	}
+}
+
+// percentage returns the percentage of part in total, rounded to two decimal
+// places.
+func percentage(part, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(float64(part)*10000/float64(total)) / 100
+}
+
+// humanize is a helper method to humanize bytes.
+func (s StorageClassReportMessage) humanize(size int64) string {
+	if s.showHumanized {
+		return strutil.HumanizeBytes(size)
+	}
+	return fmt.Sprintf("%d", size)
+}
+
+// String returns the string representation of StorageClassReportMessage as a
+// table.
+func (s StorageClassReportMessage) String() string {
+	const rowFormat = "%-20s %12s %8s %12s %8s"
+
+	lines := []string{
+		fmt.Sprintf(rowFormat, "STORAGE CLASS", "OBJECTS", "OBJECTS%", "SIZE", "SIZE%"),
+	}
+	for _, u := range s.StorageClasses {
+		class := u.StorageClass
+		if class == "" {
+			class = "-"
+		}
+		lines = append(lines, fmt.Sprintf(
+			rowFormat,
+			class,
+			fmt.Sprintf("%d", u.Count),
+			fmt.Sprintf("%.2f%%", u.CountPercentage),
+			s.humanize(u.Size),
+			fmt.Sprintf("%.2f%%", u.SizePercentage),
+		))
+	}
+
+	var totalPercentage float64
+	if s.Count > 0 {
+		totalPercentage = 100
+	}
+	lines = append(lines, fmt.Sprintf(
+		rowFormat,
+		"TOTAL",
+		fmt.Sprintf("%d", s.Count),
+		fmt.Sprintf("%.2f%%", totalPercentage),
+		s.humanize(s.Size),
+		fmt.Sprintf("%.2f%%", totalPercentage),
+	))
+	return strings.Join(lines, "\n")
+}
+
+// JSON returns the JSON representation of StorageClassReportMessage.
+func (s StorageClassReportMessage) JSON() string {
+	return strutil.JSON(s)
+}
+
+// histogramBound is an upper bound of a size range of the object size
+// histogram.
+type histogramBound struct {
+	// name is the bound as it is given, such as "1KB".
+	name string
+	size int64
+}
+
+// parseHistogramBuckets parses the comma-separated, increasing upper bounds of
+// the size ranges of the object size histogram.
+func parseHistogramBuckets(s string) ([]histogramBound, error) {
+	var bounds []histogramBound
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		size, err := strutil.ParseBytes(name)
+		if err != nil {
+			return nil, err
+		}
+		if size <= 0 {
+			return nil, fmt.Errorf("histogram bucket %q must be greater than zero", name)
+		}
+		if len(bounds) > 0 && size <= bounds[len(bounds)-1].size {
+			return nil, fmt.Errorf("histogram buckets must be in increasing order: %q", s)
+		}
+		bounds = append(bounds, histogramBound{name: name, size: size})
+	}
+	return bounds, nil
+}
+
+// histogramRange returns the index of the size range which the given size
+// falls into. The ranges include their lower bounds and exclude their upper
+// bounds.
+func histogramRange(bounds []histogramBound, size int64) int {
+	return sort.Search(len(bounds), func(i int) bool {
+		return size < bounds[i].size
+	})
+}
+
+// ObjectSizeHistogramMessage is the structure for logging the distribution of
+// objects and bytes across size ranges.
+type ObjectSizeHistogramMessage struct {
+	Source string           `json:"source"`
+	Count  int64            `json:"count"`
+	Size   int64            `json:"size"`
+	Ranges []SizeRangeUsage `json:"ranges"`
+
+	showHumanized bool
+}
+
+// SizeRangeUsage is the usage of a single size range in an object size
+// histogram. Min is inclusive and Max is exclusive, the last range has no Max.
+type SizeRangeUsage struct {
+	Range           string  `json:"range"`
+	Min             int64   `json:"min"`
+	Max             *int64  `json:"max,omitempty"`
+	Count           int64   `json:"count"`
+	Size            int64   `json:"size"`
+	CountPercentage float64 `json:"count_percentage"`
+	SizePercentage  float64 `json:"size_percentage"`
+}
+
+func newObjectSizeHistogramMessage(
+	src *url.URL,
+	bounds []histogramBound,
+	histogram []sizeAndCount,
+	total sizeAndCount,
+	humanize bool,
+) ObjectSizeHistogramMessage {
+	usages := make([]SizeRangeUsage, 0, len(histogram))
+	for i, v := range histogram {
+		usage := SizeRangeUsage{
+			Count:           v.count,
+			Size:            v.size,
+			CountPercentage: percentage(v.count, total.count),
+			SizePercentage:  percentage(v.size, total.size),
+		}
+
+		switch {
+		case i == 0:
+			usage.Range = "<" + bounds[i].name
+		case i == len(bounds):
+			usage.Range = ">=" + bounds[i-1].name
+		default:
+			usage.Range = bounds[i-1].name + "-" + bounds[i].name
+		}
+		if i > 0 {
+			usage.Min = bounds[i-1].size
+		}
+		if i < len(bounds) {
+			max := bounds[i].size
+			usage.Max = &max
+		}
+
+		usages = append(usages, usage)
+	}
+
+	return ObjectSizeHistogramMessage{
+		Source:        src.String(),
+		Count:         total.count,
+		Size:          total.size,
+		Ranges:        usages,
+		showHumanized: humanize,
+	}
+}
+
+// humanize is a helper method to humanize bytes.
+func (s ObjectSizeHistogramMessage) humanize(size int64) string {
+	if s.showHumanized {
+		return strutil.HumanizeBytes(size)
+	}
+	return fmt.Sprintf("%d", size)
+}
+
+// String returns the string representation of ObjectSizeHistogramMessage as
+// a table.
+func (s ObjectSizeHistogramMessage) String() string {
+	const rowFormat = "%-20s %12s %8s %12s %8s"
+
+	lines := []string{
+		fmt.Sprintf(rowFormat, "SIZE RANGE", "OBJECTS", "OBJECTS%", "SIZE", "SIZE%"),
+	}
+	for _, u := range s.Ranges {
+		lines = append(lines, fmt.Sprintf(
+			rowFormat,
+			u.Range,
+			fmt.Sprintf("%d", u.Count),
+			fmt.Sprintf("%.2f%%", u.CountPercentage),
+			s.humanize(u.Size),
+			fmt.Sprintf("%.2f%%", u.SizePercentage),
+		))
+	}
+
+	var totalPercentage float64
+	if s.Count > 0 {
+		totalPercentage = 100
+	}
+	lines = append(lines, fmt.Sprintf(
+		rowFormat,
+		"TOTAL",
+		fmt.Sprintf("%d", s.Count),
+		fmt.Sprintf("%.2f%%", totalPercentage),
+		s.humanize(s.Size),
+		fmt.Sprintf("%.2f%%", totalPercentage),
+	))
+	return strings.Join(lines, "\n")
+}
+
+// JSON returns the JSON representation of ObjectSizeHistogramMessage.
+func (s ObjectSizeHistogramMessage) JSON() string {
+	return strutil.JSON(s)
+}
+
 type sizeAndCount struct {
 	size  int64
 	count int64
@@ -254,6 +734,32 @@ func validateDUCommand(c *cli.Context) error {
 		return err
 	}
 
+	if c.Bool("group") && c.Bool("storage-class-report") {
+		return fmt.Errorf("it is not allowed to combine %q and %q flags", "group", "storage-class-report")
+	}
+
+	if c.Bool("object-size-histogram") {
+		for _, flag := range []string{"group", "storage-class-report"} {
+			if c.Bool(flag) {
+				return fmt.Errorf("it is not allowed to combine %q and %q flags", flag, "object-size-histogram")
+			}
+		}
+	} else if c.IsSet("buckets") {
+		return fmt.Errorf("%q flag can only be used with %q flag", "buckets", "object-size-histogram")
+	}
+
+	if c.Bool("include-mpu") {
+		for _, flag := range []string{"storage-class-report", "object-size-histogram"} {
+			if c.Bool(flag) {
+				return fmt.Errorf("it is not allowed to combine %q and %q flags", flag, "include-mpu")
+			}
+		}
+	}
+
+	if _, err := parseHistogramBuckets(c.String("buckets")); err != nil {
+		return fmt.Errorf("invalid buckets: %w", err)
+	}
+
 	srcurl, err := url.New(c.Args().First(),
 		url.WithAllVersions(c.Bool("all-versions")))
 	if err != nil {
@@ -264,6 +770,14 @@ func validateDUCommand(c *cli.Context) error {
 		return err
 	}
 
+	if c.Bool("include-mpu") && !srcurl.IsRemote() {
+		return fmt.Errorf("%q flag can only be used with remote sources", "include-mpu")
+	}
+
+	if err := validateStorageClassFilter(c, srcurl); err != nil {
+		return err
+	}
+
 	// the "all-versions" flag of du command works with GCS, because it does not
 	// depend on the generation numbers.
 	endpoint, err := urlpkg.Parse(c.String("endpoint-url"))
@@ -0,0 +1,128 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/peak/s5cmd/v2/storage"
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+func TestStorageClassReportMessage(t *testing.T) {
+	src, err := url.New("s3://bucket/prefix/*")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	storageTotal := map[string]sizeAndCount{
+		"":            {size: 100, count: 2},
+		"STANDARD":    {size: 200, count: 2},
+		"GLACIER":     {size: 600, count: 1},
+		"STANDARD_IA": {size: 100, count: 1},
+	}
+	total := sizeAndCount{size: 1000, count: 6}
+
+	msg := newStorageClassReportMessage(src, storageTotal, total, false)
+
+	expected := []StorageClassUsage{
+		{StorageClass: "GLACIER", Count: 1, Size: 600, CountPercentage: 16.67, SizePercentage: 60},
+		{StorageClass: "STANDARD", Count: 4, Size: 300, CountPercentage: 66.67, SizePercentage: 30},
+		{StorageClass: "STANDARD_IA", Count: 1, Size: 100, CountPercentage: 16.67, SizePercentage: 10},
+	}
+	if diff := cmp.Diff(expected, msg.StorageClasses); diff != "" {
+		t.Errorf("(-want +got):\n%v", diff)
+	}
+
+	expectedTable := "" +
+		"STORAGE CLASS             OBJECTS OBJECTS%         SIZE    SIZE%\n" +
+		"GLACIER                         1   16.67%          600   60.00%\n" +
+		"STANDARD                        4   66.67%          300   30.00%\n" +
+		"STANDARD_IA                     1   16.67%          100   10.00%\n" +
+		"TOTAL                           6  100.00%         1000  100.00%"
+	if diff := cmp.Diff(expectedTable, msg.String()); diff != "" {
+		t.Errorf("(-want +got):\n%v", diff)
+	}
+}
+
+func TestObjectSizeHistogramMessage(t *testing.T) {
+	src, err := url.New("s3://bucket/prefix/*")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bounds, err := parseHistogramBuckets("1KB, 1MB")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	histogram := make([]sizeAndCount, len(bounds)+1)
+	total := sizeAndCount{}
+	for _, size := range []int64{0, 1023, 1024, 5000, 1 << 20, 1 << 30} {
+		obj := &storage.Object{Size: size}
+		histogram[histogramRange(bounds, size)].addObject(obj)
+		total.addObject(obj)
+	}
+
+	msg := newObjectSizeHistogramMessage(src, bounds, histogram, total, false)
+
+	kb, mb := int64(1<<10), int64(1<<20)
+	expected := []SizeRangeUsage{
+		{Range: "<1KB", Min: 0, Max: &kb, Count: 2, Size: 1023, CountPercentage: 33.33, SizePercentage: 0},
+		{Range: "1KB-1MB", Min: kb, Max: &mb, Count: 2, Size: 6024, CountPercentage: 33.33, SizePercentage: 0},
+		{Range: ">=1MB", Min: mb, Count: 2, Size: 1<<20 + 1<<30, CountPercentage: 33.33, SizePercentage: 100},
+	}
+	if diff := cmp.Diff(expected, msg.Ranges); diff != "" {
+		t.Errorf("(-want +got):\n%v", diff)
+	}
+
+	expectedTable := "" +
+		"SIZE RANGE                OBJECTS OBJECTS%         SIZE    SIZE%\n" +
+		"<1KB                            2   33.33%         1023    0.00%\n" +
+		"1KB-1MB                         2   33.33%         6024    0.00%\n" +
+		">=1MB                           2   33.33%   1074790400  100.00%\n" +
+		"TOTAL                           6  100.00%   1074797447  100.00%"
+	if diff := cmp.Diff(expectedTable, msg.String()); diff != "" {
+		t.Errorf("(-want +got):\n%v", diff)
+	}
+}
+
+func TestParseHistogramBuckets(t *testing.T) {
+	testcases := []struct {
+		buckets   string
+		expected  []int64
+		expectErr bool
+	}{
+		{buckets: defaultHistogramBuckets, expected: []int64{1 << 10, 1 << 20, 100 << 20}},
+		{buckets: "512", expected: []int64{512}},
+		{buckets: "1MB,1KB", expectErr: true},
+		{buckets: "1KB,1KB", expectErr: true},
+		{buckets: "0,1KB", expectErr: true},
+		{buckets: "1KB,", expectErr: true},
+		{buckets: "lots", expectErr: true},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.buckets, func(t *testing.T) {
+			bounds, err := parseHistogramBuckets(tc.buckets)
+			if tc.expectErr {
+				if err == nil {
+					t.Fatalf("expected error, got %v", bounds)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var sizes []int64
+			for _, bound := range bounds {
+				sizes = append(sizes, bound.size)
+			}
+			if diff := cmp.Diff(tc.expected, sizes); diff != "" {
+				t.Errorf("(-want +got):\n%v", diff)
+			}
+		})
+	}
+}
@@ -27,13 +27,16 @@ func printDebug(op string, err error, urls ...*url.URL) {
 	log.Debug(msg)
 }
 
-// printError is the helper function to log error messages.
+// printError is the helper function to log error messages. The errors are
+// recorded to the summary of the failures of the command as well.
 func printError(command, op string, err error) {
 	// dont print cancelation errors
 	if errorpkg.IsCancelation(err) {
 		return
 	}
 
+	failures.add(err, command)
+
 	// check if we have our own error type
 	{
 		cerr, ok := err.(*errorpkg.Error)
@@ -0,0 +1,233 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/go-multierror"
+
+	errorpkg "github.com/peak/s5cmd/v2/error"
+	"github.com/peak/s5cmd/v2/log"
+	"github.com/peak/s5cmd/v2/storage"
+	"github.com/peak/s5cmd/v2/strutil"
+)
+
+// maxErrorExamples is the number of the failures kept as the examples of an
+// error code.
+const maxErrorExamples = 3
+
+// failures is the summary of the failures reported by the command, which is
+// printed once the command completes.
+var failures = newErrorSummary()
+
+// ErrorGroup is the structure for the failures with the same error code.
+type ErrorGroup struct {
+	Code     string   `json:"code"`
+	Count    int64    `json:"count"`
+	Examples []string `json:"examples,omitempty"`
+}
+
+// ErrorSummaryMessage is the structure for the failures of a command grouped
+// by their error codes, in the descending order of their counts.
+type ErrorSummaryMessage struct {
+	Errors int64        `json:"errors"`
+	Groups []ErrorGroup `json:"groups"`
+}
+
+// String is the string representation of ErrorSummaryMessage.
+func (m ErrorSummaryMessage) String() string {
+	groups := make([]string, 0, len(m.Groups))
+	for _, group := range m.Groups {
+		s := fmt.Sprintf("%v: %v", group.Code, group.Count)
+		if len(group.Examples) > 0 {
+			s += fmt.Sprintf(" (first: %v)", group.Examples[0])
+		}
+		groups = append(groups, s)
+	}
+	return fmt.Sprintf("%v errors: %v", m.Errors, strings.Join(groups, ", "))
+}
+
+// JSON is the JSON representation of ErrorSummaryMessage.
+func (m ErrorSummaryMessage) JSON() string {
+	return strutil.JSON(struct {
+		Operation string `json:"operation"`
+		ErrorSummaryMessage
+	}{
+		Operation:           "error-summary",
+		ErrorSummaryMessage: m,
+	})
+}
+
+// errorSummary collects failures grouped by their error codes. It keeps the
+// number of the failures and the first few examples of each code only, so
+// its memory doesn't grow with the number of failures. It is also the error
+// of the commands whose operations failed.
+type errorSummary struct {
+	mu     sync.Mutex
+	errors int64
+	groups map[string]*ErrorGroup
+}
+
+func newErrorSummary() *errorSummary {
+	return &errorSummary{groups: map[string]*ErrorGroup{}}
+}
+
+// add records the given error. The aggregated errors are recorded one by one.
+// The example of the failure is the URL of the error, or the given example if
+// the error has no URL.
+func (s *errorSummary) add(err error, example string) {
+	if err == nil {
+		return
+	}
+
+	switch err := err.(type) {
+	case *errorSummary:
+		s.merge(err)
+		return
+	case *multierror.Error:
+		for _, err := range err.Errors {
+			s.add(err, example)
+		}
+		return
+	}
+
+	var cerr *errorpkg.Error
+	if errors.As(err, &cerr) {
+		switch {
+		case cerr.Src != nil:
+			example = cerr.Src.String()
+		case cerr.Dst != nil:
+			example = cerr.Dst.String()
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.errors++
+	group := s.group(errorCode(err))
+	group.Count++
+	if example != "" && len(group.Examples) < maxErrorExamples {
+		group.Examples = append(group.Examples, example)
+	}
+}
+
+// merge records the failures of the given summary.
+func (s *errorSummary) merge(other *errorSummary) {
+	if other == s {
+		return
+	}
+	msg := other.message()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.errors += msg.Errors
+	for _, g := range msg.Groups {
+		group := s.group(g.Code)
+		group.Count += g.Count
+		for _, example := range g.Examples {
+			if len(group.Examples) < maxErrorExamples {
+				group.Examples = append(group.Examples, example)
+			}
+		}
+	}
+}
+
+// group returns the group of the given code. The caller must hold s.mu.
+func (s *errorSummary) group(code string) *ErrorGroup {
+	group, ok := s.groups[code]
+	if !ok {
+		group = &ErrorGroup{Code: code}
+		s.groups[code] = group
+	}
+	return group
+}
+
+// count returns the number of the failures.
+func (s *errorSummary) count() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.errors
+}
+
+// message returns the summary of the failures.
+func (s *errorSummary) message() ErrorSummaryMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	msg := ErrorSummaryMessage{
+		Errors: s.errors,
+		Groups: make([]ErrorGroup, 0, len(s.groups)),
+	}
+	for _, group := range s.groups {
+		g := *group
+		g.Examples = append([]string(nil), group.Examples...)
+		msg.Groups = append(msg.Groups, g)
+	}
+	sort.Slice(msg.Groups, func(i, j int) bool {
+		if msg.Groups[i].Count != msg.Groups[j].Count {
+			return msg.Groups[i].Count > msg.Groups[j].Count
+		}
+		return msg.Groups[i].Code < msg.Groups[j].Code
+	})
+	return msg
+}
+
+// err returns the summary as an error if there are failures, and nil
+// otherwise.
+func (s *errorSummary) err() error {
+	if s.count() == 0 {
+		return nil
+	}
+	return s
+}
+
+// Error implements the error interface.
+func (s *errorSummary) Error() string {
+	return s.message().String()
+}
+
+// errorCode returns the code the given failure is grouped by: the code of the
+// S3 API error, or a code describing the local error.
+func errorCode(err error) string {
+	// a request which exceeds the deadline of its object is canceled, check
+	// the deadline before the code of the request.
+	if errorpkg.IsTimeout(err) {
+		return "ObjectTimeout"
+	}
+	if code := storage.ErrorCode(err); code != "" {
+		return code
+	}
+
+	var objNotFound *storage.ErrGivenObjectNotFound
+	switch {
+	case errors.Is(err, storage.ErrNoObjectFound), errors.As(err, &objNotFound):
+		return "NotFound"
+	case errorpkg.IsTypeConflict(err):
+		return "TypeConflict"
+	case errors.Is(err, fs.ErrPermission):
+		return "PermissionDenied"
+	case errors.Is(err, fs.ErrNotExist):
+		return "NoSuchFile"
+	case errors.Is(err, context.Canceled):
+		return "Canceled"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "DeadlineExceeded"
+	}
+	return "Other"
+}
+
+// printErrorSummary prints the failures of the command grouped by their error
+// codes, if more than one operation failed. A single failure is printed on its
+// own already.
+func printErrorSummary() {
+	if failures.count() > 1 {
+		log.Error(failures.message())
+	}
+}
@@ -0,0 +1,129 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/google/go-cmp/cmp"
+	"github.com/hashicorp/go-multierror"
+
+	errorpkg "github.com/peak/s5cmd/v2/error"
+	"github.com/peak/s5cmd/v2/storage"
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+func newTestErrorSummary(errs ...error) error {
+	summary := newErrorSummary()
+	for _, err := range errs {
+		summary.add(err, "")
+	}
+	return summary.err()
+}
+
+func s3Error(t *testing.T, code, key string) error {
+	t.Helper()
+
+	src, err := url.New("s3://bucket/" + key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &errorpkg.Error{
+		Op:  "cp",
+		Src: src,
+		Err: awserr.New(code, "failed", nil),
+	}
+}
+
+func TestErrorSummary(t *testing.T) {
+	summary := newErrorSummary()
+	for i := 0; i < 5; i++ {
+		summary.add(s3Error(t, "AccessDenied", fmt.Sprintf("denied-%d", i)), "")
+	}
+	summary.add(multierror.Append(
+		s3Error(t, "NoSuchKey", "missing-0"),
+		s3Error(t, "NoSuchKey", "missing-1"),
+	), "")
+	summary.add(newTestErrorSummary(
+		s3Error(t, "AccessDenied", "denied-5"),
+		s3Error(t, "RequestTimeout", "slow-0"),
+	), "")
+	summary.add(fmt.Errorf("invalid input"), "run commands.txt")
+
+	expected := ErrorSummaryMessage{
+		Errors: 10,
+		Groups: []ErrorGroup{
+			{
+				Code:     "AccessDenied",
+				Count:    6,
+				Examples: []string{"s3://bucket/denied-0", "s3://bucket/denied-1", "s3://bucket/denied-2"},
+			},
+			{
+				Code:     "NoSuchKey",
+				Count:    2,
+				Examples: []string{"s3://bucket/missing-0", "s3://bucket/missing-1"},
+			},
+			{Code: "Other", Count: 1, Examples: []string{"run commands.txt"}},
+			{Code: "RequestTimeout", Count: 1, Examples: []string{"s3://bucket/slow-0"}},
+		},
+	}
+	if diff := cmp.Diff(expected, summary.message()); diff != "" {
+		t.Errorf("(-want +got):\n%v", diff)
+	}
+
+	expectedString := "10 errors: AccessDenied: 6 (first: s3://bucket/denied-0), " +
+		"NoSuchKey: 2 (first: s3://bucket/missing-0), Other: 1 (first: run commands.txt), " +
+		"RequestTimeout: 1 (first: s3://bucket/slow-0)"
+	if got := summary.Error(); got != expectedString {
+		t.Errorf("expected %q, got %q", expectedString, got)
+	}
+}
+
+func TestErrorSummaryErr(t *testing.T) {
+	if err := newErrorSummary().err(); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+	if err := newTestErrorSummary(nil); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+	if err := newTestErrorSummary(fmt.Errorf("error")); err == nil {
+		t.Errorf("expected an error")
+	}
+}
+
+func TestErrorCode(t *testing.T) {
+	testcases := []struct {
+		name     string
+		err      error
+		expected string
+	}{
+		{name: "s3 error", err: awserr.New("AccessDenied", "denied", nil), expected: "AccessDenied"},
+		{
+			name:     "wrapped s3 error",
+			err:      &errorpkg.Error{Op: "rm", Err: awserr.New("NoSuchBucket", "no bucket", nil)},
+			expected: "NoSuchBucket",
+		},
+		{
+			name:     "object timeout",
+			err:      fmt.Errorf("%w: %v", errorpkg.ErrObjectTimeout, awserr.New("RequestCanceled", "canceled", nil)),
+			expected: "ObjectTimeout",
+		},
+		{name: "no object found", err: storage.ErrNoObjectFound, expected: "NotFound"},
+		{name: "type conflict", err: fmt.Errorf("dir: %w", errorpkg.ErrTypeConflict), expected: "TypeConflict"},
+		{name: "permission", err: &os.PathError{Op: "open", Path: "file", Err: os.ErrPermission}, expected: "PermissionDenied"},
+		{name: "missing file", err: &os.PathError{Op: "open", Path: "file", Err: os.ErrNotExist}, expected: "NoSuchFile"},
+		{name: "canceled", err: context.Canceled, expected: "Canceled"},
+		{name: "other", err: fmt.Errorf("error"), expected: "Other"},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			if got := errorCode(tc.err); got != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,427 @@
+package command
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+)
+
+var examplesHelpTemplate = `Name:
+	{{.HelpName}} - {{.Usage}}
+
+Usage:
+	{{.HelpName}} [command]
+
+Examples:
+	01. Print the examples of all commands
+		 > s5cmd {{.HelpName}}
+
+	02. Print the examples of sync command
+		 > s5cmd {{.HelpName}} sync
+`
+
+// Example is a curated example of a command. The examples are run on their
+// fixtures by the tests, and their effects are checked, so that they keep
+// working as the flags of the commands change.
+type Example struct {
+	// Category groups the examples of a command.
+	Category string
+	// Description tells what the example does.
+	Description string
+	// Args are the arguments s5cmd is run with, including the global flags.
+	Args []string
+	// Fixture is the state of the storage the example is run on.
+	Fixture ExampleFixture
+	// Effect is the expected state of the storage after the example is run.
+	Effect ExampleEffect
+}
+
+// ExampleFixture is the state of the storage an example is run on.
+type ExampleFixture struct {
+	// Buckets are the buckets which are created before the objects.
+	Buckets []string
+	// Objects are the contents of the objects by their URLs, e.g.
+	// "s3://bucket/key" or "folder/file". The local paths are relative to the
+	// working directory of the example.
+	Objects map[string]string
+}
+
+// ExampleEffect is the expected state of the storage and the output after an
+// example is run.
+type ExampleEffect struct {
+	// Objects are the expected contents of the objects by their URLs.
+	Objects map[string]string
+	// Missing are the URLs of the objects which are expected not to exist.
+	Missing []string
+	// Output are the lines expected in the output, in any order.
+	Output []string
+}
+
+// CommandLine returns the example as it is typed in a shell.
+func (e Example) CommandLine() string {
+	args := make([]string, 0, len(e.Args)+1)
+	args = append(args, "s5cmd")
+	for _, arg := range e.Args {
+		// the wildcards are quoted not to be expanded by the shell.
+		if strings.ContainsAny(arg, "*? ") {
+			arg = fmt.Sprintf("%q", arg)
+		}
+		args = append(args, arg)
+	}
+	return strings.Join(args, " ")
+}
+
+// examples are the curated examples by command name. The examples of a
+// command are printed in the order of their first appearance of their
+// categories.
+var examples = map[string][]Example{
+	"cp": {
+		{
+			Category:    "Filtering",
+			Description: "Download the objects of a bucket except the log files",
+			Args:        []string{"cp", "--exclude", "*.log", "s3://bucket/*", "folder/"},
+			Fixture: ExampleFixture{
+				Buckets: []string{"bucket"},
+				Objects: map[string]string{
+					"s3://bucket/a.txt":     "a",
+					"s3://bucket/debug.log": "log",
+				},
+			},
+			Effect: ExampleEffect{
+				Objects: map[string]string{"folder/a.txt": "a"},
+				Missing: []string{"folder/debug.log"},
+			},
+		},
+		{
+			Category:    "Filtering",
+			Description: "Download the objects under nested prefixes into a single folder",
+			Args:        []string{"cp", "--flatten", "s3://bucket/logs/*", "folder/"},
+			Fixture: ExampleFixture{
+				Buckets: []string{"bucket"},
+				Objects: map[string]string{
+					"s3://bucket/logs/2024/01/a.log": "a",
+					"s3://bucket/logs/2024/02/b.log": "b",
+				},
+			},
+			Effect: ExampleEffect{
+				Objects: map[string]string{
+					"folder/a.log": "a",
+					"folder/b.log": "b",
+				},
+			},
+		},
+		{
+			Category:    "Overwriting",
+			Description: "Download the objects of a bucket without overwriting the existing files",
+			Args:        []string{"cp", "--no-clobber", "s3://bucket/*", "folder/"},
+			Fixture: ExampleFixture{
+				Buckets: []string{"bucket"},
+				Objects: map[string]string{
+					"s3://bucket/a.txt": "remote a",
+					"s3://bucket/b.txt": "remote b",
+					"folder/a.txt":      "local a",
+				},
+			},
+			Effect: ExampleEffect{
+				Objects: map[string]string{
+					"folder/a.txt": "local a",
+					"folder/b.txt": "remote b",
+				},
+			},
+		},
+		{
+			Category:    "Metadata",
+			Description: "Upload a file with its content type and cache control headers",
+			Args:        []string{"cp", "--content-type", "text/plain", "--cache-control", "public, max-age=60", "file.txt", "s3://bucket/file.txt"},
+			Fixture: ExampleFixture{
+				Buckets: []string{"bucket"},
+				Objects: map[string]string{"file.txt": "content"},
+			},
+			Effect: ExampleEffect{
+				Objects: map[string]string{"s3://bucket/file.txt": "content"},
+			},
+		},
+		{
+			Category:    "Previewing",
+			Description: "Print the uploads of a folder without uploading anything",
+			Args:        []string{"--dry-run", "cp", "folder/", "s3://bucket/prefix/"},
+			Fixture: ExampleFixture{
+				Buckets: []string{"bucket"},
+				Objects: map[string]string{"folder/a.txt": "a"},
+			},
+			Effect: ExampleEffect{
+				Missing: []string{"s3://bucket/prefix/a.txt"},
+				Output:  []string{"cp folder/a.txt s3://bucket/prefix/a.txt"},
+			},
+		},
+	},
+	"sync": {
+		{
+			Category:    "Deleting",
+			Description: "Sync folder to S3 bucket and delete the objects which are not in the folder",
+			Args:        []string{"sync", "--delete", "folder/", "s3://bucket/"},
+			Fixture: ExampleFixture{
+				Buckets: []string{"bucket"},
+				Objects: map[string]string{
+					"folder/a.txt":          "a",
+					"s3://bucket/stale.txt": "stale",
+				},
+			},
+			Effect: ExampleEffect{
+				Objects: map[string]string{"s3://bucket/a.txt": "a"},
+				Missing: []string{"s3://bucket/stale.txt"},
+			},
+		},
+		{
+			Category:    "Deleting",
+			Description: "Preview a sync with deletion which skips the log files, without changing anything",
+			Args:        []string{"--dry-run", "sync", "--delete", "--exclude", "*.log", "folder/", "s3://bucket/"},
+			Fixture: ExampleFixture{
+				Buckets: []string{"bucket"},
+				Objects: map[string]string{
+					"folder/a.txt":          "a",
+					"folder/debug.log":      "log",
+					"s3://bucket/stale.txt": "stale",
+				},
+			},
+			Effect: ExampleEffect{
+				Objects: map[string]string{"s3://bucket/stale.txt": "stale"},
+				Missing: []string{"s3://bucket/a.txt", "s3://bucket/debug.log"},
+				Output: []string{
+					"cp folder/a.txt s3://bucket/a.txt",
+					"rm s3://bucket/stale.txt",
+				},
+			},
+		},
+		{
+			Category:    "Between buckets",
+			Description: "Sync the objects under a prefix to a bucket in another region",
+			Args:        []string{"sync", "--source-region", "us-west-2", "--destination-region", "eu-central-1", "s3://bucket/prefix/*", "s3://target-bucket/"},
+			Fixture: ExampleFixture{
+				Buckets: []string{"bucket", "target-bucket"},
+				Objects: map[string]string{
+					"s3://bucket/prefix/a.txt":     "a",
+					"s3://bucket/prefix/dir/b.txt": "b",
+				},
+			},
+			Effect: ExampleEffect{
+				Objects: map[string]string{
+					"s3://target-bucket/a.txt":     "a",
+					"s3://target-bucket/dir/b.txt": "b",
+				},
+			},
+		},
+		{
+			Category:    "Comparing",
+			Description: "Sync S3 bucket to local folder, skipping the files whose sizes match regardless of their contents",
+			Args:        []string{"sync", "--size-only", "s3://bucket/*", "folder/"},
+			Fixture: ExampleFixture{
+				Buckets: []string{"bucket"},
+				Objects: map[string]string{
+					"s3://bucket/same.txt":    "remote",
+					"s3://bucket/changed.txt": "remote content",
+					"folder/same.txt":         "locale",
+					"folder/changed.txt":      "local",
+				},
+			},
+			Effect: ExampleEffect{
+				Objects: map[string]string{
+					"folder/same.txt":    "locale",
+					"folder/changed.txt": "remote content",
+				},
+			},
+		},
+	},
+	"rm": {
+		{
+			Category:    "Filtering",
+			Description: "Delete the log files under a prefix, keeping the other objects",
+			Args:        []string{"rm", "s3://bucket/logs/*.log"},
+			Fixture: ExampleFixture{
+				Buckets: []string{"bucket"},
+				Objects: map[string]string{
+					"s3://bucket/logs/a.log":    "a",
+					"s3://bucket/logs/readme":   "readme",
+					"s3://bucket/logs/01/b.log": "b",
+				},
+			},
+			Effect: ExampleEffect{
+				Objects: map[string]string{"s3://bucket/logs/readme": "readme"},
+				Missing: []string{"s3://bucket/logs/a.log", "s3://bucket/logs/01/b.log"},
+			},
+		},
+		{
+			Category:    "Filtering",
+			Description: "Delete all objects of a bucket except the text files",
+			Args:        []string{"rm", "--exclude", "*.txt", "s3://bucket/*"},
+			Fixture: ExampleFixture{
+				Buckets: []string{"bucket"},
+				Objects: map[string]string{
+					"s3://bucket/a.txt": "a",
+					"s3://bucket/b.gz":  "b",
+				},
+			},
+			Effect: ExampleEffect{
+				Objects: map[string]string{"s3://bucket/a.txt": "a"},
+				Missing: []string{"s3://bucket/b.gz"},
+			},
+		},
+		{
+			Category:    "Previewing",
+			Description: "Print the objects which would be deleted without deleting them",
+			Args:        []string{"--dry-run", "rm", "s3://bucket/*"},
+			Fixture: ExampleFixture{
+				Buckets: []string{"bucket"},
+				Objects: map[string]string{"s3://bucket/a.txt": "a"},
+			},
+			Effect: ExampleEffect{
+				Objects: map[string]string{"s3://bucket/a.txt": "a"},
+				Output:  []string{"rm s3://bucket/a.txt"},
+			},
+		},
+	},
+	"mv": {
+		{
+			Category:    "Between prefixes",
+			Description: "Move the objects under a prefix to another prefix of the same bucket",
+			Args:        []string{"mv", "s3://bucket/tmp/*", "s3://bucket/archive/"},
+			Fixture: ExampleFixture{
+				Buckets: []string{"bucket"},
+				Objects: map[string]string{
+					"s3://bucket/tmp/a.txt":     "a",
+					"s3://bucket/tmp/dir/b.txt": "b",
+				},
+			},
+			Effect: ExampleEffect{
+				Objects: map[string]string{
+					"s3://bucket/archive/a.txt":     "a",
+					"s3://bucket/archive/dir/b.txt": "b",
+				},
+				Missing: []string{"s3://bucket/tmp/a.txt", "s3://bucket/tmp/dir/b.txt"},
+			},
+		},
+	},
+	"ls": {
+		{
+			Category:    "Filtering",
+			Description: "List the objects of a bucket except the log files, with their full paths",
+			Args:        []string{"ls", "--exclude", "*.log", "--show-fullpath", "s3://bucket/*"},
+			Fixture: ExampleFixture{
+				Buckets: []string{"bucket"},
+				Objects: map[string]string{
+					"s3://bucket/a.txt":     "a",
+					"s3://bucket/debug.log": "log",
+				},
+			},
+			Effect: ExampleEffect{
+				Output: []string{"s3://bucket/a.txt"},
+			},
+		},
+	},
+}
+
+// ExampleCommands returns the names of the commands which have examples.
+func ExampleCommands() []string {
+	names := make([]string, 0, len(examples))
+	for name := range examples {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Examples returns the examples of the command.
+func Examples(command string) []Example {
+	return examples[command]
+}
+
+// exampleCategories groups the examples by their categories, in the order
+// of the first appearance of the categories.
+func exampleCategories(examples []Example) ([]string, map[string][]Example) {
+	var categories []string
+	byCategory := map[string][]Example{}
+	for _, example := range examples {
+		if _, ok := byCategory[example.Category]; !ok {
+			categories = append(categories, example.Category)
+		}
+		byCategory[example.Category] = append(byCategory[example.Category], example)
+	}
+	return categories, byCategory
+}
+
+// formatExamples returns the examples of the command in the format of the
+// examples in the help of the commands.
+func formatExamples(command string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Examples of %v:\n", command)
+
+	categories, byCategory := exampleCategories(examples[command])
+	n := 0
+	for _, category := range categories {
+		fmt.Fprintf(&b, "\n%v:\n", category)
+		for i, example := range byCategory[category] {
+			if i > 0 {
+				b.WriteString("\n")
+			}
+			n++
+			fmt.Fprintf(&b, "\t%02d. %v\n\t\t > %v\n", n, example.Description, example.CommandLine())
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// withExamplesHint appends a hint for "s5cmd examples" to the help of the
+// commands which have examples.
+func withExamplesHint(commands []*cli.Command) []*cli.Command {
+	for _, cmd := range commands {
+		if _, ok := examples[cmd.Name]; !ok || cmd.CustomHelpTemplate == "" {
+			continue
+		}
+		cmd.CustomHelpTemplate += "\n\tSee \"s5cmd examples {{.HelpName}}\" for more examples of combining the flags.\n"
+	}
+	return commands
+}
+
+func NewExamplesCommand() *cli.Command {
+	return &cli.Command{
+		Name:               "examples",
+		HelpName:           "examples",
+		Usage:              "print categorized examples of combining the flags of the commands",
+		CustomHelpTemplate: examplesHelpTemplate,
+		Before: func(c *cli.Context) error {
+			err := validateExamplesCommand(c)
+			if err != nil {
+				printError(commandFromContext(c), c.Command.Name, err)
+			}
+			return err
+		},
+		Action: func(c *cli.Context) error {
+			commands := ExampleCommands()
+			if c.Args().Present() {
+				commands = []string{c.Args().First()}
+			}
+
+			formatted := make([]string, 0, len(commands))
+			for _, command := range commands {
+				formatted = append(formatted, formatExamples(command))
+			}
+			fmt.Println(strings.Join(formatted, "\n\n"))
+			return nil
+		},
+	}
+}
+
+func validateExamplesCommand(c *cli.Context) error {
+	if c.Args().Len() > 1 {
+		return fmt.Errorf("expected at most 1 argument")
+	}
+	if c.Args().Present() {
+		if _, ok := examples[c.Args().First()]; !ok {
+			return fmt.Errorf("no examples for %q, the commands with examples are: %v",
+				c.Args().First(), strings.Join(ExampleCommands(), ", "))
+		}
+	}
+	return nil
+}
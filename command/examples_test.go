@@ -0,0 +1,52 @@
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestExampleCommandLine(t *testing.T) {
+	example := Example{
+		Args: []string{"--dry-run", "cp", "--exclude", "*.log", "--cache-control", "max-age=60, public", "s3://bucket/*", "folder/"},
+	}
+	assert.Equal(t,
+		`s5cmd --dry-run cp --exclude "*.log" --cache-control "max-age=60, public" "s3://bucket/*" folder/`,
+		example.CommandLine(),
+	)
+}
+
+func TestExamplesRegistry(t *testing.T) {
+	for _, name := range ExampleCommands() {
+		cmd := AppCommand(name)
+		assert.Assert(t, cmd != nil, "command %q doesn't exist", name)
+
+		for _, example := range Examples(name) {
+			assert.Assert(t, example.Category != "", "example of %q has no category: %v", name, example.Description)
+			// the examples run the command they are listed for.
+			assert.Assert(t, indexOf(example.Args, name) >= 0, "example of %q runs another command: %v", name, example.CommandLine())
+		}
+	}
+}
+
+func TestFormatExamples(t *testing.T) {
+	formatted := formatExamples("sync")
+	lines := strings.Split(formatted, "\n")
+	assert.Equal(t, "Examples of sync:", lines[0])
+	assert.Equal(t, "Deleting:", lines[2])
+	assert.Equal(t, "\t01. "+Examples("sync")[0].Description, lines[3])
+	assert.Equal(t, "\t\t > "+Examples("sync")[0].CommandLine(), lines[4])
+
+	// the examples are numbered across the categories.
+	assert.Assert(t, strings.Contains(formatted, "\t03. "+Examples("sync")[2].Description))
+}
+
+func indexOf(slice []string, s string) int {
+	for i, v := range slice {
+		if v == s {
+			return i
+		}
+	}
+	return -1
+}
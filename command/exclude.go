@@ -42,3 +42,12 @@ func isURLExcluded(excludePatterns []*regexp.Regexp, urlPath, sourcePrefix strin
 	}
 	return false
 }
+
+// isURLIncluded checks whether given urlPath matches any of the include
+// patterns. All paths are included if there are no include patterns.
+func isURLIncluded(includePatterns []*regexp.Regexp, urlPath, sourcePrefix string) bool {
+	if len(includePatterns) == 0 {
+		return true
+	}
+	return isURLExcluded(includePatterns, urlPath, sourcePrefix)
+}
@@ -0,0 +1,248 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/urfave/cli/v2"
+
+	errorpkg "github.com/peak/s5cmd/v2/error"
+	"github.com/peak/s5cmd/v2/log/stat"
+	"github.com/peak/s5cmd/v2/storage"
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+const (
+	// existsExitNotFound is the exit code of the exists command if nothing
+	// exists at the given path.
+	existsExitNotFound = 1
+	// existsExitError is the exit code of the exists command if the existence
+	// could not be checked.
+	existsExitError = 2
+)
+
+// errNotExists is returned by the exists command if nothing exists at the
+// given path. It is not printed.
+var errNotExists = &errorpkg.ExitError{
+	Code: existsExitNotFound,
+	Err:  errors.New("does not exist"),
+}
+
+var existsHelpTemplate = `Name:
+	{{.HelpName}} - {{.Usage}}
+
+Usage:
+	{{.HelpName}} [options] argument
+
+Options:
+	{{range .VisibleFlags}}{{.}}
+	{{end}}
+Exit status:
+	0 if the bucket, the object or the prefix exists, 1 if it does not, 2 if
+	its existence could not be checked.
+
+Examples:
+	1. Check if a bucket exists
+		 > s5cmd {{.HelpName}} s3://bucket
+
+	2. Check if an object exists
+		 > s5cmd {{.HelpName}} s3://bucket/object.gz
+
+	3. Check if there is any object under a prefix
+		 > s5cmd {{.HelpName}} s3://bucket/prefix/
+
+	4. Check if there is any object whose key starts with "2024-"
+		 > s5cmd {{.HelpName}} --prefix s3://bucket/logs/2024-
+
+	5. Check if any object matches a wildcard, stopping at the first match
+		 > s5cmd {{.HelpName}} "s3://bucket/logs/*.gz"
+
+	6. Check if there is any object in a bucket
+		 > s5cmd {{.HelpName}} --prefix s3://bucket
+
+	7. Check if a local file or directory exists
+		 > s5cmd {{.HelpName}} dir/file.txt
+
+	8. Run a command only if an object exists
+		 > s5cmd {{.HelpName}} s3://bucket/_SUCCESS && s5cmd cp "s3://bucket/*" dir/
+`
+
+func NewExistsCommand() *cli.Command {
+	cmd := &cli.Command{
+		Name:               "exists",
+		HelpName:           "exists",
+		Usage:              "check if a bucket, an object or a prefix exists",
+		CustomHelpTemplate: existsHelpTemplate,
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "prefix",
+				Usage: "check if there is any object whose key starts with the given key instead of the object with the given key",
+			},
+		},
+		Before: func(c *cli.Context) error {
+			err := validateExistsCommand(c)
+			if err != nil {
+				printError(commandFromContext(c), c.Command.Name, err)
+				return &errorpkg.ExitError{Code: existsExitError, Err: err}
+			}
+			return nil
+		},
+		Action: func(c *cli.Context) (err error) {
+			defer stat.Collect(c.Command.FullName(), &err)()
+
+			fullCommand := commandFromContext(c)
+
+			srcurl, err := url.New(c.Args().First())
+			if err != nil {
+				printError(fullCommand, c.Command.Name, err)
+				return &errorpkg.ExitError{Code: existsExitError, Err: err}
+			}
+
+			return Exists{
+				src:         srcurl,
+				op:          c.Command.Name,
+				fullCommand: fullCommand,
+				prefix:      c.Bool("prefix"),
+				storageOpts: NewStorageOpts(c),
+			}.Run(c.Context)
+		},
+	}
+
+	// the usage errors are reported with the exit code of the errors, as
+	// the other errors of the command, rather than the one of the missing
+	// objects.
+	cmd.OnUsageError = func(c *cli.Context, err error, _ bool) error {
+		_, _ = fmt.Fprintln(c.App.Writer, "Incorrect Usage:", err.Error())
+		_, _ = fmt.Fprintln(c.App.Writer)
+		_ = cli.ShowCommandHelp(c, cmd.Name)
+		return &errorpkg.ExitError{Code: existsExitError, Err: err}
+	}
+	cmd.BashComplete = getBashCompleteFn(cmd, false, false)
+	return cmd
+}
+
+// Exists holds exists operation flags and states.
+type Exists struct {
+	src         *url.URL
+	op          string
+	fullCommand string
+
+	// flags
+	prefix bool
+
+	storageOpts storage.Options
+}
+
+// Run checks if the source exists. It prints nothing, and returns an error
+// with the exit code of the result if the source does not exist or its
+// existence could not be checked.
+func (e Exists) Run(ctx context.Context) error {
+	exists, err := e.exists(ctx)
+	if err != nil {
+		printError(e.fullCommand, e.op, err)
+		return &errorpkg.ExitError{Code: existsExitError, Err: err}
+	}
+	if !exists {
+		return errNotExists
+	}
+	return nil
+}
+
+func (e Exists) exists(ctx context.Context) (bool, error) {
+	if !e.src.IsRemote() {
+		return e.localExists(ctx)
+	}
+
+	// the region of the bucket is resolved while creating the client.
+	client, err := storage.NewRemoteClient(ctx, e.src, e.storageOpts)
+	if err != nil {
+		if storage.IsNoSuchBucketError(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	switch {
+	case e.src.IsWildcard():
+		return anyMatch(ctx, client, e.src)
+	case e.prefix || e.src.IsPrefix():
+		exists, err := client.HasObjectWithPrefix(ctx, e.src.Bucket, e.src.Path)
+		if storage.IsNoSuchBucketError(err) {
+			return false, nil
+		}
+		return exists, err
+	case e.src.IsBucket():
+		err := client.HeadBucket(ctx, e.src.Bucket)
+		if storage.IsNoSuchBucketError(err) {
+			return false, nil
+		}
+		return err == nil, err
+	default:
+		_, err := client.Stat(ctx, e.src)
+		return objectExists(err)
+	}
+}
+
+func (e Exists) localExists(ctx context.Context) (bool, error) {
+	if e.src.IsWildcard() {
+		matches, err := e.src.Glob()
+		if err != nil {
+			return false, err
+		}
+		return len(matches) > 0, nil
+	}
+
+	_, err := storage.NewLocalClient(e.storageOpts).Stat(ctx, e.src)
+	return objectExists(err)
+}
+
+// anyMatch reports whether any object matches the wildcard of src. The listing
+// is stopped at the first match.
+func anyMatch(ctx context.Context, client storage.Storage, src *url.URL) (bool, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	for object := range client.List(ctx, src, false) {
+		if object.Err == storage.ErrNoObjectFound || storage.IsNoSuchBucketError(object.Err) {
+			return false, nil
+		}
+		if object.Err != nil {
+			return false, object.Err
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+// objectExists converts the error of a Stat call to the result of the exists
+// command.
+func objectExists(err error) (bool, error) {
+	var notFound *storage.ErrGivenObjectNotFound
+	if errors.As(err, &notFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func validateExistsCommand(c *cli.Context) error {
+	if c.Args().Len() != 1 {
+		return fmt.Errorf("expected only 1 argument")
+	}
+
+	srcurl, err := url.New(c.Args().First())
+	if err != nil {
+		return err
+	}
+
+	if c.Bool("prefix") {
+		if !srcurl.IsRemote() {
+			return fmt.Errorf("%q flag can only be used with remote paths", "prefix")
+		}
+		if srcurl.IsWildcard() {
+			return fmt.Errorf("%q flag cannot be used with wildcards", "prefix")
+		}
+	}
+
+	return nil
+}
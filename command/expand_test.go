@@ -5,6 +5,7 @@ import (
 	"path/filepath"
 	"reflect"
 	"sort"
+	"strings"
 	"testing"
 
 	"github.com/golang/mock/gomock"
@@ -326,6 +327,34 @@ func TestExpandSource_Do_Not_Follow_Symlinks(t *testing.T) {
 	assert.DeepEqual(t, []string{workdirJoin}, expected)
 }
 
+func TestExpandSources_Memory_Storage(t *testing.T) {
+	ctx := context.Background()
+
+	client := storage.NewMemory()
+	assert.NilError(t, client.MakeBucket("bucket"))
+	for _, key := range []string{"key", "wildcard/test.txt", "wildcard/test.gz", "dir/a/readme.md"} {
+		u, err := url.New("mem://bucket/" + key)
+		assert.NilError(t, err)
+		assert.NilError(t, client.Put(ctx, strings.NewReader(key), u, storage.NewMetadata()))
+	}
+
+	srcurls, err := newURLs(false, "", false, "mem://bucket/key", "mem://bucket/wildcard/*.txt", "mem://bucket/dir/?/readme.md")
+	assert.NilError(t, err)
+
+	var objects []string
+	for obj := range expandSources(ctx, client, false, srcurls...) {
+		assert.NilError(t, obj.Err)
+		objects = append(objects, obj.String())
+	}
+	sort.Strings(objects)
+
+	assert.DeepEqual(t, objects, []string{
+		"mem://bucket/dir/a/readme.md",
+		"mem://bucket/key",
+		"mem://bucket/wildcard/test.txt",
+	})
+}
+
 func keys(urls map[string][]*storage.Object) []string {
 	var urlKeys []string
 	for key := range urls {
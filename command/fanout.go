@@ -0,0 +1,353 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/urfave/cli/v2"
+
+	errorpkg "github.com/peak/s5cmd/v2/error"
+	"github.com/peak/s5cmd/v2/log"
+	"github.com/peak/s5cmd/v2/storage"
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+const (
+	alsoToFlagName           = "also-to"
+	fanoutRequireAllFlagName = "fanout-require-all"
+)
+
+var (
+	// errFanoutCanceled is the error of the uploads which are canceled since
+	// the upload to another destination failed with --fanout-require-all.
+	errFanoutCanceled = errors.New("upload canceled since the upload to another destination failed")
+
+	// errFanoutDeleted is the error of the uploaded objects which are deleted
+	// since the upload to another destination failed with
+	// --fanout-require-all.
+	errFanoutDeleted = errors.New("uploaded object deleted since the upload to another destination failed")
+
+	// errFanoutNoDestination is returned by fanoutWriter once the writes to
+	// all of its destinations failed.
+	errFanoutNoDestination = errors.New("no destination to write to")
+)
+
+// fanoutDestination is one of the destinations which a file is uploaded to
+// with --also-to, along with the result of its upload.
+type fanoutDestination struct {
+	url    *url.URL
+	client *storage.S3
+	err    error
+}
+
+// fanoutWriter writes to all of its writers. The writers whose writes fail
+// are dropped and the others are written to as long as there is any left.
+type fanoutWriter struct {
+	writers []io.Writer
+}
+
+func (w *fanoutWriter) Write(p []byte) (int, error) {
+	writers := w.writers[:0]
+	for _, writer := range w.writers {
+		if _, err := writer.Write(p); err == nil {
+			writers = append(writers, writer)
+		}
+	}
+	w.writers = writers
+
+	if len(w.writers) == 0 {
+		return 0, errFanoutNoDestination
+	}
+	return len(p), nil
+}
+
+// fanoutPut reads src once and uploads it to all of the destinations with
+// put, which is called concurrently for each destination with a pipe reading
+// from src. The results of the uploads are set to the destinations. The
+// destinations are written to in turn, so the uploads advance at the pace of
+// the slowest one. If failFast is set, the other uploads are canceled as soon
+// as an upload fails.
+func fanoutPut(
+	ctx context.Context,
+	src io.Reader,
+	dsts []*fanoutDestination,
+	failFast bool,
+	put func(context.Context, io.Reader, *fanoutDestination) error,
+) {
+	putCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu     sync.Mutex
+		failed bool
+		wg     sync.WaitGroup
+	)
+
+	writers := make([]io.Writer, 0, len(dsts))
+	pipes := make([]*io.PipeWriter, 0, len(dsts))
+	for _, dst := range dsts {
+		pr, pw := io.Pipe()
+		writers = append(writers, pw)
+		pipes = append(pipes, pw)
+
+		wg.Add(1)
+		go func(dst *fanoutDestination, pr *io.PipeReader) {
+			defer wg.Done()
+
+			err := put(putCtx, pr, dst)
+			// the writes to the pipe fail from now on, so that the other
+			// destinations are not blocked if the upload returns without
+			// reading the whole stream, e.g. it fails or it's a dry run.
+			pr.CloseWithError(io.ErrClosedPipe)
+
+			if err != nil && failFast {
+				mu.Lock()
+				if failed && ctx.Err() == nil {
+					err = errFanoutCanceled
+				}
+				failed = true
+				mu.Unlock()
+				cancel()
+			}
+			dst.err = err
+		}(dst, pr)
+	}
+
+	// the error of reading src is passed to the uploads. The error of
+	// writing is ignored since it only tells that all uploads returned.
+	_, err := io.Copy(&fanoutWriter{writers: writers}, src)
+	if errors.Is(err, errFanoutNoDestination) {
+		err = nil
+	}
+	for _, pw := range pipes {
+		pw.CloseWithError(err)
+	}
+	wg.Wait()
+}
+
+// doFanoutUpload uploads the local file at srcurl to all of dsturls, reading
+// the file only once. The first destination is the destination argument of
+// the command and the others are given with --also-to. Unless
+// --fanout-require-all is given, the file is uploaded to as many destinations
+// as possible and the transfer is recorded as partial if some of the uploads
+// fail. With --fanout-require-all, the file is uploaded to either all of the
+// destinations or none of them: the other uploads are canceled once an upload
+// fails and the objects already uploaded are deleted. The source file is
+// deleted by mv only if all of the uploads succeed.
+func (c Copy) doFanoutUpload(
+	ctx context.Context,
+	srcurl *url.URL,
+	dsturls []*url.URL,
+	wrapErr func(error) error,
+) error {
+	srcClient := storage.NewLocalClient(c.storageOpts)
+
+	file, release, err := srcClient.Open(ctx, srcurl.Absolute())
+	if err != nil {
+		return err
+	}
+	defer release()
+	defer file.Close()
+
+	var merror error
+	fail := func(dsturl *url.URL, err error) {
+		merror = multierror.Append(merror, &errorpkg.Error{
+			Op:       c.op,
+			Src:      srcurl,
+			Dst:      dsturl,
+			Err:      wrapErr(err),
+			Position: c.position,
+		})
+	}
+
+	var (
+		dsts    []*fanoutDestination
+		skipErr error
+	)
+	for i, dsturl := range dsturls {
+		err := c.shouldOverride(ctx, srcurl, dsturl)
+		if errorpkg.IsWarning(err) {
+			skipErr = err
+			printDebug(c.op, err, srcurl, dsturl)
+			continue
+		}
+		if err != nil {
+			fail(dsturl, err)
+			continue
+		}
+
+		// the destination region is the region of the destination argument,
+		// the regions of the other destinations are detected.
+		opts := c.storageOpts
+		if i == 0 && c.dstRegion != "" {
+			opts.SetRegion(c.dstRegion)
+		}
+		client, err := storage.NewRemoteClient(ctx, dsturl, opts)
+		if err != nil {
+			fail(dsturl, err)
+			continue
+		}
+		dsts = append(dsts, &fanoutDestination{url: dsturl, client: client})
+	}
+
+	if merror != nil && (c.fanoutRequireAll || len(dsts) == 0) {
+		return merror
+	}
+	if len(dsts) == 0 {
+		skipTransfer(ctx, skipErr)
+		return nil
+	}
+
+	metadata := c.uploadMetadata()
+	if metadata.ContentType() == "" {
+		metadata.SetContentType(guessContentType(file))
+	}
+	reader := newCountingReaderWriter(file, c.progressbar)
+	partSize := c.filePartSize(file)
+
+	fanoutPut(ctx, reader, dsts, c.fanoutRequireAll, func(ctx context.Context, r io.Reader, dst *fanoutDestination) error {
+		return dst.client.Put(ctx, r, dst.url, c.withAutoSSE(ctx, metadata, dst.url), c.concurrency, partSize)
+	})
+
+	if c.expectedSize >= 0 && !c.storageOpts.DryRun {
+		for _, dst := range dsts {
+			if dst.err == nil {
+				dst.err = verifyUploadSize(ctx, dst.client, dst.url, c.expectedSize, c.deleteOnSizeMismatch)
+			}
+		}
+	}
+
+	var uploaded []*fanoutDestination
+	for _, dst := range dsts {
+		if dst.err != nil {
+			fail(dst.url, dst.err)
+			continue
+		}
+		uploaded = append(uploaded, dst)
+	}
+
+	if merror != nil && c.fanoutRequireAll {
+		for _, dst := range uploaded {
+			err := errFanoutDeleted
+			if !c.storageOpts.DryRun {
+				if derr := dst.client.Delete(ctx, dst.url); derr != nil {
+					err = fmt.Errorf("upload to another destination failed, and the uploaded object could not be deleted: %w", derr)
+				}
+			}
+			fail(dst.url, err)
+		}
+		return merror
+	}
+
+	obj, err := srcClient.Stat(ctx, srcurl)
+	if err != nil {
+		return err
+	}
+
+	for _, dst := range uploaded {
+		objurl, err := c.urls.url(dst.client, dst.url, c.storageOpts.DryRun)
+		if err != nil {
+			return err
+		}
+		if c.showProgress {
+			continue
+		}
+
+		msg := log.InfoMessage{
+			Operation:   c.op,
+			Source:      srcurl,
+			Destination: dst.url,
+			Object: &storage.Object{
+				Size:         obj.Size,
+				StorageClass: c.storageClass,
+			},
+			URL: objurl,
+		}
+		log.Info(msg)
+	}
+
+	if merror != nil {
+		if len(uploaded) > 0 {
+			partialTransfer(ctx)
+		}
+		return merror
+	}
+
+	if c.deleteSource {
+		// close the file before deleting
+		file.Close()
+		if err := srcClient.Delete(ctx, srcurl); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fanoutDestinations returns the destinations which srcurl is uploaded to,
+// starting with dsturl.
+func (c Copy) fanoutDestinations(srcurl, dsturl *url.URL, isBatch bool) []*url.URL {
+	dsturls := []*url.URL{prepareRemoteDestination(srcurl, dsturl, c.flatten, isBatch)}
+	for _, alsoTo := range c.alsoTo {
+		dsturls = append(dsturls, prepareRemoteDestination(srcurl, alsoTo, c.flatten, isBatch))
+	}
+	return dsturls
+}
+
+// parseAlsoTo parses the destinations given with --also-to.
+func parseAlsoTo(c *cli.Context) ([]*url.URL, error) {
+	var dsturls []*url.URL
+	for _, dst := range c.StringSlice(alsoToFlagName) {
+		dsturl, err := url.New(dst, url.WithRaw(c.Bool("raw")))
+		if err != nil {
+			return nil, err
+		}
+		dsturls = append(dsturls, dsturl)
+	}
+	return dsturls, nil
+}
+
+func validateFanout(c *cli.Context, srcurl, dsturl *url.URL) error {
+	if !c.IsSet(alsoToFlagName) {
+		if c.Bool(fanoutRequireAllFlagName) {
+			return fmt.Errorf("%q flag requires %q flag", fanoutRequireAllFlagName, alsoToFlagName)
+		}
+		return nil
+	}
+
+	if srcurl.IsRemote() || !dsturl.IsRemote() {
+		return fmt.Errorf("%q flag can only be used to upload local files", alsoToFlagName)
+	}
+	if c.Bool("append-detect") {
+		return fmt.Errorf("%q flag cannot be used with %q flag", alsoToFlagName, "append-detect")
+	}
+	if c.String("on-conflict") == conflictRename {
+		return fmt.Errorf("%q flag cannot be used with \"--on-conflict %v\"", alsoToFlagName, conflictRename)
+	}
+	if c.String("on-broken-symlink") == storage.BrokenSymlinkUploadAsLink {
+		return fmt.Errorf("%q flag cannot be used with \"--on-broken-symlink %v\"", alsoToFlagName, storage.BrokenSymlinkUploadAsLink)
+	}
+
+	alsoTo, err := parseAlsoTo(c)
+	if err != nil {
+		return err
+	}
+	for _, u := range alsoTo {
+		if !u.IsRemote() {
+			return fmt.Errorf("%q flag can only be used with remote destinations", alsoToFlagName)
+		}
+		if u.IsWildcard() {
+			return fmt.Errorf("target %q can not contain glob characters", u)
+		}
+		if srcurl.IsWildcard() && !u.IsPrefix() && !u.IsBucket() {
+			return fmt.Errorf("target %q must be a bucket or a prefix", u)
+		}
+		if u.String() == dsturl.String() {
+			return fmt.Errorf("target %q is already the destination", u)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+func TestFanoutWriter(t *testing.T) {
+	var first, second bytes.Buffer
+	failing := &failingWriter{after: 3}
+
+	w := &fanoutWriter{writers: []io.Writer{&first, failing, &second}}
+
+	n, err := w.Write([]byte("abc"))
+	assert.NilError(t, err)
+	assert.Equal(t, 3, n)
+
+	// the failing writer is dropped, the others are still written to.
+	n, err = w.Write([]byte("def"))
+	assert.NilError(t, err)
+	assert.Equal(t, 3, n)
+	assert.Equal(t, 2, len(w.writers))
+	assert.Equal(t, "abcdef", first.String())
+	assert.Equal(t, "abcdef", second.String())
+
+	w = &fanoutWriter{writers: []io.Writer{failing}}
+	_, err = w.Write([]byte("ghi"))
+	assert.Assert(t, errors.Is(err, errFanoutNoDestination))
+}
+
+func TestFanoutPut(t *testing.T) {
+	const content = "content of the file"
+	errUpload := errors.New("upload failed")
+
+	// put reads the whole stream to the buffer of the destination, unless
+	// the upload of the destination fails.
+	newPut := func(buffers map[string]*bytes.Buffer, failing map[string]bool) func(context.Context, io.Reader, *fanoutDestination) error {
+		var mu sync.Mutex
+		return func(ctx context.Context, r io.Reader, dst *fanoutDestination) error {
+			if failing[dst.url.Bucket] {
+				return errUpload
+			}
+			var buf bytes.Buffer
+			if _, err := io.Copy(&buf, r); err != nil {
+				return err
+			}
+			mu.Lock()
+			buffers[dst.url.Bucket] = &buf
+			mu.Unlock()
+			return nil
+		}
+	}
+
+	newDestinations := func(buckets ...string) []*fanoutDestination {
+		var dsts []*fanoutDestination
+		for _, bucket := range buckets {
+			u, err := url.New("s3://" + bucket + "/key")
+			assert.NilError(t, err)
+			dsts = append(dsts, &fanoutDestination{url: u})
+		}
+		return dsts
+	}
+
+	t.Run("all destinations", func(t *testing.T) {
+		buffers := map[string]*bytes.Buffer{}
+		dsts := newDestinations("bucket", "dr-bucket", "backup-bucket")
+
+		fanoutPut(context.Background(), strings.NewReader(content), dsts, false, newPut(buffers, nil))
+
+		for _, dst := range dsts {
+			assert.NilError(t, dst.err)
+			assert.Equal(t, content, buffers[dst.url.Bucket].String())
+		}
+	})
+
+	t.Run("best effort", func(t *testing.T) {
+		buffers := map[string]*bytes.Buffer{}
+		dsts := newDestinations("bucket", "dr-bucket")
+
+		fanoutPut(context.Background(), strings.NewReader(content), dsts, false,
+			newPut(buffers, map[string]bool{"dr-bucket": true}))
+
+		assert.NilError(t, dsts[0].err)
+		assert.Equal(t, content, buffers["bucket"].String())
+		assert.Assert(t, errors.Is(dsts[1].err, errUpload))
+	})
+
+	t.Run("fail fast", func(t *testing.T) {
+		dsts := newDestinations("bucket", "dr-bucket")
+
+		// the upload to bucket is blocked until it's canceled.
+		fanoutPut(context.Background(), strings.NewReader(content), dsts, true,
+			func(ctx context.Context, r io.Reader, dst *fanoutDestination) error {
+				if dst.url.Bucket == "dr-bucket" {
+					return errUpload
+				}
+				<-ctx.Done()
+				return ctx.Err()
+			})
+
+		assert.Assert(t, errors.Is(dsts[0].err, errFanoutCanceled))
+		assert.Assert(t, errors.Is(dsts[1].err, errUpload))
+	})
+
+	t.Run("read error", func(t *testing.T) {
+		errRead := errors.New("read failed")
+		buffers := map[string]*bytes.Buffer{}
+		dsts := newDestinations("bucket", "dr-bucket")
+
+		src := io.MultiReader(strings.NewReader(content), &failingReader{err: errRead})
+		fanoutPut(context.Background(), src, dsts, false, newPut(buffers, nil))
+
+		for _, dst := range dsts {
+			assert.Assert(t, errors.Is(dst.err, errRead))
+		}
+	})
+}
+
+// failingWriter fails the writes after the given number of bytes.
+type failingWriter struct {
+	after int
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if len(p) > w.after {
+		return 0, io.ErrShortWrite
+	}
+	w.after -= len(p)
+	return len(p), nil
+}
+
+type failingReader struct {
+	err error
+}
+
+func (r *failingReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
@@ -2,6 +2,7 @@ package command
 
 import (
 	"fmt"
+	"net/http"
 	"strings"
 )
 
@@ -41,3 +42,65 @@ func (e EnumValue) String() string {
 func (e EnumValue) Get() interface{} {
 	return e
 }
+
+// HeaderValue is the value of a flag which is given multiple times with HTTP
+// headers in "Name=Value" form. Unlike cli.StringSlice, the values are not
+// split by commas, which are common in header values.
+type HeaderValue struct {
+	// Allowed are the names of the headers which can be given. All headers are
+	// allowed if it's empty.
+	Allowed []string
+	headers []string
+}
+
+func (h *HeaderValue) Set(value string) error {
+	name, headerValue, ok := strings.Cut(value, "=")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" {
+		return fmt.Errorf("header %q must be in Name=Value form", value)
+	}
+
+	name = http.CanonicalHeaderKey(name)
+	if len(h.Allowed) > 0 && !containsFold(h.Allowed, name) {
+		return fmt.Errorf("allowed headers: [%s]", strings.Join(h.Allowed, ", "))
+	}
+
+	h.headers = append(h.headers, name+"="+headerValue)
+	return nil
+}
+
+func (h HeaderValue) String() string {
+	return strings.Join(h.headers, ", ")
+}
+
+func (h HeaderValue) Get() interface{} {
+	return h
+}
+
+// Values returns the headers in "Name=Value" form in the order they are given.
+func (h HeaderValue) Values() []string {
+	return h.headers
+}
+
+// Headers returns the values of the headers by their canonical names. The last
+// value is kept if a header is given multiple times.
+func (h HeaderValue) Headers() map[string]string {
+	if len(h.headers) == 0 {
+		return nil
+	}
+	headers := make(map[string]string, len(h.headers))
+	for _, header := range h.headers {
+		name, value, _ := strings.Cut(header, "=")
+		headers[name] = value
+	}
+	return headers
+}
+
+func containsFold(values []string, s string) bool {
+	for _, value := range values {
+		if strings.EqualFold(value, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package command
+
+import (
+	"context"
+	"sync"
+)
+
+// downloadedFiles tracks the local files downloaded by the commands of the
+// process, so that the commands generated by "run" and "sync" share it.
+var downloadedFiles = newHardlinkRegistry()
+
+// contentKey identifies the content of a remote object. Objects with the same
+// ETag and size are considered identical.
+type contentKey struct {
+	etag string
+	size int64
+}
+
+// hardlinkRegistry maps the content of remote objects to the first local file
+// they are downloaded to. Subsequent downloads of the same content are hard
+// linked to that file instead of being downloaded again.
+type hardlinkRegistry struct {
+	mu    sync.Mutex
+	files map[contentKey]*downloadedFile
+}
+
+func newHardlinkRegistry() *hardlinkRegistry {
+	return &hardlinkRegistry{
+		files: map[contentKey]*downloadedFile{},
+	}
+}
+
+// downloadedFile is a local file that is being downloaded, or is already
+// downloaded.
+type downloadedFile struct {
+	done chan struct{}
+	path string
+	ok   bool
+}
+
+// acquire returns the file downloaded for the given content. If the content is
+// not downloaded yet, a new file is registered and owner is true; the caller
+// must download the content and call finish.
+func (r *hardlinkRegistry) acquire(key contentKey) (file *downloadedFile, owner bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if file, ok := r.files[key]; ok {
+		return file, false
+	}
+
+	file = &downloadedFile{done: make(chan struct{})}
+	r.files[key] = file
+	return file, true
+}
+
+// finish records the result of the download of the registered content. A
+// failed download is forgotten, so that the next download of the same
+// content takes over.
+func (r *hardlinkRegistry) finish(key contentKey, file *downloadedFile, path string, ok bool) {
+	if !ok {
+		r.mu.Lock()
+		delete(r.files, key)
+		r.mu.Unlock()
+	}
+
+	file.path = path
+	file.ok = ok
+	close(file.done)
+}
+
+// wait waits for the download of the file to finish. It returns the path of
+// the downloaded file and whether the download succeeded.
+func (f *downloadedFile) wait(ctx context.Context) (string, bool) {
+	select {
+	case <-ctx.Done():
+		return "", false
+	case <-f.done:
+		return f.path, f.ok
+	}
+}
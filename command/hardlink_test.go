@@ -0,0 +1,57 @@
+package command
+
+import (
+	"context"
+	"testing"
+)
+
+func TestHardlinkRegistry(t *testing.T) {
+	registry := newHardlinkRegistry()
+	key := contentKey{etag: "etag", size: 42}
+
+	first, owner := registry.acquire(key)
+	if !owner {
+		t.Fatal("expected the first download to own the content")
+	}
+
+	second, owner := registry.acquire(key)
+	if owner {
+		t.Fatal("expected the second download not to own the content")
+	}
+
+	// a failed download is forgotten, the next download takes over.
+	registry.finish(key, first, "/tmp/first", false)
+	if _, ok := second.wait(context.Background()); ok {
+		t.Fatal("expected the failed download to be reported")
+	}
+
+	third, owner := registry.acquire(key)
+	if !owner {
+		t.Fatal("expected the download after a failure to own the content")
+	}
+	registry.finish(key, third, "/tmp/third", true)
+
+	fourth, owner := registry.acquire(key)
+	if owner {
+		t.Fatal("expected the downloaded content not to be owned again")
+	}
+	if path, ok := fourth.wait(context.Background()); !ok || path != "/tmp/third" {
+		t.Fatalf("expected /tmp/third, got %q (ok: %v)", path, ok)
+	}
+
+	if _, owner := registry.acquire(contentKey{etag: "etag", size: 43}); !owner {
+		t.Fatal("expected the content with a different size to be owned")
+	}
+}
+
+func TestDownloadedFileWaitCanceled(t *testing.T) {
+	registry := newHardlinkRegistry()
+	file, _ := registry.acquire(contentKey{etag: "etag", size: 1})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, ok := file.wait(ctx); ok {
+		t.Fatal("expected a canceled wait to fail")
+	}
+}
@@ -3,6 +3,7 @@ package command
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/hashicorp/go-multierror"
 	"github.com/urfave/cli/v2"
@@ -58,6 +59,21 @@ Examples:
 	11. List all files with their fullpaths 
 		 > s5cmd {{.HelpName}} --show-fullpath "s3://bucket/*"
 
+	12. List all objects in a bucket with their owners
+		 > s5cmd {{.HelpName}} --fetch-owner "s3://bucket/*"
+
+	13. List all objects in a bucket that are owned by the given account ID
+		 > s5cmd {{.HelpName}} --owner 79a59df900b949e55d96a1e698fbacedfd6e09d98eacf8f8d5218e7cd47ef2be "s3://bucket/*"
+
+	14. List all objects in a bucket that are modified after a marker object
+		 > s5cmd {{.HelpName}} --newer-than-object s3://bucket/markers/last-run "s3://bucket/incoming/*"
+
+	15. Delete all objects in a bucket listed with their fullpaths, even if their keys contain newlines
+		 > s5cmd {{.HelpName}} --show-fullpath -0 "s3://bucket/*" | s5cmd rm --manifest - -0
+
+	16. List only the archived objects under a prefix, e.g. to restore them
+		 > s5cmd {{.HelpName}} --storage-class-filter GLACIER,DEEP_ARCHIVE "s3://bucket/prefix/*"
+
 `
 
 func NewListCommand() *cli.Command {
@@ -94,6 +110,24 @@ func NewListCommand() *cli.Command {
 				Name:  "show-fullpath",
 				Usage: "shows only the fullpath names of the object(s)",
 			},
+			&cli.BoolFlag{
+				Name:    "null",
+				Aliases: []string{"0"},
+				Usage:   "terminate the fullpath names with NUL instead of newline, requires --show-fullpath",
+			},
+			&cli.BoolFlag{
+				Name:  "fetch-owner",
+				Usage: "show owner of the object(s) in the output",
+			},
+			&cli.StringFlag{
+				Name:  "owner",
+				Usage: "list only the object(s) owned by the account with given ID",
+			},
+			&cli.StringFlag{
+				Name:  "newer-than-object",
+				Usage: "list only the object(s) modified after the given reference object, e.g. s3://bucket/marker",
+			},
+			newStorageClassFilterFlag(),
 		},
 		Before: func(c *cli.Context) error {
 			err := validateLSCommand(c)
@@ -130,6 +164,11 @@ func NewListCommand() *cli.Command {
 				showStorageClass: c.Bool("storage-class"),
 				exclude:          c.StringSlice("exclude"),
 				showFullPath:     c.Bool("show-fullpath"),
+				nullTerminated:   c.Bool("null"),
+				showOwner:        c.Bool("fetch-owner"),
+				owner:            c.String("owner"),
+				newerThanObject:  c.String("newer-than-object"),
+				storageClasses:   newStorageClassFilter(c),
 
 				storageOpts: NewStorageOpts(c),
 			}.Run(c.Context)
@@ -151,7 +190,12 @@ type List struct {
 	humanize         bool
 	showStorageClass bool
 	showFullPath     bool
+	nullTerminated   bool
+	showOwner        bool
+	owner            string
 	exclude          []string
+	newerThanObject  string
+	storageClasses   *storageClassFilter
 
 	storageOpts storage.Options
 }
@@ -194,6 +238,15 @@ func (l List) Run(ctx context.Context) error {
 		return err
 	}
 
+	var newerThan *time.Time
+	if l.newerThanObject != "" {
+		newerThan, err = referenceModTime(ctx, l.newerThanObject, l.storageOpts)
+		if err != nil {
+			printError(l.fullCommand, l.op, err)
+			return err
+		}
+	}
+
 	for object := range client.List(ctx, l.src, false) {
 		if errorpkg.IsCancelation(object.Err) {
 			continue
@@ -209,12 +262,28 @@ func (l List) Run(ctx context.Context) error {
 			continue
 		}
 
+		if !object.Type.IsDir() && !l.storageClasses.match(object.URL, object.StorageClass) {
+			continue
+		}
+
+		if l.owner != "" && !object.Type.IsDir() && (object.Owner == nil || object.Owner.ID != l.owner) {
+			continue
+		}
+
+		// the modification time of the reference object has a precision of
+		// seconds, so are the compared modification times.
+		if newerThan != nil && !object.Type.IsDir() && (object.ModTime == nil || !object.ModTime.Truncate(time.Second).After(*newerThan)) {
+			continue
+		}
+
 		msg := ListMessage{
 			Object:           object,
 			showEtag:         l.showEtag,
 			showHumanized:    l.humanize,
 			showStorageClass: l.showStorageClass,
 			showFullPath:     l.showFullPath,
+			nullTerminated:   l.nullTerminated,
+			showOwner:        l.showOwner,
 		}
 
 		log.Info(msg)
@@ -223,6 +292,29 @@ func (l List) Run(ctx context.Context) error {
 	return merror
 }
 
+// referenceModTime returns the modification time of the reference object
+// given with the "newer-than-object" flag.
+func referenceModTime(ctx context.Context, reference string, storageOpts storage.Options) (*time.Time, error) {
+	refurl, err := url.New(reference)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := storage.NewRemoteClient(ctx, refurl, storageOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	obj, err := statObject(ctx, refurl, client)
+	if err != nil {
+		return nil, err
+	}
+	if obj == nil {
+		return nil, fmt.Errorf("reference object %q does not exist", refurl)
+	}
+	return obj.ModTime, nil
+}
+
 // ListMessage is a structure for logging ls results.
 type ListMessage struct {
 	Object *storage.Object `json:"object"`
@@ -231,6 +323,8 @@ type ListMessage struct {
 	showHumanized    bool
 	showStorageClass bool
 	showFullPath     bool
+	nullTerminated   bool
+	showOwner        bool
 }
 
 // humanize is a helper function to humanize bytes.
@@ -253,7 +347,7 @@ func (l ListMessage) String() string {
 	if l.showFullPath {
 		return l.Object.URL.String()
 	}
-	var etag string
+	var etag, owner string
 	// date and storage fiels
 	var listFormat = "%19s %2s"
 
@@ -265,6 +359,14 @@ func (l ListMessage) String() string {
 		listFormat = listFormat + " %-1s"
 	}
 
+	// align owner
+	if l.showOwner {
+		owner = l.Object.Owner.String()
+		listFormat = listFormat + " %-20s"
+	} else {
+		listFormat = listFormat + "%s"
+	}
+
 	// format file size
 	listFormat = listFormat + " %12s "
 	// format key and version ID
@@ -281,6 +383,7 @@ func (l ListMessage) String() string {
 			"",
 			"",
 			"",
+			"",
 			"DIR",
 			l.Object.URL.Relative(),
 			"",
@@ -305,6 +408,7 @@ func (l ListMessage) String() string {
 		l.Object.ModTime.Format(dateFormat),
 		stclass,
 		etag,
+		owner,
 		l.humanize(),
 		path,
 		l.Object.URL.VersionID,
@@ -313,6 +417,15 @@ func (l ListMessage) String() string {
 	return s
 }
 
+// Terminator returns the terminator of the string representation of
+// ListMessage.
+func (l ListMessage) Terminator() string {
+	if l.nullTerminated {
+		return "\x00"
+	}
+	return "\n"
+}
+
 // JSON returns the JSON representation of ListMessage.
 func (l ListMessage) JSON() string {
 	return strutil.JSON(l.Object)
@@ -337,5 +450,34 @@ func validateLSCommand(c *cli.Context) error {
 		return err
 	}
 
+	if (c.Bool("fetch-owner") || c.String("owner") != "") && !srcurl.IsRemote() {
+		return fmt.Errorf("%q and %q flags can only be used with remote objects", "fetch-owner", "owner")
+	}
+
+	if c.IsSet(storageClassFilterFlagName) && !c.Args().Present() {
+		return fmt.Errorf("%q flag can not be used to list buckets", storageClassFilterFlagName)
+	}
+	if err := validateStorageClassFilter(c, srcurl); err != nil {
+		return err
+	}
+
+	if c.Bool("null") && !c.Bool("show-fullpath") {
+		return fmt.Errorf("%q flag requires %q flag", "null", "show-fullpath")
+	}
+
+	if c.IsSet("newer-than-object") {
+		if !c.Args().Present() {
+			return fmt.Errorf("%q flag can not be used to list buckets", "newer-than-object")
+		}
+
+		refurl, err := url.New(c.String("newer-than-object"))
+		if err != nil {
+			return err
+		}
+		if !refurl.IsRemote() || refurl.IsBucket() || refurl.IsPrefix() || refurl.IsWildcard() {
+			return fmt.Errorf("reference of %q flag must be a remote object, e.g. s3://bucket/marker", "newer-than-object")
+		}
+	}
+
 	return nil
 }
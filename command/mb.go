@@ -90,7 +90,9 @@ func (b MakeBucket) Run(ctx context.Context) error {
 		return err
 	}
 
-	if err := client.MakeBucket(ctx, bucket.Bucket); err != nil {
+	err = client.MakeBucket(ctx, bucket.Bucket)
+	auditLog.created(ctx, b.op, nil, bucket, 0, err)
+	if err != nil {
 		printError(b.fullCommand, b.op, err)
 		return err
 	}
@@ -0,0 +1,544 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+
+	errorpkg "github.com/peak/s5cmd/v2/error"
+	"github.com/peak/s5cmd/v2/log"
+	"github.com/peak/s5cmd/v2/log/stat"
+	"github.com/peak/s5cmd/v2/storage"
+	"github.com/peak/s5cmd/v2/storage/url"
+	"github.com/peak/s5cmd/v2/strutil"
+)
+
+const (
+	mergeOrderName     = "name"
+	mergeOrderMtime    = "mtime"
+	mergeOrderManifest = "manifest"
+
+	defaultMergeConcurrency = 5
+)
+
+var mergeHelpTemplate = `Name:
+	{{.HelpName}} - {{.Usage}}
+
+Usage:
+	{{.HelpName}} [options] source [source ...] destination
+	{{.HelpName}} [options] --manifest file destination
+
+Options:
+	{{range .VisibleFlags}}{{.}}
+	{{end}}
+Examples:
+	1. Concatenate the objects matching a wildcard into a single object, in the order of their keys
+		 > s5cmd {{.HelpName}} "s3://bucket/parts/chunk-*" s3://bucket/merged/output.bin
+
+	2. Concatenate the objects in the order of their modification times
+		 > s5cmd {{.HelpName}} --order mtime "s3://bucket/parts/chunk-*" s3://bucket/merged/output.bin
+
+	3. Concatenate the objects listed in a manifest, in the order they are listed
+		 > s5cmd {{.HelpName}} --manifest parts.txt s3://bucket/merged/output.bin
+
+	4. Print the parts of the merged object and the objects they are made of, without merging
+		 > s5cmd --dry-run {{.HelpName}} "s3://bucket/parts/chunk-*" s3://bucket/merged/output.bin
+
+	5. Delete the merged objects once they are concatenated
+		 > s5cmd {{.HelpName}} --delete-sources "s3://bucket/parts/chunk-*" s3://bucket/merged/output.bin
+`
+
+func NewMergeCommand() *cli.Command {
+	cmd := &cli.Command{
+		Name:               "merge",
+		HelpName:           "merge",
+		Usage:              "concatenate objects into a single object on the server side",
+		CustomHelpTemplate: mergeHelpTemplate,
+		Flags: []cli.Flag{
+			&cli.GenericFlag{
+				Name: "order",
+				Value: &EnumValue{
+					Enum:    []string{mergeOrderName, mergeOrderMtime, mergeOrderManifest},
+					Default: mergeOrderName,
+				},
+				Usage: "order of the objects in the merged object: (name, mtime, manifest); manifest is the default with --manifest flag",
+			},
+			&cli.StringFlag{
+				Name:  "manifest",
+				Usage: "read the full paths of the objects to merge from the given file, one per line, or from standard input if \"-\"; wildcards in the paths are not expanded",
+			},
+			&cli.BoolFlag{
+				Name:  "delete-sources",
+				Usage: "delete the merged objects once the merged object is created",
+			},
+			&cli.IntFlag{
+				Name:    "concurrency",
+				Aliases: []string{"c"},
+				Value:   defaultMergeConcurrency,
+				Usage:   "number of parts copied or uploaded at the same time",
+			},
+			&cli.StringFlag{
+				Name:  "content-type",
+				Usage: "set content type of the merged object, guessed from its extension by default",
+			},
+		},
+		Before: func(c *cli.Context) error {
+			err := validateMergeCommand(c)
+			if err != nil {
+				printError(commandFromContext(c), c.Command.Name, err)
+			}
+			return err
+		},
+		Action: func(c *cli.Context) (err error) {
+			defer stat.Collect(c.Command.FullName(), &err)()
+
+			merge, err := NewMerge(c)
+			if err != nil {
+				printError(commandFromContext(c), c.Command.Name, err)
+				return err
+			}
+			return merge.Run(c.Context)
+		},
+	}
+
+	cmd.BashComplete = getBashCompleteFn(cmd, false, false)
+	return cmd
+}
+
+// Merge holds merge operation flags and states.
+type Merge struct {
+	src         []*url.URL
+	dst         *url.URL
+	op          string
+	fullCommand string
+
+	// flags
+	order         string
+	deleteSources bool
+	concurrency   int
+	contentType   string
+
+	storageOpts storage.Options
+}
+
+// NewMerge creates a Merge from the cli context.
+func NewMerge(c *cli.Context) (*Merge, error) {
+	args := c.Args().Slice()
+	sources, destination := args[:len(args)-1], args[len(args)-1]
+
+	var raw bool
+	if manifest := c.String("manifest"); manifest != "" {
+		// the paths in the manifest are the exact paths of the objects.
+		var err error
+		sources, err = readManifest(c.Context, manifest, '\n', "merge")
+		if err != nil {
+			return nil, err
+		}
+		raw = true
+	}
+
+	srcurls := make([]*url.URL, 0, len(sources))
+	for _, src := range sources {
+		srcurl, err := url.New(src, url.WithRaw(raw))
+		if err != nil {
+			return nil, err
+		}
+		if err := checkMergeSource(srcurl); err != nil {
+			return nil, err
+		}
+		srcurls = append(srcurls, srcurl)
+	}
+
+	dsturl, err := url.New(destination)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Merge{
+		src:         srcurls,
+		dst:         dsturl,
+		op:          c.Command.Name,
+		fullCommand: commandFromContext(c),
+
+		order:         mergeOrder(c),
+		deleteSources: c.Bool("delete-sources"),
+		concurrency:   c.Int("concurrency"),
+		contentType:   c.String("content-type"),
+
+		storageOpts: NewStorageOpts(c),
+	}, nil
+}
+
+// mergeOrder returns the order of the merged objects. The objects of a
+// manifest are merged in the order they are listed, unless the order is
+// given.
+func mergeOrder(c *cli.Context) string {
+	if c.IsSet("manifest") && !c.IsSet("order") {
+		return mergeOrderManifest
+	}
+	return c.String("order")
+}
+
+// Run concatenates the source objects into the destination object.
+func (m Merge) Run(ctx context.Context) error {
+	objects, err := m.objects(ctx)
+	if err != nil {
+		printError(m.fullCommand, m.op, err)
+		return err
+	}
+
+	parts, err := storage.PlanMerge(objects)
+	if err != nil {
+		printError(m.fullCommand, m.op, err)
+		return err
+	}
+
+	if m.storageOpts.DryRun {
+		for _, part := range parts {
+			log.Info(newMergePartMessage(part))
+		}
+	}
+
+	client, err := storage.NewRemoteClient(ctx, m.dst, m.storageOpts)
+	if err != nil {
+		printError(m.fullCommand, m.op, err)
+		return err
+	}
+
+	metadata := storage.NewMetadata()
+	if m.contentType != "" {
+		metadata.SetContentType(m.contentType)
+	} else {
+		metadata.SetContentType(guessContentTypeByExtension(m.dst))
+	}
+
+	var size int64
+	for _, object := range objects {
+		size += object.Size
+	}
+
+	err = client.Merge(ctx, parts, m.dst, metadata, m.concurrency)
+	auditLog.created(ctx, m.op, nil, m.dst, size, err)
+	if err != nil {
+		printError(m.fullCommand, m.op, err)
+		return err
+	}
+
+	log.Info(MergeMessage{
+		Destination: m.dst,
+		Objects:     len(objects),
+		Parts:       len(parts),
+		Size:        size,
+	})
+
+	if !m.deleteSources {
+		return nil
+	}
+	return m.deleteObjects(ctx, objects)
+}
+
+// objects returns the objects to merge in the order they are concatenated.
+// The wildcards are expanded by listing, and the other objects are stated for
+// their sizes and ETags.
+func (m Merge) objects(ctx context.Context) ([]*storage.Object, error) {
+	var objects []*storage.Object
+	for _, srcurl := range m.src {
+		if !srcurl.IsWildcard() {
+			objects = append(objects, &storage.Object{URL: srcurl})
+			continue
+		}
+
+		client, err := storage.NewRemoteClient(ctx, srcurl, m.storageOpts)
+		if err != nil {
+			return nil, err
+		}
+		for object := range client.List(ctx, srcurl, false) {
+			if object.Err == storage.ErrNoObjectFound || object.Type.IsDir() {
+				continue
+			}
+			if object.Err != nil {
+				return nil, object.Err
+			}
+			objects = append(objects, object)
+		}
+	}
+
+	if len(objects) == 0 {
+		return nil, storage.ErrNoObjectFound
+	}
+
+	if err := m.statObjects(ctx, objects); err != nil {
+		return nil, err
+	}
+
+	for _, object := range objects {
+		if object.URL.Absolute() == m.dst.Absolute() {
+			return nil, fmt.Errorf("destination %v is one of the objects to merge", m.dst)
+		}
+	}
+
+	sortMergeObjects(objects, m.order)
+	return objects, nil
+}
+
+// statObjects replaces the objects which are not listed with their stats,
+// by the given concurrency. The objects of each bucket are stated with the
+// client of the bucket.
+func (m Merge) statObjects(ctx context.Context, objects []*storage.Object) error {
+	var buckets []string
+	indexes := map[string][]int{}
+	for i, object := range objects {
+		if object.ModTime != nil {
+			continue
+		}
+		bucket := object.URL.Bucket
+		if _, ok := indexes[bucket]; !ok {
+			buckets = append(buckets, bucket)
+		}
+		indexes[bucket] = append(indexes[bucket], i)
+	}
+
+	for _, bucket := range buckets {
+		urls := make([]*url.URL, 0, len(indexes[bucket]))
+		for _, i := range indexes[bucket] {
+			urls = append(urls, objects[i].URL)
+		}
+
+		client, err := storage.NewRemoteClient(ctx, urls[0], m.storageOpts)
+		if err != nil {
+			return err
+		}
+		for n, result := range client.StatMany(ctx, urls, m.concurrency) {
+			switch {
+			case result.NotFound:
+				return &storage.ErrGivenObjectNotFound{ObjectAbsPath: result.URL.Absolute()}
+			case result.Err != nil:
+				return result.Err
+			}
+			objects[indexes[bucket][n]] = result.Object
+		}
+	}
+	return nil
+}
+
+// sortMergeObjects sorts the objects by the given order. The objects of the
+// same modification time are sorted by name.
+func sortMergeObjects(objects []*storage.Object, order string) {
+	switch order {
+	case mergeOrderName:
+		sort.SliceStable(objects, func(i, j int) bool {
+			return objects[i].URL.Absolute() < objects[j].URL.Absolute()
+		})
+	case mergeOrderMtime:
+		sort.SliceStable(objects, func(i, j int) bool {
+			ti, tj := objects[i].ModTime, objects[j].ModTime
+			if !ti.Equal(*tj) {
+				return ti.Before(*tj)
+			}
+			return objects[i].URL.Absolute() < objects[j].URL.Absolute()
+		})
+	}
+}
+
+// deleteObjects deletes the merged objects, which are given more than once in
+// a manifest are deleted once. The objects of each bucket are deleted with
+// batch requests.
+func (m Merge) deleteObjects(ctx context.Context, objects []*storage.Object) error {
+	var (
+		buckets []string
+		urls    = map[string][]*url.URL{}
+		seen    = map[string]bool{}
+	)
+	for _, object := range objects {
+		key := object.URL.Absolute()
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		bucket := object.URL.Bucket
+		if _, ok := urls[bucket]; !ok {
+			buckets = append(buckets, bucket)
+		}
+		urls[bucket] = append(urls[bucket], object.URL)
+	}
+
+	errs := newErrorSummary()
+	for _, bucket := range buckets {
+		client, err := storage.NewRemoteClient(ctx, urls[bucket][0], m.storageOpts)
+		if err != nil {
+			errs.add(err, "")
+			printError(m.fullCommand, m.op, err)
+			continue
+		}
+
+		urlch := make(chan *url.URL)
+		go func(urls []*url.URL) {
+			defer close(urlch)
+			for _, u := range urls {
+				select {
+				case urlch <- u:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}(urls[bucket])
+
+		for obj := range client.MultiDelete(ctx, urlch) {
+			auditLog.deleted(ctx, "rm", obj.URL, obj.Err)
+			if err := obj.Err; err != nil {
+				if errorpkg.IsCancelation(err) {
+					continue
+				}
+				errs.add(err, "")
+				printError(m.fullCommand, m.op, err)
+				continue
+			}
+			log.Info(log.InfoMessage{
+				Operation: "rm",
+				Source:    obj.URL,
+			})
+		}
+	}
+	return errs.err()
+}
+
+// MergePartMessage is the structure for the parts of a merged object, which
+// are printed with --dry-run flag.
+type MergePartMessage struct {
+	Part     int64                 `json:"part"`
+	Action   string                `json:"action"`
+	Size     int64                 `json:"size"`
+	Segments []MergeSegmentMessage `json:"segments"`
+}
+
+// MergeSegmentMessage is the structure for a byte range of an object which is
+// a part of a merged object.
+type MergeSegmentMessage struct {
+	Source *url.URL `json:"source"`
+	Offset int64    `json:"offset"`
+	Length int64    `json:"length"`
+}
+
+func newMergePartMessage(part storage.MergePart) MergePartMessage {
+	msg := MergePartMessage{
+		Part:   part.Number,
+		Action: "upload",
+		Size:   part.Size(),
+	}
+	if part.IsCopy() {
+		msg.Action = "copy"
+	}
+	for _, segment := range part.Segments {
+		msg.Segments = append(msg.Segments, MergeSegmentMessage{
+			Source: segment.Object.URL,
+			Offset: segment.Offset,
+			Length: segment.Length,
+		})
+	}
+	return msg
+}
+
+// String is the string representation of MergePartMessage.
+func (m MergePartMessage) String() string {
+	segments := make([]string, 0, len(m.Segments))
+	for _, s := range m.Segments {
+		segments = append(segments, fmt.Sprintf("%v bytes=%d-%d", s.Source, s.Offset, s.Offset+s.Length-1))
+	}
+	return fmt.Sprintf("part %d %v %d %v", m.Part, m.Action, m.Size, strings.Join(segments, " "))
+}
+
+// JSON is the JSON representation of MergePartMessage.
+func (m MergePartMessage) JSON() string {
+	return strutil.JSON(struct {
+		Operation string `json:"operation"`
+		MergePartMessage
+	}{
+		Operation:        "merge-part",
+		MergePartMessage: m,
+	})
+}
+
+// MergeMessage is the structure for a merged object.
+type MergeMessage struct {
+	Destination *url.URL `json:"destination"`
+	Objects     int      `json:"objects"`
+	Parts       int      `json:"parts"`
+	Size        int64    `json:"size"`
+}
+
+// String is the string representation of MergeMessage.
+func (m MergeMessage) String() string {
+	return fmt.Sprintf("merge %v (%d objects, %d parts, %d bytes)", m.Destination, m.Objects, m.Parts, m.Size)
+}
+
+// JSON is the JSON representation of MergeMessage.
+func (m MergeMessage) JSON() string {
+	return strutil.JSON(struct {
+		Operation string `json:"operation"`
+		Success   bool   `json:"success"`
+		MergeMessage
+	}{
+		Operation:    "merge",
+		Success:      true,
+		MergeMessage: m,
+	})
+}
+
+// checkMergeSource checks that the source is a remote object or a wildcard.
+func checkMergeSource(srcurl *url.URL) error {
+	if !srcurl.IsRemote() {
+		return fmt.Errorf("source %q must be a remote object", srcurl)
+	}
+	if !srcurl.IsWildcard() && (srcurl.IsBucket() || srcurl.IsPrefix()) {
+		return fmt.Errorf("source %q must be an object or a wildcard", srcurl)
+	}
+	return nil
+}
+
+func validateMergeCommand(c *cli.Context) error {
+	if c.IsSet("manifest") {
+		if c.Args().Len() != 1 {
+			return fmt.Errorf("expected only the destination argument with %q flag", "manifest")
+		}
+		if c.String("manifest") == "" {
+			return fmt.Errorf("%q flag requires a file", "manifest")
+		}
+	} else {
+		if c.Args().Len() < 2 {
+			return fmt.Errorf("expected source and destination arguments")
+		}
+		if c.String("order") == mergeOrderManifest {
+			return fmt.Errorf("%q order requires %q flag", mergeOrderManifest, "manifest")
+		}
+	}
+
+	if c.Int("concurrency") < 1 {
+		return fmt.Errorf("%q flag must be a positive number", "concurrency")
+	}
+
+	dsturl, err := url.New(c.Args().Get(c.Args().Len() - 1))
+	if err != nil {
+		return err
+	}
+	if !dsturl.IsRemote() || dsturl.IsWildcard() || dsturl.IsBucket() || dsturl.IsPrefix() {
+		return fmt.Errorf("destination %q must be a remote object", dsturl)
+	}
+
+	if c.IsSet("manifest") {
+		return nil
+	}
+	for _, src := range c.Args().Slice()[:c.Args().Len()-1] {
+		srcurl, err := url.New(src)
+		if err != nil {
+			return err
+		}
+		if err := checkMergeSource(srcurl); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package command
+
+import (
+	"os"
+	"time"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/peak/s5cmd/v2/storage"
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+const preserveMtimeFlagName = "preserve-mtime"
+
+func newPreserveMtimeFlag() cli.Flag {
+	return &cli.BoolFlag{
+		Name:  preserveMtimeFlagName,
+		Usage: "store the modification times of the uploaded files in the object metadata and set the modification times of the downloaded files to the stored ones, or to the last modification times of the objects",
+	}
+}
+
+// modTimeMetadata returns the metadata with the modification time of the
+// uploaded file.
+func modTimeMetadata(metadata storage.Metadata, file *os.File) (storage.Metadata, error) {
+	st, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return metadata.SetModTime(st.ModTime()), nil
+}
+
+// downloadedModTime returns the modification time of the file which obj is
+// downloaded to: the one kept in the metadata of obj, or the last modification
+// time of obj if it is uploaded without the modification time of its file.
+func downloadedModTime(obj *storage.Object) *time.Time {
+	if obj.PreservedModTime != nil {
+		return obj.PreservedModTime
+	}
+	return obj.ModTime
+}
+
+// restoreModTime sets the modification time of the downloaded file of dsturl
+// to the one of obj.
+func restoreModTime(client *storage.Filesystem, obj *storage.Object, dsturl *url.URL) error {
+	modTime := downloadedModTime(obj)
+	if modTime == nil {
+		return nil
+	}
+	return client.Chtimes(dsturl.Absolute(), *modTime)
+}
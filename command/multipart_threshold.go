@@ -0,0 +1,85 @@
+package command
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/peak/s5cmd/v2/storage"
+	"github.com/peak/s5cmd/v2/strutil"
+)
+
+const multipartThresholdFlagName = "multipart-threshold"
+
+func newMultipartThresholdFlag() cli.Flag {
+	return &cli.StringFlag{
+		Name:  multipartThresholdFlagName,
+		Usage: "upload and copy the objects up to the given size with a single request rather than in parts, e.g. 100MB; the objects up to the part size are always sent with a single request",
+	}
+}
+
+// parseMultipartThreshold returns the multipart threshold, or 0 if it is not
+// set. The flag is validated before.
+func parseMultipartThreshold(c *cli.Context) int64 {
+	size, _ := strutil.ParseBytes(c.String(multipartThresholdFlagName))
+	return size
+}
+
+// uploadPartSize returns the part size of the upload of a file of the given
+// size. The uploader sends the files which fit in a part with a single
+// request, so the part of a file up to the multipart threshold is larger than
+// the file.
+func (c Copy) uploadPartSize(size int64) int64 {
+	return uploadPartSize(size, c.partSize, c.multipartThreshold)
+}
+
+// uploadPartSize returns the part size of the upload of a file of the given
+// size with the given part size and multipart threshold.
+func uploadPartSize(size, partSize, multipartThreshold int64) int64 {
+	if multipartThreshold > 0 && size >= partSize && size <= multipartThreshold {
+		return size + 1
+	}
+	return partSize
+}
+
+// filePartSize returns the part size of the upload of the given file.
+func (c Copy) filePartSize(file *os.File) int64 {
+	if c.multipartThreshold <= 0 {
+		return c.partSize
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return c.partSize
+	}
+	return c.uploadPartSize(info.Size())
+}
+
+// isMultipartCopy reports whether an object of the given size is copied in
+// parts. The objects larger than 5GiB can't be copied with a single request.
+// The others are copied in parts only if they are larger than both the
+// multipart threshold and the part size.
+func (c Copy) isMultipartCopy(size int64) bool {
+	if size > storage.MaxCopyObjectSize {
+		return true
+	}
+	return c.multipartThreshold > 0 && size > c.multipartThreshold && size > c.partSize
+}
+
+func validateMultipartThreshold(c *cli.Context) error {
+	if !c.IsSet(multipartThresholdFlagName) {
+		return nil
+	}
+
+	size, err := strutil.ParseBytes(c.String(multipartThresholdFlagName))
+	if err != nil {
+		return fmt.Errorf("invalid %v: %w", multipartThresholdFlagName, err)
+	}
+	if size < storage.MinPartSize {
+		return fmt.Errorf("%q flag must be at least 5MiB, the minimum part size", multipartThresholdFlagName)
+	}
+	if size > storage.MaxCopyObjectSize {
+		return fmt.Errorf("%q flag must be at most 5GiB, the maximum size of a single request", multipartThresholdFlagName)
+	}
+	return nil
+}
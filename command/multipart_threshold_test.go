@@ -0,0 +1,64 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/peak/s5cmd/v2/storage"
+)
+
+func TestCopyUploadPartSize(t *testing.T) {
+	const mb = int64(megabytes)
+
+	testcases := []struct {
+		name      string
+		partSize  int64
+		threshold int64
+		size      int64
+		expected  int64
+	}{
+		{name: "no threshold", partSize: 5 * mb, size: 50 * mb, expected: 5 * mb},
+		{name: "up to part size", partSize: 64 * mb, threshold: 100 * mb, size: 16 * mb, expected: 64 * mb},
+		{name: "up to threshold", partSize: 64 * mb, threshold: 100 * mb, size: 80 * mb, expected: 80*mb + 1},
+		{name: "at threshold", partSize: 64 * mb, threshold: 100 * mb, size: 100 * mb, expected: 100*mb + 1},
+		{name: "larger than threshold", partSize: 64 * mb, threshold: 100 * mb, size: 200 * mb, expected: 64 * mb},
+		{name: "threshold below part size", partSize: 64 * mb, threshold: 16 * mb, size: 32 * mb, expected: 64 * mb},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			c := Copy{partSize: tc.partSize, multipartThreshold: tc.threshold}
+			if got := c.uploadPartSize(tc.size); got != tc.expected {
+				t.Errorf("expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestCopyIsMultipartCopy(t *testing.T) {
+	const mb = int64(megabytes)
+
+	testcases := []struct {
+		name      string
+		partSize  int64
+		threshold int64
+		size      int64
+		expected  bool
+	}{
+		{name: "no threshold", partSize: 5 * mb, size: 50 * mb, expected: false},
+		{name: "no threshold larger than 5GiB", partSize: 5 * mb, size: storage.MaxCopyObjectSize + 1, expected: true},
+		{name: "up to threshold", partSize: 64 * mb, threshold: 100 * mb, size: 100 * mb, expected: false},
+		{name: "larger than threshold", partSize: 64 * mb, threshold: 100 * mb, size: 200 * mb, expected: true},
+		{name: "up to part size", partSize: 64 * mb, threshold: 16 * mb, size: 32 * mb, expected: false},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			c := Copy{partSize: tc.partSize, multipartThreshold: tc.threshold}
+			if got := c.isMultipartCopy(tc.size); got != tc.expected {
+				t.Errorf("expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
@@ -51,6 +51,8 @@ func NewMoveCommand() *cli.Command {
 		Action: func(c *cli.Context) (err error) {
 			defer stat.Collect(c.Command.FullName(), &err)()
 
+			printAWSCLINotices(c, c.Args().Get(0))
+
 			// delete source
 			copy, err := NewCopy(c, true)
 			if err != nil {
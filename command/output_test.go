@@ -0,0 +1,90 @@
+package command
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/peak/s5cmd/v2/log"
+	"github.com/peak/s5cmd/v2/log/stat"
+	"github.com/peak/s5cmd/v2/storage"
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+var updateGolden = flag.Bool("update", false, "update the golden files of JSON output schemas")
+
+// TestJSONOutputSchema locks the exact JSON records of each output schema
+// version. A change in these records breaks the consumers of the JSON output,
+// so it requires a new schema version rather than an update of the golden
+// files of an existing version.
+func TestJSONOutputSchema(t *testing.T) {
+	src, err := url.New("s3://bucket/prefix/object.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst, err := url.New("folder/object.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srcTime := time.Date(2023, time.March, 1, 10, 0, 0, 0, time.UTC)
+	dstTime := srcTime.Add(time.Hour)
+
+	messages := map[string]log.Message{
+		"info": log.InfoMessage{
+			Operation:   "cp",
+			Source:      src,
+			Destination: dst,
+			Object: &storage.Object{
+				Size:         42,
+				StorageClass: storage.StorageClass("STANDARD"),
+			},
+		},
+		"error": log.ErrorMessage{
+			Operation: "cp",
+			Command:   "cp s3://bucket/prefix/object.gz folder/",
+			Err:       "NoSuchKey: The specified key does not exist.",
+		},
+		"summary": stat.Stats{
+			{Operation: "cp", Success: 9, Error: 1},
+			{Operation: "rm", Success: 3, Error: 0},
+		},
+		"sync_skip": SkipMessage{
+			Source:      src,
+			Destination: dst,
+			Reason: newSkipReason(
+				SkipNewerAndSizesMatch,
+				&storage.Object{Size: 42, ModTime: &srcTime, Etag: "etag"},
+				&storage.Object{Size: 42, ModTime: &dstTime},
+			),
+		},
+	}
+
+	for _, version := range []int{log.OutputVersion1, log.OutputVersion2} {
+		for name, msg := range messages {
+			version, name, msg := version, name, msg
+			t.Run(fmt.Sprintf("v%d/%s", version, name), func(t *testing.T) {
+				golden := filepath.Join("testdata", "output", fmt.Sprintf("v%d", version), name+".json")
+				got := log.VersionedJSON(msg, version)
+
+				if *updateGolden {
+					if err := os.WriteFile(golden, []byte(got), 0o644); err != nil {
+						t.Fatal(err)
+					}
+				}
+
+				expected, err := os.ReadFile(golden)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if got != string(expected) {
+					t.Errorf("JSON output does not match %v\nexpected: %s\ngot:      %s", golden, expected, got)
+				}
+			})
+		}
+	}
+}
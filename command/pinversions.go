@@ -0,0 +1,100 @@
+package command
+
+import (
+	"fmt"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/peak/s5cmd/v2/storage"
+	"github.com/peak/s5cmd/v2/storage/url"
+	"github.com/peak/s5cmd/v2/strutil"
+)
+
+// VanishedMessage is the structure for the objects which are not copied
+// because their pinned versions, or their local files, don't exist anymore.
+type VanishedMessage struct {
+	Source      *url.URL
+	Destination *url.URL
+}
+
+// String is the string representation of VanishedMessage.
+func (m VanishedMessage) String() string {
+	return fmt.Sprintf("vanished %v %v: %v", m.Source, m.Destination, vanishedReason(m.Source))
+}
+
+// JSON is the JSON representation of VanishedMessage.
+func (m VanishedMessage) JSON() string {
+	return strutil.JSON(struct {
+		Operation   string   `json:"operation"`
+		Status      string   `json:"status"`
+		Source      *url.URL `json:"source"`
+		Destination *url.URL `json:"destination"`
+		VersionID   string   `json:"version_id,omitempty"`
+	}{
+		Operation:   "skip",
+		Status:      transferVanished,
+		Source:      m.Source,
+		Destination: m.Destination,
+		VersionID:   m.Source.VersionID,
+	})
+}
+
+// vanishedReason returns why the object of srcurl is not copied since it
+// vanished.
+func vanishedReason(srcurl *url.URL) string {
+	if srcurl.IsRemote() {
+		return fmt.Sprintf("pinned version %v doesn't exist anymore", srcurl.VersionID)
+	}
+	return "file doesn't exist anymore"
+}
+
+// pinnedSourceURL returns the URL which the source objects are listed with to
+// pin their versions. The versions of the objects are listed, unless a
+// version is already given, and the latest ones are pinned.
+func pinnedSourceURL(srcurl *url.URL) *url.URL {
+	if srcurl.VersionID != "" {
+		return srcurl
+	}
+	pinned := srcurl.Clone()
+	pinned.AllVersions = true
+	return pinned
+}
+
+// isPinnedVersion reports whether the object of a versions listing is the
+// version to pin, i.e. the latest version of an object which is not deleted.
+func isPinnedVersion(object *storage.Object) bool {
+	return object.IsLatest && !object.DeleteMarker
+}
+
+// versionFlags returns the default flags of the copy command of srcurl, along
+// with its pinned version, if any.
+func versionFlags(defaultFlags map[string]interface{}, srcurl *url.URL) map[string]interface{} {
+	if srcurl.VersionID == "" {
+		return defaultFlags
+	}
+	flags := make(map[string]interface{}, len(defaultFlags)+1)
+	for name, value := range defaultFlags {
+		flags[name] = value
+	}
+	flags[versionIDFlagName] = srcurl.VersionID
+	return flags
+}
+
+// isVanished reports whether the pinned version of srcurl doesn't exist
+// anymore, according to the error of its transfer.
+func isVanished(srcurl *url.URL, err error) bool {
+	return srcurl.VersionID != "" && storage.IsVersionNotFoundError(err)
+}
+
+func validatePinVersions(c *cli.Context, srcurl *url.URL) error {
+	if !c.Bool(pinVersionsFlagName) {
+		return nil
+	}
+	if !srcurl.IsRemote() {
+		return fmt.Errorf("%q flag can only be used with remote source", pinVersionsFlagName)
+	}
+	if c.Command.Name == "mv" {
+		return fmt.Errorf("%q flag cannot be used with mv, since it would delete the pinned versions permanently", pinVersionsFlagName)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package command
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/peak/s5cmd/v2/storage"
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+func TestPinnedSourceURL(t *testing.T) {
+	srcurl, err := url.New("s3://bucket/prefix/*")
+	assert.NilError(t, err)
+
+	pinned := pinnedSourceURL(srcurl)
+	assert.Assert(t, pinned.AllVersions)
+	assert.Assert(t, !srcurl.AllVersions)
+
+	// the given version is pinned as is.
+	versioned, err := url.New("s3://bucket/key", url.WithVersion("v1"))
+	assert.NilError(t, err)
+	assert.Equal(t, versioned, pinnedSourceURL(versioned))
+}
+
+func TestIsPinnedVersion(t *testing.T) {
+	testcases := []struct {
+		name     string
+		object   *storage.Object
+		expected bool
+	}{
+		{
+			name:     "latest",
+			object:   &storage.Object{IsLatest: true},
+			expected: true,
+		},
+		{
+			name:     "noncurrent",
+			object:   &storage.Object{},
+			expected: false,
+		},
+		{
+			name:     "delete marker",
+			object:   &storage.Object{IsLatest: true, DeleteMarker: true},
+			expected: false,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, isPinnedVersion(tc.object))
+		})
+	}
+}
+
+func TestVersionFlags(t *testing.T) {
+	defaultFlags := map[string]interface{}{"raw": true}
+
+	srcurl, err := url.New("s3://bucket/key")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, defaultFlags, versionFlags(defaultFlags, srcurl))
+
+	srcurl.VersionID = "v1"
+	assert.DeepEqual(t, map[string]interface{}{"raw": true, "version-id": "v1"}, versionFlags(defaultFlags, srcurl))
+	// the default flags are shared by the commands, they are not modified.
+	assert.DeepEqual(t, map[string]interface{}{"raw": true}, defaultFlags)
+}
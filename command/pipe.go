@@ -28,6 +28,9 @@ Options:
 Examples:
 	01. Stream stdin to an object
 		 > echo "content" | gzip | s5cmd {{.HelpName}} s3://bucket/prefix/object.gz
+
+	02. Stream a download of known size to an object, deleting the object if the stream is truncated
+		 > curl -s https://example.com/archive.tar.gz | s5cmd {{.HelpName}} --expected-size 52428800 --delete-on-size-mismatch s3://bucket/archive.tar.gz
 `
 
 func NewPipeCommandFlags() []cli.Flag {
@@ -84,11 +87,32 @@ func NewPipeCommandFlags() []cli.Flag {
 			Name:  "content-disposition",
 			Usage: "set content disposition for target: defines content disposition header for object, e.g. --content-disposition 'attachment; filename=\"filename.jpg\"'",
 		},
+		&cli.StringFlag{
+			Name:  "content-language",
+			Usage: "set content language for target: defines content language header for object, e.g. --content-language en-US",
+		},
+		&cli.StringFlag{
+			Name:  "website-redirect",
+			Usage: "set website redirect location for target: redirects the requests for the object to the given key or URL if the bucket is configured as a website, e.g. --website-redirect /new/page.html",
+		},
+		&cli.GenericFlag{
+			Name:  "system-header",
+			Value: &HeaderValue{Allowed: storage.SystemHeaders()},
+			Usage: "set a system-defined metadata header for target, overriding its dedicated flag, can be specified multiple times, e.g. --system-header 'Content-Language=de'",
+		},
 		&cli.BoolFlag{
 			Name:    "no-clobber",
 			Aliases: []string{"n"},
 			Usage:   "do not overwrite destination if already exists",
 		},
+		&cli.StringFlag{
+			Name:  "expected-size",
+			Usage: "verify that the size of the uploaded object is the given size, e.g. 1048576 or 1MiB",
+		},
+		&cli.BoolFlag{
+			Name:  "delete-on-size-mismatch",
+			Usage: "delete the uploaded object if its size doesn't match --expected-size",
+		},
 	}
 	return pipeFlags
 }
@@ -141,6 +165,12 @@ type Pipe struct {
 	contentType        string
 	contentEncoding    string
 	contentDisposition string
+	contentLanguage    string
+	websiteRedirect    string
+	systemHeaders      map[string]string
+	// expectedSize is -1 if the size of the object is not verified.
+	expectedSize         int64
+	deleteOnSizeMismatch bool
 
 	// s3 options
 	concurrency int
@@ -158,24 +188,35 @@ func NewPipe(c *cli.Context, deleteSource bool) (*Pipe, error) {
 		return nil, err
 	}
 
+	expectedSize, err := parseExpectedSize(c)
+	if err != nil {
+		printError(fullCommand, c.Command.Name, err)
+		return nil, err
+	}
+
 	return &Pipe{
 		dst:          dst,
 		op:           c.Command.Name,
 		fullCommand:  fullCommand,
 		deleteSource: deleteSource,
 		// flags
-		noClobber:          c.Bool("no-clobber"),
-		storageClass:       storage.StorageClass(c.String("storage-class")),
-		concurrency:        c.Int("concurrency"),
-		partSize:           c.Int64("part-size") * megabytes,
-		encryptionMethod:   c.String("sse"),
-		encryptionKeyID:    c.String("sse-kms-key-id"),
-		acl:                c.String("acl"),
-		cacheControl:       c.String("cache-control"),
-		expires:            c.String("expires"),
-		contentType:        c.String("content-type"),
-		contentEncoding:    c.String("content-encoding"),
-		contentDisposition: c.String("content-disposition"),
+		noClobber:            c.Bool("no-clobber"),
+		storageClass:         storage.StorageClass(c.String("storage-class")),
+		concurrency:          c.Int("concurrency"),
+		partSize:             c.Int64("part-size") * megabytes,
+		encryptionMethod:     c.String("sse"),
+		encryptionKeyID:      c.String("sse-kms-key-id"),
+		acl:                  c.String("acl"),
+		cacheControl:         c.String("cache-control"),
+		expires:              c.String("expires"),
+		contentType:          c.String("content-type"),
+		contentEncoding:      c.String("content-encoding"),
+		contentDisposition:   c.String("content-disposition"),
+		contentLanguage:      c.String("content-language"),
+		websiteRedirect:      c.String("website-redirect"),
+		systemHeaders:        systemHeaders(c),
+		expectedSize:         expectedSize,
+		deleteOnSizeMismatch: c.Bool("delete-on-size-mismatch"),
 
 		// s3 options
 		storageOpts: NewStorageOpts(c),
@@ -224,11 +265,41 @@ func (c Pipe) Run(ctx context.Context) error {
 		metadata.SetContentDisposition(c.contentDisposition)
 	}
 
-	err = client.Put(ctx, &stdin{file: os.Stdin}, c.dst, metadata, c.concurrency, c.partSize)
+	if c.contentLanguage != "" {
+		metadata.SetContentLanguage(c.contentLanguage)
+	}
+
+	if c.websiteRedirect != "" {
+		metadata.SetWebsiteRedirect(c.websiteRedirect)
+	}
+
+	for name, value := range c.systemHeaders {
+		metadata.SetSystemHeader(name, value)
+	}
+
+	// the standard input is read with respect to the read limits, since it
+	// is often redirected from a file.
+	release, err := storage.NewLocalClient(c.storageOpts).AcquireRead(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	input := &stdin{file: os.Stdin}
+	err = client.Put(ctx, input, c.dst, metadata, c.concurrency, c.partSize)
+	auditLog.created(ctx, c.op, nil, c.dst, input.size, err)
 	if err != nil {
 		return err
 	}
 
+	if c.expectedSize >= 0 && !c.storageOpts.DryRun {
+		err := verifyUploadSize(ctx, client, c.dst, c.expectedSize, c.deleteOnSizeMismatch)
+		if err != nil {
+			printError(c.fullCommand, c.op, err)
+			return err
+		}
+	}
+
 	msg := log.InfoMessage{
 		Operation:   c.op,
 		Source:      nil,
@@ -298,7 +369,11 @@ func validatePipeCommand(c *cli.Context) error {
 		return fmt.Errorf("target %q can not contain glob characters", dst)
 	}
 
-	return nil
+	if err := validateWebsiteRedirect(c); err != nil {
+		return err
+	}
+
+	return validateExpectedSize(c)
 }
 
 func guessContentTypeByExtension(dsturl *url.URL) string {
@@ -315,8 +390,12 @@ func guessContentTypeByExtension(dsturl *url.URL) string {
 // a specific type of file that can not seekable.
 type stdin struct {
 	file *os.File
+	// size is the number of bytes read.
+	size int64
 }
 
 func (s *stdin) Read(p []byte) (n int, err error) {
-	return s.file.Read(p)
+	n, err = s.file.Read(p)
+	s.size += int64(n)
+	return n, err
 }
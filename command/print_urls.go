@@ -0,0 +1,177 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+
+	urlpkg "net/url"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/peak/s5cmd/v2/storage"
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+const (
+	printURLsFlagName     = "print-urls"
+	printURLsFileFlagName = "print-urls-file"
+
+	urlFormHTTPS   = "https"
+	urlFormConsole = "console"
+	urlFormS3      = "s3"
+
+	awsConsoleObjectURL = "https://console.aws.amazon.com/s3/object/"
+)
+
+// newPrintURLsFlags creates the flags which print the URLs of the uploaded
+// objects.
+func newPrintURLsFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.GenericFlag{
+			Name: printURLsFlagName,
+			Value: &EnumValue{
+				Enum: []string{urlFormHTTPS, urlFormConsole, urlFormS3},
+			},
+			Usage: "print the URL of each uploaded object in the given form: (https, console, s3)",
+		},
+		&cli.StringFlag{
+			Name:  printURLsFileFlagName,
+			Usage: "append the URLs printed with --print-urls to the given file, one per line",
+		},
+	}
+}
+
+// urlPrinter builds the URLs of the uploaded objects in the form given with
+// the "print-urls" flag, and writes them to the file given with the
+// "print-urls-file" flag, if any.
+type urlPrinter struct {
+	form string
+
+	mu   sync.Mutex
+	file *os.File
+	err  error
+}
+
+// newURLPrinter creates the printer of the URLs given with the flags. It
+// returns nil if the "print-urls" flag is not given.
+func newURLPrinter(c *cli.Context) (*urlPrinter, error) {
+	form := c.String(printURLsFlagName)
+	if form == "" {
+		return nil, nil
+	}
+
+	printer := &urlPrinter{form: form}
+	if path := c.String(printURLsFileFlagName); path != "" {
+		// the file is appended to, so that the cp commands of a run collect
+		// their URLs in the same file.
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, err
+		}
+		printer.file = file
+	}
+	return printer, nil
+}
+
+// url returns the URL of the object uploaded to dsturl by client. The URL is
+// written to the file as well, unless dryRun is set. It returns an empty
+// string if p is nil.
+func (p *urlPrinter) url(client *storage.S3, dsturl *url.URL, dryRun bool) (string, error) {
+	if p == nil {
+		return "", nil
+	}
+
+	objurl, err := objectURL(client, dsturl, p.form)
+	if err != nil {
+		return "", err
+	}
+	if p.file != nil && !dryRun {
+		p.write(objurl + "\n")
+	}
+	return objurl, nil
+}
+
+// write writes the line to the file. The first error is kept and the lines
+// after it are dropped.
+func (p *urlPrinter) write(line string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.err != nil {
+		return
+	}
+	_, p.err = p.file.WriteString(line)
+}
+
+// Close closes the file of the URLs, if any. It returns the first error the
+// URLs are written with, if any. It is a no-op if p is nil.
+func (p *urlPrinter) Close() error {
+	if p == nil || p.file == nil {
+		return nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	err := p.file.Close()
+	if p.err != nil {
+		return fmt.Errorf("could not write %q: %w", printURLsFileFlagName, p.err)
+	}
+	return err
+}
+
+// objectURL returns the URL of the object at dsturl in the given form. The
+// https URLs are built by the client, so that they are in the form of the
+// requests sent to its endpoint.
+func objectURL(client *storage.S3, dsturl *url.URL, form string) (string, error) {
+	switch form {
+	case urlFormHTTPS:
+		return client.ObjectURL(dsturl)
+	case urlFormConsole:
+		return consoleURL(dsturl, client.Region()), nil
+	default:
+		return dsturl.String(), nil
+	}
+}
+
+// consoleURL returns the link of the object at dsturl in the AWS console.
+func consoleURL(dsturl *url.URL, region string) string {
+	query := urlpkg.Values{}
+	query.Set("region", region)
+	query.Set("prefix", dsturl.Path)
+	return awsConsoleObjectURL + urlpkg.PathEscape(dsturl.Bucket) + "?" + query.Encode()
+}
+
+// printURL returns the URL of the object uploaded to dsturl in the form given
+// with the "print-urls" flag, or an empty string if the flag is not given.
+func (c Copy) printURL(ctx context.Context, dsturl *url.URL) (string, error) {
+	if c.urls == nil {
+		return "", nil
+	}
+
+	client, err := storage.NewRemoteClient(ctx, dsturl, c.storageOpts)
+	if err != nil {
+		return "", err
+	}
+	return c.urls.url(client, dsturl, c.storageOpts.DryRun)
+}
+
+func validatePrintURLs(c *cli.Context, dsturl *url.URL) error {
+	form := c.String(printURLsFlagName)
+	if form == "" {
+		if c.IsSet(printURLsFileFlagName) {
+			return fmt.Errorf("%q flag requires %q flag", printURLsFileFlagName, printURLsFlagName)
+		}
+		return nil
+	}
+	if !dsturl.IsRemote() {
+		return fmt.Errorf("%q flag can only be used with remote destinations", printURLsFlagName)
+	}
+	// the console only shows the objects in AWS S3.
+	if form == urlFormConsole && c.String("endpoint-url") != "" {
+		return fmt.Errorf("%q flag can not be %q with a custom endpoint", printURLsFlagName, urlFormConsole)
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+func TestConsoleURL(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		object   string
+		region   string
+		expected string
+	}{
+		{
+			name:     "object",
+			object:   "s3://bucket/dir/key.parquet",
+			region:   "eu-west-1",
+			expected: "https://console.aws.amazon.com/s3/object/bucket?prefix=dir%2Fkey.parquet&region=eu-west-1",
+		},
+		{
+			name:     "escaped key",
+			object:   "s3://bucket/a b&c.txt",
+			region:   "us-east-1",
+			expected: "https://console.aws.amazon.com/s3/object/bucket?prefix=a+b%26c.txt&region=us-east-1",
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			u, err := url.New(tc.object)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := consoleURL(u, tc.region); got != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
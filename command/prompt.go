@@ -0,0 +1,89 @@
+package command
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/mattn/go-isatty"
+
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+// isTerminal reports whether the given file is an interactive terminal.
+func isTerminal(f *os.File) bool {
+	return isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd())
+}
+
+// overwritePrompter asks the user whether existing destinations should be
+// overwritten. Prompts are serialized, so a worker waiting for an answer does
+// not interleave its prompt with others while the rest of the workers keep
+// processing non-conflicting objects.
+type overwritePrompter struct {
+	mu     sync.Mutex
+	in     *bufio.Reader
+	out    io.Writer
+	cancel context.CancelFunc
+
+	all  bool
+	quit bool
+}
+
+func newOverwritePrompter(in io.Reader, out io.Writer) *overwritePrompter {
+	return &overwritePrompter{
+		in:  bufio.NewReader(in),
+		out: out,
+	}
+}
+
+// Confirm asks whether dsturl should be overwritten. It returns true if the
+// user answers "yes" or "all". Once the user answers "all", no further
+// prompts are shown. If the user answers "quit", the operation is canceled
+// and context.Canceled is returned.
+func (p *overwritePrompter) Confirm(dsturl *url.URL) (bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.quit {
+		return false, context.Canceled
+	}
+
+	if p.all {
+		return true, nil
+	}
+
+	for {
+		fmt.Fprintf(p.out, "overwrite %q? (y)es/(n)o/(a)ll/(q)uit: ", dsturl)
+
+		answer, err := p.in.ReadString('\n')
+		if err != nil && answer == "" {
+			// treat a closed input as quit, there is no one to ask anymore.
+			p.abort()
+			return false, context.Canceled
+		}
+
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "y", "yes":
+			return true, nil
+		case "n", "no":
+			return false, nil
+		case "a", "all":
+			p.all = true
+			return true, nil
+		case "q", "quit":
+			p.abort()
+			return false, context.Canceled
+		}
+	}
+}
+
+func (p *overwritePrompter) abort() {
+	p.quit = true
+	if p.cancel != nil {
+		p.cancel()
+	}
+}
@@ -0,0 +1,87 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+func TestOverwritePrompterConfirm(t *testing.T) {
+	dsturl, err := url.New("s3://bucket/key")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	testcases := []struct {
+		name     string
+		input    string
+		expected []bool
+		err      error
+	}{
+		{
+			name:     "yes and no",
+			input:    "y\nno\nYES\n",
+			expected: []bool{true, false, true},
+		},
+		{
+			name:     "invalid answers are asked again",
+			input:    "maybe\n\nn\n",
+			expected: []bool{false},
+		},
+		{
+			name:     "all stops prompting",
+			input:    "a\n",
+			expected: []bool{true, true, true},
+		},
+		{
+			name:     "quit cancels",
+			input:    "y\nq\n",
+			expected: []bool{true, false, false},
+			err:      context.Canceled,
+		},
+		{
+			name:     "closed input cancels",
+			input:    "",
+			expected: []bool{false},
+			err:      context.Canceled,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			canceled := false
+
+			p := newOverwritePrompter(strings.NewReader(tc.input), &out)
+			p.cancel = func() { canceled = true }
+
+			var lastErr error
+			for i, want := range tc.expected {
+				got, err := p.Confirm(dsturl)
+				if err != nil {
+					lastErr = err
+				}
+				if got != want {
+					t.Errorf("answer %d: expected %v, got %v", i, want, got)
+				}
+			}
+
+			if !errors.Is(lastErr, tc.err) {
+				t.Errorf("expected error %v, got %v", tc.err, lastErr)
+			}
+
+			if canceled != (tc.err != nil) {
+				t.Errorf("expected canceled to be %v", tc.err != nil)
+			}
+
+			if !strings.Contains(out.String(), `overwrite "s3://bucket/key"?`) {
+				t.Errorf("unexpected prompt %q", out.String())
+			}
+		})
+	}
+}
@@ -78,7 +78,9 @@ func (b RemoveBucket) Run(ctx context.Context) error {
 		return err
 	}
 
-	if err := client.RemoveBucket(ctx, bucket.Bucket); err != nil {
+	err = client.RemoveBucket(ctx, bucket.Bucket)
+	auditLog.deleted(ctx, b.op, bucket, err)
+	if err != nil {
 		printError(b.fullCommand, b.op, err)
 		return err
 	}
@@ -0,0 +1,147 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/peak/s5cmd/v2/storage"
+	"github.com/peak/s5cmd/v2/strutil"
+)
+
+// storageHooks are the hooks of the S3 clients created by the commands, and
+// requestStatistics is the statistics collected by them. They are nil unless
+// the "stat" flag is given.
+var (
+	storageHooks      *storage.Hooks
+	requestStatistics *requestStats
+)
+
+type requestStartKey struct{}
+
+// requestStats collects the number, the errors and the latencies of the S3
+// requests by their operations. It is updated by the storage hooks, so it's
+// safe for concurrent use.
+type requestStats struct {
+	mu  sync.Mutex
+	ops map[string]*RequestStat
+}
+
+func newRequestStats() *requestStats {
+	return &requestStats{ops: map[string]*RequestStat{}}
+}
+
+// hooks returns the storage hooks which collect the statistics.
+func (s *requestStats) hooks() *storage.Hooks {
+	return storage.NewHooks().
+		OnRequest(s.start).
+		OnResponse(s.done)
+}
+
+func (s *requestStats) start(ctx context.Context, _ string, _ interface{}) context.Context {
+	return context.WithValue(ctx, requestStartKey{}, time.Now())
+}
+
+func (s *requestStats) done(ctx context.Context, operation string, _ interface{}, err error) {
+	var latency time.Duration
+	if start, ok := ctx.Value(requestStartKey{}).(time.Time); ok {
+		latency = time.Since(start)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stat, ok := s.ops[operation]
+	if !ok {
+		stat = &RequestStat{Operation: operation}
+		s.ops[operation] = stat
+	}
+	stat.Total++
+	if err != nil {
+		stat.Error++
+	}
+	stat.Latency += latency
+	if latency > stat.MaxLatency {
+		stat.MaxLatency = latency
+	}
+}
+
+// Statistics returns the statistics collected so far, sorted by operation.
+func (s *requestStats) Statistics() RequestStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := make(RequestStats, 0, len(s.ops))
+	for _, stat := range s.ops {
+		result = append(result, *stat)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Operation < result[j].Operation
+	})
+	return result
+}
+
+// RequestStat is the statistics of the S3 requests of an operation.
+type RequestStat struct {
+	Operation  string
+	Total      int64
+	Error      int64
+	Latency    time.Duration
+	MaxLatency time.Duration
+}
+
+// AverageLatency returns the average latency of the requests.
+func (s RequestStat) AverageLatency() time.Duration {
+	if s.Total == 0 {
+		return 0
+	}
+	return s.Latency / time.Duration(s.Total)
+}
+
+// RequestStats implements log.Message interface.
+type RequestStats []RequestStat
+
+// String is the string representation of RequestStats.
+func (s RequestStats) String() string {
+	var buf bytes.Buffer
+
+	w := tabwriter.NewWriter(&buf, 0, 8, 1, '\t', tabwriter.AlignRight)
+
+	fmt.Fprintf(w, "\n%s\t%s\t%s\t%s\t%s\t\n", "Request", "Total", "Error", "Avg Latency", "Max Latency")
+	for _, stat := range s {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%v\t%v\t\n", stat.Operation, stat.Total, stat.Error,
+			stat.AverageLatency().Round(time.Millisecond), stat.MaxLatency.Round(time.Millisecond))
+	}
+
+	w.Flush()
+	return buf.String()
+}
+
+// JSON is the JSON representation of RequestStats.
+func (s RequestStats) JSON() string {
+	var builder strings.Builder
+
+	for _, stat := range s {
+		builder.WriteString(strutil.JSON(struct {
+			Operation      string `json:"operation"`
+			Request        string `json:"request"`
+			Total          int64  `json:"total"`
+			Error          int64  `json:"error"`
+			AverageLatency string `json:"average_latency"`
+			MaxLatency     string `json:"max_latency"`
+		}{
+			Operation:      "request",
+			Request:        stat.Operation,
+			Total:          stat.Total,
+			Error:          stat.Error,
+			AverageLatency: stat.AverageLatency().Round(time.Millisecond).String(),
+			MaxLatency:     stat.MaxLatency.Round(time.Millisecond).String(),
+		}) + "\n")
+	}
+	return builder.String()
+}
@@ -0,0 +1,47 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestRequestStats(t *testing.T) {
+	stats := newRequestStats()
+
+	request := func(operation string, latency time.Duration, err error) {
+		ctx := stats.start(context.Background(), operation, nil)
+		time.Sleep(latency)
+		stats.done(ctx, operation, nil, err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			request("PutObject", time.Millisecond, nil)
+			if i%2 == 0 {
+				request("HeadObject", 0, errors.New("not found"))
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	got := stats.Statistics()
+	assert.Equal(t, 2, len(got))
+
+	assert.Equal(t, "HeadObject", got[0].Operation)
+	assert.Equal(t, int64(5), got[0].Total)
+	assert.Equal(t, int64(5), got[0].Error)
+
+	assert.Equal(t, "PutObject", got[1].Operation)
+	assert.Equal(t, int64(10), got[1].Total)
+	assert.Equal(t, int64(0), got[1].Error)
+	assert.Assert(t, got[1].AverageLatency() >= time.Millisecond)
+	assert.Assert(t, got[1].MaxLatency >= got[1].AverageLatency())
+}
@@ -3,8 +3,12 @@ package command
 import (
 	"context"
 	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
 
-	"github.com/hashicorp/go-multierror"
 	"github.com/urfave/cli/v2"
 
 	errorpkg "github.com/peak/s5cmd/v2/error"
@@ -12,6 +16,7 @@ import (
 	"github.com/peak/s5cmd/v2/log/stat"
 	"github.com/peak/s5cmd/v2/storage"
 	"github.com/peak/s5cmd/v2/storage/url"
+	"github.com/peak/s5cmd/v2/strutil"
 )
 
 var deleteHelpTemplate = `Name:
@@ -19,6 +24,7 @@ var deleteHelpTemplate = `Name:
 
 Usage:
 	{{.HelpName}} argument [argument]
+	{{.HelpName}} --manifest file
 
 Options:
 	{{range .VisibleFlags}}{{.}}
@@ -50,6 +56,30 @@ Examples:
    
 	9. Delete all versions of all objects in the bucket
 		 > s5cmd {{.HelpName}} --all-versions "s3://bucket/*"
+
+	10. Delete all objects with a prefix and only print the number of deleted objects
+		 > s5cmd {{.HelpName}} --quiet "s3://bucket/prefix/*"
+
+	11. Delete the noncurrent versions of all objects with a prefix, which became noncurrent more than 90 days ago
+		 > s5cmd {{.HelpName}} --all-versions --noncurrent-only --noncurrent-older-than 90d "s3://bucket/prefix/*"
+
+	12. Delete the noncurrent versions of all objects with a prefix and the delete markers left without any versions
+		 > s5cmd {{.HelpName}} --all-versions --noncurrent-only --expired-delete-markers "s3://bucket/prefix/*"
+
+	13. Delete all objects with a prefix 100 at a time, e.g. on a backend which throttles large batches
+		 > s5cmd {{.HelpName}} --delete-batch-size 100 "s3://bucket/prefix/*"
+
+	14. Delete the objects listed in a file, one full path per line
+		 > s5cmd {{.HelpName}} --manifest objects.txt
+
+	15. Delete the objects listed by ls, even if their keys contain newlines
+		 > s5cmd ls --show-fullpath -0 "s3://bucket/prefix/*" | s5cmd {{.HelpName}} --manifest - -0
+
+	16. Delete all objects under a prefix as "aws s3 rm --recursive" does
+		 > s5cmd {{.HelpName}} --recursive s3://bucket/prefix
+
+	17. Delete only the objects with a prefix which are in GLACIER or DEEP_ARCHIVE storage classes
+		 > s5cmd {{.HelpName}} --storage-class-filter GLACIER,DEEP_ARCHIVE "s3://bucket/prefix/*"
 `
 
 func NewDeleteCommand() *cli.Command {
@@ -57,7 +87,7 @@ func NewDeleteCommand() *cli.Command {
 		Name:     "rm",
 		HelpName: "rm",
 		Usage:    "remove objects",
-		Flags: []cli.Flag{
+		Flags: append([]cli.Flag{
 			&cli.BoolFlag{
 				Name:  "raw",
 				Usage: "disable the wildcard operations, useful with filenames that contains glob characters",
@@ -74,7 +104,35 @@ func NewDeleteCommand() *cli.Command {
 				Name:  "version-id",
 				Usage: "use the specified version of an object",
 			},
-		},
+			&cli.BoolFlag{
+				Name:    "quiet",
+				Aliases: []string{"q"},
+				Usage:   "do not print deleted objects, only print errors and the number of deleted objects",
+			},
+			&cli.BoolFlag{
+				Name:  "noncurrent-only",
+				Usage: "delete only noncurrent versions of objects, current versions and delete markers are kept (requires --all-versions)",
+			},
+			&cli.StringFlag{
+				Name:  "noncurrent-older-than",
+				Usage: "delete only the noncurrent versions that became noncurrent more than the given duration ago, e.g. 90d or 36h (requires --noncurrent-only)",
+			},
+			&cli.BoolFlag{
+				Name:  "expired-delete-markers",
+				Usage: "also delete the delete markers whose only remaining version is the delete marker itself (requires --noncurrent-only)",
+			},
+			newDeleteBatchSizeFlag(),
+			&cli.StringFlag{
+				Name:  "manifest",
+				Usage: "read the full paths of the objects to remove from the given file, one per line, or from standard input if \"-\"; wildcards in the paths are not expanded",
+			},
+			&cli.BoolFlag{
+				Name:    "null",
+				Aliases: []string{"0"},
+				Usage:   "read the full paths in the manifest delimited with NUL instead of newline, requires --manifest",
+			},
+			newStorageClassFilterFlag(),
+		}, newAWSCLIFlags("remove")...),
 		CustomHelpTemplate: deleteHelpTemplate,
 		Before: func(c *cli.Context) error {
 			err := validateRMCommand(c)
@@ -87,8 +145,40 @@ func NewDeleteCommand() *cli.Command {
 			defer stat.Collect(c.Command.FullName(), &err)()
 			fullCommand := commandFromContext(c)
 
-			sources := c.Args().Slice()
-			srcUrls, err := newURLs(c.Bool("raw"), c.String("version-id"), c.Bool("all-versions"), sources...)
+			noncurrentOlderThan, err := parseNoncurrentOlderThan(c)
+			if err != nil {
+				printError(fullCommand, c.Command.Name, err)
+				return err
+			}
+
+			printAWSCLINotices(c, c.Args().Slice()...)
+
+			sources, raw := recursiveSources(c, c.Args().Slice()...), c.Bool("raw")
+			if manifest := c.String("manifest"); manifest != "" {
+				// the paths in the manifest are the exact paths of the
+				// objects, e.g. as listed by "ls --show-fullpath".
+				sources, err = readManifest(c.Context, manifest, inputDelimiter(c), "remove")
+				if err != nil {
+					printError(fullCommand, c.Command.Name, err)
+					return err
+				}
+				raw = true
+			}
+
+			srcUrls, err := newURLs(raw, c.String("version-id"), c.Bool("all-versions"), sources...)
+			if err != nil {
+				printError(fullCommand, c.Command.Name, err)
+				return err
+			}
+
+			if c.IsSet("manifest") {
+				if err := checkDeleteSources(srcUrls); err != nil {
+					printError(fullCommand, c.Command.Name, err)
+					return err
+				}
+			}
+
+			exclude, include, filters, err := parseFilters(c)
 			if err != nil {
 				printError(fullCommand, c.Command.Name, err)
 				return err
@@ -100,7 +190,15 @@ func NewDeleteCommand() *cli.Command {
 				fullCommand: fullCommand,
 
 				// flags
-				exclude: c.StringSlice("exclude"),
+				exclude:              exclude,
+				include:              include,
+				filters:              filters,
+				storageClasses:       newStorageClassFilter(c),
+				quiet:                c.Bool("quiet"),
+				noncurrentOnly:       c.Bool("noncurrent-only"),
+				noncurrentOlderThan:  noncurrentOlderThan,
+				expiredDeleteMarkers: c.Bool("expired-delete-markers"),
+				fromManifest:         c.IsSet("manifest"),
 
 				storageOpts: NewStorageOpts(c),
 			}.Run(c.Context)
@@ -118,7 +216,16 @@ type Delete struct {
 	fullCommand string
 
 	// flag options
-	exclude []string
+	exclude              []string
+	include              []string
+	filters              []awscliFilter
+	storageClasses       *storageClassFilter
+	quiet                bool
+	noncurrentOnly       bool
+	noncurrentOlderThan  time.Duration
+	expiredDeleteMarkers bool
+	// fromManifest is set if the sources are read from a manifest.
+	fromManifest bool
 
 	// storage options
 	storageOpts storage.Options
@@ -141,12 +248,18 @@ func (d Delete) Run(ctx context.Context) error {
 		return err
 	}
 
-	objch := expandSources(ctx, client, false, d.src...)
+	includePatterns, err := createExcludesFromWildcard(d.include)
+	if err != nil {
+		printError(d.fullCommand, d.op, err)
+		return err
+	}
+
+	objch := d.expandSources(ctx, client)
+	if d.noncurrentOnly {
+		objch = d.noncurrentVersions(objch)
+	}
 
-	var (
-		merrorObjects error
-		merrorResult  error
-	)
+	errs := newErrorSummary()
 
 	// do object->url transformation
 	urlch := make(chan *url.URL)
@@ -159,12 +272,15 @@ func (d Delete) Run(ctx context.Context) error {
 			}
 
 			if err := object.Err; err != nil {
-				merrorObjects = multierror.Append(merrorObjects, err)
+				errs.add(err, "")
 				printError(d.fullCommand, d.op, err)
 				continue
 			}
 
-			if isURLExcluded(excludePatterns, object.URL.Path, srcurl.Prefix) {
+			if isURLExcluded(excludePatterns, object.URL.Path, srcurl.Prefix) ||
+				!isURLIncluded(includePatterns, object.URL.Path, srcurl.Prefix) ||
+				isURLExcludedByFilters(d.filters, object.URL.Path, srcurl.Prefix) ||
+				!d.storageClasses.match(object.URL, object.StorageClass) {
 				continue
 			}
 
@@ -174,17 +290,28 @@ func (d Delete) Run(ctx context.Context) error {
 
 	resultch := client.MultiDelete(ctx, urlch)
 
+	var deleted, failed int64
 	for obj := range resultch {
 		if err := obj.Err; err != nil {
 			if errorpkg.IsCancelation(obj.Err) {
 				continue
 			}
 
-			merrorResult = multierror.Append(merrorResult, obj.Err)
+			failed++
+			errs.add(obj.Err, "")
+			auditLog.deleted(ctx, d.op, obj.URL, obj.Err)
+			recordSyncDelete(ctx, obj.Err)
 			printError(d.fullCommand, d.op, obj.Err)
 			continue
 		}
 
+		deleted++
+		auditLog.deleted(ctx, d.op, obj.URL, nil)
+		recordSyncDelete(ctx, nil)
+		if d.quiet {
+			continue
+		}
+
 		msg := log.InfoMessage{
 			Operation: d.op,
 			Source:    obj.URL,
@@ -192,7 +319,142 @@ func (d Delete) Run(ctx context.Context) error {
 		log.Info(msg)
 	}
 
-	return multierror.Append(merrorResult, merrorObjects).ErrorOrNil()
+	if d.quiet {
+		log.Info(DeleteSummaryMessage{
+			Operation: d.op,
+			Deleted:   deleted,
+			Failed:    failed,
+		})
+	}
+
+	return errs.err()
+}
+
+// expandSources returns the objects to remove. The remote objects in a
+// manifest are sent as they are, since they are neither listed nor checked
+// one by one, unlike the objects given as arguments.
+func (d Delete) expandSources(ctx context.Context, client storage.Storage) <-chan *storage.Object {
+	srcurl := d.src[0]
+	if !d.fromManifest || !srcurl.IsRemote() || srcurl.AllVersions {
+		return expandSources(ctx, client, false, d.src...)
+	}
+
+	ch := make(chan *storage.Object)
+	go func() {
+		defer close(ch)
+		for _, u := range d.src {
+			select {
+			case ch <- &storage.Object{URL: u}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+// noncurrentVersions filters the versions of objects sent to objch and only
+// sends the ones to be deleted: the noncurrent versions which became
+// noncurrent before the noncurrent-older-than duration and, if requested, the
+// expired delete markers. Versions listing sends the versions of a key
+// adjacently, they are collected and correlated per key.
+func (d Delete) noncurrentVersions(objch <-chan *storage.Object) <-chan *storage.Object {
+	filter := noncurrentVersionFilter{
+		cutoff:               time.Now().Add(-d.noncurrentOlderThan),
+		expiredDeleteMarkers: d.expiredDeleteMarkers,
+	}
+
+	ch := make(chan *storage.Object)
+	go func() {
+		defer close(ch)
+
+		var versions []*storage.Object
+		flush := func() {
+			for _, version := range filter.expired(versions) {
+				ch <- version
+			}
+			versions = versions[:0]
+		}
+
+		for object := range objch {
+			if object.Err != nil || object.Type.IsDir() {
+				ch <- object
+				continue
+			}
+
+			if len(versions) > 0 && versions[0].URL.Path != object.URL.Path {
+				flush()
+			}
+			versions = append(versions, object)
+		}
+		flush()
+	}()
+
+	return ch
+}
+
+// noncurrentVersionFilter selects the versions of a key to be deleted by
+// "rm --noncurrent-only".
+type noncurrentVersionFilter struct {
+	// versions which became noncurrent after cutoff are kept.
+	cutoff               time.Time
+	expiredDeleteMarkers bool
+}
+
+// expired returns the versions to be deleted among the given versions of a
+// single key. A version became noncurrent when its successor, the next newer
+// version, was created.
+func (f noncurrentVersionFilter) expired(versions []*storage.Object) []*storage.Object {
+	sort.SliceStable(versions, func(i, j int) bool {
+		vi, vj := versions[i], versions[j]
+		if vi.IsLatest != vj.IsLatest {
+			return vi.IsLatest
+		}
+		return vi.ModTime.After(*vj.ModTime)
+	})
+
+	var expired []*storage.Object
+	for i := 1; i < len(versions); i++ {
+		version := versions[i]
+		if version.DeleteMarker && !f.expiredDeleteMarkers {
+			continue
+		}
+
+		noncurrentSince := versions[i-1].ModTime
+		if noncurrentSince.After(f.cutoff) {
+			continue
+		}
+		expired = append(expired, version)
+	}
+
+	// a delete marker is expired if it is the latest version and all the
+	// other versions of the key are deleted.
+	if len(versions) > 0 && f.expiredDeleteMarkers {
+		latest := versions[0]
+		if latest.IsLatest && latest.DeleteMarker && len(expired) == len(versions)-1 {
+			expired = append(expired, latest)
+		}
+	}
+
+	return expired
+}
+
+// DeleteSummaryMessage is the structure for the summary of a delete
+// operation in quiet mode.
+type DeleteSummaryMessage struct {
+	Operation string `json:"operation"`
+	Deleted   int64  `json:"deleted"`
+	Failed    int64  `json:"failed"`
+}
+
+// String returns the string representation of DeleteSummaryMessage.
+func (m DeleteSummaryMessage) String() string {
+	return fmt.Sprintf("%v: %d objects deleted, %d failed", m.Operation, m.Deleted, m.Failed)
+}
+
+// JSON returns the JSON representation of DeleteSummaryMessage.
+func (m DeleteSummaryMessage) JSON() string {
+	return strutil.JSON(m)
 }
 
 // newSources creates object URL list from given sources.
@@ -213,9 +475,79 @@ func newURLs(isRaw bool, versionID string, isAllVersions bool, sources ...string
 	return urls, nil
 }
 
+// readManifest returns the full paths of the objects to op in the manifest at
+// path, or in the standard input if path is "-", which are delimited with
+// delim. The empty lines are skipped.
+func readManifest(ctx context.Context, path string, delim byte, op string) ([]string, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	var paths []string
+	reader := NewDelimitedReader(ctx, r, delim)
+	for line := range reader.Read() {
+		if delim == '\n' {
+			line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+		}
+		if line == "" {
+			continue
+		}
+		paths = append(paths, line)
+	}
+	if err := reader.Err(); err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("expected at least 1 object to %v in manifest %q", op, path)
+	}
+	return paths, nil
+}
+
+// parseNoncurrentOlderThan parses the duration given with the
+// noncurrent-older-than flag.
+func parseNoncurrentOlderThan(c *cli.Context) (time.Duration, error) {
+	value := c.String("noncurrent-older-than")
+	if value == "" {
+		return 0, nil
+	}
+
+	duration, err := strutil.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid noncurrent-older-than value: %w", err)
+	}
+	return duration, nil
+}
+
 func validateRMCommand(c *cli.Context) error {
-	if !c.Args().Present() {
-		return fmt.Errorf("expected at least 1 object to remove")
+	if c.IsSet("manifest") {
+		if c.Args().Present() {
+			return fmt.Errorf("%q flag can not be used with arguments", "manifest")
+		}
+		if c.String("manifest") == "" {
+			return fmt.Errorf("%q flag requires a file", "manifest")
+		}
+		if c.String("version-id") != "" {
+			return fmt.Errorf("it is not allowed to combine %q and %q flags", "manifest", "version-id")
+		}
+		if c.Bool(recursiveFlagName) {
+			return fmt.Errorf("it is not allowed to combine %q and %q flags", "manifest", recursiveFlagName)
+		}
+		if c.IsSet(storageClassFilterFlagName) {
+			return fmt.Errorf("it is not allowed to combine %q and %q flags", "manifest", storageClassFilterFlagName)
+		}
+	} else {
+		if c.Bool("null") {
+			return fmt.Errorf("%q flag requires %q flag", "null", "manifest")
+		}
+		if !c.Args().Present() {
+			return fmt.Errorf("expected at least 1 object to remove")
+		}
 	}
 
 	// It might be a reasonable request too. Consider that user wants to delete
@@ -232,7 +564,19 @@ func validateRMCommand(c *cli.Context) error {
 		return fmt.Errorf("version-id flag can only be used with single source object")
 	}
 
-	srcurls, err := newURLs(c.Bool("raw"), c.String("version-id"), c.Bool("all-versions"), c.Args().Slice()...)
+	if err := checkNoncurrentFlags(c); err != nil {
+		return err
+	}
+
+	if err := validateDeleteBatchSize(c); err != nil {
+		return err
+	}
+
+	if err := validateAWSCLIFlags(c, c.Args().Slice()...); err != nil {
+		return err
+	}
+
+	srcurls, err := newURLs(c.Bool("raw"), c.String("version-id"), c.Bool("all-versions"), recursiveSources(c, c.Args().Slice()...)...)
 	if err != nil {
 		return err
 	}
@@ -241,6 +585,22 @@ func validateRMCommand(c *cli.Context) error {
 		return err
 	}
 
+	// the storage classes of the objects are known only if they are listed.
+	for _, srcurl := range srcurls {
+		if err := validateStorageClassFilter(c, srcurl); err != nil {
+			return err
+		}
+		if c.IsSet(storageClassFilterFlagName) && !srcurl.IsWildcard() && !srcurl.AllVersions {
+			return fmt.Errorf("%q flag requires the objects to be matched with a wildcard, e.g. \"s3://bucket/prefix/*\"", storageClassFilterFlagName)
+		}
+	}
+
+	return checkDeleteSources(srcurls)
+}
+
+// checkDeleteSources checks whether the objects at srcurls can be removed in
+// a single command.
+func checkDeleteSources(srcurls []*url.URL) error {
 	var (
 		firstBucket         string
 		hasRemote, hasLocal bool
@@ -271,3 +631,48 @@ func validateRMCommand(c *cli.Context) error {
 
 	return nil
 }
+
+// checkNoncurrentFlags checks the flags which select noncurrent versions to
+// delete.
+func checkNoncurrentFlags(c *cli.Context) error {
+	noncurrentOnly := c.Bool("noncurrent-only")
+	if !noncurrentOnly {
+		if c.IsSet("noncurrent-older-than") || c.Bool("expired-delete-markers") {
+			return fmt.Errorf("noncurrent-older-than and expired-delete-markers flags can only be used with noncurrent-only flag")
+		}
+		return nil
+	}
+
+	if !c.Bool(allVersionsFlagName) {
+		return fmt.Errorf("noncurrent-only flag can only be used with all-versions flag")
+	}
+
+	// versions of a key must be listed adjacently to find out when they
+	// became noncurrent, listings of multiple sources are interleaved.
+	if c.Args().Len() > 1 {
+		return fmt.Errorf("noncurrent-only flag can only be used with single source")
+	}
+
+	_, err := parseNoncurrentOlderThan(c)
+	return err
+}
+
+// newDeleteBatchSizeFlag creates the flag of the number of objects deleted
+// with each DeleteObjects request.
+func newDeleteBatchSizeFlag() cli.Flag {
+	return &cli.IntFlag{
+		Name:  "delete-batch-size",
+		Value: storage.DeleteObjectsMax,
+		Usage: fmt.Sprintf("number of objects deleted with each request, at most %d; smaller batches spread the deletion over more requests, which may be throttled less", storage.DeleteObjectsMax),
+	}
+}
+
+func validateDeleteBatchSize(c *cli.Context) error {
+	if !c.IsSet("delete-batch-size") {
+		return nil
+	}
+	if size := c.Int("delete-batch-size"); size < 1 || size > storage.DeleteObjectsMax {
+		return fmt.Errorf("delete-batch-size must be between 1 and %d", storage.DeleteObjectsMax)
+	}
+	return nil
+}
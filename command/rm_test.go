@@ -1,10 +1,18 @@
 package command
 
 import (
+	"context"
 	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/urfave/cli/v2"
+
+	"github.com/peak/s5cmd/v2/storage"
+	"github.com/peak/s5cmd/v2/storage/url"
 )
 
 func TestValidateRMCommand(t *testing.T) {
@@ -71,3 +79,148 @@ func TestValidateRMCommand(t *testing.T) {
 		})
 	}
 }
+
+func TestNoncurrentVersionFilterExpired(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	daysAgo := func(days int) time.Time {
+		return now.Add(-time.Duration(days) * 24 * time.Hour)
+	}
+
+	version := func(id string, created time.Time, latest, deleteMarker bool) *storage.Object {
+		return &storage.Object{
+			URL:          &url.URL{Path: "key", VersionID: id},
+			ModTime:      &created,
+			IsLatest:     latest,
+			DeleteMarker: deleteMarker,
+		}
+	}
+
+	tests := []struct {
+		name                 string
+		versions             []*storage.Object
+		expiredDeleteMarkers bool
+		expected             []string
+	}{
+		{
+			name: "current version is kept",
+			versions: []*storage.Object{
+				version("v1", daysAgo(200), true, false),
+			},
+		},
+		{
+			name: "noncurrent since the successor is created",
+			versions: []*storage.Object{
+				// listed out of order
+				version("v1", daysAgo(200), false, false),
+				version("v3", daysAgo(10), true, false),
+				version("v2", daysAgo(100), false, false),
+			},
+			expected: []string{"v1"},
+		},
+		{
+			name: "delete markers are kept",
+			versions: []*storage.Object{
+				version("v3", daysAgo(100), true, true),
+				version("v2", daysAgo(150), false, true),
+				version("v1", daysAgo(200), false, false),
+			},
+			expected: []string{"v1"},
+		},
+		{
+			name: "expired delete markers",
+			versions: []*storage.Object{
+				version("v3", daysAgo(100), true, true),
+				version("v2", daysAgo(150), false, true),
+				version("v1", daysAgo(200), false, false),
+			},
+			expiredDeleteMarkers: true,
+			expected:             []string{"v2", "v1", "v3"},
+		},
+		{
+			name: "delete marker with remaining versions is kept",
+			versions: []*storage.Object{
+				version("v2", daysAgo(10), true, true),
+				version("v1", daysAgo(200), false, false),
+			},
+			expiredDeleteMarkers: true,
+		},
+		{
+			name: "lone delete marker",
+			versions: []*storage.Object{
+				version("v1", daysAgo(1), true, true),
+			},
+			expiredDeleteMarkers: true,
+			expected:             []string{"v1"},
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			filter := noncurrentVersionFilter{
+				cutoff:               daysAgo(90),
+				expiredDeleteMarkers: tc.expiredDeleteMarkers,
+			}
+
+			var got []string
+			for _, v := range filter.expired(tc.versions) {
+				got = append(got, v.URL.VersionID)
+			}
+
+			if len(got) != len(tc.expected) {
+				t.Fatalf("expected %v, got %v", tc.expected, got)
+			}
+			for i := range got {
+				if got[i] != tc.expected[i] {
+					t.Errorf("expected %v, got %v", tc.expected, got)
+				}
+			}
+		})
+	}
+}
+
+func TestReadManifest(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		content  string
+		delim    byte
+		expected []string
+	}{
+		{
+			name:     "newline",
+			content:  "s3://bucket/a\r\n\ns3://bucket/b c\ns3://bucket/d",
+			delim:    '\n',
+			expected: []string{"s3://bucket/a", "s3://bucket/b c", "s3://bucket/d"},
+		},
+		{
+			name:     "nul",
+			content:  "s3://bucket/a\nb\x00\x00s3://bucket/c\r\x00",
+			delim:    0,
+			expected: []string{"s3://bucket/a\nb", "s3://bucket/c\r"},
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "manifest")
+			if err := os.WriteFile(path, []byte(tc.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := readManifest(context.Background(), path, tc.delim, "remove")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package command
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"flag"
 	"fmt"
@@ -10,17 +11,17 @@ import (
 	"strings"
 
 	"github.com/hashicorp/go-multierror"
-	"github.com/kballard/go-shellquote"
 	"github.com/urfave/cli/v2"
 
 	"github.com/peak/s5cmd/v2/parallel"
+	"github.com/peak/s5cmd/v2/storage"
 )
 
 var runHelpTemplate = `Name:
 	{{.HelpName}} - {{.Usage}}
 
 Usage:
-	{{.HelpName}} [file]
+	{{.HelpName}} [options] [file]
 
 Options:
 	{{range .VisibleFlags}}{{.}}
@@ -31,6 +32,23 @@ Examples:
 
 	2. Read commands from standard input and execute in parallel.
 		 > cat commands.txt | s5cmd {{.HelpName}}
+
+	3. Execute the operations planned by a dry run, printed as JSON records
+		 > s5cmd --json --dry-run sync s3://bucket/prefix/ dir/ > plan.json
+		 > s5cmd {{.HelpName}} --input-format json plan.json
+
+	4. Print the number of operations, the bytes to transfer and the duration of the commands at 500MB/s, without executing them
+		 > s5cmd {{.HelpName}} --estimate --assume-throughput 500MB/s commands.txt
+
+	5. Print the estimate of the commands, then execute them showing their progress
+		 > s5cmd {{.HelpName}} --estimate-then-run commands.txt
+
+	6. Execute the commands delimited with NUL, e.g. the ones generated for the keys which contain newlines
+		 > s5cmd {{.HelpName}} --null commands.bin
+
+	7. Write the commands which failed to a file along with their line numbers, then run them again
+		 > s5cmd {{.HelpName}} --failed-output failed.txt commands.txt
+		 > s5cmd {{.HelpName}} failed.txt
 `
 
 func NewRunCommand() *cli.Command {
@@ -39,6 +57,37 @@ func NewRunCommand() *cli.Command {
 		HelpName:           "run",
 		Usage:              "run commands in batch",
 		CustomHelpTemplate: runHelpTemplate,
+		Flags: []cli.Flag{
+			&cli.GenericFlag{
+				Name: "input-format",
+				Value: &EnumValue{
+					Enum:    []string{runInputAuto, runInputS5cmd, runInputJSON},
+					Default: runInputAuto,
+				},
+				Usage: "format of the input lines: s5cmd commands or JSON operation records, detected for each line if auto: (auto, s5cmd, json)",
+			},
+			&cli.BoolFlag{
+				Name:  "estimate",
+				Usage: "print the number of operations and the bytes to transfer by the commands, without executing them",
+			},
+			&cli.BoolFlag{
+				Name:  "estimate-then-run",
+				Usage: "print the estimate, then execute the commands showing their progress with respect to the estimate",
+			},
+			&cli.StringFlag{
+				Name:  "assume-throughput",
+				Usage: "throughput of the transfers to estimate the duration of the commands with, e.g. 500MB/s",
+			},
+			&cli.BoolFlag{
+				Name:    "null",
+				Aliases: []string{"0"},
+				Usage:   "read the commands delimited with NUL instead of newline, e.g. for the keys which contain newlines",
+			},
+			&cli.StringFlag{
+				Name:  failedOutputFlagName,
+				Usage: "write the lines of the commands which failed to the given file, each preceded by a comment with its line number, so that they can be run again",
+			},
+		},
 		Before: func(c *cli.Context) error {
 			err := validateRunCommand(c)
 			if err != nil {
@@ -46,8 +95,9 @@ func NewRunCommand() *cli.Command {
 			}
 			return err
 		},
-		Action: func(c *cli.Context) error {
+		Action: func(c *cli.Context) (err error) {
 			reader := os.Stdin
+			inputName := stdinInputName
 			if c.Args().Len() == 1 {
 				f, err := os.Open(c.Args().First())
 				if err != nil {
@@ -57,9 +107,29 @@ func NewRunCommand() *cli.Command {
 				defer f.Close()
 
 				reader = f
+				inputName = c.Args().First()
+			}
+
+			run := NewRun(c, reader)
+			run.inputName = inputName
+			if path := c.String(failedOutputFlagName); path != "" {
+				failed, err := openFailedOutput(path, run.delimiter)
+				if err != nil {
+					printError(commandFromContext(c), c.Command.Name, err)
+					return err
+				}
+				defer func() {
+					if closeErr := failed.Close(); closeErr != nil {
+						printError(commandFromContext(c), c.Command.Name, closeErr)
+						if err == nil {
+							err = closeErr
+						}
+					}
+				}()
+				run.failed = failed
 			}
 
-			return NewRun(c, reader).Run(c.Context)
+			return run.Run(c.Context)
 		},
 	}
 }
@@ -69,138 +139,304 @@ type Run struct {
 	reader io.Reader
 
 	// flags
-	numWorkers int
+	numWorkers      int
+	inputFormat     string
+	estimate        bool
+	estimateThenRun bool
+	// throughput is the assumed throughput of the transfers in bytes per
+	// second, or 0 if no throughput is assumed.
+	throughput  int64
+	storageOpts storage.Options
+	// delimiter is the delimiter of the commands in the input.
+	delimiter byte
+	// inputName is the name of the input in the positions of the commands,
+	// e.g. the path of the file.
+	inputName string
+	// failed records the commands which failed, if set.
+	failed *failedOutput
+
+	// done is called with the line number of each line of the input once
+	// its command is executed or skipped, if set.
+	done func(lineno int64)
 }
 
 func NewRun(c *cli.Context, r io.Reader) Run {
+	// the flag is validated before.
+	throughput, _ := parseThroughput(c.String("assume-throughput"))
+
 	return Run{
-		c:          c,
-		reader:     r,
-		numWorkers: c.Int("numworkers"),
+		c:               c,
+		reader:          r,
+		numWorkers:      c.Int("numworkers"),
+		inputFormat:     c.String("input-format"),
+		estimate:        c.Bool("estimate"),
+		estimateThenRun: c.Bool("estimate-then-run"),
+		throughput:      throughput,
+		delimiter:       inputDelimiter(c),
+		inputName:       stdinInputName,
+		storageOpts:     NewStorageOpts(c),
 	}
 }
 
+// inputDelimiter returns the delimiter of the lines of the input, which is NUL
+// if the "null" flag is set.
+func inputDelimiter(c *cli.Context) byte {
+	if c.Bool("null") {
+		return 0
+	}
+	return '\n'
+}
+
 func (r Run) Run(ctx context.Context) error {
-	pm := parallel.New(r.numWorkers)
-	defer pm.Close()
+	if r.estimate || r.estimateThenRun {
+		return r.runWithEstimate(ctx)
+	}
 
-	waiter := parallel.NewWaiter()
+	reader := NewDelimitedReader(ctx, r.reader, r.delimiter)
+	positions := newRunPositions(r.inputName, r.delimiter)
 
-	var errDoneCh = make(chan bool)
-	var merrorWaiter error
+	errs := newErrorSummary()
+	lines := make(chan runLine)
 	go func() {
-		defer close(errDoneCh)
-		for err := range waiter.Err() {
-			merrorWaiter = multierror.Append(merrorWaiter, err)
-		}
-	}()
+		defer close(lines)
 
-	reader := NewReader(ctx, r.reader)
+		var lineno int64 = -1
+		for line := range reader.Read() {
+			lineno++
+			pos := positions.advance(line)
 
-	lineno := -1
-	for line := range reader.Read() {
-		lineno++
+			fields, err := r.parseLine(line, pos)
+			if err != nil {
+				errs.add(err, "")
+				r.lineDone(lineno)
+				continue
+			}
 
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
-		}
+			if len(fields) == 0 {
+				r.lineDone(lineno)
+				continue
+			}
 
-		// skip comment lines
-		if strings.HasPrefix(line, "#") {
-			continue
+			runline := runLine{lineno: lineno, pos: pos, text: line, fields: fields}
+			if r.done != nil {
+				lineno := lineno
+				runline.done = func() { r.lineDone(lineno) }
+			}
+			lines <- runline
 		}
+	}()
 
-		fields, err := shellquote.Split(line)
-		if err != nil {
-			return err
-		}
+	errs.add(r.runCommands(lines), "")
 
-		if len(fields) == 0 {
-			continue
-		}
+	if reader.Err() != nil {
+		printError(commandFromContext(r.c), r.c.Command.Name, reader.Err())
+	}
 
-		if fields[0] == "run" {
-			err := fmt.Errorf("%q command (line: %v) is not permitted in run-mode", "run", lineno)
-			printError(commandFromContext(r.c), r.c.Command.Name, err)
-			continue
-		}
+	errs.add(reader.Err(), "")
+	return errs.err()
+}
 
-		fn := func() error {
-			subcmd := fields[0]
+// lineDone calls the done callback of the line, if set.
+func (r Run) lineDone(lineno int64) {
+	if r.done != nil {
+		r.done(lineno)
+	}
+}
 
-			cmd := AppCommand(subcmd)
-			if cmd == nil {
-				err := fmt.Errorf("%q command (line: %v) not found", subcmd, lineno)
-				printError(commandFromContext(r.c), r.c.Command.Name, err)
-				return nil
-			}
+// runLine is a line of the run input holding a command.
+type runLine struct {
+	lineno int64
+	pos    runPosition
+	// text is the line as it is read from the input.
+	text   string
+	fields []string
+	// done is called after the command is executed, if set.
+	done func()
+}
 
-			flagset := flag.NewFlagSet(subcmd, flag.ExitOnError)
-			if err := flagset.Parse(fields); err != nil {
-				printError(commandFromContext(r.c), r.c.Command.Name, err)
-				return nil
-			}
+// runCommands executes the commands received from the given channel in
+// parallel, and returns their errors once the channel is closed and all
+// commands are executed.
+func (r Run) runCommands(lines <-chan runLine) error {
+	pm := parallel.New(r.numWorkers)
+	defer pm.Close()
+
+	waiter := parallel.NewWaiter()
 
-			ctx := cli.NewContext(app, flagset, r.c)
-			return cmd.Run(ctx)
+	var (
+		errs      = newErrorSummary()
+		errDoneCh = make(chan bool)
+	)
+	go func() {
+		defer close(errDoneCh)
+		for err := range waiter.Err() {
+			errs.add(err, "")
 		}
+	}()
 
+	for line := range lines {
+		fn := r.commandFunc(line)
+		if done := line.done; done != nil {
+			run := fn
+			fn = func() error {
+				defer done()
+				return run()
+			}
+		}
 		pm.Run(fn, waiter)
 	}
 
 	waiter.Wait()
 	<-errDoneCh
 
-	if reader.Err() != nil {
-		printError(commandFromContext(r.c), r.c.Command.Name, reader.Err())
+	return errs.err()
+}
+
+// parseLine returns the command and the arguments of the line of the input
+// at the given position. It returns no fields if the line has nothing to
+// execute.
+func (r Run) parseLine(text string, pos runPosition) ([]string, error) {
+	line := strings.TrimSpace(text)
+	if line == "" {
+		return nil, nil
+	}
+
+	// skip comment lines
+	if strings.HasPrefix(line, "#") {
+		return nil, nil
+	}
+
+	fields, err := parseRunLine(line, r.inputFormat)
+	if err != nil {
+		err := fmt.Errorf("invalid input (%v): %w", pos, err)
+		printError(commandFromContext(r.c), r.c.Command.Name, err)
+		r.failed.record(pos, text)
+		return nil, err
 	}
 
-	return multierror.Append(merrorWaiter, reader.Err()).ErrorOrNil()
+	if len(fields) > 0 && fields[0] == "run" {
+		err := fmt.Errorf("%q command (%v) is not permitted in run-mode", "run", pos)
+		printError(commandFromContext(r.c), r.c.Command.Name, err)
+		r.failed.record(pos, text)
+		return nil, nil
+	}
+
+	return fields, nil
 }
 
+// commandFunc returns the function executing the command of the given line.
+// The errors of the command are reported with the position of the line.
+func (r Run) commandFunc(line runLine) func() error {
+	return func() error {
+		subcmd := line.fields[0]
+
+		cmd := AppCommand(subcmd)
+		if cmd == nil {
+			err := fmt.Errorf("%q command (%v) not found", subcmd, line.pos)
+			printError(commandFromContext(r.c), r.c.Command.Name, err)
+			r.failed.record(line.pos, line.text)
+			return nil
+		}
+
+		flagset := flag.NewFlagSet(subcmd, flag.ExitOnError)
+		if err := flagset.Parse(line.fields); err != nil {
+			printError(commandFromContext(r.c), r.c.Command.Name, err)
+			r.failed.record(line.pos, line.text)
+			return nil
+		}
+
+		ctx := cli.NewContext(app, flagset, r.c)
+		ctx.Context = withRunPosition(ctx.Context, line.pos)
+		err := cmd.Run(ctx)
+		if err != nil {
+			r.failed.record(line.pos, line.text)
+		}
+		return err
+	}
+}
+
+// readerBufferSize is the size of the buffer the lines are read with. The
+// lines longer than the buffer are read in multiple fragments, so it is not a
+// limit on the length of the lines, e.g. the ones of "rm" commands with
+// thousands of keys generated by sync.
+const readerBufferSize = 1 << 20
+
 // Reader is a cancelable reader.
 type Reader struct {
 	*bufio.Reader
 	err    error
 	linech chan string
 	ctx    context.Context
+	// delim is the delimiter of the lines.
+	delim byte
 }
 
 // NewReader creates a new reader with cancellation.
 func NewReader(ctx context.Context, r io.Reader) *Reader {
+	return NewDelimitedReader(ctx, r, '\n')
+}
+
+// NewDelimitedReader creates a new reader with cancellation, whose lines are
+// delimited with delim, e.g. NUL for the lines which may contain newlines. The
+// lines are sent without their delimiter, unless it is a newline.
+func NewDelimitedReader(ctx context.Context, r io.Reader, delim byte) *Reader {
 	reader := &Reader{
 		ctx:    ctx,
-		Reader: bufio.NewReader(r),
+		Reader: bufio.NewReaderSize(r, readerBufferSize),
 		linech: make(chan string),
+		delim:  delim,
 	}
 
 	go reader.read()
 	return reader
 }
 
-// read reads lines from the underlying reader.
+// read reads lines from the underlying reader. The lines are read into the
+// buffer of the reader, and copied once to be sent. Only the lines longer than
+// the buffer are collected in a separate buffer, which is reused for the
+// following ones.
 func (r *Reader) read() {
 	defer close(r.linech)
 
+	var long []byte
 	for {
-		select {
-		case <-r.ctx.Done():
-			r.err = r.ctx.Err()
+		if err := r.ctx.Err(); err != nil {
+			r.err = err
 			return
-		default:
-			// If ReadString encounters an error before finding a delimiter,
-			// it returns the data read before the error and the error itself (often io.EOF).
-			line, err := r.ReadString('\n')
-			if line != "" {
-				r.linech <- line
+		}
+
+		// If ReadSlice encounters an error before finding a delimiter,
+		// it returns the data read before the error and the error itself (often io.EOF).
+		line, err := r.ReadSlice(r.delim)
+		if err == bufio.ErrBufferFull {
+			long = append(long, line...)
+			continue
+		}
+		if len(long) > 0 {
+			long = append(long, line...)
+			line = long
+		}
+
+		// the empty lines delimited with NUL are sent as well, so that the
+		// lines are numbered as they are in the input.
+		if len(line) > 0 {
+			if r.delim != '\n' {
+				line = bytes.TrimSuffix(line, []byte{r.delim})
 			}
-			if err != nil {
-				if err == io.EOF {
-					return
-				}
+			select {
+			case r.linech <- string(line):
+			case <-r.ctx.Done():
+				r.err = r.ctx.Err()
+				return
+			}
+		}
+		long = long[:0]
+
+		if err != nil {
+			if err != io.EOF {
 				r.err = multierror.Append(r.err, err)
 			}
+			return
 		}
 	}
 }
@@ -219,5 +455,22 @@ func validateRunCommand(c *cli.Context) error {
 	if c.Args().Len() > 1 {
 		return fmt.Errorf("expected only 1 file")
 	}
+
+	if c.Bool("estimate") && c.Bool("estimate-then-run") {
+		return fmt.Errorf("it is not allowed to combine %q and %q flags", "estimate", "estimate-then-run")
+	}
+
+	if c.IsSet("assume-throughput") {
+		if !c.Bool("estimate") && !c.Bool("estimate-then-run") {
+			return fmt.Errorf("%q flag requires %q or %q flag", "assume-throughput", "estimate", "estimate-then-run")
+		}
+		throughput, err := parseThroughput(c.String("assume-throughput"))
+		if err != nil {
+			return err
+		}
+		if throughput == 0 {
+			return fmt.Errorf("assume-throughput must be greater than zero")
+		}
+	}
 	return nil
 }
@@ -0,0 +1,445 @@
+package command
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+
+	"github.com/peak/s5cmd/v2/log"
+	"github.com/peak/s5cmd/v2/progressbar"
+	"github.com/peak/s5cmd/v2/storage"
+	"github.com/peak/s5cmd/v2/storage/url"
+	"github.com/peak/s5cmd/v2/strutil"
+)
+
+const (
+	transferUpload   = "upload"
+	transferDownload = "download"
+	transferCopy     = "copy"
+)
+
+// commandEstimate is the estimate of a single command of the run input.
+type commandEstimate struct {
+	op string
+	// transfer is the kind of the transfer of the command, or empty if the
+	// command doesn't transfer any objects.
+	transfer string
+	source   sourceKey
+	objects  int64
+	bytes    int64
+	// unresolved reports whether the size of the source couldn't be
+	// resolved.
+	unresolved bool
+}
+
+// sourceKey identifies a source of the commands, so that the sources used by
+// multiple commands are resolved once.
+type sourceKey struct {
+	src            string
+	raw            bool
+	followSymlinks bool
+}
+
+// sourceSize is the resolved size of a source.
+type sourceSize struct {
+	objects int64
+	bytes   int64
+	err     error
+}
+
+// runWithEstimate reads all commands of the input and prints their estimate.
+// The commands are executed afterwards if estimateThenRun is set, showing
+// their progress with respect to the estimate.
+func (r Run) runWithEstimate(ctx context.Context) error {
+	reader := NewDelimitedReader(ctx, r.reader, r.delimiter)
+	positions := newRunPositions(r.inputName, r.delimiter)
+
+	var (
+		lines       []runLine
+		merrorParse error
+	)
+	var lineno int64 = -1
+	for line := range reader.Read() {
+		lineno++
+		pos := positions.advance(line)
+
+		fields, err := r.parseLine(line, pos)
+		if err != nil {
+			merrorParse = multierror.Append(merrorParse, err)
+			continue
+		}
+
+		if len(fields) == 0 {
+			continue
+		}
+
+		lines = append(lines, runLine{lineno: lineno, pos: pos, text: line, fields: fields})
+	}
+
+	if reader.Err() != nil {
+		printError(commandFromContext(r.c), r.c.Command.Name, reader.Err())
+		return multierror.Append(merrorParse, reader.Err())
+	}
+
+	estimates := r.estimateCommands(ctx, lines)
+	log.Info(newEstimateMessage(estimates, r.throughput))
+
+	if !r.estimateThenRun {
+		return merrorParse
+	}
+
+	pb := progressbar.New()
+	for _, estimate := range estimates {
+		pb.IncrementTotalObjects()
+		pb.AddTotalBytes(estimate.bytes)
+	}
+	pb.Start()
+	defer pb.Finish()
+
+	ch := make(chan runLine)
+	go func() {
+		defer close(ch)
+		for i, line := range lines {
+			estimate := estimates[i]
+			line.done = func() {
+				pb.AddCompletedBytes(estimate.bytes)
+				pb.IncrementCompletedObjects()
+			}
+			ch <- line
+		}
+	}()
+
+	err := r.runCommands(ch)
+	return multierror.Append(err, merrorParse).ErrorOrNil()
+}
+
+// estimateCommands returns the estimates of the given commands. The sizes of
+// the sources are resolved in parallel, once for each source.
+func (r Run) estimateCommands(ctx context.Context, lines []runLine) []commandEstimate {
+	estimates := make([]commandEstimate, len(lines))
+	sizes := map[sourceKey]*sourceSize{}
+	var sources []sourceKey
+	for i, line := range lines {
+		estimates[i] = estimateCommand(line.fields)
+		if estimates[i].transfer == "" || estimates[i].unresolved {
+			continue
+		}
+		if _, ok := sizes[estimates[i].source]; !ok {
+			sizes[estimates[i].source] = nil
+			sources = append(sources, estimates[i].source)
+		}
+	}
+
+	keys := make(chan sourceKey)
+	go func() {
+		defer close(keys)
+		for _, key := range sources {
+			keys <- key
+		}
+	}()
+
+	workers := r.numWorkers
+	if workers < 1 {
+		workers = 1
+	}
+
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex
+	)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for key := range keys {
+				size := r.resolveSource(ctx, key)
+				mu.Lock()
+				sizes[key] = &size
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	for i := range estimates {
+		estimate := &estimates[i]
+		if estimate.transfer == "" || estimate.unresolved {
+			continue
+		}
+
+		size := sizes[estimate.source]
+		if size.err != nil {
+			estimate.unresolved = true
+			continue
+		}
+		estimate.objects = size.objects
+		estimate.bytes = size.bytes
+	}
+	return estimates
+}
+
+// estimateCommand returns the estimate of the given command without the size
+// of its source.
+func estimateCommand(fields []string) commandEstimate {
+	estimate := commandEstimate{op: fields[0]}
+
+	switch estimate.op {
+	case "cp", "mv", "sync":
+	default:
+		return estimate
+	}
+
+	cmd := AppCommand(estimate.op)
+	flagset := flag.NewFlagSet(estimate.op, flag.ContinueOnError)
+	flagset.SetOutput(io.Discard)
+	for _, f := range cmd.Flags {
+		if err := f.Apply(flagset); err != nil {
+			estimate.unresolved = true
+			return estimate
+		}
+	}
+
+	if err := flagset.Parse(fields[1:]); err != nil || flagset.NArg() != 2 {
+		estimate.unresolved = true
+		return estimate
+	}
+
+	key := sourceKey{
+		src:            flagset.Arg(0),
+		raw:            isFlagTrue(flagset, "raw"),
+		followSymlinks: !isFlagTrue(flagset, "no-follow-symlinks"),
+	}
+
+	srcurl, err := url.New(key.src, url.WithRaw(key.raw))
+	if err != nil {
+		estimate.unresolved = true
+		return estimate
+	}
+	dsturl, err := url.New(flagset.Arg(1), url.WithRaw(key.raw))
+	if err != nil {
+		estimate.unresolved = true
+		return estimate
+	}
+
+	switch {
+	case srcurl.IsRemote() && dsturl.IsRemote():
+		estimate.transfer = transferCopy
+	case srcurl.IsRemote():
+		estimate.transfer = transferDownload
+	case dsturl.IsRemote():
+		estimate.transfer = transferUpload
+	default:
+		return estimate
+	}
+
+	estimate.source = key
+	return estimate
+}
+
+func isFlagTrue(flagset *flag.FlagSet, name string) bool {
+	f := flagset.Lookup(name)
+	return f != nil && f.Value.String() == "true"
+}
+
+// resolveSource returns the number of objects and the total size of the
+// objects of the given source. Directories, prefixes and wildcards are
+// walked.
+func (r Run) resolveSource(ctx context.Context, key sourceKey) sourceSize {
+	srcurl, err := url.New(key.src, url.WithRaw(key.raw))
+	if err != nil {
+		return sourceSize{err: err}
+	}
+
+	size := r.sizeOf(ctx, srcurl, key.followSymlinks)
+	if size.err != nil {
+		printDebug(r.c.Command.Name, size.err, srcurl)
+	}
+	return size
+}
+
+func (r Run) sizeOf(ctx context.Context, srcurl *url.URL, followSymlinks bool) sourceSize {
+	// stop listing on error.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	client, err := storage.NewClient(ctx, srcurl, r.storageOpts)
+	if err != nil {
+		return sourceSize{err: err}
+	}
+
+	isRemotePrefix := srcurl.IsRemote() && (srcurl.IsBucket() || srcurl.IsPrefix())
+	if !srcurl.IsWildcard() && !isRemotePrefix {
+		obj, err := client.Stat(ctx, srcurl)
+		if err != nil {
+			return sourceSize{err: err}
+		}
+		if !obj.Type.IsDir() {
+			return sourceSize{objects: 1, bytes: obj.Size}
+		}
+	}
+
+	// remote prefixes are walked by sync only, as "prefix/*".
+	if isRemotePrefix && !srcurl.IsWildcard() {
+		var err error
+		srcurl, err = url.New(fmt.Sprintf("s3://%v/%v*", srcurl.Bucket, url.EscapeGlob(srcurl.Path)))
+		if err != nil {
+			return sourceSize{err: err}
+		}
+	}
+
+	var size sourceSize
+	for object := range client.List(ctx, srcurl, followSymlinks) {
+		if err := object.Err; err != nil {
+			if err == storage.ErrNoObjectFound {
+				continue
+			}
+			return sourceSize{err: err}
+		}
+		if object.Type.IsDir() {
+			continue
+		}
+		size.objects++
+		size.bytes += object.Size
+	}
+	return size
+}
+
+// parseThroughput parses a throughput such as "500MB/s" or "1G", in bytes per
+// second.
+func parseThroughput(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+
+	throughput, err := strutil.ParseBytes(strings.TrimSuffix(strings.ToLower(strings.TrimSpace(s)), "/s"))
+	if err != nil {
+		return 0, fmt.Errorf("invalid assume-throughput %q", s)
+	}
+	return throughput, nil
+}
+
+// TransferEstimate is the estimate of the transfers of a kind.
+type TransferEstimate struct {
+	Operations int64 `json:"operations"`
+	Objects    int64 `json:"objects"`
+	Bytes      int64 `json:"bytes"`
+}
+
+func (e TransferEstimate) String() string {
+	return fmt.Sprintf("%v operations, %v objects, %v", e.Operations, e.Objects, strutil.HumanizeBytes(e.Bytes))
+}
+
+// EstimateMessage is the structure for the estimate of the commands printed
+// by "run --estimate".
+type EstimateMessage struct {
+	// Operations is the number of operations of each command.
+	Operations map[string]int64 `json:"operations"`
+	Upload     TransferEstimate `json:"upload"`
+	Download   TransferEstimate `json:"download"`
+	Copy       TransferEstimate `json:"copy"`
+	// Unresolved is the number of operations whose sources couldn't be
+	// sized.
+	Unresolved int64 `json:"unresolved"`
+	// Throughput is the assumed throughput in bytes per second, and ETA is
+	// the duration of the uploads and downloads with this throughput.
+	Throughput int64         `json:"throughput,omitempty"`
+	ETA        time.Duration `json:"-"`
+}
+
+func newEstimateMessage(estimates []commandEstimate, throughput int64) EstimateMessage {
+	msg := EstimateMessage{
+		Operations: map[string]int64{},
+		Throughput: throughput,
+	}
+
+	for _, estimate := range estimates {
+		msg.Operations[estimate.op]++
+		if estimate.unresolved {
+			msg.Unresolved++
+		}
+
+		var transfer *TransferEstimate
+		switch estimate.transfer {
+		case transferUpload:
+			transfer = &msg.Upload
+		case transferDownload:
+			transfer = &msg.Download
+		case transferCopy:
+			transfer = &msg.Copy
+		default:
+			continue
+		}
+		transfer.Operations++
+		transfer.Objects += estimate.objects
+		transfer.Bytes += estimate.bytes
+	}
+
+	// the copies are done on the server side, so they don't consume the
+	// throughput of the host.
+	if throughput > 0 {
+		seconds := float64(msg.Upload.Bytes+msg.Download.Bytes) / float64(throughput)
+		msg.ETA = time.Duration(seconds * float64(time.Second)).Round(time.Second)
+	}
+	return msg
+}
+
+// String returns the string representation of EstimateMessage.
+func (m EstimateMessage) String() string {
+	const rowFormat = "%-11s %s"
+
+	names := make([]string, 0, len(m.Operations))
+	for name := range m.Operations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	ops := make([]string, 0, len(names))
+	var total int64
+	for _, name := range names {
+		ops = append(ops, fmt.Sprintf("%v %v", m.Operations[name], name))
+		total += m.Operations[name]
+	}
+
+	operations := fmt.Sprint(total)
+	if len(ops) > 0 {
+		operations = fmt.Sprintf("%v (%v)", total, strings.Join(ops, ", "))
+	}
+
+	lines := []string{
+		fmt.Sprintf(rowFormat, "operations", operations),
+		fmt.Sprintf(rowFormat, "upload", m.Upload),
+		fmt.Sprintf(rowFormat, "download", m.Download),
+		fmt.Sprintf(rowFormat, "copy", m.Copy),
+		fmt.Sprintf(rowFormat, "unresolved", fmt.Sprintf("%v operations", m.Unresolved)),
+	}
+	if m.Throughput > 0 {
+		eta := fmt.Sprintf("%v at %v/s", m.ETA, strutil.HumanizeBytes(m.Throughput))
+		lines = append(lines, fmt.Sprintf(rowFormat, "eta", eta))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// JSON returns the JSON representation of EstimateMessage.
+func (m EstimateMessage) JSON() string {
+	var eta string
+	if m.Throughput > 0 {
+		eta = m.ETA.String()
+	}
+
+	return strutil.JSON(struct {
+		Operation string `json:"operation"`
+		EstimateMessage
+		ETA string `json:"eta,omitempty"`
+	}{
+		Operation:       "estimate",
+		EstimateMessage: m,
+		ETA:             eta,
+	})
+}
@@ -0,0 +1,120 @@
+package command
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestEstimateCommand(t *testing.T) {
+	testcases := []struct {
+		name     string
+		fields   []string
+		expected commandEstimate
+	}{
+		{
+			name:   "upload",
+			fields: []string{"cp", "--storage-class", "STANDARD_IA", "dir/", "s3://bucket/prefix/"},
+			expected: commandEstimate{
+				op:       "cp",
+				transfer: transferUpload,
+				source:   sourceKey{src: "dir/", followSymlinks: true},
+			},
+		},
+		{
+			name:   "download without following symlinks",
+			fields: []string{"mv", "--no-follow-symlinks", "s3://bucket/*", "dir/"},
+			expected: commandEstimate{
+				op:       "mv",
+				transfer: transferDownload,
+				source:   sourceKey{src: "s3://bucket/*"},
+			},
+		},
+		{
+			name:   "raw copy",
+			fields: []string{"sync", "--raw", "s3://bucket/a*b", "s3://target/"},
+			expected: commandEstimate{
+				op:       "sync",
+				transfer: transferCopy,
+				source:   sourceKey{src: "s3://bucket/a*b", raw: true, followSymlinks: true},
+			},
+		},
+		{
+			name:     "command without transfer",
+			fields:   []string{"rm", "s3://bucket/*"},
+			expected: commandEstimate{op: "rm"},
+		},
+		{
+			name:     "unknown flag",
+			fields:   []string{"cp", "--unknown", "s3://bucket/key", "dir/"},
+			expected: commandEstimate{op: "cp", unresolved: true},
+		},
+		{
+			name:     "missing destination",
+			fields:   []string{"cp", "s3://bucket/key"},
+			expected: commandEstimate{op: "cp", unresolved: true},
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			got := estimateCommand(tc.fields)
+			if diff := cmp.Diff(tc.expected, got, cmp.AllowUnexported(commandEstimate{}, sourceKey{})); diff != "" {
+				t.Errorf("(-want +got):\n%v", diff)
+			}
+		})
+	}
+}
+
+func TestParseThroughput(t *testing.T) {
+	testcases := []struct {
+		value     string
+		expected  int64
+		expectErr bool
+	}{
+		{value: "500MB/s", expected: 500 << 20},
+		{value: "1G", expected: 1 << 30},
+		{value: "100", expected: 100},
+		{value: "", expected: 0},
+		{value: "fast", expectErr: true},
+	}
+
+	for _, tc := range testcases {
+		got, err := parseThroughput(tc.value)
+		if (err != nil) != tc.expectErr {
+			t.Errorf("%q: unexpected error: %v", tc.value, err)
+		}
+		if got != tc.expected {
+			t.Errorf("%q: expected %v, got %v", tc.value, tc.expected, got)
+		}
+	}
+}
+
+func TestNewEstimateMessage(t *testing.T) {
+	estimates := []commandEstimate{
+		{op: "cp", transfer: transferUpload, objects: 2, bytes: 3 << 20},
+		{op: "cp", transfer: transferDownload, objects: 1, bytes: 1 << 20},
+		{op: "sync", transfer: transferCopy, objects: 10, bytes: 1 << 30},
+		{op: "cp", transfer: transferDownload, unresolved: true},
+		{op: "rm"},
+	}
+
+	msg := newEstimateMessage(estimates, 1<<20)
+
+	// the copies are not included in the duration.
+	if msg.ETA != 4*time.Second {
+		t.Errorf("expected ETA of 4s, got %v", msg.ETA)
+	}
+
+	expected := `operations  5 (3 cp, 1 rm, 1 sync)
+upload      1 operations, 2 objects, 3.0M
+download    2 operations, 1 objects, 1024.0K
+copy        1 operations, 10 objects, 1024.0M
+unresolved  1 operations
+eta         4s at 1024.0K/s`
+	if got := msg.String(); got != expected {
+		t.Errorf("expected\n%v\ngot\n%v", expected, got)
+	}
+}
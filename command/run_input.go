@@ -0,0 +1,149 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/kballard/go-shellquote"
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	// runInputAuto detects the format of each line of the input.
+	runInputAuto = "auto"
+	// runInputS5cmd is the format of the lines holding s5cmd commands.
+	runInputS5cmd = "s5cmd"
+	// runInputJSON is the format of the lines holding JSON operation records,
+	// such as the ones printed by the commands with "--json --dry-run".
+	runInputJSON = "json"
+)
+
+// runOperation is a JSON operation record read by "run --input-format json".
+// Its fields are the ones of the JSON records printed by the operations, so
+// that the output of "--json --dry-run" can be executed as it is.
+type runOperation struct {
+	Operation   string   `json:"operation"`
+	Source      string   `json:"source"`
+	Destination string   `json:"destination"`
+	VersionID   string   `json:"version_id"`
+	Flags       []string `json:"flags"`
+
+	// Change is the kind of change of the records printed by "sync --diff".
+	Change string `json:"change"`
+	// Action is the command of the records printed by "sync --dry-run".
+	Action string `json:"action"`
+}
+
+// parseRunLine splits the given line of the run input to the command and its
+// arguments with respect to the input format. It returns no fields if the line
+// has nothing to execute.
+func parseRunLine(line, format string) ([]string, error) {
+	switch format {
+	case runInputJSON:
+		return parseRunOperation(line)
+	case runInputS5cmd:
+		return shellquote.Split(line)
+	default:
+		// s5cmd commands can't start with a brace, so a line starting with
+		// a brace is a JSON record.
+		if strings.HasPrefix(line, "{") {
+			return parseRunOperation(line)
+		}
+		return shellquote.Split(line)
+	}
+}
+
+// parseRunOperation decodes the JSON operation record to the command and its
+// arguments.
+func parseRunOperation(line string) ([]string, error) {
+	var op runOperation
+	if err := json.Unmarshal([]byte(line), &op); err != nil {
+		return nil, fmt.Errorf("invalid JSON operation record: %w", err)
+	}
+
+	switch op.Operation {
+	case "":
+		return nil, fmt.Errorf("JSON operation record has no operation")
+	case "diff_summary":
+		return nil, nil
+	case "diff":
+		// the changes planned by "sync --diff" are executed as the
+		// operations sync would run for them.
+		switch op.Change {
+		case DiffNew.name(), DiffModify.name():
+			op.Operation = "cp"
+		case DiffDelete.name():
+			op.Operation, op.Source, op.Destination = "rm", op.Destination, ""
+		default:
+			return nil, fmt.Errorf("unknown change %q in JSON diff record", op.Change)
+		}
+	case "sync-plan":
+		switch op.Action {
+		case "cp":
+			op.Operation = "cp"
+		case "rm":
+			op.Operation, op.Source, op.Destination = "rm", op.Destination, ""
+		default:
+			return nil, fmt.Errorf("unknown action %q in JSON sync plan record", op.Action)
+		}
+	}
+
+	if op.Source == "" && op.Destination == "" {
+		return nil, fmt.Errorf("%q JSON operation record has no source or destination", op.Operation)
+	}
+
+	fields := []string{op.Operation}
+
+	// the records hold the URLs of the objects rather than wildcards, they
+	// are used as they are if the command supports it.
+	if hasRawFlag(op.Operation) {
+		fields = append(fields, "--raw")
+	}
+	if op.VersionID != "" {
+		fields = append(fields, "--version-id", op.VersionID)
+	}
+	fields = append(fields, op.Flags...)
+
+	for _, arg := range []string{op.Source, op.Destination} {
+		if arg != "" {
+			fields = append(fields, arg)
+		}
+	}
+	return fields, nil
+}
+
+var (
+	rawFlagCommandsOnce sync.Once
+	rawFlagCommands     map[string]bool
+)
+
+// hasRawFlag reports whether the command with the given name has "raw" flag.
+// The commands are built once rather than for each record, since building
+// them is far more expensive than decoding a record.
+func hasRawFlag(name string) bool {
+	rawFlagCommandsOnce.Do(func() {
+		rawFlagCommands = make(map[string]bool)
+		for _, cmd := range Commands() {
+			if hasFlag(cmd, "raw") {
+				for _, name := range cmd.Names() {
+					rawFlagCommands[name] = true
+				}
+			}
+		}
+	})
+	return rawFlagCommands[name]
+}
+
+// hasFlag reports whether the command has a flag with the given name.
+func hasFlag(cmd *cli.Command, name string) bool {
+	for _, flag := range cmd.Flags {
+		for _, flagName := range flag.Names() {
+			if flagName == name {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,144 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseRunLine(t *testing.T) {
+	testcases := []struct {
+		name      string
+		line      string
+		format    string
+		expected  []string
+		expectErr bool
+	}{
+		{
+			name:     "s5cmd command",
+			line:     `cp "s3://bucket/my file" dir/`,
+			format:   runInputS5cmd,
+			expected: []string{"cp", "s3://bucket/my file", "dir/"},
+		},
+		{
+			name:     "json record with s5cmd format",
+			line:     `{"operation":"rm","source":"s3://bucket/key"}`,
+			format:   runInputS5cmd,
+			expected: []string{"{operation:rm,source:s3://bucket/key}"},
+		},
+		{
+			name:     "copy record",
+			line:     `{"v":2,"operation":"cp","success":true,"source":"s3://bucket/key","destination":"dir/key"}`,
+			format:   runInputJSON,
+			expected: []string{"cp", "--raw", "s3://bucket/key", "dir/key"},
+		},
+		{
+			name:     "remove record with version",
+			line:     `{"operation":"rm","success":true,"source":"s3://bucket/key","version_id":"v1"}`,
+			format:   runInputJSON,
+			expected: []string{"rm", "--raw", "--version-id", "v1", "s3://bucket/key"},
+		},
+		{
+			name:     "record with flags",
+			line:     `{"operation":"cp","source":"file","destination":"s3://bucket/file","flags":["--sse","aws:kms"]}`,
+			format:   runInputJSON,
+			expected: []string{"cp", "--raw", "--sse", "aws:kms", "file", "s3://bucket/file"},
+		},
+		{
+			name:     "new diff record",
+			line:     `{"operation":"diff","change":"new","source":"s3://bucket/key","destination":"dir/key","reason":"source only"}`,
+			format:   runInputJSON,
+			expected: []string{"cp", "--raw", "s3://bucket/key", "dir/key"},
+		},
+		{
+			name:     "modify diff record",
+			line:     `{"operation":"diff","change":"modify","source":"s3://bucket/key","destination":"dir/key","reason":"differs"}`,
+			format:   runInputJSON,
+			expected: []string{"cp", "--raw", "s3://bucket/key", "dir/key"},
+		},
+		{
+			name:     "delete diff record",
+			line:     `{"operation":"diff","change":"delete","destination":"dir/key","reason":"destination only"}`,
+			format:   runInputJSON,
+			expected: []string{"rm", "--raw", "dir/key"},
+		},
+		{
+			name:     "copy sync plan record",
+			line:     `{"operation":"sync-plan","action":"cp","source":"s3://bucket/key","destination":"dir/key","reason":"newer"}`,
+			format:   runInputJSON,
+			expected: []string{"cp", "--raw", "s3://bucket/key", "dir/key"},
+		},
+		{
+			name:     "delete sync plan record",
+			line:     `{"operation":"sync-plan","action":"rm","destination":"dir/key","reason":"only-in-destination"}`,
+			format:   runInputJSON,
+			expected: []string{"rm", "--raw", "dir/key"},
+		},
+		{
+			name:      "unknown sync plan action",
+			line:      `{"operation":"sync-plan","action":"mv","source":"s3://bucket/key","destination":"dir/key"}`,
+			format:    runInputJSON,
+			expectErr: true,
+		},
+		{
+			name:   "diff summary record",
+			line:   `{"operation":"diff_summary","new":1,"deleted":0,"modified":0}`,
+			format: runInputJSON,
+		},
+		{
+			name:      "unknown diff change",
+			line:      `{"operation":"diff","change":"rename","destination":"dir/key"}`,
+			format:    runInputJSON,
+			expectErr: true,
+		},
+		{
+			name:      "record without operation",
+			line:      `{"key":"s3://bucket/key","size":5}`,
+			format:    runInputJSON,
+			expectErr: true,
+		},
+		{
+			name:      "record without urls",
+			line:      `{"operation":"cp"}`,
+			format:    runInputJSON,
+			expectErr: true,
+		},
+		{
+			name:      "invalid json",
+			line:      `{"operation":"cp"`,
+			format:    runInputJSON,
+			expectErr: true,
+		},
+		{
+			name:     "auto detects s5cmd command",
+			line:     "rm s3://bucket/key",
+			format:   runInputAuto,
+			expected: []string{"rm", "s3://bucket/key"},
+		},
+		{
+			name:     "auto detects json record",
+			line:     `{"operation":"rm","source":"s3://bucket/key"}`,
+			format:   runInputAuto,
+			expected: []string{"rm", "--raw", "s3://bucket/key"},
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseRunLine(tc.line, tc.format)
+			if tc.expectErr {
+				if err == nil {
+					t.Fatalf("expected error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tc.expected, got); diff != "" {
+				t.Errorf("(-want +got):\n%v", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package command
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	failedOutputFlagName = "failed-output"
+
+	// stdinInputName is the name of the standard input in the positions of
+	// the commands read from it.
+	stdinInputName = "stdin"
+	// planInputName is the name of the plan of a sync in the positions of the
+	// commands it generates, which are numbered in the order they are
+	// planned.
+	planInputName = "plan"
+)
+
+// runPosition is the position of a command in the input of run.
+type runPosition struct {
+	// input is the name of the input, e.g. the path of the file.
+	input string
+	// line is the line number of the command, starting from 1.
+	line int64
+	// offset is the byte offset of the line in the input.
+	offset int64
+}
+
+// String returns the position in "input:line" form, e.g. "commands.txt:3".
+func (p runPosition) String() string {
+	return fmt.Sprintf("%v:%v", p.input, p.line)
+}
+
+type runPositionKey struct{}
+
+// withRunPosition returns the context of the command at the given position,
+// whose errors are reported with the position.
+func withRunPosition(ctx context.Context, pos runPosition) context.Context {
+	return context.WithValue(ctx, runPositionKey{}, pos)
+}
+
+// runPositionFromContext returns the position of the command of c, if it is
+// run by run or sync.
+func runPositionFromContext(c *cli.Context) (runPosition, bool) {
+	if c.Context == nil {
+		return runPosition{}, false
+	}
+	pos, ok := c.Context.Value(runPositionKey{}).(runPosition)
+	return pos, ok
+}
+
+// commandPosition returns the position of the command of c in "input:line"
+// form, or an empty string if it is not run by run or sync.
+func commandPosition(c *cli.Context) string {
+	if pos, ok := runPositionFromContext(c); ok {
+		return pos.String()
+	}
+	return ""
+}
+
+// runPositions numbers the lines read from the input of run.
+type runPositions struct {
+	input string
+	delim byte
+	next  runPosition
+}
+
+func newRunPositions(input string, delim byte) *runPositions {
+	return &runPositions{
+		input: input,
+		delim: delim,
+		next:  runPosition{input: input, line: 1},
+	}
+}
+
+// advance returns the position of the given line, which is the next line of
+// the input. The lines delimited with newline hold their delimiter, and the
+// others don't.
+func (p *runPositions) advance(line string) runPosition {
+	pos := p.next
+	p.next.line++
+	p.next.offset += int64(len(line))
+	if p.delim != '\n' {
+		p.next.offset++
+	}
+	return pos
+}
+
+// failedOutput writes the lines of the commands which failed, each preceded
+// by a comment holding its position, so that the failed commands can be run
+// again with the same input.
+type failedOutput struct {
+	mu    sync.Mutex
+	file  *os.File
+	w     *bufio.Writer
+	delim byte
+	err   error
+}
+
+func openFailedOutput(path string, delim byte) (*failedOutput, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &failedOutput{file: f, w: bufio.NewWriter(f), delim: delim}, nil
+}
+
+// record writes the line of the failed command at the given position. It is
+// a no-op if o is nil.
+func (o *failedOutput) record(pos runPosition, line string) {
+	if o == nil {
+		return
+	}
+	if o.delim == '\n' {
+		line = strings.TrimRight(line, "\r\n")
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.err != nil {
+		return
+	}
+	_, o.err = fmt.Fprintf(o.w, "# %v (offset %v)%c%v%c", pos, pos.offset, o.delim, line, o.delim)
+}
+
+// Close flushes the recorded lines and closes the file.
+func (o *failedOutput) Close() error {
+	if o == nil {
+		return nil
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.err == nil {
+		o.err = o.w.Flush()
+	}
+	if err := o.file.Close(); o.err == nil {
+		o.err = err
+	}
+	return o.err
+}
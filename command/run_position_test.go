@@ -0,0 +1,98 @@
+package command
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"gotest.tools/v3/assert"
+)
+
+func TestRunPositions(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		delim    byte
+		lines    []string
+		expected []runPosition
+	}{
+		{
+			name:  "newline delimited",
+			delim: '\n',
+			lines: []string{"cp a b\n", "\n", "rm c\r\n", "ls"},
+			expected: []runPosition{
+				{input: "commands.txt", line: 1, offset: 0},
+				{input: "commands.txt", line: 2, offset: 7},
+				{input: "commands.txt", line: 3, offset: 8},
+				{input: "commands.txt", line: 4, offset: 14},
+			},
+		},
+		{
+			name:  "NUL delimited",
+			delim: 0,
+			lines: []string{"rm a\nb", "", "rm c"},
+			expected: []runPosition{
+				{input: "commands.txt", line: 1, offset: 0},
+				{input: "commands.txt", line: 2, offset: 7},
+				{input: "commands.txt", line: 3, offset: 8},
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			positions := newRunPositions("commands.txt", tc.delim)
+			var got []runPosition
+			for _, line := range tc.lines {
+				got = append(got, positions.advance(line))
+			}
+			if diff := cmp.Diff(tc.expected, got, cmp.AllowUnexported(runPosition{})); diff != "" {
+				t.Errorf("(-want +got):\n%v", diff)
+			}
+		})
+	}
+}
+
+func TestFailedOutput(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		delim    byte
+		expected string
+	}{
+		{
+			name:     "newline delimited",
+			delim:    '\n',
+			expected: "# commands.txt:2 (offset 7)\ncp a b\n",
+		},
+		{
+			name:     "NUL delimited",
+			delim:    0,
+			expected: "# commands.txt:2 (offset 7)\x00cp a b\n\x00",
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "failed.txt")
+			failed, err := openFailedOutput(path, tc.delim)
+			assert.NilError(t, err)
+
+			failed.record(runPosition{input: "commands.txt", line: 2, offset: 7}, "cp a b\n")
+			assert.NilError(t, failed.Close())
+
+			content, err := os.ReadFile(path)
+			assert.NilError(t, err)
+			assert.Equal(t, string(content), tc.expected)
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestReader(t *testing.T) {
+	// longer than both the buffer of the reader and the default limit of
+	// bufio.Scanner.
+	long := "rm" + strings.Repeat(" s3://bucket/key", 2*readerBufferSize/16)
+
+	testcases := []struct {
+		name     string
+		input    string
+		delim    byte
+		expected []string
+	}{
+		{
+			name:     "newline delimited",
+			input:    "cp a b\n\nrm c\n# comment",
+			delim:    '\n',
+			expected: []string{"cp a b\n", "\n", "rm c\n", "# comment"},
+		},
+		{
+			name:     "NUL delimited",
+			input:    "rm a\nb\x00rm c\x00",
+			delim:    0,
+			expected: []string{"rm a\nb", "rm c"},
+		},
+		{
+			name:     "empty NUL delimited lines",
+			input:    "rm a\x00\x00rm c",
+			delim:    0,
+			expected: []string{"rm a", "", "rm c"},
+		},
+		{
+			name:     "long lines",
+			input:    long + "\nrm c\n" + long,
+			delim:    '\n',
+			expected: []string{long + "\n", "rm c\n", long},
+		},
+		{
+			name:     "long NUL delimited lines",
+			input:    long + "\x00" + long + "\x00",
+			delim:    0,
+			expected: []string{long, long},
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			reader := NewDelimitedReader(context.Background(), strings.NewReader(tc.input), tc.delim)
+
+			var lines []string
+			for line := range reader.Read() {
+				lines = append(lines, line)
+			}
+			if err := reader.Err(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tc.expected, lines); diff != "" {
+				t.Errorf("(-want +got):\n%v", diff)
+			}
+		})
+	}
+}
+
+func TestReaderError(t *testing.T) {
+	errRead := errors.New("read error")
+	r := io.MultiReader(strings.NewReader("cp a b\nrm"), &errReader{err: errRead})
+
+	reader := NewReader(context.Background(), r)
+
+	var lines []string
+	for line := range reader.Read() {
+		lines = append(lines, line)
+	}
+	if diff := cmp.Diff([]string{"cp a b\n", "rm"}, lines); diff != "" {
+		t.Errorf("(-want +got):\n%v", diff)
+	}
+	if err := reader.Err(); err == nil || !strings.Contains(err.Error(), errRead.Error()) {
+		t.Errorf("expected %v, got %v", errRead, err)
+	}
+}
+
+type errReader struct {
+	err error
+}
+
+func (r *errReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
+
+// syntheticRunInput is an endless run input repeating the given lines.
+type syntheticRunInput struct {
+	lines  []byte
+	offset int
+}
+
+func (r *syntheticRunInput) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		copied := copy(p[n:], r.lines[r.offset:])
+		n += copied
+		r.offset = (r.offset + copied) % len(r.lines)
+	}
+	return n, nil
+}
+
+// BenchmarkRunReadAndParse reads and parses b.N lines of a synthetic run
+// input, e.g. 100M lines with "-benchtime 100000000x".
+func BenchmarkRunReadAndParse(b *testing.B) {
+	input := &syntheticRunInput{
+		lines: []byte(strings.Join([]string{
+			`cp "s3://bucket/prefix/my file.txt" dir/`,
+			`rm s3://bucket/prefix/a.txt s3://bucket/prefix/b.txt`,
+			`# comment`,
+			``,
+			`{"operation":"cp","source":"s3://bucket/key","destination":"dir/key"}`,
+		}, "\n") + "\n"),
+	}
+	run := Run{inputFormat: runInputAuto}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	reader := NewReader(ctx, input)
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(input.lines) / 5))
+	b.ResetTimer()
+
+	lines := reader.Read()
+	for i := 0; i < b.N; i++ {
+		if _, err := run.parseLine(<-lines, runPosition{input: stdinInputName, line: int64(i) + 1}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
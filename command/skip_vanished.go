@@ -0,0 +1,46 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/peak/s5cmd/v2/log"
+	"github.com/peak/s5cmd/v2/storage"
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+const skipVanishedFlagName = "skip-vanished"
+
+func newSkipVanishedFlag() cli.Flag {
+	return &cli.BoolFlag{
+		Name:  skipVanishedFlagName,
+		Usage: "skip the local files which are removed or renamed after they are listed, reporting them as vanished rather than as errors",
+	}
+}
+
+// isVanishedFile reports whether the local file of srcurl doesn't exist
+// anymore, according to the error of its transfer. The files are listed
+// before they are copied, so they existed once.
+func isVanishedFile(srcurl *url.URL, err error) bool {
+	if srcurl.IsRemote() || err == nil {
+		return false
+	}
+	var notFound *storage.ErrGivenObjectNotFound
+	return errors.As(err, &notFound) || errors.Is(err, fs.ErrNotExist)
+}
+
+// vanishFile reports the source file of srcurl, which doesn't exist anymore
+// when the copy starts, as vanished and records its transfer as such.
+func (c Copy) vanishFile(ctx context.Context, srcurl *url.URL) {
+	dsturl := prepareRemoteDestination(srcurl, c.dst, c.flatten, false)
+
+	ctx, done := startTransfer(ctx, c.op, srcurl, 0)
+	transferDestination(ctx, dsturl)
+	vanishTransfer(ctx, srcurl)
+	done(nil)
+
+	log.Info(VanishedMessage{Source: srcurl, Destination: dsturl})
+}
@@ -0,0 +1,76 @@
+package command
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/peak/s5cmd/v2/storage"
+	"github.com/peak/s5cmd/v2/strutil"
+)
+
+const (
+	tempDirFlagName = "temp-dir"
+	tempMaxFlagName = "temp-max"
+)
+
+// tempSpool holds the temporary files of the run, such as the objects
+// downloaded for the compare command of sync and the sorted chunks of the
+// compared objects. It is replaced by the one of the "temp-dir" and
+// "temp-max" flags before the commands are run.
+var tempSpool = storage.NewSpool("", 0)
+
+func newSpoolFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:        tempDirFlagName,
+			Usage:       "create the temporary files of the run in a directory of its own under the given directory, which is removed on exit",
+			DefaultText: "system default",
+		},
+		&cli.StringFlag{
+			Name:        tempMaxFlagName,
+			Usage:       "limit the total size of the temporary files of the run, e.g. 50GB; the operations which would exceed it fail early with a spool exhausted error",
+			DefaultText: "unlimited",
+		},
+	}
+}
+
+// newSpool returns the spool of the "temp-dir" and "temp-max" flags.
+func newSpool(c *cli.Context) (*storage.Spool, error) {
+	dir := c.String(tempDirFlagName)
+	if dir != "" {
+		fi, err := os.Stat(dir)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %v: %w", tempDirFlagName, err)
+		}
+		if !fi.IsDir() {
+			return nil, fmt.Errorf("invalid %v: %v is not a directory", tempDirFlagName, dir)
+		}
+	}
+
+	var limit int64
+	if c.IsSet(tempMaxFlagName) {
+		size, err := strutil.ParseBytes(c.String(tempMaxFlagName))
+		if err != nil {
+			return nil, fmt.Errorf("invalid %v: %w", tempMaxFlagName, err)
+		}
+		if size <= 0 {
+			return nil, fmt.Errorf("%q flag must be greater than zero", tempMaxFlagName)
+		}
+		limit = size
+	}
+	return storage.NewSpool(dir, limit), nil
+}
+
+// spoolDir returns the directory of the spool for the temporary files which
+// are created by the libraries, or the default directory for temporary files
+// if it can't be created.
+func spoolDir(op string) string {
+	dir, err := tempSpool.Dir()
+	if err != nil {
+		printDebug(op, fmt.Errorf("create temporary directory: %w", err))
+		return ""
+	}
+	return dir
+}
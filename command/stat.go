@@ -0,0 +1,309 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/peak/s5cmd/v2/log"
+	"github.com/peak/s5cmd/v2/log/stat"
+	"github.com/peak/s5cmd/v2/storage"
+	"github.com/peak/s5cmd/v2/storage/url"
+	"github.com/peak/s5cmd/v2/strutil"
+)
+
+var statHelpTemplate = `Name:
+	{{.HelpName}} - {{.Usage}}
+
+Usage:
+	{{.HelpName}} [options] s3://bucket
+
+Options:
+	{{range .VisibleFlags}}{{.}}
+	{{end}}
+Examples:
+	1. Show the region, versioning, object lock, encryption, public access block, lifecycle, CORS and policy configuration of a bucket
+		 > s5cmd {{.HelpName}} s3://bucket
+
+	2. Show the configuration of a bucket as JSON
+		 > s5cmd --json {{.HelpName}} s3://bucket
+`
+
+func NewStatCommand() *cli.Command {
+	cmd := &cli.Command{
+		Name:               "stat",
+		HelpName:           "stat",
+		Usage:              "show bucket configuration",
+		CustomHelpTemplate: statHelpTemplate,
+		Before: func(c *cli.Context) error {
+			err := validateStatCommand(c)
+			if err != nil {
+				printError(commandFromContext(c), c.Command.Name, err)
+			}
+			return err
+		},
+		Action: func(c *cli.Context) (err error) {
+			defer stat.Collect(c.Command.FullName(), &err)()
+
+			fullCommand := commandFromContext(c)
+
+			srcurl, err := url.New(c.Args().First())
+			if err != nil {
+				printError(fullCommand, c.Command.Name, err)
+				return err
+			}
+
+			return Stat{
+				src:         srcurl,
+				op:          c.Command.Name,
+				fullCommand: fullCommand,
+				storageOpts: NewStorageOpts(c),
+			}.Run(c.Context)
+		},
+	}
+
+	cmd.BashComplete = getBashCompleteFn(cmd, true, true)
+	return cmd
+}
+
+// Stat holds stat operation flags and states.
+type Stat struct {
+	src         *url.URL
+	op          string
+	fullCommand string
+
+	storageOpts storage.Options
+}
+
+// Run prints the configuration of the bucket. The configuration items are
+// read in parallel, and the items which can't be read, e.g. due to missing
+// permissions, are reported without failing the command.
+func (s Stat) Run(ctx context.Context) error {
+	bucket := s.src.Bucket
+
+	// the region of the bucket is resolved while creating the client.
+	client, err := storage.NewRemoteClient(ctx, s.src, s.storageOpts)
+	if err == nil {
+		err = client.HeadBucket(ctx, bucket)
+	}
+
+	// the configuration can be readable even if listing the bucket is
+	// denied, so only a nonexistent bucket is an error.
+	if err != nil && (client == nil || storage.IsNoSuchBucketError(err)) {
+		if storage.IsNoSuchBucketError(err) {
+			err = fmt.Errorf("bucket %q does not exist", bucket)
+		}
+		printError(s.fullCommand, s.op, err)
+		return err
+	}
+
+	msg := BucketStatMessage{
+		Bucket: bucket,
+		Errors: map[string]string{},
+	}
+
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex
+	)
+	read := func(name string, fn func() error) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := fn(); err != nil {
+				mu.Lock()
+				msg.Errors[name] = statError(err)
+				mu.Unlock()
+			}
+		}()
+	}
+
+	read("region", func() (err error) {
+		msg.Region, err = client.BucketRegion(ctx, bucket)
+		return err
+	})
+	read("versioning", func() error {
+		status, err := client.GetBucketVersioning(ctx, bucket)
+		if err != nil {
+			return err
+		}
+		msg.Versioning = status
+		if status == "" {
+			msg.Versioning = "Unversioned"
+		}
+		return nil
+	})
+	read("object_lock", func() error {
+		lock, err := client.GetObjectLockConfiguration(ctx, bucket)
+		if err != nil {
+			return err
+		}
+		msg.ObjectLock = &lock
+		return nil
+	})
+	read("encryption", func() error {
+		encryption, err := client.GetBucketEncryption(ctx, bucket)
+		if err != nil {
+			return err
+		}
+		msg.Encryption = &encryption
+		return nil
+	})
+	read("public_access_block", func() (err error) {
+		msg.PublicAccessBlock, err = client.GetPublicAccessBlock(ctx, bucket)
+		return err
+	})
+	read("lifecycle_rules", func() error {
+		count, err := client.BucketLifecycleRuleCount(ctx, bucket)
+		if err != nil {
+			return err
+		}
+		msg.LifecycleRules = &count
+		return nil
+	})
+	read("cors_rules", func() error {
+		count, err := client.BucketCORSRuleCount(ctx, bucket)
+		if err != nil {
+			return err
+		}
+		msg.CORSRules = &count
+		return nil
+	})
+	read("policy", func() error {
+		ok, err := client.HasBucketPolicy(ctx, bucket)
+		if err != nil {
+			return err
+		}
+		msg.Policy = &ok
+		return nil
+	})
+
+	wg.Wait()
+
+	log.Info(msg)
+	return nil
+}
+
+// statError returns the message of an error which a configuration item of
+// the bucket can't be read with.
+func statError(err error) string {
+	if storage.IsAccessDeniedError(err) {
+		return "access denied"
+	}
+	return cleanupError(err)
+}
+
+// BucketStatMessage is the structure for the configuration of a bucket.
+type BucketStatMessage struct {
+	Bucket            string                     `json:"bucket"`
+	Region            string                     `json:"region,omitempty"`
+	Versioning        string                     `json:"versioning,omitempty"`
+	ObjectLock        *storage.ObjectLock        `json:"object_lock,omitempty"`
+	Encryption        *storage.BucketEncryption  `json:"encryption,omitempty"`
+	PublicAccessBlock *storage.PublicAccessBlock `json:"public_access_block,omitempty"`
+	LifecycleRules    *int                       `json:"lifecycle_rules,omitempty"`
+	CORSRules         *int                       `json:"cors_rules,omitempty"`
+	Policy            *bool                      `json:"policy,omitempty"`
+	// Errors holds the errors of the items which can't be read, keyed by
+	// the JSON names of the items.
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// String returns the string representation of BucketStatMessage.
+func (m BucketStatMessage) String() string {
+	const rowFormat = "%-21s %s"
+
+	lines := []string{fmt.Sprintf(rowFormat, "Bucket:", m.Bucket)}
+	row := func(name, label, value string) {
+		if err, ok := m.Errors[name]; ok {
+			value = err
+		}
+		lines = append(lines, fmt.Sprintf(rowFormat, label+":", value))
+	}
+
+	row("region", "Region", m.Region)
+	row("versioning", "Versioning", m.Versioning)
+	row("object_lock", "Object lock", formatObjectLock(m.ObjectLock))
+	row("encryption", "Encryption", formatBucketEncryption(m.Encryption))
+	row("public_access_block", "Public access block", formatPublicAccessBlock(m.PublicAccessBlock))
+	row("lifecycle_rules", "Lifecycle rules", formatCount(m.LifecycleRules))
+	row("cors_rules", "CORS rules", formatCount(m.CORSRules))
+
+	policy := "none"
+	if m.Policy != nil && *m.Policy {
+		policy = "present"
+	}
+	row("policy", "Policy", policy)
+
+	return strings.Join(lines, "\n")
+}
+
+// JSON returns the JSON representation of BucketStatMessage.
+func (m BucketStatMessage) JSON() string {
+	return strutil.JSON(m)
+}
+
+func formatObjectLock(lock *storage.ObjectLock) string {
+	if lock == nil || !lock.Enabled {
+		return "Disabled"
+	}
+	switch {
+	case lock.Days > 0:
+		return fmt.Sprintf("Enabled, %v mode for %v days by default", lock.Mode, lock.Days)
+	case lock.Years > 0:
+		return fmt.Sprintf("Enabled, %v mode for %v years by default", lock.Mode, lock.Years)
+	default:
+		return "Enabled"
+	}
+}
+
+func formatBucketEncryption(encryption *storage.BucketEncryption) string {
+	if encryption == nil || encryption.Algorithm == "" {
+		return "none"
+	}
+	parts := []string{encryption.Algorithm}
+	if encryption.KMSKeyID != "" {
+		parts = append(parts, "key "+encryption.KMSKeyID)
+	}
+	if encryption.BucketKeyEnabled {
+		parts = append(parts, "bucket key enabled")
+	}
+	return strings.Join(parts, ", ")
+}
+
+func formatPublicAccessBlock(block *storage.PublicAccessBlock) string {
+	if block == nil {
+		return "not configured"
+	}
+	return fmt.Sprintf(
+		"BlockPublicAcls=%v IgnorePublicAcls=%v BlockPublicPolicy=%v RestrictPublicBuckets=%v",
+		block.BlockPublicAcls, block.IgnorePublicAcls, block.BlockPublicPolicy, block.RestrictPublicBuckets,
+	)
+}
+
+func formatCount(count *int) string {
+	if count == nil {
+		return "0"
+	}
+	return fmt.Sprint(*count)
+}
+
+func validateStatCommand(c *cli.Context) error {
+	if c.Args().Len() != 1 {
+		return fmt.Errorf("expected only 1 argument")
+	}
+
+	srcurl, err := url.New(c.Args().First())
+	if err != nil {
+		return err
+	}
+
+	if !srcurl.IsBucket() {
+		return fmt.Errorf("argument must be a bucket, e.g. s3://bucket")
+	}
+
+	return nil
+}
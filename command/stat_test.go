@@ -0,0 +1,46 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/peak/s5cmd/v2/storage"
+)
+
+func TestBucketStatMessage(t *testing.T) {
+	lifecycleRules, corsRules, policy := 2, 0, true
+
+	msg := BucketStatMessage{
+		Bucket:         "bucket",
+		Region:         "eu-west-1",
+		Versioning:     "Enabled",
+		ObjectLock:     &storage.ObjectLock{Enabled: true, Mode: "GOVERNANCE", Days: 30},
+		Encryption:     &storage.BucketEncryption{Algorithm: "aws:kms", KMSKeyID: "key-id", BucketKeyEnabled: true},
+		LifecycleRules: &lifecycleRules,
+		CORSRules:      &corsRules,
+		Policy:         &policy,
+		Errors: map[string]string{
+			"public_access_block": "access denied",
+		},
+	}
+
+	expected := `Bucket:               bucket
+Region:               eu-west-1
+Versioning:           Enabled
+Object lock:          Enabled, GOVERNANCE mode for 30 days by default
+Encryption:           aws:kms, key key-id, bucket key enabled
+Public access block:  access denied
+Lifecycle rules:      2
+CORS rules:           0
+Policy:               present`
+	if got := msg.String(); got != expected {
+		t.Errorf("expected\n%v\ngot\n%v", expected, got)
+	}
+
+	expected = `{"bucket":"bucket","region":"eu-west-1","versioning":"Enabled",` +
+		`"object_lock":{"enabled":true,"mode":"GOVERNANCE","days":30},` +
+		`"encryption":{"algorithm":"aws:kms","kms_key_id":"key-id","bucket_key_enabled":true},` +
+		`"lifecycle_rules":2,"cors_rules":0,"policy":true,"errors":{"public_access_block":"access denied"}}`
+	if got := msg.JSON(); got != expected {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
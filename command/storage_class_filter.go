@@ -0,0 +1,105 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/peak/s5cmd/v2/storage"
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+const storageClassFilterFlagName = "storage-class-filter"
+
+// newStorageClassFilterFlag creates the flag which selects the remote objects
+// to process by their storage classes.
+func newStorageClassFilterFlag() cli.Flag {
+	return &cli.StringFlag{
+		Name:  storageClassFilterFlagName,
+		Usage: "process only the remote objects in the given comma-separated storage classes, or the ones not in the classes prefixed with \"!\", e.g. GLACIER,DEEP_ARCHIVE or !STANDARD",
+	}
+}
+
+// storageClassFilter selects the objects either in the allowed storage
+// classes, or not in the denied ones. The classes are compared by their
+// names, so that the classes unknown to s5cmd can be filtered as well.
+type storageClassFilter struct {
+	classes []string
+	negated bool
+}
+
+// parseStorageClassFilter parses the value of the "storage-class-filter"
+// flag. It returns nil if the value is empty.
+func parseStorageClassFilter(value string) (*storageClassFilter, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	var (
+		filter  storageClassFilter
+		allowed bool
+	)
+	for _, class := range strings.Split(value, ",") {
+		class = strings.TrimSpace(class)
+		negated := strings.HasPrefix(class, "!")
+		class = strings.TrimSpace(strings.TrimPrefix(class, "!"))
+		if class == "" {
+			return nil, fmt.Errorf("invalid %q: empty storage class in %q", storageClassFilterFlagName, value)
+		}
+
+		if negated {
+			filter.negated = true
+		} else {
+			allowed = true
+		}
+		if filter.negated && allowed {
+			return nil, fmt.Errorf("invalid %q: the allowed and the negated storage classes can not be combined in %q", storageClassFilterFlagName, value)
+		}
+		filter.classes = append(filter.classes, class)
+	}
+	return &filter, nil
+}
+
+// newStorageClassFilter returns the filter given with the
+// "storage-class-filter" flag, or nil if it is not given. The flag is
+// validated before.
+func newStorageClassFilter(c *cli.Context) *storageClassFilter {
+	filter, _ := parseStorageClassFilter(c.String(storageClassFilterFlagName))
+	return filter
+}
+
+// match reports whether the object at u in the given storage class is
+// selected. The local files have no storage class, and are not filtered. A
+// nil filter matches all objects.
+func (f *storageClassFilter) match(u *url.URL, class storage.StorageClass) bool {
+	if f == nil || !u.IsRemote() {
+		return true
+	}
+	// S3 omits the storage class of STANDARD objects in some responses.
+	if class == "" {
+		class = "STANDARD"
+	}
+	for _, c := range f.classes {
+		if strings.EqualFold(c, string(class)) {
+			return !f.negated
+		}
+	}
+	return f.negated
+}
+
+// validateStorageClassFilter validates the "storage-class-filter" flag. The
+// storage classes are only known for the objects listed from a remote
+// storage, so the filter requires srcurl to be remote.
+func validateStorageClassFilter(c *cli.Context, srcurl *url.URL) error {
+	if !c.IsSet(storageClassFilterFlagName) {
+		return nil
+	}
+	if _, err := parseStorageClassFilter(c.String(storageClassFilterFlagName)); err != nil {
+		return err
+	}
+	if srcurl != nil && !srcurl.IsRemote() {
+		return fmt.Errorf("%q flag can only be used with remote objects", storageClassFilterFlagName)
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/peak/s5cmd/v2/storage"
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+func TestStorageClassFilter(t *testing.T) {
+	t.Parallel()
+
+	remote, err := url.New("s3://bucket/key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	local, err := url.New("dir/file")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := []struct {
+		name      string
+		value     string
+		matched   []storage.StorageClass
+		unmatched []storage.StorageClass
+		expectErr bool
+	}{
+		{
+			name:    "no filter",
+			value:   "",
+			matched: []storage.StorageClass{"STANDARD", "GLACIER"},
+		},
+		{
+			name:      "allowed classes",
+			value:     "GLACIER,DEEP_ARCHIVE",
+			matched:   []storage.StorageClass{"GLACIER", "DEEP_ARCHIVE"},
+			unmatched: []storage.StorageClass{"STANDARD", "GLACIER_IR", ""},
+		},
+		{
+			name:      "negated classes",
+			value:     "!STANDARD, !INTELLIGENT_TIERING",
+			matched:   []storage.StorageClass{"GLACIER", "STANDARD_IA"},
+			unmatched: []storage.StorageClass{"STANDARD", "INTELLIGENT_TIERING", ""},
+		},
+		{
+			name:      "classes are compared case-insensitively",
+			value:     "standard",
+			matched:   []storage.StorageClass{"STANDARD", ""},
+			unmatched: []storage.StorageClass{"GLACIER"},
+		},
+		{
+			name:      "unknown classes",
+			value:     "COLDLINE",
+			matched:   []storage.StorageClass{"COLDLINE"},
+			unmatched: []storage.StorageClass{"STANDARD"},
+		},
+		{
+			name:      "allowed and negated classes",
+			value:     "GLACIER,!STANDARD",
+			expectErr: true,
+		},
+		{
+			name:      "empty class",
+			value:     "GLACIER,,DEEP_ARCHIVE",
+			expectErr: true,
+		},
+		{
+			name:      "empty negated class",
+			value:     "!",
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			filter, err := parseStorageClassFilter(tc.value)
+			if tc.expectErr {
+				if err == nil {
+					t.Fatalf("expected an error for %q", tc.value)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			for _, class := range tc.matched {
+				if !filter.match(remote, class) {
+					t.Errorf("expected %q to match %q", tc.value, class)
+				}
+			}
+			for _, class := range tc.unmatched {
+				if filter.match(remote, class) {
+					t.Errorf("expected %q not to match %q", tc.value, class)
+				}
+			}
+			// the local files are not filtered.
+			if !filter.match(local, "") {
+				t.Errorf("expected %q to match the local files", tc.value)
+			}
+		})
+	}
+}
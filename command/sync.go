@@ -6,18 +6,22 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/hashicorp/go-multierror"
 	"github.com/lanrat/extsort"
 	"github.com/urfave/cli/v2"
 
 	errorpkg "github.com/peak/s5cmd/v2/error"
+	"github.com/peak/s5cmd/v2/log"
 	"github.com/peak/s5cmd/v2/log/stat"
 	"github.com/peak/s5cmd/v2/parallel"
 	"github.com/peak/s5cmd/v2/storage"
 	"github.com/peak/s5cmd/v2/storage/url"
+	"github.com/peak/s5cmd/v2/strutil"
 )
 
 const (
@@ -48,7 +52,7 @@ Examples:
 		 > s5cmd {{.HelpName}} --delete folder/ s3://bucket/
 
 	05. Sync S3 bucket to local folder but use size as only comparison criteria.
-		 > s5cmd {{.HelpName}} --size-only "s3://bucket/*" folder/
+		 > s5cmd {{.HelpName}} --sync-strategy size "s3://bucket/*" folder/
 
 	06. Sync a file to S3 bucket
 		 > s5cmd {{.HelpName}} myfile.gz s3://bucket/
@@ -64,6 +68,114 @@ Examples:
 
 	10. Sync all files to S3 bucket but exclude the ones with txt and gz extension
 		 > s5cmd {{.HelpName}} --exclude "*.txt" --exclude "*.gz" dir/ s3://bucket
+
+	11. Sync S3 bucket to local folder and print why the unchanged objects are skipped
+		 > s5cmd {{.HelpName}} --show-skips "s3://bucket/*" folder/
+
+	12. Sync append-only log files to S3 bucket, uploading only their new tails
+		 > s5cmd {{.HelpName}} --append-detect logs/ s3://bucket/logs/
+
+	13. Sync S3 bucket to another S3 bucket matching "v1/data/file" in source with "data/file" in destination
+		 > s5cmd {{.HelpName}} --delete --compare-key-strip-prefix v1/ "s3://bucket/*" s3://target-bucket/
+
+	14. Sync folder to S3 bucket, failing early if the bucket does not exist or is not accessible
+		 > s5cmd {{.HelpName}} --verify-bucket folder/ s3://bucket/
+
+	15. Sync S3 bucket to local folder, overwriting the local files that differ even if they are newer
+		 > s5cmd {{.HelpName}} --prefer-source "s3://bucket/*" folder/
+
+	16. Sync a folder on a FAT or SMB filesystem to S3 bucket, ignoring modification time differences up to 2 seconds
+		 > s5cmd {{.HelpName}} --skew-tolerance 2s /mnt/share/ s3://bucket/share/
+
+	17. Review the changes a sync with deletion would make on S3 bucket, in a format similar to "diff -r"
+		 > s5cmd --dry-run {{.HelpName}} --diff --delete folder/ s3://bucket/
+
+	18. Sync each top-level prefix of S3 bucket separately, 8 prefixes at a time, even if some of them fail
+		 > s5cmd {{.HelpName}} --partition-by-prefix --partition-concurrency 8 --continue-on-partition-failure "s3://bucket/*" s3://target-bucket/
+
+	19. Sync folder to S3 bucket, comparing the MD5 checksums of the files with the ETags of the objects
+		 > s5cmd {{.HelpName}} --sync-strategy checksum folder/ s3://bucket/
+
+	20. Sync folder to S3 bucket, deleting the objects only in the bucket 1000 at a time as soon as the files before them are uploaded
+		 > s5cmd {{.HelpName}} --delete --delete-batch-interleave 1000 folder/ s3://bucket/
+
+	21. Sync the latest versions of the objects in a versioned bucket at listing time, even if they change during the sync
+		 > s5cmd {{.HelpName}} --pin-versions "s3://bucket/*" s3://target-bucket/
+
+	22. Sync folder to S3 bucket, also re-uploading the unchanged files whose objects are not encrypted with the given KMS key
+		 > s5cmd {{.HelpName}} --sse aws:kms --sse-kms-key-id <your-kms-key-id> --match-encryption folder/ s3://bucket/
+
+	23. Sync S3 bucket to another S3 bucket, deleting the objects not in source except the ".keep" objects of any prefix
+		 > s5cmd {{.HelpName}} --delete --keep-sentinels ".keep" "s3://bucket/*" s3://target-bucket/
+
+	24. Check the keys a sync from a network share would create on S3 bucket for colons, backslashes and names with leading or trailing spaces
+		 > s5cmd --dry-run {{.HelpName}} --validate-keys --disallowed-key-chars ':\' /mnt/share/ s3://bucket/share/
+
+	25. Sync a large, mostly static folder to S3 bucket, listing the files of the directories unchanged since the previous sync from a state file
+		 > s5cmd {{.HelpName}} --dir-mtime-shortcut /var/lib/s5cmd/archive.state /mnt/archive/ s3://bucket/archive/
+
+	26. Sync Parquet files to S3 bucket, skipping the files with different checksums which the given command finds equivalent to their objects
+		 > s5cmd {{.HelpName}} --sync-strategy checksum --compare-exec "/usr/local/bin/pq-equal {src} {dst}" --compare-exec-input file folder/ s3://bucket/
+
+	27. Sync folder to S3 prefix which other folders are synced to as well, deleting only the objects with the ".a.csv" suffix this folder is authoritative for
+		 > s5cmd {{.HelpName}} --delete --delete-scope "*.a.csv" srcA/ s3://bucket/merged/
+
+	28. Sync log files to S3 bucket under prefixes of the days they were last modified on, e.g. s3://bucket/logs/2024/01/02/app.log
+		 > s5cmd {{.HelpName}} --dest-date-prefix 2006/01/02 logs/ s3://bucket/logs/
+
+	29. Sync S3 bucket to another S3 bucket, stripping the metadata of the images with the given command as they are copied
+		 > s5cmd {{.HelpName}} --transform-exec "exiftool -all= -" "s3://bucket/images/*" s3://target-bucket/images/
+
+	30. Sync a bucket with billions of objects, sorting the listings in at most 8GB of memory and spilling the rest to temporary files
+		 > s5cmd {{.HelpName}} --max-compare-memory 8GB "s3://bucket/*" s3://target-bucket/
+
+	31. Sync a website and invalidate the CDN cache only if any object is copied or deleted
+		 > s5cmd {{.HelpName}} --delete --exit-code-on-change site/ s3://bucket/site/; [ $? -eq 10 ] && invalidate-cache
+
+	32. Print the commands a sync with deletion would run on S3 bucket, with the reason of each of them, without running them
+		 > s5cmd --json {{.HelpName}} --dry-run --delete folder/ s3://bucket/
+
+	33. Sync S3 bucket to a folder on an exFAT drive which can't be probed, matching the keys which only differ in case and ignoring modification time differences up to 10 milliseconds
+		 > s5cmd {{.HelpName}} --assume-fs-precision 10ms --assume-case-insensitive "s3://bucket/*" /mnt/usb/
+
+	34. Sync folder to S3 bucket, comparing the checksums of the files with the ETags of the objects, including the ones uploaded in 64MB parts
+		 > s5cmd {{.HelpName}} --checksum --part-size 64 folder/ s3://bucket/
+
+	35. Sync only the parquet files of a folder to S3 bucket, deleting only the parquet files which are not in the folder
+		 > s5cmd {{.HelpName}} --delete --include "*.parquet" folder/ s3://bucket/
+
+	36. Sync folder to S3 bucket with deletion, aborting without any change if more than 100 objects are to be deleted
+		 > s5cmd {{.HelpName}} --delete --max-delete 100 folder/ s3://bucket/
+
+	37. Sync the contents of the folder into S3 bucket whether or not it is given with a trailing slash
+		 > s5cmd {{.HelpName}} --contents-only folder s3://bucket/
+
+	38. Sync S3 bucket to another S3 bucket, copying the objects whose content types or user-defined metadata are different as well
+		 > s5cmd {{.HelpName}} --preserve-metadata "s3://bucket/*" s3://target-bucket/
+
+	39. Sync S3 bucket with millions of objects to another S3 bucket, comparing the objects as they are listed
+		 > s5cmd {{.HelpName}} --stream "s3://bucket/*" s3://target-bucket/
+
+	40. Sync a local folder and an S3 prefix in both directions, copying the newer objects over the older ones
+		 > s5cmd {{.HelpName}} --two-way workdir/ s3://bucket/workdir/
+
+	41. Sync only the objects in STANDARD storage class of S3 bucket to another S3 bucket
+		 > s5cmd {{.HelpName}} --storage-class-filter STANDARD "s3://bucket/*" s3://target-bucket/
+
+	42. Sync folder to S3 bucket with deletion, aborting without any change if more than 10% of the objects in bucket are to be deleted
+		 > s5cmd {{.HelpName}} --delete --max-delete-percent 10 folder/ s3://bucket/
+
+	43. Check whether folder and S3 bucket are in sync without changing anything, which exits with code 2 if they are not
+		 > s5cmd --dry-run {{.HelpName}} --exit-code folder/ s3://bucket/
+
+	44. Sync folder to S3 bucket and print the number of the copied, deleted, skipped and failed objects once it completes
+		 > s5cmd {{.HelpName}} --delete --summary folder/ s3://bucket/
+
+	45. Sync folder to S3 bucket, deleting the log files in bucket as well although they are excluded
+		 > s5cmd {{.HelpName}} --delete --exclude "*.log" --delete-excluded folder/ s3://bucket/
+
+	46. Sync folder to S3 bucket unless another sync with the same lock is running, waiting for it up to 30 minutes
+		 > s5cmd {{.HelpName}} --delete --lock s3://bucket/.s5cmd.lock --lock-ttl 2h --lock-wait 30m folder/ s3://bucket/
 `
 
 func NewSyncCommandFlags() []cli.Flag {
@@ -72,11 +184,202 @@ func NewSyncCommandFlags() []cli.Flag {
 			Name:  "delete",
 			Usage: "delete objects in destination but not in source",
 		},
+		&cli.BoolFlag{
+			Name:  "dry-run",
+			Usage: "print the cp and rm commands the sync would run, one per line, instead of running them",
+		},
+		&cli.IntFlag{
+			Name:  "delete-batch-interleave",
+			Usage: "delete objects in destination in batches of the given size as soon as the objects before them are copied, instead of at once, requires --delete",
+		},
+		newDeleteBatchSizeFlag(),
+		&cli.IntFlag{
+			Name:  maxDeleteFlagName,
+			Value: -1,
+			Usage: "abort the sync before copying or deleting any object if more than the given number of objects in destination are to be deleted, requires --delete, -1 disables the limit",
+		},
+		&cli.Float64Flag{
+			Name:  maxDeletePercentFlagName,
+			Value: -1,
+			Usage: "abort the sync before copying or deleting any object if more than the given percentage of the objects in destination are to be deleted, requires --delete, -1 disables the limit",
+		},
+		&cli.StringSliceFlag{
+			Name:  keepSentinelsFlagName,
+			Usage: "keep the objects in destination whose names match the given pattern, e.g. \".keep\", even if they are not in source, requires --delete, can be specified multiple times",
+		},
+		&cli.StringSliceFlag{
+			Name:  includeFlagName,
+			Usage: "only sync the objects whose keys relative to the source or the destination match the given pattern, the other objects in destination are not deleted with --delete, can be specified multiple times",
+		},
+		newStorageClassFilterFlag(),
+		&cli.BoolFlag{
+			Name:  contentsOnlyFlagName,
+			Usage: "sync the contents of the source directory into the destination, as if the source had a trailing slash",
+		},
+		&cli.BoolFlag{
+			Name:  layoutConfirmedFlagName,
+			Usage: "do not check whether the objects in destination are laid out by the other spelling of the source directory, with or without a trailing slash",
+		},
+		&cli.StringSliceFlag{
+			Name:  deleteScopeFlagName,
+			Usage: "only delete the objects in destination whose keys relative to the destination match the given pattern, for the destinations synced from multiple sources, requires --delete, can be specified multiple times",
+		},
+		&cli.BoolFlag{
+			Name:  deleteExcludedFlagName,
+			Usage: "delete the objects in destination which are excluded with --exclude or not included with --include as well, requires --delete",
+		},
+		&cli.GenericFlag{
+			Name: "sync-strategy",
+			Value: &EnumValue{
+				Enum:    syncStrategyNames(),
+				Default: syncStrategySizeMtime,
+			},
+			Usage: "comparison criteria to decide whether an object should be synced: (size, size-mtime, checksum, etag, always)",
+		},
 		&cli.BoolFlag{
 			Name:  "size-only",
-			Usage: "make size of object only criteria to decide whether an object should be synced",
+			Usage: "make size of object only criteria to decide whether an object should be synced, alias for --sync-strategy size",
+		},
+		&cli.BoolFlag{
+			Name:  "checksum",
+			Usage: "compare the MD5 checksums of the objects to decide whether an object should be synced, the multipart ETags of the objects uploaded in multiple parts are compared with the ones of the files computed with the part size, alias for --sync-strategy checksum",
+		},
+		&cli.BoolFlag{
+			Name:  "show-skips",
+			Usage: "print the reason and the compared values for each object that is not synced",
+		},
+		&cli.StringFlag{
+			Name:  "compare-key-strip-prefix",
+			Usage: "strip the given prefix from the keys of source and destination objects before matching them",
+		},
+		&cli.BoolFlag{
+			Name:  "prefer-source",
+			Usage: "overwrite destination objects whose size or modification time differ from the source, even if the destination is newer",
+		},
+		&cli.DurationFlag{
+			Name:  "skew-tolerance",
+			Usage: "treat modification times within the given duration of each other as equal, e.g. 2s for FAT or SMB sources",
+		},
+		&cli.BoolFlag{
+			Name:  "diff",
+			Usage: "print the planned changes as new (+), deleted (-) and modified (M) objects with their reasons instead of commands, requires --dry-run",
+		},
+		&cli.BoolFlag{
+			Name:  validateKeysFlagName,
+			Usage: "report the destination keys which are too long or contain disallowed characters, control characters or names with leading or trailing spaces, requires --dry-run",
+		},
+		&cli.IntFlag{
+			Name:  maxKeyLengthFlagName,
+			Value: defaultMaxKeyLength,
+			Usage: "maximum length of the destination keys in bytes, requires --validate-keys",
+		},
+		&cli.StringFlag{
+			Name:  disallowedKeyCharsFlagName,
+			Value: defaultDisallowedKeyChars,
+			Usage: "characters which are not allowed in the destination keys, requires --validate-keys",
+		},
+		&cli.StringFlag{
+			Name:  dirMtimeShortcutFlagName,
+			Usage: "keep the state of the directories of the local source in the given file, and list the files of the directories whose modification time and number of entries are not changed since the previous sync from it instead of the filesystem",
+		},
+		&cli.BoolFlag{
+			Name:  noShortcutFlagName,
+			Usage: "walk all directories of the local source and refresh the state file, requires --dir-mtime-shortcut",
+		},
+		&cli.StringFlag{
+			Name:  compareExecFlagName,
+			Usage: "run the given command for the objects which the sync strategy finds different, with {src} and {dst} replaced by the objects; exit code 0 skips the object, 1 syncs it",
+		},
+		&cli.GenericFlag{
+			Name: compareExecInputFlagName,
+			Value: &EnumValue{
+				Enum:    []string{compareExecInputURL, compareExecInputFile},
+				Default: compareExecInputURL,
+			},
+			Usage: "give the remote objects to the compare command as presigned URLs or downloaded temporary files: (url, file)",
+		},
+		&cli.IntFlag{
+			Name:  compareExecConcurrencyFlagName,
+			Value: defaultCompareExecConcurrency,
+			Usage: "number of compare commands run at the same time, requires --compare-exec",
+		},
+		&cli.DurationFlag{
+			Name:  compareExecTimeoutFlagName,
+			Value: defaultCompareExecTimeout,
+			Usage: "time limit of each compare command, 0 for no limit, requires --compare-exec",
+		},
+		&cli.GenericFlag{
+			Name: compareExecOnErrorFlagName,
+			Value: &EnumValue{
+				Enum:    []string{compareExecOnErrorSync, compareExecOnErrorSkip},
+				Default: compareExecOnErrorSync,
+			},
+			Usage: "sync or skip the objects whose compare command fails or times out: (sync, skip)",
+		},
+		&cli.BoolFlag{
+			Name:  "partition-by-prefix",
+			Usage: "sync each prefix of the remote source down to --depth levels separately, with its own listing, plan and errors",
+		},
+		&cli.IntFlag{
+			Name:  "depth",
+			Value: 1,
+			Usage: "number of prefix levels the source is partitioned by, requires --partition-by-prefix",
+		},
+		&cli.IntFlag{
+			Name:  "partition-concurrency",
+			Value: 4,
+			Usage: "number of partitions synced at the same time, requires --partition-by-prefix",
+		},
+		&cli.BoolFlag{
+			Name:  "continue-on-partition-failure",
+			Usage: "keep syncing the remaining partitions and exit successfully if some partitions fail, requires --partition-by-prefix",
+		},
+		&cli.BoolFlag{
+			Name:  "match-encryption",
+			Usage: "sync the objects which would be skipped if their destination objects are not encrypted as given with --sse and --sse-kms-key-id, requires --sse",
+		},
+		&cli.BoolFlag{
+			Name:  preserveMetadataFlagName,
+			Usage: "sync the objects which would be skipped if their content types or user-defined metadata are different in destination, and keep them on the transformed objects, requires remote source and destination",
+		},
+		newDestDatePrefixFlag(),
+		newMaxCompareMemoryFlag(),
+		&cli.BoolFlag{
+			Name:  twoWayFlagName,
+			Usage: "copy the objects only in destination to source as well, and the newer one of the objects which are different in source and destination to the other, instead of treating source as the source of truth",
+		},
+		&cli.BoolFlag{
+			Name:  streamFlagName,
+			Usage: "compare the remote objects as they are listed in the order of their keys rather than sorting them first, which keeps the memory of the sync constant; the local files are still sorted",
+		},
+		&cli.BoolFlag{
+			Name:  exitCodeOnChangeFlagName,
+			Usage: fmt.Sprintf("print the number of the copied and deleted objects, and exit with code %d if any object is copied or deleted, 0 if nothing is changed", syncExitChanged),
+		},
+		&cli.BoolFlag{
+			Name:  exitCodeFlagName,
+			Usage: fmt.Sprintf("exit with code %d if any object is planned to be copied or deleted, 0 if nothing is to be changed, e.g. with --dry-run to check whether the source and destination are in sync", syncExitPlanned),
+		},
+		&cli.BoolFlag{
+			Name:  summaryFlagName,
+			Usage: "print the number of the objects copied, deleted, skipped as up-to-date and failed, and the bytes transferred once the sync completes",
+		},
+		&cli.StringFlag{
+			Name:  lockFlagName,
+			Usage: "acquire the lock object at the given remote URL before syncing, so that the syncs with the same lock don't run at the same time, e.g. s3://bucket/prefix/.s5cmd.lock",
+		},
+		&cli.DurationFlag{
+			Name:  lockTTLFlagName,
+			Value: defaultLockTTL,
+			Usage: "expiry of the lock given with --lock, which is refreshed while the sync runs, and taken over by another sync once it expires",
+		},
+		&cli.DurationFlag{
+			Name:  lockWaitFlagName,
+			Usage: "wait up to the given duration for the lock given with --lock if another sync holds it, instead of failing",
 		},
 	}
+	syncFlags = append(syncFlags, newTransformFlags()...)
+	syncFlags = append(syncFlags, newFilesystemFlags()...)
 	sharedFlags := NewSharedFlags()
 	return append(syncFlags, sharedFlags...)
 }
@@ -89,8 +392,14 @@ func NewSyncCommand() *cli.Command {
 		Flags:              NewSyncCommandFlags(),
 		CustomHelpTemplate: syncHelpTemplate,
 		Before: func(c *cli.Context) error {
-			// sync command share same validation method as copy command
-			err := validateCopyCommand(c)
+			err := validateSyncStrategyFlags(c)
+			if err == nil {
+				// sync command share same validation method as copy command
+				err = validateCopyCommand(c)
+			}
+			if err == nil {
+				err = validateSyncPartitionFlags(c)
+			}
 			if err != nil {
 				printError(commandFromContext(c), c.Command.Name, err)
 			}
@@ -99,7 +408,21 @@ func NewSyncCommand() *cli.Command {
 		Action: func(c *cli.Context) (err error) {
 			defer stat.Collect(c.Command.FullName(), &err)()
 
-			return NewSync(c).Run(c)
+			s := NewSync(c)
+			defer s.sentinels.report()
+			defer s.deleteScope.report()
+			defer s.compareExec.report(s.op)
+			defer s.planSummary.report()
+
+			ctx, err := s.lock.acquire(c.Context)
+			if err != nil {
+				printError(s.fullCommand, s.op, err)
+				return err
+			}
+			defer s.lock.release(s.fullCommand)
+
+			c.Context = s.changes.context(ctx)
+			return s.exitCodeResult(s.changes.result(s.lock.result(s.Run(c))))
 		},
 	}
 
@@ -111,16 +434,143 @@ type ObjectPair struct {
 	src, dst *storage.Object
 }
 
+// SkipMessage is the structure for objects that are not synced because the
+// sync strategy decided they are up to date.
+type SkipMessage struct {
+	Source      *url.URL
+	Destination *url.URL
+	Reason      *SkipReason
+}
+
+// String is the string representation of SkipMessage.
+func (m SkipMessage) String() string {
+	return fmt.Sprintf("skip %v %v: %v", m.Source, m.Destination, m.Reason)
+}
+
+// JSON is the JSON representation of SkipMessage.
+func (m SkipMessage) JSON() string {
+	return strutil.JSON(struct {
+		Operation   string         `json:"operation"`
+		Source      *url.URL       `json:"source"`
+		Destination *url.URL       `json:"destination"`
+		Reason      SkipReasonCode `json:"reason"`
+		Message     string         `json:"message"`
+		SourceAttrs ComparedObject `json:"source_attributes"`
+		DestAttrs   ComparedObject `json:"destination_attributes"`
+	}{
+		Operation:   "skip",
+		Source:      m.Source,
+		Destination: m.Destination,
+		Reason:      m.Reason.Code,
+		Message:     m.Reason.Err().Error(),
+		SourceAttrs: m.Reason.Source,
+		DestAttrs:   m.Reason.Destination,
+	})
+}
+
 // Sync holds sync operation flags and states.
 type Sync struct {
 	src         string
 	dst         string
 	op          string
 	fullCommand string
+	// position is the position of the command in the input of run, if any.
+	position string
 
 	// flags
-	delete   bool
-	sizeOnly bool
+	delete         bool
+	syncStrategy   string
+	preferSource   bool
+	skewTolerance  time.Duration
+	showSkips      bool
+	stripKeyPrefix string
+	diff           bool
+	// destFS is the limitations of the local destination filesystem.
+	destFS fsTraits
+	// printPlan is set with the "dry-run" flag of sync, rather than the
+	// global one, to print the planned commands instead of running them.
+	printPlan bool
+	// maxCompareMemory is the memory the objects are sorted in while they
+	// are compared, or 0 for the chunks of extsortChunkSize objects.
+	maxCompareMemory int64
+	// streamOrder checks the order of the listings which are compared as
+	// they are listed with --stream, if set. It is shared by the partitions.
+	streamOrder *streamOrder
+	// destDatePrefix is the time layout of the prefixes which the objects
+	// are put under in destination by their modification time, if set.
+	destDatePrefix string
+	// transformed is set if the objects are transformed with --transform-exec
+	// while they are copied.
+	transformed bool
+	// partSize and multipartThreshold are the ones the files are uploaded
+	// with, which the multipart ETags of the files are computed with.
+	partSize           int64
+	multipartThreshold int64
+	// deleteBatchInterleave is the size of the batches which the objects only
+	// in destination are deleted in as the copies progress, or 0 if they are
+	// deleted at once.
+	deleteBatchInterleave int
+	// deleteBatchSize is the number of the objects only in destination which
+	// are deleted with each rm command.
+	deleteBatchSize int
+	// maxDelete limits the objects only in destination which are deleted,
+	// if set.
+	maxDelete *deleteLimit
+	// contentsOnly is set if the contents of a source directory are synced
+	// into the destination whether or not it has a trailing slash.
+	contentsOnly bool
+	// layoutConfirmed disables the check of the layout of the destination.
+	layoutConfirmed bool
+	// sentinels keeps the objects only in destination which match
+	// --keep-sentinels, if set.
+	sentinels *sentinelKeeper
+	// deleteScope restricts the objects only in destination which are
+	// deleted to the ones matching --delete-scope, if set.
+	deleteScope *deleteScope
+	// excludePatterns and includePatterns restrict the objects in source and
+	// destination to the ones matching no --exclude pattern and any --include
+	// pattern, if set.
+	excludePatterns []*regexp.Regexp
+	includePatterns []*regexp.Regexp
+	// deleteExcluded is set if the objects in destination which are excluded
+	// or not included are deleted as the objects only in destination.
+	deleteExcluded bool
+	// storageClasses restricts the remote objects in source and destination
+	// to the ones in the storage classes given with --storage-class-filter,
+	// if set.
+	storageClasses *storageClassFilter
+	// keyRules are the rules the destination keys are checked against with
+	// --validate-keys, if set.
+	keyRules *keyRules
+	// dirStatePath is the file of the state of the directories of the local
+	// source, if set. See storage.DirState.
+	dirStatePath string
+	noShortcut   bool
+	dirState     *storage.DirState
+	// compareExec runs the compare command given with --compare-exec, if
+	// any. It is shared by the partitions.
+	compareExec *compareExec
+	// changes counts the objects copied and deleted by the sync with
+	// --exit-code-on-change or --summary, if set. It is shared by the
+	// partitions.
+	changes *syncChanges
+	// planSummary counts the objects planned to be copied and deleted with
+	// --dry-run or --exit-code, if set. It is shared by the partitions.
+	planSummary *syncPlanSummary
+	// exitCode is set if the sync exits with syncExitPlanned if it planned
+	// any change.
+	exitCode bool
+	// lock is the lock object given with --lock, if set. It is acquired
+	// once for all partitions.
+	lock *syncLock
+
+	partitionByPrefix          bool
+	partitionDepth             int
+	partitionConcurrency       int
+	continueOnPartitionFailure bool
+	// shallow is set for the partitions which sync the objects directly
+	// under their prefix only. See syncPartition.
+	shallow bool
 
 	// s3 options
 	storageOpts storage.Options
@@ -128,6 +578,23 @@ type Sync struct {
 	followSymlinks bool
 	storageClass   storage.StorageClass
 	raw            bool
+	verifyBucket   bool
+	spaceCheck     bool
+	onTypeConflict string
+	force          bool
+	pinVersions    bool
+
+	// encryptionMethod and encryptionKeyID are the server side encryption
+	// the objects are copied with.
+	encryptionMethod string
+	encryptionKeyID  string
+	matchEncryption  bool
+	// preserveMetadata is set if the content types and the user-defined
+	// metadata of the objects are compared and kept on the copies.
+	preserveMetadata bool
+	// twoWay is set if the objects are synced from destination to source as
+	// well.
+	twoWay bool
 
 	srcRegion string
 	dstRegion string
@@ -135,20 +602,75 @@ type Sync struct {
 
 // NewSync creates Sync from cli.Context
 func NewSync(c *cli.Context) Sync {
+	// the patterns are valid, since their metacharacters are quoted.
+	excludePatterns, _ := createExcludesFromWildcard(c.StringSlice("exclude"))
+	includePatterns, _ := createExcludesFromWildcard(c.StringSlice(includeFlagName))
+
 	return Sync{
 		src:         c.Args().Get(0),
 		dst:         c.Args().Get(1),
 		op:          c.Command.Name,
 		fullCommand: commandFromContext(c),
+		position:    commandPosition(c),
 
 		// flags
-		delete:   c.Bool("delete"),
-		sizeOnly: c.Bool("size-only"),
+		delete:         c.Bool("delete"),
+		syncStrategy:   syncStrategyName(c),
+		preferSource:   c.Bool("prefer-source"),
+		skewTolerance:  c.Duration("skew-tolerance"),
+		showSkips:      c.Bool("show-skips"),
+		stripKeyPrefix: normalizeKeyPrefix(c.String("compare-key-strip-prefix")),
+		diff:           c.Bool("diff"),
+		printPlan:      c.Bool("dry-run"),
+		destFS:         newFSTraits(c),
+		destDatePrefix: c.String(destDatePrefixFlagName),
+		transformed:    c.String(transformExecFlagName) != "",
+
+		partSize:              c.Int64("part-size") * megabytes,
+		multipartThreshold:    parseMultipartThreshold(c),
+		deleteBatchInterleave: c.Int("delete-batch-interleave"),
+		deleteBatchSize:       c.Int("delete-batch-size"),
+		maxDelete:             newDeleteLimit(c),
+		contentsOnly:          c.Bool(contentsOnlyFlagName),
+		layoutConfirmed:       c.Bool(layoutConfirmedFlagName),
+		sentinels:             newSentinelKeeper(c.StringSlice(keepSentinelsFlagName)),
+		deleteScope:           newDeleteScope(c.StringSlice(deleteScopeFlagName), c.Args().Get(1), c.Bool("raw")),
+		excludePatterns:       excludePatterns,
+		includePatterns:       includePatterns,
+		deleteExcluded:        c.Bool(deleteExcludedFlagName),
+		lock:                  newSyncLock(c),
+		storageClasses:        newStorageClassFilter(c),
+		keyRules:              newKeyRules(c),
+		dirStatePath:          c.String(dirMtimeShortcutFlagName),
+		noShortcut:            c.Bool(noShortcutFlagName),
+		compareExec:           newCompareExec(c),
+		maxCompareMemory:      parseMaxCompareMemory(c),
+		streamOrder:           newStreamOrder(c),
+		changes:               newSyncChanges(c),
+		planSummary:           newSyncPlanSummary(isDryRun(c), c.Bool(exitCodeFlagName)),
+		exitCode:              c.Bool(exitCodeFlagName),
+
+		partitionByPrefix:          c.Bool("partition-by-prefix"),
+		partitionDepth:             c.Int("depth"),
+		partitionConcurrency:       c.Int("partition-concurrency"),
+		continueOnPartitionFailure: c.Bool("continue-on-partition-failure"),
 
 		// flags
 		followSymlinks: !c.Bool("no-follow-symlinks"),
 		storageClass:   storage.StorageClass(c.String("storage-class")),
 		raw:            c.Bool("raw"),
+		verifyBucket:   c.Bool("verify-bucket"),
+		spaceCheck:     c.Bool("space-check"),
+		onTypeConflict: c.String("on-type-conflict"),
+		force:          c.Bool("force"),
+		pinVersions:    c.Bool("pin-versions"),
+
+		encryptionMethod: c.String("sse"),
+		encryptionKeyID:  c.String("sse-kms-key-id"),
+		matchEncryption:  c.Bool("match-encryption"),
+		preserveMetadata: c.Bool(preserveMetadataFlagName),
+		twoWay:           c.Bool(twoWayFlagName),
+
 		// region settings
 		srcRegion:   c.String("source-region"),
 		dstRegion:   c.String("destination-region"),
@@ -159,6 +681,10 @@ func NewSync(c *cli.Context) Sync {
 // Run compares files, plans necessary s5cmd commands to execute
 // and executes them in order to sync source to destination.
 func (s Sync) Run(c *cli.Context) error {
+	if s.contentsOnly {
+		s.src = contentsOnlySource(s.src, s.raw)
+	}
+
 	srcurl, err := url.New(s.src, url.WithRaw(s.raw))
 	if err != nil {
 		return err
@@ -169,13 +695,40 @@ func (s Sync) Run(c *cli.Context) error {
 		return err
 	}
 
+	if s.verifyBucket {
+		err := verifyBuckets(c.Context, s.storageOpts, srcurl, s.srcRegion, dsturl, s.dstRegion)
+		if err != nil {
+			printError(s.fullCommand, s.op, err)
+			return err
+		}
+	}
+
+	// the destination is probed once for all partitions.
+	s.destFS = s.probeDestination(dsturl)
+
+	if s.partitionByPrefix {
+		return s.runPartitions(c, srcurl, dsturl)
+	}
+
+	if err := s.checkLayout(c.Context, srcurl, dsturl); err != nil {
+		printError(s.fullCommand, s.op, err)
+		return err
+	}
+
+	s.dirState, err = s.openDirState()
+	if err != nil {
+		printError(s.fullCommand, s.op, err)
+		return err
+	}
+	defer s.saveDirState(s.dirState)
+
 	sourceObjects, destObjects, err := s.getSourceAndDestinationObjects(c.Context, srcurl, dsturl)
 	if err != nil {
 		printError(s.fullCommand, s.op, err)
 		return err
 	}
 
-	isBatch := srcurl.IsWildcard()
+	isBatch := srcurl.IsWildcard() || s.shallow
 	if !isBatch && !srcurl.IsRemote() {
 		sourceClient, err := storage.NewClient(c.Context, srcurl, s.storageOpts)
 		if err != nil {
@@ -190,15 +743,16 @@ func (s Sync) Run(c *cli.Context) error {
 		isBatch = obj != nil && obj.Type.IsDir()
 	}
 
-	onlySource, onlyDest, commonObjects := compareObjects(sourceObjects, destObjects)
+	onlySource, onlyDest, commonObjects := compareObjects(sourceObjects, destObjects, s.destFS.caseInsensitive)
+	onlyDest = s.sentinels.filter(s.deleteScope.filter(onlyDest))
 
 	sourceObjects = nil
 	destObjects = nil
 
 	waiter := parallel.NewWaiter()
 	var (
-		merrorWaiter error
-		errDoneCh    = make(chan bool)
+		errs      = newErrorSummary()
+		errDoneCh = make(chan bool)
 	)
 
 	go func() {
@@ -211,28 +765,83 @@ func (s Sync) Run(c *cli.Context) error {
 				os.Exit(1)
 			}
 			printError(s.fullCommand, s.op, err)
-			merrorWaiter = multierror.Append(merrorWaiter, err)
+			errs.add(err, "")
 		}
 	}()
 
-	strategy := NewStrategy(s.sizeOnly) // create comparison strategy.
+	// create comparison strategy.
+	strategy := NewStrategy(s.syncStrategy, StrategyOptions{
+		PreferSource:           s.preferSource,
+		SkewTolerance:          s.compareSkewTolerance(),
+		OpaqueDestinationEtags: s.hasOpaqueDestinationEtags(c.Context, dsturl),
+		Transformed:            s.transformed,
+		OnMultipartEtag: func(obj *storage.Object) {
+			log.Warning(log.WarningMessage{
+				Operation: s.op,
+				Warning: fmt.Sprintf("%v has a multipart ETag which can't be compared with the checksum of the other object, it is compared by size and modification time",
+					obj.URL),
+			})
+		},
+		PartSize:           s.partSize,
+		MultipartThreshold: s.multipartThreshold,
+	})
+	if s.matchEncryption {
+		strategy, err = s.newEncryptionStrategy(c.Context, dsturl, strategy)
+		if err != nil {
+			printError(s.fullCommand, s.op, err)
+			return err
+		}
+	}
+	if s.preserveMetadata {
+		strategy, err = s.newMetadataStrategy(c, srcurl, dsturl, strategy)
+		if err != nil {
+			printError(s.fullCommand, s.op, err)
+			return err
+		}
+	}
+	if s.twoWay {
+		strategy = &TwoWayStrategy{Strategy: strategy}
+	}
+	strategy, err = s.newModTimeStrategy(c.Context, srcurl, dsturl, strategy)
+	if err != nil {
+		printError(s.fullCommand, s.op, err)
+		return err
+	}
 	pipeReader, pipeWriter := io.Pipe() // create a reader, writer pipe to pass commands to run
 
+	var interleaver *deleteInterleaver
+	if s.delete && s.deleteBatchInterleave > 0 && !s.diff && !s.printPlan {
+		interleaver = newDeleteInterleaver(s.deleteBatchInterleave)
+	}
+
 	// Create commands in background.
-	go s.planRun(c, onlySource, onlyDest, commonObjects, dsturl, strategy, pipeWriter, isBatch)
+	planErrCh := make(chan error, 1)
+	go func() {
+		planErrCh <- s.planRun(c, srcurl, onlySource, onlyDest, commonObjects, dsturl, strategy, pipeWriter, isBatch, interleaver)
+	}()
 
-	err = NewRun(c, pipeReader).Run(c.Context)
-	return multierror.Append(err, merrorWaiter).ErrorOrNil()
+	run := NewRun(c, pipeReader)
+	run.inputName = planInputName
+	if interleaver != nil {
+		run.done = interleaver.complete
+	}
+	errs.add(run.Run(c.Context), "")
+	errs.add(<-planErrCh, "")
+	errs.add(s.streamOrder.Err(), "")
+	return errs.err()
 }
 
 // compareObjects compares source and destination objects. It assumes that
 // sourceObjects and destObjects channels are already sorted in ascending order.
 // Returns objects those in only source, only destination
-// and both.
-func compareObjects(sourceObjects, destObjects chan *storage.Object) (chan *url.URL, chan *url.URL, chan *ObjectPair) {
+// and both. The keys which only differ in case are matched if foldCase is set.
+// The objects are merged as they are received, pulling the next object only
+// from the channel whose object is sent, so neither of the listings is kept
+// in memory.
+func compareObjects(sourceObjects, destObjects chan *storage.Object, foldCase bool) (chan *storage.Object, chan *storage.Object, chan *ObjectPair) {
 	var (
-		srcOnly   = make(chan *url.URL, extsortChannelBufferSize)
-		dstOnly   = make(chan *url.URL, extsortChannelBufferSize)
+		srcOnly   = make(chan *storage.Object, extsortChannelBufferSize)
+		dstOnly   = make(chan *storage.Object, extsortChannelBufferSize)
 		commonObj = make(chan *ObjectPair, extsortChannelBufferSize)
 		srcName   string
 		dstName   string
@@ -249,28 +858,34 @@ func compareObjects(sourceObjects, destObjects chan *storage.Object) (chan *url.
 		for {
 			if srcOk {
 				srcName = filepath.ToSlash(src.URL.Relative())
+				if foldCase {
+					srcName = foldKey(srcName)
+				}
 			}
 			if dstOk {
 				dstName = filepath.ToSlash(dst.URL.Relative())
+				if foldCase {
+					dstName = foldKey(dstName)
+				}
 			}
 
 			if srcOk && dstOk {
 				if srcName < dstName {
-					srcOnly <- src.URL
+					srcOnly <- src
 					src, srcOk = <-sourceObjects
 				} else if srcName == dstName { // if there is a match.
 					commonObj <- &ObjectPair{src: src, dst: dst}
 					src, srcOk = <-sourceObjects
 					dst, dstOk = <-destObjects
 				} else {
-					dstOnly <- dst.URL
+					dstOnly <- dst
 					dst, dstOk = <-destObjects
 				}
 			} else if srcOk {
-				srcOnly <- src.URL
+				srcOnly <- src
 				src, srcOk = <-sourceObjects
 			} else if dstOk {
-				dstOnly <- dst.URL
+				dstOnly <- dst
 				dst, dstOk = <-destObjects
 			} else /* if !srcOK && !dstOk */ {
 				break
@@ -285,7 +900,10 @@ func compareObjects(sourceObjects, destObjects chan *storage.Object) (chan *url.
 // given URLs. The returned channels gives objects sorted in ascending order
 // with respect to their url.Relative path. See also storage.Less.
 func (s Sync) getSourceAndDestinationObjects(ctx context.Context, srcurl, dsturl *url.URL) (chan *storage.Object, chan *storage.Object, error) {
-	sourceClient, err := storage.NewClient(ctx, srcurl, s.storageOpts)
+	// only the source is walked with the state of the directories.
+	sourceOpts := s.storageOpts
+	sourceOpts.DirState = s.dirState
+	sourceClient, err := storage.NewClient(ctx, srcurl, sourceOpts)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -303,6 +921,12 @@ func (s Sync) getSourceAndDestinationObjects(ctx context.Context, srcurl, dsturl
 		destinationURLPath = s.dst + "/*"
 	}
 
+	// the remote objects of a shallow partition are listed with a delimiter
+	// instead, not to list the other partitions.
+	if s.shallow && dsturl.IsRemote() {
+		destinationURLPath = s.dst
+	}
+
 	destObjectsURL, err := url.New(destinationURLPath)
 	if err != nil {
 		return nil, nil, err
@@ -313,19 +937,17 @@ func (s Sync) getSourceAndDestinationObjects(ctx context.Context, srcurl, dsturl
 		destObjects   = make(chan *storage.Object, extsortChannelBufferSize)
 	)
 
-	extsortDefaultConfig := extsort.DefaultConfig()
-	extsortConfig := &extsort.Config{
-		ChunkSize:          extsortChunkSize,
-		NumWorkers:         extsortDefaultConfig.NumWorkers,
-		ChanBuffSize:       extsortChannelBufferSize,
-		SortedChanBuffSize: extsortChannelBufferSize,
-	}
-	extsortDefaultConfig = nil
+	extsortConfig := newExtsortConfig(s.maxCompareMemory, s.sorters())
+	extsortConfig.TempFilesDir = spoolDir(s.op)
 
 	// get source objects.
 	go func() {
 		defer close(sourceObjects)
-		unfilteredSrcObjectChannel := sourceClient.List(ctx, srcurl, s.followSymlinks)
+		listurl := srcurl
+		if s.pinVersions {
+			listurl = pinnedSourceURL(srcurl)
+		}
+		unfilteredSrcObjectChannel := sourceClient.List(ctx, listurl, s.followSymlinks)
 		filteredSrcObjectChannel := make(chan extsort.SortType, extsortChannelBufferSize)
 
 		go func() {
@@ -335,31 +957,32 @@ func (s Sync) getSourceAndDestinationObjects(ctx context.Context, srcurl, dsturl
 				if s.shouldSkipObject(st, true) {
 					continue
 				}
+				if listurl.AllVersions && !isPinnedVersion(st) {
+					continue
+				}
+				st.URL.TrimRelativePrefix(s.stripKeyPrefix)
+				if s.destDatePrefix != "" {
+					st.URL.PrependRelativePrefix(datePrefix(s.op, st, s.destDatePrefix))
+				}
 				filteredSrcObjectChannel <- *st
 			}
 		}()
 
-		var (
-			sorter        *extsort.SortTypeSorter
-			srcOutputChan chan extsort.SortType
-		)
-
-		sorter, srcOutputChan, srcErrCh := extsort.New(filteredSrcObjectChannel, storage.FromBytes, storage.Less, extsortConfig)
-		sorter.Sort(ctx)
-
-		for srcObject := range srcOutputChan {
-			o := srcObject.(storage.Object)
-			sourceObjects <- &o
+		if s.isStreamed(listurl, true) {
+			s.streamObjects(ctx, filteredSrcObjectChannel, listurl, sourceObjects)
+			return
 		}
-
-		// read and print the external sort errors
-		go func() {
-			for err := range srcErrCh {
-				printError(s.fullCommand, s.op, err)
-			}
-		}()
+		s.sortObjects(ctx, filteredSrcObjectChannel, extsortConfig, sourceObjects)
 	}()
 
+	// the objects in destination which are excluded or not included are
+	// listed with --delete-excluded, so that they are deleted as the objects
+	// only in destination.
+	destFilter := s
+	if s.deleteExcluded {
+		destFilter.excludePatterns, destFilter.includePatterns = nil, nil
+	}
+
 	// get destination objects.
 	go func() {
 		defer close(destObjects)
@@ -371,72 +994,232 @@ func (s Sync) getSourceAndDestinationObjects(ctx context.Context, srcurl, dsturl
 
 			// filter and redirect objects
 			for dt := range unfilteredDestObjectsChannel {
-				if s.shouldSkipObject(dt, false) {
+				if destFilter.shouldSkipObject(dt, false) {
 					continue
 				}
+				s.maxDelete.addListed()
+				dt.URL.TrimRelativePrefix(s.stripKeyPrefix)
 				filteredDstObjectChannel <- *dt
 			}
 		}()
 
-		var (
-			dstSorter     *extsort.SortTypeSorter
-			dstOutputChan chan extsort.SortType
-		)
+		if s.isStreamed(destObjectsURL, false) {
+			s.streamObjects(ctx, filteredDstObjectChannel, destObjectsURL, destObjects)
+			return
+		}
+		s.sortObjects(ctx, filteredDstObjectChannel, extsortConfig, destObjects)
+	}()
 
-		dstSorter, dstOutputChan, dstErrCh := extsort.New(filteredDstObjectChannel, storage.FromBytes, storage.Less, extsortConfig)
-		dstSorter.Sort(ctx)
+	return sourceObjects, destObjects, nil
+}
 
-		for destObject := range dstOutputChan {
-			o := destObject.(storage.Object)
-			destObjects <- &o
+// sortObjects sorts the objects externally with respect to their url.Relative
+// path, case-insensitively for the case-insensitive destinations, and sends
+// them to sorted. The objects whose keys collide with the previous ones are
+// skipped.
+func (s Sync) sortObjects(ctx context.Context, objects chan extsort.SortType, config *extsort.Config, sorted chan<- *storage.Object) {
+	less := storage.Less
+	if s.destFS.caseInsensitive {
+		less = lessFold
+	}
+	sorter, outputChan, errCh := extsort.New(objects, storage.FromBytes, less, config)
+	sorter.Sort(ctx)
+
+	var prev *storage.Object
+	for object := range outputChan {
+		o := object.(storage.Object)
+		if s.collides(prev, &o) {
+			continue
 		}
+		prev = &o
+		sorted <- &o
+	}
 
-		// read and print the external sort errors
-		go func() {
-			for err := range dstErrCh {
-				printError(s.fullCommand, s.op, err)
-			}
-		}()
+	// read and print the external sort errors
+	go func() {
+		for err := range errCh {
+			printError(s.fullCommand, s.op, err)
+		}
 	}()
-
-	return sourceObjects, destObjects, nil
 }
 
-// planRun prepares the commands and writes them to writer 'w'.
+// planRun prepares the commands and writes them to writer 'w'. If diff flag
+// is set, the planned changes are printed instead and no commands are written,
+// and the commands themselves are printed instead with the dry-run flag of
+// sync.
+// With --space-check, the commands of the downloads are written once all of
+// them are planned and their total size is checked against the free space of
+// the destination.
 func (s Sync) planRun(
 	c *cli.Context,
-	onlySource, onlyDest chan *url.URL,
+	srcurl *url.URL,
+	onlySource chan *storage.Object,
+	onlyDest chan *storage.Object,
 	common chan *ObjectPair,
 	dsturl *url.URL,
 	strategy SyncStrategy,
 	w io.WriteCloser,
 	isBatch bool,
-) {
+	interleaver *deleteInterleaver,
+) error {
 	defer w.Close()
 
+	checkSpace := srcurl.IsRemote() && !dsturl.IsRemote() && s.spaceCheck && !s.storageOpts.DryRun
+	// the commands are not run until their total size is checked with
+	// --space-check, or until the number of the deletes is known, if it is
+	// limited.
+	plannedCommands := &commandPlan{
+		w:           w,
+		buffered:    checkSpace || s.maxDelete != nil,
+		interleaver: interleaver,
+	}
+
 	// Always use raw mode since sync command generates commands
 	// from raw S3 objects. Otherwise, generated copy command will
 	// try to expand given source.
 	defaultFlags := map[string]interface{}{
 		"raw": true,
 	}
+	// the files are uploaded with their modification times and downloaded
+	// with the ones kept in the metadata of the objects, so that the files
+	// synced back and forth are not synced again.
+	cpDefaultFlags := map[string]interface{}{
+		"raw": true,
+	}
+	if preservesModTime(srcurl, dsturl) {
+		cpDefaultFlags[preserveMtimeFlagName] = true
+	}
+	// the files which are removed or renamed after they are listed are
+	// skipped rather than failing the sync.
+	if !srcurl.IsRemote() || (s.twoWay && !dsturl.IsRemote()) {
+		cpDefaultFlags[skipVanishedFlagName] = true
+	}
+	// the flags of the commands are the same for all objects, so they are
+	// encoded once rather than for each command.
+	cpCommands := newCommandGenerator(c, "cp", cpDefaultFlags)
+	rmCommands := newCommandGenerator(c, "rm", defaultFlags)
+
+	// the metadata of the transformed objects is given to their copies.
+	var metadataStat func(context.Context, *url.URL) (*storage.Object, error)
+	if s.preserveMetadata && s.transformed {
+		client, err := storage.NewRemoteClient(c.Context, srcurl, s.storageOpts)
+		if err != nil {
+			return err
+		}
+		metadataStat = client.Stat
+	}
+
+	// the objects only in destination are copied under the root of source
+	// with --two-way.
+	var twoWayRoot *url.URL
+	if s.twoWay {
+		root, err := s.twoWayRoot()
+		if err != nil {
+			return err
+		}
+		twoWayRoot = root
+	}
 
 	// it should wait until both of the child goroutines for onlySource and common channels
 	// are completed before closing the WriteCloser w to ensure that all URLs are processed.
 	var wg sync.WaitGroup
 
+	var plan *diffPlan
+	if s.diff {
+		config := newExtsortConfig(s.maxCompareMemory, s.sorters())
+		config.TempFilesDir = spoolDir(s.op)
+		plan = newDiffPlan(c.Context, config)
+		defer func() {
+			if err := plan.print(); err != nil {
+				printError(s.fullCommand, s.op, err)
+			}
+		}()
+	}
+
+	// the deletes are written as they are released, until all of them are
+	// written or the copies are not going to be executed.
+	interleaveDone := make(chan struct{})
+	go func() {
+		defer close(interleaveDone)
+		interleaver.run(func(dstURLs []*url.URL) {
+			s.planDeletes(rmCommands, dstURLs, func(command string, _ []*url.URL) {
+				plannedCommands.add(command, "", 0)
+			})
+		})
+	}()
+
+	var (
+		mu              sync.Mutex
+		merrorConflicts error
+		merrorKeys      error
+	)
+	validateKey := func(srcurl, dsturl *url.URL) {
+		if err := s.keyRules.validate(s.op, s.fullCommand, srcurl, dsturl); err != nil {
+			mu.Lock()
+			merrorKeys = multierror.Append(merrorKeys, err)
+			mu.Unlock()
+		}
+	}
+	// copyFlags returns the extra flags of the copy of the object at srcurl,
+	// or false if its metadata to keep can't be read.
+	copyFlags := func(srcurl *url.URL) (map[string]interface{}, bool) {
+		flags := versionFlags(nil, srcurl)
+		if metadataStat == nil {
+			return flags, true
+		}
+		metadataFlags, err := preservedMetadataFlags(c, metadataStat, srcurl)
+		if err != nil {
+			printError(s.fullCommand, s.op, err)
+			mu.Lock()
+			merrorConflicts = multierror.Append(merrorConflicts, err)
+			mu.Unlock()
+			return nil, false
+		}
+		for name, value := range flags {
+			metadataFlags[name] = value
+		}
+		return metadataFlags, true
+	}
+
 	// only in source
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		for srcurl := range onlySource {
-			curDestURL := generateDestinationURL(srcurl, dsturl, isBatch)
-			command, err := generateCommand(c, "cp", defaultFlags, srcurl, curDestURL)
+		defer interleaver.finish(streamOnlySource)
+		for srcObject := range onlySource {
+			srcurl := srcObject.URL
+			// the date prefixes are part of the relative paths of the
+			// source objects, so they are kept for a single object as well.
+			curDestURL := generateDestinationURL(srcurl, dsturl, isBatch || s.destDatePrefix != "")
+			interleaver.advance(streamOnlySource, syncKey(srcurl))
+
+			// the local destinations are listed without directories, so the
+			// path of an object only in source may exist as a directory.
+			ok, err := s.resolveTypeConflict(c.Context, srcurl, curDestURL)
 			if err != nil {
-				printDebug(s.op, err, srcurl, curDestURL)
+				mu.Lock()
+				merrorConflicts = multierror.Append(merrorConflicts, err)
+				mu.Unlock()
+			}
+			if !ok {
+				continue
+			}
+			validateKey(srcurl, curDestURL)
+			s.planSummary.addUpload(srcObject.Size)
+			if plan != nil {
+				plan.add(DiffNew, srcurl, curDestURL, "only in source")
+				continue
+			}
+			flags, ok := copyFlags(srcurl)
+			if !ok {
+				continue
+			}
+			command := cpCommands.generate(flags, srcurl, curDestURL)
+			if s.printPlan {
+				log.Info(newSyncPlanCopy(command, srcurl, curDestURL, syncPlanOnlyInSource))
 				continue
 			}
-			fmt.Fprintln(w, command)
+			plannedCommands.add(command, syncKey(srcurl), srcObject.Size)
 		}
 	}()
 
@@ -444,47 +1227,150 @@ func (s Sync) planRun(
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		for commonObject := range common {
-			sourceObject, destObject := commonObject.src, commonObject.dst
+		defer interleaver.finish(streamCommon)
+		// check if objects should be copied.
+		for compared := range s.compareExec.compare(c.Context, s.op, common, strategy) {
+			sourceObject, destObject := compared.src, compared.dst
 			curSourceURL, curDestURL := sourceObject.URL, destObject.URL
-			err := strategy.ShouldSync(sourceObject, destObject) // check if object should be copied.
-			if err != nil {
-				printDebug(s.op, err, curSourceURL, curDestURL)
+			interleaver.advance(streamCommon, syncKey(curSourceURL))
+			if err := compared.err; err != nil {
+				printError(s.fullCommand, s.op, err)
+				if !compared.sync {
+					accessLog.record(s.op, curSourceURL, sourceObject.Size, accessSkipped, err.Error())
+					s.changes.addFailed()
+					continue
+				}
+			}
+			if reason := compared.reason; reason != nil {
+				accessLog.record(s.op, curSourceURL, sourceObject.Size, accessSkipped, reason.Err().Error())
+				s.changes.addSkipped()
+				if s.showSkips {
+					log.Info(SkipMessage{
+						Source:      curSourceURL,
+						Destination: curDestURL,
+						Reason:      reason,
+					})
+				} else {
+					printDebug(s.op, reason, curSourceURL, curDestURL)
+				}
 				continue
 			}
 
-			command, err := generateCommand(c, "cp", defaultFlags, curSourceURL, curDestURL)
-			if err != nil {
-				printDebug(s.op, err, curSourceURL, curDestURL)
+			// the newer object is copied over the other one.
+			if s.twoWay {
+				reversed, err := s.isTwoWayReversed(sourceObject, destObject)
+				if err != nil {
+					printError(s.fullCommand, s.op, err)
+					s.changes.addFailed()
+					mu.Lock()
+					merrorConflicts = multierror.Append(merrorConflicts, err)
+					mu.Unlock()
+					continue
+				}
+				if reversed {
+					sourceObject, destObject = destObject, sourceObject
+					curSourceURL, curDestURL = curDestURL, curSourceURL
+				}
+			}
+
+			validateKey(curSourceURL, curDestURL)
+			s.planSummary.addUpdate(sourceObject.Size)
+			if plan != nil {
+				plan.add(DiffModify, curSourceURL, curDestURL, diffReason(sourceObject, destObject))
 				continue
 			}
-			fmt.Fprintln(w, command)
+
+			flags, ok := copyFlags(curSourceURL)
+			if !ok {
+				continue
+			}
+			command := cpCommands.generate(flags, curSourceURL, curDestURL)
+			if s.printPlan {
+				log.Info(newSyncPlanCopy(command, curSourceURL, curDestURL, s.planReason(sourceObject, destObject)))
+				continue
+			}
+			plannedCommands.add(command, syncKey(curSourceURL), sourceObject.Size)
 		}
 	}()
 
 	// only in destination
+	var deletes int
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		if s.delete {
-			// unfortunately we need to read them all!
-			// or rewrite generateCommand function?
-			dstURLs := make([]*url.URL, 0, extsortChunkSize)
-
+		if s.twoWay {
+			for destObject := range onlyDest {
+				curDestURL := twoWayRoot.Join(destObject.URL.Relative())
+				ok, err := s.resolveTypeConflict(c.Context, destObject.URL, curDestURL)
+				if err != nil {
+					mu.Lock()
+					merrorConflicts = multierror.Append(merrorConflicts, err)
+					mu.Unlock()
+				}
+				if !ok {
+					continue
+				}
+				validateKey(destObject.URL, curDestURL)
+				s.planSummary.addUpload(destObject.Size)
+				flags, ok := copyFlags(destObject.URL)
+				if !ok {
+					continue
+				}
+				command := cpCommands.generate(flags, destObject.URL, curDestURL)
+				if s.printPlan {
+					log.Info(newSyncPlanCopy(command, destObject.URL, curDestURL, syncPlanOnlyInDestination))
+					continue
+				}
+				plannedCommands.add(command, syncKey(destObject.URL), destObject.Size)
+			}
+		} else if s.delete && plan != nil {
 			for d := range onlyDest {
-				dstURLs = append(dstURLs, d)
+				deletes++
+				s.planSummary.addDelete(1)
+				plan.add(DiffDelete, nil, d.URL, "only in destination")
 			}
-
-			if len(dstURLs) == 0 {
-				return
+		} else if s.delete && interleaver != nil {
+			defer interleaver.closeDeletes()
+			for d := range onlyDest {
+				deletes++
+				s.planSummary.addDelete(1)
+				interleaver.addDelete(d.URL)
 			}
+		} else if s.delete {
+			// the rm commands are planned as the batches of the objects are
+			// filled, rather than once all of them are received.
+			batcher := s.planDeleteBatcher(rmCommands, func(command string, batch []*url.URL) {
+				if s.printPlan {
+					log.Info(newSyncPlanDelete(command, batch))
+					return
+				}
+				plannedCommands.add(command, "", 0)
+			})
 
-			command, err := generateCommand(c, "rm", defaultFlags, dstURLs...)
-			if err != nil {
-				printDebug(s.op, err, dstURLs...)
-				return
+			// the objects of the streamed listings may be only in
+			// destination since the listings are stopped out of order, so
+			// they are held back until both of the listings are completed.
+			var held heldDeletes
+			for d := range onlyDest {
+				if s.streamOrder != nil {
+					held.add(d.URL)
+					continue
+				}
+				batcher.add(d.URL)
+			}
+			if s.streamOrder.Err() != nil {
+				held.remove()
+			} else if err := held.replay(batcher.add); err != nil {
+				err = fmt.Errorf("hold the objects only in destination: %w", err)
+				printError(s.fullCommand, s.op, err)
+				mu.Lock()
+				merrorConflicts = multierror.Append(merrorConflicts, err)
+				mu.Unlock()
 			}
-			fmt.Fprintln(w, command)
+			s.finishDeletes(batcher)
+
+			deletes = batcher.total
+			s.planSummary.addDelete(batcher.total)
 		} else {
 			// we only need  to consume them from the channel so that rest of the objects
 			// can be sent to channel.
@@ -495,6 +1381,140 @@ func (s Sync) planRun(
 	}()
 
 	wg.Wait()
+
+	merrorConflicts = multierror.Append(merrorConflicts, merrorKeys).ErrorOrNil()
+
+	// the deletes of the dry runs are checked as well, so that they report
+	// whether the sync would be aborted.
+	if err := s.maxDelete.check(deletes); err != nil {
+		printError(s.fullCommand, s.op, err)
+		interleaver.stop()
+		<-interleaveDone
+		return multierror.Append(merrorConflicts, err)
+	}
+
+	if !plannedCommands.buffered {
+		<-interleaveDone
+		return merrorConflicts
+	}
+
+	if checkSpace {
+		err := checkDiskSpace(storage.NewLocalClient(s.storageOpts), dsturl.Absolute(), plannedCommands.totalSize())
+		if err != nil {
+			printError(s.fullCommand, s.op, err)
+			interleaver.stop()
+			<-interleaveDone
+			return multierror.Append(merrorConflicts, err)
+		}
+	}
+	plannedCommands.flush()
+	<-interleaveDone
+	return merrorConflicts
+}
+
+// resolveTypeConflict resolves the type conflict on the local destination of
+// an object only in source with respect to the on-type-conflict flag, and
+// reports whether the object should be copied. The skipped objects are
+// reported without an error. See resolveTypeConflict function for details.
+func (s Sync) resolveTypeConflict(ctx context.Context, srcurl, dsturl *url.URL) (bool, error) {
+	if dsturl.IsRemote() {
+		return true, nil
+	}
+
+	err := resolveTypeConflict(ctx, storage.NewLocalClient(s.storageOpts), dsturl, s.onTypeConflict, s.force)
+	if err == nil {
+		return true, nil
+	}
+
+	if errorpkg.IsTypeConflict(err) && s.onTypeConflict == typeConflictSkip {
+		log.Info(TypeConflictMessage{
+			Source:      srcurl,
+			Destination: dsturl,
+			Err:         err,
+		})
+		return false, nil
+	}
+
+	err = &errorpkg.Error{
+		Op:       s.op,
+		Src:      srcurl,
+		Dst:      dsturl,
+		Err:      err,
+		Position: s.position,
+	}
+	printError(s.fullCommand, s.op, err)
+	return false, err
+}
+
+// commandPlan writes the planned commands. If buffered is set, the commands
+// are kept until flush is called.
+type commandPlan struct {
+	w        io.Writer
+	buffered bool
+	// interleaver tracks the copies to release the deletes, if set.
+	interleaver *deleteInterleaver
+
+	mu       sync.Mutex
+	commands []plannedCommand
+	size     int64
+	// lines is the number of the written commands.
+	lines int64
+}
+
+// plannedCommand is a command with the key of the object it copies, or an
+// empty key if it doesn't copy an object.
+type plannedCommand struct {
+	command string
+	key     string
+}
+
+// add writes the command, which copies the object of key and transfers size
+// bytes, or keeps it if the commands are buffered.
+func (p *commandPlan) add(command, key string, size int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if key != "" {
+		p.interleaver.plan(key)
+	}
+
+	if !p.buffered {
+		p.write(plannedCommand{command: command, key: key})
+		return
+	}
+
+	p.commands = append(p.commands, plannedCommand{command: command, key: key})
+	p.size += size
+}
+
+// totalSize returns the number of bytes the buffered commands transfer. The
+// deletes may still be added while it is read.
+func (p *commandPlan) totalSize() int64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.size
+}
+
+// flush writes the buffered commands. The commands added after flush are
+// written immediately.
+func (p *commandPlan) flush() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, command := range p.commands {
+		p.write(command)
+	}
+	p.commands = nil
+	p.buffered = false
+}
+
+// write writes the command. It must be called with the lock held.
+func (p *commandPlan) write(command plannedCommand) {
+	if command.key != "" {
+		p.interleaver.written(p.lines, command.key)
+	}
+	fmt.Fprintln(p.w, command.command)
+	p.lines++
 }
 
 // generateDestinationURL generates destination url for given
@@ -516,12 +1536,65 @@ func generateDestinationURL(srcurl, dsturl *url.URL, isBatch bool) *url.URL {
 	return dsturl.Join(objname)
 }
 
+// normalizeKeyPrefix returns the given key prefix as a slash separated
+// directory prefix, e.g. "v1" and "/v1/" both become "v1/".
+func normalizeKeyPrefix(prefix string) string {
+	prefix = strings.Trim(filepath.ToSlash(prefix), "/")
+	if prefix == "" {
+		return ""
+	}
+	return prefix + "/"
+}
+
+// collides reports whether the given object has the same key as the previous
+// object of the same side after the key prefix is stripped, or only differs
+// from it in case on a case-insensitive destination. Such objects can not be
+// matched unambiguously, so only the first one is synced and the rest are
+// reported as errors. Objects are sorted by their keys, hence colliding
+// objects are adjacent.
+func (s Sync) collides(prev, object *storage.Object) bool {
+	if prev == nil {
+		return false
+	}
+
+	var err error
+	switch {
+	case s.stripKeyPrefix != "" && prev.URL.Relative() == object.URL.Relative():
+		err = fmt.Errorf("object %v has the same key as %v after stripping %q prefix", object.URL, prev.URL, s.stripKeyPrefix)
+	case s.destFS.caseInsensitive && foldKey(prev.URL.Relative()) == foldKey(object.URL.Relative()):
+		err = fmt.Errorf("object %v has the same key as %v on the case-insensitive destination", object.URL, prev.URL)
+	default:
+		return false
+	}
+	printError(s.fullCommand, s.op, err)
+	return true
+}
+
+// compareSkewTolerance returns the duration that the modification times
+// within are treated as equal, which is at least the precision of the
+// destination filesystem.
+func (s Sync) compareSkewTolerance() time.Duration {
+	if s.destFS.precision > s.skewTolerance {
+		return s.destFS.precision
+	}
+	return s.skewTolerance
+}
+
 // shouldSkipObject checks is object should be skipped.
 func (s Sync) shouldSkipObject(object *storage.Object, verbose bool) bool {
 	if object.Type.IsDir() || errorpkg.IsCancelation(object.Err) {
 		return true
 	}
 
+	if s.shallow && object.Err == nil && !isShallowPartitionObject(object) {
+		return true
+	}
+
+	// the lock object is not synced, nor deleted with --delete.
+	if object.Err == nil && s.lock.isLockObject(object.URL) {
+		return true
+	}
+
 	if err := object.Err; err != nil {
 		if verbose {
 			printError(s.fullCommand, s.op, err)
@@ -529,8 +1602,36 @@ func (s Sync) shouldSkipObject(object *storage.Object, verbose bool) bool {
 		return true
 	}
 
+	// the objects which are excluded or not included are skipped in
+	// destination as well, so that they are not deleted as the objects only
+	// in destination, unless --delete-excluded is given. The patterns are
+	// matched against the keys relative to the source and the destination.
+	key := filepath.ToSlash(object.URL.Relative())
+	if isURLExcluded(s.excludePatterns, key, "") {
+		if verbose {
+			accessLog.record(s.op, object.URL, object.Size, accessExcluded, "matches an exclude pattern")
+		}
+		return true
+	}
+	if !isURLIncluded(s.includePatterns, key, "") {
+		if verbose {
+			accessLog.record(s.op, object.URL, object.Size, accessExcluded, "matches no include pattern")
+		}
+		return true
+	}
+
+	// the objects in destination which are not in the filtered storage
+	// classes are left alone, as the excluded ones.
+	if !s.storageClasses.match(object.URL, object.StorageClass) {
+		if verbose {
+			accessLog.record(s.op, object.URL, object.Size, accessExcluded, "storage class is filtered out")
+		}
+		return true
+	}
+
 	if object.StorageClass.IsGlacier() {
 		if verbose {
+			accessLog.record(s.op, object.URL, object.Size, accessSkipped, "object is on Glacier storage")
 			err := fmt.Errorf("object '%v' is on Glacier storage", object)
 			printError(s.fullCommand, s.op, err)
 		}
@@ -538,3 +1639,156 @@ func (s Sync) shouldSkipObject(object *storage.Object, verbose bool) bool {
 	}
 	return false
 }
+
+// syncStrategyName returns the name of the sync strategy selected by the
+// flags.
+func syncStrategyName(c *cli.Context) string {
+	if c.Bool("size-only") {
+		return syncStrategySize
+	}
+	if c.Bool("checksum") {
+		return syncStrategyChecksum
+	}
+	return c.String("sync-strategy")
+}
+
+func validateSyncStrategyFlags(c *cli.Context) error {
+	if c.Bool("size-only") && c.Bool("prefer-source") {
+		return fmt.Errorf("it is not allowed to combine %q and %q flags", "size-only", "prefer-source")
+	}
+
+	if c.Bool("size-only") && c.IsSet("sync-strategy") && c.String("sync-strategy") != syncStrategySize {
+		return fmt.Errorf("it is not allowed to combine %q and %q flags", "size-only", "sync-strategy")
+	}
+
+	if c.Bool("checksum") && c.Bool("size-only") {
+		return fmt.Errorf("it is not allowed to combine %q and %q flags", "checksum", "size-only")
+	}
+
+	if c.Bool("checksum") && c.IsSet("sync-strategy") && c.String("sync-strategy") != syncStrategyChecksum {
+		return fmt.Errorf("it is not allowed to combine %q and %q flags", "checksum", "sync-strategy")
+	}
+
+	strategy := syncStrategyName(c)
+	if c.Bool("prefer-source") && strategy != syncStrategySizeMtime && strategy != syncStrategyChecksum {
+		return fmt.Errorf("%q flag requires %q or %q sync strategy", "prefer-source", syncStrategySizeMtime, syncStrategyChecksum)
+	}
+
+	if strategy == syncStrategyEtag && c.Args().Len() == 2 {
+		for _, arg := range c.Args().Slice() {
+			if u, err := url.New(arg); err == nil && !u.IsRemote() {
+				return fmt.Errorf("%q sync strategy requires remote source and destination", syncStrategyEtag)
+			}
+		}
+	}
+
+	if c.Duration("skew-tolerance") < 0 {
+		return fmt.Errorf("skew-tolerance cannot be negative")
+	}
+
+	if c.Bool("diff") && !isDryRun(c) {
+		return fmt.Errorf("%q flag requires %q flag", "diff", "dry-run")
+	}
+
+	if err := validateMatchEncryption(c); err != nil {
+		return err
+	}
+
+	if c.IsSet("delete-batch-size") && !c.Bool("delete") {
+		return fmt.Errorf("%q flag requires %q flag", "delete-batch-size", "delete")
+	}
+	if err := validateDeleteBatchSize(c); err != nil {
+		return err
+	}
+
+	if err := validateKeepSentinels(c); err != nil {
+		return err
+	}
+
+	if err := validateDeleteScope(c); err != nil {
+		return err
+	}
+
+	if err := validateDeleteExcluded(c); err != nil {
+		return err
+	}
+
+	if err := validateLock(c); err != nil {
+		return err
+	}
+
+	if err := validateMaxDelete(c); err != nil {
+		return err
+	}
+
+	if err := validatePreserveMetadata(c); err != nil {
+		return err
+	}
+
+	if err := validateKeyRules(c); err != nil {
+		return err
+	}
+
+	if err := validateDirMtimeShortcut(c); err != nil {
+		return err
+	}
+
+	if err := validateCompareExec(c); err != nil {
+		return err
+	}
+
+	if err := validateMaxCompareMemory(c); err != nil {
+		return err
+	}
+
+	if err := validateStream(c); err != nil {
+		return err
+	}
+
+	// the local files in source or destination have no storage class, and
+	// are not filtered.
+	if err := validateStorageClassFilter(c, nil); err != nil {
+		return err
+	}
+
+	if err := validateTwoWay(c); err != nil {
+		return err
+	}
+
+	if err := validateExitCodeOnChange(c); err != nil {
+		return err
+	}
+
+	if err := validateExitCode(c); err != nil {
+		return err
+	}
+
+	if err := validateFilesystemFlags(c); err != nil {
+		return err
+	}
+
+	// the sizes and the checksums of the transformed objects are not the
+	// ones of their sources.
+	if c.String(transformExecFlagName) != "" {
+		if strategy != syncStrategySizeMtime && strategy != syncStrategyAlways {
+			return fmt.Errorf("%q flag requires %q or %q sync strategy", transformExecFlagName, syncStrategySizeMtime, syncStrategyAlways)
+		}
+		if c.Bool("prefer-source") {
+			return fmt.Errorf("it is not allowed to combine %q and %q flags", transformExecFlagName, "prefer-source")
+		}
+		if c.IsSet(compareExecFlagName) {
+			return fmt.Errorf("it is not allowed to combine %q and %q flags", transformExecFlagName, compareExecFlagName)
+		}
+	}
+
+	if c.IsSet("delete-batch-interleave") {
+		if !c.Bool("delete") {
+			return fmt.Errorf("%q flag requires %q flag", "delete-batch-interleave", "delete")
+		}
+		if c.Int("delete-batch-interleave") <= 0 {
+			return fmt.Errorf("delete-batch-interleave must be greater than zero")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,205 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/urfave/cli/v2"
+
+	errorpkg "github.com/peak/s5cmd/v2/error"
+	"github.com/peak/s5cmd/v2/log"
+	"github.com/peak/s5cmd/v2/strutil"
+)
+
+const (
+	exitCodeOnChangeFlagName = "exit-code-on-change"
+
+	// syncExitChanged is the exit code of sync with --exit-code-on-change
+	// flag if it made changes without any failures.
+	syncExitChanged = 10
+)
+
+// errSyncChanged is returned by sync with --exit-code-on-change flag if it
+// made changes. It is not printed.
+var errSyncChanged = &errorpkg.ExitError{
+	Code: syncExitChanged,
+	Err:  errors.New("changes made"),
+}
+
+// SyncChangesMessage is the structure for the number of the changes a sync
+// made.
+type SyncChangesMessage struct {
+	ChangesMade bool  `json:"changes_made"`
+	Copied      int64 `json:"copied"`
+	Deleted     int64 `json:"deleted"`
+}
+
+// String is the string representation of SyncChangesMessage.
+func (m SyncChangesMessage) String() string {
+	if !m.ChangesMade {
+		return "no changes made"
+	}
+	return fmt.Sprintf("changes made: copied %d objects, deleted %d objects", m.Copied, m.Deleted)
+}
+
+// JSON is the JSON representation of SyncChangesMessage.
+func (m SyncChangesMessage) JSON() string {
+	return strutil.JSON(struct {
+		Operation string `json:"operation"`
+		SyncChangesMessage
+	}{
+		Operation:          "sync-changes",
+		SyncChangesMessage: m,
+	})
+}
+
+type syncChangesKey struct{}
+
+// syncChanges counts the objects which are copied and deleted successfully by
+// the commands a sync runs, so that it tells whether the sync changed the
+// destination. The objects which are skipped or failed are counted as well for
+// the summary of the sync. The counters are carried by the context of the
+// sync, which the commands it runs inherit. It is shared by the partitions of
+// a sync.
+type syncChanges struct {
+	copied  int64
+	deleted int64
+	bytes   int64
+	skipped int64
+	failed  int64
+
+	// onChange is set if the sync exits with syncExitChanged if it made any
+	// change.
+	onChange bool
+	// summary is set if the summary of the sync is printed once it is
+	// completed.
+	summary bool
+}
+
+// newSyncChanges returns the change counter of the sync if the
+// "exit-code-on-change" or the "summary" flag is given, and nil otherwise. The
+// dry runs print the summary of their plans instead.
+func newSyncChanges(c *cli.Context) *syncChanges {
+	onChange := c.Bool(exitCodeOnChangeFlagName)
+	summary := c.Bool(summaryFlagName) && !isDryRun(c)
+	if !onChange && !summary {
+		return nil
+	}
+	return &syncChanges{onChange: onChange, summary: summary}
+}
+
+// context returns the context which the commands of the sync must be run with
+// to be counted. It returns ctx as is if s is nil.
+func (s *syncChanges) context(ctx context.Context) context.Context {
+	if s == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, syncChangesKey{}, s)
+}
+
+// recordSyncTransfer counts the transfer of an object of the given size made
+// with ctx which is completed with the given status. It is counted as a change
+// if it wrote the destination. It is a no-op if the transfer is not made by a
+// sync which counts its changes.
+func recordSyncTransfer(ctx context.Context, status string, size int64) {
+	s, ok := ctx.Value(syncChangesKey{}).(*syncChanges)
+	if !ok {
+		return
+	}
+	switch status {
+	// the partial transfers wrote some of their destinations.
+	case transferSucceeded, transferPartial:
+		atomic.AddInt64(&s.copied, 1)
+		atomic.AddInt64(&s.bytes, size)
+	case transferFailed:
+		atomic.AddInt64(&s.failed, 1)
+	default:
+		atomic.AddInt64(&s.skipped, 1)
+	}
+}
+
+// recordSyncDelete counts an object deleted with ctx as a change, or as a
+// failure if it couldn't be deleted with err. It is a no-op if the object is
+// not deleted by a sync which counts its changes.
+func recordSyncDelete(ctx context.Context, err error) {
+	s, ok := ctx.Value(syncChangesKey{}).(*syncChanges)
+	if !ok {
+		return
+	}
+	if err != nil {
+		atomic.AddInt64(&s.failed, 1)
+		return
+	}
+	atomic.AddInt64(&s.deleted, 1)
+}
+
+// addSkipped counts an object which is not copied by the sync itself, e.g.
+// because it's up-to-date in destination. It is a no-op if s is nil.
+func (s *syncChanges) addSkipped() {
+	if s != nil {
+		atomic.AddInt64(&s.skipped, 1)
+	}
+}
+
+// addFailed counts an object which the sync failed to compare. It is a no-op
+// if s is nil.
+func (s *syncChanges) addFailed() {
+	if s != nil {
+		atomic.AddInt64(&s.failed, 1)
+	}
+}
+
+// message returns the changes the sync made.
+func (s *syncChanges) message() SyncChangesMessage {
+	msg := SyncChangesMessage{
+		Copied:  atomic.LoadInt64(&s.copied),
+		Deleted: atomic.LoadInt64(&s.deleted),
+	}
+	msg.ChangesMade = msg.Copied > 0 || msg.Deleted > 0
+	return msg
+}
+
+// result prints the summary of the sync and the changes it made, if they are
+// asked for, and returns the error of the sync which completed with err. It
+// returns err as is if s is nil.
+func (s *syncChanges) result(err error) error {
+	if s == nil {
+		return err
+	}
+	if s.summary {
+		log.Summary(s.summaryMessage())
+	}
+	if !s.onChange {
+		return err
+	}
+	msg := s.message()
+	log.Info(msg)
+	return syncChangesError(msg, err)
+}
+
+// syncChangesError returns the error of a sync which made the given changes
+// and completed with err: err itself if it failed, errSyncChanged if it made
+// changes and nil otherwise.
+func syncChangesError(msg SyncChangesMessage, err error) error {
+	if err != nil {
+		return err
+	}
+	if msg.ChangesMade {
+		return errSyncChanged
+	}
+	return nil
+}
+
+func validateExitCodeOnChange(c *cli.Context) error {
+	if !c.Bool(exitCodeOnChangeFlagName) {
+		return nil
+	}
+	// the exit codes of the commands in run-mode are not the exit code of
+	// s5cmd.
+	if inRunMode(c) {
+		return fmt.Errorf("%q flag is not permitted in run-mode", exitCodeOnChangeFlagName)
+	}
+	return nil
+}
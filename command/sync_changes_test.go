@@ -0,0 +1,109 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	errorpkg "github.com/peak/s5cmd/v2/error"
+)
+
+func TestSyncChangesResult(t *testing.T) {
+	errFailed := errors.New("failed")
+
+	testcases := []struct {
+		name         string
+		transfers    []string
+		deletes      int
+		err          error
+		expectedCode int
+	}{
+		{
+			name:         "no changes",
+			transfers:    []string{transferSkipped, transferFailed, transferVanished},
+			expectedCode: 0,
+		},
+		{
+			name:         "copied",
+			transfers:    []string{transferSkipped, transferSucceeded},
+			expectedCode: syncExitChanged,
+		},
+		{
+			name:         "partially copied",
+			transfers:    []string{transferPartial},
+			expectedCode: syncExitChanged,
+		},
+		{
+			name:         "deleted",
+			deletes:      1,
+			expectedCode: syncExitChanged,
+		},
+		{
+			name:         "failed",
+			transfers:    []string{transferSucceeded},
+			err:          errFailed,
+			expectedCode: 1,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			changes := &syncChanges{onChange: true}
+			ctx := changes.context(context.Background())
+			for _, status := range tc.transfers {
+				recordSyncTransfer(ctx, status, 10)
+			}
+			for i := 0; i < tc.deletes; i++ {
+				recordSyncDelete(ctx, nil)
+			}
+
+			err := syncChangesError(changes.message(), tc.err)
+			if got := errorpkg.ExitCode(err); got != tc.expectedCode {
+				t.Errorf("expected exit code %v, got %v", tc.expectedCode, got)
+			}
+			if tc.err != nil && !errors.Is(err, tc.err) {
+				t.Errorf("expected error %v, got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestSyncChangesNotCounted(t *testing.T) {
+	var changes *syncChanges
+
+	ctx := changes.context(context.Background())
+	recordSyncTransfer(ctx, transferSucceeded, 10)
+	recordSyncDelete(ctx, nil)
+
+	errFailed := errors.New("failed")
+	if err := changes.result(errFailed); err != errFailed {
+		t.Errorf("expected error %v, got %v", errFailed, err)
+	}
+}
+
+func TestSyncChangesSummary(t *testing.T) {
+	changes := &syncChanges{summary: true}
+	ctx := changes.context(context.Background())
+
+	recordSyncTransfer(ctx, transferSucceeded, 10)
+	recordSyncTransfer(ctx, transferPartial, 20)
+	recordSyncTransfer(ctx, transferFailed, 40)
+	recordSyncTransfer(ctx, transferSkipped, 80)
+	recordSyncTransfer(ctx, transferVanished, 160)
+	recordSyncDelete(ctx, nil)
+	recordSyncDelete(ctx, errors.New("access denied"))
+	changes.addSkipped()
+	changes.addFailed()
+
+	expected := SyncSummaryMessage{
+		Copied:  2,
+		Bytes:   30,
+		Deleted: 1,
+		Skipped: 3,
+		Failed:  3,
+	}
+	if got := changes.summaryMessage(); got != expected {
+		t.Errorf("expected summary %+v, got %+v", expected, got)
+	}
+}
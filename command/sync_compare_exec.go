@@ -0,0 +1,352 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/kballard/go-shellquote"
+	"github.com/urfave/cli/v2"
+
+	errorpkg "github.com/peak/s5cmd/v2/error"
+	"github.com/peak/s5cmd/v2/log"
+	"github.com/peak/s5cmd/v2/storage"
+	"github.com/peak/s5cmd/v2/strutil"
+)
+
+const (
+	compareExecFlagName            = "compare-exec"
+	compareExecInputFlagName       = "compare-exec-input"
+	compareExecConcurrencyFlagName = "compare-exec-concurrency"
+	compareExecTimeoutFlagName     = "compare-exec-timeout"
+	compareExecOnErrorFlagName     = "compare-exec-on-error"
+
+	compareExecInputURL  = "url"
+	compareExecInputFile = "file"
+
+	compareExecOnErrorSync = "sync"
+	compareExecOnErrorSkip = "skip"
+
+	defaultCompareExecConcurrency = 4
+	defaultCompareExecTimeout     = 5 * time.Minute
+
+	// compareExecPresignExpiry is the expiry of the presigned URLs if the
+	// compare command has no timeout.
+	compareExecPresignExpiry = time.Hour
+
+	// the exit code of the compare command for the objects which are not
+	// equivalent. Zero means they are equivalent, and the other codes mean
+	// the comparison failed, like cmp and diff.
+	compareExecExitDifferent = 1
+)
+
+// compareExec runs the compare command given with --compare-exec for the
+// objects in both source and destination which the sync strategy finds
+// different, to let the command decide whether they are equivalent, e.g. the
+// files with the same data in a different layout. The commands of all
+// partitions of a sync are run with the same limit.
+type compareExec struct {
+	args        []string
+	input       string
+	timeout     time.Duration
+	syncOnError bool
+	semaphore   chan struct{}
+	// position is the position of the sync in the input of run, if any.
+	position string
+
+	srcOpts storage.Options
+	dstOpts storage.Options
+
+	compared   int64
+	equivalent int64
+	different  int64
+	failed     int64
+}
+
+// newCompareExec creates the compare command runner from the flags. It
+// returns nil if the "compare-exec" flag is not given.
+func newCompareExec(c *cli.Context) *compareExec {
+	command := c.String(compareExecFlagName)
+	if command == "" {
+		return nil
+	}
+
+	srcOpts, dstOpts := NewStorageOpts(c), NewStorageOpts(c)
+	srcOpts.SetRegion(c.String("source-region"))
+	dstOpts.SetRegion(c.String("destination-region"))
+
+	// the command is split as the lines of run input are, so that its
+	// arguments can be quoted. It is validated by validateCompareExec.
+	args, _ := shellquote.Split(command)
+
+	return &compareExec{
+		args:        args,
+		input:       c.String(compareExecInputFlagName),
+		timeout:     c.Duration(compareExecTimeoutFlagName),
+		syncOnError: c.String(compareExecOnErrorFlagName) == compareExecOnErrorSync,
+		semaphore:   make(chan struct{}, c.Int(compareExecConcurrencyFlagName)),
+		srcOpts:     srcOpts,
+		dstOpts:     dstOpts,
+		position:    commandPosition(c),
+	}
+}
+
+// comparedPair is an object pair along with the result of its comparison.
+type comparedPair struct {
+	*ObjectPair
+	// reason is the reason the object is not synced, if any.
+	reason *SkipReason
+	// err is the error of the compare command, and sync tells whether the
+	// object is synced despite it.
+	err  error
+	sync bool
+}
+
+// compare compares the object pairs with the sync strategy, and runs the
+// compare command for the ones the strategy finds different. The results are
+// sent in the order of the pairs. The pairs are only compared with the sync
+// strategy if e is nil.
+func (e *compareExec) compare(
+	ctx context.Context,
+	op string,
+	common <-chan *ObjectPair,
+	strategy SyncStrategy,
+) <-chan comparedPair {
+	out := make(chan comparedPair)
+
+	if e == nil {
+		go func() {
+			defer close(out)
+			for pair := range common {
+				out <- comparedPair{ObjectPair: pair, reason: strategy.ShouldSync(pair.src, pair.dst)}
+			}
+		}()
+		return out
+	}
+
+	// the results are queued in the order of the pairs, while the commands
+	// are run concurrently.
+	results := make(chan chan comparedPair, 2*cap(e.semaphore))
+	go func() {
+		defer close(results)
+		for pair := range common {
+			result := make(chan comparedPair, 1)
+			results <- result
+
+			if reason := strategy.ShouldSync(pair.src, pair.dst); reason != nil {
+				result <- comparedPair{ObjectPair: pair, reason: reason}
+				continue
+			}
+
+			e.semaphore <- struct{}{}
+			go func(pair *ObjectPair) {
+				defer func() { <-e.semaphore }()
+				result <- e.run(ctx, op, pair)
+			}(pair)
+		}
+	}()
+
+	go func() {
+		defer close(out)
+		for result := range results {
+			out <- <-result
+		}
+	}()
+	return out
+}
+
+// run runs the compare command for the object pair.
+func (e *compareExec) run(ctx context.Context, op string, pair *ObjectPair) comparedPair {
+	atomic.AddInt64(&e.compared, 1)
+
+	equivalent, err := e.exec(ctx, pair.src, pair.dst)
+	switch {
+	case err != nil:
+		atomic.AddInt64(&e.failed, 1)
+		return comparedPair{
+			ObjectPair: pair,
+			err: &errorpkg.Error{
+				Op:       op,
+				Src:      pair.src.URL,
+				Dst:      pair.dst.URL,
+				Err:      fmt.Errorf("compare command failed: %w", err),
+				Position: e.position,
+			},
+			sync: e.syncOnError,
+		}
+	case equivalent:
+		atomic.AddInt64(&e.equivalent, 1)
+		return comparedPair{
+			ObjectPair: pair,
+			reason:     newSkipReason(SkipCompareExecEquivalent, pair.src, pair.dst),
+		}
+	default:
+		atomic.AddInt64(&e.different, 1)
+		return comparedPair{ObjectPair: pair}
+	}
+}
+
+// exec runs the compare command with the inputs of srcObj and dstObj, and
+// reports whether it finds them equivalent.
+func (e *compareExec) exec(ctx context.Context, srcObj, dstObj *storage.Object) (bool, error) {
+	if e.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.timeout)
+		defer cancel()
+	}
+
+	src, cleanup, err := e.prepareInput(ctx, srcObj, e.srcOpts)
+	if err != nil {
+		return false, err
+	}
+	defer cleanup()
+
+	dst, cleanup, err := e.prepareInput(ctx, dstObj, e.dstOpts)
+	if err != nil {
+		return false, err
+	}
+	defer cleanup()
+
+	// the arguments are not passed through a shell, so that the keys are
+	// not interpreted by it.
+	replacer := strings.NewReplacer("{src}", src, "{dst}", dst)
+	args := make([]string, 0, len(e.args))
+	for _, arg := range e.args {
+		args = append(args, replacer.Replace(arg))
+	}
+
+	// the errors of the command are printed as they are. They are not
+	// captured, since the children of the command would keep the command
+	// running after it is killed on timeout, until they close the pipe.
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stderr = os.Stderr
+
+	err = cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return false, fmt.Errorf("timed out after %v", e.timeout)
+	}
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.As(err, &exitErr) && exitErr.ExitCode() == compareExecExitDifferent:
+		return false, nil
+	}
+	return false, err
+}
+
+// prepareInput returns the argument which the object is given to the compare
+// command with, and the function to clean it up once the command is
+// completed. The local files are given with their paths, and the remote
+// objects are given with presigned URLs or downloaded to the temporary files
+// of the spool depending on --compare-exec-input.
+func (e *compareExec) prepareInput(ctx context.Context, obj *storage.Object, opts storage.Options) (string, func(), error) {
+	noop := func() {}
+	u := obj.URL
+	if !u.IsRemote() {
+		return u.Absolute(), noop, nil
+	}
+
+	client, err := storage.NewRemoteClient(ctx, u, opts)
+	if err != nil {
+		return "", noop, err
+	}
+
+	if e.input == compareExecInputURL {
+		expiry := e.timeout
+		if expiry == 0 {
+			expiry = compareExecPresignExpiry
+		}
+		presigned, err := client.Presign(ctx, u, expiry)
+		return presigned, noop, err
+	}
+
+	file, err := tempSpool.Create("compare-*", obj.Size)
+	if err != nil {
+		return "", noop, err
+	}
+	cleanup := func() { file.Remove() }
+
+	_, err = client.Get(ctx, u, file, defaultCopyConcurrency, defaultPartSize*megabytes)
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		cleanup()
+		return "", noop, err
+	}
+	return file.Name(), cleanup, nil
+}
+
+// report prints the number of the objects compared with the compare command
+// by their results. It is a no-op if e is nil.
+func (e *compareExec) report(op string) {
+	if e == nil {
+		return
+	}
+	log.Info(CompareExecSummaryMessage{
+		Operation:  op,
+		Compared:   atomic.LoadInt64(&e.compared),
+		Equivalent: atomic.LoadInt64(&e.equivalent),
+		Different:  atomic.LoadInt64(&e.different),
+		Failed:     atomic.LoadInt64(&e.failed),
+	})
+}
+
+// CompareExecSummaryMessage is the number of the objects compared with the
+// compare command by their results.
+type CompareExecSummaryMessage struct {
+	Operation  string `json:"operation"`
+	Compared   int64  `json:"compared"`
+	Equivalent int64  `json:"equivalent"`
+	Different  int64  `json:"different"`
+	Failed     int64  `json:"failed"`
+}
+
+// String returns the string representation of CompareExecSummaryMessage.
+func (m CompareExecSummaryMessage) String() string {
+	return fmt.Sprintf("%v: %d objects compared with compare command, %d equivalent, %d different, %d failed",
+		m.Operation, m.Compared, m.Equivalent, m.Different, m.Failed)
+}
+
+// JSON returns the JSON representation of CompareExecSummaryMessage.
+func (m CompareExecSummaryMessage) JSON() string {
+	return strutil.JSON(m)
+}
+
+func validateCompareExec(c *cli.Context) error {
+	command := c.String(compareExecFlagName)
+	for _, flag := range []string{
+		compareExecInputFlagName,
+		compareExecConcurrencyFlagName,
+		compareExecTimeoutFlagName,
+		compareExecOnErrorFlagName,
+	} {
+		if c.IsSet(flag) && command == "" {
+			return fmt.Errorf("%q flag requires %q flag", flag, compareExecFlagName)
+		}
+	}
+	if command == "" {
+		return nil
+	}
+	args, err := shellquote.Split(command)
+	if err != nil {
+		return fmt.Errorf("invalid %q flag: %w", compareExecFlagName, err)
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("%q flag requires a command", compareExecFlagName)
+	}
+	if c.Int(compareExecConcurrencyFlagName) < 1 {
+		return fmt.Errorf("%q flag must be greater than zero", compareExecConcurrencyFlagName)
+	}
+	if c.Duration(compareExecTimeoutFlagName) < 0 {
+		return fmt.Errorf("%q flag must not be negative", compareExecTimeoutFlagName)
+	}
+	return nil
+}
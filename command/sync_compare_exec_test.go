@@ -0,0 +1,179 @@
+package command
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/peak/s5cmd/v2/storage"
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+// compareScript is a compare command which finds the files with "same" in
+// their names equivalent, fails for the ones with "fail" and hangs for the
+// ones with "slow".
+const compareScript = `#!/bin/sh
+case "$1" in
+*same*) exit 0 ;;
+*fail*) exit 2 ;;
+*slow*) exec sleep 10 ;;
+esac
+exit 1
+`
+
+func TestCompareExec(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("compare script requires a POSIX shell")
+	}
+
+	dir := t.TempDir()
+	script := filepath.Join(dir, "compare.sh")
+	assert.NilError(t, os.WriteFile(script, []byte(compareScript), 0o755))
+
+	newObject := func(path string) *storage.Object {
+		u, err := url.New(filepath.Join(dir, path))
+		assert.NilError(t, err)
+		return &storage.Object{URL: u, Size: 10}
+	}
+
+	names := []string{"a-same", "b-diff", "c-fail", "d-slow", "e-same"}
+	common := make(chan *ObjectPair, len(names)+1)
+	for _, name := range names {
+		common <- &ObjectPair{src: newObject("src/" + name), dst: newObject("dst/" + name)}
+	}
+	// the objects which the strategy doesn't sync are not compared.
+	small := &ObjectPair{src: newObject("src/f-fail"), dst: newObject("dst/f-fail")}
+	common <- small
+	close(common)
+
+	e := &compareExec{
+		args:        []string{script, "{src}", "{dst}"},
+		input:       compareExecInputURL,
+		timeout:     500 * time.Millisecond,
+		syncOnError: true,
+		semaphore:   make(chan struct{}, 2),
+	}
+
+	var results []comparedPair
+	for result := range e.compare(context.Background(), "sync", common, skipPairStrategy{skip: small}) {
+		results = append(results, result)
+	}
+
+	assert.Equal(t, len(results), 6)
+	// the results are in the order of the pairs.
+	for i, name := range append(names, "f-fail") {
+		assert.Assert(t, strings.HasSuffix(results[i].src.URL.Path, name))
+	}
+
+	assert.Equal(t, results[0].reason.Code, SkipCompareExecEquivalent)
+	assert.Assert(t, results[1].reason == nil && results[1].err == nil)
+	assert.ErrorContains(t, results[2].err, "compare command failed")
+	assert.ErrorContains(t, results[2].err, "exit status 2")
+	assert.Assert(t, results[2].sync)
+	assert.ErrorContains(t, results[3].err, "timed out")
+	assert.Equal(t, results[4].reason.Code, SkipCompareExecEquivalent)
+	assert.Equal(t, results[5].reason.Code, SkipSizesMatch)
+
+	assert.Equal(t, e.compared, int64(5))
+	assert.Equal(t, e.equivalent, int64(2))
+	assert.Equal(t, e.different, int64(1))
+	assert.Equal(t, e.failed, int64(2))
+}
+
+func TestCompareExecNil(t *testing.T) {
+	var e *compareExec
+
+	u, err := url.New("s3://bucket/key")
+	assert.NilError(t, err)
+	pair := &ObjectPair{src: &storage.Object{URL: u}, dst: &storage.Object{URL: u}}
+
+	common := make(chan *ObjectPair, 1)
+	common <- pair
+	close(common)
+
+	var results []comparedPair
+	for result := range e.compare(context.Background(), "sync", common, skipPairStrategy{skip: pair}) {
+		results = append(results, result)
+	}
+	assert.Equal(t, len(results), 1)
+	assert.Equal(t, results[0].reason.Code, SkipSizesMatch)
+}
+
+// skipPairStrategy syncs all objects except the given pair.
+type skipPairStrategy struct {
+	skip *ObjectPair
+}
+
+func (s skipPairStrategy) ShouldSync(srcObj, dstObj *storage.Object) *SkipReason {
+	if srcObj == s.skip.src {
+		return newSkipReason(SkipSizesMatch, srcObj, dstObj)
+	}
+	return nil
+}
+
+func TestCompareExecQuotedArguments(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("compare command requires a POSIX shell")
+	}
+
+	dir := t.TempDir()
+	newObject := func(path, content string) *storage.Object {
+		path = filepath.Join(dir, path)
+		assert.NilError(t, os.WriteFile(path, []byte(content), 0o644))
+		u, err := url.New(path)
+		assert.NilError(t, err)
+		return &storage.Object{URL: u, Size: int64(len(content))}
+	}
+
+	// the script is a single quoted argument of sh.
+	c := newSyncContext(t, map[string][]string{
+		compareExecFlagName: {`sh -c 'cmp -s "$0" "$1"' {src} {dst}`},
+	})
+	assert.NilError(t, validateCompareExec(c))
+
+	e := newCompareExec(c)
+	assert.DeepEqual(t, e.args, []string{"sh", "-c", `cmp -s "$0" "$1"`, "{src}", "{dst}"})
+
+	equivalent, err := e.exec(context.Background(), newObject("src same", "content"), newObject("dst same", "content"))
+	assert.NilError(t, err)
+	assert.Assert(t, equivalent)
+
+	equivalent, err = e.exec(context.Background(), newObject("src diff", "content"), newObject("dst diff", "other"))
+	assert.NilError(t, err)
+	assert.Assert(t, !equivalent)
+}
+
+func TestValidateCompareExec(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		command  string
+		expected string
+	}{
+		{name: "quoted arguments", command: `cmp "{src}" '{dst}'`},
+		{name: "unterminated quote", command: `cmp "{src} {dst}`, expected: `invalid "compare-exec" flag: Unterminated double-quoted string`},
+		{name: "blank command", command: "  ", expected: `"compare-exec" flag requires a command`},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			c := newSyncContext(t, map[string][]string{compareExecFlagName: {tc.command}})
+			err := validateCompareExec(c)
+			if tc.expected == "" {
+				assert.NilError(t, err)
+				return
+			}
+			assert.Error(t, err, tc.expected)
+		})
+	}
+}
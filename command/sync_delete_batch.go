@@ -0,0 +1,86 @@
+package command
+
+import (
+	"fmt"
+
+	"github.com/peak/s5cmd/v2/log"
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+// deleteBatcher collects the URLs of the objects only in destination into the
+// batches of at most size URLs, which are deleted with an rm command each. A
+// batch is emitted as soon as it is filled, so the objects are not kept until
+// all of them are received, and a failing batch doesn't abort the others.
+type deleteBatcher struct {
+	size int
+	emit func(batch []*url.URL)
+
+	batch []*url.URL
+	// total and batches are the numbers of the added URLs and the emitted
+	// batches.
+	total   int
+	batches int
+}
+
+// newDeleteBatcher returns a batcher which passes the batches of at most size
+// URLs to emit. The URLs are emitted in a single batch if size is not
+// positive.
+func newDeleteBatcher(size int, emit func(batch []*url.URL)) *deleteBatcher {
+	return &deleteBatcher{
+		size: size,
+		emit: emit,
+	}
+}
+
+// add adds the URL to the current batch, and emits the batch once it is
+// filled.
+func (b *deleteBatcher) add(u *url.URL) {
+	b.batch = append(b.batch, u)
+	b.total++
+	if b.size > 0 && len(b.batch) >= b.size {
+		b.flush()
+	}
+}
+
+// flush emits the current batch, if it is not empty.
+func (b *deleteBatcher) flush() {
+	if len(b.batch) == 0 {
+		return
+	}
+	batch := b.batch
+	b.batch = nil
+	b.batches++
+	b.emit(batch)
+}
+
+// planDeleteBatcher returns a batcher which generates the rm commands of the
+// objects only in destination, one for each batch of them, and passes them to
+// add with their objects.
+func (s Sync) planDeleteBatcher(rmCommands *commandGenerator, add func(command string, batch []*url.URL)) *deleteBatcher {
+	return newDeleteBatcher(s.deleteBatchSize, func(batch []*url.URL) {
+		add(rmCommands.generate(nil, batch...), batch)
+	})
+}
+
+// finishDeletes emits the last batch of the deletes and logs the number of
+// the rm commands they are deleted with.
+func (s Sync) finishDeletes(b *deleteBatcher) {
+	b.flush()
+	if b.batches > 1 {
+		log.Debug(log.DebugMessage{
+			Operation: s.op,
+			Err:       fmt.Sprintf("deleting %d objects in destination with %d rm commands of at most %d objects", b.total, b.batches, s.deleteBatchSize),
+		})
+	}
+}
+
+// planDeletes generates the rm commands of the given objects only in
+// destination, one for each batch of them, and passes them to add with their
+// objects.
+func (s Sync) planDeletes(rmCommands *commandGenerator, dsturls []*url.URL, add func(command string, batch []*url.URL)) {
+	batcher := s.planDeleteBatcher(rmCommands, add)
+	for _, u := range dsturls {
+		batcher.add(u)
+	}
+	s.finishDeletes(batcher)
+}
@@ -0,0 +1,129 @@
+package command
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"testing"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/peak/s5cmd/v2/log"
+	"github.com/peak/s5cmd/v2/storage"
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+func TestDeleteBatcher(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		urls     int
+		size     int
+		expected []int
+	}{
+		{name: "no urls", urls: 0, size: 1000, expected: nil},
+		{name: "single batch", urls: 3, size: 1000, expected: []int{3}},
+		{name: "exact batches", urls: 4, size: 2, expected: []int{2, 2}},
+		{name: "last batch smaller", urls: 5, size: 2, expected: []int{2, 2, 1}},
+		{name: "batches of one", urls: 3, size: 1, expected: []int{1, 1, 1}},
+		{name: "no size", urls: 3, size: 0, expected: []int{3}},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var urls []*url.URL
+			for i := 0; i < tc.urls; i++ {
+				urls = append(urls, mustNewURL(t, fmt.Sprintf("s3://bucket/%d", i)))
+			}
+
+			var (
+				sizes []int
+				got   []*url.URL
+			)
+			batcher := newDeleteBatcher(tc.size, func(batch []*url.URL) {
+				sizes = append(sizes, len(batch))
+				got = append(got, batch...)
+			})
+			for i, u := range urls {
+				batcher.add(u)
+				// the filled batches are emitted as the urls are added.
+				if tc.size > 0 {
+					assert.Equal(t, len(got), (i+1)/tc.size*tc.size)
+				}
+			}
+			batcher.flush()
+
+			assert.DeepEqual(t, sizes, tc.expected)
+			assert.Equal(t, batcher.total, tc.urls)
+			assert.Equal(t, batcher.batches, len(tc.expected))
+			// the batches keep the order of the urls.
+			assert.Equal(t, len(got), len(urls))
+			for i := range urls {
+				assert.Equal(t, got[i], urls[i])
+			}
+		})
+	}
+}
+
+func TestSyncPlanRunDeletesBeforeListingIsCompleted(t *testing.T) {
+	log.Init("error", false)
+
+	c := newSyncContext(t, map[string][]string{"delete": {"true"}})
+	s := Sync{
+		op:              "sync",
+		delete:          true,
+		deleteBatchSize: 2,
+	}
+
+	var (
+		onlySource = make(chan *storage.Object)
+		onlyDest   = make(chan *storage.Object)
+		common     = make(chan *ObjectPair)
+	)
+	close(onlySource)
+	close(common)
+
+	reader, writer := io.Pipe()
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- s.planRun(
+			c,
+			mustNewURL(t, "s3://bucket/src/"),
+			onlySource,
+			onlyDest,
+			common,
+			mustNewURL(t, "s3://bucket/dst/"),
+			nil,
+			writer,
+			true,
+			nil,
+		)
+	}()
+
+	object := func(key string) *storage.Object {
+		u := mustNewURL(t, "s3://bucket/dst/"+key)
+		u.SetRelative(mustNewURL(t, "s3://bucket/dst/"))
+		return &storage.Object{URL: u}
+	}
+
+	lines := bufio.NewScanner(reader)
+
+	onlyDest <- object("a")
+	onlyDest <- object("b")
+
+	// the first batch is planned while the channel is still open.
+	assert.Assert(t, lines.Scan())
+	assert.Equal(t, lines.Text(), `rm --raw=true "s3://bucket/dst/a" "s3://bucket/dst/b"`)
+
+	onlyDest <- object("c")
+	close(onlyDest)
+
+	assert.Assert(t, lines.Scan())
+	assert.Equal(t, lines.Text(), `rm --raw=true "s3://bucket/dst/c"`)
+	assert.Assert(t, !lines.Scan())
+	assert.NilError(t, <-errCh)
+}
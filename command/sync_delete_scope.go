@@ -0,0 +1,136 @@
+package command
+
+import (
+	"fmt"
+	"regexp"
+	"sync/atomic"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/peak/s5cmd/v2/log"
+	"github.com/peak/s5cmd/v2/storage"
+	"github.com/peak/s5cmd/v2/storage/url"
+	"github.com/peak/s5cmd/v2/strutil"
+)
+
+const (
+	deleteScopeFlagName    = "delete-scope"
+	deleteExcludedFlagName = "delete-excluded"
+)
+
+// OutOfDeleteScopeMessage is the structure for the number of the objects only
+// in destination which are not deleted since they don't match --delete-scope.
+type OutOfDeleteScopeMessage struct {
+	Patterns []string `json:"patterns"`
+	Kept     int64    `json:"kept"`
+}
+
+// String is the string representation of OutOfDeleteScopeMessage.
+func (m OutOfDeleteScopeMessage) String() string {
+	return fmt.Sprintf("kept %d objects out of delete scope %v", m.Kept, m.Patterns)
+}
+
+// JSON is the JSON representation of OutOfDeleteScopeMessage.
+func (m OutOfDeleteScopeMessage) JSON() string {
+	return strutil.JSON(struct {
+		Operation string `json:"operation"`
+		OutOfDeleteScopeMessage
+	}{
+		Operation:               "delete-scope",
+		OutOfDeleteScopeMessage: m,
+	})
+}
+
+// deleteScope restricts the objects only in destination which are deleted to
+// the ones whose keys match one of its patterns, so that the syncs of
+// multiple sources into the same destination prefix only delete the objects
+// their source is authoritative for. The patterns are matched against the
+// keys relative to the destination given to the sync, in the same way as
+// --exclude patterns are matched against the source keys. It is shared by the
+// partitions of a sync, whose destinations are under the same prefix.
+type deleteScope struct {
+	patterns []string
+	regexps  []*regexp.Regexp
+	// prefix is the prefix of the destination of the sync.
+	prefix string
+	kept   int64
+}
+
+// newDeleteScope returns the delete scope of the patterns for the destination
+// dst, or nil if there are no patterns. The patterns are validated before.
+func newDeleteScope(patterns []string, dst string, raw bool) *deleteScope {
+	if len(patterns) == 0 {
+		return nil
+	}
+	regexps, _ := createExcludesFromWildcard(patterns)
+
+	var prefix string
+	if dsturl, err := url.New(dst, url.WithRaw(raw)); err == nil {
+		prefix = dsturl.Prefix
+	}
+	return &deleteScope{
+		patterns: patterns,
+		regexps:  regexps,
+		prefix:   prefix,
+	}
+}
+
+// filter returns the objects only in destination which are in the scope. It
+// returns onlyDest as is if d is nil.
+func (d *deleteScope) filter(onlyDest chan *storage.Object) chan *storage.Object {
+	if d == nil {
+		return onlyDest
+	}
+
+	filtered := make(chan *storage.Object)
+	go func() {
+		defer close(filtered)
+		for object := range onlyDest {
+			if !isURLExcluded(d.regexps, object.URL.Path, d.prefix) {
+				atomic.AddInt64(&d.kept, 1)
+				continue
+			}
+			filtered <- object
+		}
+	}()
+	return filtered
+}
+
+// report prints the number of the objects kept since they are out of the
+// scope. It is a no-op if d is nil.
+func (d *deleteScope) report() {
+	if d == nil {
+		return
+	}
+	log.Info(OutOfDeleteScopeMessage{
+		Patterns: d.patterns,
+		Kept:     atomic.LoadInt64(&d.kept),
+	})
+}
+
+func validateDeleteScope(c *cli.Context) error {
+	patterns := c.StringSlice(deleteScopeFlagName)
+	if len(patterns) == 0 {
+		return nil
+	}
+	if !c.Bool("delete") {
+		return fmt.Errorf("%q flag requires %q flag", deleteScopeFlagName, "delete")
+	}
+	if _, err := createExcludesFromWildcard(patterns); err != nil {
+		return fmt.Errorf("invalid %v pattern: %w", deleteScopeFlagName, err)
+	}
+	return nil
+}
+
+func validateDeleteExcluded(c *cli.Context) error {
+	if !c.Bool(deleteExcludedFlagName) {
+		return nil
+	}
+	if !c.Bool("delete") {
+		return fmt.Errorf("%q flag requires %q flag", deleteExcludedFlagName, "delete")
+	}
+	if !c.IsSet("exclude") && !c.IsSet(includeFlagName) {
+		return fmt.Errorf("%q flag requires %q or %q flag", deleteExcludedFlagName, "exclude", includeFlagName)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package command
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/peak/s5cmd/v2/storage"
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+func TestDeleteScopeFilter(t *testing.T) {
+	scope := newDeleteScope([]string{"a/*", "*.a.csv"}, "s3://bucket/merged/", false)
+
+	onlyDest := make(chan *storage.Object)
+	go func() {
+		defer close(onlyDest)
+		for _, key := range []string{
+			"s3://bucket/merged/a/1.txt",
+			"s3://bucket/merged/b/1.txt",
+			"s3://bucket/merged/x.a.csv",
+			"s3://bucket/merged/x.b.csv",
+			"s3://bucket/merged/b/y.a.csv",
+		} {
+			u, err := url.New(key)
+			assert.NilError(t, err)
+			onlyDest <- &storage.Object{URL: u}
+		}
+	}()
+
+	var deleted []string
+	for object := range scope.filter(onlyDest) {
+		deleted = append(deleted, object.URL.String())
+	}
+
+	assert.DeepEqual(t, []string{
+		"s3://bucket/merged/a/1.txt",
+		"s3://bucket/merged/x.a.csv",
+		"s3://bucket/merged/b/y.a.csv",
+	}, deleted)
+	assert.Equal(t, int64(2), scope.kept)
+}
+
+func TestDeleteScopeNil(t *testing.T) {
+	scope := newDeleteScope(nil, "s3://bucket/", false)
+	assert.Assert(t, scope == nil)
+
+	onlyDest := make(chan *storage.Object)
+	assert.Equal(t, onlyDest, scope.filter(onlyDest))
+	scope.report()
+}
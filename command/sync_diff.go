@@ -0,0 +1,235 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/lanrat/extsort"
+
+	"github.com/peak/s5cmd/v2/log"
+	"github.com/peak/s5cmd/v2/storage"
+	"github.com/peak/s5cmd/v2/storage/url"
+	"github.com/peak/s5cmd/v2/strutil"
+)
+
+// DiffChange is the kind of change a sync would make on a destination object.
+type DiffChange string
+
+const (
+	// DiffNew is an object that is only in source and would be copied.
+	DiffNew DiffChange = "+"
+	// DiffDelete is an object that is only in destination and would be
+	// deleted.
+	DiffDelete DiffChange = "-"
+	// DiffModify is an object that is in both source and destination and
+	// would be overwritten.
+	DiffModify DiffChange = "M"
+)
+
+// name returns the name of the change used in JSON output.
+func (c DiffChange) name() string {
+	switch c {
+	case DiffNew:
+		return "new"
+	case DiffDelete:
+		return "delete"
+	case DiffModify:
+		return "modify"
+	}
+	return "unknown"
+}
+
+// DiffMessage is the structure for a change planned by "sync --dry-run
+// --diff".
+type DiffMessage struct {
+	Change      DiffChange
+	Source      *url.URL
+	Destination *url.URL
+	Reason      string
+}
+
+// String returns the string representation of DiffMessage, similar to the
+// output of "diff -r".
+func (m DiffMessage) String() string {
+	return fmt.Sprintf("%v %v (%v)", m.Change, m.Destination, m.Reason)
+}
+
+// JSON returns the JSON representation of DiffMessage.
+func (m DiffMessage) JSON() string {
+	return strutil.JSON(struct {
+		Operation   string   `json:"operation"`
+		Change      string   `json:"change"`
+		Source      *url.URL `json:"source,omitempty"`
+		Destination *url.URL `json:"destination"`
+		Reason      string   `json:"reason"`
+	}{
+		Operation:   "diff",
+		Change:      m.Change.name(),
+		Source:      m.Source,
+		Destination: m.Destination,
+		Reason:      m.Reason,
+	})
+}
+
+// DiffSummaryMessage is the structure for the number of changes planned by
+// "sync --dry-run --diff".
+type DiffSummaryMessage struct {
+	New      int64 `json:"new"`
+	Deleted  int64 `json:"deleted"`
+	Modified int64 `json:"modified"`
+}
+
+// String returns the string representation of DiffSummaryMessage.
+func (m DiffSummaryMessage) String() string {
+	return fmt.Sprintf("%d new, %d deleted, %d modified", m.New, m.Deleted, m.Modified)
+}
+
+// JSON returns the JSON representation of DiffSummaryMessage.
+func (m DiffSummaryMessage) JSON() string {
+	return strutil.JSON(struct {
+		Operation string `json:"operation"`
+		DiffSummaryMessage
+	}{
+		Operation:          "diff_summary",
+		DiffSummaryMessage: m,
+	})
+}
+
+// ToBytes encodes the change, so that the changes are sorted externally. See
+// diffPlan.
+func (m DiffMessage) ToBytes() []byte {
+	var source []byte
+	if m.Source != nil {
+		source = m.Source.ToBytes()
+	}
+
+	buf := bytes.NewBuffer(make([]byte, 0, 200))
+	enc := gob.NewEncoder(buf)
+	enc.Encode(string(m.Change))
+	enc.Encode(source)
+	enc.Encode(m.Destination.ToBytes())
+	enc.Encode(m.Reason)
+
+	return buf.Bytes()
+}
+
+// diffMessageFromBytes decodes a change encoded with DiffMessage.ToBytes.
+func diffMessageFromBytes(data []byte) extsort.SortType {
+	dec := gob.NewDecoder(bytes.NewBuffer(data))
+
+	var (
+		m                   DiffMessage
+		change              string
+		source, destination []byte
+	)
+	dec.Decode(&change)
+	dec.Decode(&source)
+	dec.Decode(&destination)
+	dec.Decode(&m.Reason)
+
+	m.Change = DiffChange(change)
+	if len(source) > 0 {
+		m.Source = url.FromBytes(source).(*url.URL)
+	}
+	m.Destination = url.FromBytes(destination).(*url.URL)
+	return m
+}
+
+// diffMessageLess orders the changes by their destinations.
+func diffMessageLess(a, b extsort.SortType) bool {
+	return a.(DiffMessage).Destination.String() < b.(DiffMessage).Destination.String()
+}
+
+// diffPlan collects the changes planned by sync, so that they are printed
+// sorted by destination like "diff -r" instead of in the order the planning
+// goroutines produce them. The changes are sorted externally like the compared
+// objects, not to keep all of them in memory.
+type diffPlan struct {
+	changes chan extsort.SortType
+	sorted  chan extsort.SortType
+	errs    chan error
+}
+
+func newDiffPlan(ctx context.Context, config *extsort.Config) *diffPlan {
+	changes := make(chan extsort.SortType, extsortChannelBufferSize)
+	sorter, sorted, errs := extsort.New(changes, diffMessageFromBytes, diffMessageLess, config)
+	go sorter.Sort(ctx)
+
+	return &diffPlan{
+		changes: changes,
+		sorted:  sorted,
+		errs:    errs,
+	}
+}
+
+func (p *diffPlan) add(change DiffChange, srcurl, dsturl *url.URL, reason string) {
+	p.changes <- DiffMessage{
+		Change:      change,
+		Source:      srcurl,
+		Destination: dsturl,
+		Reason:      reason,
+	}
+}
+
+// print prints the changes followed by the number of changes of each kind. It
+// must be called once all changes are added.
+func (p *diffPlan) print() error {
+	close(p.changes)
+
+	var summary DiffSummaryMessage
+	for sorted := range p.sorted {
+		change := sorted.(DiffMessage)
+		switch change.Change {
+		case DiffNew:
+			summary.New++
+		case DiffDelete:
+			summary.Deleted++
+		case DiffModify:
+			summary.Modified++
+		}
+		log.Info(change)
+	}
+
+	var merr error
+	for err := range p.errs {
+		merr = multierror.Append(merr, err)
+	}
+	if merr != nil {
+		return merr
+	}
+
+	log.Info(summary)
+	return nil
+}
+
+// diffReason describes how the source object differs from the destination
+// object.
+func diffReason(srcObj, dstObj *storage.Object) string {
+	var reasons []string
+	if srcObj.Size != dstObj.Size {
+		reasons = append(reasons, fmt.Sprintf("size %d -> %d", dstObj.Size, srcObj.Size))
+	}
+
+	if srcObj.ModTime != nil && dstObj.ModTime != nil {
+		switch srcMod, dstMod := *srcObj.ModTime, *dstObj.ModTime; {
+		case srcMod.After(dstMod):
+			reasons = append(reasons, fmt.Sprintf("source is newer by %v", srcMod.Sub(dstMod).Round(time.Second)))
+		case srcMod.Before(dstMod):
+			reasons = append(reasons, fmt.Sprintf("destination is newer by %v", dstMod.Sub(srcMod).Round(time.Second)))
+		}
+	}
+
+	if !metadataMatches(srcObj, dstObj) {
+		reasons = append(reasons, "metadata differs")
+	}
+
+	if len(reasons) == 0 {
+		return "differs"
+	}
+	return strings.Join(reasons, ", ")
+}
@@ -0,0 +1,56 @@
+package command
+
+import (
+	"testing"
+	"time"
+
+	"github.com/peak/s5cmd/v2/storage"
+)
+
+func TestDiffReason(t *testing.T) {
+	ft := time.Now()
+	timePtr := func(tt time.Time) *time.Time {
+		return &tt
+	}
+
+	testcases := []struct {
+		name     string
+		src      *storage.Object
+		dst      *storage.Object
+		expected string
+	}{
+		{
+			name:     "source is newer, sizes are different",
+			src:      &storage.Object{ModTime: timePtr(ft.Add(time.Minute)), Size: 10},
+			dst:      &storage.Object{ModTime: timePtr(ft), Size: 5},
+			expected: "size 5 -> 10, source is newer by 1m0s",
+		},
+		{
+			name:     "destination is newer, sizes are same",
+			src:      &storage.Object{ModTime: timePtr(ft), Size: 10},
+			dst:      &storage.Object{ModTime: timePtr(ft.Add(time.Hour)), Size: 10},
+			expected: "destination is newer by 1h0m0s",
+		},
+		{
+			name:     "same age, sizes are different",
+			src:      &storage.Object{ModTime: timePtr(ft), Size: 10},
+			dst:      &storage.Object{ModTime: timePtr(ft), Size: 5},
+			expected: "size 5 -> 10",
+		},
+		{
+			name:     "same age, sizes are same",
+			src:      &storage.Object{ModTime: timePtr(ft), Size: 10},
+			dst:      &storage.Object{ModTime: timePtr(ft), Size: 10},
+			expected: "differs",
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			if got := diffReason(tc.src, tc.dst); got != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
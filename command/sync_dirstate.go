@@ -0,0 +1,53 @@
+package command
+
+import (
+	"fmt"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/peak/s5cmd/v2/storage"
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+const (
+	dirMtimeShortcutFlagName = "dir-mtime-shortcut"
+	noShortcutFlagName       = "no-shortcut"
+)
+
+// openDirState opens the state of the directories of the local source which
+// is kept in the file given with --dir-mtime-shortcut. It returns nil if the
+// flag is not given.
+func (s Sync) openDirState() (*storage.DirState, error) {
+	if s.dirStatePath == "" {
+		return nil, nil
+	}
+	state, err := storage.OpenDirState(s.dirStatePath, s.noShortcut)
+	if err != nil {
+		return nil, fmt.Errorf("could not read directory state: %w", err)
+	}
+	return state, nil
+}
+
+// saveDirState saves the state of the directories walked in this run for the
+// next one. The state is not saved with --dry-run.
+func (s Sync) saveDirState(state *storage.DirState) {
+	if state == nil || s.storageOpts.DryRun {
+		return
+	}
+	if err := state.Save(); err != nil {
+		printError(s.fullCommand, s.op, fmt.Errorf("could not save directory state: %w", err))
+	}
+}
+
+func validateDirMtimeShortcut(c *cli.Context) error {
+	if c.Bool(noShortcutFlagName) && c.String(dirMtimeShortcutFlagName) == "" {
+		return fmt.Errorf("%q flag requires %q flag", noShortcutFlagName, dirMtimeShortcutFlagName)
+	}
+	if c.String(dirMtimeShortcutFlagName) == "" || c.Args().Len() != 2 {
+		return nil
+	}
+	if srcurl, err := url.New(c.Args().Get(0)); err == nil && (srcurl.IsRemote() || srcurl.IsWildcard()) {
+		return fmt.Errorf("%q flag can only be used with a local source directory", dirMtimeShortcutFlagName)
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/peak/s5cmd/v2/storage"
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+// EncryptionStrategy syncs the objects which Strategy skips, if their
+// destination objects are not encrypted with Method and KeyID. The
+// encryption of the objects is not listed, so the destination objects are
+// requested with Stat one by one.
+type EncryptionStrategy struct {
+	Strategy SyncStrategy
+	Method   string
+	KeyID    string
+	Stat     func(*url.URL) (*storage.Object, error)
+}
+
+func (es *EncryptionStrategy) ShouldSync(srcObj, dstObj *storage.Object) *SkipReason {
+	reason := es.Strategy.ShouldSync(srcObj, dstObj)
+	if reason == nil {
+		return nil
+	}
+
+	// the object is synced if its encryption can't be determined, the copy
+	// fails with the same error if the object is not accessible.
+	obj, err := es.Stat(dstObj.URL)
+	if err != nil {
+		return nil
+	}
+
+	if !encryptionMatches(obj, es.Method, es.KeyID) {
+		return nil
+	}
+	return reason
+}
+
+// encryptionMatches reports whether the object is encrypted with the given
+// method and key. Any key matches if the key is not given.
+func encryptionMatches(obj *storage.Object, method, keyID string) bool {
+	if obj.EncryptionMethod != method {
+		return false
+	}
+	if keyID == "" || obj.EncryptionKeyID == keyID {
+		return true
+	}
+	// the keys of the objects are returned as ARNs, e.g.
+	// "arn:aws:kms:us-east-1:111122223333:key/<key id>", whereas they can
+	// be given as key ids.
+	return strings.HasSuffix(obj.EncryptionKeyID, "/"+keyID)
+}
+
+// isKMSEncryption reports whether the server side encryption method uses KMS
+// keys, e.g. "aws:kms" or "aws:kms:dsse". The ETags of the objects encrypted
+// with KMS keys are not the MD5 checksums of their contents.
+func isKMSEncryption(method string) bool {
+	return strings.HasPrefix(method, "aws:kms")
+}
+
+// hasOpaqueDestinationEtags reports whether the ETags of the objects copied to
+// dsturl are not the MD5 checksums of their contents. The objects are
+// encrypted with the method given with --sse, or with the default encryption
+// of the destination bucket if --sse is not given.
+func (s Sync) hasOpaqueDestinationEtags(ctx context.Context, dsturl *url.URL) bool {
+	// only the checksum and etag strategies compare ETags, the default
+	// encryption of the bucket is not requested for the others.
+	if !dsturl.IsRemote() || (s.syncStrategy != syncStrategyChecksum && s.syncStrategy != syncStrategyEtag) {
+		return false
+	}
+
+	if s.encryptionMethod != "" {
+		return isKMSEncryption(s.encryptionMethod)
+	}
+
+	client, err := storage.NewRemoteClient(ctx, dsturl, s.storageOpts)
+	if err != nil {
+		printDebug(s.op, err, dsturl)
+		return false
+	}
+
+	encryption, err := client.GetBucketEncryption(ctx, dsturl.Bucket)
+	if err != nil {
+		// the ETags are compared as before if the default encryption of the
+		// bucket is not readable.
+		printDebug(s.op, fmt.Errorf("default encryption of the bucket: %w", err), dsturl)
+		return false
+	}
+	return isKMSEncryption(encryption.Algorithm)
+}
+
+// newEncryptionStrategy wraps the strategy to sync the objects which are not
+// encrypted as given with --sse and --sse-kms-key-id.
+func (s Sync) newEncryptionStrategy(ctx context.Context, dsturl *url.URL, strategy SyncStrategy) (SyncStrategy, error) {
+	client, err := storage.NewRemoteClient(ctx, dsturl, s.storageOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &EncryptionStrategy{
+		Strategy: strategy,
+		Method:   s.encryptionMethod,
+		KeyID:    s.encryptionKeyID,
+		Stat: func(u *url.URL) (*storage.Object, error) {
+			return client.Stat(ctx, u)
+		},
+	}, nil
+}
+
+func validateMatchEncryption(c *cli.Context) error {
+	if !c.Bool("match-encryption") {
+		return nil
+	}
+	if c.String("sse") == "" {
+		return fmt.Errorf("%q flag requires %q flag", "match-encryption", "sse")
+	}
+	if c.Args().Len() == 2 {
+		if u, err := url.New(c.Args().Get(1)); err == nil && !u.IsRemote() {
+			return fmt.Errorf("%q flag can only be used with remote destination", "match-encryption")
+		}
+	}
+	// the keys of the objects are returned as ARNs, which the aliases of the
+	// keys can't be matched with.
+	if keyID := c.String("sse-kms-key-id"); strings.HasPrefix(keyID, "alias/") || strings.Contains(keyID, ":alias/") {
+		return fmt.Errorf("%q flag requires the id or the ARN of the key, not an alias", "match-encryption")
+	}
+	return nil
+}
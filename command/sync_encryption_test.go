@@ -0,0 +1,121 @@
+package command
+
+import (
+	"errors"
+	"testing"
+
+	errorpkg "github.com/peak/s5cmd/v2/error"
+	"github.com/peak/s5cmd/v2/storage"
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+func TestEncryptionStrategy_ShouldSync(t *testing.T) {
+	const keyARN = "arn:aws:kms:us-east-1:111122223333:key/1234abcd-12ab-34cd-56ef-1234567890ab"
+
+	dsturl, err := url.New("s3://bucket/key")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := []struct {
+		name     string
+		method   string
+		keyID    string
+		etag     string
+		stat     *storage.Object
+		statErr  error
+		expected error
+	}{
+		{
+			name:     "etags are different",
+			method:   "aws:kms",
+			etag:     "def",
+			expected: nil,
+		},
+		{
+			name:     "method matches",
+			method:   "aws:kms",
+			etag:     "abc",
+			stat:     &storage.Object{EncryptionMethod: "aws:kms", EncryptionKeyID: keyARN},
+			expected: errorpkg.ErrObjectEtagsMatch,
+		},
+		{
+			name:     "method doesn't match",
+			method:   "aws:kms",
+			etag:     "abc",
+			stat:     &storage.Object{EncryptionMethod: "AES256"},
+			expected: nil,
+		},
+		{
+			name:     "key id matches the key arn",
+			method:   "aws:kms",
+			keyID:    "1234abcd-12ab-34cd-56ef-1234567890ab",
+			etag:     "abc",
+			stat:     &storage.Object{EncryptionMethod: "aws:kms", EncryptionKeyID: keyARN},
+			expected: errorpkg.ErrObjectEtagsMatch,
+		},
+		{
+			name:     "key arn matches",
+			method:   "aws:kms",
+			keyID:    keyARN,
+			etag:     "abc",
+			stat:     &storage.Object{EncryptionMethod: "aws:kms", EncryptionKeyID: keyARN},
+			expected: errorpkg.ErrObjectEtagsMatch,
+		},
+		{
+			name:     "key doesn't match",
+			method:   "aws:kms",
+			keyID:    "0000abcd-12ab-34cd-56ef-1234567890ab",
+			etag:     "abc",
+			stat:     &storage.Object{EncryptionMethod: "aws:kms", EncryptionKeyID: keyARN},
+			expected: nil,
+		},
+		{
+			name:     "encryption can't be determined",
+			method:   "aws:kms",
+			etag:     "abc",
+			statErr:  errors.New("access denied"),
+			expected: nil,
+		},
+	}
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			strategy := &EncryptionStrategy{
+				Strategy: &EtagStrategy{},
+				Method:   tc.method,
+				KeyID:    tc.keyID,
+				Stat: func(u *url.URL) (*storage.Object, error) {
+					if u != dsturl {
+						t.Errorf("expected stat of %v, got %v", dsturl, u)
+					}
+					return tc.stat, tc.statErr
+				},
+			}
+
+			src := &storage.Object{Etag: "abc", Size: 10}
+			dst := &storage.Object{URL: dsturl, Etag: tc.etag, Size: 10}
+
+			var got error
+			if reason := strategy.ShouldSync(src, dst); reason != nil {
+				got = reason.Err()
+			}
+			if got != tc.expected {
+				t.Fatalf("expected: %q(%T), got: %q(%T)", tc.expected, tc.expected, got, got)
+			}
+		})
+	}
+}
+
+func TestIsKMSEncryption(t *testing.T) {
+	for method, expected := range map[string]bool{
+		"aws:kms":      true,
+		"aws:kms:dsse": true,
+		"AES256":       false,
+		"":             false,
+	} {
+		if got := isKMSEncryption(method); got != expected {
+			t.Errorf("%q: expected %v, got %v", method, expected, got)
+		}
+	}
+}
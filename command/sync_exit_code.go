@@ -0,0 +1,63 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/urfave/cli/v2"
+
+	errorpkg "github.com/peak/s5cmd/v2/error"
+)
+
+const (
+	exitCodeFlagName = "exit-code"
+
+	// syncExitPlanned is the exit code of sync with --exit-code flag if it
+	// planned changes without any failures.
+	syncExitPlanned = 2
+)
+
+// errSyncPlanned is returned by sync with --exit-code flag if it planned
+// changes. It is not printed.
+var errSyncPlanned = &errorpkg.ExitError{
+	Code: syncExitPlanned,
+	Err:  errors.New("changes planned"),
+}
+
+// exitCodeResult returns the error of the sync which completed with err: err
+// itself if it failed, errSyncPlanned if it planned to copy or delete any
+// object with --exit-code, and nil otherwise.
+func (s Sync) exitCodeResult(err error) error {
+	if !s.exitCode || err != nil {
+		return err
+	}
+	if s.planSummary.changed() {
+		return errSyncPlanned
+	}
+	return nil
+}
+
+// inRunMode reports whether the command of c is run by the run command.
+func inRunMode(c *cli.Context) bool {
+	for _, parent := range c.Lineage()[1:] {
+		if parent.Command != nil && parent.Command.Name == "run" {
+			return true
+		}
+	}
+	return false
+}
+
+func validateExitCode(c *cli.Context) error {
+	if !c.Bool(exitCodeFlagName) {
+		return nil
+	}
+	if c.Bool(exitCodeOnChangeFlagName) {
+		return fmt.Errorf("it is not allowed to combine %q and %q flags", exitCodeFlagName, exitCodeOnChangeFlagName)
+	}
+	// the exit codes of the commands in run-mode are not the exit code of
+	// s5cmd.
+	if inRunMode(c) {
+		return fmt.Errorf("%q flag is not permitted in run-mode", exitCodeFlagName)
+	}
+	return nil
+}
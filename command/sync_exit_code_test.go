@@ -0,0 +1,49 @@
+package command
+
+import (
+	"errors"
+	"testing"
+
+	errorpkg "github.com/peak/s5cmd/v2/error"
+)
+
+func TestSyncExitCodeResult(t *testing.T) {
+	errFailed := errors.New("failed")
+
+	testcases := []struct {
+		name         string
+		exitCode     bool
+		uploads      int
+		deletes      int
+		err          error
+		expectedCode int
+	}{
+		{name: "without flag", uploads: 1, expectedCode: 0},
+		{name: "nothing planned", exitCode: true, expectedCode: 0},
+		{name: "copy planned", exitCode: true, uploads: 1, expectedCode: syncExitPlanned},
+		{name: "delete planned", exitCode: true, deletes: 1, expectedCode: syncExitPlanned},
+		{name: "failed", exitCode: true, uploads: 1, err: errFailed, expectedCode: 1},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			s := Sync{
+				exitCode:    tc.exitCode,
+				planSummary: newSyncPlanSummary(false, tc.exitCode),
+			}
+			for i := 0; i < tc.uploads; i++ {
+				s.planSummary.addUpload(1)
+			}
+			s.planSummary.addDelete(tc.deletes)
+
+			err := s.exitCodeResult(tc.err)
+			if code := errorpkg.ExitCode(err); code != tc.expectedCode {
+				t.Errorf("expected exit code %v, got %v (%v)", tc.expectedCode, code, err)
+			}
+			if tc.err != nil && err != tc.err {
+				t.Errorf("expected error %v, got %v", tc.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,231 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/lanrat/extsort"
+	"github.com/urfave/cli/v2"
+
+	"github.com/peak/s5cmd/v2/log"
+	"github.com/peak/s5cmd/v2/storage"
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+const (
+	assumeFSPrecisionFlagName     = "assume-fs-precision"
+	assumeCaseInsensitiveFlagName = "assume-case-insensitive"
+
+	fsProbePattern = ".s5cmd-probe-*.tmp"
+)
+
+// fsProbeTime is the modification time set to the probe file. Its seconds are
+// odd and its nanoseconds are not a multiple of a microsecond, so that the
+// time stored by the filesystem tells its precision.
+var fsProbeTime = time.Unix(1600000001, 987654321)
+
+// fsPrecisions are the granularities of the modification times of the common
+// filesystems, e.g. 2s for FAT, 10ms for exFAT and 100ns for NTFS, from the
+// coarsest to the finest.
+var fsPrecisions = []time.Duration{
+	2 * time.Second,
+	time.Second,
+	10 * time.Millisecond,
+	time.Millisecond,
+	time.Microsecond,
+	100 * time.Nanosecond,
+}
+
+func newFilesystemFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.DurationFlag{
+			Name:  assumeFSPrecisionFlagName,
+			Usage: "assume the local destination stores modification times in the given precision, e.g. 2s for FAT, instead of probing it",
+		},
+		&cli.BoolFlag{
+			Name:  assumeCaseInsensitiveFlagName,
+			Usage: "assume the local destination is case-insensitive, or case-sensitive if it is false, instead of probing it",
+		},
+	}
+}
+
+// fsTraits are the limitations of a local destination filesystem which the
+// comparison of the objects adapts to.
+type fsTraits struct {
+	// precision is the granularity of the modification times, or 0 if they
+	// are stored as they are set.
+	precision time.Duration
+	// caseInsensitive is set if the names which only differ in case are the
+	// same file.
+	caseInsensitive bool
+
+	// precisionKnown and caseKnown are set once the traits are given with the
+	// flags or probed, so that the partitions of a sync don't probe them
+	// again.
+	precisionKnown bool
+	caseKnown      bool
+}
+
+// newFSTraits returns the traits of the destination given with the flags.
+func newFSTraits(c *cli.Context) fsTraits {
+	return fsTraits{
+		precision:       c.Duration(assumeFSPrecisionFlagName),
+		caseInsensitive: c.Bool(assumeCaseInsensitiveFlagName),
+		precisionKnown:  c.IsSet(assumeFSPrecisionFlagName),
+		caseKnown:       c.IsSet(assumeCaseInsensitiveFlagName),
+	}
+}
+
+// probeDestination probes the traits of the local destination which are not
+// given with the flags, by writing temporary files to it, and prints the
+// limitations it detects. Nothing is probed if the destination doesn't exist
+// yet, since there is nothing in it to compare.
+func (s Sync) probeDestination(dsturl *url.URL) fsTraits {
+	traits := s.destFS
+	if dsturl.IsRemote() || (traits.precisionKnown && traits.caseKnown) {
+		return traits
+	}
+	probePrecision, probeCase := !traits.precisionKnown, !traits.caseKnown
+	traits.precisionKnown, traits.caseKnown = true, true
+
+	dir := dsturl.Absolute()
+	fi, err := os.Stat(dir)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			printDebug(s.op, fmt.Errorf("probe destination filesystem: %w", err), dsturl)
+		}
+		return traits
+	}
+	if !fi.IsDir() {
+		dir = filepath.Dir(dir)
+	}
+
+	if probePrecision {
+		precision, err := probeFSPrecision(dir)
+		if err != nil {
+			printDebug(s.op, fmt.Errorf("probe modification time precision: %w", err), dsturl)
+		} else if precision > 0 {
+			traits.precision = precision
+			log.Warning(log.WarningMessage{
+				Operation: s.op,
+				Warning: fmt.Sprintf("%v stores modification times in %v precision, the differences up to %v are ignored",
+					dsturl, precision, precision),
+			})
+		}
+	}
+
+	if probeCase {
+		caseInsensitive, err := probeCaseInsensitive(dir)
+		if err != nil {
+			printDebug(s.op, fmt.Errorf("probe case sensitivity: %w", err), dsturl)
+		} else if caseInsensitive {
+			traits.caseInsensitive = true
+			log.Warning(log.WarningMessage{
+				Operation: s.op,
+				Warning:   fmt.Sprintf("%v is case-insensitive, the keys which only differ in case are matched", dsturl),
+			})
+		}
+	}
+	return traits
+}
+
+// probeFSPrecision sets the modification time of a temporary file in dir and
+// returns the precision the filesystem stores it in, or 0 if it is stored as
+// it is set.
+func probeFSPrecision(dir string) (time.Duration, error) {
+	f, err := os.CreateTemp(dir, fsProbePattern)
+	if err != nil {
+		return 0, err
+	}
+	name := f.Name()
+	defer os.Remove(name)
+	if err := f.Close(); err != nil {
+		return 0, err
+	}
+
+	if err := os.Chtimes(name, fsProbeTime, fsProbeTime); err != nil {
+		return 0, err
+	}
+	fi, err := os.Stat(name)
+	if err != nil {
+		return 0, err
+	}
+	return fsPrecision(fi.ModTime()), nil
+}
+
+// fsPrecision returns the precision of the modification time which is stored
+// for fsProbeTime, i.e. the coarsest granularity it is a multiple of.
+func fsPrecision(stored time.Time) time.Duration {
+	if stored.Equal(fsProbeTime) {
+		return 0
+	}
+	nanos := stored.UnixNano()
+	for _, precision := range fsPrecisions {
+		if nanos%int64(precision) == 0 {
+			return precision
+		}
+	}
+	// the time is stored in a precision which is none of the known ones,
+	// its difference from the time which is set is the least to tolerate.
+	return fsProbeTime.Sub(stored).Abs()
+}
+
+// probeCaseInsensitive creates a temporary file in dir and reports whether
+// the upper case variant of its name is the same file.
+func probeCaseInsensitive(dir string) (bool, error) {
+	f, err := os.CreateTemp(dir, fsProbePattern)
+	if err != nil {
+		return false, err
+	}
+	name := f.Name()
+	defer os.Remove(name)
+	if err := f.Close(); err != nil {
+		return false, err
+	}
+
+	variant := filepath.Join(dir, strings.ToUpper(filepath.Base(name)))
+	v, err := os.OpenFile(variant, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0o600)
+	if errors.Is(err, os.ErrExist) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	v.Close()
+	return false, os.Remove(variant)
+}
+
+// foldKey returns the key which the keys differing only in case share on a
+// case-insensitive destination.
+func foldKey(key string) string {
+	return strings.ToLower(key)
+}
+
+// lessFold is storage.Less for the objects matched with the ones of a
+// case-insensitive destination.
+func lessFold(a, b extsort.SortType) bool {
+	return foldKey(a.(storage.Object).URL.Relative()) < foldKey(b.(storage.Object).URL.Relative())
+}
+
+func validateFilesystemFlags(c *cli.Context) error {
+	if c.Duration(assumeFSPrecisionFlagName) < 0 {
+		return fmt.Errorf("%q flag cannot be negative", assumeFSPrecisionFlagName)
+	}
+	if c.Args().Len() != 2 {
+		return nil
+	}
+	dsturl, err := url.New(c.Args().Get(1))
+	if err != nil || !dsturl.IsRemote() {
+		return nil
+	}
+	for _, flag := range []string{assumeFSPrecisionFlagName, assumeCaseInsensitiveFlagName} {
+		if c.IsSet(flag) {
+			return fmt.Errorf("%q flag requires local destination", flag)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package command
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/peak/s5cmd/v2/storage"
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+func TestFSPrecision(t *testing.T) {
+	testcases := []struct {
+		name     string
+		stored   time.Time
+		expected time.Duration
+	}{
+		{
+			name:     "stored as it is set",
+			stored:   fsProbeTime,
+			expected: 0,
+		},
+		{
+			name:     "FAT rounds up to even seconds",
+			stored:   time.Unix(1600000002, 0),
+			expected: 2 * time.Second,
+		},
+		{
+			name:     "seconds",
+			stored:   time.Unix(1600000001, 0),
+			expected: time.Second,
+		},
+		{
+			name:     "exFAT",
+			stored:   time.Unix(1600000001, 980000000),
+			expected: 10 * time.Millisecond,
+		},
+		{
+			name:     "milliseconds",
+			stored:   time.Unix(1600000001, 987000000),
+			expected: time.Millisecond,
+		},
+		{
+			name:     "NTFS",
+			stored:   time.Unix(1600000001, 987654300),
+			expected: 100 * time.Nanosecond,
+		},
+		{
+			name:     "unknown precision",
+			stored:   time.Unix(1600000001, 987654320),
+			expected: time.Nanosecond,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			if got := fsPrecision(tc.stored); got != tc.expected {
+				t.Errorf("expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestProbeFilesystemLeavesNoFiles(t *testing.T) {
+	dir := t.TempDir()
+
+	if _, err := probeFSPrecision(dir); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := probeCaseInsensitive(dir); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("expected no probe files to be left, got %d files", len(entries))
+	}
+}
+
+func TestCompareObjectsFoldCase(t *testing.T) {
+	base, err := url.New("s3://bucket/*")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	newObjects := func(keys ...string) chan *storage.Object {
+		ch := make(chan *storage.Object, len(keys))
+		for _, key := range keys {
+			u, err := url.New("s3://bucket/" + key)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			u.SetRelative(base)
+			ch <- &storage.Object{URL: u}
+		}
+		close(ch)
+		return ch
+	}
+
+	onlySource, onlyDest, common := compareObjects(
+		newObjects("Docs/Guide.txt", "new.txt", "README.md"),
+		newObjects("docs/guide.txt", "obsolete.txt", "readme.md"),
+		true,
+	)
+
+	var pairs, sources, dests []string
+	for pair := range common {
+		pairs = append(pairs, pair.src.URL.Relative()+" "+pair.dst.URL.Relative())
+	}
+	for object := range onlySource {
+		sources = append(sources, object.URL.Relative())
+	}
+	for object := range onlyDest {
+		dests = append(dests, object.URL.Relative())
+	}
+
+	assertStrings := func(name string, got, expected []string) {
+		t.Helper()
+		if len(got) != len(expected) {
+			t.Fatalf("%v: expected %q, got %q", name, expected, got)
+		}
+		for i := range got {
+			if got[i] != expected[i] {
+				t.Errorf("%v: expected %q, got %q", name, expected, got)
+			}
+		}
+	}
+	assertStrings("common", pairs, []string{"Docs/Guide.txt docs/guide.txt", "README.md readme.md"})
+	assertStrings("only source", sources, []string{"new.txt"})
+	assertStrings("only destination", dests, []string{"obsolete.txt"})
+}
@@ -0,0 +1,276 @@
+package command
+
+import (
+	"container/heap"
+	"path/filepath"
+	"sync"
+
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+// the streams of the objects which are planned to be copied. The objects of
+// each stream are planned in ascending order of their keys.
+const (
+	streamOnlySource = iota
+	streamCommon
+)
+
+// deleteInterleaver holds the objects only in destination until the copies of
+// the objects before them are completed, and releases them in batches. So the
+// destination neither keeps all of the deleted objects until the end of the
+// sync, nor loses them before the objects replacing them are copied.
+//
+// The watermark is the smallest key of the objects whose copies are not
+// completed yet, either because they are not planned yet or they are being
+// copied. The objects only in destination below the watermark are released.
+//
+// All methods are no-op if the receiver is nil.
+type deleteInterleaver struct {
+	batchSize int
+
+	mu   sync.Mutex
+	cond *sync.Cond
+
+	// frontiers are the keys of the last objects seen by the copy streams
+	// which are not finished yet.
+	frontiers map[int]string
+	// pending is the number of the planned copies of each key which are not
+	// completed yet, and pendingKeys is the heap of their keys. The keys of
+	// the completed copies are removed from the heap lazily.
+	pending     map[string]int
+	pendingKeys keyHeap
+	// lines are the keys of the copy commands by their line numbers.
+	lines map[int64]string
+
+	deletes     []*url.URL
+	deleteKeys  []string
+	deletesDone bool
+	stopped     bool
+}
+
+func newDeleteInterleaver(batchSize int) *deleteInterleaver {
+	d := &deleteInterleaver{
+		batchSize: batchSize,
+		frontiers: map[int]string{
+			streamOnlySource: "",
+			streamCommon:     "",
+		},
+		pending: map[string]int{},
+		lines:   map[int64]string{},
+	}
+	d.cond = sync.NewCond(&d.mu)
+	return d
+}
+
+// syncKey returns the key which the objects of source and destination are
+// matched and ordered by. See compareObjects.
+func syncKey(u *url.URL) string {
+	return filepath.ToSlash(u.Relative())
+}
+
+// advance marks the objects of the stream before key as planned.
+func (d *deleteInterleaver) advance(stream int, key string) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.frontiers[stream] = key
+	d.cond.Broadcast()
+}
+
+// finish marks all objects of the stream as planned.
+func (d *deleteInterleaver) finish(stream int) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	delete(d.frontiers, stream)
+	d.cond.Broadcast()
+}
+
+// plan marks the copy of key as planned but not completed.
+func (d *deleteInterleaver) plan(key string) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.pending[key]++
+	if d.pending[key] == 1 {
+		heap.Push(&d.pendingKeys, key)
+	}
+}
+
+// written records the line number which the copy command of key is written
+// with.
+func (d *deleteInterleaver) written(lineno int64, key string) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.lines[lineno] = key
+}
+
+// complete marks the command of the line as completed. It is called for every
+// line, including the ones which are not copy commands.
+func (d *deleteInterleaver) complete(lineno int64) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	key, ok := d.lines[lineno]
+	if !ok {
+		return
+	}
+	delete(d.lines, lineno)
+
+	d.pending[key]--
+	if d.pending[key] <= 0 {
+		delete(d.pending, key)
+	}
+	d.cond.Broadcast()
+}
+
+// addDelete adds an object only in destination. The objects must be added in
+// ascending order of their keys.
+func (d *deleteInterleaver) addDelete(dsturl *url.URL) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.deletes = append(d.deletes, dsturl)
+	d.deleteKeys = append(d.deleteKeys, syncKey(dsturl))
+	d.cond.Broadcast()
+}
+
+// closeDeletes marks all objects only in destination as added.
+func (d *deleteInterleaver) closeDeletes() {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.deletesDone = true
+	d.cond.Broadcast()
+}
+
+// stop drops the objects which are not released yet, e.g. if the copies are
+// not going to be executed.
+func (d *deleteInterleaver) stop() {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.stopped = true
+	d.cond.Broadcast()
+}
+
+// run calls release with each batch of the objects only in destination as
+// soon as it is released, until all of them are released or the interleaver
+// is stopped.
+func (d *deleteInterleaver) run(release func([]*url.URL)) {
+	if d == nil {
+		return
+	}
+	for {
+		batch := d.next()
+		if batch == nil {
+			return
+		}
+		release(batch)
+	}
+}
+
+// next waits for the next batch of the objects to release. It returns nil if
+// there are no more objects to release.
+func (d *deleteInterleaver) next() []*url.URL {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for !d.stopped && !(d.deletesDone && len(d.deletes) == 0) {
+		if batch := d.take(); batch != nil {
+			return batch
+		}
+		d.cond.Wait()
+	}
+	return nil
+}
+
+// take removes and returns the next batch of the objects below the
+// watermark, or nil if there is no batch to release yet. A partial batch is
+// released only if no more objects are coming. It must be called with the
+// lock held.
+func (d *deleteInterleaver) take() []*url.URL {
+	watermark, bounded := d.watermark()
+	n := len(d.deletes)
+	if bounded {
+		n = 0
+		for n < len(d.deletes) && d.deleteKeys[n] < watermark {
+			n++
+		}
+	}
+
+	if n < d.batchSize && (n == 0 || n < len(d.deletes) || !d.deletesDone) {
+		return nil
+	}
+	if n > d.batchSize {
+		n = d.batchSize
+	}
+
+	batch := d.deletes[:n:n]
+	d.deletes = d.deletes[n:]
+	d.deleteKeys = d.deleteKeys[n:]
+	return batch
+}
+
+// watermark returns the smallest key of the copies which are not completed
+// yet. It reports false if all copies are completed. It must be called with
+// the lock held.
+func (d *deleteInterleaver) watermark() (string, bool) {
+	for d.pendingKeys.Len() > 0 && d.pending[d.pendingKeys[0]] == 0 {
+		heap.Pop(&d.pendingKeys)
+	}
+
+	var (
+		watermark string
+		bounded   bool
+	)
+	if d.pendingKeys.Len() > 0 {
+		watermark, bounded = d.pendingKeys[0], true
+	}
+	for _, key := range d.frontiers {
+		if !bounded || key < watermark {
+			watermark, bounded = key, true
+		}
+	}
+	return watermark, bounded
+}
+
+// keyHeap is a min-heap of keys.
+type keyHeap []string
+
+func (h keyHeap) Len() int            { return len(h) }
+func (h keyHeap) Less(i, j int) bool  { return h[i] < h[j] }
+func (h keyHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *keyHeap) Push(x interface{}) { *h = append(*h, x.(string)) }
+
+func (h *keyHeap) Pop() interface{} {
+	old := *h
+	key := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return key
+}
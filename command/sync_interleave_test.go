@@ -0,0 +1,104 @@
+package command
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+func TestDeleteInterleaver(t *testing.T) {
+	d := newDeleteInterleaver(2)
+
+	keys := func(batch []*url.URL) []string {
+		var keys []string
+		for _, u := range batch {
+			keys = append(keys, syncKey(u))
+		}
+		return keys
+	}
+	base, err := url.New("s3://bucket/*")
+	assert.NilError(t, err)
+	addDelete := func(key string) {
+		u, err := url.New("s3://bucket/" + key)
+		assert.NilError(t, err)
+		u.SetRelative(base)
+		d.addDelete(u)
+	}
+
+	for _, key := range []string{"a", "c", "e", "g", "h"} {
+		addDelete(key)
+	}
+
+	// nothing is planned yet.
+	assert.Assert(t, d.take() == nil)
+
+	// "b" is being copied and nothing after "d" is planned by the other
+	// stream yet.
+	d.advance(streamOnlySource, "b")
+	d.plan("b")
+	d.written(0, "b")
+	d.advance(streamCommon, "d")
+	assert.Assert(t, d.take() == nil)
+
+	// the next object of the stream may be before "c".
+	d.complete(0)
+	assert.Assert(t, d.take() == nil)
+
+	// "d" is not copied.
+	d.advance(streamOnlySource, "d")
+	assert.DeepEqual(t, []string{"a", "c"}, keys(d.take()))
+	assert.Assert(t, d.take() == nil)
+
+	// "f" is being copied, the objects after it wait for a full batch.
+	d.advance(streamOnlySource, "f")
+	d.plan("f")
+	d.written(1, "f")
+	d.finish(streamCommon)
+	assert.Assert(t, d.take() == nil)
+
+	d.complete(1)
+	d.finish(streamOnlySource)
+	assert.DeepEqual(t, []string{"e", "g"}, keys(d.take()))
+
+	// the partial batch is released once no more objects are coming.
+	assert.Assert(t, d.take() == nil)
+	d.closeDeletes()
+	assert.DeepEqual(t, []string{"h"}, keys(d.take()))
+	assert.Assert(t, d.next() == nil)
+}
+
+func TestDeleteInterleaverStop(t *testing.T) {
+	d := newDeleteInterleaver(1)
+
+	u, err := url.New("s3://bucket/b")
+	assert.NilError(t, err)
+	d.addDelete(u)
+
+	d.advance(streamOnlySource, "a")
+	d.plan("a")
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		d.run(func([]*url.URL) {
+			t.Error("unexpected release")
+		})
+	}()
+
+	d.stop()
+	<-done
+}
+
+func TestDeleteInterleaverNil(t *testing.T) {
+	var d *deleteInterleaver
+
+	d.advance(streamCommon, "a")
+	d.plan("a")
+	d.complete(0)
+	d.finish(streamCommon)
+	d.run(func([]*url.URL) {
+		t.Error("unexpected release")
+	})
+}
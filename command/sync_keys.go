@@ -0,0 +1,121 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/urfave/cli/v2"
+
+	errorpkg "github.com/peak/s5cmd/v2/error"
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+const (
+	validateKeysFlagName       = "validate-keys"
+	maxKeyLengthFlagName       = "max-key-length"
+	disallowedKeyCharsFlagName = "disallowed-key-chars"
+
+	// defaultMaxKeyLength is the maximum length of S3 keys in bytes.
+	defaultMaxKeyLength = 1024
+	// defaultDisallowedKeyChars are the characters which AWS recommends to
+	// avoid in keys, and the ones which are not allowed in Windows file
+	// names.
+	defaultDisallowedKeyChars = "\\:*?\"<>|{}^%`[]~#"
+)
+
+// keyRules are the rules which the destination keys of a sync are checked
+// against with --validate-keys. The keys which are valid on S3 may still be
+// troublesome for other tools, e.g. the keys with colons can't be downloaded
+// to Windows, so the violations are reported before the keys are written.
+type keyRules struct {
+	maxLength  int
+	disallowed string
+	// position is the position of the sync in the input of run, if any.
+	position string
+}
+
+// newKeyRules returns the key rules given with the flags, or nil if the keys
+// are not validated.
+func newKeyRules(c *cli.Context) *keyRules {
+	if !c.Bool(validateKeysFlagName) {
+		return nil
+	}
+	return &keyRules{
+		maxLength:  c.Int(maxKeyLengthFlagName),
+		disallowed: c.String(disallowedKeyCharsFlagName),
+		position:   commandPosition(c),
+	}
+}
+
+// check returns the first rule the key violates, or nil if it is valid.
+func (r *keyRules) check(key string) error {
+	if len(key) > r.maxLength {
+		return fmt.Errorf("key is %d bytes, longer than %d bytes", len(key), r.maxLength)
+	}
+	if !utf8.ValidString(key) {
+		return errors.New("key is not valid UTF-8")
+	}
+	for _, ch := range key {
+		if unicode.IsControl(ch) {
+			return fmt.Errorf("key contains control character %U", ch)
+		}
+		if strings.ContainsRune(r.disallowed, ch) {
+			return fmt.Errorf("key contains disallowed character %q", ch)
+		}
+	}
+	// each part of the key is a file or a directory name once it's
+	// downloaded.
+	for _, part := range strings.Split(key, "/") {
+		if part != strings.TrimSpace(part) {
+			return fmt.Errorf("%q has leading or trailing spaces", part)
+		}
+	}
+	return nil
+}
+
+// validate checks the destination key of the object which is going to be
+// copied from srcurl to dsturl, and prints the violation, if any. It is a
+// no-op if r is nil.
+func (r *keyRules) validate(op, fullCommand string, srcurl, dsturl *url.URL) error {
+	if r == nil {
+		return nil
+	}
+	if err := r.check(dsturl.Path); err != nil {
+		err = &errorpkg.Error{
+			Op:       op,
+			Src:      srcurl,
+			Dst:      dsturl,
+			Err:      fmt.Errorf("invalid destination key: %w", err),
+			Position: r.position,
+		}
+		printError(fullCommand, op, err)
+		return err
+	}
+	return nil
+}
+
+func validateKeyRules(c *cli.Context) error {
+	for _, flag := range []string{maxKeyLengthFlagName, disallowedKeyCharsFlagName} {
+		if c.IsSet(flag) && !c.Bool(validateKeysFlagName) {
+			return fmt.Errorf("%q flag requires %q flag", flag, validateKeysFlagName)
+		}
+	}
+	if !c.Bool(validateKeysFlagName) {
+		return nil
+	}
+	if !isDryRun(c) {
+		return fmt.Errorf("%q flag requires %q flag", validateKeysFlagName, "dry-run")
+	}
+	if c.Args().Len() == 2 {
+		if dsturl, err := url.New(c.Args().Get(1)); err == nil && !dsturl.IsRemote() {
+			return fmt.Errorf("%q flag requires remote destination", validateKeysFlagName)
+		}
+	}
+	if c.Int(maxKeyLengthFlagName) < 1 {
+		return fmt.Errorf("%q flag must be greater than zero", maxKeyLengthFlagName)
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestKeyRulesCheck(t *testing.T) {
+	rules := &keyRules{maxLength: 20, disallowed: defaultDisallowedKeyChars}
+
+	testcases := []struct {
+		key      string
+		expected string
+	}{
+		{key: "dir/file.txt"},
+		{key: "dir/file name.txt"},
+		{key: "dir/überstunden.csv"},
+		{key: "dir/a-very-long-file.txt", expected: "key is 24 bytes, longer than 20 bytes"},
+		{key: "dir/c:file.txt", expected: `key contains disallowed character ':'`},
+		{key: `dir\file.txt`, expected: `key contains disallowed character '\\'`},
+		{key: "dir/file\t.txt", expected: "key contains control character U+0009"},
+		{key: "dir/ file.txt", expected: `" file.txt" has leading or trailing spaces`},
+		{key: "dir /file.txt", expected: `"dir " has leading or trailing spaces`},
+		{key: "dir/\xff.txt", expected: "key is not valid UTF-8"},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.key, func(t *testing.T) {
+			err := rules.check(tc.key)
+			if tc.expected == "" {
+				assert.NilError(t, err)
+				return
+			}
+			assert.Error(t, err, tc.expected)
+		})
+	}
+
+	rules = &keyRules{maxLength: defaultMaxKeyLength}
+	assert.NilError(t, rules.check("dir/c:file.txt"))
+	assert.ErrorContains(t, rules.check(strings.Repeat("a", defaultMaxKeyLength+1)), "longer than 1024 bytes")
+}
+
+func TestKeyRulesNil(t *testing.T) {
+	var rules *keyRules
+	assert.NilError(t, rules.validate("sync", "sync", nil, nil))
+}
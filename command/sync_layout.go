@@ -0,0 +1,147 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/peak/s5cmd/v2/log"
+	"github.com/peak/s5cmd/v2/storage"
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+const (
+	contentsOnlyFlagName    = "contents-only"
+	layoutConfirmedFlagName = "layout-confirmed"
+
+	// layoutProbeSize is the number of the files of the source which are
+	// looked up in destination to detect its layout.
+	layoutProbeSize = 16
+)
+
+// contentsOnlySource returns the given local source directory with a trailing
+// slash, so that its contents are synced into the destination rather than
+// under its name. The other sources are returned as they are.
+func contentsOnlySource(src string, raw bool) string {
+	srcurl, err := url.New(src, url.WithRaw(raw))
+	if err != nil || srcurl.IsRemote() || srcurl.IsWildcard() || strings.HasSuffix(filepath.ToSlash(src), "/") {
+		return src
+	}
+	fi, err := os.Stat(srcurl.Absolute())
+	if err != nil || !fi.IsDir() {
+		return src
+	}
+	return src + "/"
+}
+
+// checkLayout checks whether the destination has the files of a local source
+// directory laid out by the other spelling of the source: "dir" syncs the
+// files under "dir/" in destination, whereas "dir/" and "dir/*" sync them
+// into the destination itself. A few of the files of the source are looked up
+// in destination with both of the layouts. If none of them are found with the
+// layout of the sync but some are with the other one, a warning is printed, or
+// an error is returned if the objects only in destination are deleted.
+func (s Sync) checkLayout(ctx context.Context, srcurl, dsturl *url.URL) error {
+	if s.layoutConfirmed || s.stripKeyPrefix != "" || s.destDatePrefix != "" {
+		return nil
+	}
+	if srcurl.IsRemote() || (dsturl.IsRemote() && !dsturl.IsPrefix() && !dsturl.IsBucket()) {
+		return nil
+	}
+
+	src := filepath.ToSlash(s.src)
+	if srcurl.IsWildcard() {
+		// only "dir/*" is the other spelling of "dir".
+		if !strings.HasSuffix(src, "/*") || strings.ContainsAny(strings.TrimSuffix(src, "*"), "*?[") {
+			return nil
+		}
+		src = strings.TrimSuffix(src, "*")
+	} else {
+		fi, err := os.Stat(srcurl.Absolute())
+		if err != nil || !fi.IsDir() {
+			return nil
+		}
+	}
+
+	name := path.Base(src)
+	if name == "." || name == ".." || name == "/" {
+		return nil
+	}
+	underName := !strings.HasSuffix(src, "/")
+	prefix := name + "/"
+
+	// the broken symbolic links are reported as the errors of the probe,
+	// which are skipped, not to warn about them twice.
+	sourceOpts := s.storageOpts
+	sourceOpts.OnBrokenSymlink = storage.BrokenSymlinkError
+	sourceClient, err := storage.NewClient(ctx, srcurl, sourceOpts)
+	if err != nil {
+		return err
+	}
+	destClient, err := storage.NewClient(ctx, dsturl, s.storageOpts)
+	if err != nil {
+		return err
+	}
+
+	listctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var urls []*url.URL
+	for obj := range sourceClient.List(listctx, srcurl, s.followSymlinks) {
+		if len(urls) == 2*layoutProbeSize {
+			cancel()
+			continue
+		}
+		if s.shouldSkipObject(obj, false) {
+			continue
+		}
+		rel := filepath.ToSlash(obj.URL.Relative())
+		other := prefix + rel
+		if underName {
+			other = strings.TrimPrefix(rel, prefix)
+		}
+		urls = append(urls, generateDestinationURL(obj.URL, dsturl, true), dsturl.Join(other))
+	}
+
+	var found, foundOther int
+	for i, result := range destClient.StatMany(ctx, urls, layoutProbeSize) {
+		switch {
+		case result.Err != nil:
+			printDebug(s.op, fmt.Errorf("check the layout of destination: %w", result.Err), result.URL)
+		case result.NotFound:
+		case i%2 == 0:
+			found++
+		default:
+			foundOther++
+		}
+	}
+	if found > 0 || foundOther == 0 {
+		return nil
+	}
+
+	var msg string
+	if underName {
+		msg = fmt.Sprintf("%v has the files of %q in itself, as synced from %q, but they are synced under %q; use %q or %q flag to sync into the same layout",
+			s.dst, s.src, src+"/", prefix, src+"/", contentsOnlyFlagName)
+	} else {
+		spelling := fmt.Sprintf("%q", strings.TrimSuffix(src, "/"))
+		if s.contentsOnly {
+			spelling += fmt.Sprintf(" without %q flag", contentsOnlyFlagName)
+		}
+		msg = fmt.Sprintf("%v has the files of %q under %q, as synced from %q, but they are synced into it; use %v to sync into the same layout",
+			s.dst, s.src, prefix, strings.TrimSuffix(src, "/"), spelling)
+	}
+
+	if s.delete {
+		return fmt.Errorf("%v; refusing to delete the objects in destination with %q flag unless %q flag is given",
+			msg, "delete", layoutConfirmedFlagName)
+	}
+	log.Warning(log.WarningMessage{
+		Operation: s.op,
+		Warning:   fmt.Sprintf("%v, or %q flag if it is intended", msg, layoutConfirmedFlagName),
+	})
+	return nil
+}
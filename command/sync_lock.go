@@ -0,0 +1,382 @@
+package command
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/peak/s5cmd/v2/log"
+	"github.com/peak/s5cmd/v2/storage"
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+const (
+	lockFlagName     = "lock"
+	lockTTLFlagName  = "lock-ttl"
+	lockWaitFlagName = "lock-wait"
+
+	defaultLockTTL = time.Hour
+
+	// lockPollInterval is the interval which a held lock is checked at while
+	// waiting for it with --lock-wait.
+	lockPollInterval = 10 * time.Second
+)
+
+// errLockLost is the error of a sync whose lock is taken over by another sync
+// or deleted while the sync is running.
+var errLockLost = errors.New("lock is lost")
+
+// lockRecord is the content of the lock object.
+type lockRecord struct {
+	Token    string    `json:"token"`
+	Owner    string    `json:"owner"`
+	Acquired time.Time `json:"acquired"`
+	Expires  time.Time `json:"expires"`
+}
+
+// lockHeldError is the error of a sync which couldn't acquire the lock since
+// another sync holds it.
+type lockHeldError struct {
+	url    *url.URL
+	holder *lockRecord
+}
+
+func (e *lockHeldError) Error() string {
+	if e.holder == nil {
+		return fmt.Sprintf("lock %v is held by another sync", e.url)
+	}
+	return fmt.Sprintf("lock %v is held by %q until %v", e.url, e.holder.Owner, e.holder.Expires.Format(time.RFC3339))
+}
+
+// syncLock is the lock object given with --lock, which keeps the syncs to the
+// same destination from running at the same time. A sync acquires the lock by
+// writing the object with a random token, only if it doesn't exist or it has
+// expired, and reads it back to verify that the token is its own, since some
+// endpoints ignore the conditions of the writes. The lock expires after
+// --lock-ttl, unless it is refreshed by its holder, so the lock of a sync which
+// crashed is taken over once it expires. The lock object is neither synced nor
+// deleted by the sync.
+type syncLock struct {
+	url    *url.URL
+	ttl    time.Duration
+	wait   time.Duration
+	dryRun bool
+	opts   storage.Options
+
+	client *storage.S3
+	token  string
+	owner  string
+
+	// the fields below are guarded by mu, since they are updated by the
+	// refresh loop.
+	mu      sync.Mutex
+	expires time.Time
+	lost    error
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// newSyncLock returns the lock given with the flags, or nil if the "lock" flag
+// is not given. The flags are validated before.
+func newSyncLock(c *cli.Context) *syncLock {
+	lockurl, err := url.New(c.String(lockFlagName))
+	if c.String(lockFlagName) == "" || err != nil {
+		return nil
+	}
+	return &syncLock{
+		url:    lockurl,
+		ttl:    c.Duration(lockTTLFlagName),
+		wait:   c.Duration(lockWaitFlagName),
+		dryRun: isDryRun(c),
+		opts:   NewStorageOpts(c),
+	}
+}
+
+// isLockObject reports whether u is the lock object. It returns false if l is
+// nil.
+func (l *syncLock) isLockObject(u *url.URL) bool {
+	if l == nil || !u.IsRemote() {
+		return false
+	}
+	return u.Bucket == l.url.Bucket && u.Path == l.url.Path
+}
+
+// acquire acquires the lock, waiting for it up to --lock-wait if another sync
+// holds it, and starts refreshing it. It returns the context which the sync
+// must be run with, which is canceled if the lock is lost. It returns ctx as is
+// if l is nil or it is a dry run.
+func (l *syncLock) acquire(ctx context.Context) (context.Context, error) {
+	if l == nil || l.dryRun {
+		return ctx, nil
+	}
+
+	client, err := storage.NewRemoteClient(ctx, l.url, l.opts)
+	if err != nil {
+		return ctx, err
+	}
+	l.client = client
+	l.token, err = newLockToken()
+	if err != nil {
+		return ctx, err
+	}
+	l.owner = lockOwner()
+
+	deadline := time.Now().Add(l.wait)
+	for {
+		err := l.tryAcquire(ctx)
+		if err == nil {
+			break
+		}
+		var held *lockHeldError
+		if !errors.As(err, &held) || !time.Now().Before(deadline) {
+			return ctx, err
+		}
+
+		wait := time.Until(deadline)
+		if wait > lockPollInterval {
+			wait = lockPollInterval
+		}
+		select {
+		case <-ctx.Done():
+			return ctx, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	l.stop = make(chan struct{})
+	l.done = make(chan struct{})
+	go l.refreshLoop(ctx, cancel)
+	return ctx, nil
+}
+
+// tryAcquire writes the lock object if it doesn't exist or it has expired. It
+// returns a lockHeldError if another sync holds the lock.
+func (l *syncLock) tryAcquire(ctx context.Context) error {
+	holder, etag, err := l.read(ctx)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if holder != nil && now.Before(holder.Expires) {
+		return &lockHeldError{url: l.url, holder: holder}
+	}
+
+	// the expired lock is taken over only if it's not taken over by another
+	// sync in the meantime.
+	expires := now.Add(l.ttl)
+	_, err = l.write(ctx, now, expires, etag)
+	if errors.Is(err, storage.ErrPreconditionFailed) {
+		return &lockHeldError{url: l.url}
+	}
+	if err != nil {
+		return err
+	}
+
+	// the last writer holds the lock on the endpoints which ignore the
+	// conditions.
+	holder, _, err = l.read(ctx)
+	if err != nil {
+		return err
+	}
+	if holder == nil || holder.Token != l.token {
+		return &lockHeldError{url: l.url, holder: holder}
+	}
+
+	l.mu.Lock()
+	l.expires = expires
+	l.mu.Unlock()
+	return nil
+}
+
+// refreshLoop extends the expiry of the lock periodically until the lock is
+// released. If the lock is lost, or it can't be refreshed before it expires,
+// the sync is canceled, since another sync may acquire the lock.
+func (l *syncLock) refreshLoop(ctx context.Context, cancel context.CancelFunc) {
+	defer close(l.done)
+
+	ticker := time.NewTicker(l.ttl / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		err := l.refresh(ctx)
+		if err == nil {
+			continue
+		}
+
+		l.mu.Lock()
+		expired := !time.Now().Before(l.expires)
+		if errors.Is(err, errLockLost) || expired {
+			l.lost = fmt.Errorf("%v: %w", l.url, err)
+			l.mu.Unlock()
+			cancel()
+			return
+		}
+		l.mu.Unlock()
+
+		// the lock is refreshed again with the next tick.
+		log.Warning(log.WarningMessage{
+			Operation: "sync",
+			Warning:   fmt.Sprintf("could not refresh lock %v: %v", l.url, err),
+		})
+	}
+}
+
+// refresh extends the expiry of the lock if it's still held by the sync.
+func (l *syncLock) refresh(ctx context.Context) error {
+	holder, etag, err := l.read(ctx)
+	if err != nil {
+		return err
+	}
+	if holder == nil || holder.Token != l.token {
+		return errLockLost
+	}
+
+	expires := time.Now().Add(l.ttl)
+	_, err = l.write(ctx, holder.Acquired, expires, etag)
+	if errors.Is(err, storage.ErrPreconditionFailed) {
+		return errLockLost
+	}
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	l.expires = expires
+	l.mu.Unlock()
+	return nil
+}
+
+// release stops refreshing the lock and deletes the lock object if it's still
+// held by the sync. It is a no-op if the lock is not acquired.
+func (l *syncLock) release(command string) {
+	if l == nil || l.stop == nil {
+		return
+	}
+	close(l.stop)
+	<-l.done
+
+	// the sync is completed, but the lock is released even if the context
+	// of the sync is canceled.
+	ctx := context.Background()
+	holder, _, err := l.read(ctx)
+	if err == nil && (holder == nil || holder.Token != l.token) {
+		return
+	}
+	if err == nil {
+		err = l.client.Delete(ctx, l.url)
+	}
+	if err != nil {
+		printError(command, "sync", fmt.Errorf("could not release lock %v: %w", l.url, err))
+	}
+}
+
+// result returns the error of the sync which completed with err, which is the
+// loss of the lock if the sync is canceled because of it. It returns err as is
+// if l is nil.
+func (l *syncLock) result(err error) error {
+	if l == nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.lost != nil {
+		return l.lost
+	}
+	return err
+}
+
+// read returns the lock object and its ETag, or nil if it doesn't exist.
+func (l *syncLock) read(ctx context.Context) (*lockRecord, string, error) {
+	content, etag, err := l.client.ReadAll(ctx, l.url)
+	if err != nil {
+		var notFound *storage.ErrGivenObjectNotFound
+		if errors.As(err, &notFound) {
+			return nil, "", nil
+		}
+		return nil, "", err
+	}
+
+	var record lockRecord
+	if err := json.Unmarshal(content, &record); err != nil {
+		return nil, "", fmt.Errorf("invalid lock %v: %w", l.url, err)
+	}
+	return &record, etag, nil
+}
+
+// write writes the lock object of the sync, only if it doesn't exist if etag
+// is empty, or if it's not changed since it's read with etag otherwise.
+func (l *syncLock) write(ctx context.Context, acquired, expires time.Time, etag string) (string, error) {
+	content, err := json.Marshal(lockRecord{
+		Token:    l.token,
+		Owner:    l.owner,
+		Acquired: acquired.UTC(),
+		Expires:  expires.UTC(),
+	})
+	if err != nil {
+		return "", err
+	}
+	return l.client.PutConditional(ctx, l.url, content, etag)
+}
+
+// newLockToken returns a random token which tells the lock of a sync from the
+// locks of the others.
+func newLockToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// lockOwner returns the owner of the lock written to the lock object, to tell
+// the users which sync holds the lock.
+func lockOwner() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%v:%d", host, os.Getpid())
+}
+
+func validateLock(c *cli.Context) error {
+	if !c.IsSet(lockFlagName) {
+		for _, flag := range []string{lockTTLFlagName, lockWaitFlagName} {
+			if c.IsSet(flag) {
+				return fmt.Errorf("%q flag requires %q flag", flag, lockFlagName)
+			}
+		}
+		return nil
+	}
+
+	lockurl, err := url.New(c.String(lockFlagName))
+	if err != nil {
+		return fmt.Errorf("invalid %q: %w", lockFlagName, err)
+	}
+	if !lockurl.IsRemote() || lockurl.IsBucket() || lockurl.IsPrefix() || lockurl.IsWildcard() {
+		return fmt.Errorf("%q flag must be the URL of a remote object, e.g. s3://bucket/prefix/.s5cmd.lock", lockFlagName)
+	}
+	if c.Duration(lockTTLFlagName) <= 0 {
+		return fmt.Errorf("%q flag must be greater than zero", lockTTLFlagName)
+	}
+	if c.Duration(lockWaitFlagName) < 0 {
+		return fmt.Errorf("%q flag must not be negative", lockWaitFlagName)
+	}
+	return nil
+}
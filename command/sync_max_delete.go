@@ -0,0 +1,91 @@
+package command
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	maxDeleteFlagName        = "max-delete"
+	maxDeletePercentFlagName = "max-delete-percent"
+)
+
+// deleteLimit is the limit of the objects only in destination which are
+// deleted by a sync, set by --max-delete and --max-delete-percent.
+type deleteLimit struct {
+	// count is the maximum number of the deletes, or -1 if not limited.
+	count int
+	// percent is the maximum percentage of the deletes among the objects
+	// listed in destination, or -1 if not limited.
+	percent float64
+	// listed is the number of the objects listed in destination.
+	listed atomic.Int64
+}
+
+// newDeleteLimit returns the limit of the deletes set by the flags, or nil if
+// they are not limited.
+func newDeleteLimit(c *cli.Context) *deleteLimit {
+	count, percent := c.Int(maxDeleteFlagName), c.Float64(maxDeletePercentFlagName)
+	if !c.Bool("delete") || (count < 0 && percent < 0) {
+		return nil
+	}
+	return &deleteLimit{count: count, percent: percent}
+}
+
+// addListed counts an object listed in destination.
+func (l *deleteLimit) addListed() {
+	if l != nil {
+		l.listed.Add(1)
+	}
+}
+
+// check returns an error if the number of the objects only in destination
+// which are to be deleted is more than the limit. A nil limit allows any
+// number of deletes.
+func (l *deleteLimit) check(deletes int) error {
+	if l == nil {
+		return nil
+	}
+	if l.count >= 0 && deletes > l.count {
+		return fmt.Errorf(
+			"refusing to delete %d objects in destination, which is more than the %d allowed by %q flag; nothing is copied or deleted",
+			deletes, l.count, maxDeleteFlagName,
+		)
+	}
+
+	listed := l.listed.Load()
+	if l.percent >= 0 && listed > 0 && float64(deletes)*100 > l.percent*float64(listed) {
+		return fmt.Errorf(
+			"refusing to delete %d of %d objects in destination (%.1f%%), which is more than the %v%% allowed by %q flag; nothing is copied or deleted",
+			deletes, listed, float64(deletes)*100/float64(listed), l.percent, maxDeletePercentFlagName,
+		)
+	}
+	return nil
+}
+
+func validateMaxDelete(c *cli.Context) error {
+	for _, flag := range []string{maxDeleteFlagName, maxDeletePercentFlagName} {
+		if !c.IsSet(flag) {
+			continue
+		}
+		if !c.Bool("delete") {
+			return fmt.Errorf("%q flag requires %q flag", flag, "delete")
+		}
+		// the partitions are synced concurrently, so the total number of
+		// the deletes is not known before the commands of any of them are
+		// run.
+		if c.Bool("partition-by-prefix") {
+			return fmt.Errorf("it is not allowed to combine %q and %q flags", flag, "partition-by-prefix")
+		}
+	}
+
+	if count := c.Int(maxDeleteFlagName); count < -1 {
+		return fmt.Errorf("%q flag must not be negative, except -1 which disables the limit", maxDeleteFlagName)
+	}
+	if percent := c.Float64(maxDeletePercentFlagName); percent != -1 && (percent < 0 || percent > 100) {
+		return fmt.Errorf("%q flag must be between 0 and 100, or -1 which disables the limit", maxDeletePercentFlagName)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package command
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestDeleteLimitCheck(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name        string
+		deletes     int
+		count       int
+		percent     float64
+		listed      int64
+		expectedErr string
+	}{
+		{name: "no limit", deletes: 1000, count: -1, percent: -1, listed: 1000},
+		{name: "below limit", deletes: 9, count: 10, percent: -1},
+		{name: "at limit", deletes: 10, count: 10, percent: -1},
+		{
+			name:        "above limit",
+			deletes:     11,
+			count:       10,
+			percent:     -1,
+			expectedErr: `refusing to delete 11 objects in destination, which is more than the 10 allowed by "max-delete" flag`,
+		},
+		{
+			name:        "no deletes allowed",
+			deletes:     1,
+			count:       0,
+			percent:     -1,
+			expectedErr: `refusing to delete 1 objects in destination, which is more than the 0 allowed by "max-delete" flag`,
+		},
+		{name: "below percentage", deletes: 4, count: -1, percent: 50, listed: 10},
+		{name: "at percentage", deletes: 5, count: -1, percent: 50, listed: 10},
+		{
+			name:        "above percentage",
+			deletes:     6,
+			count:       -1,
+			percent:     50,
+			listed:      10,
+			expectedErr: `refusing to delete 6 of 10 objects in destination (60.0%), which is more than the 50% allowed by "max-delete-percent" flag`,
+		},
+		{
+			name:        "above percentage within count",
+			deletes:     3,
+			count:       100,
+			percent:     2.5,
+			listed:      100,
+			expectedErr: `refusing to delete 3 of 100 objects in destination (3.0%), which is more than the 2.5% allowed by "max-delete-percent" flag`,
+		},
+		{name: "empty destination", deletes: 0, count: -1, percent: 0},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			limit := &deleteLimit{count: tc.count, percent: tc.percent}
+			limit.listed.Store(tc.listed)
+
+			err := limit.check(tc.deletes)
+			if tc.expectedErr == "" {
+				assert.NilError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tc.expectedErr)
+		})
+	}
+}
+
+func TestDeleteLimitNil(t *testing.T) {
+	t.Parallel()
+
+	var limit *deleteLimit
+	limit.addListed()
+	assert.NilError(t, limit.check(1000))
+}
@@ -0,0 +1,147 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/peak/s5cmd/v2/storage"
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+const preserveMetadataFlagName = "preserve-metadata"
+
+// MetadataStrategy syncs the objects which Strategy skips, if the content
+// types or the user-defined metadata of their source and destination objects
+// are different. The metadata of the objects is not listed, so the objects
+// are requested with Stat one by one.
+type MetadataStrategy struct {
+	Strategy        SyncStrategy
+	SourceStat      func(*url.URL) (*storage.Object, error)
+	DestinationStat func(*url.URL) (*storage.Object, error)
+}
+
+func (ms *MetadataStrategy) ShouldSync(srcObj, dstObj *storage.Object) *SkipReason {
+	reason := ms.Strategy.ShouldSync(srcObj, dstObj)
+	if reason == nil {
+		return nil
+	}
+
+	// the object is synced if its metadata can't be determined, the copy
+	// fails with the same error if the object is not accessible.
+	src, err := ms.SourceStat(srcObj.URL)
+	if err != nil {
+		return nil
+	}
+	dst, err := ms.DestinationStat(dstObj.URL)
+	if err != nil {
+		return nil
+	}
+
+	// the metadata is kept on the objects to report why they are synced.
+	srcObj.ContentType, srcObj.UserMetadata = src.ContentType, src.UserMetadata
+	dstObj.ContentType, dstObj.UserMetadata = dst.ContentType, dst.UserMetadata
+
+	if !metadataMatches(srcObj, dstObj) {
+		return nil
+	}
+	return reason
+}
+
+// metadataMatches reports whether the objects have the same content type and
+// user-defined metadata. The metadata of the objects is only known if they
+// are requested with Stat.
+func metadataMatches(srcObj, dstObj *storage.Object) bool {
+	if srcObj.ContentType != dstObj.ContentType {
+		return false
+	}
+	if len(srcObj.UserMetadata) == 0 && len(dstObj.UserMetadata) == 0 {
+		return true
+	}
+	return reflect.DeepEqual(srcObj.UserMetadata, dstObj.UserMetadata)
+}
+
+// newMetadataStrategy wraps the strategy to sync the objects whose metadata
+// are different in source and destination.
+func (s Sync) newMetadataStrategy(c *cli.Context, srcurl, dsturl *url.URL, strategy SyncStrategy) (SyncStrategy, error) {
+	srcClient, err := storage.NewRemoteClient(c.Context, srcurl, s.storageOpts)
+	if err != nil {
+		return nil, err
+	}
+	dstClient, err := storage.NewRemoteClient(c.Context, dsturl, s.storageOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MetadataStrategy{
+		Strategy: strategy,
+		SourceStat: func(u *url.URL) (*storage.Object, error) {
+			return srcClient.Stat(c.Context, u)
+		},
+		DestinationStat: func(u *url.URL) (*storage.Object, error) {
+			return dstClient.Stat(c.Context, u)
+		},
+	}, nil
+}
+
+// preservedMetadataFlags returns the flags of the copy of the object at srcurl,
+// requested with stat, which set its content type and user-defined metadata on
+// the destination object. They are only needed for the transformed objects,
+// which are uploaded rather than copied on the server side along with their
+// metadata. The flags given to the sync take precedence over the metadata of
+// the source object.
+func preservedMetadataFlags(
+	c *cli.Context,
+	stat func(context.Context, *url.URL) (*storage.Object, error),
+	srcurl *url.URL,
+) (map[string]interface{}, error) {
+	obj, err := stat(c.Context, srcurl)
+	if err != nil {
+		return nil, fmt.Errorf("metadata of %v: %w", srcurl, err)
+	}
+
+	flags := map[string]interface{}{}
+	if obj.ContentType != "" && !c.IsSet("content-type") {
+		flags["content-type"] = []string{obj.ContentType}
+	}
+
+	metadata := map[string]string{}
+	for name, value := range obj.UserMetadata {
+		metadata[name] = value
+	}
+	for name, value := range userMetadata(c) {
+		metadata[strings.ToLower(name)] = value
+	}
+	if len(metadata) > 0 {
+		values := make([]string, 0, len(metadata))
+		for name, value := range metadata {
+			values = append(values, name+"="+value)
+		}
+		sort.Strings(values)
+		flags["metadata"] = values
+	}
+	return flags, nil
+}
+
+func validatePreserveMetadata(c *cli.Context) error {
+	if !c.Bool(preserveMetadataFlagName) || c.Args().Len() != 2 {
+		return nil
+	}
+	srcurl, err := url.New(c.Args().Get(0))
+	if err != nil {
+		return nil
+	}
+	dsturl, err := url.New(c.Args().Get(1))
+	if err != nil {
+		return nil
+	}
+	// the local files have no metadata.
+	if !srcurl.IsRemote() || !dsturl.IsRemote() {
+		return fmt.Errorf("%q flag can only be used with remote source and destination", preserveMetadataFlagName)
+	}
+	return nil
+}
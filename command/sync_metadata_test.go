@@ -0,0 +1,157 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	errorpkg "github.com/peak/s5cmd/v2/error"
+	"github.com/peak/s5cmd/v2/storage"
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+func TestMetadataStrategy_ShouldSync(t *testing.T) {
+	t.Parallel()
+
+	srcurl := mustNewURL(t, "s3://bucket/key")
+	dsturl := mustNewURL(t, "s3://target/key")
+
+	testcases := []struct {
+		name     string
+		etag     string
+		src      *storage.Object
+		dst      *storage.Object
+		statErr  error
+		expected error
+	}{
+		{
+			name:     "etags are different",
+			etag:     "def",
+			expected: nil,
+		},
+		{
+			name:     "metadata matches",
+			etag:     "abc",
+			src:      &storage.Object{ContentType: "text/csv", UserMetadata: map[string]string{"owner": "alice"}},
+			dst:      &storage.Object{ContentType: "text/csv", UserMetadata: map[string]string{"owner": "alice"}},
+			expected: errorpkg.ErrObjectEtagsMatch,
+		},
+		{
+			name:     "no metadata",
+			etag:     "abc",
+			src:      &storage.Object{},
+			dst:      &storage.Object{UserMetadata: map[string]string{}},
+			expected: errorpkg.ErrObjectEtagsMatch,
+		},
+		{
+			name:     "content types are different",
+			etag:     "abc",
+			src:      &storage.Object{ContentType: "text/csv"},
+			dst:      &storage.Object{ContentType: "binary/octet-stream"},
+			expected: nil,
+		},
+		{
+			name:     "user-defined metadata is different",
+			etag:     "abc",
+			src:      &storage.Object{UserMetadata: map[string]string{"owner": "alice"}},
+			dst:      &storage.Object{UserMetadata: map[string]string{"owner": "bob"}},
+			expected: nil,
+		},
+		{
+			name:     "user-defined metadata is missing",
+			etag:     "abc",
+			src:      &storage.Object{UserMetadata: map[string]string{"owner": "alice"}},
+			dst:      &storage.Object{},
+			expected: nil,
+		},
+		{
+			name:     "metadata can't be determined",
+			etag:     "abc",
+			statErr:  errors.New("access denied"),
+			expected: nil,
+		},
+	}
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			stat := func(expected *url.URL, obj *storage.Object) func(*url.URL) (*storage.Object, error) {
+				return func(u *url.URL) (*storage.Object, error) {
+					if u != expected {
+						t.Errorf("expected stat of %v, got %v", expected, u)
+					}
+					return obj, tc.statErr
+				}
+			}
+			strategy := &MetadataStrategy{
+				Strategy:        &EtagStrategy{},
+				SourceStat:      stat(srcurl, tc.src),
+				DestinationStat: stat(dsturl, tc.dst),
+			}
+
+			src := &storage.Object{URL: srcurl, Etag: "abc", Size: 10}
+			dst := &storage.Object{URL: dsturl, Etag: tc.etag, Size: 10}
+
+			var got error
+			if reason := strategy.ShouldSync(src, dst); reason != nil {
+				got = reason.Err()
+			}
+			if got != tc.expected {
+				t.Fatalf("expected: %q(%T), got: %q(%T)", tc.expected, tc.expected, got, got)
+			}
+		})
+	}
+}
+
+func TestPreservedMetadataFlags(t *testing.T) {
+	t.Parallel()
+
+	srcurl := mustNewURL(t, "s3://bucket/users.csv")
+	stat := func(_ context.Context, u *url.URL) (*storage.Object, error) {
+		return &storage.Object{
+			URL:          u,
+			ContentType:  "text/csv",
+			UserMetadata: map[string]string{"owner": "alice", "team": "data"},
+		}, nil
+	}
+
+	testcases := []struct {
+		name     string
+		flags    map[string][]string
+		expected string
+	}{
+		{
+			name:     "source metadata",
+			expected: `cp --content-type=text/csv --metadata=owner=alice --metadata=team=data --raw=true "s3://bucket/users.csv" "s3://target/users.csv"`,
+		},
+		{
+			name: "flags of the sync",
+			flags: map[string][]string{
+				"content-type": {"text/plain"},
+				"metadata":     {"Team=analytics", "reviewed=yes it is"},
+			},
+			expected: `cp --content-type=text/plain --metadata="reviewed=yes it is" --metadata=owner=alice --metadata=team=analytics --raw=true "s3://bucket/users.csv" "s3://target/users.csv"`,
+		},
+	}
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := newSyncContext(t, tc.flags)
+			flags, err := preservedMetadataFlags(ctx, stat, srcurl)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			generator := newCommandGenerator(ctx, "cp", map[string]interface{}{"raw": true})
+			got := generator.generate(flags, srcurl, mustNewURL(t, "s3://target/users.csv"))
+			if diff := cmp.Diff(tc.expected, got); diff != "" {
+				t.Errorf("(-want +got):\n%v", diff)
+			}
+		})
+	}
+}
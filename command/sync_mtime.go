@@ -0,0 +1,80 @@
+package command
+
+import (
+	"context"
+	"time"
+
+	"github.com/peak/s5cmd/v2/storage"
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+// ModTimeStrategy skips the objects which Strategy syncs, if they are
+// downloaded to the files with the modification times kept in their metadata,
+// or with their last modification times. The files downloaded with
+// --preserve-mtime are older than their objects, or than the last
+// modification times listed with subsecond precision, so they would be synced
+// again otherwise. The metadata of the objects is not listed, so only the
+// objects whose sizes match their files are requested with Stat one by one.
+type ModTimeStrategy struct {
+	Strategy      SyncStrategy
+	SkewTolerance time.Duration
+	Stat          func(*url.URL) (*storage.Object, error)
+}
+
+func (ms *ModTimeStrategy) ShouldSync(srcObj, dstObj *storage.Object) *SkipReason {
+	reason := ms.Strategy.ShouldSync(srcObj, dstObj)
+	if reason != nil || srcObj.Size != dstObj.Size {
+		return reason
+	}
+
+	// the object is synced if its modification time can't be determined,
+	// the copy fails with the same error if the object is not accessible.
+	obj, err := ms.Stat(srcObj.URL)
+	if err != nil {
+		return nil
+	}
+	modTime := downloadedModTime(obj)
+	if modTime == nil {
+		return nil
+	}
+
+	diff := modTime.Sub(*dstObj.ModTime)
+	if diff > ms.SkewTolerance || -diff > ms.SkewTolerance {
+		return nil
+	}
+
+	// the reason reports the modification time the file is downloaded with.
+	downloaded := *srcObj
+	downloaded.ModTime = modTime
+	return newSkipReason(SkipSameAgeAndSizesMatch, &downloaded, dstObj)
+}
+
+// preservesModTime reports whether the objects synced from srcurl to dsturl
+// are copied with the modification times of the files, which is the case if
+// either of them is local.
+func preservesModTime(srcurl, dsturl *url.URL) bool {
+	return !srcurl.IsRemote() || !dsturl.IsRemote()
+}
+
+// newModTimeStrategy wraps the strategy to skip the objects downloaded with
+// the modification times kept in their metadata. Only the strategies which
+// compare sizes and modification times are wrapped, the others don't compare
+// the modification times of the files.
+func (s Sync) newModTimeStrategy(ctx context.Context, srcurl, dsturl *url.URL, strategy SyncStrategy) (SyncStrategy, error) {
+	if !srcurl.IsRemote() || dsturl.IsRemote() || s.syncStrategy != syncStrategySizeMtime || s.transformed {
+		return strategy, nil
+	}
+
+	client, err := storage.NewRemoteClient(ctx, srcurl, s.storageOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ModTimeStrategy{
+		Strategy:      strategy,
+		SkewTolerance: s.compareSkewTolerance(),
+		Stat: func(u *url.URL) (*storage.Object, error) {
+			return client.Stat(ctx, u)
+		},
+	}, nil
+}
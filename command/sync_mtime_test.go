@@ -0,0 +1,117 @@
+package command
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	errorpkg "github.com/peak/s5cmd/v2/error"
+	"github.com/peak/s5cmd/v2/storage"
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+func TestModTimeStrategy_ShouldSync(t *testing.T) {
+	t.Parallel()
+
+	srcurl := mustNewURL(t, "s3://bucket/key")
+	dsturl := mustNewURL(t, "dir/key")
+
+	uploaded := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	lastModified := uploaded.Add(time.Hour)
+	timePtr := func(t time.Time) *time.Time { return &t }
+
+	testcases := []struct {
+		name      string
+		dstSize   int64
+		dstTime   time.Time
+		preserved *time.Time
+		statTime  *time.Time
+		statErr   error
+		expected  error
+		stat      bool
+	}{
+		{
+			name:     "file is newer",
+			dstSize:  10,
+			dstTime:  lastModified.Add(time.Minute),
+			expected: errorpkg.ErrObjectIsNewerAndSizesMatch,
+		},
+		{
+			name:     "sizes are different",
+			dstSize:  20,
+			dstTime:  uploaded,
+			expected: nil,
+		},
+		{
+			name:      "file has preserved modification time",
+			dstSize:   10,
+			dstTime:   uploaded,
+			preserved: timePtr(uploaded),
+			expected:  errorpkg.ErrObjectIsSameAgeAndSizesMatch,
+			stat:      true,
+		},
+		{
+			name:      "file has another modification time",
+			dstSize:   10,
+			dstTime:   uploaded.Add(-time.Minute),
+			preserved: timePtr(uploaded),
+			expected:  nil,
+			stat:      true,
+		},
+		{
+			name:     "file has last modification time",
+			dstSize:  10,
+			dstTime:  lastModified.Add(-time.Millisecond),
+			statTime: timePtr(lastModified.Add(-time.Millisecond)),
+			expected: errorpkg.ErrObjectIsSameAgeAndSizesMatch,
+			stat:     true,
+		},
+		{
+			name:     "object has no modification time",
+			dstSize:  10,
+			dstTime:  uploaded,
+			expected: nil,
+			stat:     true,
+		},
+		{
+			name:     "modification time can't be determined",
+			dstSize:  10,
+			dstTime:  uploaded,
+			statErr:  errors.New("access denied"),
+			expected: nil,
+			stat:     true,
+		},
+	}
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var stats int
+			strategy := &ModTimeStrategy{
+				Strategy: &SizeAndModificationStrategy{},
+				Stat: func(u *url.URL) (*storage.Object, error) {
+					stats++
+					if u != srcurl {
+						t.Errorf("expected stat of %v, got %v", srcurl, u)
+					}
+					return &storage.Object{URL: u, ModTime: tc.statTime, PreservedModTime: tc.preserved}, tc.statErr
+				},
+			}
+
+			src := &storage.Object{URL: srcurl, Size: 10, ModTime: timePtr(lastModified)}
+			dst := &storage.Object{URL: dsturl, Size: tc.dstSize, ModTime: timePtr(tc.dstTime)}
+
+			var got error
+			if reason := strategy.ShouldSync(src, dst); reason != nil {
+				got = reason.Err()
+			}
+			if got != tc.expected {
+				t.Fatalf("expected: %q(%T), got: %q(%T)", tc.expected, tc.expected, got, got)
+			}
+			if tc.stat != (stats > 0) {
+				t.Errorf("expected stat: %v, got %d stats", tc.stat, stats)
+			}
+		})
+	}
+}
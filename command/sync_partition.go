@@ -0,0 +1,346 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/urfave/cli/v2"
+
+	"github.com/peak/s5cmd/v2/log"
+	"github.com/peak/s5cmd/v2/storage"
+	"github.com/peak/s5cmd/v2/storage/url"
+	"github.com/peak/s5cmd/v2/strutil"
+)
+
+const (
+	partitionSucceeded = "succeeded"
+	partitionFailed    = "failed"
+	partitionSkipped   = "skipped"
+)
+
+// syncPartition is a prefix of the source which is synced independently of
+// the other prefixes by "sync --partition-by-prefix".
+type syncPartition struct {
+	// name is the prefix of the partition relative to the source, e.g.
+	// "tenant/". It is empty for the objects directly under the source.
+	name string
+	// shallow reports whether the partition consists of the objects directly
+	// under the prefix only, rather than all objects under the prefix.
+	shallow bool
+}
+
+// PartitionMessage is the structure for the result of a partition synced by
+// "sync --partition-by-prefix".
+type PartitionMessage struct {
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+	Status      string `json:"status"`
+	Errors      int64  `json:"errors"`
+}
+
+// String is the string representation of PartitionMessage.
+func (m PartitionMessage) String() string {
+	status := m.Status
+	if m.Status == partitionFailed {
+		status = fmt.Sprintf("%v with %v errors", m.Status, m.Errors)
+	}
+	return fmt.Sprintf("partition %v %v: %v", m.Source, m.Destination, status)
+}
+
+// JSON is the JSON representation of PartitionMessage.
+func (m PartitionMessage) JSON() string {
+	return strutil.JSON(struct {
+		Operation string `json:"operation"`
+		PartitionMessage
+	}{
+		Operation:        "partition",
+		PartitionMessage: m,
+	})
+}
+
+// PartitionSummaryMessage is the structure for the aggregated results of the
+// partitions synced by "sync --partition-by-prefix".
+type PartitionSummaryMessage struct {
+	Partitions int64 `json:"partitions"`
+	Succeeded  int64 `json:"succeeded"`
+	Failed     int64 `json:"failed"`
+	Skipped    int64 `json:"skipped"`
+	Errors     int64 `json:"errors"`
+}
+
+// add aggregates the result of a partition.
+func (m *PartitionSummaryMessage) add(result PartitionMessage) {
+	m.Partitions++
+	m.Errors += result.Errors
+	switch result.Status {
+	case partitionSucceeded:
+		m.Succeeded++
+	case partitionFailed:
+		m.Failed++
+	case partitionSkipped:
+		m.Skipped++
+	}
+}
+
+// String is the string representation of PartitionSummaryMessage.
+func (m PartitionSummaryMessage) String() string {
+	return fmt.Sprintf(
+		"%v partitions: %v succeeded, %v failed, %v skipped, %v errors",
+		m.Partitions, m.Succeeded, m.Failed, m.Skipped, m.Errors,
+	)
+}
+
+// JSON is the JSON representation of PartitionSummaryMessage.
+func (m PartitionSummaryMessage) JSON() string {
+	return strutil.JSON(struct {
+		Operation string `json:"operation"`
+		PartitionSummaryMessage
+	}{
+		Operation:               "partition-summary",
+		PartitionSummaryMessage: m,
+	})
+}
+
+// runPartitions discovers the prefixes of the source down to the partition
+// depth and syncs each of them with a separate sync, which has its own
+// listing, plan and errors. Once a partition fails, the partitions which are
+// not started yet are skipped unless continueOnPartitionFailure is set.
+func (s Sync) runPartitions(c *cli.Context, srcurl, dsturl *url.URL) error {
+	client, err := storage.NewClient(c.Context, srcurl, s.storageOpts)
+	if err != nil {
+		printError(s.fullCommand, s.op, err)
+		return err
+	}
+
+	partitions, err := s.discoverPartitions(c.Context, client, srcurl, "", s.partitionDepth)
+	if err != nil {
+		printError(s.fullCommand, s.op, err)
+		return err
+	}
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		failed    bool
+		summary   PartitionSummaryMessage
+		semaphore = make(chan bool, s.partitionConcurrency)
+	)
+
+	report := func(result PartitionMessage) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if result.Status == partitionFailed {
+			failed = true
+		}
+		summary.add(result)
+		log.Info(result)
+	}
+
+	for _, partition := range partitions {
+		semaphore <- true
+
+		sub := s.partition(srcurl, dsturl, partition)
+		result := PartitionMessage{
+			Source:      sub.src,
+			Destination: sub.dst,
+		}
+
+		mu.Lock()
+		skip := failed && !s.continueOnPartitionFailure
+		mu.Unlock()
+
+		if skip {
+			<-semaphore
+			result.Status = partitionSkipped
+			report(result)
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			err := sub.Run(c)
+			result.Status = partitionSucceeded
+			if err != nil {
+				result.Status = partitionFailed
+				result.Errors = errorCount(err)
+			}
+			report(result)
+		}()
+	}
+
+	wg.Wait()
+
+	log.Info(summary)
+
+	if summary.Failed > 0 && !s.continueOnPartitionFailure {
+		return fmt.Errorf("%v of %v partitions failed", summary.Failed, summary.Partitions)
+	}
+	return nil
+}
+
+// partition returns the sync of the given partition of the source.
+func (s Sync) partition(srcurl, dsturl *url.URL, partition syncPartition) Sync {
+	src := fmt.Sprintf("s3://%v/%v", srcurl.Bucket, url.EscapeGlob(srcurl.Prefix+partition.name))
+	if !partition.shallow {
+		src += "*"
+	}
+
+	name := partition.name
+	if dsturl.IsRemote() || runtime.GOOS != "windows" {
+		name = url.EscapeGlob(name)
+	}
+	dst := s.dst
+	if !strings.HasSuffix(dst, "/") {
+		dst += "/"
+	}
+
+	sub := s
+	sub.src = src
+	sub.dst = dst + name
+	sub.shallow = partition.shallow
+	// the partitions synced at the same time share the memory limit.
+	if s.partitionConcurrency > 0 {
+		sub.maxCompareMemory = s.maxCompareMemory / int64(s.partitionConcurrency)
+	}
+	sub.partitionByPrefix = false
+	// the buckets are verified once for all partitions.
+	sub.verifyBucket = false
+	return sub
+}
+
+// discoverPartitions lists the prefixes under the given prefix of the source
+// with a delimiter, and returns the partitions down to the given depth. The
+// objects directly under a prefix which is not a partition itself form a
+// shallow partition.
+func (s Sync) discoverPartitions(
+	ctx context.Context,
+	client storage.Storage,
+	srcurl *url.URL,
+	name string,
+	depth int,
+) ([]syncPartition, error) {
+	listurl, err := url.New(fmt.Sprintf("s3://%v/%v", srcurl.Bucket, url.EscapeGlob(srcurl.Prefix+name)))
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		prefixes   []string
+		hasObjects bool
+	)
+	for object := range client.List(ctx, listurl, false) {
+		if err := object.Err; err != nil {
+			if errors.Is(err, storage.ErrNoObjectFound) && name != "" {
+				continue
+			}
+			return nil, err
+		}
+
+		prefix := strings.TrimPrefix(object.URL.Path, srcurl.Prefix)
+		if !object.Type.IsDir() {
+			hasObjects = true
+		} else if prefix != name {
+			// skip the directory marker of the prefix itself.
+			prefixes = append(prefixes, prefix)
+		}
+	}
+
+	var partitions []syncPartition
+	// the objects only in destination are deleted by the shallow partition.
+	if hasObjects || s.delete {
+		partitions = append(partitions, syncPartition{name: name, shallow: true})
+	}
+
+	for _, prefix := range prefixes {
+		if depth <= 1 {
+			partitions = append(partitions, syncPartition{name: prefix})
+			continue
+		}
+
+		subpartitions, err := s.discoverPartitions(ctx, client, srcurl, prefix, depth-1)
+		if err != nil {
+			return nil, err
+		}
+		partitions = append(partitions, subpartitions...)
+	}
+	return partitions, nil
+}
+
+// errorCount returns the number of errors err consists of.
+func errorCount(err error) int64 {
+	if err == nil {
+		return 0
+	}
+
+	var summary *errorSummary
+	if errors.As(err, &summary) {
+		return summary.count()
+	}
+
+	var merr *multierror.Error
+	if errors.As(err, &merr) {
+		var count int64
+		for _, err := range merr.Errors {
+			count += errorCount(err)
+		}
+		return count
+	}
+	return 1
+}
+
+// isShallowPartitionObject reports whether the object is directly under the
+// prefix of a shallow partition, rather than under one of its sub-prefixes.
+func isShallowPartitionObject(object *storage.Object) bool {
+	return !strings.Contains(filepath.ToSlash(object.URL.Relative()), "/")
+}
+
+func validateSyncPartitionFlags(c *cli.Context) error {
+	if !c.Bool("partition-by-prefix") {
+		for _, name := range []string{"depth", "partition-concurrency", "continue-on-partition-failure"} {
+			if c.IsSet(name) {
+				return fmt.Errorf("%q flag requires %q flag", name, "partition-by-prefix")
+			}
+		}
+		return nil
+	}
+
+	if c.Int("depth") < 1 {
+		return fmt.Errorf("depth must be at least 1")
+	}
+
+	if c.Int("partition-concurrency") < 1 {
+		return fmt.Errorf("partition-concurrency must be at least 1")
+	}
+
+	if c.Bool("raw") {
+		return fmt.Errorf("it is not allowed to combine %q and %q flags", "partition-by-prefix", "raw")
+	}
+
+	if c.IsSet("compare-key-strip-prefix") {
+		return fmt.Errorf("it is not allowed to combine %q and %q flags", "partition-by-prefix", "compare-key-strip-prefix")
+	}
+
+	src := c.Args().Get(0)
+	srcurl, err := url.New(src)
+	if err != nil {
+		return err
+	}
+	prefixurl, err := url.New(strings.TrimSuffix(src, "*"))
+	if err != nil {
+		return err
+	}
+	if !srcurl.IsRemote() || !strings.HasSuffix(src, "/*") || prefixurl.IsWildcard() {
+		return fmt.Errorf("source of %q flag must be a bucket or a prefix followed by \"/*\", e.g. \"s3://bucket/prefix/*\"", "partition-by-prefix")
+	}
+	return nil
+}
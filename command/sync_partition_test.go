@@ -0,0 +1,146 @@
+package command
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/go-multierror"
+
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+func TestSyncPartition(t *testing.T) {
+	testcases := []struct {
+		name        string
+		src         string
+		dst         string
+		partition   syncPartition
+		expectedSrc string
+		expectedDst string
+	}{
+		{
+			name:        "prefix of bucket",
+			src:         "s3://bucket/*",
+			dst:         "s3://target/",
+			partition:   syncPartition{name: "tenant/"},
+			expectedSrc: "s3://bucket/tenant/*",
+			expectedDst: "s3://target/tenant/",
+		},
+		{
+			name:        "objects directly under bucket",
+			src:         "s3://bucket/*",
+			dst:         "s3://target/",
+			partition:   syncPartition{shallow: true},
+			expectedSrc: "s3://bucket/",
+			expectedDst: "s3://target/",
+		},
+		{
+			name:        "prefix of prefix, destination without trailing slash",
+			src:         "s3://bucket/data/*",
+			dst:         "s3://target/backup",
+			partition:   syncPartition{name: "tenant/2024/"},
+			expectedSrc: "s3://bucket/data/tenant/2024/*",
+			expectedDst: "s3://target/backup/tenant/2024/",
+		},
+		{
+			name:        "objects directly under prefix of prefix",
+			src:         "s3://bucket/data/*",
+			dst:         "s3://target/",
+			partition:   syncPartition{name: "tenant/", shallow: true},
+			expectedSrc: "s3://bucket/data/tenant/",
+			expectedDst: "s3://target/tenant/",
+		},
+		{
+			name:        "prefix with wildcard characters",
+			src:         "s3://bucket/*",
+			dst:         "s3://target/",
+			partition:   syncPartition{name: "a*b/"},
+			expectedSrc: `s3://bucket/a\*b/*`,
+			expectedDst: `s3://target/a\*b/`,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			srcurl, err := url.New(tc.src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			dsturl, err := url.New(tc.dst)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			s := Sync{src: tc.src, dst: tc.dst, partitionByPrefix: true, verifyBucket: true}
+			sub := s.partition(srcurl, dsturl, tc.partition)
+			if sub.src != tc.expectedSrc {
+				t.Errorf("expected source %q, got %q", tc.expectedSrc, sub.src)
+			}
+			if sub.dst != tc.expectedDst {
+				t.Errorf("expected destination %q, got %q", tc.expectedDst, sub.dst)
+			}
+			if sub.shallow != tc.partition.shallow || sub.partitionByPrefix || sub.verifyBucket {
+				t.Errorf("unexpected partition flags: %+v", sub)
+			}
+		})
+	}
+}
+
+func TestPartitionSummaryMessage(t *testing.T) {
+	var summary PartitionSummaryMessage
+	for _, result := range []PartitionMessage{
+		{Source: "s3://bucket/", Destination: "dir/", Status: partitionSucceeded},
+		{Source: "s3://bucket/a/*", Destination: "dir/a/", Status: partitionFailed, Errors: 2},
+		{Source: "s3://bucket/b/*", Destination: "dir/b/", Status: partitionSkipped},
+		{Source: "s3://bucket/c/*", Destination: "dir/c/", Status: partitionFailed, Errors: 1},
+	} {
+		summary.add(result)
+	}
+
+	expected := "4 partitions: 1 succeeded, 2 failed, 1 skipped, 3 errors"
+	if got := summary.String(); got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+
+	expected = `{"operation":"partition-summary","partitions":4,"succeeded":1,"failed":2,"skipped":1,"errors":3}`
+	if got := summary.JSON(); got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
+
+func TestErrorCount(t *testing.T) {
+	testcases := []struct {
+		name     string
+		err      error
+		expected int64
+	}{
+		{name: "nil", err: nil, expected: 0},
+		{name: "single error", err: fmt.Errorf("error"), expected: 1},
+		{
+			name: "nested multierror",
+			err: multierror.Append(
+				multierror.Append(nil, fmt.Errorf("first"), fmt.Errorf("second")),
+				fmt.Errorf("third"),
+			),
+			expected: 3,
+		},
+		{
+			name: "summaries",
+			err: multierror.Append(
+				newTestErrorSummary(fmt.Errorf("first"), fmt.Errorf("second")),
+				newTestErrorSummary(fmt.Errorf("third")),
+			),
+			expected: 3,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			if got := errorCount(tc.err); got != tc.expected {
+				t.Errorf("expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,215 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"github.com/peak/s5cmd/v2/log"
+	"github.com/peak/s5cmd/v2/storage"
+	"github.com/peak/s5cmd/v2/storage/url"
+	"github.com/peak/s5cmd/v2/strutil"
+)
+
+// the reasons of the operations planned by "sync --dry-run".
+const (
+	syncPlanOnlyInSource      = "only-in-source"
+	syncPlanOnlyInDestination = "only-in-destination"
+	syncPlanSizeDiffers       = "size-differs"
+	syncPlanContentDiffers    = "content-differs"
+	syncPlanNewer             = "newer"
+	syncPlanOlder             = "older"
+	syncPlanAlways            = "always"
+	syncPlanDiffers           = "differs"
+	syncPlanMetadataDiffers   = "metadata-differs"
+)
+
+// SyncPlanOperation is an operation on an object planned by "sync --dry-run".
+type SyncPlanOperation struct {
+	Action      string   `json:"action"`
+	Source      *url.URL `json:"source,omitempty"`
+	Destination *url.URL `json:"destination"`
+	Reason      string   `json:"reason"`
+}
+
+// SyncPlanMessage is the structure for a command planned by "sync --dry-run".
+type SyncPlanMessage struct {
+	Command    string
+	Operations []SyncPlanOperation
+}
+
+func newSyncPlanCopy(command string, srcurl, dsturl *url.URL, reason string) SyncPlanMessage {
+	return SyncPlanMessage{
+		Command: command,
+		Operations: []SyncPlanOperation{
+			{Action: "cp", Source: srcurl, Destination: dsturl, Reason: reason},
+		},
+	}
+}
+
+func newSyncPlanDelete(command string, dsturls []*url.URL) SyncPlanMessage {
+	msg := SyncPlanMessage{Command: command}
+	for _, dsturl := range dsturls {
+		msg.Operations = append(msg.Operations, SyncPlanOperation{
+			Action:      "rm",
+			Destination: dsturl,
+			Reason:      syncPlanOnlyInDestination,
+		})
+	}
+	return msg
+}
+
+// String is the string representation of SyncPlanMessage, which is the
+// command as it would be run.
+func (m SyncPlanMessage) String() string {
+	return m.Command
+}
+
+// JSON is the JSON representation of SyncPlanMessage. The objects of a command
+// are represented with a line each, e.g. the objects of a batch delete.
+func (m SyncPlanMessage) JSON() string {
+	lines := make([]string, 0, len(m.Operations))
+	for _, operation := range m.Operations {
+		lines = append(lines, strutil.JSON(struct {
+			Operation string `json:"operation"`
+			SyncPlanOperation
+		}{
+			Operation:         "sync-plan",
+			SyncPlanOperation: operation,
+		}))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// planReason tells why the object in both source and destination is planned
+// to be copied, as far as the sync strategy tells.
+func (s Sync) planReason(srcObj, dstObj *storage.Object) string {
+	// the metadata of the objects is only compared if they are the same
+	// otherwise.
+	if !metadataMatches(srcObj, dstObj) {
+		return syncPlanMetadataDiffers
+	}
+
+	switch s.syncStrategy {
+	case syncStrategyAlways:
+		return syncPlanAlways
+	case syncStrategyChecksum, syncStrategyEtag:
+		if srcObj.Size == dstObj.Size {
+			return syncPlanContentDiffers
+		}
+	}
+
+	if srcObj.Size != dstObj.Size {
+		return syncPlanSizeDiffers
+	}
+	if srcObj.ModTime != nil && dstObj.ModTime != nil {
+		switch {
+		case srcObj.ModTime.After(*dstObj.ModTime):
+			return syncPlanNewer
+		case srcObj.ModTime.Before(*dstObj.ModTime):
+			return syncPlanOlder
+		}
+	}
+	return syncPlanDiffers
+}
+
+// SyncPlanSummaryMessage is the structure for the number of the objects a sync
+// with --dry-run would upload, update and delete, and the number of bytes it
+// would transfer.
+type SyncPlanSummaryMessage struct {
+	Upload int64 `json:"upload"`
+	Update int64 `json:"update"`
+	Delete int64 `json:"delete"`
+	Bytes  int64 `json:"bytes"`
+}
+
+// String is the string representation of SyncPlanSummaryMessage.
+func (m SyncPlanSummaryMessage) String() string {
+	return fmt.Sprintf("dry-run: %d objects to upload, %d to update, %d to delete, %d bytes to transfer",
+		m.Upload, m.Update, m.Delete, m.Bytes)
+}
+
+// JSON is the JSON representation of SyncPlanSummaryMessage.
+func (m SyncPlanSummaryMessage) JSON() string {
+	return strutil.JSON(struct {
+		Operation string `json:"operation"`
+		SyncPlanSummaryMessage
+	}{
+		Operation:              "sync-plan-summary",
+		SyncPlanSummaryMessage: m,
+	})
+}
+
+// syncPlanSummary counts the objects planned to be copied and deleted by a
+// sync with --dry-run, global or the one of sync, or with --exit-code. The
+// objects only in source are uploaded, and the ones in both source and
+// destination are updated. It is shared by the partitions of a sync, so that
+// a single summary is printed for all of them.
+type syncPlanSummary struct {
+	upload int64
+	update int64
+	delete int64
+	bytes  int64
+	// printed is set if the summary is printed once the sync is completed,
+	// which is the case for the dry runs.
+	printed bool
+}
+
+// newSyncPlanSummary returns the summary of the planned changes if it is a dry
+// run or the changes tell the exit code, or nil otherwise.
+func newSyncPlanSummary(dryRun, exitCode bool) *syncPlanSummary {
+	if !dryRun && !exitCode {
+		return nil
+	}
+	return &syncPlanSummary{printed: dryRun}
+}
+
+// addUpload counts an object only in source of the given size. It is a no-op
+// if p is nil, as the other methods.
+func (p *syncPlanSummary) addUpload(size int64) {
+	if p == nil {
+		return
+	}
+	atomic.AddInt64(&p.upload, 1)
+	atomic.AddInt64(&p.bytes, size)
+}
+
+// addUpdate counts an object in both source and destination of the given
+// size.
+func (p *syncPlanSummary) addUpdate(size int64) {
+	if p == nil {
+		return
+	}
+	atomic.AddInt64(&p.update, 1)
+	atomic.AddInt64(&p.bytes, size)
+}
+
+// addDelete counts the given number of objects only in destination.
+func (p *syncPlanSummary) addDelete(n int) {
+	if p == nil {
+		return
+	}
+	atomic.AddInt64(&p.delete, int64(n))
+}
+
+// changed reports whether any object is planned to be copied or deleted.
+func (p *syncPlanSummary) changed() bool {
+	if p == nil {
+		return false
+	}
+	return atomic.LoadInt64(&p.upload)+atomic.LoadInt64(&p.update)+atomic.LoadInt64(&p.delete) > 0
+}
+
+// report prints the summary of a dry run to standard error, regardless of
+// the log level.
+func (p *syncPlanSummary) report() {
+	if p == nil || !p.printed {
+		return
+	}
+	log.Summary(SyncPlanSummaryMessage{
+		Upload: atomic.LoadInt64(&p.upload),
+		Update: atomic.LoadInt64(&p.update),
+		Delete: atomic.LoadInt64(&p.delete),
+		Bytes:  atomic.LoadInt64(&p.bytes),
+	})
+}
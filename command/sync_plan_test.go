@@ -0,0 +1,96 @@
+package command
+
+import (
+	"testing"
+	"time"
+
+	"github.com/peak/s5cmd/v2/storage"
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+func TestSyncPlanReason(t *testing.T) {
+	ft := time.Now()
+	timePtr := func(tt time.Time) *time.Time {
+		return &tt
+	}
+
+	testcases := []struct {
+		name     string
+		strategy string
+		src      *storage.Object
+		dst      *storage.Object
+		expected string
+	}{
+		{
+			name:     "source is newer, sizes are different",
+			src:      &storage.Object{ModTime: timePtr(ft.Add(time.Minute)), Size: 10},
+			dst:      &storage.Object{ModTime: timePtr(ft), Size: 5},
+			expected: syncPlanSizeDiffers,
+		},
+		{
+			name:     "source is newer, sizes are same",
+			src:      &storage.Object{ModTime: timePtr(ft.Add(time.Minute)), Size: 10},
+			dst:      &storage.Object{ModTime: timePtr(ft), Size: 10},
+			expected: syncPlanNewer,
+		},
+		{
+			name:     "destination is newer, sizes are same",
+			src:      &storage.Object{ModTime: timePtr(ft), Size: 10},
+			dst:      &storage.Object{ModTime: timePtr(ft.Add(time.Hour)), Size: 10},
+			expected: syncPlanOlder,
+		},
+		{
+			name:     "same age, sizes are same",
+			src:      &storage.Object{ModTime: timePtr(ft), Size: 10},
+			dst:      &storage.Object{ModTime: timePtr(ft), Size: 10},
+			expected: syncPlanDiffers,
+		},
+		{
+			name:     "checksum strategy, sizes are same",
+			strategy: syncStrategyChecksum,
+			src:      &storage.Object{ModTime: timePtr(ft.Add(time.Minute)), Size: 10},
+			dst:      &storage.Object{ModTime: timePtr(ft), Size: 10},
+			expected: syncPlanContentDiffers,
+		},
+		{
+			name:     "checksum strategy, sizes are different",
+			strategy: syncStrategyChecksum,
+			src:      &storage.Object{ModTime: timePtr(ft), Size: 10},
+			dst:      &storage.Object{ModTime: timePtr(ft), Size: 5},
+			expected: syncPlanSizeDiffers,
+		},
+		{
+			name:     "always strategy",
+			strategy: syncStrategyAlways,
+			src:      &storage.Object{ModTime: timePtr(ft), Size: 10},
+			dst:      &storage.Object{ModTime: timePtr(ft), Size: 10},
+			expected: syncPlanAlways,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			s := Sync{syncStrategy: tc.strategy}
+			if got := s.planReason(tc.src, tc.dst); got != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestSyncPlanMessageJSON(t *testing.T) {
+	first, _ := url.New("s3://bucket/a")
+	second, _ := url.New("s3://bucket/b")
+
+	msg := newSyncPlanDelete(`rm "s3://bucket/a" "s3://bucket/b"`, []*url.URL{first, second})
+
+	expected := `{"operation":"sync-plan","action":"rm","destination":"s3://bucket/a","reason":"only-in-destination"}` + "\n" +
+		`{"operation":"sync-plan","action":"rm","destination":"s3://bucket/b","reason":"only-in-destination"}`
+	if got := msg.JSON(); got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+	if got := msg.String(); got != msg.Command {
+		t.Errorf("expected %q, got %q", msg.Command, got)
+	}
+}
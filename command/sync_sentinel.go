@@ -0,0 +1,120 @@
+package command
+
+import (
+	"fmt"
+	"path"
+	"sync/atomic"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/peak/s5cmd/v2/log"
+	"github.com/peak/s5cmd/v2/storage"
+	"github.com/peak/s5cmd/v2/storage/url"
+	"github.com/peak/s5cmd/v2/strutil"
+)
+
+const keepSentinelsFlagName = "keep-sentinels"
+
+// KeptSentinelsMessage is the structure for the number of the objects only in
+// destination which are not deleted since they match --keep-sentinels.
+type KeptSentinelsMessage struct {
+	Patterns []string `json:"patterns"`
+	Kept     int64    `json:"kept"`
+}
+
+// String is the string representation of KeptSentinelsMessage.
+func (m KeptSentinelsMessage) String() string {
+	return fmt.Sprintf("kept %d sentinel objects matching %v", m.Kept, m.Patterns)
+}
+
+// JSON is the JSON representation of KeptSentinelsMessage.
+func (m KeptSentinelsMessage) JSON() string {
+	return strutil.JSON(struct {
+		Operation string `json:"operation"`
+		KeptSentinelsMessage
+	}{
+		Operation:            "keep-sentinels",
+		KeptSentinelsMessage: m,
+	})
+}
+
+// sentinelKeeper keeps the objects only in destination whose names match one
+// of its patterns, e.g. ".keep", so that the prefixes which must always have
+// an object are not emptied by "sync --delete". The patterns are matched
+// against the names of the objects, regardless of their depth. It is shared by
+// the partitions of a sync, so that the kept objects are counted once for all
+// of them.
+type sentinelKeeper struct {
+	patterns []string
+	kept     int64
+}
+
+// newSentinelKeeper returns the sentinel keeper of the patterns, or nil if
+// there are no patterns.
+func newSentinelKeeper(patterns []string) *sentinelKeeper {
+	if len(patterns) == 0 {
+		return nil
+	}
+	return &sentinelKeeper{patterns: patterns}
+}
+
+// isSentinel reports whether the name of the object at u matches one of the
+// patterns.
+func (k *sentinelKeeper) isSentinel(u *url.URL) bool {
+	name := u.Base()
+	for _, pattern := range k.patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// filter returns the objects only in destination which are not sentinels.
+// It returns onlyDest as is if k is nil.
+func (k *sentinelKeeper) filter(onlyDest chan *storage.Object) chan *storage.Object {
+	if k == nil {
+		return onlyDest
+	}
+
+	filtered := make(chan *storage.Object)
+	go func() {
+		defer close(filtered)
+		for object := range onlyDest {
+			if k.isSentinel(object.URL) {
+				atomic.AddInt64(&k.kept, 1)
+				continue
+			}
+			filtered <- object
+		}
+	}()
+	return filtered
+}
+
+// report prints the number of the kept sentinel objects. It is a no-op if k
+// is nil.
+func (k *sentinelKeeper) report() {
+	if k == nil {
+		return
+	}
+	log.Info(KeptSentinelsMessage{
+		Patterns: k.patterns,
+		Kept:     atomic.LoadInt64(&k.kept),
+	})
+}
+
+func validateKeepSentinels(c *cli.Context) error {
+	patterns := c.StringSlice(keepSentinelsFlagName)
+	if len(patterns) == 0 {
+		return nil
+	}
+	if !c.Bool("delete") {
+		return fmt.Errorf("%q flag requires %q flag", keepSentinelsFlagName, "delete")
+	}
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid %v pattern %q: %w", keepSentinelsFlagName, pattern, err)
+		}
+	}
+	return nil
+}
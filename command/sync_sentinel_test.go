@@ -0,0 +1,47 @@
+package command
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/peak/s5cmd/v2/storage"
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+func TestSentinelKeeperFilter(t *testing.T) {
+	keeper := newSentinelKeeper([]string{".keep", "_SUCCESS*"})
+
+	onlyDest := make(chan *storage.Object)
+	go func() {
+		defer close(onlyDest)
+		for _, key := range []string{
+			"s3://bucket/.keep",
+			"s3://bucket/a.txt",
+			"s3://bucket/dir/.keep",
+			"s3://bucket/dir/nested/_SUCCESS.json",
+			"s3://bucket/dir/not.keep",
+		} {
+			u, err := url.New(key)
+			assert.NilError(t, err)
+			onlyDest <- &storage.Object{URL: u}
+		}
+	}()
+
+	var deleted []string
+	for object := range keeper.filter(onlyDest) {
+		deleted = append(deleted, object.URL.String())
+	}
+
+	assert.DeepEqual(t, []string{"s3://bucket/a.txt", "s3://bucket/dir/not.keep"}, deleted)
+	assert.Equal(t, int64(3), keeper.kept)
+}
+
+func TestSentinelKeeperNil(t *testing.T) {
+	keeper := newSentinelKeeper(nil)
+	assert.Assert(t, keeper == nil)
+
+	onlyDest := make(chan *storage.Object)
+	assert.Equal(t, onlyDest, keeper.filter(onlyDest))
+	keeper.report()
+}
@@ -0,0 +1,100 @@
+package command
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/lanrat/extsort"
+	"github.com/urfave/cli/v2"
+
+	"github.com/peak/s5cmd/v2/strutil"
+)
+
+const (
+	maxCompareMemoryFlagName = "max-compare-memory"
+
+	// extsortNumWorkers is the number of chunks sorted at the same time by a
+	// sorter.
+	extsortNumWorkers = 2
+	// extsortChunksInMemory is the number of chunks a sorter keeps in memory
+	// at most: the one being filled, the one waiting to be sorted, the ones
+	// being sorted, the one waiting to be saved and the one being saved.
+	extsortChunksInMemory = extsortNumWorkers + 4
+	// extsortMinChunkSize is the number of objects of the smallest chunk, not
+	// to spill a temporary file for every few objects with a small budget.
+	extsortMinChunkSize = 1_000
+
+	// compareObjectMemory is the estimated size of a listed object in memory,
+	// with its URL and the copies of its key.
+	compareObjectMemory = 1 << 10
+)
+
+func newMaxCompareMemoryFlag() cli.Flag {
+	return &cli.StringFlag{
+		Name:  maxCompareMemoryFlagName,
+		Usage: "limit the memory the source and destination objects are sorted in while they are compared, e.g. 8GB; the objects beyond the limit are spilled to sorted temporary files and merged",
+	}
+}
+
+// newExtsortConfig returns the configuration of the sorters of the objects,
+// which are run at the same time. If maxMemory is greater than zero, the
+// chunks of the sorters are sized to keep them within maxMemory in total.
+// Otherwise, the chunks are extsortChunkSize objects.
+//
+// Each chunk is sorted in memory and saved to a temporary file, and the files
+// are merged with a read buffer for each, so a larger chunk trades memory
+// for fewer files to merge.
+func newExtsortConfig(maxMemory int64, sorters int) *extsort.Config {
+	chunkSize := int64(extsortChunkSize)
+	if maxMemory > 0 {
+		chunkSize = maxMemory / (int64(sorters) * extsortChunksInMemory * compareObjectMemory)
+		if chunkSize < extsortMinChunkSize {
+			chunkSize = extsortMinChunkSize
+		}
+		if chunkSize > math.MaxInt32 {
+			chunkSize = math.MaxInt32
+		}
+	}
+
+	return &extsort.Config{
+		ChunkSize:  int(chunkSize),
+		NumWorkers: extsortNumWorkers,
+		// the chunks are handed over one by one, otherwise the buffers of
+		// the chunks waiting to be sorted or saved could hold many of them
+		// while the temporary files are written.
+		ChanBuffSize:       1,
+		SortedChanBuffSize: extsortChannelBufferSize,
+	}
+}
+
+// sorters returns the number of the sorters run at the same time: the ones of
+// the source and destination objects, and the one of the planned changes with
+// --diff.
+func (s Sync) sorters() int {
+	if s.diff {
+		return 3
+	}
+	return 2
+}
+
+// parseMaxCompareMemory returns the memory limit of the sorters, or 0 if it is
+// not set. The flag is validated before.
+func parseMaxCompareMemory(c *cli.Context) int64 {
+	size, _ := strutil.ParseBytes(c.String(maxCompareMemoryFlagName))
+	return size
+}
+
+func validateMaxCompareMemory(c *cli.Context) error {
+	if !c.IsSet(maxCompareMemoryFlagName) {
+		return nil
+	}
+
+	size, err := strutil.ParseBytes(c.String(maxCompareMemoryFlagName))
+	if err != nil {
+		return fmt.Errorf("invalid %v: %w", maxCompareMemoryFlagName, err)
+	}
+	if size <= 0 {
+		return fmt.Errorf("%q flag must be greater than zero", maxCompareMemoryFlagName)
+	}
+	return nil
+}
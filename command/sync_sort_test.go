@@ -0,0 +1,137 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/lanrat/extsort"
+
+	"github.com/peak/s5cmd/v2/storage"
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+func TestNewExtsortConfig(t *testing.T) {
+	testcases := []struct {
+		name      string
+		maxMemory int64
+		sorters   int
+		expected  int
+	}{
+		{name: "no limit", maxMemory: 0, sorters: 2, expected: extsortChunkSize},
+		{name: "8GB", maxMemory: 8 << 30, sorters: 2, expected: 699050},
+		{name: "8GB with diff", maxMemory: 8 << 30, sorters: 3, expected: 466033},
+		{name: "below the smallest chunk", maxMemory: 1 << 20, sorters: 2, expected: extsortMinChunkSize},
+		{name: "above the largest chunk", maxMemory: 1 << 62, sorters: 2, expected: 1<<31 - 1},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			config := newExtsortConfig(tc.maxMemory, tc.sorters)
+			if config.ChunkSize != tc.expected {
+				t.Errorf("expected chunk size %v, got %v", tc.expected, config.ChunkSize)
+			}
+			if config.ChanBuffSize != 1 {
+				t.Errorf("expected 1 chunk to be buffered, got %v", config.ChanBuffSize)
+			}
+		})
+	}
+}
+
+// syntheticListing sends n objects under the prefix in random order, as they
+// are listed from a large bucket.
+func syntheticListing(t testing.TB, n int, objects chan<- extsort.SortType) {
+	defer close(objects)
+
+	base, err := url.New("s3://bucket/prefix/*")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	modTime := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	for _, i := range rand.New(rand.NewSource(1)).Perm(n) {
+		u, err := url.New(fmt.Sprintf("s3://bucket/prefix/%02d/key-%09d", i%97, i))
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		u.SetRelative(base)
+		objects <- storage.Object{URL: u, ModTime: &modTime, Size: int64(i)}
+	}
+}
+
+// TestSyncSortObjectsSpill sorts a synthetic listing many times larger than
+// the chunks of a small memory limit, so that the objects are spilled to
+// sorted temporary files and merged.
+func TestSyncSortObjectsSpill(t *testing.T) {
+	n := 50_000
+	if testing.Short() {
+		n = 10_000
+	}
+
+	config := newExtsortConfig(1<<20, 2)
+	config.TempFilesDir = t.TempDir()
+	if n < 10*config.ChunkSize {
+		t.Fatalf("the listing of %v objects must be spilled in chunks of %v objects", n, config.ChunkSize)
+	}
+
+	objects := make(chan extsort.SortType, extsortChannelBufferSize)
+	go syntheticListing(t, n, objects)
+
+	sorted := make(chan *storage.Object, extsortChannelBufferSize)
+	go func() {
+		defer close(sorted)
+		Sync{}.sortObjects(context.Background(), objects, config, sorted)
+	}()
+
+	var (
+		count int
+		prev  string
+	)
+	for object := range sorted {
+		if key := object.URL.Relative(); key <= prev {
+			t.Fatalf("%q is sorted after %q", key, prev)
+		} else {
+			prev = key
+		}
+		if expected := fmt.Sprintf("%02d/key-%09d", object.Size%97, object.Size); object.URL.Relative() != expected {
+			t.Fatalf("expected %q, got %q", expected, object.URL.Relative())
+		}
+		count++
+	}
+
+	if count != n {
+		t.Errorf("expected %v objects, got %v", n, count)
+	}
+}
+
+func TestDiffMessageToBytes(t *testing.T) {
+	src, err := url.New("s3://bucket/prefix/a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst, err := url.New("folder/a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := []DiffMessage{
+		{Change: DiffNew, Source: src, Destination: dst, Reason: "only in source"},
+		{Change: DiffDelete, Destination: dst, Reason: "only in destination"},
+	}
+
+	for _, tc := range testcases {
+		got := diffMessageFromBytes(tc.ToBytes()).(DiffMessage)
+		if diff := cmp.Diff(tc.String(), got.String()); diff != "" {
+			t.Errorf("(-want +got):\n%v", diff)
+		}
+		if diff := cmp.Diff(tc.JSON(), got.JSON()); diff != "" {
+			t.Errorf("(-want +got):\n%v", diff)
+		}
+	}
+}
@@ -1,30 +1,273 @@
 package command
 
 import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
 	errorpkg "github.com/peak/s5cmd/v2/error"
 	"github.com/peak/s5cmd/v2/storage"
 )
 
 // SyncStrategy is the interface to make decision whether given source object should be synced
-// to destination object
+// to destination object. A nil SkipReason means the object should be synced.
 type SyncStrategy interface {
-	ShouldSync(srcObject, dstObject *storage.Object) error
+	ShouldSync(srcObject, dstObject *storage.Object) *SkipReason
+}
+
+const (
+	syncStrategySize      = "size"
+	syncStrategySizeMtime = "size-mtime"
+	syncStrategyChecksum  = "checksum"
+	syncStrategyEtag      = "etag"
+	syncStrategyAlways    = "always"
+)
+
+// StrategyOptions holds the options of the sync strategies which compare
+// modification times.
+type StrategyOptions struct {
+	// PreferSource overwrites the destination objects which are newer than
+	// the source objects.
+	PreferSource bool
+	// SkewTolerance is the duration that the modification times within are
+	// considered equal.
+	SkewTolerance time.Duration
+	// OpaqueDestinationEtags indicates that the ETags of the destination
+	// objects are not the MD5 checksums of their contents, e.g. because they
+	// are encrypted with KMS keys. The strategies which compare ETags compare
+	// sizes and modification times instead.
+	OpaqueDestinationEtags bool
+	// Transformed indicates that the objects are transformed while they are
+	// copied, e.g. with --transform-exec, so the sizes of the destination
+	// objects are not the ones of the source objects. The strategies which
+	// compare sizes and modification times compare modification times only.
+	Transformed bool
+	// OnMultipartEtag is called for the objects whose ETags are the ones of
+	// multipart uploads before the strategies which compare checksums compare
+	// them with their fallback strategies instead.
+	OnMultipartEtag func(obj *storage.Object)
+	// PartSize and MultipartThreshold are the ones the files are uploaded
+	// with. The strategies which compare checksums compute the multipart
+	// ETags of the files with them.
+	PartSize           int64
+	MultipartThreshold int64
 }
 
-func NewStrategy(sizeOnly bool) SyncStrategy {
-	if sizeOnly {
-		return &SizeOnlyStrategy{}
-	} else {
-		return &SizeAndModificationStrategy{}
+// syncStrategies are the sync strategies selectable by name, in the order
+// they are listed in the help of sync command.
+var syncStrategies = []struct {
+	name string
+	new  func(opts StrategyOptions) SyncStrategy
+}{
+	{
+		name: syncStrategySize,
+		new: func(StrategyOptions) SyncStrategy {
+			return &SizeOnlyStrategy{}
+		},
+	},
+	{
+		name: syncStrategySizeMtime,
+		new:  newSizeAndModificationStrategy,
+	},
+	{
+		name: syncStrategyChecksum,
+		new: func(opts StrategyOptions) SyncStrategy {
+			return &ChecksumStrategy{
+				Fallback:               newSizeAndModificationStrategy(opts),
+				OpaqueDestinationEtags: opts.OpaqueDestinationEtags,
+				OnMultipartEtag:        opts.OnMultipartEtag,
+				PartSize:               opts.PartSize,
+				MultipartThreshold:     opts.MultipartThreshold,
+			}
+		},
+	},
+	{
+		name: syncStrategyEtag,
+		new: func(opts StrategyOptions) SyncStrategy {
+			if opts.OpaqueDestinationEtags {
+				return &EtagStrategy{Fallback: newSizeAndModificationStrategy(opts)}
+			}
+			return &EtagStrategy{}
+		},
+	},
+	{
+		name: syncStrategyAlways,
+		new: func(StrategyOptions) SyncStrategy {
+			return &AlwaysStrategy{}
+		},
+	},
+}
+
+// syncStrategyNames returns the names of the sync strategies.
+func syncStrategyNames() []string {
+	names := make([]string, 0, len(syncStrategies))
+	for _, strategy := range syncStrategies {
+		names = append(names, strategy.name)
+	}
+	return names
+}
+
+// NewStrategy creates the sync strategy with the given name. It returns nil
+// if there is no such strategy.
+func NewStrategy(name string, opts StrategyOptions) SyncStrategy {
+	for _, strategy := range syncStrategies {
+		if strategy.name == name {
+			return strategy.new(opts)
+		}
+	}
+	return nil
+}
+
+func newSizeAndModificationStrategy(opts StrategyOptions) SyncStrategy {
+	if opts.Transformed {
+		return &ModificationOnlyStrategy{SkewTolerance: opts.SkewTolerance}
+	}
+	if opts.PreferSource {
+		return &PreferSourceStrategy{SkewTolerance: opts.SkewTolerance}
+	}
+	return &SizeAndModificationStrategy{SkewTolerance: opts.SkewTolerance}
+}
+
+// SkipReasonCode identifies why a sync strategy decided not to sync an object.
+type SkipReasonCode int
+
+const (
+	// SkipSizesMatch indicates the sizes of source and destination match.
+	SkipSizesMatch SkipReasonCode = iota + 1
+	// SkipNewerAndSizesMatch indicates the destination is newer or same age
+	// and the sizes of source and destination match.
+	SkipNewerAndSizesMatch
+	// SkipSameAgeAndSizesMatch indicates the modification times and the sizes
+	// of source and destination match.
+	SkipSameAgeAndSizesMatch
+	// SkipChecksumsMatch indicates the checksums of the contents of source
+	// and destination match.
+	SkipChecksumsMatch
+	// SkipEtagsMatch indicates the ETags of source and destination match.
+	SkipEtagsMatch
+	// SkipCompareExecEquivalent indicates the compare command given with
+	// --compare-exec finds source and destination equivalent.
+	SkipCompareExecEquivalent
+	// SkipNewer indicates the destination is newer or same age.
+	SkipNewer
+)
+
+// String returns the string representation of SkipReasonCode.
+func (c SkipReasonCode) String() string {
+	switch c {
+	case SkipSizesMatch:
+		return "size_match"
+	case SkipNewerAndSizesMatch:
+		return "newer_and_size_match"
+	case SkipSameAgeAndSizesMatch:
+		return "same_age_and_size_match"
+	case SkipChecksumsMatch:
+		return "checksum_match"
+	case SkipEtagsMatch:
+		return "etag_match"
+	case SkipCompareExecEquivalent:
+		return "compare_exec_equivalent"
+	case SkipNewer:
+		return "newer"
+	}
+	return "unknown"
+}
+
+// MarshalJSON returns the stringer of SkipReasonCode as a marshalled json.
+func (c SkipReasonCode) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.String())
+}
+
+// ComparedObject holds the attributes of an object that are compared by a
+// sync strategy.
+type ComparedObject struct {
+	Size     int64      `json:"size"`
+	ModTime  *time.Time `json:"last_modified,omitempty"`
+	Etag     string     `json:"etag,omitempty"`
+	Checksum string     `json:"checksum,omitempty"`
+}
+
+func newComparedObject(obj *storage.Object) ComparedObject {
+	return ComparedObject{
+		Size:    obj.Size,
+		ModTime: obj.ModTime,
+		Etag:    obj.Etag,
+	}
+}
+
+// String returns the string representation of ComparedObject.
+func (o ComparedObject) String() string {
+	parts := []string{fmt.Sprintf("size=%d", o.Size)}
+	if o.ModTime != nil {
+		parts = append(parts, fmt.Sprintf("mtime=%v", o.ModTime.UTC().Format(time.RFC3339)))
+	}
+	if o.Etag != "" {
+		parts = append(parts, fmt.Sprintf("etag=%v", o.Etag))
+	}
+	if o.Checksum != "" {
+		parts = append(parts, fmt.Sprintf("md5=%v", o.Checksum))
 	}
+	return strings.Join(parts, " ")
+}
+
+// SkipReason describes why a sync strategy decided not to sync an object,
+// along with the values it compared.
+type SkipReason struct {
+	Code        SkipReasonCode `json:"reason"`
+	Source      ComparedObject `json:"source"`
+	Destination ComparedObject `json:"destination"`
+}
+
+func newSkipReason(code SkipReasonCode, srcObj, dstObj *storage.Object) *SkipReason {
+	return &SkipReason{
+		Code:        code,
+		Source:      newComparedObject(srcObj),
+		Destination: newComparedObject(dstObj),
+	}
+}
+
+// Err returns the warning error corresponding to the reason code.
+func (r *SkipReason) Err() error {
+	switch r.Code {
+	case SkipSizesMatch:
+		return errorpkg.ErrObjectSizesMatch
+	case SkipNewerAndSizesMatch:
+		return errorpkg.ErrObjectIsNewerAndSizesMatch
+	case SkipSameAgeAndSizesMatch:
+		return errorpkg.ErrObjectIsSameAgeAndSizesMatch
+	case SkipChecksumsMatch:
+		return errorpkg.ErrObjectChecksumsMatch
+	case SkipEtagsMatch:
+		return errorpkg.ErrObjectEtagsMatch
+	case SkipCompareExecEquivalent:
+		return errorpkg.ErrObjectsEquivalent
+	case SkipNewer:
+		return errorpkg.ErrObjectIsNewer
+	}
+	return fmt.Errorf("unknown skip reason %d", r.Code)
+}
+
+// Error implements the error interface. It includes the compared values.
+func (r *SkipReason) Error() string {
+	return fmt.Sprintf("%v (source: %v, destination: %v)", r.Err(), r.Source, r.Destination)
+}
+
+// Unwrap returns the warning error corresponding to the reason code.
+func (r *SkipReason) Unwrap() error {
+	return r.Err()
 }
 
 // SizeOnlyStrategy determines to sync based on objects' file sizes.
 type SizeOnlyStrategy struct{}
 
-func (s *SizeOnlyStrategy) ShouldSync(srcObj, dstObj *storage.Object) error {
+func (s *SizeOnlyStrategy) ShouldSync(srcObj, dstObj *storage.Object) *SkipReason {
 	if srcObj.Size == dstObj.Size {
-		return errorpkg.ErrObjectSizesMatch
+		return newSkipReason(SkipSizesMatch, srcObj, dstObj)
 	}
 	return nil
 }
@@ -36,11 +279,16 @@ func (s *SizeOnlyStrategy) ShouldSync(srcObj, dstObj *storage.Object) error {
 //	time: src > dst        size: src == dst    should sync: yes
 //	time: src <= dst       size: src != dst    should sync: yes
 //	time: src <= dst       size: src == dst    should sync: no
-type SizeAndModificationStrategy struct{}
+//
+// Source is considered newer only if it is newer than destination by more
+// than SkewTolerance.
+type SizeAndModificationStrategy struct {
+	SkewTolerance time.Duration
+}
 
-func (sm *SizeAndModificationStrategy) ShouldSync(srcObj, dstObj *storage.Object) error {
+func (sm *SizeAndModificationStrategy) ShouldSync(srcObj, dstObj *storage.Object) *SkipReason {
 	srcMod, dstMod := srcObj.ModTime, dstObj.ModTime
-	if srcMod.After(*dstMod) {
+	if srcMod.Sub(*dstMod) > sm.SkewTolerance {
 		return nil
 	}
 
@@ -48,5 +296,233 @@ func (sm *SizeAndModificationStrategy) ShouldSync(srcObj, dstObj *storage.Object
 		return nil
 	}
 
-	return errorpkg.ErrObjectIsNewerAndSizesMatch
+	return newSkipReason(SkipNewerAndSizesMatch, srcObj, dstObj)
+}
+
+// ModificationOnlyStrategy determines to sync based on objects' modification
+// times only, for the objects whose sizes differ since they are transformed
+// while they are copied;
+//
+//	time: src > dst        should sync: yes
+//	time: src <= dst       should sync: no
+//
+// Source is considered newer only if it is newer than destination by more
+// than SkewTolerance.
+type ModificationOnlyStrategy struct {
+	SkewTolerance time.Duration
+}
+
+func (m *ModificationOnlyStrategy) ShouldSync(srcObj, dstObj *storage.Object) *SkipReason {
+	if srcObj.ModTime.Sub(*dstObj.ModTime) > m.SkewTolerance {
+		return nil
+	}
+	return newSkipReason(SkipNewer, srcObj, dstObj)
+}
+
+// PreferSourceStrategy determines to sync based on objects' both sizes and
+// modification times, regardless of which object is newer. It overwrites a
+// destination that is newer than the source, e.g. one written by a bad writer;
+//
+//	time: src != dst       size: src != dst    should sync: yes
+//	time: src != dst       size: src == dst    should sync: yes
+//	time: src == dst       size: src != dst    should sync: yes
+//	time: src == dst       size: src == dst    should sync: no
+//
+// Modification times within SkewTolerance of each other are considered equal.
+type PreferSourceStrategy struct {
+	SkewTolerance time.Duration
+}
+
+func (ps *PreferSourceStrategy) ShouldSync(srcObj, dstObj *storage.Object) *SkipReason {
+	diff := srcObj.ModTime.Sub(*dstObj.ModTime)
+	if diff < 0 {
+		diff = -diff
+	}
+
+	if diff > ps.SkewTolerance {
+		return nil
+	}
+
+	if srcObj.Size != dstObj.Size {
+		return nil
+	}
+
+	return newSkipReason(SkipSameAgeAndSizesMatch, srcObj, dstObj)
+}
+
+// ChecksumStrategy determines to sync based on the MD5 checksums of objects'
+// contents. The checksums of the local files are computed, and the ETags of
+// the remote objects are used as their checksums. The ETags of the objects
+// uploaded in multiple parts are the checksums of the checksums of their parts,
+// so they are compared with the ETags the local files would have if they were
+// uploaded with PartSize and MultipartThreshold, computed by streaming the
+// files. The objects whose checksums can't be determined, such as the ones
+// uploaded in a different number of parts, are compared with the Fallback
+// strategy, and OnMultipartEtag is called for the ones uploaded in multiple
+// parts if it is set. If OpaqueDestinationEtags is set, all objects are
+// compared with the Fallback strategy.
+type ChecksumStrategy struct {
+	Fallback               SyncStrategy
+	OpaqueDestinationEtags bool
+	OnMultipartEtag        func(obj *storage.Object)
+	PartSize               int64
+	MultipartThreshold     int64
+}
+
+func (cs *ChecksumStrategy) ShouldSync(srcObj, dstObj *storage.Object) *SkipReason {
+	// the contents differ if their sizes differ, no need to read them.
+	if srcObj.Size != dstObj.Size {
+		return nil
+	}
+
+	if cs.OpaqueDestinationEtags {
+		return cs.Fallback.ShouldSync(srcObj, dstObj)
+	}
+
+	srcSum, ok := cs.checksum(srcObj, dstObj)
+	if !ok {
+		return cs.Fallback.ShouldSync(srcObj, dstObj)
+	}
+	dstSum, ok := cs.checksum(dstObj, srcObj)
+	if !ok {
+		return cs.Fallback.ShouldSync(srcObj, dstObj)
+	}
+
+	if srcSum != dstSum {
+		return nil
+	}
+
+	reason := newSkipReason(SkipChecksumsMatch, srcObj, dstObj)
+	reason.Source.Checksum = srcSum
+	reason.Destination.Checksum = dstSum
+	return reason
+}
+
+// checksum returns the checksum of the object's content to compare with the
+// one of other. The checksum of a local file compared with a remote object
+// uploaded in multiple parts is its multipart ETag. It calls OnMultipartEtag
+// if the checksum can't be determined since one of the objects is uploaded in
+// multiple parts.
+func (cs *ChecksumStrategy) checksum(obj, other *storage.Object) (string, bool) {
+	if obj.URL == nil || other.URL == nil {
+		return "", false
+	}
+
+	if obj.URL.IsRemote() {
+		parts, multipart := obj.MultipartEtagParts()
+		if !multipart {
+			return objectChecksum(obj)
+		}
+		if other.URL.IsRemote() || !cs.computesMultipartEtag(other, parts) {
+			cs.onMultipartEtag(obj)
+			return "", false
+		}
+		return strings.ToLower(obj.Etag), true
+	}
+
+	if parts, multipart := other.MultipartEtagParts(); multipart && other.URL.IsRemote() {
+		if !cs.computesMultipartEtag(obj, parts) {
+			cs.onMultipartEtag(other)
+			return "", false
+		}
+		return cs.multipartEtag(obj)
+	}
+	return objectChecksum(obj)
+}
+
+// computesMultipartEtag reports whether the multipart ETag of the local file
+// is computed to compare with an ETag of the given number of parts. The files
+// which are uploaded in a different number of parts would have a different
+// ETag even if their contents are the same.
+func (cs *ChecksumStrategy) computesMultipartEtag(local *storage.Object, parts int) bool {
+	return cs.PartSize > 0 && cs.uploadPartCount(local.Size) == parts
+}
+
+func (cs *ChecksumStrategy) onMultipartEtag(obj *storage.Object) {
+	if cs.OnMultipartEtag != nil {
+		cs.OnMultipartEtag(obj)
+	}
+}
+
+// uploadPartSize returns the part size a file of the given size is uploaded
+// with.
+func (cs *ChecksumStrategy) uploadPartSize(size int64) int64 {
+	return uploadPartSize(size, cs.PartSize, cs.MultipartThreshold)
+}
+
+// uploadPartCount returns the number of parts a file of the given size is
+// uploaded in.
+func (cs *ChecksumStrategy) uploadPartCount(size int64) int {
+	return storage.UploadPartCount(size, cs.uploadPartSize(size))
+}
+
+// multipartEtag returns the multipart ETag of the local file, streaming its
+// content. It reports false if it can't be read.
+func (cs *ChecksumStrategy) multipartEtag(obj *storage.Object) (string, bool) {
+	f, err := os.Open(obj.URL.Absolute())
+	if err != nil {
+		return "", false
+	}
+	defer f.Close()
+
+	etag, err := storage.MultipartEtag(f, obj.Size, cs.uploadPartSize(obj.Size))
+	if err != nil {
+		return "", false
+	}
+	return etag, true
+}
+
+// objectChecksum returns the hex encoded MD5 checksum of the object's
+// content. It reports false if the checksum can't be determined.
+func objectChecksum(obj *storage.Object) (string, bool) {
+	if obj.URL == nil {
+		return "", false
+	}
+
+	if obj.URL.IsRemote() {
+		// the ETags of the objects uploaded in multiple parts are not MD5
+		// checksums of their contents, and have a "-<number of parts>"
+		// suffix.
+		if obj.Etag == "" || strings.Contains(obj.Etag, "-") {
+			return "", false
+		}
+		return strings.ToLower(obj.Etag), true
+	}
+
+	f, err := os.Open(obj.URL.Absolute())
+	if err != nil {
+		return "", false
+	}
+	defer f.Close()
+
+	h := md5.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", false
+	}
+	return hex.EncodeToString(h.Sum(nil)), true
+}
+
+// EtagStrategy determines to sync based on objects' ETags. It is meant for
+// syncing between remote storages, whose objects have ETags. If Fallback is
+// set, the ETags are not compared and the objects are compared with it
+// instead.
+type EtagStrategy struct {
+	Fallback SyncStrategy
+}
+
+func (es *EtagStrategy) ShouldSync(srcObj, dstObj *storage.Object) *SkipReason {
+	if es.Fallback != nil {
+		return es.Fallback.ShouldSync(srcObj, dstObj)
+	}
+	if srcObj.Etag == "" || srcObj.Etag != dstObj.Etag {
+		return nil
+	}
+	return newSkipReason(SkipEtagsMatch, srcObj, dstObj)
+}
+
+// AlwaysStrategy syncs all objects regardless of their attributes.
+type AlwaysStrategy struct{}
+
+func (as *AlwaysStrategy) ShouldSync(srcObj, dstObj *storage.Object) *SkipReason {
+	return nil
 }
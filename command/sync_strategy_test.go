@@ -1,11 +1,20 @@
 package command
 
 import (
+	"crypto/md5"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
 	errorpkg "github.com/peak/s5cmd/v2/error"
 	"github.com/peak/s5cmd/v2/storage"
+	"github.com/peak/s5cmd/v2/storage/url"
+	"github.com/peak/s5cmd/v2/strutil"
 )
 
 func TestSizeAndModificationStrategy_ShouldSync(t *testing.T) {
@@ -71,7 +80,199 @@ func TestSizeAndModificationStrategy_ShouldSync(t *testing.T) {
 	for _, tc := range testcases {
 		t.Run(tc.name, func(t *testing.T) {
 			strategy := &SizeAndModificationStrategy{}
-			if got := strategy.ShouldSync(tc.src, tc.dst); got != tc.expected {
+			var got error
+			if reason := strategy.ShouldSync(tc.src, tc.dst); reason != nil {
+				got = reason.Err()
+			}
+			if got != tc.expected {
+				t.Fatalf("expected: %q(%T), got: %q(%T)", tc.expected, tc.expected, got, got)
+			}
+		})
+	}
+}
+
+func TestSizeAndModificationStrategyWithSkewTolerance_ShouldSync(t *testing.T) {
+	ft := time.Now()
+	timePtr := func(tt time.Time) *time.Time {
+		return &tt
+	}
+	tolerance := 2 * time.Second
+
+	testcases := []struct {
+		name     string
+		src      *storage.Object
+		dst      *storage.Object
+		expected error
+	}{
+		{
+			name:     "source is newer within tolerance, sizes are same",
+			src:      &storage.Object{ModTime: timePtr(ft.Add(time.Second)), Size: 10},
+			dst:      &storage.Object{ModTime: timePtr(ft), Size: 10},
+			expected: errorpkg.ErrObjectIsNewerAndSizesMatch,
+		},
+		{
+			name:     "source is newer by exactly tolerance, sizes are same",
+			src:      &storage.Object{ModTime: timePtr(ft.Add(tolerance)), Size: 10},
+			dst:      &storage.Object{ModTime: timePtr(ft), Size: 10},
+			expected: errorpkg.ErrObjectIsNewerAndSizesMatch,
+		},
+		{
+			name:     "source is newer beyond tolerance, sizes are same",
+			src:      &storage.Object{ModTime: timePtr(ft.Add(tolerance + time.Nanosecond)), Size: 10},
+			dst:      &storage.Object{ModTime: timePtr(ft), Size: 10},
+			expected: nil,
+		},
+		{
+			name:     "source is newer within tolerance, sizes are different",
+			src:      &storage.Object{ModTime: timePtr(ft.Add(time.Second)), Size: 10},
+			dst:      &storage.Object{ModTime: timePtr(ft), Size: 5},
+			expected: nil,
+		},
+		{
+			name:     "source is older, sizes are same",
+			src:      &storage.Object{ModTime: timePtr(ft), Size: 10},
+			dst:      &storage.Object{ModTime: timePtr(ft.Add(time.Minute)), Size: 10},
+			expected: errorpkg.ErrObjectIsNewerAndSizesMatch,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			strategy := &SizeAndModificationStrategy{SkewTolerance: tolerance}
+			var got error
+			if reason := strategy.ShouldSync(tc.src, tc.dst); reason != nil {
+				got = reason.Err()
+			}
+			if got != tc.expected {
+				t.Fatalf("expected: %q(%T), got: %q(%T)", tc.expected, tc.expected, got, got)
+			}
+		})
+	}
+}
+
+func TestModificationOnlyStrategy_ShouldSync(t *testing.T) {
+	ft := time.Now()
+	timePtr := func(tt time.Time) *time.Time {
+		return &tt
+	}
+	tolerance := 2 * time.Second
+
+	testcases := []struct {
+		name     string
+		src      *storage.Object
+		dst      *storage.Object
+		expected error
+	}{
+		{
+			name:     "source is newer beyond tolerance",
+			src:      &storage.Object{ModTime: timePtr(ft.Add(tolerance + time.Nanosecond)), Size: 10},
+			dst:      &storage.Object{ModTime: timePtr(ft), Size: 10},
+			expected: nil,
+		},
+		{
+			name:     "source is newer within tolerance, sizes are different",
+			src:      &storage.Object{ModTime: timePtr(ft.Add(time.Second)), Size: 10},
+			dst:      &storage.Object{ModTime: timePtr(ft), Size: 5},
+			expected: errorpkg.ErrObjectIsNewer,
+		},
+		{
+			name:     "source is older, sizes are different",
+			src:      &storage.Object{ModTime: timePtr(ft), Size: 10},
+			dst:      &storage.Object{ModTime: timePtr(ft.Add(time.Minute)), Size: 5},
+			expected: errorpkg.ErrObjectIsNewer,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			strategy := &ModificationOnlyStrategy{SkewTolerance: tolerance}
+			var got error
+			if reason := strategy.ShouldSync(tc.src, tc.dst); reason != nil {
+				got = reason.Err()
+			}
+			if got != tc.expected {
+				t.Fatalf("expected: %q(%T), got: %q(%T)", tc.expected, tc.expected, got, got)
+			}
+		})
+	}
+}
+
+func TestPreferSourceStrategy_ShouldSync(t *testing.T) {
+	ft := time.Now()
+	timePtr := func(tt time.Time) *time.Time {
+		return &tt
+	}
+
+	testcases := []struct {
+		name      string
+		tolerance time.Duration
+		src       *storage.Object
+		dst       *storage.Object
+		expected  error
+	}{
+		{
+			//	time: src > dst       size: src == dst
+			name:     "source is newer, sizes are same",
+			src:      &storage.Object{ModTime: timePtr(ft.Add(time.Minute)), Size: 10},
+			dst:      &storage.Object{ModTime: timePtr(ft), Size: 10},
+			expected: nil,
+		},
+		{
+			//	time: src < dst       size: src == dst
+			name:     "source is older, sizes are same",
+			src:      &storage.Object{ModTime: timePtr(ft), Size: 10},
+			dst:      &storage.Object{ModTime: timePtr(ft.Add(time.Minute)), Size: 10},
+			expected: nil,
+		},
+		{
+			//	time: src < dst       size: src != dst
+			name:     "source is older, sizes are different",
+			src:      &storage.Object{ModTime: timePtr(ft), Size: 10},
+			dst:      &storage.Object{ModTime: timePtr(ft.Add(time.Minute)), Size: 5},
+			expected: nil,
+		},
+		{
+			//	time: src = dst       size: src != dst
+			name:     "files have same age, sizes are different",
+			src:      &storage.Object{ModTime: timePtr(ft), Size: 10},
+			dst:      &storage.Object{ModTime: timePtr(ft), Size: 5},
+			expected: nil,
+		},
+		{
+			//	time: src = dst       size: src == dst
+			name:     "files have same age, sizes are same",
+			src:      &storage.Object{ModTime: timePtr(ft), Size: 10},
+			dst:      &storage.Object{ModTime: timePtr(ft), Size: 10},
+			expected: errorpkg.ErrObjectIsSameAgeAndSizesMatch,
+		},
+		{
+			name:      "source is older by exactly tolerance, sizes are same",
+			tolerance: 2 * time.Second,
+			src:       &storage.Object{ModTime: timePtr(ft), Size: 10},
+			dst:       &storage.Object{ModTime: timePtr(ft.Add(2 * time.Second)), Size: 10},
+			expected:  errorpkg.ErrObjectIsSameAgeAndSizesMatch,
+		},
+		{
+			name:      "source is newer within tolerance, sizes are same",
+			tolerance: 2 * time.Second,
+			src:       &storage.Object{ModTime: timePtr(ft.Add(time.Second)), Size: 10},
+			dst:       &storage.Object{ModTime: timePtr(ft), Size: 10},
+			expected:  errorpkg.ErrObjectIsSameAgeAndSizesMatch,
+		},
+		{
+			name:      "source is older beyond tolerance, sizes are same",
+			tolerance: 2 * time.Second,
+			src:       &storage.Object{ModTime: timePtr(ft), Size: 10},
+			dst:       &storage.Object{ModTime: timePtr(ft.Add(2*time.Second + time.Nanosecond)), Size: 10},
+			expected:  nil,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			strategy := &PreferSourceStrategy{SkewTolerance: tc.tolerance}
+			var got error
+			if reason := strategy.ShouldSync(tc.src, tc.dst); reason != nil {
+				got = reason.Err()
+			}
+			if got != tc.expected {
 				t.Fatalf("expected: %q(%T), got: %q(%T)", tc.expected, tc.expected, got, got)
 			}
 		})
@@ -141,9 +342,326 @@ func TestSizeOnlyStrategy_ShouldSync(t *testing.T) {
 	for _, tc := range testcases {
 		t.Run(tc.name, func(t *testing.T) {
 			strategy := &SizeOnlyStrategy{}
-			if got := strategy.ShouldSync(tc.src, tc.dst); got != tc.expected {
+			var got error
+			if reason := strategy.ShouldSync(tc.src, tc.dst); reason != nil {
+				got = reason.Err()
+			}
+			if got != tc.expected {
+				t.Fatalf("expected: %q(%T), got: %q(%T)", tc.expected, tc.expected, got, got)
+			}
+		})
+	}
+}
+
+func TestChecksumStrategy_ShouldSync(t *testing.T) {
+	ft := time.Now()
+	timePtr := func(tt time.Time) *time.Time {
+		return &tt
+	}
+
+	dir := t.TempDir()
+	writeFile := func(name, content string) *url.URL {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		u, err := url.New(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return u
+	}
+	remoteURL := func(key string) *url.URL {
+		u, err := url.New("s3://bucket/" + key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return u
+	}
+
+	// md5 of "content" and "CONTENT".
+	const (
+		contentMD5 = "9a0364b9e99bb480dd25e1f0284c8555"
+		otherMD5   = "45685e95985e20822fb2538a522a5ccf"
+	)
+
+	// the files larger than the part size are uploaded in parts, their ETags
+	// are the checksums of the checksums of their parts.
+	const partSize = storage.MinPartSize
+	large := strings.Repeat("content ", (partSize+1024)/8)
+	largeSize := int64(len(large))
+	multipartEtag := func(content string) string {
+		first, second := md5.Sum([]byte(content[:partSize])), md5.Sum([]byte(content[partSize:]))
+		return fmt.Sprintf("%x-2", md5.Sum(append(first[:], second[:]...)))
+	}
+	largeEtag := multipartEtag(large)
+	otherLargeEtag := multipartEtag(strings.ToUpper(large))
+	partOpts := StrategyOptions{PartSize: partSize}
+
+	testcases := []struct {
+		name     string
+		opts     StrategyOptions
+		src      *storage.Object
+		dst      *storage.Object
+		expected error
+		// multipart is the objects reported to have multipart ETags.
+		multipart []string
+	}{
+		{
+			name:     "local files with same content, source is newer",
+			src:      &storage.Object{URL: writeFile("a", "content"), ModTime: timePtr(ft.Add(time.Minute)), Size: 7},
+			dst:      &storage.Object{URL: writeFile("b", "content"), ModTime: timePtr(ft), Size: 7},
+			expected: errorpkg.ErrObjectChecksumsMatch,
+		},
+		{
+			name:     "local files with different content, source is older",
+			src:      &storage.Object{URL: writeFile("c", "content"), ModTime: timePtr(ft), Size: 7},
+			dst:      &storage.Object{URL: writeFile("d", "CONTENT"), ModTime: timePtr(ft.Add(time.Minute)), Size: 7},
+			expected: nil,
+		},
+		{
+			name:     "local file and remote object with same content",
+			src:      &storage.Object{URL: writeFile("e", "content"), ModTime: timePtr(ft.Add(time.Minute)), Size: 7},
+			dst:      &storage.Object{URL: remoteURL("e"), Etag: strings.ToUpper(contentMD5), ModTime: timePtr(ft), Size: 7},
+			expected: errorpkg.ErrObjectChecksumsMatch,
+		},
+		{
+			name:     "remote objects with different etags",
+			src:      &storage.Object{URL: remoteURL("f"), Etag: contentMD5, ModTime: timePtr(ft), Size: 7},
+			dst:      &storage.Object{URL: remoteURL("f"), Etag: otherMD5, ModTime: timePtr(ft.Add(time.Minute)), Size: 7},
+			expected: nil,
+		},
+		{
+			name:     "sizes are different",
+			src:      &storage.Object{URL: remoteURL("g"), Etag: contentMD5, ModTime: timePtr(ft), Size: 7},
+			dst:      &storage.Object{URL: remoteURL("g"), Etag: contentMD5, ModTime: timePtr(ft), Size: 8},
+			expected: nil,
+		},
+		{
+			name:     "single-part remote objects with same etags",
+			src:      &storage.Object{URL: remoteURL("s"), Etag: contentMD5, ModTime: timePtr(ft.Add(time.Minute)), Size: 7},
+			dst:      &storage.Object{URL: remoteURL("s"), Etag: contentMD5, ModTime: timePtr(ft), Size: 7},
+			expected: errorpkg.ErrObjectChecksumsMatch,
+		},
+		{
+			name:      "multipart etag falls back to size and modification time",
+			src:       &storage.Object{URL: writeFile("h", "content"), ModTime: timePtr(ft), Size: 7},
+			dst:       &storage.Object{URL: remoteURL("h"), Etag: contentMD5 + "-2", ModTime: timePtr(ft.Add(time.Minute)), Size: 7},
+			expected:  errorpkg.ErrObjectIsNewerAndSizesMatch,
+			multipart: []string{"s3://bucket/h"},
+		},
+		{
+			name:      "multipart etag of source falls back to size and modification time",
+			src:       &storage.Object{URL: remoteURL("m"), Etag: contentMD5 + "-3", ModTime: timePtr(ft.Add(time.Minute)), Size: 7},
+			dst:       &storage.Object{URL: remoteURL("m"), Etag: contentMD5, ModTime: timePtr(ft), Size: 7},
+			expected:  nil,
+			multipart: []string{"s3://bucket/m"},
+		},
+		{
+			name:     "local file and remote object uploaded in parts with same content, source is newer",
+			opts:     partOpts,
+			src:      &storage.Object{URL: writeFile("p1", large), ModTime: timePtr(ft.Add(time.Minute)), Size: largeSize},
+			dst:      &storage.Object{URL: remoteURL("p1"), Etag: largeEtag, ModTime: timePtr(ft), Size: largeSize},
+			expected: errorpkg.ErrObjectChecksumsMatch,
+		},
+		{
+			name:     "local file and remote object uploaded in parts with different content",
+			opts:     partOpts,
+			src:      &storage.Object{URL: writeFile("p2", large), ModTime: timePtr(ft), Size: largeSize},
+			dst:      &storage.Object{URL: remoteURL("p2"), Etag: otherLargeEtag, ModTime: timePtr(ft.Add(time.Minute)), Size: largeSize},
+			expected: nil,
+		},
+		{
+			name:     "remote object uploaded in parts and local file with same content",
+			opts:     partOpts,
+			src:      &storage.Object{URL: remoteURL("p3"), Etag: strings.ToUpper(largeEtag), ModTime: timePtr(ft.Add(time.Minute)), Size: largeSize},
+			dst:      &storage.Object{URL: writeFile("p3", large), ModTime: timePtr(ft), Size: largeSize},
+			expected: errorpkg.ErrObjectChecksumsMatch,
+		},
+		{
+			name:      "remote object uploaded in a different number of parts falls back to size and modification time",
+			opts:      partOpts,
+			src:       &storage.Object{URL: writeFile("p4", large), ModTime: timePtr(ft), Size: largeSize},
+			dst:       &storage.Object{URL: remoteURL("p4"), Etag: contentMD5 + "-3", ModTime: timePtr(ft.Add(time.Minute)), Size: largeSize},
+			expected:  errorpkg.ErrObjectIsNewerAndSizesMatch,
+			multipart: []string{"s3://bucket/p4"},
+		},
+		{
+			name:      "file up to the multipart threshold is uploaded with a single request",
+			opts:      StrategyOptions{PartSize: partSize, MultipartThreshold: 2 * partSize},
+			src:       &storage.Object{URL: writeFile("p5", large), ModTime: timePtr(ft), Size: largeSize},
+			dst:       &storage.Object{URL: remoteURL("p5"), Etag: largeEtag, ModTime: timePtr(ft.Add(time.Minute)), Size: largeSize},
+			expected:  errorpkg.ErrObjectIsNewerAndSizesMatch,
+			multipart: []string{"s3://bucket/p5"},
+		},
+		{
+			name:      "remote objects uploaded in parts fall back to size and modification time",
+			opts:      partOpts,
+			src:       &storage.Object{URL: remoteURL("p6"), Etag: largeEtag, ModTime: timePtr(ft.Add(time.Minute)), Size: largeSize},
+			dst:       &storage.Object{URL: remoteURL("p6"), Etag: largeEtag, ModTime: timePtr(ft), Size: largeSize},
+			expected:  nil,
+			multipart: []string{"s3://bucket/p6"},
+		},
+		{
+			name:     "etag with a dash but no part count is not a checksum",
+			src:      &storage.Object{URL: writeFile("n", "content"), ModTime: timePtr(ft), Size: 7},
+			dst:      &storage.Object{URL: remoteURL("n"), Etag: contentMD5 + "-", ModTime: timePtr(ft.Add(time.Minute)), Size: 7},
+			expected: errorpkg.ErrObjectIsNewerAndSizesMatch,
+		},
+		{
+			name:     "opaque destination etags fall back to size and modification time",
+			opts:     StrategyOptions{OpaqueDestinationEtags: true},
+			src:      &storage.Object{URL: writeFile("i", "content"), ModTime: timePtr(ft.Add(time.Minute)), Size: 7},
+			dst:      &storage.Object{URL: remoteURL("i"), Etag: contentMD5, ModTime: timePtr(ft), Size: 7},
+			expected: nil,
+		},
+		{
+			name:     "opaque destination etags of a newer object",
+			opts:     StrategyOptions{OpaqueDestinationEtags: true},
+			src:      &storage.Object{URL: writeFile("j", "content"), ModTime: timePtr(ft), Size: 7},
+			dst:      &storage.Object{URL: remoteURL("j"), Etag: otherMD5, ModTime: timePtr(ft.Add(time.Minute)), Size: 7},
+			expected: errorpkg.ErrObjectIsNewerAndSizesMatch,
+		},
+	}
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			var multipart []string
+			opts := tc.opts
+			opts.OnMultipartEtag = func(obj *storage.Object) {
+				multipart = append(multipart, obj.URL.String())
+			}
+			strategy := NewStrategy(syncStrategyChecksum, opts)
+			var got error
+			if reason := strategy.ShouldSync(tc.src, tc.dst); reason != nil {
+				got = reason.Err()
+			}
+			if got != tc.expected {
 				t.Fatalf("expected: %q(%T), got: %q(%T)", tc.expected, tc.expected, got, got)
 			}
+			if !reflect.DeepEqual(multipart, tc.multipart) {
+				t.Fatalf("expected multipart etags of %v, got %v", tc.multipart, multipart)
+			}
 		})
 	}
 }
+
+func TestEtagStrategy_ShouldSync(t *testing.T) {
+	testcases := []struct {
+		name     string
+		src      *storage.Object
+		dst      *storage.Object
+		expected error
+	}{
+		{
+			name:     "etags are same",
+			src:      &storage.Object{Etag: "abc", Size: 10},
+			dst:      &storage.Object{Etag: "abc", Size: 10},
+			expected: errorpkg.ErrObjectEtagsMatch,
+		},
+		{
+			name:     "etags are different",
+			src:      &storage.Object{Etag: "abc", Size: 10},
+			dst:      &storage.Object{Etag: "def", Size: 10},
+			expected: nil,
+		},
+		{
+			name:     "etags are missing",
+			src:      &storage.Object{Size: 10},
+			dst:      &storage.Object{Size: 10},
+			expected: nil,
+		},
+	}
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			var got error
+			if reason := (&EtagStrategy{}).ShouldSync(tc.src, tc.dst); reason != nil {
+				got = reason.Err()
+			}
+			if got != tc.expected {
+				t.Fatalf("expected: %q(%T), got: %q(%T)", tc.expected, tc.expected, got, got)
+			}
+		})
+	}
+}
+
+func TestNewStrategy(t *testing.T) {
+	testcases := []struct {
+		name     string
+		opts     StrategyOptions
+		expected SyncStrategy
+	}{
+		{name: syncStrategySize, expected: &SizeOnlyStrategy{}},
+		{name: syncStrategySizeMtime, expected: &SizeAndModificationStrategy{}},
+		{
+			name:     syncStrategySizeMtime,
+			opts:     StrategyOptions{PreferSource: true, SkewTolerance: time.Second},
+			expected: &PreferSourceStrategy{SkewTolerance: time.Second},
+		},
+		{
+			name:     syncStrategyChecksum,
+			opts:     StrategyOptions{SkewTolerance: time.Second},
+			expected: &ChecksumStrategy{Fallback: &SizeAndModificationStrategy{SkewTolerance: time.Second}},
+		},
+		{
+			name:     syncStrategyChecksum,
+			opts:     StrategyOptions{OpaqueDestinationEtags: true},
+			expected: &ChecksumStrategy{Fallback: &SizeAndModificationStrategy{}, OpaqueDestinationEtags: true},
+		},
+		{name: syncStrategyEtag, expected: &EtagStrategy{}},
+		{
+			name:     syncStrategyEtag,
+			opts:     StrategyOptions{OpaqueDestinationEtags: true},
+			expected: &EtagStrategy{Fallback: &SizeAndModificationStrategy{}},
+		},
+		{
+			name:     syncStrategySizeMtime,
+			opts:     StrategyOptions{Transformed: true, SkewTolerance: time.Second},
+			expected: &ModificationOnlyStrategy{SkewTolerance: time.Second},
+		},
+		{name: syncStrategyAlways, expected: &AlwaysStrategy{}},
+		{name: "unknown", expected: nil},
+	}
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			got := NewStrategy(tc.name, tc.opts)
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expected %#v, got %#v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestSkipReason(t *testing.T) {
+	ft := time.Date(2023, time.January, 2, 3, 4, 5, 0, time.UTC)
+	src := &storage.Object{ModTime: &ft, Size: 10}
+	dst := &storage.Object{ModTime: &ft, Size: 10, Etag: "abc"}
+
+	reason := (&SizeAndModificationStrategy{}).ShouldSync(src, dst)
+	if reason == nil {
+		t.Fatal("expected a skip reason, got nil")
+	}
+
+	if reason.Code != SkipNewerAndSizesMatch {
+		t.Errorf("expected code %v, got %v", SkipNewerAndSizesMatch, reason.Code)
+	}
+
+	if !errors.Is(reason, errorpkg.ErrObjectIsNewerAndSizesMatch) {
+		t.Errorf("expected %q to wrap %q", reason, errorpkg.ErrObjectIsNewerAndSizesMatch)
+	}
+
+	expected := "object is newer or same age and object size matches " +
+		"(source: size=10 mtime=2023-01-02T03:04:05Z, destination: size=10 mtime=2023-01-02T03:04:05Z etag=abc)"
+	if got := reason.Error(); got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+
+	expectedJSON := `{"reason":"newer_and_size_match",` +
+		`"source":{"size":10,"last_modified":"2023-01-02T03:04:05Z"},` +
+		`"destination":{"size":10,"last_modified":"2023-01-02T03:04:05Z","etag":"abc"}}`
+	if got := strutil.JSON(reason); got != expectedJSON {
+		t.Errorf("expected %v, got %v", expectedJSON, got)
+	}
+}
@@ -0,0 +1,198 @@
+package command
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/lanrat/extsort"
+	"github.com/urfave/cli/v2"
+
+	"github.com/peak/s5cmd/v2/storage"
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+const streamFlagName = "stream"
+
+// streamOrder records the first listing which is found out of order while
+// its objects are streamed without sorting. It is shared by the listings of
+// source and destination, which both stop once it is set.
+type streamOrder struct {
+	mu  sync.Mutex
+	err error
+}
+
+// newStreamOrder returns the order check of the streamed listings if the
+// "stream" flag is given, or nil otherwise.
+func newStreamOrder(c *cli.Context) *streamOrder {
+	if !c.Bool(streamFlagName) {
+		return nil
+	}
+	return &streamOrder{}
+}
+
+// fail records err, and reports whether it is the first one.
+func (o *streamOrder) fail(err error) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.err != nil {
+		return false
+	}
+	o.err = err
+	return true
+}
+
+// Err returns the error of the listing found out of order, if any. It is nil
+// if o is nil.
+func (o *streamOrder) Err() error {
+	if o == nil {
+		return nil
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.err
+}
+
+// isStreamed reports whether the objects listed from u are compared as they
+// are listed rather than sorted first. The remote objects are listed in the
+// lexicographic order of their keys, which is the order of their relative
+// paths, unless the paths are changed before they are compared or more than
+// one version of an object is listed. The local files are not walked in that
+// order, e.g. "a/b" is walked before "a.txt", so they are always sorted.
+func (s Sync) isStreamed(u *url.URL, source bool) bool {
+	if s.streamOrder == nil || !u.IsRemote() {
+		return false
+	}
+	if s.stripKeyPrefix != "" || s.shallow || s.destFS.caseInsensitive {
+		return false
+	}
+	if source && (s.destDatePrefix != "" || s.pinVersions) {
+		return false
+	}
+	return !u.AllVersions && u.VersionID == ""
+}
+
+// streamObjects sends the objects to sorted as they are received, checking
+// that they are in the order of their relative paths. If an object is out of
+// order, the error is printed and recorded, and both listings are stopped,
+// since the objects compared after it may not be matched with their
+// counterparts.
+func (s Sync) streamObjects(ctx context.Context, objects chan extsort.SortType, listurl *url.URL, sorted chan<- *storage.Object) {
+	// the objects are consumed until the listing is completed, so that it
+	// is not blocked once the stream is stopped.
+	defer func() {
+		for range objects {
+		}
+	}()
+
+	var prev *storage.Object
+	for object := range objects {
+		if ctx.Err() != nil || s.streamOrder.Err() != nil {
+			return
+		}
+
+		o := object.(storage.Object)
+		if prev != nil && storage.Less(o, *prev) {
+			err := fmt.Errorf(
+				"listing of %v is not in lexicographic order, %q is listed after %q; sync without %q flag",
+				listurl, o.URL.Relative(), prev.URL.Relative(), streamFlagName,
+			)
+			if s.streamOrder.fail(err) {
+				printError(s.fullCommand, s.op, err)
+			}
+			return
+		}
+		prev = &o
+		sorted <- &o
+	}
+}
+
+// heldDeletes holds the objects only in destination of the streamed listings
+// until both of the listings are completed. They are written to a temporary
+// file of the spool rather than kept in memory, since they may be as many as
+// the objects of the destination.
+type heldDeletes struct {
+	file *storage.SpoolFile
+	w    *bufio.Writer
+	err  error
+}
+
+// add writes u to the file, which is created on the first call. The write
+// errors are returned by replay.
+func (h *heldDeletes) add(u *url.URL) {
+	if h.err != nil {
+		return
+	}
+	if h.file == nil {
+		h.file, h.err = tempSpool.Create("deletes-*", 0)
+		if h.err != nil {
+			return
+		}
+		h.w = bufio.NewWriter(h.file)
+	}
+
+	data := u.ToBytes()
+	var size [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(size[:], uint64(len(data)))
+	if _, h.err = h.w.Write(size[:n]); h.err != nil {
+		return
+	}
+	_, h.err = h.w.Write(data)
+}
+
+// replay calls fn with the held objects in the order they are added, and
+// removes the file.
+func (h *heldDeletes) replay(fn func(*url.URL)) error {
+	if h.file == nil {
+		return h.err
+	}
+	defer h.remove()
+
+	if h.err != nil {
+		return h.err
+	}
+	if err := h.w.Flush(); err != nil {
+		return err
+	}
+	if _, err := h.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	r := bufio.NewReader(h.file)
+	for {
+		size, err := binary.ReadUvarint(r)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		data := make([]byte, size)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return err
+		}
+		fn(url.FromBytes(data).(*url.URL))
+	}
+}
+
+// remove removes the file without replaying the held objects.
+func (h *heldDeletes) remove() {
+	if h.file != nil {
+		h.file.Remove()
+	}
+}
+
+func validateStream(c *cli.Context) error {
+	if !c.Bool(streamFlagName) {
+		return nil
+	}
+	// the deletes are held back until both of the listings are checked to
+	// be in order.
+	if c.Int("delete-batch-interleave") > 0 {
+		return fmt.Errorf("it is not allowed to combine %q and %q flags", streamFlagName, "delete-batch-interleave")
+	}
+	return nil
+}
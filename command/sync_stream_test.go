@@ -0,0 +1,217 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/lanrat/extsort"
+
+	"github.com/peak/s5cmd/v2/log"
+	"github.com/peak/s5cmd/v2/storage"
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+// orderedListing sends the objects from first to last under the prefix, in
+// the order of their keys as they are listed from a bucket. Every step-th
+// key is left out.
+func orderedListing(t testing.TB, first, last, step int, objects chan<- extsort.SortType) {
+	defer close(objects)
+
+	base, err := url.New("s3://bucket/prefix/*")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	modTime := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	for i := first; i < last; i++ {
+		if step > 0 && i%step == 0 {
+			continue
+		}
+		u, err := url.New(fmt.Sprintf("s3://bucket/prefix/key-%09d", i), url.WithRaw(true))
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		u.SetRelative(base)
+		objects <- storage.Object{URL: u, ModTime: &modTime, Size: int64(i)}
+	}
+}
+
+// TestSyncStreamObjectsMemory compares two synthetic listings as they are
+// listed. The listings would take many times the memory budget if they were
+// kept, whereas the objects in the buffers of the channels take a fraction of
+// it. The live heap is sampled while they are compared.
+func TestSyncStreamObjectsMemory(t *testing.T) {
+	n := 500_000
+	if testing.Short() {
+		n = 100_000
+	}
+	const budget = 24 << 20
+
+	s := Sync{streamOrder: &streamOrder{}}
+	ctx := context.Background()
+	listurl, err := url.New("s3://bucket/prefix/*")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stream := func(first, last, step int) chan *storage.Object {
+		listed := make(chan extsort.SortType, extsortChannelBufferSize)
+		go orderedListing(t, first, last, step, listed)
+
+		objects := make(chan *storage.Object, extsortChannelBufferSize)
+		go func() {
+			defer close(objects)
+			s.streamObjects(ctx, listed, listurl, objects)
+		}()
+		return objects
+	}
+
+	// the source leaves out every 7th key, and the destination every 5th
+	// key and the first tenth of the keys.
+	onlySource, onlyDest, common := compareObjects(stream(0, n, 7), stream(n/10, n, 5), false)
+
+	var (
+		srcCount, dstCount, commonCount int
+		peak                            uint64
+	)
+	sample := func() {
+		var stats runtime.MemStats
+		runtime.GC()
+		runtime.ReadMemStats(&stats)
+		if stats.HeapAlloc > peak {
+			peak = stats.HeapAlloc
+		}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range onlyDest {
+			dstCount++
+		}
+	}()
+	go func() {
+		for range common {
+			commonCount++
+		}
+	}()
+	for range onlySource {
+		srcCount++
+		if srcCount%10_000 == 0 {
+			sample()
+		}
+	}
+	<-done
+	sample()
+
+	var expectedSrc, expectedDst int
+	for i := 0; i < n; i++ {
+		inSrc := i%7 != 0
+		inDst := i >= n/10 && i%5 != 0
+		switch {
+		case inSrc && !inDst:
+			expectedSrc++
+		case inDst && !inSrc:
+			expectedDst++
+		}
+	}
+
+	if srcCount != expectedSrc {
+		t.Errorf("expected %v objects only in source, got %v", expectedSrc, srcCount)
+	}
+	if dstCount != expectedDst {
+		t.Errorf("expected %v objects only in destination, got %v", expectedDst, dstCount)
+	}
+	if err := s.streamOrder.Err(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if peak > budget {
+		t.Errorf("expected the heap to be within %v bytes, got %v", budget, peak)
+	}
+}
+
+func TestSyncStreamObjectsOutOfOrder(t *testing.T) {
+	log.Init("error", false)
+
+	base, err := url.New("s3://bucket/*")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	listed := make(chan extsort.SortType, 4)
+	for _, key := range []string{"a", "c", "b", "d"} {
+		u, err := url.New("s3://bucket/" + key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		u.SetRelative(base)
+		listed <- storage.Object{URL: u}
+	}
+	close(listed)
+
+	s := Sync{streamOrder: &streamOrder{}, op: "sync", fullCommand: "sync"}
+	sorted := make(chan *storage.Object, 4)
+	s.streamObjects(context.Background(), listed, base, sorted)
+	close(sorted)
+
+	var keys []string
+	for object := range sorted {
+		keys = append(keys, object.URL.Relative())
+	}
+	if fmt.Sprint(keys) != "[a c]" {
+		t.Errorf("expected the stream to stop before the object out of order, got %v", keys)
+	}
+
+	expected := `listing of s3://bucket/* is not in lexicographic order, "b" is listed after "c"; sync without "stream" flag`
+	if err := s.streamOrder.Err(); err == nil || err.Error() != expected {
+		t.Errorf("expected error %q, got %v", expected, err)
+	}
+}
+
+func TestSyncHeldDeletes(t *testing.T) {
+	spool := storage.NewSpool(t.TempDir(), 0)
+	defer spool.Cleanup()
+
+	defer func(s *storage.Spool) { tempSpool = s }(tempSpool)
+	tempSpool = spool
+
+	base, err := url.New("s3://bucket/prefix/*")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		held     heldDeletes
+		expected []string
+	)
+	for i := 0; i < 1000; i++ {
+		u, err := url.New(fmt.Sprintf("s3://bucket/prefix/key\n%04d", i), url.WithRaw(true))
+		if err != nil {
+			t.Fatal(err)
+		}
+		u.SetRelative(base)
+		held.add(u)
+		expected = append(expected, u.Absolute()+" "+u.Relative())
+	}
+	if held.file == nil {
+		t.Fatal("expected the objects to be held in a file")
+	}
+
+	var replayed []string
+	if err := held.replay(func(u *url.URL) {
+		replayed = append(replayed, u.Absolute()+" "+u.Relative())
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(replayed) != fmt.Sprint(expected) {
+		t.Errorf("expected the objects to be replayed in the order they are added")
+	}
+	if used := spool.Used(); used != 0 {
+		t.Errorf("expected the file to be removed, %v bytes are in use", used)
+	}
+}
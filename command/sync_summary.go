@@ -0,0 +1,49 @@
+package command
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/peak/s5cmd/v2/strutil"
+)
+
+const summaryFlagName = "summary"
+
+// SyncSummaryMessage is the structure for the summary of a sync: the number of
+// the objects it copied, deleted, skipped as up-to-date and failed to sync,
+// and the number of bytes it transferred.
+type SyncSummaryMessage struct {
+	Copied  int64 `json:"copied"`
+	Bytes   int64 `json:"bytes"`
+	Deleted int64 `json:"deleted"`
+	Skipped int64 `json:"skipped"`
+	Failed  int64 `json:"failed"`
+}
+
+// String is the string representation of SyncSummaryMessage.
+func (m SyncSummaryMessage) String() string {
+	return fmt.Sprintf("summary: %d objects copied, %d bytes transferred, %d deleted, %d skipped, %d failed",
+		m.Copied, m.Bytes, m.Deleted, m.Skipped, m.Failed)
+}
+
+// JSON is the JSON representation of SyncSummaryMessage.
+func (m SyncSummaryMessage) JSON() string {
+	return strutil.JSON(struct {
+		Operation string `json:"operation"`
+		SyncSummaryMessage
+	}{
+		Operation:          "sync-summary",
+		SyncSummaryMessage: m,
+	})
+}
+
+// summaryMessage returns the summary of the sync.
+func (s *syncChanges) summaryMessage() SyncSummaryMessage {
+	return SyncSummaryMessage{
+		Copied:  atomic.LoadInt64(&s.copied),
+		Bytes:   atomic.LoadInt64(&s.bytes),
+		Deleted: atomic.LoadInt64(&s.deleted),
+		Skipped: atomic.LoadInt64(&s.skipped),
+		Failed:  atomic.LoadInt64(&s.failed),
+	}
+}
@@ -0,0 +1,108 @@
+package command
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/peak/s5cmd/v2/storage"
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+const twoWayFlagName = "two-way"
+
+// twoWayIncompatibleFlags are the flags which can't be combined with
+// --two-way, since they treat the source as the source of truth, or change
+// the keys or the contents of the objects in one direction only.
+var twoWayIncompatibleFlags = []string{
+	"delete",
+	"prefer-source",
+	"compare-key-strip-prefix",
+	destDatePrefixFlagName,
+	transformExecFlagName,
+	"partition-by-prefix",
+	"diff",
+	"match-encryption",
+	preserveMetadataFlagName,
+	"compare-exec",
+	"pin-versions",
+	"raw",
+}
+
+// TwoWayStrategy syncs the objects which Strategy finds different in either
+// direction, i.e. from source to destination or from destination to source.
+// An object is skipped only if Strategy skips it in both directions, and the
+// newer one of the objects which are synced is copied over the other one.
+type TwoWayStrategy struct {
+	Strategy SyncStrategy
+}
+
+func (tw *TwoWayStrategy) ShouldSync(srcObj, dstObj *storage.Object) *SkipReason {
+	reason := tw.Strategy.ShouldSync(srcObj, dstObj)
+	if reason == nil {
+		return nil
+	}
+	if tw.Strategy.ShouldSync(dstObj, srcObj) == nil {
+		return nil
+	}
+	return reason
+}
+
+// twoWayRoot returns the URL the objects only in destination are copied
+// under in source with --two-way, which is the source directory or the
+// prefix of the source wildcard. The source is validated before.
+func (s Sync) twoWayRoot() (*url.URL, error) {
+	root := strings.TrimSuffix(filepath.ToSlash(s.src), "*")
+	return url.New(root, url.WithRaw(true))
+}
+
+// isTwoWayReversed reports whether the different objects in source and
+// destination are synced from destination to source, which is the case if
+// the destination object is newer. It returns an error if neither of them is
+// newer by more than the skew tolerance, since it is not known which one is
+// changed.
+func (s Sync) isTwoWayReversed(srcObj, dstObj *storage.Object) (bool, error) {
+	if srcObj.ModTime == nil || dstObj.ModTime == nil {
+		return false, fmt.Errorf("%v and %v are different, but their modification times are not known; neither of them is copied", srcObj.URL, dstObj.URL)
+	}
+
+	diff := srcObj.ModTime.Sub(*dstObj.ModTime)
+	tolerance := s.compareSkewTolerance()
+	switch {
+	case diff > tolerance:
+		return false, nil
+	case -diff > tolerance:
+		return true, nil
+	}
+	return false, fmt.Errorf("%v and %v are different, but they are modified at the same time; neither of them is copied", srcObj.URL, dstObj.URL)
+}
+
+func validateTwoWay(c *cli.Context) error {
+	if !c.Bool(twoWayFlagName) {
+		return nil
+	}
+	for _, flag := range twoWayIncompatibleFlags {
+		if c.IsSet(flag) {
+			return fmt.Errorf("it is not allowed to combine %q and %q flags", twoWayFlagName, flag)
+		}
+	}
+
+	// the objects only in destination are copied into the source, so the
+	// source must be a directory or a prefix which they belong to.
+	src := c.Args().Get(0)
+	if c.Bool(contentsOnlyFlagName) {
+		src = contentsOnlySource(src, false)
+	}
+	src = filepath.ToSlash(src)
+	srcurl, err := url.New(src)
+	if err != nil {
+		return err
+	}
+	root := strings.TrimSuffix(src, "*")
+	if !strings.HasSuffix(root, "/") || (srcurl.IsRemote() && !strings.HasSuffix(src, "*")) || strings.ContainsAny(root, "*?[") {
+		return fmt.Errorf("%q flag requires the source to be a directory with a trailing slash, or a prefix followed by \"/*\"", twoWayFlagName)
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package command
+
+import (
+	"testing"
+	"time"
+
+	errorpkg "github.com/peak/s5cmd/v2/error"
+	"github.com/peak/s5cmd/v2/storage"
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+func TestTwoWayStrategy_ShouldSync(t *testing.T) {
+	ft := time.Now()
+	timePtr := func(tt time.Time) *time.Time {
+		return &tt
+	}
+
+	testcases := []struct {
+		name     string
+		src      *storage.Object
+		dst      *storage.Object
+		expected error
+	}{
+		{
+			name:     "source is newer, sizes are different",
+			src:      &storage.Object{ModTime: timePtr(ft.Add(time.Minute)), Size: 10},
+			dst:      &storage.Object{ModTime: timePtr(ft), Size: 5},
+			expected: nil,
+		},
+		{
+			name:     "destination is newer, sizes are different",
+			src:      &storage.Object{ModTime: timePtr(ft), Size: 10},
+			dst:      &storage.Object{ModTime: timePtr(ft.Add(time.Minute)), Size: 5},
+			expected: nil,
+		},
+		{
+			name:     "destination is newer, sizes are same",
+			src:      &storage.Object{ModTime: timePtr(ft), Size: 10},
+			dst:      &storage.Object{ModTime: timePtr(ft.Add(time.Minute)), Size: 10},
+			expected: nil,
+		},
+		{
+			name:     "source is newer, sizes are same",
+			src:      &storage.Object{ModTime: timePtr(ft.Add(time.Minute)), Size: 10},
+			dst:      &storage.Object{ModTime: timePtr(ft), Size: 10},
+			expected: nil,
+		},
+		{
+			name:     "same age, sizes are same",
+			src:      &storage.Object{ModTime: timePtr(ft), Size: 10},
+			dst:      &storage.Object{ModTime: timePtr(ft), Size: 10},
+			expected: errorpkg.ErrObjectIsNewerAndSizesMatch,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			strategy := &TwoWayStrategy{Strategy: &SizeAndModificationStrategy{}}
+
+			var got error
+			reason := strategy.ShouldSync(tc.src, tc.dst)
+			if reason != nil {
+				got = reason.Err()
+			}
+			if got != tc.expected {
+				t.Fatalf("expected: %q(%T), got: %q(%T)", tc.expected, tc.expected, got, got)
+			}
+			// the compared values are reported as the ones of source and
+			// destination.
+			if reason != nil && !reason.Source.ModTime.Equal(*tc.src.ModTime) {
+				t.Errorf("expected source mtime %v, got %v", tc.src.ModTime, reason.Source.ModTime)
+			}
+		})
+	}
+}
+
+func TestTwoWayStrategyCopiesNewerDestinationOfSameSize(t *testing.T) {
+	ft := time.Now()
+	srcurl, err := url.New("folder/a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dsturl, err := url.New("s3://bucket/a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dstTime := ft.Add(time.Minute)
+	src := &storage.Object{URL: srcurl, ModTime: &ft, Size: 10}
+	dst := &storage.Object{URL: dsturl, ModTime: &dstTime, Size: 10}
+
+	strategy := &TwoWayStrategy{Strategy: &SizeAndModificationStrategy{}}
+	if reason := strategy.ShouldSync(src, dst); reason != nil {
+		t.Fatalf("expected the objects to be synced, got: %v", reason.Err())
+	}
+
+	s := Sync{skewTolerance: 2 * time.Second}
+	reversed, err := s.isTwoWayReversed(src, dst)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reversed {
+		t.Errorf("expected the destination to be copied to the source")
+	}
+}
+
+func TestSyncIsTwoWayReversed(t *testing.T) {
+	ft := time.Now()
+	timePtr := func(tt time.Time) *time.Time {
+		return &tt
+	}
+	srcurl, err := url.New("folder/a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dsturl, err := url.New("s3://bucket/a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := []struct {
+		name      string
+		srcTime   time.Time
+		dstTime   time.Time
+		reversed  bool
+		expectErr bool
+	}{
+		{name: "source is newer", srcTime: ft.Add(time.Minute), dstTime: ft},
+		{name: "destination is newer", srcTime: ft, dstTime: ft.Add(time.Minute), reversed: true},
+		{name: "destination is newer within skew tolerance", srcTime: ft, dstTime: ft.Add(time.Second), expectErr: true},
+		{name: "same age", srcTime: ft, dstTime: ft, expectErr: true},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			s := Sync{skewTolerance: 2 * time.Second}
+			src := &storage.Object{URL: srcurl, ModTime: timePtr(tc.srcTime)}
+			dst := &storage.Object{URL: dsturl, ModTime: timePtr(tc.dstTime)}
+
+			reversed, err := s.isTwoWayReversed(src, dst)
+			if (err != nil) != tc.expectErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if reversed != tc.reversed {
+				t.Errorf("expected reversed %v, got %v", tc.reversed, reversed)
+			}
+		})
+	}
+}
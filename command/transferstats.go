@@ -0,0 +1,235 @@
+package command
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/peak/s5cmd/v2/storage"
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+const (
+	transferSucceeded = "succeeded"
+	transferFailed    = "failed"
+	transferSkipped   = "skipped"
+	transferVanished  = "vanished"
+	transferPartial   = "partial"
+)
+
+var transferStatsHeader = []string{
+	"operation", "key", "bytes", "duration", "throughput", "retries", "status", "version_id",
+}
+
+// transferStats is the recorder of the transfer statistics of the objects. It
+// is nil unless the "stats-csv" flag is given.
+var transferStats *transferStatsWriter
+
+// transferStatsWriter writes the statistics of each transfer as a CSV record
+// as soon as the transfer is completed, so that the records of a long run are
+// not lost if it is interrupted.
+type transferStatsWriter struct {
+	mu   sync.Mutex
+	file *os.File
+	w    *csv.Writer
+	err  error
+}
+
+// openTransferStats creates the CSV file at path and writes its header.
+func openTransferStats(path string) (*transferStatsWriter, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &transferStatsWriter{file: file, w: csv.NewWriter(file)}
+	s.write(transferStatsHeader)
+	if s.err != nil {
+		file.Close()
+		return nil, s.err
+	}
+	return s, nil
+}
+
+// start starts recording a transfer of the object at srcurl. It returns the
+// context which the transfer must be made with and the function to call with
+// the result of the transfer once it is completed. It is a no-op if s is nil.
+func (s *transferStatsWriter) start(
+	ctx context.Context,
+	op string,
+	srcurl *url.URL,
+	size int64,
+) (context.Context, func(error)) {
+	if s == nil {
+		return ctx, func(error) {}
+	}
+
+	ctx = storage.WithRetryCounter(ctx)
+	ctx, marked := withTransferStatus(ctx)
+	start := time.Now()
+
+	return ctx, func(err error) {
+		duration := time.Since(start)
+		status := marked.result(err)
+
+		var throughput string
+		if status == transferSucceeded && duration > 0 {
+			throughput = strconv.FormatFloat(float64(size)/duration.Seconds(), 'f', 0, 64)
+		}
+
+		s.write([]string{
+			op,
+			srcurl.String(),
+			strconv.FormatInt(size, 10),
+			strconv.FormatFloat(duration.Seconds(), 'f', 6, 64),
+			throughput,
+			strconv.FormatInt(storage.RetryCount(ctx), 10),
+			status,
+			srcurl.VersionID,
+		})
+	}
+}
+
+// write writes the record and flushes it to the file. The first error is
+// kept and the records after it are dropped.
+func (s *transferStatsWriter) write(record []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return
+	}
+	if err := s.w.Write(record); err != nil {
+		s.err = err
+		return
+	}
+	s.w.Flush()
+	s.err = s.w.Error()
+}
+
+// Close closes the CSV file. It returns the first error the records are
+// written with, if any.
+func (s *transferStatsWriter) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := s.file.Close()
+	if s.err != nil {
+		return fmt.Errorf("could not write transfer statistics: %w", s.err)
+	}
+	return err
+}
+
+type transferStatusKey struct{}
+
+// transferStatus is the status of a transfer which is marked during the
+// transfer, along with its reason.
+type transferStatus struct {
+	status string
+	reason string
+	// destinations are the destinations of the transfer, once they are
+	// known.
+	destinations []*url.URL
+}
+
+// withTransferStatus returns the context which the status of a transfer is
+// marked with, along with the status. The status of ctx is returned if it's
+// already set, so that all recorders of a transfer share the same status.
+func withTransferStatus(ctx context.Context) (context.Context, *transferStatus) {
+	if status, ok := ctx.Value(transferStatusKey{}).(*transferStatus); ok {
+		return ctx, status
+	}
+	status := &transferStatus{}
+	return context.WithValue(ctx, transferStatusKey{}, status), status
+}
+
+// result returns the status of the transfer which is completed with err.
+func (s *transferStatus) result(err error) string {
+	switch {
+	case s.status == transferPartial:
+		// the partial transfers fail, but they are told apart from the
+		// transfers which failed at all destinations.
+		return s.status
+	case err != nil:
+		return transferFailed
+	case s.status != "":
+		return s.status
+	}
+	return transferSucceeded
+}
+
+// startTransfer starts recording a transfer of the object at srcurl to the
+// transfer statistics, the access log, the audit log and the changes of the
+// sync which runs it. It returns the context which the transfer must be made
+// with and the function to call with the result of the transfer once it is
+// completed.
+func startTransfer(
+	ctx context.Context,
+	op string,
+	srcurl *url.URL,
+	size int64,
+) (context.Context, func(error)) {
+	ctx, statsDone := transferStats.start(ctx, op, srcurl, size)
+	ctx, accessDone := accessLog.start(ctx, op, srcurl, size)
+	ctx, auditDone := auditLog.start(ctx, op, srcurl, size)
+	ctx, marked := withTransferStatus(ctx)
+	return ctx, func(err error) {
+		statsDone(err)
+		accessDone(err)
+		auditDone(err)
+		recordSyncTransfer(ctx, marked.result(err), size)
+	}
+}
+
+// transferDestination marks the destinations of the transfer made with ctx.
+func transferDestination(ctx context.Context, dsturls ...*url.URL) {
+	if marked, ok := ctx.Value(transferStatusKey{}).(*transferStatus); ok {
+		marked.destinations = dsturls
+	}
+}
+
+// skipTransfer marks the transfer made with ctx as skipped for the given
+// reason, e.g. because the destination is not overridden.
+func skipTransfer(ctx context.Context, reason error) {
+	markTransfer(ctx, transferSkipped, reason.Error())
+}
+
+// vanishTransfer marks the transfer made with ctx as vanished, i.e. the
+// pinned version of the source object, or the source file, doesn't exist
+// anymore.
+func vanishTransfer(ctx context.Context, srcurl *url.URL) {
+	if srcurl.IsRemote() {
+		markTransfer(ctx, transferVanished, "pinned version doesn't exist anymore")
+		return
+	}
+	markTransfer(ctx, transferVanished, "file doesn't exist anymore")
+}
+
+// partialTransfer marks the transfer made with ctx as partial, i.e. the
+// object is uploaded to some of the destinations given with --also-to.
+func partialTransfer(ctx context.Context) {
+	markTransfer(ctx, transferPartial, "")
+}
+
+func markTransfer(ctx context.Context, status, reason string) {
+	if marked, ok := ctx.Value(transferStatusKey{}).(*transferStatus); ok {
+		marked.status = status
+		marked.reason = reason
+	}
+}
+
+// closeTransferStats closes the transfer statistics file, if any, and reports
+// the error of writing it.
+func closeTransferStats(command string) {
+	if transferStats == nil {
+		return
+	}
+	if err := transferStats.Close(); err != nil {
+		printError(command, "stats-csv", err)
+	}
+	transferStats = nil
+}
@@ -0,0 +1,87 @@
+package command
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gotest.tools/v3/assert"
+
+	errorpkg "github.com/peak/s5cmd/v2/error"
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+func TestTransferStatsWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stats.csv")
+
+	stats, err := openTransferStats(path)
+	assert.NilError(t, err)
+
+	versions := map[string]string{"s3://bucket/d.txt": "v1"}
+
+	record := func(key string, size int64, result func(ctx context.Context) error) {
+		srcurl, err := url.New(key, url.WithVersion(versions[key]))
+		assert.NilError(t, err)
+
+		ctx, done := stats.start(context.Background(), "cp", srcurl, size)
+		done(result(ctx))
+	}
+
+	record("s3://bucket/a.txt", 10, func(context.Context) error { return nil })
+	record("s3://bucket/b.txt", 20, func(ctx context.Context) error {
+		skipTransfer(ctx, errorpkg.ErrObjectExists)
+		return nil
+	})
+	record("s3://bucket/c.txt", 30, func(context.Context) error { return fmt.Errorf("failed") })
+	record("s3://bucket/d.txt", 40, func(ctx context.Context) error {
+		vanishTransfer(ctx, mustNewURL(t, "s3://bucket/d.txt"))
+		return nil
+	})
+
+	// the records are flushed before the file is closed.
+	file, err := os.Open(path)
+	assert.NilError(t, err)
+	defer file.Close()
+
+	records, err := csv.NewReader(file).ReadAll()
+	assert.NilError(t, err)
+	assert.Equal(t, 5, len(records))
+	assert.DeepEqual(t, transferStatsHeader, records[0])
+
+	for i, expected := range []struct {
+		key    string
+		bytes  string
+		status string
+	}{
+		{key: "s3://bucket/a.txt", bytes: "10", status: transferSucceeded},
+		{key: "s3://bucket/b.txt", bytes: "20", status: transferSkipped},
+		{key: "s3://bucket/c.txt", bytes: "30", status: transferFailed},
+		{key: "s3://bucket/d.txt", bytes: "40", status: transferVanished},
+	} {
+		got := records[i+1]
+		assert.Equal(t, "cp", got[0])
+		assert.Equal(t, expected.key, got[1])
+		assert.Equal(t, expected.bytes, got[2])
+		assert.Equal(t, "0", got[5])
+		assert.Equal(t, expected.status, got[6])
+		assert.Equal(t, versions[expected.key], got[7])
+		// the throughput is only recorded for the succeeded transfers.
+		assert.Equal(t, expected.status == transferSucceeded, got[4] != "")
+	}
+
+	assert.NilError(t, stats.Close())
+}
+
+func TestTransferStatsWriterNil(t *testing.T) {
+	var stats *transferStatsWriter
+
+	srcurl, err := url.New("s3://bucket/key")
+	assert.NilError(t, err)
+
+	ctx, done := stats.start(context.Background(), "cp", srcurl, 10)
+	skipTransfer(ctx, errorpkg.ErrObjectExists)
+	done(nil)
+}
@@ -0,0 +1,219 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/kballard/go-shellquote"
+	"github.com/urfave/cli/v2"
+
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+const (
+	transformExecFlagName        = "transform-exec"
+	transformConcurrencyFlagName = "transform-concurrency"
+
+	defaultTransformConcurrency = 4
+
+	// transformStderrLimit is the size of the end of the standard error of
+	// a failed transform command which is kept in its error.
+	transformStderrLimit = 4 << 10
+)
+
+func newTransformFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:  transformExecFlagName,
+			Usage: "pipe the content of each object through the given command on its standard input and copy its standard output instead, e.g. \"gzip -c\"",
+		},
+		&cli.IntFlag{
+			Name:  transformConcurrencyFlagName,
+			Value: defaultTransformConcurrency,
+			Usage: "number of transform commands run at the same time, requires --transform-exec",
+		},
+	}
+}
+
+// transformSemaphore limits the number of the transform commands run at the
+// same time. It is shared by the commands of a run or a sync, which copy the
+// objects with the same flags, and it is created with the limit of the first
+// copy which transforms an object.
+var transformSemaphore struct {
+	once sync.Once
+	ch   chan struct{}
+}
+
+// transformExec pipes the content of the objects through the command given
+// with --transform-exec during the copy.
+type transformExec struct {
+	args        []string
+	concurrency int
+}
+
+// newTransformExec creates the transform command runner from the flags. It
+// returns nil if the "transform-exec" flag is not given.
+func newTransformExec(c *cli.Context) *transformExec {
+	command := c.String(transformExecFlagName)
+	if command == "" {
+		return nil
+	}
+	// the command is validated by validateTransformExec.
+	args, _ := shellquote.Split(command)
+	return &transformExec{
+		args:        args,
+		concurrency: c.Int(transformConcurrencyFlagName),
+	}
+}
+
+// pipe runs the transform command with src on its standard input and calls
+// consume with its standard output. The output reports the failure of the
+// command instead of its end, so that a truncated output is never consumed
+// as a whole. It returns the size of the output.
+func (t *transformExec) pipe(ctx context.Context, src io.Reader, consume func(io.Reader) error) (int64, error) {
+	transformSemaphore.once.Do(func() {
+		transformSemaphore.ch = make(chan struct{}, t.concurrency)
+	})
+	select {
+	case transformSemaphore.ch <- struct{}{}:
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+	defer func() { <-transformSemaphore.ch }()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// the arguments are not passed through a shell, as the ones of the
+	// compare command.
+	cmd := exec.CommandContext(ctx, t.args[0], t.args[1:]...)
+	cmd.Stdin = src
+	stderr := &tailBuffer{limit: transformStderrLimit}
+	cmd.Stderr = stderr
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return 0, err
+	}
+	if err := cmd.Start(); err != nil {
+		return 0, fmt.Errorf("transform command failed: %w", err)
+	}
+
+	output := &transformOutput{
+		reader: stdout,
+		wait: func() error {
+			if err := cmd.Wait(); err != nil {
+				if msg := strings.TrimSpace(stderr.String()); msg != "" {
+					return fmt.Errorf("transform command failed: %w: %v", err, msg)
+				}
+				return fmt.Errorf("transform command failed: %w", err)
+			}
+			return nil
+		},
+	}
+
+	err = consume(output)
+	// the error of the command is reported rather than the error of consume
+	// reading its output.
+	if output.waited && output.waitErr != nil {
+		return output.size, output.waitErr
+	}
+	// the command is killed if its output is not consumed to the end.
+	cancel()
+	if werr := output.finish(); err == nil {
+		err = werr
+	}
+	return output.size, err
+}
+
+// transformOutput is the standard output of a transform command. It returns
+// the error of the command instead of io.EOF if the command fails.
+type transformOutput struct {
+	reader io.Reader
+	size   int64
+
+	wait    func() error
+	waited  bool
+	waitErr error
+}
+
+func (o *transformOutput) Read(p []byte) (int, error) {
+	n, err := o.reader.Read(p)
+	o.size += int64(n)
+	if err == io.EOF {
+		if werr := o.finish(); werr != nil {
+			return n, werr
+		}
+	}
+	return n, err
+}
+
+// finish waits for the command to exit, once, and returns its error.
+func (o *transformOutput) finish() error {
+	if !o.waited {
+		o.waited = true
+		o.waitErr = o.wait()
+	}
+	return o.waitErr
+}
+
+// tailBuffer keeps the last limit bytes written to it.
+type tailBuffer struct {
+	mu    sync.Mutex
+	buf   bytes.Buffer
+	limit int
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.buf.Write(p)
+	if excess := b.buf.Len() - b.limit; excess > 0 {
+		b.buf.Next(excess)
+	}
+	return len(p), nil
+}
+
+func (b *tailBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func validateTransformExec(c *cli.Context, dsturl *url.URL) error {
+	command := c.String(transformExecFlagName)
+	if c.IsSet(transformConcurrencyFlagName) && command == "" {
+		return fmt.Errorf("%q flag requires %q flag", transformConcurrencyFlagName, transformExecFlagName)
+	}
+	if command == "" {
+		return nil
+	}
+	args, err := shellquote.Split(command)
+	if err != nil {
+		return fmt.Errorf("invalid %q flag: %w", transformExecFlagName, err)
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("%q flag requires a command", transformExecFlagName)
+	}
+	if c.Int(transformConcurrencyFlagName) < 1 {
+		return fmt.Errorf("%q flag must be greater than zero", transformConcurrencyFlagName)
+	}
+	if !dsturl.IsRemote() {
+		return fmt.Errorf("%q flag can only be used with remote destinations", transformExecFlagName)
+	}
+
+	// the size and the checksum of a transformed object are not the ones
+	// of its source.
+	for _, flag := range []string{"append-detect", "expected-size", alsoToFlagName} {
+		if c.IsSet(flag) {
+			return fmt.Errorf("it is not allowed to combine %q and %q flags", transformExecFlagName, flag)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"io"
+	"runtime"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestTransformExec(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("transform commands require a POSIX shell")
+	}
+
+	testcases := []struct {
+		name     string
+		args     []string
+		expected string
+		err      string
+	}{
+		{
+			name:     "transformed",
+			args:     []string{"tr", "a-z", "A-Z"},
+			expected: "CONTENT",
+		},
+		{
+			name: "command fails",
+			args: []string{"sh", "-c", "cat; echo broken input >&2; exit 3"},
+			err:  "transform command failed: exit status 3: broken input",
+		},
+		{
+			name: "command not found",
+			args: []string{"s5cmd-no-such-transform"},
+			err:  "transform command failed",
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			transform := &transformExec{args: tc.args, concurrency: 2}
+
+			var output string
+			size, err := transform.pipe(context.Background(), strings.NewReader("content"), func(r io.Reader) error {
+				data, err := io.ReadAll(r)
+				output = string(data)
+				return err
+			})
+			if tc.err != "" {
+				assert.ErrorContains(t, err, tc.err)
+				return
+			}
+			assert.NilError(t, err)
+			assert.Equal(t, output, tc.expected)
+			assert.Equal(t, size, int64(len(tc.expected)))
+		})
+	}
+}
+
+func TestTransformExecConsumeError(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("transform commands require a POSIX shell")
+	}
+
+	errConsume := errors.New("upload failed")
+	transform := &transformExec{args: []string{"yes"}, concurrency: 1}
+
+	// the command writing endlessly is killed once its output is not read.
+	_, err := transform.pipe(context.Background(), strings.NewReader(""), func(r io.Reader) error {
+		_, _ = r.Read(make([]byte, 16))
+		return errConsume
+	})
+	assert.Equal(t, err, errConsume)
+}
+
+func TestTailBuffer(t *testing.T) {
+	b := &tailBuffer{limit: 4}
+	_, _ = b.Write([]byte("abc"))
+	_, _ = b.Write([]byte("defg"))
+	assert.Equal(t, b.String(), "defg")
+}
+
+func TestTransformExecQuotedArguments(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("transform commands require a POSIX shell")
+	}
+
+	c := newCommandContext(t, "cp", map[string][]string{
+		transformExecFlagName: {`sh -c 'tr a-z A-Z; echo " done"'`},
+	})
+	assert.NilError(t, validateTransformExec(c, mustNewURL(t, "s3://bucket/prefix/")))
+
+	transform := newTransformExec(c)
+	assert.DeepEqual(t, transform.args, []string{"sh", "-c", `tr a-z A-Z; echo " done"`})
+
+	var output string
+	_, err := transform.pipe(context.Background(), strings.NewReader("content"), func(r io.Reader) error {
+		data, err := io.ReadAll(r)
+		output = string(data)
+		return err
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, output, "CONTENT done\n")
+}
+
+func TestValidateTransformExecCommand(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		command  string
+		expected string
+	}{
+		{name: "quoted arguments", command: `gzip -c "-9"`},
+		{name: "unterminated quote", command: `sh -c 'gzip -9`, expected: `invalid "transform-exec" flag: Unterminated single-quoted string`},
+		{name: "blank command", command: "  ", expected: `"transform-exec" flag requires a command`},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			c := newCommandContext(t, "cp", map[string][]string{transformExecFlagName: {tc.command}})
+			err := validateTransformExec(c, mustNewURL(t, "s3://bucket/prefix/"))
+			if tc.expected == "" {
+				assert.NilError(t, err)
+				return
+			}
+			assert.Error(t, err, tc.expected)
+		})
+	}
+}
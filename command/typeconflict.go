@@ -0,0 +1,98 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"syscall"
+
+	errorpkg "github.com/peak/s5cmd/v2/error"
+	"github.com/peak/s5cmd/v2/storage"
+	"github.com/peak/s5cmd/v2/storage/url"
+	"github.com/peak/s5cmd/v2/strutil"
+)
+
+const (
+	// typeConflictFail reports the type conflicts as errors.
+	typeConflictFail = "fail"
+	// typeConflictSkip skips the objects whose destinations have type
+	// conflicts.
+	typeConflictSkip = "skip"
+	// typeConflictReplace removes the conflicting entries before writing the
+	// objects.
+	typeConflictReplace = "replace"
+)
+
+// TypeConflictMessage is the structure for the objects which are not
+// downloaded because of a type conflict on the destination.
+type TypeConflictMessage struct {
+	Source      *url.URL
+	Destination *url.URL
+	Err         error
+}
+
+// String is the string representation of TypeConflictMessage.
+func (m TypeConflictMessage) String() string {
+	return fmt.Sprintf("skip %v %v: %v", m.Source, m.Destination, m.Err)
+}
+
+// JSON is the JSON representation of TypeConflictMessage.
+func (m TypeConflictMessage) JSON() string {
+	return strutil.JSON(struct {
+		Operation   string   `json:"operation"`
+		Status      string   `json:"status"`
+		Source      *url.URL `json:"source"`
+		Destination *url.URL `json:"destination"`
+		Message     string   `json:"message"`
+	}{
+		Operation:   "skip",
+		Status:      "type_conflict",
+		Source:      m.Source,
+		Destination: m.Destination,
+		Message:     m.Err.Error(),
+	})
+}
+
+// resolveTypeConflict checks that a file can be downloaded to dsturl. See
+// resolveTypeConflict function for details.
+func (c Copy) resolveTypeConflict(ctx context.Context, client *storage.Filesystem, dsturl *url.URL) error {
+	return resolveTypeConflict(ctx, client, dsturl, c.onTypeConflict, c.force)
+}
+
+// resolveTypeConflict checks that a file can be downloaded to dsturl. If the
+// path of the file exists as a directory, or the path of one of its parent
+// directories exists as a file, the conflicting entry is removed if the
+// action is "replace", recursively only if force is set. Otherwise, an error
+// wrapping errorpkg.ErrTypeConflict is returned.
+func resolveTypeConflict(
+	ctx context.Context,
+	client *storage.Filesystem,
+	dsturl *url.URL,
+	action string,
+	force bool,
+) error {
+	conflict, err := client.FindTypeConflict(dsturl.Absolute())
+	if err != nil || conflict == nil {
+		return err
+	}
+
+	if action != typeConflictReplace {
+		if conflict.IsDir {
+			return fmt.Errorf("%w: %v is a directory", errorpkg.ErrTypeConflict, conflict.Path)
+		}
+		return fmt.Errorf("%w: %v is not a directory", errorpkg.ErrTypeConflict, conflict.Path)
+	}
+
+	if conflict.IsDir && force {
+		return client.RemoveAll(conflict.Path)
+	}
+
+	err = client.Delete(ctx, &url.URL{Path: conflict.Path, Type: dsturl.Type})
+	if errors.Is(err, syscall.ENOTEMPTY) || errors.Is(err, syscall.EEXIST) {
+		return fmt.Errorf(
+			"%w: %v is a non-empty directory; use --force flag to replace it",
+			errorpkg.ErrTypeConflict, conflict.Path,
+		)
+	}
+	return err
+}
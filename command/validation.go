@@ -13,6 +13,7 @@ const (
 	versioningNotSupportedWarning = "versioning related features are not supported with the given endpoint %q"
 	allVersionsFlagName           = "all-versions"
 	versionIDFlagName             = "version-id"
+	pinVersionsFlagName           = "pin-versions"
 )
 
 // checkVersinoningURLRemote checks if the versioning related flags are used with
@@ -48,7 +49,7 @@ func checkVersioningWithGoogleEndpoint(ctx *cli.Context) error {
 		return err
 	}
 
-	if storage.IsGoogleEndpoint(*u) && (ctx.Bool(allVersionsFlagName) || ctx.String(versionIDFlagName) != "") {
+	if storage.IsGoogleEndpoint(*u) && (ctx.Bool(allVersionsFlagName) || ctx.String(versionIDFlagName) != "" || ctx.Bool(pinVersionsFlagName)) {
 		return fmt.Errorf(versioningNotSupportedWarning, endpoint)
 	}
 
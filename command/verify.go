@@ -0,0 +1,93 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/peak/s5cmd/v2/storage"
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+// verifiedBuckets caches the results of bucket verifications, so that the
+// commands of a "run" or a "sync" operation check each bucket only once.
+var verifiedBuckets = &bucketVerificationCache{
+	results: map[bucketVerificationKey]error{},
+}
+
+type bucketVerificationKey struct {
+	opts   storage.Options
+	bucket string
+}
+
+type bucketVerificationCache struct {
+	mu      sync.Mutex
+	results map[bucketVerificationKey]error
+}
+
+// verify checks whether the bucket of the given remote URL exists and is
+// accessible. Local URLs are not checked.
+func (c *bucketVerificationCache) verify(ctx context.Context, u *url.URL, opts storage.Options) error {
+	if !u.IsRemote() {
+		return nil
+	}
+
+	key := bucketVerificationKey{opts: opts, bucket: u.Bucket}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err, ok := c.results[key]; ok {
+		return err
+	}
+
+	// region detection of the client requests the bucket as well.
+	client, err := storage.NewRemoteClient(ctx, u, opts)
+	if err == nil {
+		err = client.HeadBucket(ctx, u.Bucket)
+	}
+
+	switch {
+	case err == nil:
+	case storage.IsNoSuchBucketError(err):
+		err = fmt.Errorf("bucket %q does not exist", u.Bucket)
+	case storage.IsAccessDeniedError(err):
+		err = fmt.Errorf("access to bucket %q is denied", u.Bucket)
+	default:
+		// do not cache transient errors such as timeouts and cancelations.
+		return fmt.Errorf("bucket %q could not be verified: %w", u.Bucket, err)
+	}
+
+	c.results[key] = err
+	return err
+}
+
+// verifyBuckets checks whether the source and destination buckets exist and
+// are accessible, before any listing begins. Regions, if given, override the
+// region of the storage options for the corresponding bucket.
+func verifyBuckets(
+	ctx context.Context,
+	opts storage.Options,
+	srcurl *url.URL,
+	srcRegion string,
+	dsturl *url.URL,
+	dstRegion string,
+) error {
+	srcOpts := opts
+	if srcRegion != "" {
+		srcOpts.SetRegion(srcRegion)
+	}
+	if err := verifiedBuckets.verify(ctx, srcurl, srcOpts); err != nil {
+		return fmt.Errorf("source %w", err)
+	}
+
+	dstOpts := opts
+	if dstRegion != "" {
+		dstOpts.SetRegion(dstRegion)
+	}
+	if err := verifiedBuckets.verify(ctx, dsturl, dstOpts); err != nil {
+		return fmt.Errorf("destination %w", err)
+	}
+
+	return nil
+}
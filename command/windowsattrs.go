@@ -0,0 +1,94 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/peak/s5cmd/v2/log"
+	"github.com/peak/s5cmd/v2/storage"
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+const preserveWindowsAttrsFlagName = "preserve-windows-attrs"
+
+func newPreserveWindowsAttrsFlag() cli.Flag {
+	return &cli.BoolFlag{
+		Name:  preserveWindowsAttrsFlagName,
+		Usage: "store the read-only, hidden, system and archive attributes of the uploaded files in the object metadata and restore them on download, only supported on Windows",
+	}
+}
+
+// alternateDataStreamFiles is the number of the uploaded files whose alternate
+// data streams are not uploaded. It is reported once the command completes.
+var alternateDataStreamFiles int64
+
+// warnAlternateDataStreams warns if the file of srcurl has alternate data
+// streams, which are not uploaded with its content. It is a no-op on the
+// platforms other than Windows.
+func warnAlternateDataStreams(op string, client *storage.Filesystem, srcurl *url.URL) {
+	streams, err := client.AlternateDataStreams(srcurl.Absolute())
+	if errors.Is(err, storage.ErrWindowsAttributesNotSupported) {
+		return
+	}
+	if err != nil {
+		printDebug(op, err, srcurl)
+		return
+	}
+	if len(streams) == 0 {
+		return
+	}
+
+	atomic.AddInt64(&alternateDataStreamFiles, 1)
+	log.Warning(log.WarningMessage{
+		Operation: op,
+		Warning:   fmt.Sprintf("%v has alternate data streams which are not uploaded: %v", srcurl, strings.Join(streams, ", ")),
+	})
+}
+
+// printAlternateDataStreamsSummary warns about the number of the uploaded
+// files whose alternate data streams are not uploaded, if any.
+func printAlternateDataStreamsSummary() {
+	if n := atomic.LoadInt64(&alternateDataStreamFiles); n > 0 {
+		log.Warning(log.WarningMessage{
+			Warning: fmt.Sprintf("%d files have alternate data streams which are not uploaded", n),
+		})
+	}
+}
+
+// windowsAttributesMetadata returns the attributes of the file of srcurl as
+// they are kept in the object metadata.
+func windowsAttributesMetadata(client *storage.Filesystem, srcurl *url.URL) (string, error) {
+	attrs, err := client.WindowsAttributes(srcurl.Absolute())
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatUint(uint64(attrs), 10), nil
+}
+
+// restoreWindowsAttributes sets the attributes of the downloaded file of
+// dsturl to the ones kept in the metadata of obj. The files of the objects
+// uploaded without their attributes are left as they are.
+func restoreWindowsAttributes(client *storage.Filesystem, obj *storage.Object, dsturl *url.URL) error {
+	if obj.WindowsAttributes == "" {
+		return nil
+	}
+
+	attrs, err := strconv.ParseUint(obj.WindowsAttributes, 10, 32)
+	if err != nil {
+		return fmt.Errorf("invalid windows attributes %q of %v: %w", obj.WindowsAttributes, obj.URL, err)
+	}
+	return client.SetWindowsAttributes(dsturl.Absolute(), uint32(attrs))
+}
+
+func validatePreserveWindowsAttrs(c *cli.Context) error {
+	if c.Bool(preserveWindowsAttrsFlagName) && runtime.GOOS != "windows" {
+		return fmt.Errorf("%q flag: %w", preserveWindowsAttrsFlagName, storage.ErrWindowsAttributesNotSupported)
+	}
+	return nil
+}
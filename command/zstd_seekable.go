@@ -0,0 +1,284 @@
+package command
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/peak/s5cmd/v2/storage"
+)
+
+// The zstd seekable format stores the content in independently compressed
+// frames, followed by a skippable frame holding the seek table. The table
+// lists the compressed and the decompressed sizes of the frames and ends with
+// a footer:
+//
+//	Skippable_Magic_Number (4) | Frame_Size (4) |
+//	  Seek_Table_Entries (8 or 12 each) |
+//	  Number_Of_Frames (4) | Seek_Table_Descriptor (1) | Seekable_Magic_Number (4)
+//
+// All of the numbers are little endian.
+const (
+	zstdSkippableMagic       = 0x184D2A5E
+	zstdSeekableMagic        = 0x8F92EAB1
+	zstdSkippableHeaderSize  = 8
+	zstdSeekTableFooterSize  = 9
+	zstdSeekTableChecksumBit = 1 << 7
+	zstdSeekTableReserved    = 0x7c
+
+	// zstdSeekableMetadata is the user-defined metadata which marks the
+	// objects in zstd seekable format, besides the .zst suffix of their keys.
+	zstdSeekableMetadata = "zstd-seekable"
+)
+
+// errNotZstdSeekable is returned for the objects which are marked as zstd
+// seekable but don't end with a seek table.
+var errNotZstdSeekable = errors.New("object is not in zstd seekable format")
+
+// zstdFrame is an entry of the seek table, along with the offsets of the frame
+// in the compressed and in the decompressed content.
+type zstdFrame struct {
+	compressedOffset   int64
+	compressedSize     int64
+	decompressedOffset int64
+	decompressedSize   int64
+}
+
+// zstdSeekTable is the seek table of an object in zstd seekable format.
+type zstdSeekTable struct {
+	frames []zstdFrame
+}
+
+// isZstdSeekableCandidate reports whether the object may be in zstd seekable
+// format, which is confirmed by the magic number of its seek table.
+func isZstdSeekableCandidate(obj *storage.Object) bool {
+	if _, ok := obj.UserMetadata[zstdSeekableMetadata]; ok {
+		return true
+	}
+	return strings.HasSuffix(obj.URL.Path, ".zst")
+}
+
+// zstdSeekTableFooter is the footer at the end of a seek table.
+type zstdSeekTableFooter struct {
+	frames    int
+	entrySize int64
+	// tableSize is the size of the skippable frame of the table.
+	tableSize int64
+}
+
+// parseZstdSeekTableFooter parses the footer at the end of a seek table.
+func parseZstdSeekTableFooter(data []byte) (zstdSeekTableFooter, error) {
+	if len(data) != zstdSeekTableFooterSize ||
+		binary.LittleEndian.Uint32(data[5:]) != zstdSeekableMagic {
+		return zstdSeekTableFooter{}, errNotZstdSeekable
+	}
+
+	descriptor := data[4]
+	if descriptor&zstdSeekTableReserved != 0 {
+		return zstdSeekTableFooter{}, fmt.Errorf("%w: reserved bits of the seek table are set", errNotZstdSeekable)
+	}
+
+	footer := zstdSeekTableFooter{
+		frames:    int(binary.LittleEndian.Uint32(data)),
+		entrySize: 8,
+	}
+	if descriptor&zstdSeekTableChecksumBit != 0 {
+		footer.entrySize = 12
+	}
+	footer.tableSize = zstdSkippableHeaderSize + int64(footer.frames)*footer.entrySize + zstdSeekTableFooterSize
+	return footer, nil
+}
+
+// parseZstdSeekTable parses the skippable frame of the seek table, which is at
+// the end of an object of the given size. The checksums of the frames are not
+// verified.
+func parseZstdSeekTable(data []byte, objectSize int64) (*zstdSeekTable, error) {
+	if len(data) < zstdSkippableHeaderSize+zstdSeekTableFooterSize {
+		return nil, errNotZstdSeekable
+	}
+
+	footer, err := parseZstdSeekTableFooter(data[len(data)-zstdSeekTableFooterSize:])
+	if err != nil {
+		return nil, err
+	}
+	size := footer.tableSize
+	if size != int64(len(data)) ||
+		binary.LittleEndian.Uint32(data) != zstdSkippableMagic ||
+		int64(binary.LittleEndian.Uint32(data[4:])) != size-zstdSkippableHeaderSize {
+		return nil, fmt.Errorf("%w: invalid seek table", errNotZstdSeekable)
+	}
+
+	table := &zstdSeekTable{
+		frames: make([]zstdFrame, 0, footer.frames),
+	}
+
+	var compressed, decompressed int64
+	for i := 0; i < footer.frames; i++ {
+		entry := data[zstdSkippableHeaderSize+int64(i)*footer.entrySize:]
+		frame := zstdFrame{
+			compressedOffset:   compressed,
+			compressedSize:     int64(binary.LittleEndian.Uint32(entry)),
+			decompressedOffset: decompressed,
+			decompressedSize:   int64(binary.LittleEndian.Uint32(entry[4:])),
+		}
+		table.frames = append(table.frames, frame)
+		compressed += frame.compressedSize
+		decompressed += frame.decompressedSize
+	}
+
+	if compressed+size != objectSize {
+		return nil, fmt.Errorf("%w: the seek table doesn't match the size of the object", errNotZstdSeekable)
+	}
+	return table, nil
+}
+
+// decompressedSize returns the size of the decompressed content.
+func (t *zstdSeekTable) decompressedSize() int64 {
+	if len(t.frames) == 0 {
+		return 0
+	}
+	last := t.frames[len(t.frames)-1]
+	return last.decompressedOffset + last.decompressedSize
+}
+
+// framesOf returns the frames holding the given range of the decompressed
+// content, up to its end if length is negative.
+func (t *zstdSeekTable) framesOf(offset, length int64) []zstdFrame {
+	end := t.decompressedSize()
+	if length >= 0 && offset+length < end {
+		end = offset + length
+	}
+
+	var frames []zstdFrame
+	for _, frame := range t.frames {
+		if frame.decompressedOffset+frame.decompressedSize <= offset {
+			continue
+		}
+		if frame.decompressedOffset >= end {
+			break
+		}
+		frames = append(frames, frame)
+	}
+	return frames
+}
+
+// readRangeFunc reads the given byte range of an object, up to its end if
+// length is negative.
+type readRangeFunc func(offset, length int64) (io.ReadCloser, error)
+
+// readZstdSeekableRange writes the given range of the decompressed content of
+// an object in zstd seekable format to w, up to its end if length is negative.
+// Only the seek table and the frames holding the range are read.
+func readZstdSeekableRange(read readRangeFunc, objectSize, offset, length int64, w io.Writer) (int64, error) {
+	if objectSize < zstdSkippableHeaderSize+zstdSeekTableFooterSize {
+		return 0, errNotZstdSeekable
+	}
+
+	data, err := readAllRange(read, objectSize-zstdSeekTableFooterSize, zstdSeekTableFooterSize)
+	if err != nil {
+		return 0, err
+	}
+	footer, err := parseZstdSeekTableFooter(data)
+	if err != nil {
+		return 0, err
+	}
+	tableSize := footer.tableSize
+	if tableSize > objectSize {
+		return 0, fmt.Errorf("%w: the seek table doesn't match the size of the object", errNotZstdSeekable)
+	}
+
+	data, err = readAllRange(read, objectSize-tableSize, tableSize)
+	if err != nil {
+		return 0, err
+	}
+	table, err := parseZstdSeekTable(data, objectSize)
+	if err != nil {
+		return 0, err
+	}
+
+	if offset > table.decompressedSize() {
+		return 0, fmt.Errorf("offset %d is beyond the decompressed size %d of the object", offset, table.decompressedSize())
+	}
+
+	frames := table.framesOf(offset, length)
+	if len(frames) == 0 {
+		return 0, nil
+	}
+
+	// the frames are adjacent, they are fetched with a single request.
+	first, last := frames[0], frames[len(frames)-1]
+	body, err := read(first.compressedOffset, last.compressedOffset+last.compressedSize-first.compressedOffset)
+	if err != nil {
+		return 0, err
+	}
+	defer body.Close()
+
+	decoder, err := zstd.NewReader(nil, zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		return 0, err
+	}
+	defer decoder.Close()
+
+	end := int64(-1)
+	if length >= 0 {
+		end = offset + length
+	}
+
+	var (
+		written    int64
+		compressed []byte
+		content    []byte
+	)
+	for _, frame := range frames {
+		if int64(cap(compressed)) < frame.compressedSize {
+			compressed = make([]byte, frame.compressedSize)
+		}
+		compressed = compressed[:frame.compressedSize]
+		if _, err := io.ReadFull(body, compressed); err != nil {
+			return written, err
+		}
+
+		content, err = decoder.DecodeAll(compressed, content[:0])
+		if err != nil {
+			return written, fmt.Errorf("could not decompress the object: %w", err)
+		}
+		if int64(len(content)) != frame.decompressedSize {
+			return written, fmt.Errorf("%w: a frame doesn't match the seek table", errNotZstdSeekable)
+		}
+
+		// the part of the frame in the range.
+		from, to := int64(0), frame.decompressedSize
+		if offset > frame.decompressedOffset {
+			from = offset - frame.decompressedOffset
+		}
+		if end >= 0 && end < frame.decompressedOffset+to {
+			to = end - frame.decompressedOffset
+		}
+
+		n, err := w.Write(content[from:to])
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// readAllRange reads the given byte range of an object.
+func readAllRange(read readRangeFunc, offset, length int64) ([]byte, error) {
+	body, err := read(offset, length)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	data := make([]byte, length)
+	if _, err := io.ReadFull(body, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
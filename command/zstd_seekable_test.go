@@ -0,0 +1,280 @@
+package command
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"gotest.tools/v3/assert"
+)
+
+// newZstdSeekable compresses each of the frames separately and appends their
+// seek table, with checksums if withChecksum is set.
+func newZstdSeekable(t *testing.T, frames []string, withChecksum bool) []byte {
+	t.Helper()
+
+	encoder, err := zstd.NewWriter(nil)
+	assert.NilError(t, err)
+	defer encoder.Close()
+
+	var (
+		content []byte
+		entries []byte
+	)
+	for _, frame := range frames {
+		compressed := encoder.EncodeAll([]byte(frame), nil)
+		content = append(content, compressed...)
+		entries = binary.LittleEndian.AppendUint32(entries, uint32(len(compressed)))
+		entries = binary.LittleEndian.AppendUint32(entries, uint32(len(frame)))
+		if withChecksum {
+			entries = binary.LittleEndian.AppendUint32(entries, 0)
+		}
+	}
+
+	var descriptor byte
+	if withChecksum {
+		descriptor = zstdSeekTableChecksumBit
+	}
+
+	table := binary.LittleEndian.AppendUint32(nil, zstdSkippableMagic)
+	table = binary.LittleEndian.AppendUint32(table, uint32(len(entries)+zstdSeekTableFooterSize))
+	table = append(table, entries...)
+	table = binary.LittleEndian.AppendUint32(table, uint32(len(frames)))
+	table = append(table, descriptor)
+	table = binary.LittleEndian.AppendUint32(table, zstdSeekableMagic)
+
+	return append(content, table...)
+}
+
+// rangeRecorder reads the ranges of data and records them.
+type rangeRecorder struct {
+	data   []byte
+	ranges [][2]int64
+}
+
+func (r *rangeRecorder) read(offset, length int64) (io.ReadCloser, error) {
+	r.ranges = append(r.ranges, [2]int64{offset, length})
+	end := int64(len(r.data))
+	if length >= 0 && offset+length < end {
+		end = offset + length
+	}
+	return io.NopCloser(bytes.NewReader(r.data[offset:end])), nil
+}
+
+func TestParseZstdSeekTable(t *testing.T) {
+	t.Parallel()
+
+	frames := []string{"first frame\n", "", strings.Repeat("second frame\n", 100)}
+	for _, withChecksum := range []bool{false, true} {
+		data := newZstdSeekable(t, frames, withChecksum)
+
+		footer, err := parseZstdSeekTableFooter(data[len(data)-zstdSeekTableFooterSize:])
+		assert.NilError(t, err)
+		assert.Equal(t, footer.frames, len(frames))
+
+		table, err := parseZstdSeekTable(data[int64(len(data))-footer.tableSize:], int64(len(data)))
+		assert.NilError(t, err)
+		assert.Equal(t, len(table.frames), len(frames))
+		assert.Equal(t, table.decompressedSize(), int64(len(strings.Join(frames, ""))))
+
+		var compressed, decompressed int64
+		for i, frame := range table.frames {
+			assert.Equal(t, frame.compressedOffset, compressed, "frame %v", i)
+			assert.Equal(t, frame.decompressedOffset, decompressed, "frame %v", i)
+			assert.Equal(t, frame.decompressedSize, int64(len(frames[i])), "frame %v", i)
+			compressed += frame.compressedSize
+			decompressed += frame.decompressedSize
+		}
+		assert.Equal(t, compressed+footer.tableSize, int64(len(data)))
+	}
+}
+
+func TestParseZstdSeekTableInvalid(t *testing.T) {
+	t.Parallel()
+
+	data := newZstdSeekable(t, []string{"content"}, false)
+	tableSize := int64(zstdSkippableHeaderSize + 8 + zstdSeekTableFooterSize)
+	table := data[int64(len(data))-tableSize:]
+
+	testcases := []struct {
+		name   string
+		modify func([]byte)
+		size   int64
+	}{
+		{
+			name:   "seekable magic",
+			modify: func(b []byte) { b[len(b)-1] = 0 },
+		},
+		{
+			name:   "skippable magic",
+			modify: func(b []byte) { b[0] = 0 },
+		},
+		{
+			name:   "reserved bits",
+			modify: func(b []byte) { b[len(b)-5] = 0x04 },
+		},
+		{
+			name:   "frame count",
+			modify: func(b []byte) { b[len(b)-9] = 2 },
+		},
+		{
+			name: "object size",
+			size: int64(len(data)) + 1,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			invalid := append([]byte(nil), table...)
+			if tc.modify != nil {
+				tc.modify(invalid)
+			}
+			size := int64(len(data))
+			if tc.size != 0 {
+				size = tc.size
+			}
+
+			_, err := parseZstdSeekTable(invalid, size)
+			assert.Assert(t, errors.Is(err, errNotZstdSeekable), "unexpected error: %v", err)
+		})
+	}
+}
+
+func TestZstdSeekTableFramesOf(t *testing.T) {
+	t.Parallel()
+
+	table := &zstdSeekTable{frames: []zstdFrame{
+		{compressedOffset: 0, compressedSize: 5, decompressedOffset: 0, decompressedSize: 10},
+		{compressedOffset: 5, compressedSize: 5, decompressedOffset: 10, decompressedSize: 10},
+		{compressedOffset: 10, compressedSize: 5, decompressedOffset: 20, decompressedSize: 10},
+	}}
+
+	testcases := []struct {
+		name     string
+		offset   int64
+		length   int64
+		expected []int64
+	}{
+		{name: "whole content", offset: 0, length: -1, expected: []int64{0, 10, 20}},
+		{name: "within a frame", offset: 12, length: 5, expected: []int64{10}},
+		{name: "across frames", offset: 5, length: 10, expected: []int64{0, 10}},
+		{name: "up to the end", offset: 15, length: -1, expected: []int64{10, 20}},
+		{name: "frame boundary", offset: 10, length: 10, expected: []int64{10}},
+		{name: "beyond the end", offset: 25, length: 100, expected: []int64{20}},
+		{name: "at the end", offset: 30, length: -1},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var offsets []int64
+			for _, frame := range table.framesOf(tc.offset, tc.length) {
+				offsets = append(offsets, frame.decompressedOffset)
+			}
+			assert.DeepEqual(t, offsets, tc.expected)
+		})
+	}
+}
+
+func TestReadZstdSeekableRange(t *testing.T) {
+	t.Parallel()
+
+	frames := []string{
+		strings.Repeat("a", 100),
+		strings.Repeat("b", 100),
+		strings.Repeat("c", 100),
+		strings.Repeat("d", 50),
+	}
+	content := strings.Join(frames, "")
+	data := newZstdSeekable(t, frames, false)
+
+	testcases := []struct {
+		name   string
+		offset int64
+		length int64
+		// requests is the number of ranged reads, which is 2 for the seek
+		// table and 1 for the frames.
+		requests int
+	}{
+		{name: "whole content", offset: 0, length: -1, requests: 3},
+		{name: "within a frame", offset: 110, length: 20, requests: 3},
+		{name: "across frames", offset: 150, length: 100, requests: 3},
+		{name: "last frame up to the end", offset: 320, length: -1, requests: 3},
+		{name: "length beyond the end", offset: 340, length: 1000, requests: 3},
+		{name: "at the end", offset: 350, length: -1, requests: 2},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			recorder := &rangeRecorder{data: data}
+			var buf bytes.Buffer
+			n, err := readZstdSeekableRange(recorder.read, int64(len(data)), tc.offset, tc.length, &buf)
+			assert.NilError(t, err)
+
+			end := int64(len(content))
+			if tc.length >= 0 && tc.offset+tc.length < end {
+				end = tc.offset + tc.length
+			}
+			assert.Equal(t, buf.String(), content[tc.offset:end])
+			assert.Equal(t, n, end-tc.offset)
+			assert.Equal(t, len(recorder.ranges), tc.requests)
+		})
+	}
+}
+
+func TestReadZstdSeekableRangeFetchesOnlyTheFrames(t *testing.T) {
+	t.Parallel()
+
+	frames := []string{strings.Repeat("a", 1000), strings.Repeat("b", 1000), strings.Repeat("c", 1000)}
+	data := newZstdSeekable(t, frames, false)
+
+	recorder := &rangeRecorder{data: data}
+	var buf bytes.Buffer
+	_, err := readZstdSeekableRange(recorder.read, int64(len(data)), 1500, 10, &buf)
+	assert.NilError(t, err)
+	assert.Equal(t, buf.String(), strings.Repeat("b", 10))
+
+	// only the second frame is fetched.
+	footer, err := parseZstdSeekTableFooter(data[len(data)-zstdSeekTableFooterSize:])
+	assert.NilError(t, err)
+	table, err := parseZstdSeekTable(data[int64(len(data))-footer.tableSize:], int64(len(data)))
+	assert.NilError(t, err)
+
+	second := table.frames[1]
+	assert.DeepEqual(t, recorder.ranges[len(recorder.ranges)-1], [2]int64{second.compressedOffset, second.compressedSize})
+}
+
+func TestReadZstdSeekableRangeNotSeekable(t *testing.T) {
+	t.Parallel()
+
+	encoder, err := zstd.NewWriter(nil)
+	assert.NilError(t, err)
+	data := encoder.EncodeAll([]byte(strings.Repeat("content\n", 10)), nil)
+	encoder.Close()
+
+	recorder := &rangeRecorder{data: data}
+	_, err = readZstdSeekableRange(recorder.read, int64(len(data)), 0, -1, io.Discard)
+	assert.Assert(t, errors.Is(err, errNotZstdSeekable), "unexpected error: %v", err)
+}
+
+func TestReadZstdSeekableRangeOffsetBeyondEnd(t *testing.T) {
+	t.Parallel()
+
+	data := newZstdSeekable(t, []string{"content"}, false)
+
+	recorder := &rangeRecorder{data: data}
+	_, err := readZstdSeekableRange(recorder.read, int64(len(data)), 100, -1, io.Discard)
+	assert.ErrorContains(t, err, "beyond the decompressed size")
+}
@@ -1,9 +1,16 @@
 package e2e
 
 import (
+	jsonpkg "encoding/json"
 	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
 	"strconv"
 	"strings"
 	"testing"
@@ -11,6 +18,7 @@ import (
 	"github.com/peak/s5cmd/v2/command"
 
 	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
 	"gotest.tools/v3/icmd"
 )
 
@@ -72,6 +80,56 @@ func TestAppRetryCount(t *testing.T) {
 	}
 }
 
+// --buffer-pool-size size cp s3://bucket/object dir/
+func TestAppBufferPoolSize(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name          string
+		size          string
+		expectedError string
+	}{
+		{name: "custom_size", size: "1MiB"},
+		{name: "disabled", size: "0"},
+		{name: "invalid_size", size: "foo", expectedError: `invalid buffer-pool-size: invalid size "foo"`},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			s3client, s5cmd := setup(t)
+
+			bucket := s3BucketFromTestName(t)
+			createBucket(t, s3client, bucket)
+
+			const content = "this is a file content"
+			putFile(t, s3client, bucket, "file.txt", content)
+
+			workdir := fs.NewDir(t, "somedir")
+			defer workdir.Remove()
+
+			srcpath := fmt.Sprintf("s3://%v/file.txt", bucket)
+			dstpath := filepath.ToSlash(workdir.Path()) + "/"
+
+			cmd := s5cmd("--buffer-pool-size", tc.size, "cp", srcpath, dstpath)
+			result := icmd.RunCmd(cmd)
+
+			if tc.expectedError != "" {
+				result.Assert(t, icmd.Expected{ExitCode: 1})
+				assertLines(t, result.Stderr(), map[int]compareFunc{
+					0: contains(tc.expectedError),
+				})
+				return
+			}
+
+			result.Assert(t, icmd.Success)
+			assert.Assert(t, fs.Equal(workdir.Path(), fs.Expected(t, fs.WithFile("file.txt", content))))
+		})
+	}
+}
+
 // Checks if the stats are written in necessary conditions.
 // 1. Print with every log level when there is an operation
 // 2. Do not print when used with help & version commands.
@@ -136,6 +194,32 @@ func TestAppDashStat(t *testing.T) {
 	}
 }
 
+// --json --stat ls s3://bucket/
+func TestAppDashStatRequests(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+	putFile(t, s3client, bucket, "file1.txt", "this is a file content")
+
+	cmd := s5cmd("--json", "--stat", "ls", "s3://"+bucket+"/")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	// the statistics of the S3 requests are collected by the storage hooks.
+	assert.Assert(t, strings.Contains(result.Stdout(), `"operation":"request","request":"ListObjectsV2","total":1,"error":0`))
+
+	cmd = s5cmd("--stat", "ls", "s3://"+bucket+"/")
+	result = icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+	assert.Assert(t, strings.Contains(result.Stdout(), "Avg Latency\tMax Latency\t"))
+	assert.Assert(t, strings.Contains(result.Stdout(), "ListObjectsV2\t1\t0\t"))
+}
+
 func TestAppProxy(t *testing.T) {
 	testcases := []struct {
 		name string
@@ -312,3 +396,567 @@ func TestAppEndpointShouldHaveScheme(t *testing.T) {
 		})
 	}
 }
+
+func TestAppOutputVersion(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name             string
+		args             []string
+		expected         string
+		expectedExitCode int
+	}{
+		{
+			name:     "latest_version_by_default",
+			args:     []string{"--json"},
+			expected: `{"v":2,"operation":"rb","command":"rb s3://invalid/bucket/name","error":"invalid s3 bucket"}`,
+		},
+		{
+			name:     "previous_version",
+			args:     []string{"--json", "--output-version", "1"},
+			expected: `{"operation":"rb","command":"rb s3://invalid/bucket/name","error":"invalid s3 bucket"}`,
+		},
+		{
+			name:     "unsupported_version",
+			args:     []string{"--json", "--output-version", "3"},
+			expected: `{"v":2,"command":" rb s3://invalid/bucket/name","error":"output version must be between 1 and 2"}`,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, s5cmd := setup(t)
+
+			args := append(tc.args, "rb", "s3://invalid/bucket/name")
+			cmd := s5cmd(args...)
+			result := icmd.RunCmd(cmd)
+
+			result.Assert(t, icmd.Expected{ExitCode: 1})
+
+			assertLines(t, result.Stderr(), map[int]compareFunc{
+				0: equals(tc.expected),
+			}, jsonCheck(true))
+		})
+	}
+}
+
+func TestAppMaxConcurrentRequestsPerHost(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	for i := 0; i < 5; i++ {
+		putFile(t, s3client, bucket, fmt.Sprintf("file%d.txt", i), "content")
+	}
+
+	workdir := fs.NewDir(t, t.Name())
+	defer workdir.Remove()
+
+	srcpath := fmt.Sprintf("s3://%v/*", bucket)
+	dstpath := filepath.ToSlash(workdir.Path()) + "/"
+
+	// requests are serialized, but all of the objects are downloaded.
+	cmd := s5cmd("--max-concurrent-requests-per-host", "1", "cp", srcpath, dstpath)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp s3://%v/file0.txt %vfile0.txt`, bucket, dstpath),
+		1: equals(`cp s3://%v/file1.txt %vfile1.txt`, bucket, dstpath),
+		2: equals(`cp s3://%v/file2.txt %vfile2.txt`, bucket, dstpath),
+		3: equals(`cp s3://%v/file3.txt %vfile3.txt`, bucket, dstpath),
+		4: equals(`cp s3://%v/file4.txt %vfile4.txt`, bucket, dstpath),
+	}, sortInput(true))
+}
+
+func TestAppMaxConcurrentRequestsPerHostNegative(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd := setup(t)
+
+	cmd := s5cmd("--max-concurrent-requests-per-host", "-1", "ls")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR " ls": max concurrent requests per host cannot be a negative value`),
+	})
+}
+
+// --stats-csv stats.csv cp -n s3://bucket/* dir/
+func TestAppStatsCSV(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	putFile(t, s3client, bucket, "a.txt", "content of a")
+	putFile(t, s3client, bucket, "b.txt", "content of b")
+
+	workdir := fs.NewDir(t, t.Name(), fs.WithFile("a.txt", "existing"))
+	defer workdir.Remove()
+
+	statsfile := filepath.Join(t.TempDir(), "stats.csv")
+	srcpath := fmt.Sprintf("s3://%v/*", bucket)
+	dstpath := filepath.ToSlash(workdir.Path()) + "/"
+
+	cmd := s5cmd("--stats-csv", statsfile, "cp", "-n", srcpath, dstpath)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	content, err := os.ReadFile(statsfile)
+	assert.NilError(t, err)
+
+	// the duration and the throughput of the transfers vary.
+	assertLines(t, string(content), map[int]compareFunc{
+		0: match(fmt.Sprintf(`^cp,s3://%v/a.txt,12,[0-9.]+,,0,skipped,$`, bucket)),
+		1: match(fmt.Sprintf(`^cp,s3://%v/b.txt,12,[0-9.]+,[0-9]+,0,succeeded,$`, bucket)),
+		2: equals("operation,key,bytes,duration,throughput,retries,status,version_id"),
+	}, sortInput(true))
+}
+
+func TestAppStatsCSVInvalidPath(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd := setup(t)
+
+	statsfile := filepath.Join(t.TempDir(), "missing", "stats.csv")
+
+	cmd := s5cmd("--stats-csv", statsfile, "ls")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: prefix(`ERROR " ls": could not create stats file: open %v:`, statsfile),
+	})
+}
+
+// --access-log access.log cp -n --exclude "*.gz" s3://bucket/* dir/
+func TestAppAccessLog(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	putFile(t, s3client, bucket, "a.txt", "content of a")
+	putFile(t, s3client, bucket, "b.txt", "content of b")
+	putFile(t, s3client, bucket, "c.gz", "content of c")
+
+	workdir := fs.NewDir(t, t.Name(), fs.WithFile("a.txt", "existing"))
+	defer workdir.Remove()
+
+	logfile := filepath.Join(t.TempDir(), "access.log")
+	srcpath := fmt.Sprintf("s3://%v/*", bucket)
+	dstpath := filepath.ToSlash(workdir.Path()) + "/"
+
+	cmd := s5cmd("--access-log", logfile, "cp", "-n", "--exclude", "*.gz", srcpath, dstpath)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	content, err := os.ReadFile(logfile)
+	assert.NilError(t, err)
+
+	// the time of the records varies.
+	records := regexp.MustCompile(`"time":"[^"]+",`).ReplaceAllString(string(content), "")
+	assertLines(t, records, map[int]compareFunc{
+		0: equals(`{"operation":"cp","source":"s3://%v/a.txt","size":12,"action":"skipped","reason":"object already exists"}`, bucket),
+		1: equals(`{"operation":"cp","source":"s3://%v/b.txt","size":12,"action":"read"}`, bucket),
+		2: equals(`{"operation":"cp","source":"s3://%v/c.gz","size":12,"action":"excluded","reason":"matches an exclude pattern"}`, bucket),
+	}, sortInput(true))
+}
+
+// --access-log access.log sync --size-only dir/ s3://bucket/
+func TestAppAccessLogSync(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	putFile(t, s3client, bucket, "a.txt", "content of a")
+
+	workdir := fs.NewDir(t, t.Name(),
+		fs.WithFile("a.txt", "content of a"),
+		fs.WithFile("b.txt", "content of b"),
+	)
+	defer workdir.Remove()
+
+	logfile := filepath.Join(t.TempDir(), "access.log")
+	srcpath := filepath.ToSlash(workdir.Path()) + "/"
+	dstpath := fmt.Sprintf("s3://%v/", bucket)
+
+	cmd := s5cmd("--access-log", logfile, "sync", "--size-only", srcpath, dstpath)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	content, err := os.ReadFile(logfile)
+	assert.NilError(t, err)
+
+	records := regexp.MustCompile(`"time":"[^"]+",`).ReplaceAllString(string(content), "")
+	assertLines(t, records, map[int]compareFunc{
+		0: equals(`{"operation":"cp","source":"%vb.txt","size":12,"action":"read"}`, srcpath),
+		1: equals(`{"operation":"sync","source":"%va.txt","size":12,"action":"skipped","reason":"object size matches"}`, srcpath),
+	}, sortInput(true))
+}
+
+// --audit-log audit.log cp dir/* s3://bucket/
+// --audit-log audit.log rm s3://bucket/a.txt
+func TestAppAuditLog(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	workdir := fs.NewDir(t, t.Name(),
+		fs.WithFile("a.txt", "content of a"),
+		fs.WithFile("b.txt", "content of b"),
+	)
+	defer workdir.Remove()
+
+	logfile := filepath.Join(t.TempDir(), "audit.log")
+	srcpath := filepath.ToSlash(workdir.Path())
+
+	cmd := s5cmd("--audit-log", logfile, "cp", srcpath+"/*", fmt.Sprintf("s3://%v/", bucket))
+	result := icmd.RunCmd(cmd)
+	result.Assert(t, icmd.Success)
+
+	// the objects which are not changed are not recorded.
+	cmd = s5cmd("--audit-log", logfile, "cp", "-n", srcpath+"/*", fmt.Sprintf("s3://%v/", bucket))
+	result = icmd.RunCmd(cmd)
+	result.Assert(t, icmd.Success)
+
+	cmd = s5cmd("--audit-log", logfile, "rm", fmt.Sprintf("s3://%v/a.txt", bucket))
+	result = icmd.RunCmd(cmd)
+	result.Assert(t, icmd.Success)
+
+	content, err := os.ReadFile(logfile)
+	assert.NilError(t, err)
+
+	// the records are chained by their hashes, across the runs.
+	hashes := regexp.MustCompile(`"prev_hash":"([^"]*)","hash":"([^"]+)"`).FindAllStringSubmatch(string(content), -1)
+	assert.Equal(t, len(hashes), 3)
+	for i := 1; i < len(hashes); i++ {
+		assert.Equal(t, hashes[i][1], hashes[i-1][2])
+	}
+
+	// the time, the user and the command line of the records vary.
+	records := regexp.MustCompile(`"(time|user|command)":"[^"]*",`).ReplaceAllString(string(content), "")
+	records = regexp.MustCompile(`,"prev_hash":"[^"]*","hash":"[^"]*"`).ReplaceAllString(records, "")
+	assertLines(t, records, map[int]compareFunc{
+		0: equals(`{"operation":"cp","source":"%v/a.txt","destination":"s3://%v/a.txt","size":12,"result":"succeeded"}`, srcpath, bucket),
+		1: equals(`{"operation":"cp","source":"%v/b.txt","destination":"s3://%v/b.txt","size":12,"result":"succeeded"}`, srcpath, bucket),
+		2: equals(`{"operation":"rm","source":"s3://%v/a.txt","size":0,"result":"succeeded"}`, bucket),
+	}, sortInput(true))
+}
+
+// --dry-run --audit-log audit.log rm s3://bucket/a.txt
+func TestAppAuditLogDryRun(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+	putFile(t, s3client, bucket, "a.txt", "content of a")
+
+	logfile := filepath.Join(t.TempDir(), "audit.log")
+
+	cmd := s5cmd("--dry-run", "--audit-log", logfile, "rm", fmt.Sprintf("s3://%v/a.txt", bucket))
+	result := icmd.RunCmd(cmd)
+	result.Assert(t, icmd.Success)
+
+	// nothing is changed, so nothing is recorded.
+	_, err := os.Stat(logfile)
+	assert.Assert(t, os.IsNotExist(err))
+}
+
+// --env-prefix TENANT1_ ls s3://bucket/
+func TestAppEnvPrefix(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+	putFile(t, s3client, bucket, "file.txt", "content")
+
+	cmd := s5cmd("--env-prefix", "TENANT1_", "ls", fmt.Sprintf("s3://%v/", bucket))
+	result := icmd.RunCmd(
+		cmd,
+		withEnv("TENANT1_AWS_ACCESS_KEY_ID", defaultAccessKeyID),
+		withEnv("TENANT1_AWS_SECRET_ACCESS_KEY", defaultSecretAccessKey),
+	)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: suffix(" 7 file.txt"),
+	})
+}
+
+func TestAppEnvPrefixMissingCredentials(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	// the credentials without the prefix are not used.
+	cmd := s5cmd("--env-prefix", "TENANT2_", "ls", fmt.Sprintf("s3://%v/", bucket))
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR session: fetching region failed: TENANT2_AWS_ACCESS_KEY_ID or TENANT2_AWS_ACCESS_KEY not found in environment`),
+		1: equals(`ERROR "ls s3://%v/": TENANT2_AWS_ACCESS_KEY_ID or TENANT2_AWS_ACCESS_KEY not found in environment`, bucket),
+	})
+}
+
+func TestAppEnvPrefixWithCredentialsCommand(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd := setup(t)
+
+	cmd := s5cmd("--env-prefix", "TENANT1_", "--credentials-command", "echo", "ls")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR " ls": "env-prefix" and "credentials-command" flags cannot be used together`),
+	})
+}
+
+// --endpoint-url http://s3.e2e.test:port --resolve s3.e2e.test:port:127.0.0.1 ls s3://bucket
+func TestAppResolve(t *testing.T) {
+	t.Parallel()
+
+	if isEndpointFromEnv() {
+		t.Skip("the endpoint from the environment can't be pinned")
+	}
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+	putFile(t, s3client, bucket, "file.txt", "content")
+
+	endpoint, err := url.Parse(s3client.Endpoint)
+	assert.NilError(t, err)
+
+	// the host of the endpoint doesn't exist, it is connected to the
+	// address of the test server.
+	const host = "s3.e2e.test"
+	cmd := s5cmd(
+		"--endpoint-url", fmt.Sprintf("%v://%v:%v", endpoint.Scheme, host, endpoint.Port()),
+		"--resolve", fmt.Sprintf("%v:%v:%v", host, endpoint.Port(), endpoint.Hostname()),
+		"--ip-version", "4",
+		"ls", "s3://"+bucket,
+	)
+	// the proxy of the parallel tests must not be used for the host.
+	result := icmd.RunCmd(cmd, withEnv("NO_PROXY", host))
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: suffix(` 7 file.txt`),
+	}, strictLineCheck(true))
+}
+
+func TestAppResolveValidation(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "invalid entry",
+			args:     []string{"--resolve", "s3.amazonaws.com:443", "ls"},
+			expected: `ERROR " ls": invalid resolve entry "s3.amazonaws.com:443": missing address, it must be in host:port:addr format`,
+		},
+		{
+			name:     "address of another ip version",
+			args:     []string{"--ip-version", "6", "--resolve", "s3.amazonaws.com:443:52.216.0.1", "ls"},
+			expected: `ERROR " ls": invalid resolve entry "s3.amazonaws.com:443:52.216.0.1": 52.216.0.1 is not an IPv6 address`,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, s5cmd := setup(t)
+
+			result := icmd.RunCmd(s5cmd(tc.args...))
+			result.Assert(t, icmd.Expected{ExitCode: 1})
+
+			assertLines(t, result.Stderr(), map[int]compareFunc{
+				0: equals(tc.expected),
+			})
+		})
+	}
+}
+
+// --on-complete-exec "hook.sh summary.json" cp s3://bucket/missing.txt dir/
+func TestAppOnCompleteExec(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("the completion command is a shell script")
+	}
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	workdir := fs.NewDir(t, t.Name(),
+		fs.WithFile("hook.sh", "#!/bin/sh\ncat > \"$1\"\necho \"$S5CMD_EXIT_CODE\" > \"$1.code\"\n", fs.WithMode(0o755)),
+	)
+	defer workdir.Remove()
+
+	summaryfile := filepath.Join(workdir.Path(), "summary.json")
+	hook := fmt.Sprintf("%v %v", workdir.Join("hook.sh"), summaryfile)
+	src := fmt.Sprintf("s3://%v/missing.txt", bucket)
+
+	cmd := s5cmd("--on-complete-exec", hook, "cp", src, workdir.Path()+"/")
+	result := icmd.RunCmd(cmd)
+
+	// the exit code is not changed by the hook.
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	code, err := os.ReadFile(summaryfile + ".code")
+	assert.NilError(t, err)
+	assert.Equal(t, string(code), "1\n")
+
+	content, err := os.ReadFile(summaryfile)
+	assert.NilError(t, err)
+
+	var summary map[string]interface{}
+	assert.NilError(t, jsonpkg.Unmarshal(content, &summary))
+	assert.Equal(t, summary["command"], fmt.Sprintf("cp %v %v/", src, workdir.Path()))
+	assert.Equal(t, summary["success"], false)
+	assert.Equal(t, summary["exit_code"], float64(1))
+	assert.Equal(t, summary["errors"], float64(1))
+}
+
+// --on-complete-webhook http://host/hook ls s3://bucket/
+func TestAppOnCompleteWebhook(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+	putFile(t, s3client, bucket, "file.txt", "content")
+
+	requests := make(chan []byte, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- body
+	}))
+	defer server.Close()
+
+	cmd := s5cmd("--on-complete-webhook", server.URL+"/hook", "ls", "s3://"+bucket+"/")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assert.Equal(t, len(requests), 1)
+
+	var summary map[string]interface{}
+	assert.NilError(t, jsonpkg.Unmarshal(<-requests, &summary))
+	assert.Equal(t, summary["command"], "ls s3://"+bucket+"/")
+	assert.Equal(t, summary["success"], true)
+	assert.Equal(t, summary["exit_code"], float64(0))
+}
+
+// --on-complete-webhook http://host/missing ls s3://bucket/
+func TestAppOnCompleteWebhookFailure(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+	putFile(t, s3client, bucket, "file.txt", "content")
+
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	cmd := s5cmd("--on-complete-webhook", server.URL+"/missing", "ls", "s3://"+bucket+"/")
+	result := icmd.RunCmd(cmd)
+
+	// the failure of the hook doesn't change the exit code.
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "ls s3://%v/": completion webhook failed: %v/missing returned 404 Not Found`, bucket, server.URL),
+	}, strictLineCheck(true))
+}
+
+func TestAppOnCompleteValidation(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "webhook without scheme",
+			args:     []string{"--on-complete-webhook", "example.com/hook", "ls"},
+			expected: `ERROR " ls": "on-complete-webhook" flag must be an http or https URL`,
+		},
+		{
+			name:     "empty command",
+			args:     []string{"--on-complete-exec", " ", "ls"},
+			expected: `ERROR " ls": "on-complete-exec" flag requires a command`,
+		},
+		{
+			name:     "negative timeout",
+			args:     []string{"--on-complete-timeout", "-1s", "ls"},
+			expected: `ERROR " ls": "on-complete-timeout" flag must not be negative`,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, s5cmd := setup(t)
+
+			result := icmd.RunCmd(s5cmd(tc.args...))
+			result.Assert(t, icmd.Expected{ExitCode: 1})
+
+			assertLines(t, result.Stderr(), map[int]compareFunc{
+				0: equals(tc.expected),
+			})
+		})
+	}
+}
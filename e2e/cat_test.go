@@ -1,11 +1,15 @@
 package e2e
 
 import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
 	"fmt"
 	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/klauspost/compress/zstd"
 	"gotest.tools/v3/assert"
 	"gotest.tools/v3/icmd"
 )
@@ -127,7 +131,7 @@ func TestCatS3ObjectFail(t *testing.T) {
 				"cat",
 			},
 			expected: map[int]compareFunc{
-				0: match(`{"operation":"cat","command":"cat s3:\/\/(.*)\/prefix\/file\.txt","error":"(.*) not found`),
+				0: match(`{"v":2,"operation":"cat","command":"cat s3:\/\/(.*)\/prefix\/file\.txt","error":"(.*) not found`),
 			},
 			assertOps: []assertOp{
 				jsonCheck(true),
@@ -141,7 +145,7 @@ func TestCatS3ObjectFail(t *testing.T) {
 				"cat",
 			},
 			expected: map[int]compareFunc{
-				0: match(`{"operation":"cat","command":"cat s3:\/\/(.+)?\/prefix\/file\.txt\/\*","error":"remote source \\"s3:\/\/(.*)\/prefix\/file\.txt\/\*\\" can not contain glob characters"}`),
+				0: match(`{"v":2,"operation":"cat","command":"cat s3:\/\/(.+)?\/prefix\/file\.txt\/\*","error":"remote source \\"s3:\/\/(.*)\/prefix\/file\.txt\/\*\\" can not contain glob characters"}`),
 			},
 			assertOps: []assertOp{
 				jsonCheck(true),
@@ -208,7 +212,7 @@ func TestCatLocalFileFail(t *testing.T) {
 				filename,
 			},
 			expected: map[int]compareFunc{
-				0: contains(`{"operation":"cat","command":"cat file.txt","error":"source must be a remote object"}`),
+				0: contains(`{"v":2,"operation":"cat","command":"cat file.txt","error":"source must be a remote object"}`),
 			},
 		},
 	}
@@ -245,6 +249,127 @@ func getSequentialFileContent(size int64) (string, map[int]compareFunc) {
 	return sb.String(), expectedLines
 }
 
+// newZstdSeekable compresses each of the frames separately and appends their
+// seek table.
+func newZstdSeekable(t *testing.T, frames ...string) string {
+	t.Helper()
+
+	encoder, err := zstd.NewWriter(nil)
+	assert.NilError(t, err)
+	defer encoder.Close()
+
+	var content, entries []byte
+	for _, frame := range frames {
+		compressed := encoder.EncodeAll([]byte(frame), nil)
+		content = append(content, compressed...)
+		entries = binary.LittleEndian.AppendUint32(entries, uint32(len(compressed)))
+		entries = binary.LittleEndian.AppendUint32(entries, uint32(len(frame)))
+	}
+
+	content = binary.LittleEndian.AppendUint32(content, 0x184D2A5E)
+	content = binary.LittleEndian.AppendUint32(content, uint32(len(entries)+9))
+	content = append(content, entries...)
+	content = binary.LittleEndian.AppendUint32(content, uint32(len(frames)))
+	content = append(content, 0)
+	content = binary.LittleEndian.AppendUint32(content, 0x8F92EAB1)
+	return string(content)
+}
+
+func TestCatByteRange(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	frames := []string{strings.Repeat("a", 100), strings.Repeat("b", 100), strings.Repeat("c", 100)}
+	putFile(t, s3client, bucket, "file.txt", "0123456789")
+	putFile(t, s3client, bucket, "file.zst", newZstdSeekable(t, frames...))
+	putFile(t, s3client, bucket, "plain.zst", "not seekable")
+
+	testcases := []struct {
+		name     string
+		flags    []string
+		key      string
+		expected string
+	}{
+		{
+			name:     "offset and length",
+			flags:    []string{"--offset", "2", "--length", "3"},
+			key:      "file.txt",
+			expected: "234",
+		},
+		{
+			name:     "offset up to the end",
+			flags:    []string{"--offset", "7"},
+			key:      "file.txt",
+			expected: "789",
+		},
+		{
+			name:     "zstd seekable across frames",
+			flags:    []string{"--offset", "95", "--length", "10"},
+			key:      "file.zst",
+			expected: strings.Repeat("a", 5) + strings.Repeat("b", 5),
+		},
+		{
+			name:     "zstd seekable up to the end",
+			flags:    []string{"--offset", "290"},
+			key:      "file.zst",
+			expected: strings.Repeat("c", 10),
+		},
+	}
+
+	for _, tc := range testcases {
+		args := append([]string{"cat"}, tc.flags...)
+		result := icmd.RunCmd(s5cmd(append(args, fmt.Sprintf("s3://%v/%v", bucket, tc.key))...))
+
+		result.Assert(t, icmd.Success)
+		assert.Equal(t, result.Stdout(), tc.expected, tc.name)
+	}
+
+	src := fmt.Sprintf("s3://%v/plain.zst", bucket)
+	result := icmd.RunCmd(s5cmd("cat", "--offset", "1", src))
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "cat --offset=1 %v": object is not in zstd seekable format`, src),
+	})
+}
+
+func TestCatByteRangeValidation(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd := setup(t)
+
+	testcases := []struct {
+		flags    []string
+		expected string
+	}{
+		{
+			flags:    []string{"--length", "0"},
+			expected: `ERROR "cat --length=0 s3://bucket/file.txt": "length" flag must be a positive number`,
+		},
+		{
+			flags:    []string{"--offset", "-1"},
+			expected: `ERROR "cat --offset=-1 s3://bucket/file.txt": "offset" flag must not be negative`,
+		},
+		{
+			flags:    []string{"--offset", "1", "--head", "1"},
+			expected: `ERROR "cat --head=1 --offset=1 s3://bucket/file.txt": it is not allowed to combine "offset" and "length" flags with "head" flag`,
+		},
+	}
+
+	for _, tc := range testcases {
+		args := append([]string{"cat"}, tc.flags...)
+		result := icmd.RunCmd(s5cmd(append(args, "s3://bucket/file.txt")...))
+		result.Assert(t, icmd.Expected{ExitCode: 1})
+
+		assertLines(t, result.Stderr(), map[int]compareFunc{
+			0: equals(tc.expected),
+		})
+	}
+}
+
 func TestCatByVersionID(t *testing.T) {
 	skipTestIfGCS(t, "versioning is not supported in GCS")
 
@@ -306,3 +431,171 @@ func TestCatByVersionID(t *testing.T) {
 		}
 	}
 }
+
+func TestCatHeadTail(t *testing.T) {
+	t.Parallel()
+
+	// the lines are longer than the first range read from the end of the
+	// object, so that the ranges are grown to find the last lines.
+	long := strings.Repeat("x", 100*int(kb))
+	lines := []string{"first", long, "third", long + "y", "last"}
+	content := strings.Join(lines, "\n") + "\n"
+
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	_, err := gz.Write([]byte(content))
+	assert.NilError(t, err)
+	assert.NilError(t, gz.Close())
+
+	testcases := []struct {
+		name     string
+		content  string
+		flags    []string
+		expected string
+	}{
+		{
+			name:     "head",
+			content:  content,
+			flags:    []string{"--head", "2"},
+			expected: "first\n" + long + "\n",
+		},
+		{
+			name:     "head with more lines than object",
+			content:  content,
+			flags:    []string{"--head", "10"},
+			expected: content,
+		},
+		{
+			name:     "tail",
+			content:  content,
+			flags:    []string{"--tail", "2"},
+			expected: long + "y\nlast\n",
+		},
+		{
+			name:     "tail with more lines than object",
+			content:  content,
+			flags:    []string{"--tail", "10"},
+			expected: content,
+		},
+		{
+			name:     "tail of small object without trailing newline",
+			content:  "a\nb\nc",
+			flags:    []string{"--tail", "2"},
+			expected: "b\nc",
+		},
+		{
+			name:     "head of compressed object",
+			content:  compressed.String(),
+			flags:    []string{"--decompress", "--head", "1"},
+			expected: "first\n",
+		},
+		{
+			name:     "tail of compressed object",
+			content:  compressed.String(),
+			flags:    []string{"--decompress", "--tail", "2"},
+			expected: long + "y\nlast\n",
+		},
+		{
+			name:     "compressed object",
+			content:  compressed.String(),
+			flags:    []string{"--decompress"},
+			expected: content,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			s3client, s5cmd := setup(t)
+
+			bucket := s3BucketFromTestName(t)
+			createBucket(t, s3client, bucket)
+			putFile(t, s3client, bucket, "file.txt", tc.content)
+
+			args := append([]string{"cat"}, tc.flags...)
+			cmd := s5cmd(append(args, fmt.Sprintf("s3://%v/file.txt", bucket))...)
+			result := icmd.RunCmd(cmd)
+
+			result.Assert(t, icmd.Success)
+
+			if diff := cmp.Diff(tc.expected, result.Stdout()); diff != "" {
+				t.Errorf("(-want +got):\n%v", diff)
+			}
+		})
+	}
+}
+
+func TestCatHeadTailByVersionID(t *testing.T) {
+	skipTestIfGCS(t, "versioning is not supported in GCS")
+
+	t.Parallel()
+
+	bucket := s3BucketFromTestName(t)
+
+	// versioninng is only supported with in memory backend!
+	s3client, s5cmd := setup(t, withS3Backend("mem"))
+
+	createBucket(t, s3client, bucket)
+	setBucketVersioning(t, s3client, bucket, "Enabled")
+
+	putFile(t, s3client, bucket, "file.txt", "first\nsecond\nthird\n")
+	putFile(t, s3client, bucket, "file.txt", "latest\n")
+
+	result := icmd.RunCmd(s5cmd("ls", "--all-versions", "s3://"+bucket+"/file.txt"))
+	result.Assert(t, icmd.Success)
+
+	// the versions are listed from the oldest.
+	row := strings.Split(result.Stdout(), "\n")[0]
+	fields := strings.Fields(row)
+	version := fields[len(fields)-1]
+
+	src := fmt.Sprintf("s3://%v/file.txt", bucket)
+
+	result = icmd.RunCmd(s5cmd("cat", "--version-id", version, "--head", "1", src))
+	result.Assert(t, icmd.Success)
+	assert.Equal(t, result.Stdout(), "first\n")
+
+	result = icmd.RunCmd(s5cmd("cat", "--version-id", version, "--tail", "1", src))
+	result.Assert(t, icmd.Success)
+	assert.Equal(t, result.Stdout(), "third\n")
+}
+
+func TestCatHeadTailValidation(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		flags    []string
+		expected string
+	}{
+		{
+			name:     "head and tail",
+			flags:    []string{"--head", "1", "--tail", "1"},
+			expected: `ERROR "cat --head=1 --tail=1 s3://bucket/file.txt": it is not allowed to combine "head" and "tail" flags`,
+		},
+		{
+			name:     "non positive lines",
+			flags:    []string{"--tail", "0"},
+			expected: `ERROR "cat --tail=0 s3://bucket/file.txt": "tail" flag must be a positive number`,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, s5cmd := setup(t)
+
+			args := append([]string{"cat"}, tc.flags...)
+			result := icmd.RunCmd(s5cmd(append(args, "s3://bucket/file.txt")...))
+			result.Assert(t, icmd.Expected{ExitCode: 1})
+
+			assertLines(t, result.Stderr(), map[int]compareFunc{
+				0: equals(tc.expected),
+			})
+		})
+	}
+}
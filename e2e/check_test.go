@@ -0,0 +1,127 @@
+package e2e
+
+import (
+	"testing"
+
+	"gotest.tools/v3/icmd"
+)
+
+func TestCheck(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	putFile(t, s3client, bucket, "prefix/file.txt", "content")
+
+	cmd := s5cmd("check", "--write", "s3://"+bucket+"/prefix/")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals("CHECK STATUS MESSAGE"),
+		1: match(`^credentials pass resolved by \S+$`),
+		2: equals("endpoint pass endpoint is reachable"),
+		3: equals("bucket pass bucket %q exists and is accessible", bucket),
+		4: match(`^region pass bucket "` + bucket + `" is in \S+$`),
+		5: equals("list pass objects under s3://%v/prefix/* are listed", bucket),
+		6: equals("head pass metadata of s3://%v/prefix/file.txt is read", bucket),
+		7: match(`^write pass probe object s3://` + bucket + `/prefix/\.s5cmd-check-[0-9a-f]{16} is written$`),
+		8: match(`^delete pass probe object s3://` + bucket + `/prefix/\.s5cmd-check-[0-9a-f]{16} is deleted$`),
+	}, strictLineCheck(true))
+
+	// the probe object must be deleted.
+	cmd = s5cmd("ls", "s3://"+bucket+"/prefix/*")
+	result = icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: suffix("file.txt"),
+	}, strictLineCheck(true))
+}
+
+func TestCheckWithoutWrite(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	cmd := s5cmd("check", "s3://"+bucket)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals("CHECK STATUS MESSAGE"),
+		1: match(`^credentials pass `),
+		2: equals("endpoint pass endpoint is reachable"),
+		3: equals("bucket pass bucket %q exists and is accessible", bucket),
+		4: match(`^region pass `),
+		5: equals("list pass no objects found under s3://%v/*", bucket),
+		6: equals("head skip no objects to check"),
+		7: equals("write skip use --write flag to check"),
+		8: equals("delete skip use --write flag to check"),
+	})
+}
+
+func TestCheckNonExistingBucket(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd := setup(t)
+	bucket := s3BucketFromTestName(t)
+
+	cmd := s5cmd("check", "--write", "s3://"+bucket+"/prefix/")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals("CHECK STATUS MESSAGE"),
+		1: match(`^credentials pass `),
+		2: equals("endpoint pass endpoint is reachable"),
+		3: equals("bucket fail bucket %q does not exist; check the bucket name", bucket),
+		4: equals("region skip bucket check failed"),
+		5: equals("list skip bucket check failed"),
+		6: equals("head skip bucket check failed"),
+		7: equals("write skip bucket check failed"),
+		8: equals("delete skip bucket check failed"),
+	})
+}
+
+func TestCheckJSON(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd := setup(t)
+	bucket := s3BucketFromTestName(t)
+
+	cmd := s5cmd("--json", "check", "s3://"+bucket)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: contains(`"success":false`),
+	})
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: contains(`{"check":"bucket","status":"fail","message":"bucket \"%v\" does not exist; check the bucket name"}`, bucket),
+	})
+}
+
+func TestCheckLocalSource(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd := setup(t)
+
+	cmd := s5cmd("check", "dir/")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "check dir/": source must be a remote bucket or prefix`),
+	})
+}
@@ -32,6 +32,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
 	"gotest.tools/v3/assert"
 	"gotest.tools/v3/fs"
 	"gotest.tools/v3/icmd"
@@ -165,6 +167,7 @@ func TestCopySingleS3ObjectToLocalJSON(t *testing.T) {
 
 	jsonText := `
 		{
+			"v": 2,
 			"operation": "cp",
 			"success": true,
 			"source": "s3://%v/testfile1.txt",
@@ -222,6 +225,40 @@ func TestCopySingleS3ObjectToLocalWithDestinationWildcard(t *testing.T) {
 	assert.Assert(t, ensureS3Object(s3client, bucket, filename, content))
 }
 
+// cp --offset --length s3://bucket/object file
+func TestCopyS3ObjectByteRangeToLocal(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	putFile(t, s3client, bucket, "file.txt", "0123456789")
+	putFile(t, s3client, bucket, "file.zst", newZstdSeekable(t, strings.Repeat("a", 100), strings.Repeat("b", 100)))
+
+	cmd := s5cmd("cp", "--offset", "2", "--length", "3", "s3://"+bucket+"/file.txt", "part.txt")
+	result := icmd.RunCmd(cmd)
+	result.Assert(t, icmd.Success)
+
+	cmd = s5cmd("cp", "--offset", "98", "--length", "4", "s3://"+bucket+"/file.zst", "part.log")
+	result = icmd.RunCmd(cmd)
+	result.Assert(t, icmd.Success)
+
+	expected := fs.Expected(t,
+		fs.WithFile("part.txt", "234", fs.WithMode(0644)),
+		fs.WithFile("part.log", "aabb", fs.WithMode(0644)),
+	)
+	assert.Assert(t, fs.Equal(cmd.Dir, expected))
+
+	// only single objects are downloaded with a range.
+	result = icmd.RunCmd(s5cmd("cp", "--offset", "2", "s3://"+bucket+"/*", "dir/"))
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "cp --offset=2 s3://%v/* dir/": "offset" and "length" flags can only be used to download a single object`, bucket),
+	})
+}
+
 // cp s3://bucket/prefix/ dir/
 func TestCopyS3PrefixToLocalMustReturnError(t *testing.T) {
 	t.Parallel()
@@ -416,6 +453,7 @@ func TestCopyMultipleFlatS3ObjectsToLocalJSON(t *testing.T) {
 	assertLines(t, result.Stdout(), map[int]compareFunc{
 		0: json(`
 			{
+				"v": 2,
 				"operation": "cp",
 				"success": true,
 				"source": "s3://%v/a/another_test_file.txt",
@@ -428,6 +466,7 @@ func TestCopyMultipleFlatS3ObjectsToLocalJSON(t *testing.T) {
 		`, bucket),
 		1: json(`
 			{
+				"v": 2,
 				"operation": "cp",
 				"success": true,
 				"source": "s3://%v/b/filename-with-hypen.gz",
@@ -440,6 +479,7 @@ func TestCopyMultipleFlatS3ObjectsToLocalJSON(t *testing.T) {
 		`, bucket),
 		2: json(`
 			{
+				"v": 2,
 				"operation": "cp",
 				"success": true,
 				"source": "s3://%v/readme.md",
@@ -452,6 +492,7 @@ func TestCopyMultipleFlatS3ObjectsToLocalJSON(t *testing.T) {
 		`, bucket),
 		3: json(`
 			{
+				"v": 2,
 				"operation": "cp",
 				"success": true,
 				"source": "s3://%v/testfile1.txt",
@@ -819,6 +860,7 @@ func TestCopySingleFileToS3JSON(t *testing.T) {
 
 	jsonText := `
 		{
+			"v": 2,
 			"operation": "cp",
 			"success": true,
 			"source": "%v",
@@ -891,6 +933,155 @@ func TestCopyDirToS3(t *testing.T) {
 	assert.Assert(t, ensureS3Object(s3client, bucket, "c/file2.txt", "this is the second test file"))
 }
 
+// --stat --read-concurrency 1 --read-iops 100 cp dir/ s3://bucket/
+func TestCopyDirToS3WithReadLimits(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	folderLayout := []fs.PathOp{
+		fs.WithFile("file1.txt", "this is the first test file"),
+		fs.WithFile("readme.md", "this is a readme file"),
+		fs.WithDir(
+			"c",
+			fs.WithFile("file2.txt", "this is the second test file"),
+		),
+	}
+
+	workdir := fs.NewDir(t, t.Name(), folderLayout...)
+	defer workdir.Remove()
+	srcpath := filepath.ToSlash(workdir.Path())
+	dstpath := fmt.Sprintf("s3://%v/", bucket)
+
+	cmd := s5cmd("--stat", "--read-concurrency", "1", "--read-iops", "100", "cp", srcpath+"/", dstpath)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	// the statistics of the reads and the uploads are printed after the
+	// statistics of the operations.
+	lines := strings.Split(strings.TrimSpace(result.Stdout()), "\n")
+	assertLines(t, strings.Join(lines[len(lines)-2:], "\n"), map[int]compareFunc{
+		0: prefix(`read 3 files, 1.00 at a time on average, 1 at most, waited`),
+		1: equals(`uploaded 3 objects with a single request and 0 objects in parts`),
+	})
+
+	assert.Assert(t, ensureS3Object(s3client, bucket, "file1.txt", "this is the first test file"))
+	assert.Assert(t, ensureS3Object(s3client, bucket, "readme.md", "this is a readme file"))
+	assert.Assert(t, ensureS3Object(s3client, bucket, "c/file2.txt", "this is the second test file"))
+}
+
+// cp --expected-size 19 file s3://bucket/
+func TestCopySingleFileToS3WithExpectedSize(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	const (
+		filename = "testfile1.txt"
+		content  = "this is the content"
+	)
+
+	workdir := fs.NewDir(t, bucket, fs.WithFile(filename, content))
+	defer workdir.Remove()
+
+	srcpath := filepath.ToSlash(workdir.Join(filename))
+	dstpath := fmt.Sprintf("s3://%v/", bucket)
+
+	cmd := s5cmd("cp", "--expected-size", "19", srcpath, dstpath)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %v %v%v`, srcpath, dstpath, filename),
+	})
+
+	assert.Assert(t, ensureS3Object(s3client, bucket, filename, content))
+}
+
+// cp --expected-size 20 --delete-on-size-mismatch file s3://bucket/
+func TestCopySingleFileToS3WithExpectedSizeMismatchAndDelete(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	const (
+		filename = "testfile1.txt"
+		content  = "this is the content"
+	)
+
+	workdir := fs.NewDir(t, bucket, fs.WithFile(filename, content))
+	defer workdir.Remove()
+
+	srcpath := filepath.ToSlash(workdir.Join(filename))
+	dstpath := fmt.Sprintf("s3://%v/", bucket)
+
+	cmd := s5cmd("cp", "--expected-size", "20", "--delete-on-size-mismatch", srcpath, dstpath)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "cp %v %v%v": uploaded object size 19 does not match expected size 20, the object is deleted`, srcpath, dstpath, filename),
+	})
+
+	err := ensureS3Object(s3client, bucket, filename, content)
+	assertError(t, err, errS3NoSuchKey)
+
+	// the source file is kept.
+	assert.Assert(t, fs.Equal(workdir.Path(), fs.Expected(t, fs.WithFile(filename, content))))
+}
+
+// cp --expected-size 19 dir/ s3://bucket/
+func TestCopyDirToS3WithExpectedSize(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	workdir := fs.NewDir(t, bucket, fs.WithFile("testfile1.txt", "this is the content"))
+	defer workdir.Remove()
+
+	srcpath := filepath.ToSlash(workdir.Path()) + "/"
+	dstpath := fmt.Sprintf("s3://%v/", bucket)
+
+	cmd := s5cmd("cp", "--expected-size", "19", srcpath, dstpath)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "cp --expected-size=19 %v %v": "expected-size" flag can only be used to upload a single file`, srcpath, dstpath),
+	})
+}
+
+func TestReadLimitsWithNegativeValue(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd := setup(t)
+
+	cmd := s5cmd("--read-concurrency", "-1", "cp", "dir/", "s3://bucket/")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR " cp dir/ s3://bucket/": read concurrency cannot be a negative value`),
+	})
+}
+
 // cp dir/{file, folderWithBackslash} s3://bucket
 func TestCopyDirBackslashedToS3(t *testing.T) {
 	if runtime.GOOS == "windows" {
@@ -1025,6 +1216,94 @@ func TestFlattenCopyDirToS3(t *testing.T) {
 	assert.Assert(t, ensureS3Object(s3client, bucket, "file2.txt", "this is the second test file"))
 }
 
+// cp --flatten --on-conflict rename dir/ s3://bucket/prefix/
+func TestFlattenCopyDirToS3WithOnConflictRename(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	workdir := fs.NewDir(t, t.Name(),
+		fs.WithDir("a", fs.WithFile("report.csv", "report of a")),
+		fs.WithDir("b", fs.WithFile("report.csv", "report of b")),
+		fs.WithDir("c", fs.WithFile("report.csv", "report of c")),
+		fs.WithFile("readme.md", "this is a readme file"),
+	)
+	defer workdir.Remove()
+
+	srcpath := filepath.ToSlash(workdir.Path())
+	dstpath := fmt.Sprintf("s3://%v/prefix/", bucket)
+
+	cmd := s5cmd("cp", "--flatten", "--on-conflict", "rename", workdir.Path()+"/", dstpath)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: match(fmt.Sprintf(`^cp %v/[abc]/report.csv %vreport.csv$`, srcpath, dstpath)),
+		1: match(fmt.Sprintf(`^cp %v/[abc]/report.csv %vreport-1.csv$`, srcpath, dstpath)),
+		2: match(fmt.Sprintf(`^cp %v/[abc]/report.csv %vreport-2.csv$`, srcpath, dstpath)),
+		3: equals(`cp %v/readme.md %vreadme.md`, srcpath, dstpath),
+	}, sortInput(true))
+
+	// each file is uploaded to the key it is reported with.
+	for _, line := range strings.Split(strings.TrimSpace(result.Stdout()), "\n") {
+		fields := strings.Fields(line)
+		src, dst := fields[1], fields[2]
+
+		content, err := os.ReadFile(src)
+		assert.NilError(t, err)
+
+		key := strings.TrimPrefix(dst, fmt.Sprintf("s3://%v/", bucket))
+		assert.Assert(t, ensureS3Object(s3client, bucket, key, string(content)))
+	}
+}
+
+func TestCopyOnConflictRenameValidation(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "local destination",
+			args:     []string{"cp", "--on-conflict", "rename", "s3://bucket/*", "dir/"},
+			expected: `ERROR "cp --on-conflict=rename s3://bucket/* dir/": "--on-conflict rename" can only be used with remote destination`,
+		},
+		{
+			name:     "suffix format without rename",
+			args:     []string{"cp", "--conflict-suffix-format", "-{{.N}}", "dir/*", "s3://bucket/"},
+			expected: `ERROR "cp --conflict-suffix-format=-{{.N}} dir/* s3://bucket/": "conflict-suffix-format" flag requires "--on-conflict rename"`,
+		},
+		{
+			name:     "unknown field",
+			args:     []string{"cp", "--on-conflict", "rename", "--conflict-suffix-format", "-{{.Name}}", "dir/*", "s3://bucket/"},
+			expected: `ERROR "cp --on-conflict=rename --conflict-suffix-format=-{{.Name}} dir/* s3://bucket/": invalid conflict-suffix-format: `,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, s5cmd := setup(t)
+
+			result := icmd.RunCmd(s5cmd(tc.args...))
+
+			result.Assert(t, icmd.Expected{ExitCode: 1})
+
+			assertLines(t, result.Stderr(), map[int]compareFunc{
+				0: prefix(tc.expected),
+			})
+		})
+	}
+}
+
 // cp dir/* s3://bucket/
 func TestCopyMultipleFilesToS3Bucket(t *testing.T) {
 	t.Parallel()
@@ -1726,6 +2005,7 @@ func TestCopySingleS3ObjectToS3JSON(t *testing.T) {
 
 	jsonText := fmt.Sprintf(`
 		{
+			"v":2,
 			"operation":"cp",
 			"success":true,
 			"source":"%v",
@@ -2109,6 +2389,7 @@ func TestCopyMultipleS3ObjectsToS3JSON(t *testing.T) {
 	assertLines(t, result.Stdout(), map[int]compareFunc{
 		0: json(`
 			{
+				"v": 2,
 				"operation": "cp",
 				"success": true,
 				"source": "s3://%v/readme.md",
@@ -2121,6 +2402,7 @@ func TestCopyMultipleS3ObjectsToS3JSON(t *testing.T) {
 		`, bucket, bucket, bucket),
 		1: json(`
 			{
+				"v": 2,
 				"operation": "cp",
 				"success": true,
 				"source": "s3://%v/testfile1.txt",
@@ -2290,6 +2572,44 @@ func TestCopyS3ToLocalWithSameFilenameOverrideIfSizeDiffers(t *testing.T) {
 	assert.Assert(t, fs.Equal(workdir.Path(), expected))
 }
 
+// cp -i s3://bucket/object dir/ (stdin is not a terminal)
+func TestCopyS3ToLocalWithInteractiveWithoutTerminal(t *testing.T) {
+	t.Parallel()
+
+	bucket := s3BucketFromTestName(t)
+
+	s3client, s5cmd := setup(t)
+
+	const (
+		filename        = "testfile1.txt"
+		content         = "this is the content"
+		expectedContent = content + "\n"
+	)
+
+	workdir := fs.NewDir(t, t.Name(), fs.WithFile(filename, content))
+	defer workdir.Remove()
+
+	createBucket(t, s3client, bucket)
+	putFile(t, s3client, bucket, filename, expectedContent)
+
+	cmd := s5cmd("cp", "-i", "s3://"+bucket+"/"+filename, ".")
+	result := icmd.RunCmd(cmd, withWorkingDir(workdir))
+
+	// '-i' is ignored since there is no terminal to prompt.
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`WARNING "cp --interactive=true s3://%v/%v .": standard input is not a terminal, ignoring --interactive flag`, bucket, filename),
+	})
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp s3://%v/%v %v`, bucket, filename, filename),
+	})
+
+	expected := fs.Expected(t, fs.WithFile(filename, expectedContent))
+	assert.Assert(t, fs.Equal(workdir.Path(), expected))
+}
+
 // cp -n -u s3://bucket/object dir/ (source is newer)
 func TestCopyS3ToLocalWithSameFilenameOverrideIfSourceIsNewer(t *testing.T) {
 	t.Parallel()
@@ -2971,7 +3291,7 @@ func TestCopyWithFollowSymlink(t *testing.T) {
 	assert.Assert(t, ensureS3Object(s3client, bucket, "prefix/c/link2", fileContent))
 }
 
-func TestCopyErrorWhenGivenObjectIsNotFoundUsingWildcard(t *testing.T) {
+func TestCopySkipBrokenSymlinkUsingWildcard(t *testing.T) {
 	t.Parallel()
 
 	s3client, s5cmd := setup(t)
@@ -2981,8 +3301,8 @@ func TestCopyErrorWhenGivenObjectIsNotFoundUsingWildcard(t *testing.T) {
 
 	folderLayout := []fs.PathOp{
 		// we intentionally did not create a/f1.txt to
-		// trigger given object not found error.
-		fs.WithDir("b"),
+		// create a broken symbolic link.
+		fs.WithDir("b", fs.WithFile("f2.txt", "content")),
 		fs.WithSymlink("b/link1", "a/f1.txt"),
 	}
 
@@ -2994,15 +3314,23 @@ func TestCopyErrorWhenGivenObjectIsNotFoundUsingWildcard(t *testing.T) {
 	cmd := s5cmd("cp", "*", dst)
 	result := icmd.RunCmd(cmd, withWorkingDir(workdir))
 
-	result.Assert(t, icmd.Expected{ExitCode: 1})
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals("cp b/f2.txt %vb/f2.txt", dst),
+	})
 
 	assertLines(t, result.Stderr(), map[int]compareFunc{
-		0: equals(`ERROR "cp * %v": given object b/link1 not found`, dst),
-	}, sortInput(true))
+		0: prefix(`WARNING skipping broken symbolic link b/link1 -> `),
+	})
+
+	assert.Assert(t, ensureS3Object(s3client, bucket, "prefix/b/f2.txt", "content"))
+	err := ensureS3Object(s3client, bucket, "prefix/b/link1", "")
+	assertError(t, err, errS3NoSuchKey)
 }
 
-// cp --no-follow-symlinks * s3://bucket/prefix/
-func TestCopyWithNoFollowSymlink(t *testing.T) {
+// cp --on-broken-symlink error * s3://bucket/prefix/
+func TestCopyErrorOnBrokenSymlinkUsingWildcard(t *testing.T) {
 	t.Parallel()
 
 	s3client, s5cmd := setup(t)
@@ -3010,16 +3338,9 @@ func TestCopyWithNoFollowSymlink(t *testing.T) {
 	bucket := s3BucketFromTestName(t)
 	createBucket(t, s3client, bucket)
 
-	fileContent := "CAFEBABE"
 	folderLayout := []fs.PathOp{
-		fs.WithDir(
-			"a",
-			fs.WithFile("f1.txt", fileContent),
-		),
-		fs.WithDir("b"),
-		fs.WithDir("c"),
+		fs.WithDir("b", fs.WithFile("f2.txt", "content")),
 		fs.WithSymlink("b/link1", "a/f1.txt"),
-		fs.WithSymlink("c/link2", "b/link1"),
 	}
 
 	workdir := fs.NewDir(t, t.Name(), folderLayout...)
@@ -3027,21 +3348,24 @@ func TestCopyWithNoFollowSymlink(t *testing.T) {
 
 	dst := fmt.Sprintf("s3://%v/prefix/", bucket)
 
-	cmd := s5cmd("cp", "--no-follow-symlinks", "*", dst)
+	cmd := s5cmd("cp", "--on-broken-symlink", "error", "*", dst)
 	result := icmd.RunCmd(cmd, withWorkingDir(workdir))
 
-	result.Assert(t, icmd.Success)
+	result.Assert(t, icmd.Expected{ExitCode: 1})
 
 	assertLines(t, result.Stdout(), map[int]compareFunc{
-		0: equals("cp a/f1.txt %va/f1.txt", dst),
-	}, sortInput(true))
+		0: equals("cp b/f2.txt %vb/f2.txt", dst),
+	})
 
-	// assert s3 objects
-	assert.Assert(t, ensureS3Object(s3client, bucket, "prefix/a/f1.txt", fileContent))
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: contains(`: broken symbolic link b/link1 -> `),
+	})
+
+	assert.Assert(t, ensureS3Object(s3client, bucket, "prefix/b/f2.txt", "content"))
 }
 
-// --dry-run cp dir/ s3://bucket/
-func TestCopyDirToS3DryRun(t *testing.T) {
+// cp --on-broken-symlink upload-as-link dir/ s3://bucket/prefix/
+func TestCopyUploadBrokenSymlinkAsLink(t *testing.T) {
 	t.Parallel()
 
 	s3client, s5cmd := setup(t)
@@ -3050,10 +3374,84 @@ func TestCopyDirToS3DryRun(t *testing.T) {
 	createBucket(t, s3client, bucket)
 
 	folderLayout := []fs.PathOp{
-		fs.WithFile("file1.txt", "content"),
-		fs.WithDir(
-			"c",
-			fs.WithFile("file2.txt", "content"),
+		fs.WithDir("b", fs.WithFile("f2.txt", "content")),
+	}
+
+	workdir := fs.NewDir(t, t.Name(), folderLayout...)
+	defer workdir.Remove()
+
+	assert.NilError(t, os.Symlink("missing.txt", workdir.Join("b", "link1")))
+
+	dst := fmt.Sprintf("s3://%v/prefix/", bucket)
+
+	cmd := s5cmd("cp", "--on-broken-symlink", "upload-as-link", "b/", dst)
+	result := icmd.RunCmd(cmd, withWorkingDir(workdir))
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals("cp b/f2.txt %vf2.txt", dst),
+		1: equals("cp b/link1 %vlink1", dst),
+	}, sortInput(true))
+
+	assert.Assert(t, ensureS3Object(s3client, bucket, "prefix/f2.txt", "content"))
+	assert.Assert(t, ensureS3Object(s3client, bucket, "prefix/link1", "missing.txt",
+		ensureMetadata("S5cmd-Symlink-Target", "missing.txt")))
+}
+
+// cp --no-follow-symlinks * s3://bucket/prefix/
+func TestCopyWithNoFollowSymlink(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	fileContent := "CAFEBABE"
+	folderLayout := []fs.PathOp{
+		fs.WithDir(
+			"a",
+			fs.WithFile("f1.txt", fileContent),
+		),
+		fs.WithDir("b"),
+		fs.WithDir("c"),
+		fs.WithSymlink("b/link1", "a/f1.txt"),
+		fs.WithSymlink("c/link2", "b/link1"),
+	}
+
+	workdir := fs.NewDir(t, t.Name(), folderLayout...)
+	defer workdir.Remove()
+
+	dst := fmt.Sprintf("s3://%v/prefix/", bucket)
+
+	cmd := s5cmd("cp", "--no-follow-symlinks", "*", dst)
+	result := icmd.RunCmd(cmd, withWorkingDir(workdir))
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals("cp a/f1.txt %va/f1.txt", dst),
+	}, sortInput(true))
+
+	// assert s3 objects
+	assert.Assert(t, ensureS3Object(s3client, bucket, "prefix/a/f1.txt", fileContent))
+}
+
+// --dry-run cp dir/ s3://bucket/
+func TestCopyDirToS3DryRun(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	folderLayout := []fs.PathOp{
+		fs.WithFile("file1.txt", "content"),
+		fs.WithDir(
+			"c",
+			fs.WithFile("file2.txt", "content"),
 		),
 	}
 
@@ -4120,6 +4518,148 @@ func TestVersionedDownload(t *testing.T) {
 	assert.Assert(t, fs.Equal(workdir.Path(), fs.ManifestFromDir(t, newDir.Path())))
 }
 
+// cp --pin-versions s3://bucket/* dir/
+func TestCopyS3ObjectsWithPinVersions(t *testing.T) {
+	t.Parallel()
+
+	bucket := s3BucketFromTestName(t)
+
+	// versioninng is only supported with in memory backend!
+	s3client, s5cmd := setup(t, withS3Backend("mem"))
+
+	createBucket(t, s3client, bucket)
+	setBucketVersioning(t, s3client, bucket, "Enabled")
+
+	putFile(t, s3client, bucket, "a.txt", "first content of a")
+	putFile(t, s3client, bucket, "a.txt", "second content of a")
+	putFile(t, s3client, bucket, "b.txt", "content of b")
+	// the deleted objects are not copied.
+	putFile(t, s3client, bucket, "c.txt", "content of c")
+	deleteObject(t, s3client, bucket, "c.txt")
+
+	versions := latestVersions(t, s3client, bucket)
+
+	workdir := fs.NewDir(t, t.Name())
+	defer workdir.Remove()
+
+	srcpath := fmt.Sprintf("s3://%v/*", bucket)
+	dstpath := filepath.ToSlash(workdir.Path()) + "/"
+
+	cmd := s5cmd("--json", "cp", "--pin-versions", srcpath, dstpath)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	jsonText := `
+		{
+			"v": 2,
+			"operation": "cp",
+			"success": true,
+			"source": "s3://%v/%v",
+			"destination": "%v%v",
+			"object": {
+				"type": "file",
+				"size": %v
+			},
+			"version_id": "%v"
+		}
+	`
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: json(jsonText, bucket, "a.txt", dstpath, "a.txt", 19, versions["a.txt"]),
+		1: json(jsonText, bucket, "b.txt", dstpath, "b.txt", 12, versions["b.txt"]),
+	}, sortInput(true), jsonCheck(true))
+
+	expected := fs.Expected(t,
+		fs.WithFile("a.txt", "second content of a"),
+		fs.WithFile("b.txt", "content of b"),
+	)
+	assert.Assert(t, fs.Equal(workdir.Path(), expected))
+}
+
+// cp --pin-versions --version-id VERSION s3://bucket/object dir/
+func TestCopyS3ObjectWithPinVersionsVanished(t *testing.T) {
+	t.Parallel()
+
+	bucket := s3BucketFromTestName(t)
+
+	// versioninng is only supported with in memory backend!
+	s3client, s5cmd := setup(t, withS3Backend("mem"))
+
+	createBucket(t, s3client, bucket)
+	setBucketVersioning(t, s3client, bucket, "Enabled")
+
+	putFile(t, s3client, bucket, "a.txt", "first content of a")
+	version := latestVersions(t, s3client, bucket)["a.txt"]
+	putFile(t, s3client, bucket, "a.txt", "second content of a")
+
+	// the pinned version is deleted after it is listed.
+	_, err := s3client.DeleteObject(&s3.DeleteObjectInput{
+		Bucket:    aws.String(bucket),
+		Key:       aws.String("a.txt"),
+		VersionId: aws.String(version),
+	})
+	assert.NilError(t, err)
+
+	workdir := fs.NewDir(t, t.Name())
+	defer workdir.Remove()
+
+	srcpath := fmt.Sprintf("s3://%v/a.txt", bucket)
+	dstpath := filepath.ToSlash(workdir.Path()) + "/"
+
+	cmd := s5cmd("cp", "--pin-versions", "--version-id", version, srcpath, dstpath)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`vanished %v %va.txt: pinned version %v doesn't exist anymore`, srcpath, dstpath, version),
+	})
+
+	// the current version is not copied instead.
+	assert.Assert(t, fs.Equal(workdir.Path(), fs.Expected(t)))
+}
+
+func TestCopyPinVersionsValidation(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd := setup(t)
+
+	workdir := fs.NewDir(t, t.Name(), fs.WithFile("a.txt", "content"))
+	defer workdir.Remove()
+
+	testcases := []struct {
+		name     string
+		cmd      []string
+		expected string
+	}{
+		{
+			name:     "local source",
+			cmd:      []string{"cp", "--pin-versions", "a.txt", "s3://bucket/"},
+			expected: `ERROR "cp --pin-versions=true a.txt s3://bucket/": "pin-versions" flag can only be used with remote source`,
+		},
+		{
+			name:     "mv",
+			cmd:      []string{"mv", "--pin-versions", "s3://bucket/*", "dir/"},
+			expected: `ERROR "mv --pin-versions=true s3://bucket/* dir/": "pin-versions" flag cannot be used with mv, since it would delete the pinned versions permanently`,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			cmd := s5cmd(tc.cmd...)
+			result := icmd.RunCmd(cmd, withWorkingDir(workdir))
+
+			result.Assert(t, icmd.Expected{ExitCode: 1})
+
+			assertLines(t, result.Stderr(), map[int]compareFunc{
+				0: equals(tc.expected),
+			})
+		})
+	}
+}
+
 // Before downloading a file from s3 a local target file is created. If download
 // fails the created file should be deleted.
 func TestDeleteFileWhenDownloadFailed(t *testing.T) {
@@ -4196,3 +4736,1489 @@ func TestCountingWriter(t *testing.T) {
 	expected := fs.Expected(t, fs.WithFile(filename, content, fs.WithMode(0644)))
 	assert.Assert(t, fs.Equal(cmd.Dir, expected))
 }
+
+// cp --preallocate "s3://bucket/*" dir/
+func TestCopyMultipleS3ObjectsToLocalWithPreallocate(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	largeContent := strings.Repeat("s5cmd", 1<<16)
+	putFile(t, s3client, bucket, "file.txt", "this is a file content")
+	putFile(t, s3client, bucket, "large.txt", largeContent)
+
+	cmd := s5cmd("cp", "--preallocate", "s3://"+bucket+"/*", "dir/")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp s3://%v/file.txt dir/file.txt`, bucket),
+		1: equals(`cp s3://%v/large.txt dir/large.txt`, bucket),
+	}, sortInput(true))
+
+	expected := fs.Expected(t, fs.WithDir("dir",
+		fs.WithFile("file.txt", "this is a file content", fs.WithMode(0644)),
+		fs.WithFile("large.txt", largeContent, fs.WithMode(0644)),
+	))
+	assert.Assert(t, fs.Equal(cmd.Dir, expected))
+}
+
+// cp --append-detect file s3://bucket/file
+func TestCopySingleFileToS3WithAppendDetectUploadsSmallFilesInFull(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	const (
+		filename = "app.log"
+		content  = "line 1\nline 2\nline 3\n"
+	)
+
+	// the existing object is too small to be copied as a part of a multipart
+	// upload, so the whole file is uploaded.
+	putFile(t, s3client, bucket, filename, "line 1\n")
+
+	workdir := fs.NewDir(t, bucket, fs.WithFile(filename, content))
+	defer workdir.Remove()
+
+	srcpath := filepath.ToSlash(workdir.Join(filename))
+	dstpath := fmt.Sprintf("s3://%v/%v", bucket, filename)
+
+	cmd := s5cmd("cp", "--append-detect", srcpath, dstpath)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %v %v`, srcpath, dstpath),
+	})
+
+	assert.Assert(t, ensureS3Object(s3client, bucket, filename, content))
+}
+
+func TestCopyWithVerifyBucketFailsForNonexistentDestinationBucket(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+	putFile(t, s3client, bucket, "file.txt", "content")
+
+	dstbucket := s3BucketFromTestNameWithPrefix(t, "dst")
+
+	srcpath := fmt.Sprintf("s3://%v/*", bucket)
+	dstpath := fmt.Sprintf("s3://%v/", dstbucket)
+
+	cmd := s5cmd("cp", "--verify-bucket", srcpath, dstpath)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{})
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "cp --verify-bucket=true %v %v": destination bucket %q does not exist`, srcpath, dstpath, dstbucket),
+	}, strictLineCheck(true))
+}
+
+func TestCopyWithVerifyBucket(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+	putFile(t, s3client, bucket, "file.txt", "content")
+
+	workdir := fs.NewDir(t, bucket)
+	defer workdir.Remove()
+
+	srcpath := fmt.Sprintf("s3://%v/file.txt", bucket)
+	dstpath := filepath.ToSlash(workdir.Path()) + "/"
+
+	cmd := s5cmd("cp", "--verify-bucket", srcpath, dstpath)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %v %vfile.txt`, srcpath, dstpath),
+	})
+
+	expected := fs.Expected(t, fs.WithFile("file.txt", "content"))
+	assert.Assert(t, fs.Equal(workdir.Path(), expected))
+}
+
+func TestCopyMultipleS3ObjectsToLocalWithHardlinkIdentical(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	putFile(t, s3client, bucket, "a/file.txt", "duplicate content")
+	putFile(t, s3client, bucket, "b/file.txt", "duplicate content")
+	putFile(t, s3client, bucket, "c/file.txt", "unique content")
+
+	workdir := fs.NewDir(t, t.Name())
+	defer workdir.Remove()
+
+	srcpath := fmt.Sprintf("s3://%v/*", bucket)
+	dstpath := filepath.ToSlash(workdir.Path()) + "/"
+
+	cmd := s5cmd("cp", "--hardlink-identical", srcpath, dstpath)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp s3://%v/a/file.txt %va/file.txt`, bucket, dstpath),
+		1: equals(`cp s3://%v/b/file.txt %vb/file.txt`, bucket, dstpath),
+		2: equals(`cp s3://%v/c/file.txt %vc/file.txt`, bucket, dstpath),
+	}, sortInput(true))
+
+	expected := fs.Expected(t,
+		fs.WithDir("a", fs.WithFile("file.txt", "duplicate content")),
+		fs.WithDir("b", fs.WithFile("file.txt", "duplicate content")),
+		fs.WithDir("c", fs.WithFile("file.txt", "unique content")),
+	)
+	assert.Assert(t, fs.Equal(workdir.Path(), expected))
+
+	stat := func(name string) os.FileInfo {
+		t.Helper()
+		fi, err := os.Stat(workdir.Join(name, "file.txt"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return fi
+	}
+
+	assert.Assert(t, os.SameFile(stat("a"), stat("b")), "expected identical files to be hard linked")
+	assert.Assert(t, !os.SameFile(stat("a"), stat("c")), "expected different files not to be hard linked")
+}
+
+// cp --max-disk-usage 1MB s3://bucket/* dir/
+func TestCopyMultipleS3ObjectsToLocalWithMaxDiskUsage(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	putFile(t, s3client, bucket, "file1.txt", "content")
+	putFile(t, s3client, bucket, "file2.txt", "more content")
+
+	workdir := fs.NewDir(t, t.Name())
+	defer workdir.Remove()
+
+	srcpath := fmt.Sprintf("s3://%v/*", bucket)
+	dstpath := filepath.ToSlash(workdir.Path()) + "/"
+
+	cmd := s5cmd("cp", "--max-disk-usage", "1MB", srcpath, dstpath)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp s3://%v/file1.txt %vfile1.txt`, bucket, dstpath),
+		1: equals(`cp s3://%v/file2.txt %vfile2.txt`, bucket, dstpath),
+	}, sortInput(true))
+
+	expected := fs.Expected(t,
+		fs.WithFile("file1.txt", "content"),
+		fs.WithFile("file2.txt", "more content"),
+	)
+	assert.Assert(t, fs.Equal(workdir.Path(), expected))
+}
+
+// cp --max-disk-usage 4B s3://bucket/object dir/
+func TestCopyS3ObjectToLocalLargerThanMaxDiskUsage(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	putFile(t, s3client, bucket, "file.txt", "content")
+
+	workdir := fs.NewDir(t, t.Name())
+	defer workdir.Remove()
+
+	srcpath := fmt.Sprintf("s3://%v/file.txt", bucket)
+	dstpath := filepath.ToSlash(workdir.Path()) + "/"
+
+	cmd := s5cmd("cp", "--max-disk-usage", "4B", srcpath, dstpath)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "cp %v %vfile.txt": object size 7 exceeds the maximum disk usage 4`, srcpath, dstpath),
+	})
+
+	// the object is not downloaded.
+	assert.Assert(t, fs.Equal(workdir.Path(), fs.Expected(t)))
+}
+
+func TestCopyWithInvalidMaxDiskUsage(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd := setup(t)
+
+	cmd := s5cmd("cp", "--max-disk-usage", "lots", "s3://bucket/*", "dir/")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "cp --max-disk-usage=lots s3://bucket/* dir/": invalid max-disk-usage: invalid size "lots"`),
+	})
+}
+
+// cp --space-check s3://bucket/* dir/
+func TestCopyMultipleS3ObjectsToLocalWithSpaceCheck(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	putFile(t, s3client, bucket, "file.txt", "content")
+
+	workdir := fs.NewDir(t, t.Name())
+	defer workdir.Remove()
+
+	srcpath := fmt.Sprintf("s3://%v/*", bucket)
+	dstpath := filepath.ToSlash(workdir.Path()) + "/"
+
+	cmd := s5cmd("cp", "--space-check", srcpath, dstpath)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp s3://%v/file.txt %vfile.txt`, bucket, dstpath),
+	})
+
+	assert.Assert(t, fs.Equal(workdir.Path(), fs.Expected(t, fs.WithFile("file.txt", "content"))))
+}
+
+// cp s3://bucket/* dir/ (dir/file.txt is a directory)
+func TestCopyS3ObjectsToLocalTypeConflictSkip(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	putFile(t, s3client, bucket, "file.txt", "content")
+	putFile(t, s3client, bucket, "dir/nested.txt", "nested")
+	putFile(t, s3client, bucket, "other.txt", "other")
+
+	workdir := fs.NewDir(t, t.Name(),
+		fs.WithDir("file.txt", fs.WithFile("existing.txt", "existing")),
+		fs.WithFile("dir", "i am a file"),
+	)
+	defer workdir.Remove()
+
+	srcpath := fmt.Sprintf("s3://%v/*", bucket)
+	dstpath := filepath.ToSlash(workdir.Path()) + "/"
+
+	cmd := s5cmd("cp", srcpath, dstpath)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp s3://%v/other.txt %vother.txt`, bucket, dstpath),
+		1: equals(`skip s3://%v/dir/nested.txt %vdir/nested.txt: type conflict: %vdir is not a directory`, bucket, dstpath, workdir.Path()+"/"),
+		2: equals(`skip s3://%v/file.txt %vfile.txt: type conflict: %vfile.txt is a directory`, bucket, dstpath, dstpath),
+	}, sortInput(true))
+
+	expected := fs.Expected(t,
+		fs.WithDir("file.txt", fs.WithFile("existing.txt", "existing")),
+		fs.WithFile("dir", "i am a file"),
+		fs.WithFile("other.txt", "other"),
+	)
+	assert.Assert(t, fs.Equal(workdir.Path(), expected))
+}
+
+// cp --on-type-conflict fail s3://bucket/* dir/
+func TestCopyS3ObjectsToLocalTypeConflictFail(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	putFile(t, s3client, bucket, "file.txt", "content")
+
+	workdir := fs.NewDir(t, t.Name(), fs.WithDir("file.txt"))
+	defer workdir.Remove()
+
+	srcpath := fmt.Sprintf("s3://%v/*", bucket)
+	dstpath := filepath.ToSlash(workdir.Path()) + "/"
+
+	cmd := s5cmd("cp", "--on-type-conflict", "fail", srcpath, dstpath)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "cp s3://%v/file.txt %vfile.txt": type conflict: %vfile.txt is a directory`, bucket, dstpath, dstpath),
+	})
+
+	assert.Assert(t, fs.Equal(workdir.Path(), fs.Expected(t, fs.WithDir("file.txt"))))
+}
+
+// cp --on-type-conflict replace s3://bucket/* dir/
+func TestCopyS3ObjectsToLocalTypeConflictReplace(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	putFile(t, s3client, bucket, "empty.txt", "content")
+	putFile(t, s3client, bucket, "full.txt", "content")
+	putFile(t, s3client, bucket, "dir/nested.txt", "nested")
+
+	workdir := fs.NewDir(t, t.Name(),
+		fs.WithDir("empty.txt"),
+		fs.WithDir("full.txt", fs.WithFile("existing.txt", "existing")),
+		fs.WithFile("dir", "i am a file"),
+	)
+	defer workdir.Remove()
+
+	srcpath := fmt.Sprintf("s3://%v/*", bucket)
+	dstpath := filepath.ToSlash(workdir.Path()) + "/"
+
+	cmd := s5cmd("cp", "--on-type-conflict", "replace", srcpath, dstpath)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp s3://%v/dir/nested.txt %vdir/nested.txt`, bucket, dstpath),
+		1: equals(`cp s3://%v/empty.txt %vempty.txt`, bucket, dstpath),
+	}, sortInput(true))
+
+	// non-empty directories are replaced only with --force flag.
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "cp s3://%v/full.txt %vfull.txt": type conflict: %vfull.txt is a non-empty directory; use --force flag to replace it`, bucket, dstpath, dstpath),
+	})
+
+	expected := fs.Expected(t,
+		fs.WithFile("empty.txt", "content"),
+		fs.WithDir("full.txt", fs.WithFile("existing.txt", "existing")),
+		fs.WithDir("dir", fs.WithFile("nested.txt", "nested")),
+	)
+	assert.Assert(t, fs.Equal(workdir.Path(), expected))
+
+	cmd = s5cmd("cp", "--on-type-conflict", "replace", "--force", srcpath, dstpath)
+	result = icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	expected = fs.Expected(t,
+		fs.WithFile("empty.txt", "content"),
+		fs.WithFile("full.txt", "content"),
+		fs.WithDir("dir", fs.WithFile("nested.txt", "nested")),
+	)
+	assert.Assert(t, fs.Equal(workdir.Path(), expected))
+}
+
+func TestCopyForceWithoutTypeConflictReplace(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd := setup(t)
+
+	cmd := s5cmd("cp", "--force", "s3://bucket/*", "dir/")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "cp --force=true s3://bucket/* dir/": "force" flag can only be used with "--on-type-conflict replace"`),
+	})
+}
+
+// cp --also-to s3://dr-bucket/prefix/ dir/* s3://bucket/prefix/
+func TestCopyDirToS3WithAlsoTo(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	drBucket := "dr-" + bucket
+	createBucket(t, s3client, bucket)
+	createBucket(t, s3client, drBucket)
+
+	workdir := fs.NewDir(t, bucket,
+		fs.WithFile("a.txt", "content of a"),
+		fs.WithDir("dir", fs.WithFile("b.txt", "content of b")),
+	)
+	defer workdir.Remove()
+
+	srcpath := filepath.ToSlash(workdir.Path())
+
+	cmd := s5cmd("cp", "--also-to", fmt.Sprintf("s3://%v/prefix/", drBucket), srcpath+"/*", fmt.Sprintf("s3://%v/prefix/", bucket))
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %v/a.txt s3://%v/prefix/a.txt`, srcpath, drBucket),
+		1: equals(`cp %v/a.txt s3://%v/prefix/a.txt`, srcpath, bucket),
+		2: equals(`cp %v/dir/b.txt s3://%v/prefix/dir/b.txt`, srcpath, drBucket),
+		3: equals(`cp %v/dir/b.txt s3://%v/prefix/dir/b.txt`, srcpath, bucket),
+	}, sortInput(true))
+
+	for _, b := range []string{bucket, drBucket} {
+		assert.Assert(t, ensureS3Object(s3client, b, "prefix/a.txt", "content of a"))
+		assert.Assert(t, ensureS3Object(s3client, b, "prefix/dir/b.txt", "content of b"))
+	}
+}
+
+// mv --also-to s3://nonexistent-bucket/ file s3://bucket/
+func TestMoveFileToS3WithAlsoToFailingDestination(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	const (
+		filename = "testfile1.txt"
+		content  = "this is the content"
+	)
+
+	workdir := fs.NewDir(t, bucket, fs.WithFile(filename, content))
+	defer workdir.Remove()
+
+	srcpath := filepath.ToSlash(workdir.Join(filename))
+	dstpath := fmt.Sprintf("s3://%v/", bucket)
+	drpath := "s3://nonexistent-bucket/"
+
+	cmd := s5cmd("mv", "--also-to", drpath, srcpath, dstpath)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	// the file is uploaded to the destinations which are available.
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`mv %v %v%v`, srcpath, dstpath, filename),
+	})
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: prefix(`ERROR "mv %v %v%v": `, srcpath, drpath, filename),
+	})
+
+	assert.Assert(t, ensureS3Object(s3client, bucket, filename, content))
+
+	// the source file is kept since it's not uploaded to all destinations.
+	assert.Assert(t, fs.Equal(workdir.Path(), fs.Expected(t, fs.WithFile(filename, content))))
+}
+
+// cp --also-to s3://nonexistent-bucket/ --fanout-require-all file s3://bucket/
+func TestCopyFileToS3WithAlsoToRequireAll(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	const (
+		filename = "testfile1.txt"
+		content  = "this is the content"
+	)
+
+	workdir := fs.NewDir(t, bucket, fs.WithFile(filename, content))
+	defer workdir.Remove()
+
+	srcpath := filepath.ToSlash(workdir.Join(filename))
+	dstpath := fmt.Sprintf("s3://%v/", bucket)
+	drpath := "s3://nonexistent-bucket/"
+
+	cmd := s5cmd("cp", "--also-to", drpath, "--fanout-require-all", srcpath, dstpath)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{})
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: prefix(`ERROR "cp %v %v%v": `, srcpath, drpath, filename),
+	})
+
+	// the file is not uploaded to any of the destinations.
+	err := ensureS3Object(s3client, bucket, filename, content)
+	assertError(t, err, errS3NoSuchKey)
+}
+
+func TestCopyAlsoToValidation(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "local also-to",
+			args:     []string{"cp", "--also-to", "dir/", "file", "s3://bucket/"},
+			expected: `ERROR "cp --also-to=dir/ file s3://bucket/": "also-to" flag can only be used with remote destinations`,
+		},
+		{
+			name:     "download",
+			args:     []string{"cp", "--also-to", "s3://dr-bucket/", "s3://bucket/key", "dir/"},
+			expected: `ERROR "cp --also-to=s3://dr-bucket/ s3://bucket/key dir/": "also-to" flag can only be used to upload local files`,
+		},
+		{
+			name:     "wildcard source to object",
+			args:     []string{"cp", "--also-to", "s3://dr-bucket/key", "dir/*", "s3://bucket/"},
+			expected: `ERROR "cp --also-to=s3://dr-bucket/key dir/* s3://bucket/": target "s3://dr-bucket/key" must be a bucket or a prefix`,
+		},
+		{
+			name:     "require all without also-to",
+			args:     []string{"cp", "--fanout-require-all", "file", "s3://bucket/"},
+			expected: `ERROR "cp --fanout-require-all=true file s3://bucket/": "fanout-require-all" flag requires "also-to" flag`,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, s5cmd := setup(t)
+
+			result := icmd.RunCmd(s5cmd(tc.args...))
+			result.Assert(t, icmd.Expected{ExitCode: 1})
+
+			assertLines(t, result.Stderr(), map[int]compareFunc{
+				0: equals(tc.expected),
+			})
+		})
+	}
+}
+
+func TestCopyMultipartValidation(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "zero",
+			args:     []string{"cp", "--multipart-copy-concurrency", "0", "s3://bucket/key", "s3://target-bucket/"},
+			expected: `ERROR "cp --multipart-copy-concurrency=0 s3://bucket/key s3://target-bucket/": "multipart-copy-concurrency" flag must be greater than zero`,
+		},
+		{
+			name:     "negative with sync",
+			args:     []string{"sync", "--multipart-copy-concurrency", "-1", "s3://bucket/*", "s3://target-bucket/"},
+			expected: `ERROR "sync --multipart-copy-concurrency=-1 s3://bucket/* s3://target-bucket/": "multipart-copy-concurrency" flag must be greater than zero`,
+		},
+		{
+			name:     "invalid multipart threshold",
+			args:     []string{"cp", "--multipart-threshold", "large", "file", "s3://bucket/"},
+			expected: `ERROR "cp --multipart-threshold=large file s3://bucket/": invalid multipart-threshold: invalid size "large"`,
+		},
+		{
+			name:     "multipart threshold below the minimum part size",
+			args:     []string{"cp", "--multipart-threshold", "1MB", "file", "s3://bucket/"},
+			expected: `ERROR "cp --multipart-threshold=1MB file s3://bucket/": "multipart-threshold" flag must be at least 5MiB, the minimum part size`,
+		},
+		{
+			name:     "multipart threshold above the maximum object size with sync",
+			args:     []string{"sync", "--multipart-threshold", "6GB", "dir/", "s3://bucket/"},
+			expected: `ERROR "sync --multipart-threshold=6GB dir/ s3://bucket/": "multipart-threshold" flag must be at most 5GiB, the maximum size of a single request`,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, s5cmd := setup(t)
+
+			result := icmd.RunCmd(s5cmd(tc.args...))
+			result.Assert(t, icmd.Expected{ExitCode: 1})
+
+			assertLines(t, result.Stderr(), map[int]compareFunc{
+				0: equals(tc.expected),
+			})
+		})
+	}
+}
+
+// cp --part-size 5 --multipart-threshold 20MB dir/file s3://bucket/
+func TestCopySingleFileToS3WithMultipartThreshold(t *testing.T) {
+	t.Parallel()
+
+	content := strings.Repeat("s5cmd", 12<<20/5)
+
+	testcases := []struct {
+		name     string
+		flags    []string
+		expected string
+	}{
+		{
+			name:     "larger than part size",
+			flags:    []string{"--part-size", "5"},
+			expected: "uploaded 0 objects with a single request and 1 objects in parts",
+		},
+		{
+			name:     "up to multipart threshold",
+			flags:    []string{"--part-size", "5", "--multipart-threshold", "20MB"},
+			expected: "uploaded 1 objects with a single request and 0 objects in parts",
+		},
+		{
+			name:     "larger than multipart threshold",
+			flags:    []string{"--part-size", "5", "--multipart-threshold", "10MB"},
+			expected: "uploaded 0 objects with a single request and 1 objects in parts",
+		},
+		{
+			name:     "up to part size",
+			flags:    []string{"--part-size", "20", "--multipart-threshold", "10MB"},
+			expected: "uploaded 1 objects with a single request and 0 objects in parts",
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			s3client, s5cmd := setup(t)
+
+			bucket := s3BucketFromTestName(t)
+			createBucket(t, s3client, bucket)
+
+			workdir := fs.NewDir(t, bucket, fs.WithFile("file.bin", content))
+			defer workdir.Remove()
+
+			args := append([]string{"--stat", "cp"}, tc.flags...)
+			args = append(args, workdir.Join("file.bin"), fmt.Sprintf("s3://%v/", bucket))
+			result := icmd.RunCmd(s5cmd(args...))
+
+			result.Assert(t, icmd.Success)
+
+			lines := strings.Split(strings.TrimSpace(result.Stdout()), "\n")
+			assertLines(t, lines[len(lines)-1], map[int]compareFunc{
+				0: equals(tc.expected),
+			})
+
+			assert.Assert(t, ensureS3Object(s3client, bucket, "file.bin", content))
+		})
+	}
+}
+
+// cp --website-redirect /new.html dir/file s3://bucket/
+// cp --content-language de s3://bucket/file s3://bucket/copy
+// cp --system-header X-Amz-Website-Redirect-Location=https://example.com/other.html s3://bucket/file s3://bucket/override
+func TestCopyWithWebsiteRedirect(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	const (
+		filename = "old.html"
+		content  = "moved"
+	)
+
+	workdir := fs.NewDir(t, bucket, fs.WithFile(filename, content))
+	defer workdir.Remove()
+
+	srcpath := filepath.ToSlash(workdir.Join(filename))
+	result := icmd.RunCmd(s5cmd("cp", "--website-redirect", "/new.html", srcpath, fmt.Sprintf("s3://%v/", bucket)))
+	result.Assert(t, icmd.Success)
+	assert.Assert(t, ensureS3Object(s3client, bucket, filename, content, ensureWebsiteRedirect("/new.html")))
+
+	// the redirect location of the source is kept by the copies which
+	// override the other headers.
+	src := fmt.Sprintf("s3://%v/%v", bucket, filename)
+	result = icmd.RunCmd(s5cmd("cp", "--content-language", "de", src, fmt.Sprintf("s3://%v/copy.html", bucket)))
+	result.Assert(t, icmd.Success)
+	assert.Assert(t, ensureS3Object(s3client, bucket, "copy.html", content, ensureWebsiteRedirect("/new.html")))
+
+	result = icmd.RunCmd(s5cmd("cp", "--system-header", "x-amz-website-redirect-location=https://example.com/other.html", src, fmt.Sprintf("s3://%v/override.html", bucket)))
+	result.Assert(t, icmd.Success)
+	assert.Assert(t, ensureS3Object(s3client, bucket, "override.html", content, ensureWebsiteRedirect("https://example.com/other.html")))
+}
+
+func TestCopySystemHeaderValidation(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "website redirect is not a key or a URL",
+			args:     []string{"cp", "--website-redirect", "new.html", "file", "s3://bucket/"},
+			expected: `ERROR "cp --website-redirect=new.html file s3://bucket/": website redirect location "new.html" must start with "/", "http://" or "https://"`,
+		},
+		{
+			name:     "website redirect with system header",
+			args:     []string{"sync", "--system-header", "X-Amz-Website-Redirect-Location=new.html", "dir/*", "s3://bucket/"},
+			expected: `ERROR "sync --system-header=X-Amz-Website-Redirect-Location=new.html dir/* s3://bucket/": website redirect location "new.html" must start with "/", "http://" or "https://"`,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, s5cmd := setup(t)
+
+			result := icmd.RunCmd(s5cmd(tc.args...))
+			result.Assert(t, icmd.Expected{ExitCode: 1})
+
+			assertLines(t, result.Stderr(), map[int]compareFunc{
+				0: equals(tc.expected),
+			})
+		})
+	}
+
+	_, s5cmd := setup(t)
+	result := icmd.RunCmd(s5cmd("cp", "--system-header", "X-Amz-Meta-Owner=team", "file", "s3://bucket/"))
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+	assert.Assert(t, strings.HasPrefix(result.Stdout(), `Incorrect Usage: invalid value "X-Amz-Meta-Owner=team" for flag -system-header: allowed headers: [Cache-Control, `))
+}
+
+// cp --dest-date-prefix 2006/01/02 "dir/*" s3://bucket/logs/
+func TestCopyDirToS3WithDestDatePrefix(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	day := time.Date(2024, 1, 2, 23, 30, 0, 0, time.UTC)
+	workdir := fs.NewDir(t, "somedir",
+		fs.WithFile("a.log", "content", fs.WithTimestamps(day, day)),
+		fs.WithDir("app",
+			fs.WithFile("b.log", "content", fs.WithTimestamps(day.Add(time.Hour), day.Add(time.Hour))),
+		),
+	)
+	defer workdir.Remove()
+
+	src := filepath.ToSlash(workdir.Path())
+	dst := fmt.Sprintf("s3://%v/logs/", bucket)
+
+	result := icmd.RunCmd(s5cmd("cp", "--dest-date-prefix", "2006/01/02", src+"/*", dst))
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %v/a.log %v2024/01/02/a.log`, src, dst),
+		1: equals(`cp %v/app/b.log %v2024/01/03/app/b.log`, src, dst),
+	}, sortInput(true), strictLineCheck(true))
+
+	assert.Assert(t, ensureS3Object(s3client, bucket, "logs/2024/01/02/a.log", "content"))
+	assert.Assert(t, ensureS3Object(s3client, bucket, "logs/2024/01/03/app/b.log", "content"))
+}
+
+// cp --dest-date-prefix 2006-01 s3://bucket/file dir/
+func TestCopySingleS3ObjectToLocalWithDestDatePrefix(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+	putFile(t, s3client, bucket, "file.txt", "content")
+
+	workdir := fs.NewDir(t, "somedir")
+	defer workdir.Remove()
+
+	result := icmd.RunCmd(s5cmd("cp", "--dest-date-prefix", "2006-01", fmt.Sprintf("s3://%v/file.txt", bucket), workdir.Path()+"/"))
+	result.Assert(t, icmd.Success)
+
+	// the objects are put under the month of their modification time.
+	obj, err := s3client.HeadObject(&s3.HeadObjectInput{Bucket: aws.String(bucket), Key: aws.String("file.txt")})
+	assert.NilError(t, err)
+	prefix := obj.LastModified.UTC().Format("2006-01")
+
+	content, err := os.ReadFile(workdir.Join(prefix, "file.txt"))
+	assert.NilError(t, err)
+	assert.Equal(t, string(content), "content")
+}
+
+func TestCopyDestDatePrefixValidation(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "layout without time elements",
+			args:     []string{"cp", "--dest-date-prefix", "logs/", "dir/*", "s3://bucket/"},
+			expected: `ERROR "cp --dest-date-prefix=logs/ dir/* s3://bucket/": "dest-date-prefix" flag must be a Go time layout, e.g. 2006/01/02`,
+		},
+		{
+			name:     "remote object destination",
+			args:     []string{"cp", "--dest-date-prefix", "2006/01/02", "file", "s3://bucket/file"},
+			expected: `ERROR "cp --dest-date-prefix=2006/01/02 file s3://bucket/file": "dest-date-prefix" flag requires the destination to be a bucket or a prefix`,
+		},
+		{
+			name:     "also to",
+			args:     []string{"cp", "--also-to", "s3://other/", "--dest-date-prefix", "2006/01/02", "file", "s3://bucket/"},
+			expected: `ERROR "cp --also-to=s3://other/ --dest-date-prefix=2006/01/02 file s3://bucket/": "dest-date-prefix" flag cannot be used with "also-to" flag`,
+		},
+		{
+			name:     "sync partitions",
+			args:     []string{"sync", "--partition-by-prefix", "--dest-date-prefix", "2006/01/02", "dir/", "s3://bucket/"},
+			expected: `ERROR "sync --partition-by-prefix=true --dest-date-prefix=2006/01/02 dir/ s3://bucket/": it is not allowed to combine "dest-date-prefix" and "partition-by-prefix" flags`,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, s5cmd := setup(t)
+
+			result := icmd.RunCmd(s5cmd(tc.args...))
+			result.Assert(t, icmd.Expected{ExitCode: 1})
+
+			assertLines(t, result.Stderr(), map[int]compareFunc{
+				0: equals(tc.expected),
+			})
+		})
+	}
+}
+
+// cp --transform-exec "tr a-z A-Z" "dir/*" s3://bucket/
+// cp --transform-exec "tr a-z A-Z" "s3://bucket/*" s3://bucket/upper/
+func TestCopyWithTransformExec(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("transform command requires tr")
+	}
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	workdir := fs.NewDir(t, "somedir",
+		fs.WithFile("a.txt", "content a"),
+		fs.WithDir("dir", fs.WithFile("b.txt", "content b")),
+	)
+	defer workdir.Remove()
+
+	src := filepath.ToSlash(workdir.Path())
+	dst := fmt.Sprintf("s3://%v/", bucket)
+
+	result := icmd.RunCmd(s5cmd("cp", "--transform-exec", "tr a-z A-Z", src+"/*", dst))
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %v/a.txt %va.txt`, src, dst),
+		1: equals(`cp %v/dir/b.txt %vdir/b.txt`, src, dst),
+	}, sortInput(true), strictLineCheck(true))
+
+	assert.Assert(t, ensureS3Object(s3client, bucket, "a.txt", "CONTENT A", ensureContentType("text/plain; charset=utf-8")))
+	assert.Assert(t, ensureS3Object(s3client, bucket, "dir/b.txt", "CONTENT B"))
+
+	// the remote objects are transformed as they are downloaded and
+	// uploaded.
+	result = icmd.RunCmd(s5cmd("cp", "--transform-exec", "tr A-Z a-z", fmt.Sprintf("s3://%v/dir/*", bucket), dst+"lower/"))
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %vdir/b.txt %vlower/b.txt`, dst, dst),
+	}, strictLineCheck(true))
+
+	assert.Assert(t, ensureS3Object(s3client, bucket, "lower/b.txt", "content b"))
+}
+
+// cp --transform-exec "cat /nonexistent" file s3://bucket/
+func TestCopyWithFailingTransformExec(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("transform command requires cat")
+	}
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	workdir := fs.NewDir(t, "somedir", fs.WithFile("a.txt", "content"))
+	defer workdir.Remove()
+
+	src := filepath.ToSlash(workdir.Join("a.txt"))
+	dst := fmt.Sprintf("s3://%v/", bucket)
+
+	result := icmd.RunCmd(s5cmd("cp", "--transform-exec", "cat /s5cmd/nonexistent", src, dst))
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	// the standard error of the command is reported.
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: match(`^ERROR "cp .*a.txt s3://.*/a.txt": transform command failed: exit status 1: cat: /s5cmd/nonexistent: No such file or directory$`),
+	}, strictLineCheck(true))
+
+	err := ensureS3Object(s3client, bucket, "a.txt", "content")
+	assertError(t, err, errS3NoSuchKey)
+}
+
+func TestCopyTransformExecValidation(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "transform concurrency without transform exec",
+			args:     []string{"cp", "--transform-concurrency", "2", "file", "s3://bucket/"},
+			expected: `ERROR "cp --transform-concurrency=2 file s3://bucket/": "transform-concurrency" flag requires "transform-exec" flag`,
+		},
+		{
+			name:     "local destination",
+			args:     []string{"cp", "--transform-exec", "gzip -c", "s3://bucket/file", "dir/"},
+			expected: `ERROR "cp --transform-exec=gzip -c s3://bucket/file dir/": "transform-exec" flag can only be used with remote destinations`,
+		},
+		{
+			name:     "expected size",
+			args:     []string{"cp", "--expected-size", "10", "--transform-exec", "gzip -c", "file", "s3://bucket/file"},
+			expected: `ERROR "cp --expected-size=10 --transform-exec=gzip -c file s3://bucket/file": it is not allowed to combine "transform-exec" and "expected-size" flags`,
+		},
+		{
+			name:     "checksum sync strategy",
+			args:     []string{"sync", "--sync-strategy", "checksum", "--transform-exec", "gzip -c", "dir/", "s3://bucket/"},
+			expected: `ERROR "sync --sync-strategy=checksum --transform-exec=gzip -c dir/ s3://bucket/": "transform-exec" flag requires "size-mtime" or "always" sync strategy`,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, s5cmd := setup(t)
+
+			result := icmd.RunCmd(s5cmd(tc.args...))
+			result.Assert(t, icmd.Expected{ExitCode: 1})
+
+			assertLines(t, result.Stderr(), map[int]compareFunc{
+				0: equals(tc.expected),
+			})
+		})
+	}
+}
+
+func TestCopyPreserveWindowsAttrsUnsupported(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("windows file attributes are supported on Windows")
+	}
+
+	testcases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "cp",
+			args:     []string{"cp", "--preserve-windows-attrs", "file", "s3://bucket/"},
+			expected: `ERROR "cp --preserve-windows-attrs=true file s3://bucket/": "preserve-windows-attrs" flag: windows file attributes are not supported on this platform`,
+		},
+		{
+			name:     "sync",
+			args:     []string{"sync", "--preserve-windows-attrs", "s3://bucket/*", "dir/"},
+			expected: `ERROR "sync --preserve-windows-attrs=true s3://bucket/* dir/": "preserve-windows-attrs" flag: windows file attributes are not supported on this platform`,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, s5cmd := setup(t)
+
+			result := icmd.RunCmd(s5cmd(tc.args...))
+			result.Assert(t, icmd.Expected{ExitCode: 1})
+
+			assertLines(t, result.Stderr(), map[int]compareFunc{
+				0: equals(tc.expected),
+			})
+		})
+	}
+}
+
+// cp --recursive s3://bucket/prefix dir/
+func TestCopyS3PrefixToLocalRecursive(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	const content = "content"
+	putFile(t, s3client, bucket, "prefix/a.txt", content)
+	putFile(t, s3client, bucket, "prefix/nested/b.txt", content)
+	putFile(t, s3client, bucket, "other.txt", content)
+
+	cmd := s5cmd("cp", "--recursive", "s3://"+bucket+"/prefix", "dir/")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`WARNING "recursive" flag is mapped to the wildcard source "s3://%v/prefix/*", which matches all objects under "s3://%v/prefix" without the flag`, bucket, bucket),
+	})
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp s3://%v/prefix/a.txt dir/a.txt`, bucket),
+		1: equals(`cp s3://%v/prefix/nested/b.txt dir/nested/b.txt`, bucket),
+	}, sortInput(true))
+
+	expected := fs.Expected(t,
+		fs.WithDir("dir",
+			fs.WithFile("a.txt", content),
+			fs.WithDir("nested", fs.WithFile("b.txt", content)),
+		),
+	)
+	assert.Assert(t, fs.Equal(cmd.Dir, expected))
+}
+
+// cp --awscli-compat --exclude "*" --include "*.txt" s3://bucket/* .
+// cp --awscli-compat --include "*.txt" --exclude "*" s3://bucket/* .
+func TestCopyS3ObjectsWithAWSCLICompatFilters(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		filters  []string
+		expected []string
+		files    []fs.PathOp
+	}{
+		{
+			name:     "include after exclude",
+			filters:  []string{"--exclude", "*", "--include", "*.txt"},
+			expected: []string{"a.txt", "dir/c.txt"},
+			files: []fs.PathOp{
+				fs.WithFile("a.txt", "content"),
+				fs.WithDir("dir", fs.WithFile("c.txt", "content")),
+			},
+		},
+		{
+			name:    "exclude after include",
+			filters: []string{"--include", "*.txt", "--exclude", "*"},
+		},
+		{
+			name:     "exclude after include of a subset",
+			filters:  []string{"--exclude", "*", "--include", "*.txt", "--exclude", "dir/*"},
+			expected: []string{"a.txt"},
+			files:    []fs.PathOp{fs.WithFile("a.txt", "content")},
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			s3client, s5cmd := setup(t)
+
+			bucket := s3BucketFromTestName(t)
+			createBucket(t, s3client, bucket)
+
+			const content = "content"
+			for _, key := range []string{"a.txt", "b.log", "dir/c.txt", "dir/d.csv"} {
+				putFile(t, s3client, bucket, key, content)
+			}
+
+			args := append([]string{"cp", "--awscli-compat"}, tc.filters...)
+			cmd := s5cmd(append(args, "s3://"+bucket+"/*", ".")...)
+			result := icmd.RunCmd(cmd)
+
+			result.Assert(t, icmd.Success)
+
+			assertLines(t, result.Stderr(), map[int]compareFunc{
+				0: equals(`WARNING "awscli-compat" flag evaluates the patterns in the order they are given, the last matching pattern wins; without the flag, the objects matching any "exclude" pattern or no "include" pattern are excluded`),
+			})
+
+			expected := map[int]compareFunc{}
+			for i, key := range tc.expected {
+				expected[i] = equals(`cp s3://%v/%v %v`, bucket, key, key)
+			}
+			assertLines(t, result.Stdout(), expected, sortInput(true))
+
+			assert.Assert(t, fs.Equal(cmd.Dir, fs.Expected(t, tc.files...)))
+		})
+	}
+}
+
+// cp --include "*.txt" --exclude "dir/*" s3://bucket/* .
+func TestCopyS3ObjectsWithIncludeFilters(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	const content = "content"
+	for _, key := range []string{"a.txt", "b.log", "dir/c.txt", "dir/d.csv", "e.csv"} {
+		putFile(t, s3client, bucket, key, content)
+	}
+
+	cmd := s5cmd("cp", "--include", "*.txt", "--include", "*.log", "--exclude", "dir/*", "s3://"+bucket+"/*", ".")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp s3://%v/a.txt a.txt`, bucket),
+		1: equals(`cp s3://%v/b.log b.log`, bucket),
+	}, sortInput(true))
+
+	expected := fs.Expected(t,
+		fs.WithFile("a.txt", content),
+		fs.WithFile("b.log", content),
+	)
+	assert.Assert(t, fs.Equal(cmd.Dir, expected))
+}
+
+func TestCopyAWSCLIFlagsValidation(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "recursive with wildcard",
+			args:     []string{"cp", "--recursive", "s3://bucket/*", "dir/"},
+			expected: `ERROR "cp --recursive=true s3://bucket/* dir/": "recursive" flag cannot be used with wildcards`,
+		},
+		{
+			name:     "recursive with raw",
+			args:     []string{"mv", "--recursive", "--raw", "s3://bucket/prefix", "dir/"},
+			expected: `ERROR "mv --recursive=true --raw=true s3://bucket/prefix dir/": it is not allowed to combine "recursive" and "raw" flags`,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, s5cmd := setup(t)
+
+			result := icmd.RunCmd(s5cmd(tc.args...))
+			result.Assert(t, icmd.Expected{ExitCode: 1})
+
+			assertLines(t, result.Stderr(), map[int]compareFunc{
+				0: equals(tc.expected),
+			})
+		})
+	}
+}
+
+// cp --auto-sse file s3://bucket/
+func TestCopySingleFileToS3WithAutoSSE(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	const content = "content"
+	workdir := fs.NewDir(t, bucket, fs.WithFile("file.txt", content))
+	defer workdir.Remove()
+
+	srcpath := filepath.ToSlash(workdir.Join("file.txt"))
+	dstpath := fmt.Sprintf("s3://%v/", bucket)
+
+	// the test bucket has no default encryption, the object is uploaded
+	// without explicit encryption.
+	cmd := s5cmd("cp", "--auto-sse", srcpath, dstpath)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %v %vfile.txt`, srcpath, dstpath),
+	}, strictLineCheck(true))
+	assertLines(t, result.Stderr(), map[int]compareFunc{})
+
+	assert.Assert(t, ensureS3Object(s3client, bucket, "file.txt", content))
+}
+
+// cp --print-urls https --print-urls-file urls.txt dir/* s3://bucket/prefix/
+func TestCopyDirToS3WithPrintURLs(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	workdir := fs.NewDir(t, bucket,
+		fs.WithFile("a.txt", "content of a"),
+		fs.WithDir("dir", fs.WithFile("b c.txt", "content of b")),
+	)
+	defer workdir.Remove()
+
+	outdir := fs.NewDir(t, "out")
+	defer outdir.Remove()
+
+	srcpath := filepath.ToSlash(workdir.Path())
+	urlspath := outdir.Join("urls.txt")
+
+	cmd := s5cmd("cp", "--print-urls", "https", "--print-urls-file", urlspath, srcpath+"/*", fmt.Sprintf("s3://%v/prefix/", bucket))
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	// the test server is a custom endpoint, so the objects are addressed in
+	// path style.
+	endpoint := s3client.Endpoint
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %v/a.txt s3://%v/prefix/a.txt`, srcpath, bucket),
+		1: equals(`cp %v/dir/b c.txt s3://%v/prefix/dir/b c.txt`, srcpath, bucket),
+		2: equals(`%v/%v/prefix/a.txt`, endpoint, bucket),
+		3: equals(`%v/%v/prefix/dir/b%%20c.txt`, endpoint, bucket),
+	}, sortInput(true), strictLineCheck(true))
+
+	urls, err := os.ReadFile(urlspath)
+	assert.NilError(t, err)
+	assertLines(t, string(urls), map[int]compareFunc{
+		0: equals(`%v/%v/prefix/a.txt`, endpoint, bucket),
+		1: equals(`%v/%v/prefix/dir/b%%20c.txt`, endpoint, bucket),
+	}, sortInput(true), strictLineCheck(true))
+
+	assert.Assert(t, ensureS3Object(s3client, bucket, "prefix/a.txt", "content of a"))
+	assert.Assert(t, ensureS3Object(s3client, bucket, "prefix/dir/b c.txt", "content of b"))
+}
+
+// --json cp --print-urls s3 file s3://bucket/
+func TestCopySingleFileToS3WithPrintURLsJSON(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	const (
+		filename = "testfile1.txt"
+		content  = "this is a test file"
+	)
+
+	workdir := fs.NewDir(t, bucket, fs.WithFile(filename, content))
+	defer workdir.Remove()
+
+	fpath := workdir.Join(filename)
+
+	cmd := s5cmd("--json", "cp", "--print-urls", "s3", fpath, "s3://"+bucket+"/")
+	result := icmd.RunCmd(cmd)
+
+	jsonText := `
+		{
+			"v": 2,
+			"operation": "cp",
+			"success": true,
+			"source": "%v",
+			"destination": "s3://%v/testfile1.txt",
+			"object": {
+				"type": "file",
+				"size":19
+			},
+			"url": "s3://%v/testfile1.txt"
+		}
+	`
+
+	result.Assert(t, icmd.Success)
+	fpath = filepath.ToSlash(fpath)
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: json(jsonText, fpath, bucket, bucket),
+	}, jsonCheck(true), strictLineCheck(true))
+
+	assert.Assert(t, ensureS3Object(s3client, bucket, filename, content))
+}
+
+func TestCopyPrintURLsValidation(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "file without print urls",
+			args:     []string{"cp", "--print-urls-file", "urls.txt", "file", "s3://bucket/"},
+			expected: `ERROR "cp --print-urls-file=urls.txt file s3://bucket/": "print-urls-file" flag requires "print-urls" flag`,
+		},
+		{
+			name:     "local destination",
+			args:     []string{"cp", "--print-urls", "s3", "s3://bucket/file", "dir/"},
+			expected: `ERROR "cp --print-urls=s3 s3://bucket/file dir/": "print-urls" flag can only be used with remote destinations`,
+		},
+		{
+			name:     "console with custom endpoint",
+			args:     []string{"cp", "--print-urls", "console", "file", "s3://bucket/"},
+			expected: `ERROR "cp --print-urls=console file s3://bucket/": "print-urls" flag can not be "console" with a custom endpoint`,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, s5cmd := setup(t)
+
+			result := icmd.RunCmd(s5cmd(tc.args...))
+			result.Assert(t, icmd.Expected{ExitCode: 1})
+
+			assertLines(t, result.Stderr(), map[int]compareFunc{
+				0: equals(tc.expected),
+			})
+		})
+	}
+}
+
+// cp --preserve-mtime file s3://bucket/
+func TestCopySingleFileToS3WithPreserveMtime(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	modTime := time.Date(2020, 1, 2, 3, 4, 5, 123456789, time.UTC)
+	workdir := fs.NewDir(t, bucket, fs.WithFile("file.txt", "content", fs.WithTimestamps(modTime, modTime)))
+	defer workdir.Remove()
+
+	srcpath := filepath.ToSlash(workdir.Join("file.txt"))
+
+	cmd := s5cmd("cp", "--preserve-mtime", srcpath, fmt.Sprintf("s3://%v/", bucket))
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %v s3://%v/file.txt`, srcpath, bucket),
+	}, strictLineCheck(true))
+
+	assert.Assert(t, ensureS3Object(s3client, bucket, "file.txt", "content",
+		ensureMetadata("S5cmd-Mtime", "2020-01-02T03:04:05.123456789Z")))
+}
+
+// cp --preserve-mtime s3://bucket/* dir/
+func TestCopyS3ObjectsToLocalWithPreserveMtime(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	timeSource := newFixedTimeSource(now)
+	s3client, s5cmd := setup(t, withTimeSource(timeSource))
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	// the object uploaded with the modification time of its file is
+	// downloaded with it, the other one with its last modification time.
+	modTime := now.Add(-24 * time.Hour)
+	workdir := fs.NewDir(t, bucket, fs.WithFile("uploaded.txt", "content", fs.WithTimestamps(modTime, modTime)))
+	defer workdir.Remove()
+
+	cmd := s5cmd("cp", "--preserve-mtime", workdir.Join("uploaded.txt"), fmt.Sprintf("s3://%v/", bucket))
+	icmd.RunCmd(cmd).Assert(t, icmd.Success)
+
+	putFile(t, s3client, bucket, "put.txt", "content")
+
+	outdir := fs.NewDir(t, "out")
+	defer outdir.Remove()
+
+	cmd = s5cmd("cp", "--preserve-mtime", fmt.Sprintf("s3://%v/*", bucket), outdir.Path()+"/")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	expected := fs.Expected(t,
+		fs.WithFile("uploaded.txt", "content"),
+		fs.WithFile("put.txt", "content"),
+	)
+	assert.Assert(t, fs.Equal(outdir.Path(), expected))
+
+	for name, expected := range map[string]time.Time{"uploaded.txt": modTime, "put.txt": now} {
+		st, err := os.Stat(outdir.Join(name))
+		assert.NilError(t, err)
+		assert.Assert(t, st.ModTime().Equal(expected), "%v: expected %v, got %v", name, expected, st.ModTime())
+	}
+}
+
+// cp --skip-vanished dir/missing.txt s3://bucket/
+func TestCopyVanishedFileToS3WithSkipVanished(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	workdir := fs.NewDir(t, t.Name())
+	defer workdir.Remove()
+
+	srcpath := filepath.ToSlash(workdir.Join("missing.txt"))
+	dstpath := fmt.Sprintf("s3://%v/", bucket)
+
+	cmd := s5cmd("cp", srcpath, dstpath)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	cmd = s5cmd("cp", "--skip-vanished", srcpath, dstpath)
+	result = icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`vanished %v %vmissing.txt: file doesn't exist anymore`, srcpath, dstpath),
+	})
+
+	err := ensureS3Object(s3client, bucket, "missing.txt", "")
+	assertError(t, err, errS3NoSuchKey)
+}
@@ -49,6 +49,7 @@ func TestDiskUsageSingleS3ObjectJSON(t *testing.T) {
 	assertLines(t, result.Stdout(), map[int]compareFunc{
 		0: json(`
 			{
+				"v": 2,
 				"source": "s3://%v/testfile1.txt",
 				"count":1,
 				"size":22
@@ -300,3 +301,282 @@ func TestDiskUsageByVersionIDAndAllVersions(t *testing.T) {
 		})
 	}
 }
+
+func TestDiskUsageStorageClassReport(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	putFile(t, s3client, bucket, "file1.txt", "content")
+	putFile(t, s3client, bucket, "file2.txt", "more content")
+	putFile(t, s3client, bucket, "file3.txt", "this is a large file content")
+
+	cmd := s5cmd("du", "--storage-class-report", "s3://"+bucket+"/*")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals("STORAGE CLASS OBJECTS OBJECTS%% SIZE SIZE%%"),
+		1: equals("STANDARD 3 100.00%% 47 100.00%%"),
+		2: equals("TOTAL 3 100.00%% 47 100.00%%"),
+	}, strictLineCheck(true))
+}
+
+// du --storage-class-filter !STANDARD s3://bucket/*
+func TestDiskUsageWithStorageClassFilter(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	putFile(t, s3client, bucket, "file1.txt", "content")
+	putFile(t, s3client, bucket, "file2.txt", "more content")
+
+	cmd := s5cmd("du", "--storage-class-filter", "STANDARD", "s3://"+bucket+"/*")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals("19 bytes in 2 objects: s3://%v/*", bucket),
+	}, strictLineCheck(true))
+
+	cmd = s5cmd("du", "--storage-class-filter", "!STANDARD", "s3://"+bucket+"/*")
+	result = icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals("0 bytes in 0 objects: s3://%v/*", bucket),
+	}, strictLineCheck(true))
+}
+
+func TestDiskUsageStorageClassReportJSON(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	putFile(t, s3client, bucket, "file1.txt", "content")
+	putFile(t, s3client, bucket, "file2.txt", "more content")
+
+	cmd := s5cmd("--json", "du", "--storage-class-report", "s3://"+bucket+"/*")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: json(`
+			{
+				"v":2,
+				"source":"s3://%v/*",
+				"count":2,
+				"size":19,
+				"storage_classes":[
+					{
+						"storage_class":"STANDARD",
+						"count":2,
+						"size":19,
+						"count_percentage":100,
+						"size_percentage":100
+					}
+				]
+			}
+		`, bucket),
+	})
+}
+
+func TestDiskUsageObjectSizeHistogram(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	putFile(t, s3client, bucket, "small.txt", "content")
+	putFile(t, s3client, bucket, "medium.txt", strings.Repeat("x", 2048))
+	putFile(t, s3client, bucket, "large.txt", strings.Repeat("x", 5000))
+
+	cmd := s5cmd("du", "--object-size-histogram", "--buckets", "1KB,4KB", "s3://"+bucket+"/*")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals("SIZE RANGE OBJECTS OBJECTS%% SIZE SIZE%%"),
+		1: equals("<1KB 1 33.33%% 7 0.10%%"),
+		2: equals("1KB-4KB 1 33.33%% 2048 29.03%%"),
+		3: equals(">=4KB 1 33.33%% 5000 70.87%%"),
+		4: equals("TOTAL 3 100.00%% 7055 100.00%%"),
+	}, strictLineCheck(true))
+}
+
+func TestDiskUsageObjectSizeHistogramJSON(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	putFile(t, s3client, bucket, "file1.txt", "content")
+	putFile(t, s3client, bucket, "file2.txt", strings.Repeat("x", 2048))
+
+	cmd := s5cmd("--json", "du", "--object-size-histogram", "--buckets", "1KB", "s3://"+bucket+"/*")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: json(`
+			{
+				"v":2,
+				"source":"s3://%v/*",
+				"count":2,
+				"size":2055,
+				"ranges":[
+					{
+						"range":"\u003c1KB",
+						"min":0,
+						"max":1024,
+						"count":1,
+						"size":7,
+						"count_percentage":50,
+						"size_percentage":0.34
+					},
+					{
+						"range":"\u003e=1KB",
+						"min":1024,
+						"count":1,
+						"size":2048,
+						"count_percentage":50,
+						"size_percentage":99.66
+					}
+				]
+			}
+		`, bucket),
+	})
+}
+
+func TestDiskUsageObjectSizeHistogramInvalidBuckets(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd := setup(t)
+
+	cmd := s5cmd("du", "--object-size-histogram", "--buckets", "1MB,1KB", "s3://bucket/*")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "du --object-size-histogram=true --buckets=1MB,1KB s3://bucket/*": invalid buckets: histogram buckets must be in increasing order: "1MB,1KB"`),
+	})
+}
+
+func TestDiskUsageIncludeMultipartUploads(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	putFile(t, s3client, bucket, "dir/file1.txt", "content")
+	putFile(t, s3client, bucket, "dir/file2.txt", "more content")
+	createMultipartUpload(t, s3client, bucket, "dir/upload1.bin", "part one", "part two")
+	createMultipartUpload(t, s3client, bucket, "dir/upload2.bin", "part")
+	// the uploads whose keys don't match are not included.
+	createMultipartUpload(t, s3client, bucket, "upload3.bin", "unmatched part")
+	createMultipartUpload(t, s3client, bucket, "dir/upload4.py", "excluded part")
+
+	cmd := s5cmd("du", "--exclude", "*.py", "--include-mpu", "s3://"+bucket+"/dir/*")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals("20 bytes in 3 parts of 2 incomplete multipart uploads: s3://%v/dir/*", bucket),
+		1: equals("39 bytes in 2 objects and 2 incomplete multipart uploads: s3://%v/dir/*", bucket),
+	}, strictLineCheck(true))
+}
+
+func TestDiskUsageIncludeMultipartUploadsJSON(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	putFile(t, s3client, bucket, "file1.txt", "content")
+	createMultipartUpload(t, s3client, bucket, "upload.bin", "part one")
+
+	cmd := s5cmd("--json", "du", "--include-mpu", "s3://"+bucket+"/*")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: json(`
+			{
+				"v":2,
+				"source":"s3://%v/*",
+				"multipart_uploads":1,
+				"parts":1,
+				"size":8
+			}
+		`, bucket),
+		1: json(`
+			{
+				"v":2,
+				"source":"s3://%v/*",
+				"count":1,
+				"size":15,
+				"multipart_uploads":1
+			}
+		`, bucket),
+	})
+}
+
+func TestDiskUsageIncludeMultipartUploadsValidation(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "storage class report",
+			args:     []string{"du", "--include-mpu", "--storage-class-report", "s3://bucket/*"},
+			expected: `ERROR "du --storage-class-report=true --include-mpu=true s3://bucket/*": it is not allowed to combine "storage-class-report" and "include-mpu" flags`,
+		},
+		{
+			name:     "object size histogram",
+			args:     []string{"du", "--include-mpu", "--object-size-histogram", "s3://bucket/*"},
+			expected: `ERROR "du --object-size-histogram=true --include-mpu=true s3://bucket/*": it is not allowed to combine "object-size-histogram" and "include-mpu" flags`,
+		},
+		{
+			name:     "local source",
+			args:     []string{"du", "--include-mpu", "dir/"},
+			expected: `ERROR "du --include-mpu=true dir/": "include-mpu" flag can only be used with remote sources`,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, s5cmd := setup(t)
+
+			result := icmd.RunCmd(s5cmd(tc.args...))
+
+			result.Assert(t, icmd.Expected{ExitCode: 1})
+
+			assertLines(t, result.Stderr(), map[int]compareFunc{
+				0: equals(tc.expected),
+			})
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package e2e
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/icmd"
+
+	"github.com/peak/s5cmd/v2/command"
+)
+
+// TestExamples runs the examples printed by "s5cmd examples" on their
+// fixtures and checks their effects.
+func TestExamples(t *testing.T) {
+	t.Parallel()
+
+	for _, name := range command.ExampleCommands() {
+		for _, example := range command.Examples(name) {
+			example := example
+			t.Run(name+"/"+example.Description, func(t *testing.T) {
+				t.Parallel()
+
+				s3client, s5cmd := setup(t)
+
+				cmd := s5cmd(example.Args...)
+				for _, bucket := range example.Fixture.Buckets {
+					createBucket(t, s3client, bucket)
+				}
+				for objurl, content := range example.Fixture.Objects {
+					if bucket, key, ok := splitRemoteURL(objurl); ok {
+						putFile(t, s3client, bucket, key, content)
+						continue
+					}
+					path := filepath.Join(cmd.Dir, filepath.FromSlash(objurl))
+					assert.NilError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+					assert.NilError(t, os.WriteFile(path, []byte(content), 0o644))
+				}
+
+				result := icmd.RunCmd(cmd)
+				result.Assert(t, icmd.Success)
+
+				for objurl, content := range example.Effect.Objects {
+					if bucket, key, ok := splitRemoteURL(objurl); ok {
+						assert.Assert(t, ensureS3Object(s3client, bucket, key, content))
+						continue
+					}
+					got, err := os.ReadFile(filepath.Join(cmd.Dir, filepath.FromSlash(objurl)))
+					assert.NilError(t, err)
+					assert.Equal(t, content, string(got), objurl)
+				}
+
+				for _, objurl := range example.Effect.Missing {
+					if bucket, key, ok := splitRemoteURL(objurl); ok {
+						err := ensureS3Object(s3client, bucket, key, "")
+						assertError(t, err, errS3NoSuchKey)
+						continue
+					}
+					_, err := os.Stat(filepath.Join(cmd.Dir, filepath.FromSlash(objurl)))
+					assert.Assert(t, errors.Is(err, os.ErrNotExist), objurl)
+				}
+
+				lines := strings.Split(result.Stdout(), "\n")
+				for _, line := range example.Effect.Output {
+					assert.Assert(t, indexSlice(lines, line, strings.HasSuffix) >= 0,
+						"expected line %q in output:\n%v", line, result.Stdout())
+				}
+			})
+		}
+	}
+}
+
+// splitRemoteURL returns the bucket and the key of the remote object URL.
+func splitRemoteURL(objurl string) (bucket, key string, ok bool) {
+	if !strings.HasPrefix(objurl, "s3://") {
+		return "", "", false
+	}
+	bucket, key, _ = strings.Cut(strings.TrimPrefix(objurl, "s3://"), "/")
+	return bucket, key, true
+}
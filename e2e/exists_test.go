@@ -0,0 +1,131 @@
+package e2e
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"gotest.tools/v3/fs"
+	"gotest.tools/v3/icmd"
+)
+
+func TestExists(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	putFile(t, s3client, bucket, "prefix/file.txt", "content")
+	putFile(t, s3client, bucket, "logs/2024-01-01.gz", "content")
+
+	workdir := fs.NewDir(t, bucket, fs.WithDir("dir", fs.WithFile("file.txt", "content")))
+	defer workdir.Remove()
+
+	testcases := []struct {
+		name     string
+		args     []string
+		exitCode int
+	}{
+		{name: "bucket", args: []string{"s3://" + bucket}, exitCode: 0},
+		{name: "nonexistent bucket", args: []string{"s3://" + bucket + "-nonexistent"}, exitCode: 1},
+		{name: "object", args: []string{fmt.Sprintf("s3://%v/prefix/file.txt", bucket)}, exitCode: 0},
+		{name: "nonexistent object", args: []string{fmt.Sprintf("s3://%v/prefix/file", bucket)}, exitCode: 1},
+		{name: "object in nonexistent bucket", args: []string{fmt.Sprintf("s3://%v-nonexistent/file.txt", bucket)}, exitCode: 1},
+		{name: "prefix", args: []string{fmt.Sprintf("s3://%v/prefix/", bucket)}, exitCode: 0},
+		{name: "nonexistent prefix", args: []string{fmt.Sprintf("s3://%v/other/", bucket)}, exitCode: 1},
+		{name: "key prefix", args: []string{"--prefix", fmt.Sprintf("s3://%v/logs/2024-", bucket)}, exitCode: 0},
+		{name: "nonexistent key prefix", args: []string{"--prefix", fmt.Sprintf("s3://%v/logs/2025-", bucket)}, exitCode: 1},
+		{name: "nonempty bucket", args: []string{"--prefix", "s3://" + bucket}, exitCode: 0},
+		{name: "wildcard", args: []string{fmt.Sprintf("s3://%v/*/*.gz", bucket)}, exitCode: 0},
+		{name: "wildcard without match", args: []string{fmt.Sprintf("s3://%v/*.zip", bucket)}, exitCode: 1},
+		{name: "local file", args: []string{filepath.Join(workdir.Path(), "dir", "file.txt")}, exitCode: 0},
+		{name: "local directory", args: []string{filepath.Join(workdir.Path(), "dir")}, exitCode: 0},
+		{name: "nonexistent local file", args: []string{filepath.Join(workdir.Path(), "file.txt")}, exitCode: 1},
+		{name: "local wildcard", args: []string{filepath.Join(workdir.Path(), "*", "*.txt")}, exitCode: 0},
+		{name: "local wildcard without match", args: []string{filepath.Join(workdir.Path(), "*.txt")}, exitCode: 1},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			cmd := s5cmd(append([]string{"exists"}, tc.args...)...)
+			result := icmd.RunCmd(cmd)
+
+			result.Assert(t, icmd.Expected{ExitCode: tc.exitCode})
+			assertLines(t, result.Stdout(), map[int]compareFunc{})
+			assertLines(t, result.Stderr(), map[int]compareFunc{})
+		})
+	}
+}
+
+func TestExistsValidation(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd := setup(t)
+
+	testcases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "no argument",
+			args:     []string{"exists"},
+			expected: `ERROR "exists": expected only 1 argument`,
+		},
+		{
+			name:     "prefix with local path",
+			args:     []string{"exists", "--prefix", "dir/file"},
+			expected: `ERROR "exists --prefix=true dir/file": "prefix" flag can only be used with remote paths`,
+		},
+		{
+			name:     "prefix with wildcard",
+			args:     []string{"exists", "--prefix", "s3://bucket/*.gz"},
+			expected: `ERROR "exists --prefix=true s3://bucket/*.gz": "prefix" flag cannot be used with wildcards`,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			cmd := s5cmd(tc.args...)
+			result := icmd.RunCmd(cmd)
+
+			result.Assert(t, icmd.Expected{ExitCode: 2})
+			assertLines(t, result.Stderr(), map[int]compareFunc{
+				0: equals(tc.expected),
+			})
+		})
+	}
+}
+
+func TestExistsWithUsageError(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd := setup(t)
+
+	cmd := s5cmd("exists", "--recursive", "s3://bucket/file")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 2})
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals("Incorrect Usage: flag provided but not defined: -recursive"),
+	}, strictLineCheck(false))
+}
+
+func TestExistsWithError(t *testing.T) {
+	t.Parallel()
+
+	// nothing listens on the endpoint, so the existence of the object is not
+	// known.
+	_, s5cmd := setup(t, withEndpointURL("http://127.0.0.1:1"))
+
+	cmd := s5cmd("--retry-count", "0", "exists", "s3://bucket/file")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 2})
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		2: prefix(`ERROR "exists s3://bucket/file":`),
+	}, strictLineCheck(false))
+}
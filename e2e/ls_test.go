@@ -5,6 +5,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"gotest.tools/v3/fs"
 	"gotest.tools/v3/icmd"
@@ -116,7 +117,7 @@ func TestListSingleS3ObjectJSON(t *testing.T) {
 	result.Assert(t, icmd.Success)
 
 	assertLines(t, result.Stdout(), map[int]compareFunc{
-		0: prefix(`{"key":"s3://%v/testfile1.txt",`, bucket),
+		0: prefix(`{"v":2,"key":"s3://%v/testfile1.txt",`, bucket),
 	}, jsonCheck(true))
 }
 
@@ -786,3 +787,224 @@ func TestListNestedLocalFolders(t *testing.T) {
 		2: match(filepath.ToSlash("file.txt")),
 	}, trimMatch(dateRe), alignment(true))
 }
+
+// ls --fetch-owner dir/
+func TestListLocalFolderWithFetchOwner(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd := setup(t)
+
+	workdir := fs.NewDir(t, t.Name(), fs.WithFile("file.txt", "content"))
+	defer workdir.Remove()
+	srcpath := filepath.ToSlash(workdir.Path())
+
+	cmd := s5cmd("ls", "--fetch-owner", srcpath)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "ls --fetch-owner=true %v": "fetch-owner" and "owner" flags can only be used with remote objects`, srcpath),
+	})
+}
+
+// ls --fetch-owner s3://bucket/*
+func TestListS3ObjectsWithFetchOwner(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+	putFile(t, s3client, bucket, "testfile.txt", "content")
+
+	cmd := s5cmd("--json", "ls", "--fetch-owner", "s3://"+bucket+"/*")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: contains(`"key":"s3://%v/testfile.txt"`, bucket),
+	})
+}
+
+// ls --newer-than-object s3://bucket/marker s3://bucket/*
+func TestListS3ObjectsNewerThanObject(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	timeSource := newFixedTimeSource(now)
+	s3client, s5cmd := setup(t, withTimeSource(timeSource))
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	timeSource.Advance(-3 * time.Minute)
+	putFile(t, s3client, bucket, "old.txt", "content")
+
+	timeSource.Advance(time.Minute)
+	putFile(t, s3client, bucket, "markers/last-run", "marker")
+
+	timeSource.Advance(time.Minute)
+	putFile(t, s3client, bucket, "new.txt", "content")
+	putFile(t, s3client, bucket, "dir/new.txt", "content")
+
+	cmd := s5cmd("ls", "--show-fullpath", "--newer-than-object", "s3://"+bucket+"/markers/last-run", "s3://"+bucket+"/*")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals("s3://%v/dir/new.txt", bucket),
+		1: equals("s3://%v/new.txt", bucket),
+	}, strictLineCheck(true))
+}
+
+// ls --newer-than-object s3://bucket/nonexistent s3://bucket/*
+func TestListS3ObjectsNewerThanNonexistentObject(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+	putFile(t, s3client, bucket, "file.txt", "content")
+
+	reference := "s3://" + bucket + "/nonexistent"
+	cmd := s5cmd("ls", "--newer-than-object", reference, "s3://"+bucket+"/*")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "ls --newer-than-object=%v s3://%v/*": reference object %q does not exist`, reference, bucket, reference),
+	}, strictLineCheck(true))
+}
+
+// ls --newer-than-object s3://bucket/prefix/ s3://bucket/*
+func TestListS3ObjectsNewerThanPrefix(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+
+	reference := "s3://" + bucket + "/prefix/"
+	cmd := s5cmd("ls", "--newer-than-object", reference, "s3://"+bucket+"/*")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "ls --newer-than-object=%v s3://%v/*": reference of "newer-than-object" flag must be a remote object, e.g. s3://bucket/marker`, reference, bucket),
+	}, strictLineCheck(true))
+}
+
+// ls --storage-class-filter STANDARD s3://bucket/*
+func TestListS3ObjectsWithStorageClassFilter(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		filter   string
+		expected []string
+	}{
+		{
+			name:     "allowed class",
+			filter:   "STANDARD",
+			expected: []string{"a.txt", "dir/b.txt"},
+		},
+		{
+			name:   "negated class",
+			filter: "!STANDARD",
+		},
+		{
+			name:     "unknown class",
+			filter:   "!COLDLINE",
+			expected: []string{"a.txt", "dir/b.txt"},
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			s3client, s5cmd := setup(t)
+
+			bucket := s3BucketFromTestName(t)
+			createBucket(t, s3client, bucket)
+			putFile(t, s3client, bucket, "a.txt", "content")
+			putFile(t, s3client, bucket, "dir/b.txt", "content")
+
+			cmd := s5cmd("ls", "--show-fullpath", "--storage-class-filter", tc.filter, "s3://"+bucket+"/*")
+			result := icmd.RunCmd(cmd)
+
+			result.Assert(t, icmd.Success)
+
+			expected := map[int]compareFunc{}
+			for i, key := range tc.expected {
+				expected[i] = equals("s3://%v/%v", bucket, key)
+			}
+			assertLines(t, result.Stdout(), expected, strictLineCheck(true))
+		})
+	}
+}
+
+func TestListStorageClassFilterValidation(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "buckets",
+			args:     []string{"ls", "--storage-class-filter", "GLACIER"},
+			expected: `ERROR "ls --storage-class-filter=GLACIER": "storage-class-filter" flag can not be used to list buckets`,
+		},
+		{
+			name:     "local files",
+			args:     []string{"ls", "--storage-class-filter", "GLACIER", "dir/"},
+			expected: `ERROR "ls --storage-class-filter=GLACIER dir/": "storage-class-filter" flag can only be used with remote objects`,
+		},
+		{
+			name:     "allowed and negated classes",
+			args:     []string{"ls", "--storage-class-filter", "GLACIER,!STANDARD", "s3://bucket/*"},
+			expected: `ERROR "ls --storage-class-filter=GLACIER,!STANDARD s3://bucket/*": invalid "storage-class-filter": the allowed and the negated storage classes can not be combined in "GLACIER,!STANDARD"`,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, s5cmd := setup(t)
+
+			result := icmd.RunCmd(s5cmd(tc.args...))
+
+			result.Assert(t, icmd.Expected{ExitCode: 1})
+
+			assertLines(t, result.Stderr(), map[int]compareFunc{
+				0: equals(tc.expected),
+			})
+		})
+	}
+}
+
+func TestListNullWithoutShowFullpath(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd := setup(t)
+
+	cmd := s5cmd("ls", "-0", "s3://bucket/*")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "ls --null=true s3://bucket/*": "null" flag requires "show-fullpath" flag`),
+	})
+}
@@ -55,6 +55,7 @@ func TestMakeBucket_success_json(t *testing.T) {
 
 	jsonText := `
 		{
+			"v": 2,
 			"operation": "mb",
 			"success": true,
 			"source": "%v"
@@ -111,6 +112,6 @@ func TestMakeBucket_failure_json(t *testing.T) {
 	result.Assert(t, icmd.Expected{ExitCode: 1})
 
 	assertLines(t, result.Stderr(), map[int]compareFunc{
-		0: equals(`{"operation":"mb","command":"mb %v","error":"invalid s3 bucket"}`, src),
+		0: equals(`{"v":2,"operation":"mb","command":"mb %v","error":"invalid s3 bucket"}`, src),
 	}, jsonCheck(true))
 }
@@ -0,0 +1,232 @@
+package e2e
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+	"gotest.tools/v3/icmd"
+)
+
+// merge s3://bucket/parts/chunk-* s3://bucket/merged.txt
+func TestMerge(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	putFile(t, s3client, bucket, "parts/chunk-3", "third\n")
+	putFile(t, s3client, bucket, "parts/chunk-1", "first\n")
+	putFile(t, s3client, bucket, "parts/chunk-2", "second\n")
+
+	cmd := s5cmd("merge", "s3://"+bucket+"/parts/chunk-*", "s3://"+bucket+"/merged.txt")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`merge s3://%v/merged.txt (3 objects, 1 parts, 19 bytes)`, bucket),
+	}, strictLineCheck(true))
+
+	assert.Assert(t, ensureS3Object(s3client, bucket, "merged.txt", "first\nsecond\nthird\n"))
+	// the sources are kept.
+	assert.Assert(t, ensureS3Object(s3client, bucket, "parts/chunk-1", "first\n"))
+}
+
+// merge --order mtime s3://bucket/parts/* s3://bucket/merged.txt
+func TestMergeOrderByModificationTime(t *testing.T) {
+	t.Parallel()
+
+	timeSource := newFixedTimeSource(time.Now())
+	s3client, s5cmd := setup(t, withTimeSource(timeSource))
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	timeSource.Advance(-3 * time.Minute)
+	putFile(t, s3client, bucket, "parts/c", "first\n")
+	timeSource.Advance(time.Minute)
+	putFile(t, s3client, bucket, "parts/a", "second\n")
+	timeSource.Advance(time.Minute)
+	putFile(t, s3client, bucket, "parts/b", "third\n")
+
+	cmd := s5cmd("merge", "--order", "mtime", "s3://"+bucket+"/parts/*", "s3://"+bucket+"/merged.txt")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assert.Assert(t, ensureS3Object(s3client, bucket, "merged.txt", "first\nsecond\nthird\n"))
+}
+
+// merge --manifest parts.txt s3://bucket/merged.txt
+func TestMergeManifest(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	putFile(t, s3client, bucket, "parts/a", "second\n")
+	putFile(t, s3client, bucket, "parts/b", "first\n")
+	putFile(t, s3client, bucket, "parts/c", "third\n")
+
+	manifest := fs.NewFile(t, "manifest", fs.WithContent(fmt.Sprintf(
+		"s3://%[1]v/parts/b\ns3://%[1]v/parts/a\n\ns3://%[1]v/parts/c\n", bucket,
+	)))
+	defer manifest.Remove()
+
+	cmd := s5cmd("merge", "--manifest", manifest.Path(), "s3://"+bucket+"/merged.txt")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assert.Assert(t, ensureS3Object(s3client, bucket, "merged.txt", "first\nsecond\nthird\n"))
+}
+
+// --dry-run merge s3://bucket/parts/* s3://bucket/merged.txt
+func TestMergeDryRun(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	putFile(t, s3client, bucket, "parts/1", "first\n")
+	putFile(t, s3client, bucket, "parts/2", "second\n")
+
+	cmd := s5cmd("--dry-run", "merge", "--delete-sources", "s3://"+bucket+"/parts/*", "s3://"+bucket+"/merged.txt")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`part 1 upload 13 s3://%[1]v/parts/1 bytes=0-5 s3://%[1]v/parts/2 bytes=0-6`, bucket),
+		1: equals(`merge s3://%v/merged.txt (2 objects, 1 parts, 13 bytes)`, bucket),
+		2: equals(`rm s3://%v/parts/1`, bucket),
+		3: equals(`rm s3://%v/parts/2`, bucket),
+	}, strictLineCheck(true))
+
+	err := ensureS3Object(s3client, bucket, "merged.txt", "")
+	assertError(t, err, errS3NoSuchKey)
+	assert.Assert(t, ensureS3Object(s3client, bucket, "parts/1", "first\n"))
+}
+
+// merge --delete-sources s3://bucket/parts/* s3://bucket/merged.txt
+func TestMergeDeleteSources(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	putFile(t, s3client, bucket, "parts/1", "first\n")
+	putFile(t, s3client, bucket, "parts/2", "second\n")
+
+	cmd := s5cmd("merge", "--delete-sources", "s3://"+bucket+"/parts/*", "s3://"+bucket+"/merged.txt")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`merge s3://%v/merged.txt (2 objects, 1 parts, 13 bytes)`, bucket),
+		1: equals(`rm s3://%v/parts/1`, bucket),
+		2: equals(`rm s3://%v/parts/2`, bucket),
+	}, strictLineCheck(true))
+
+	assert.Assert(t, ensureS3Object(s3client, bucket, "merged.txt", "first\nsecond\n"))
+	for _, key := range []string{"parts/1", "parts/2"} {
+		err := ensureS3Object(s3client, bucket, key, "")
+		assertError(t, err, errS3NoSuchKey)
+	}
+}
+
+// merge s3://bucket/* s3://bucket/merged.txt
+func TestMergeDestinationIsSource(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	putFile(t, s3client, bucket, "part", "first\n")
+	putFile(t, s3client, bucket, "merged.txt", "second\n")
+
+	cmd := s5cmd("merge", "s3://"+bucket+"/*", "s3://"+bucket+"/merged.txt")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "merge s3://%[1]v/* s3://%[1]v/merged.txt": destination s3://%[1]v/merged.txt is one of the objects to merge`, bucket),
+	}, strictLineCheck(true))
+}
+
+func TestMergeValidation(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "no destination",
+			args:     []string{"merge", "s3://bucket/parts/*"},
+			expected: `ERROR "merge s3://bucket/parts/*": expected source and destination arguments`,
+		},
+		{
+			name:     "manifest order without manifest",
+			args:     []string{"merge", "--order", "manifest", "s3://bucket/parts/*", "s3://bucket/merged"},
+			expected: `ERROR "merge --order=manifest s3://bucket/parts/* s3://bucket/merged": "manifest" order requires "manifest" flag`,
+		},
+		{
+			name:     "manifest with sources",
+			args:     []string{"merge", "--manifest", "parts.txt", "s3://bucket/parts/*", "s3://bucket/merged"},
+			expected: `ERROR "merge --manifest=parts.txt s3://bucket/parts/* s3://bucket/merged": expected only the destination argument with "manifest" flag`,
+		},
+		{
+			name:     "prefix destination",
+			args:     []string{"merge", "s3://bucket/parts/*", "s3://bucket/merged/"},
+			expected: `ERROR "merge s3://bucket/parts/* s3://bucket/merged/": destination "s3://bucket/merged/" must be a remote object`,
+		},
+		{
+			name:     "local source",
+			args:     []string{"merge", "parts/*", "s3://bucket/merged"},
+			expected: `ERROR "merge parts/* s3://bucket/merged": source "parts/*" must be a remote object`,
+		},
+		{
+			name:     "prefix source",
+			args:     []string{"merge", "s3://bucket/parts/", "s3://bucket/merged"},
+			expected: `ERROR "merge s3://bucket/parts/ s3://bucket/merged": source "s3://bucket/parts/" must be an object or a wildcard`,
+		},
+		{
+			name:     "zero concurrency",
+			args:     []string{"merge", "--concurrency", "0", "s3://bucket/parts/*", "s3://bucket/merged"},
+			expected: `ERROR "merge --concurrency=0 s3://bucket/parts/* s3://bucket/merged": "concurrency" flag must be a positive number`,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, s5cmd := setup(t)
+
+			result := icmd.RunCmd(s5cmd(tc.args...))
+			result.Assert(t, icmd.Expected{ExitCode: 1})
+
+			assertLines(t, result.Stderr(), map[int]compareFunc{
+				0: equals(tc.expected),
+			}, strictLineCheck(true))
+		})
+	}
+}
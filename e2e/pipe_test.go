@@ -351,6 +351,7 @@ func TestUploadStdinToS3JSON(t *testing.T) {
 
 	jsonText := `
 		{
+			"v": 2,
 			"operation": "pipe",
 			"success": true,
 			"destination": "s3://%v/testfile1.txt",
@@ -448,3 +449,131 @@ func TestUploadStdinToToS3WithContentDisposition(t *testing.T) {
 	// assert S3
 	assert.Assert(t, ensureS3Object(s3client, bucket, filename, content, ensureContentType(expectedContentType), ensureContentDisposition(expectedContentDisposition)))
 }
+
+// pipe --website-redirect /new.html --system-header Content-Type=text/plain s3://bucket/object
+func TestUploadStdinToS3WithWebsiteRedirect(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	const (
+		filename = "old.html"
+		content  = "moved"
+	)
+
+	dstpath := fmt.Sprintf("s3://%v/%v", bucket, filename)
+	cmd := s5cmd("pipe", "--website-redirect", "/new.html", "--system-header", "content-type=text/plain", dstpath)
+	result := icmd.RunCmd(cmd, icmd.WithStdin(bytes.NewBufferString(content)))
+
+	result.Assert(t, icmd.Success)
+
+	assert.Assert(t, ensureS3Object(s3client, bucket, filename, content, ensureContentType("text/plain"), ensureWebsiteRedirect("/new.html")))
+}
+
+// pipe --expected-size 19 s3://bucket/object
+func TestUploadStdinToS3WithExpectedSize(t *testing.T) {
+	t.Parallel()
+
+	bucket := s3BucketFromTestName(t)
+
+	s3client, s5cmd := setup(t)
+
+	createBucket(t, s3client, bucket)
+
+	const (
+		filename = "testfile1.txt"
+		content  = "this is the content"
+	)
+
+	dst := fmt.Sprintf("s3://%v/%v", bucket, filename)
+	cmd := s5cmd("pipe", "--expected-size", "19", dst)
+	result := icmd.RunCmd(cmd, icmd.WithStdin(bytes.NewBufferString(content)))
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`pipe %v`, dst),
+	})
+
+	assert.Assert(t, ensureS3Object(s3client, bucket, filename, content))
+}
+
+// pipe --expected-size 1KB s3://bucket/object
+func TestUploadStdinToS3WithExpectedSizeMismatch(t *testing.T) {
+	t.Parallel()
+
+	bucket := s3BucketFromTestName(t)
+
+	s3client, s5cmd := setup(t)
+
+	createBucket(t, s3client, bucket)
+
+	const (
+		filename = "testfile1.txt"
+		content  = "this is the content"
+	)
+
+	dst := fmt.Sprintf("s3://%v/%v", bucket, filename)
+	cmd := s5cmd("pipe", "--expected-size", "1KB", dst)
+	result := icmd.RunCmd(cmd, icmd.WithStdin(bytes.NewBufferString(content)))
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "pipe --expected-size=1KB %v": uploaded object size 19 does not match expected size 1024`, dst),
+	})
+
+	// the object is kept unless --delete-on-size-mismatch is given.
+	assert.Assert(t, ensureS3Object(s3client, bucket, filename, content))
+}
+
+// pipe --expected-size 1KB --delete-on-size-mismatch s3://bucket/object
+func TestUploadStdinToS3WithExpectedSizeMismatchAndDelete(t *testing.T) {
+	t.Parallel()
+
+	bucket := s3BucketFromTestName(t)
+
+	s3client, s5cmd := setup(t)
+
+	createBucket(t, s3client, bucket)
+
+	const (
+		filename = "testfile1.txt"
+		content  = "this is the content"
+	)
+
+	dst := fmt.Sprintf("s3://%v/%v", bucket, filename)
+	cmd := s5cmd("pipe", "--expected-size", "1KB", "--delete-on-size-mismatch", dst)
+	result := icmd.RunCmd(cmd, icmd.WithStdin(bytes.NewBufferString(content)))
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "pipe --expected-size=1KB --delete-on-size-mismatch=true %v": uploaded object size 19 does not match expected size 1024, the object is deleted`, dst),
+	})
+
+	err := ensureS3Object(s3client, bucket, filename, content)
+	assertError(t, err, errS3NoSuchKey)
+}
+
+// pipe --delete-on-size-mismatch s3://bucket/object
+func TestUploadStdinToS3DeleteOnSizeMismatchWithoutExpectedSize(t *testing.T) {
+	t.Parallel()
+
+	bucket := s3BucketFromTestName(t)
+
+	_, s5cmd := setup(t)
+
+	dst := fmt.Sprintf("s3://%v/object", bucket)
+	cmd := s5cmd("pipe", "--delete-on-size-mismatch", dst)
+	result := icmd.RunCmd(cmd, icmd.WithStdin(bytes.NewBufferString("content")))
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "pipe --delete-on-size-mismatch=true %v": "delete-on-size-mismatch" flag requires "expected-size" flag`, dst),
+	})
+}
@@ -53,6 +53,7 @@ func TestRemoveBucketSuccessJson(t *testing.T) {
 
 	jsonText := `
 		{
+			"v": 2,
 			"operation": "rb",
 			"success": true,
 			"source": "%v"
@@ -99,7 +100,7 @@ func TestRemoveBucketFailureJson(t *testing.T) {
 	result.Assert(t, icmd.Expected{ExitCode: 1})
 
 	assertLines(t, result.Stderr(), map[int]compareFunc{
-		0: equals(`{"operation":"rb","command":"rb %v","error":"invalid s3 bucket"}`, src),
+		0: equals(`{"v":2,"operation":"rb","command":"rb %v","error":"invalid s3 bucket"}`, src),
 	}, jsonCheck(true))
 }
 
@@ -6,6 +6,7 @@ import (
 	"runtime"
 	"strings"
 	"testing"
+	"time"
 
 	"gotest.tools/v3/assert"
 	"gotest.tools/v3/fs"
@@ -70,6 +71,7 @@ func TestRemoveSingleS3ObjectJSON(t *testing.T) {
 	assertLines(t, result.Stdout(), map[int]compareFunc{
 		0: json(`
 			{
+				"v": 2,
 				"operation": "rm",
 				"success": true,
 				"source": "s3://%v/%v"
@@ -123,6 +125,122 @@ func TestRemoveMultipleS3Objects(t *testing.T) {
 	}
 }
 
+// rm --delete-batch-size 2 s3://bucket/*
+func TestRemoveMultipleS3ObjectsWithDeleteBatchSize(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	filenames := []string{"a.txt", "b.txt", "c.txt", "d.txt", "e.txt"}
+	for _, filename := range filenames {
+		putFile(t, s3client, bucket, filename, "content of "+filename)
+	}
+
+	cmd := s5cmd("rm", "--delete-batch-size", "2", "s3://"+bucket+"/*")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{})
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`rm s3://%v/a.txt`, bucket),
+		1: equals(`rm s3://%v/b.txt`, bucket),
+		2: equals(`rm s3://%v/c.txt`, bucket),
+		3: equals(`rm s3://%v/d.txt`, bucket),
+		4: equals(`rm s3://%v/e.txt`, bucket),
+	}, sortInput(true), strictLineCheck(true))
+
+	for _, filename := range filenames {
+		err := ensureS3Object(s3client, bucket, filename, "content of "+filename)
+		assertError(t, err, errS3NoSuchKey)
+	}
+}
+
+func TestRemoveDeleteBatchSizeValidation(t *testing.T) {
+	t.Parallel()
+
+	for _, size := range []string{"0", "1001"} {
+		size := size
+		t.Run(size, func(t *testing.T) {
+			t.Parallel()
+
+			_, s5cmd := setup(t)
+
+			cmd := s5cmd("rm", "--delete-batch-size", size, "s3://bucket/*")
+			result := icmd.RunCmd(cmd)
+
+			result.Assert(t, icmd.Expected{ExitCode: 1})
+
+			assertLines(t, result.Stderr(), map[int]compareFunc{
+				0: equals(`ERROR "rm --delete-batch-size=%v s3://bucket/*": delete-batch-size must be between 1 and 1000`, size),
+			}, strictLineCheck(true))
+		})
+	}
+}
+
+// rm --quiet s3://bucket/*
+func TestRemoveMultipleS3ObjectsQuiet(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	filesToContent := map[string]string{
+		"testfile1.txt":         "this is a test file 1",
+		"readme.md":             "this is a readme file",
+		"another_test_file.txt": "yet another txt file. yatf.",
+	}
+
+	for filename, content := range filesToContent {
+		putFile(t, s3client, bucket, filename, content)
+	}
+
+	cmd := s5cmd("rm", "--quiet", "s3://"+bucket+"/*")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{})
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`rm: 3 objects deleted, 0 failed`),
+	})
+
+	// assert s3 objects
+	for filename, content := range filesToContent {
+		err := ensureS3Object(s3client, bucket, filename, content)
+		assertError(t, err, errS3NoSuchKey)
+	}
+}
+
+// --json rm -q s3://bucket/*
+func TestRemoveMultipleS3ObjectsQuietJSON(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	putFile(t, s3client, bucket, "testfile1.txt", "this is a test file 1")
+	putFile(t, s3client, bucket, "readme.md", "this is a readme file")
+
+	cmd := s5cmd("--json", "rm", "-q", "s3://"+bucket+"/*")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`{"v":2,"operation":"rm","deleted":2,"failed":0}`),
+	}, jsonCheck(true))
+}
+
 // --json rm s3://bucket/*
 func TestRemoveMultipleS3ObjectsJSON(t *testing.T) {
 	t.Parallel()
@@ -153,6 +271,7 @@ func TestRemoveMultipleS3ObjectsJSON(t *testing.T) {
 	assertLines(t, result.Stdout(), map[int]compareFunc{
 		0: json(`
 			{
+				"v": 2,
 				"operation": "rm",
 				"success": true,
 				"source": "s3://%v/another_test_file.txt"
@@ -160,6 +279,7 @@ func TestRemoveMultipleS3ObjectsJSON(t *testing.T) {
 		`, bucket),
 		1: json(`
 			{
+				"v": 2,
 				"operation": "rm",
 				"success": true,
 				"source": "s3://%v/filename-with-hypen.gz"
@@ -167,6 +287,7 @@ func TestRemoveMultipleS3ObjectsJSON(t *testing.T) {
 		`, bucket),
 		2: json(`
 			{
+				"v": 2,
 				"operation": "rm",
 				"success": true,
 				"source": "s3://%v/readme.md"
@@ -174,6 +295,7 @@ func TestRemoveMultipleS3ObjectsJSON(t *testing.T) {
 		`, bucket),
 		3: json(`
 			{
+				"v": 2,
 				"operation": "rm",
 				"success": true,
 				"source": "s3://%v/testfile1.txt"
@@ -1301,3 +1423,330 @@ func TestRemoveByVersionID(t *testing.T) {
 	result = icmd.RunCmd(cmd)
 	assert.Assert(t, result.Stdout() == "")
 }
+
+func TestRemoveNoncurrentVersionsOlderThan(t *testing.T) {
+	skipTestIfGCS(t, "versioning is not supported in GCS")
+
+	t.Parallel()
+
+	bucket := s3BucketFromTestName(t)
+
+	timeSource := newFixedTimeSource(time.Now().Add(-200 * 24 * time.Hour))
+
+	// versioninng is only supported with in memory backend!
+	s3client, s5cmd := setup(t, withS3Backend("mem"), withTimeSource(timeSource))
+
+	createBucket(t, s3client, bucket)
+	setBucketVersioning(t, s3client, bucket, "Enabled")
+
+	// 200 days ago
+	putFile(t, s3client, bucket, "prefix/object", "first version")
+	putFile(t, s3client, bucket, "prefix/deleted", "deleted long ago")
+	putFile(t, s3client, bucket, "prefix/recently-deleted", "deleted recently")
+
+	// 100 days ago
+	timeSource.Advance(100 * 24 * time.Hour)
+	putFile(t, s3client, bucket, "prefix/object", "second version")
+	deleteObject(t, s3client, bucket, "prefix/deleted")
+
+	// 10 days ago
+	timeSource.Advance(90 * 24 * time.Hour)
+	putFile(t, s3client, bucket, "prefix/object", "third version")
+	deleteObject(t, s3client, bucket, "prefix/recently-deleted")
+
+	cmd := s5cmd("rm", "--all-versions", "--noncurrent-only", "--noncurrent-older-than", "90d", "s3://"+bucket+"/prefix/*")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	// only the first version of "object" and the version of "deleted" became
+	// noncurrent more than 90 days ago. delete markers are kept.
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: contains("rm s3://%v/prefix/deleted", bucket),
+		1: contains("rm s3://%v/prefix/object", bucket),
+	}, sortInput(true))
+
+	assert.DeepEqual(t, map[string]int{
+		"prefix/deleted":          1,
+		"prefix/object":           2,
+		"prefix/recently-deleted": 2,
+	}, countVersions(t, s3client, bucket))
+
+	cmd = s5cmd("rm", "--all-versions", "--noncurrent-only", "--noncurrent-older-than", "90d", "--expired-delete-markers", "s3://"+bucket+"/prefix/*")
+	result = icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	// the delete marker of "deleted" is the only remaining version of it.
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: contains("rm s3://%v/prefix/deleted", bucket),
+	}, strictLineCheck(true))
+
+	assert.DeepEqual(t, map[string]int{
+		"prefix/object":           2,
+		"prefix/recently-deleted": 2,
+	}, countVersions(t, s3client, bucket))
+}
+
+func TestRemoveNoncurrentVersionsWithoutAllVersions(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	cmd := s5cmd("rm", "--noncurrent-only", "s3://"+bucket+"/*")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "rm --noncurrent-only=true s3://%v/*": noncurrent-only flag can only be used with all-versions flag`, bucket),
+	}, strictLineCheck(true))
+}
+
+// ls --show-fullpath -0 s3://bucket/dir/* | rm --manifest - -0
+func TestRemoveNullDelimitedManifestFromList(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	keys := []string{
+		"dir/carriage\rreturn.txt",
+		"dir/new\nline.txt",
+		`dir/quo"te's.txt`,
+		"dir/star*.txt",
+	}
+	for _, key := range keys {
+		putFile(t, s3client, bucket, key, "content")
+	}
+	putFile(t, s3client, bucket, "kept.txt", "content")
+
+	result := icmd.RunCmd(s5cmd("ls", "--show-fullpath", "-0", "s3://"+bucket+"/dir/*"))
+	result.Assert(t, icmd.Success)
+
+	var expected string
+	for _, key := range keys {
+		expected += fmt.Sprintf("s3://%v/%v\x00", bucket, key)
+	}
+	assert.Equal(t, expected, result.Stdout())
+
+	cmd := s5cmd("rm", "--manifest", "-", "-0")
+	result = icmd.RunCmd(cmd, icmd.WithStdin(strings.NewReader(result.Stdout())))
+	result.Assert(t, icmd.Success)
+
+	for _, key := range keys {
+		err := ensureS3Object(s3client, bucket, key, "content")
+		assertError(t, err, errS3NoSuchKey)
+	}
+	assert.Assert(t, ensureS3Object(s3client, bucket, "kept.txt", "content"))
+}
+
+// rm --manifest objects.txt
+func TestRemoveManifestFile(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	putFile(t, s3client, bucket, "a.txt", "content")
+	putFile(t, s3client, bucket, "b*.txt", "content")
+	putFile(t, s3client, bucket, "bc.txt", "content")
+
+	// the wildcards in the manifest are not expanded.
+	manifest := fmt.Sprintf("s3://%v/a.txt\r\n\ns3://%v/b*.txt\n", bucket, bucket)
+	workdir := fs.NewDir(t, t.Name(), fs.WithFile("objects.txt", manifest))
+	defer workdir.Remove()
+
+	cmd := s5cmd("rm", "--manifest", workdir.Join("objects.txt"))
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals("rm s3://%v/a.txt", bucket),
+		1: equals("rm s3://%v/b*.txt", bucket),
+	}, sortInput(true))
+
+	for _, key := range []string{"a.txt", "b*.txt"} {
+		err := ensureS3Object(s3client, bucket, key, "content")
+		assertError(t, err, errS3NoSuchKey)
+	}
+	assert.Assert(t, ensureS3Object(s3client, bucket, "bc.txt", "content"))
+}
+
+func TestRemoveManifestValidation(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "manifest with arguments",
+			args:     []string{"rm", "--manifest", "-", "s3://bucket/key"},
+			expected: `ERROR "rm --manifest=- s3://bucket/key": "manifest" flag can not be used with arguments`,
+		},
+		{
+			name:     "null without manifest",
+			args:     []string{"rm", "-0", "s3://bucket/key"},
+			expected: `ERROR "rm --null=true s3://bucket/key": "null" flag requires "manifest" flag`,
+		},
+		{
+			name:     "manifest with version id",
+			args:     []string{"rm", "--manifest", "-", "--version-id", "1"},
+			expected: `ERROR "rm --version-id=1 --manifest=-": it is not allowed to combine "manifest" and "version-id" flags`,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, s5cmd := setup(t)
+
+			result := icmd.RunCmd(s5cmd(tc.args...))
+
+			result.Assert(t, icmd.Expected{ExitCode: 1})
+
+			assertLines(t, result.Stderr(), map[int]compareFunc{
+				0: equals(tc.expected),
+			})
+		})
+	}
+}
+
+// rm --storage-class-filter !STANDARD s3://bucket/*
+func TestRemoveS3ObjectsWithStorageClassFilter(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	const content = "content"
+	putFile(t, s3client, bucket, "a.txt", content)
+	putFile(t, s3client, bucket, "dir/b.txt", content)
+
+	cmd := s5cmd("rm", "--storage-class-filter", "!STANDARD", "s3://"+bucket+"/*")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+	assertLines(t, result.Stdout(), map[int]compareFunc{}, strictLineCheck(true))
+
+	// the objects in STANDARD storage class are not removed.
+	for _, key := range []string{"a.txt", "dir/b.txt"} {
+		assert.Assert(t, ensureS3Object(s3client, bucket, key, content))
+	}
+
+	cmd = s5cmd("rm", "--storage-class-filter", "STANDARD", "s3://"+bucket+"/dir/*")
+	result = icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals("rm s3://%v/dir/b.txt", bucket),
+	}, strictLineCheck(true))
+
+	assert.Assert(t, ensureS3Object(s3client, bucket, "a.txt", content))
+	err := ensureS3Object(s3client, bucket, "dir/b.txt", content)
+	assertError(t, err, errS3NoSuchKey)
+}
+
+func TestRemoveStorageClassFilterValidation(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "single object",
+			args:     []string{"rm", "--storage-class-filter", "GLACIER", "s3://bucket/key"},
+			expected: `ERROR "rm --storage-class-filter=GLACIER s3://bucket/key": "storage-class-filter" flag requires the objects to be matched with a wildcard, e.g. "s3://bucket/prefix/*"`,
+		},
+		{
+			name:     "local files",
+			args:     []string{"rm", "--storage-class-filter", "GLACIER", "dir/*"},
+			expected: `ERROR "rm --storage-class-filter=GLACIER dir/*": "storage-class-filter" flag can only be used with remote objects`,
+		},
+		{
+			name:     "manifest",
+			args:     []string{"rm", "--storage-class-filter", "GLACIER", "--manifest", "-"},
+			expected: `ERROR "rm --manifest=- --storage-class-filter=GLACIER": it is not allowed to combine "manifest" and "storage-class-filter" flags`,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, s5cmd := setup(t)
+
+			result := icmd.RunCmd(s5cmd(tc.args...))
+
+			result.Assert(t, icmd.Expected{ExitCode: 1})
+
+			assertLines(t, result.Stderr(), map[int]compareFunc{
+				0: equals(tc.expected),
+			})
+		})
+	}
+}
+
+// rm --recursive s3://bucket/prefix
+func TestRemoveS3PrefixRecursive(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	const content = "content"
+	putFile(t, s3client, bucket, "prefix/a.txt", content)
+	putFile(t, s3client, bucket, "prefix/nested/b.txt", content)
+	putFile(t, s3client, bucket, "other.txt", content)
+
+	result := icmd.RunCmd(s5cmd("rm", "--recursive", "s3://"+bucket+"/prefix"))
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`WARNING "recursive" flag is mapped to the wildcard source "s3://%v/prefix/*", which matches all objects under "s3://%v/prefix" without the flag`, bucket, bucket),
+	})
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`rm s3://%v/prefix/a.txt`, bucket),
+		1: equals(`rm s3://%v/prefix/nested/b.txt`, bucket),
+	}, sortInput(true))
+
+	assertError(t, ensureS3Object(s3client, bucket, "prefix/a.txt", content), errS3NoSuchKey)
+	assertError(t, ensureS3Object(s3client, bucket, "prefix/nested/b.txt", content), errS3NoSuchKey)
+	assert.Assert(t, ensureS3Object(s3client, bucket, "other.txt", content))
+}
+
+// rm --manifest - --recursive
+func TestRemoveManifestRecursive(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd := setup(t)
+
+	result := icmd.RunCmd(s5cmd("rm", "--manifest", "-", "--recursive"))
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "rm --manifest=- --recursive=true": it is not allowed to combine "manifest" and "recursive" flags`),
+	})
+}
@@ -2,6 +2,9 @@ package e2e
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 	"testing"
 	"time"
@@ -97,8 +100,9 @@ func TestRunFromStdinWithErrors(t *testing.T) {
 	assertLines(t, result.Stdout(), map[int]compareFunc{})
 
 	assertLines(t, result.Stderr(), map[int]compareFunc{
-		0: contains(`ERROR "cp s3://%v/nonexistentobject nonexistentobject": NoSuchKey:`, bucket),
-		1: equals(`ERROR "ls s3/": given object s3/ not found`),
+		0: equals(`ERROR "stdin:1: ls s3/": given object s3/ not found`),
+		1: contains(`ERROR "stdin:2: cp s3://%v/nonexistentobject nonexistentobject": NoSuchKey:`, bucket),
+		2: equals(`ERROR 2 errors: NoSuchKey: 1 (first: s3://%v/nonexistentobject), NotFound: 1 (first: stdin:1: ls s3/)`, bucket),
 	}, sortInput(true))
 }
 
@@ -124,8 +128,8 @@ func TestRunFromStdinJSON(t *testing.T) {
 	result.Assert(t, icmd.Success)
 
 	assertLines(t, result.Stdout(), map[int]compareFunc{
-		0: prefix(`{"key":"s3://%v/file1.txt",`, bucket),
-		1: prefix(`{"key":"s3://%v/file2.txt",`, bucket),
+		0: prefix(`{"v":2,"key":"s3://%v/file1.txt",`, bucket),
+		1: prefix(`{"v":2,"key":"s3://%v/file2.txt",`, bucket),
 	}, sortInput(true), jsonCheck(true))
 
 	assertLines(t, result.Stderr(), map[int]compareFunc{})
@@ -186,8 +190,8 @@ func TestRunFromFileJSON(t *testing.T) {
 	result.Assert(t, icmd.Success)
 
 	assertLines(t, result.Stdout(), map[int]compareFunc{
-		0: prefix(`{"key":"s3://%v/file1.txt",`, bucket),
-		1: prefix(`{"key":"s3://%v/file2.txt",`, bucket),
+		0: prefix(`{"v":2,"key":"s3://%v/file1.txt",`, bucket),
+		1: prefix(`{"v":2,"key":"s3://%v/file2.txt",`, bucket),
 	}, sortInput(true), jsonCheck(true))
 
 	assertLines(t, result.Stderr(), map[int]compareFunc{})
@@ -350,3 +354,359 @@ func TestRunFixDataRace_Issue301(t *testing.T) {
 
 	assertLines(t, result.Stderr(), map[int]compareFunc{})
 }
+
+func TestRunInputFormatJSONDryRunPlan(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+	putFile(t, s3client, bucket, "file1.txt", "content1")
+	putFile(t, s3client, bucket, "file[2].txt", "content2")
+	putFile(t, s3client, bucket, "file2.txt", "content3")
+
+	// print the plan of the copy operations as JSON records.
+	cmd := s5cmd("--json", "--dry-run", "cp", fmt.Sprintf("s3://%v/file*", bucket), fmt.Sprintf("s3://%v/copy/", bucket))
+	result := icmd.RunCmd(cmd)
+	result.Assert(t, icmd.Success)
+
+	file := fs.NewFile(t, "plan", fs.WithContent(result.Stdout()))
+	defer file.Remove()
+
+	cmd = s5cmd("run", "--input-format", "json", file.Path())
+	result = icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp s3://%v/file1.txt s3://%v/copy/file1.txt`, bucket, bucket),
+		1: equals(`cp s3://%v/file2.txt s3://%v/copy/file2.txt`, bucket, bucket),
+		2: equals(`cp s3://%v/file[2].txt s3://%v/copy/file[2].txt`, bucket, bucket),
+	}, sortInput(true))
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{})
+
+	assert.Assert(t, ensureS3Object(s3client, bucket, "copy/file1.txt", "content1"))
+	assert.Assert(t, ensureS3Object(s3client, bucket, "copy/file[2].txt", "content2"))
+	assert.Assert(t, ensureS3Object(s3client, bucket, "copy/file2.txt", "content3"))
+}
+
+func TestRunInputFormatAutoDetect(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+	putFile(t, s3client, bucket, "file1.txt", "content")
+	putFile(t, s3client, bucket, "file2.txt", "content")
+
+	input := strings.NewReader(
+		strings.Join([]string{
+			fmt.Sprintf("rm s3://%v/file1.txt", bucket),
+			fmt.Sprintf(`{"operation":"rm","success":true,"source":"s3://%v/file2.txt"}`, bucket),
+		}, "\n"),
+	)
+	cmd := s5cmd("run")
+	result := icmd.RunCmd(cmd, icmd.WithStdin(input))
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`rm s3://%v/file1.txt`, bucket),
+		1: equals(`rm s3://%v/file2.txt`, bucket),
+	}, sortInput(true))
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{})
+
+	err := ensureS3Object(s3client, bucket, "file1.txt", "content")
+	assertError(t, err, errS3NoSuchKey)
+	err = ensureS3Object(s3client, bucket, "file2.txt", "content")
+	assertError(t, err, errS3NoSuchKey)
+}
+
+func TestRunInputFormatJSONInvalidRecord(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd := setup(t)
+
+	input := strings.NewReader(`{"key":"s3://bucket/file1.txt"}`)
+	cmd := s5cmd("run", "--input-format", "json")
+	result := icmd.RunCmd(cmd, icmd.WithStdin(input))
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "run --input-format=json": invalid input (stdin:1): JSON operation record has no operation`),
+	})
+}
+
+func TestRunEstimate(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+	putFile(t, s3client, bucket, "data/file1.txt", "0123456789")
+	putFile(t, s3client, bucket, "data/file2.txt", "0123456789")
+	putFile(t, s3client, bucket, "object.txt", "01234")
+
+	workdir := fs.NewDir(t, "estimate",
+		fs.WithFile("file.txt", "0123456789012345678901234567890123456789"),
+		fs.WithDir("dir",
+			fs.WithFile("a.txt", "0123456789"),
+			fs.WithFile("b.txt", "0123456789"),
+		),
+	)
+	defer workdir.Remove()
+
+	filecontent := strings.Join([]string{
+		fmt.Sprintf("cp %v s3://%v/upload/", filepath.ToSlash(workdir.Join("file.txt")), bucket),
+		fmt.Sprintf("cp %v/ s3://%v/upload/", filepath.ToSlash(workdir.Join("dir")), bucket),
+		fmt.Sprintf("cp 's3://%v/data/*' %v/", bucket, filepath.ToSlash(workdir.Join("download"))),
+		fmt.Sprintf("cp --raw s3://%v/object.txt s3://%v/copy/object.txt", bucket, bucket),
+		fmt.Sprintf("sync s3://%v/data/ s3://%v/copy/", bucket, bucket),
+		fmt.Sprintf("rm s3://%v/object.txt", bucket),
+		fmt.Sprintf("cp s3://%v/nonexistent.txt .", bucket),
+	}, "\n")
+
+	file := fs.NewFile(t, "prefix", fs.WithContent(filecontent))
+	defer file.Remove()
+
+	cmd := s5cmd("run", "--estimate", "--assume-throughput", "10B/s", file.Path())
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals("operations 7 (5 cp, 1 rm, 1 sync)"),
+		1: equals("upload 2 operations, 3 objects, 60"),
+		2: equals("download 2 operations, 2 objects, 20"),
+		3: equals("copy 2 operations, 3 objects, 25"),
+		4: equals("unresolved 1 operations"),
+		5: equals("eta 8s at 10/s"),
+	}, strictLineCheck(true))
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{})
+
+	// nothing is executed.
+	err := ensureS3Object(s3client, bucket, "upload/file.txt", "0123456789012345678901234567890123456789")
+	assertError(t, err, errS3NoSuchKey)
+	assert.Assert(t, ensureS3Object(s3client, bucket, "object.txt", "01234"))
+}
+
+func TestRunEstimateJSON(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+	putFile(t, s3client, bucket, "object.txt", "01234")
+
+	file := fs.NewFile(t, "prefix", fs.WithContent(fmt.Sprintf("cp s3://%v/object.txt .", bucket)))
+	defer file.Remove()
+
+	cmd := s5cmd("--json", "run", "--estimate", file.Path())
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`{"v":2,"operation":"estimate","operations":{"cp":1},"upload":{"operations":0,"objects":0,"bytes":0},"download":{"operations":1,"objects":1,"bytes":5},"copy":{"operations":0,"objects":0,"bytes":0},"unresolved":0}`),
+	}, strictLineCheck(true))
+}
+
+func TestRunEstimateThenRun(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+	putFile(t, s3client, bucket, "file1.txt", "content")
+	putFile(t, s3client, bucket, "file2.txt", "content")
+
+	filecontent := strings.Join([]string{
+		fmt.Sprintf("cp s3://%v/file1.txt s3://%v/copy/", bucket, bucket),
+		fmt.Sprintf("cp s3://%v/file2.txt s3://%v/copy/", bucket, bucket),
+	}, "\n")
+
+	file := fs.NewFile(t, "prefix", fs.WithContent(filecontent))
+	defer file.Remove()
+
+	cmd := s5cmd("run", "--estimate-then-run", file.Path())
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals("copy 2 operations, 2 objects, 14"),
+		1: equals("cp s3://%v/file1.txt s3://%v/copy/file1.txt", bucket, bucket),
+		2: equals("cp s3://%v/file2.txt s3://%v/copy/file2.txt", bucket, bucket),
+		3: equals("download 0 operations, 0 objects, 0"),
+		4: equals("operations 2 (2 cp)"),
+		5: equals("unresolved 0 operations"),
+		6: equals("upload 0 operations, 0 objects, 0"),
+	}, sortInput(true))
+
+	assert.Assert(t, ensureS3Object(s3client, bucket, "copy/file1.txt", "content"))
+	assert.Assert(t, ensureS3Object(s3client, bucket, "copy/file2.txt", "content"))
+}
+
+func TestRunEstimateWithInvalidFlags(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		flags    []string
+		expected string
+	}{
+		{
+			name:     "estimate and estimate-then-run",
+			flags:    []string{"--estimate", "--estimate-then-run"},
+			expected: `ERROR "run --estimate=true --estimate-then-run=true": it is not allowed to combine "estimate" and "estimate-then-run" flags`,
+		},
+		{
+			name:     "assume-throughput without estimate",
+			flags:    []string{"--assume-throughput", "10MB/s"},
+			expected: `ERROR "run --assume-throughput=10MB/s": "assume-throughput" flag requires "estimate" or "estimate-then-run" flag`,
+		},
+		{
+			name:     "invalid throughput",
+			flags:    []string{"--estimate", "--assume-throughput", "fast"},
+			expected: `ERROR "run --estimate=true --assume-throughput=fast": invalid assume-throughput "fast"`,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, s5cmd := setup(t)
+
+			cmd := s5cmd(append([]string{"run"}, tc.flags...)...)
+			result := icmd.RunCmd(cmd, icmd.WithStdin(strings.NewReader("")))
+
+			result.Assert(t, icmd.Expected{ExitCode: 1})
+
+			assertLines(t, result.Stderr(), map[int]compareFunc{
+				0: equals(tc.expected),
+			}, strictLineCheck(true))
+		})
+	}
+}
+
+func TestRunNullDelimited(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	putFile(t, s3client, bucket, "new\nline.txt", "content")
+	putFile(t, s3client, bucket, "file.txt", "content")
+
+	input := strings.NewReader(fmt.Sprintf(
+		"rm 's3://%v/new\nline.txt'\x00rm s3://%v/file.txt\x00",
+		bucket, bucket,
+	))
+
+	cmd := s5cmd("run", "--null")
+	result := icmd.RunCmd(cmd, icmd.WithStdin(input))
+
+	result.Assert(t, icmd.Success)
+
+	for _, key := range []string{"new\nline.txt", "file.txt"} {
+		err := ensureS3Object(s3client, bucket, key, "content")
+		assertError(t, err, errS3NoSuchKey)
+	}
+}
+
+// run with the lines longer than the buffer of the reader, e.g. the "rm"
+// commands generated by sync with thousands of keys.
+func TestRunLongLine(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	putFile(t, s3client, bucket, "file.txt", "content")
+
+	// the keys which don't exist are not reported by rm.
+	var line strings.Builder
+	fmt.Fprintf(&line, "rm s3://%v/file.txt", bucket)
+	for i := 0; line.Len() <= 1<<20; i++ {
+		fmt.Fprintf(&line, " s3://%v/%v/missing-%08d.txt", bucket, strings.Repeat("x", 900), i)
+	}
+	input := strings.NewReader(line.String() + "\n")
+
+	cmd := s5cmd("run")
+	result := icmd.RunCmd(cmd, icmd.WithStdin(input))
+
+	result.Assert(t, icmd.Success)
+
+	err := ensureS3Object(s3client, bucket, "file.txt", "content")
+	assertError(t, err, errS3NoSuchKey)
+}
+
+func TestRunErrorPositions(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	putFile(t, s3client, bucket, "file.txt", "content")
+
+	commands := fs.NewFile(t, "commands", fs.WithContent(strings.Join([]string{
+		"# comment",
+		fmt.Sprintf("cp s3://%v/file.txt .", bucket),
+		fmt.Sprintf("cp s3://%v/missing.txt .", bucket),
+		`cp "s3://unterminated`,
+		"",
+		"nosuchcommand a b",
+	}, "\n")+"\n"))
+	failed := fs.NewFile(t, "failed")
+
+	cmd := s5cmd("run", "--failed-output", failed.Path(), commands.Path())
+	result := icmd.RunCmd(cmd, withWorkingDir(fs.NewDir(t, "workdir")))
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: contains(`ERROR "%v:3: cp s3://%v/missing.txt missing.txt": NoSuchKey`, commands.Path(), bucket),
+		1: equals(`ERROR "run --failed-output=%v %v": "nosuchcommand" command (%v:6) not found`, failed.Path(), commands.Path(), commands.Path()),
+		2: contains(`ERROR "run --failed-output=%v %v": invalid input (%v:4): `, failed.Path(), commands.Path(), commands.Path()),
+		3: prefix(`ERROR 3 errors:`),
+	}, sortInput(true))
+
+	first := len("# comment\n") + len(fmt.Sprintf("cp s3://%v/file.txt .\n", bucket))
+	second := first + len(fmt.Sprintf("cp s3://%v/missing.txt .\n", bucket))
+	third := second + len("cp \"s3://unterminated\n\n")
+	expected := []string{
+		fmt.Sprintf("# %v:3 (offset %v)\ncp s3://%v/missing.txt .\n", commands.Path(), first, bucket),
+		fmt.Sprintf("# %v:4 (offset %v)\ncp \"s3://unterminated\n", commands.Path(), second),
+		fmt.Sprintf("# %v:6 (offset %v)\nnosuchcommand a b\n", commands.Path(), third),
+	}
+
+	content, err := os.ReadFile(failed.Path())
+	assert.NilError(t, err)
+
+	// the commands are run in parallel, so the failed ones are written in
+	// any order. Each of them is preceded by a comment with its position.
+	lines := strings.SplitAfter(string(content), "\n")
+	var records []string
+	for i := 0; i+1 < len(lines); i += 2 {
+		records = append(records, lines[i]+lines[i+1])
+	}
+	sort.Strings(records)
+	assert.DeepEqual(t, records, expected)
+}
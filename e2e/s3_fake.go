@@ -16,7 +16,11 @@ func s3ServerEndpoint(t *testing.T, testdir *fs.Dir, loglvl, backend string, tim
 	var s3backend gofakes3.Backend
 	switch backend {
 	case "mem":
-		s3backend = s3mem.New()
+		var opts []s3mem.Option
+		if timeSource != nil {
+			opts = append(opts, s3mem.WithTimeSource(timeSource))
+		}
+		s3backend = s3mem.New(opts...)
 	case "bolt":
 		dbpath := testdir.Join("s3.boltdb")
 		// we use boltdb as the s3 backend because listing buckets in in-memory
@@ -0,0 +1,83 @@
+package e2e
+
+import (
+	"testing"
+
+	"gotest.tools/v3/icmd"
+)
+
+func TestStatBucket(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	cmd := s5cmd("stat", "s3://"+bucket)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	// the configuration items other than the region and the versioning are
+	// not supported by the fake S3 server.
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals("Bucket: %v", bucket),
+		1: match(`^Region: \S+$`),
+		2: equals("Versioning: Unversioned"),
+		3: prefix("Object lock: "),
+		4: prefix("Encryption: "),
+		5: prefix("Public access block: "),
+		6: prefix("Lifecycle rules: "),
+		7: prefix("CORS rules: "),
+		8: prefix("Policy: "),
+	}, strictLineCheck(true))
+}
+
+func TestStatBucketJSON(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	cmd := s5cmd("--json", "stat", "s3://"+bucket)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: prefix(`{"v":2,"bucket":%q,"region":`, bucket),
+	}, strictLineCheck(true))
+}
+
+func TestStatNonExistingBucket(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd := setup(t)
+	bucket := s3BucketFromTestName(t)
+
+	cmd := s5cmd("stat", "s3://"+bucket)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "stat s3://%v": bucket %q does not exist`, bucket, bucket),
+	}, strictLineCheck(true))
+}
+
+func TestStatObject(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd := setup(t)
+	bucket := s3BucketFromTestName(t)
+
+	cmd := s5cmd("stat", "s3://"+bucket+"/object")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "stat s3://%v/object": argument must be a bucket, e.g. s3://bucket`, bucket),
+	}, strictLineCheck(true))
+}
@@ -2,8 +2,11 @@ package e2e
 
 import (
 	"fmt"
+	"os"
 	"path/filepath"
+	"regexp"
 	"runtime"
+	"strings"
 	"testing"
 	"time"
 
@@ -406,6 +409,58 @@ func TestSyncS3BucketToEmptyS3Bucket(t *testing.T) {
 	}
 }
 
+// sync s3://bucket.with.dots/* s3://bucket/
+func TestSyncS3BucketWithDotsToS3Bucket(t *testing.T) {
+	t.Parallel()
+	s3client, s5cmd := setup(t)
+
+	// the source bucket uses path-style and the destination bucket uses the
+	// style of the endpoint.
+	bucket := addRandomSuffixTo("src.with.dots")
+	dstbucket := s3BucketFromTestNameWithPrefix(t, "dst")
+
+	createBucket(t, s3client, bucket)
+	createBucket(t, s3client, dstbucket)
+
+	S3Content := map[string]string{
+		"testfile.txt":    "S: this is a test file",
+		"abc/def/test.py": "S: file in nested folders",
+	}
+
+	for filename, content := range S3Content {
+		putFile(t, s3client, bucket, filename, content)
+	}
+	putFile(t, s3client, dstbucket, "readme.md", "D: this is a readme file")
+
+	bucketPath := fmt.Sprintf("s3://%v", bucket)
+	src := fmt.Sprintf("%v/*", bucketPath)
+	dst := fmt.Sprintf("s3://%v/", dstbucket)
+
+	cmd := s5cmd("sync", "--delete", src, dst)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %v/abc/def/test.py %vabc/def/test.py`, bucketPath, dst),
+		1: equals(`cp %v/testfile.txt %vtestfile.txt`, bucketPath, dst),
+		2: equals(`rm %vreadme.md`, dst),
+	}, sortInput(true))
+
+	// assert s3 objects in source bucket.
+	for key, content := range S3Content {
+		assert.Assert(t, ensureS3Object(s3client, bucket, key, content))
+	}
+
+	// assert s3 objects in dest bucket
+	for key, content := range S3Content {
+		assert.Assert(t, ensureS3Object(s3client, dstbucket, key, content))
+	}
+
+	err := ensureS3Object(s3client, dstbucket, "readme.md", "D: this is a readme file")
+	assertError(t, err, errS3NoSuchKey)
+}
+
 // sync folder/ s3://bucket (source older, same objects)
 func TestSyncLocalFolderToS3BucketSameObjectsSourceOlder(t *testing.T) {
 	t.Parallel()
@@ -458,10 +513,10 @@ func TestSyncLocalFolderToS3BucketSameObjectsSourceOlder(t *testing.T) {
 	result.Assert(t, icmd.Success)
 
 	assertLines(t, result.Stdout(), map[int]compareFunc{
-		0: equals(`DEBUG "sync %va/another_test_file.txt %va/another_test_file.txt": object is newer or same age and object size matches`, src, dst),
-		1: equals(`DEBUG "sync %vmain.py %vmain.py": object is newer or same age and object size matches`, src, dst),
-		2: equals(`DEBUG "sync %vreadme.md %vreadme.md": object is newer or same age and object size matches`, src, dst),
-		3: equals(`DEBUG "sync %vtestfile.txt %vtestfile.txt": object is newer or same age and object size matches`, src, dst),
+		0: prefix(`DEBUG "sync %va/another_test_file.txt %va/another_test_file.txt": object is newer or same age and object size matches (source: size=`, src, dst),
+		1: prefix(`DEBUG "sync %vmain.py %vmain.py": object is newer or same age and object size matches (source: size=`, src, dst),
+		2: prefix(`DEBUG "sync %vreadme.md %vreadme.md": object is newer or same age and object size matches (source: size=`, src, dst),
+		3: prefix(`DEBUG "sync %vtestfile.txt %vtestfile.txt": object is newer or same age and object size matches (source: size=`, src, dst),
 	}, sortInput(true))
 
 	// expected folder structure
@@ -613,10 +668,10 @@ func TestSyncS3BucketToLocalFolderSameObjectsSourceOlder(t *testing.T) {
 	result.Assert(t, icmd.Success)
 
 	assertLines(t, result.Stdout(), map[int]compareFunc{
-		0: equals(`DEBUG "sync %v/a/another_test_file.txt %va/another_test_file.txt": object is newer or same age and object size matches`, bucketPath, dst),
-		1: equals(`DEBUG "sync %v/main.py %vmain.py": object is newer or same age and object size matches`, bucketPath, dst),
-		2: equals(`DEBUG "sync %v/readme.md %vreadme.md": object is newer or same age and object size matches`, bucketPath, dst),
-		3: equals(`DEBUG "sync %v/testfile.txt %vtestfile.txt": object is newer or same age and object size matches`, bucketPath, dst),
+		0: prefix(`DEBUG "sync %v/a/another_test_file.txt %va/another_test_file.txt": object is newer or same age and object size matches (source: size=`, bucketPath, dst),
+		1: prefix(`DEBUG "sync %v/main.py %vmain.py": object is newer or same age and object size matches (source: size=`, bucketPath, dst),
+		2: prefix(`DEBUG "sync %v/readme.md %vreadme.md": object is newer or same age and object size matches (source: size=`, bucketPath, dst),
+		3: prefix(`DEBUG "sync %v/testfile.txt %vtestfile.txt": object is newer or same age and object size matches (source: size=`, bucketPath, dst),
 	}, sortInput(true))
 
 	// expected folder structure without the timestamp.
@@ -833,10 +888,10 @@ func TestSyncS3BucketToS3BucketSameSizesSourceOlder(t *testing.T) {
 	result.Assert(t, icmd.Success)
 
 	assertLines(t, result.Stdout(), map[int]compareFunc{
-		0: equals(`DEBUG "sync %v/a/another_test_file.txt %va/another_test_file.txt": object is newer or same age and object size matches`, bucketPath, dst),
-		1: equals(`DEBUG "sync %v/main.py %vmain.py": object is newer or same age and object size matches`, bucketPath, dst),
-		2: equals(`DEBUG "sync %v/readme.md %vreadme.md": object is newer or same age and object size matches`, bucketPath, dst),
-		3: equals(`DEBUG "sync %v/testfile.txt %vtestfile.txt": object is newer or same age and object size matches`, bucketPath, dst),
+		0: prefix(`DEBUG "sync %v/a/another_test_file.txt %va/another_test_file.txt": object is newer or same age and object size matches (source: size=`, bucketPath, dst),
+		1: prefix(`DEBUG "sync %v/main.py %vmain.py": object is newer or same age and object size matches (source: size=`, bucketPath, dst),
+		2: prefix(`DEBUG "sync %v/readme.md %vreadme.md": object is newer or same age and object size matches (source: size=`, bucketPath, dst),
+		3: prefix(`DEBUG "sync %v/testfile.txt %vtestfile.txt": object is newer or same age and object size matches (source: size=`, bucketPath, dst),
 	}, sortInput(true))
 
 	// assert s3 objects in source
@@ -894,9 +949,9 @@ func TestSyncS3BucketToLocalFolderSameObjectsSizeOnly(t *testing.T) {
 	result.Assert(t, icmd.Success)
 
 	assertLines(t, result.Stdout(), map[int]compareFunc{
-		0: equals(`DEBUG "sync %v/a/another_test_file.txt %va/another_test_file.txt": object size matches`, bucketPath, dst),
-		1: equals(`DEBUG "sync %v/readme.md %vreadme.md": object size matches`, bucketPath, dst),
-		2: equals(`DEBUG "sync %v/testfile.txt %vtestfile.txt": object size matches`, bucketPath, dst),
+		0: prefix(`DEBUG "sync %v/a/another_test_file.txt %va/another_test_file.txt": object size matches (source: size=`, bucketPath, dst),
+		1: prefix(`DEBUG "sync %v/readme.md %vreadme.md": object size matches (source: size=`, bucketPath, dst),
+		2: prefix(`DEBUG "sync %v/testfile.txt %vtestfile.txt": object size matches (source: size=`, bucketPath, dst),
 		3: equals(`cp %v/abc/def/main.py %vabc/def/main.py`, bucketPath, dst),
 		4: equals(`cp %v/test.py %vtest.py`, bucketPath, dst),
 	}, sortInput(true))
@@ -973,9 +1028,9 @@ func TestSyncLocalFolderToS3BucketSameObjectsSizeOnly(t *testing.T) {
 	result.Assert(t, icmd.Success)
 
 	assertLines(t, result.Stdout(), map[int]compareFunc{
-		0: equals(`DEBUG "sync %va/another_test_file.txt %va/another_test_file.txt": object size matches`, src, dst),
-		1: equals(`DEBUG "sync %vreadme.md %vreadme.md": object size matches`, src, dst),
-		2: equals(`DEBUG "sync %vtest.py %vtest.py": object size matches`, src, dst),
+		0: prefix(`DEBUG "sync %va/another_test_file.txt %va/another_test_file.txt": object size matches (source: size=`, src, dst),
+		1: prefix(`DEBUG "sync %vreadme.md %vreadme.md": object size matches (source: size=`, src, dst),
+		2: prefix(`DEBUG "sync %vtest.py %vtest.py": object size matches (source: size=`, src, dst),
 		3: equals(`cp %vabc/def/main.py %vabc/def/main.py`, src, dst),
 		4: equals(`cp %vtestfile.txt %vtestfile.txt`, src, dst),
 	}, sortInput(true))
@@ -1048,9 +1103,9 @@ func TestSyncS3BucketToS3BucketSizeOnly(t *testing.T) {
 	result.Assert(t, icmd.Success)
 
 	assertLines(t, result.Stdout(), map[int]compareFunc{
-		0: equals(`DEBUG "sync %v/a/another_test_file.txt %va/another_test_file.txt": object size matches`, bucketPath, dst),
-		1: equals(`DEBUG "sync %v/readme.md %vreadme.md": object size matches`, bucketPath, dst),
-		2: equals(`DEBUG "sync %v/testfile.txt %vtestfile.txt": object size matches`, bucketPath, dst),
+		0: prefix(`DEBUG "sync %v/a/another_test_file.txt %va/another_test_file.txt": object size matches (source: size=`, bucketPath, dst),
+		1: prefix(`DEBUG "sync %v/readme.md %vreadme.md": object size matches (source: size=`, bucketPath, dst),
+		2: prefix(`DEBUG "sync %v/testfile.txt %vtestfile.txt": object size matches (source: size=`, bucketPath, dst),
 		3: equals(`cp %v/main.py %vmain.py`, bucketPath, dst),
 	}, sortInput(true))
 
@@ -1072,6 +1127,134 @@ func TestSyncS3BucketToS3BucketSizeOnly(t *testing.T) {
 	}
 }
 
+// sync --size-only --show-skips s3://bucket/* s3://destbucket/
+func TestSyncS3BucketToS3BucketSizeOnlyWithShowSkips(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	dstbucket := s3BucketFromTestNameWithPrefix(t, "dst")
+	createBucket(t, s3client, bucket)
+	createBucket(t, s3client, dstbucket)
+
+	putFile(t, s3client, bucket, "testfile.txt", "S: this is a test file")
+	putFile(t, s3client, dstbucket, "testfile.txt", "D: this is a test file")
+
+	src := fmt.Sprintf("s3://%v/*", bucket)
+	dst := fmt.Sprintf("s3://%v/", dstbucket)
+
+	cmd := s5cmd("sync", "--size-only", "--show-skips", src, dst)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: match(fmt.Sprintf(`^skip s3://%v/testfile.txt s3://%v/testfile.txt: object size matches \(source: size=22 mtime=\S+ etag=[0-9a-f]+, destination: size=22 mtime=\S+ etag=[0-9a-f]+\)$`, bucket, dstbucket)),
+	})
+
+	cmd = s5cmd("--json", "sync", "--size-only", "--show-skips", src, dst)
+	result = icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: contains(`"operation":"skip","source":"s3://%v/testfile.txt","destination":"s3://%v/testfile.txt","reason":"size_match","message":"object size matches","source_attributes":{"size":22,`, bucket, dstbucket),
+	})
+
+	// assert s3 object in destination is not overwritten
+	assert.Assert(t, ensureS3Object(s3client, dstbucket, "testfile.txt", "D: this is a test file"))
+}
+
+// sync --size-only --delete --compare-key-strip-prefix v1/ s3://bucket/* s3://destbucket/
+func TestSyncS3BucketToS3BucketWithCompareKeyStripPrefix(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	dstbucket := s3BucketFromTestNameWithPrefix(t, "dst")
+	createBucket(t, s3client, bucket)
+	createBucket(t, s3client, dstbucket)
+
+	srcContent := map[string]string{
+		"v1/data/a.txt": "S: file a",
+		"v1/data/b.txt": "S: file b",
+		"readme.md":     "S: readme",
+	}
+	dstContent := map[string]string{
+		"data/a.txt":     "D: file a",
+		"data/stale.txt": "D: stale file",
+	}
+
+	for key, content := range srcContent {
+		putFile(t, s3client, bucket, key, content)
+	}
+	for key, content := range dstContent {
+		putFile(t, s3client, dstbucket, key, content)
+	}
+
+	src := fmt.Sprintf("s3://%v/*", bucket)
+	dst := fmt.Sprintf("s3://%v/", dstbucket)
+
+	cmd := s5cmd("--log", "debug", "sync", "--size-only", "--delete", "--compare-key-strip-prefix", "v1", src, dst)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	// "v1/data/a.txt" is matched with "data/a.txt", deletions target the
+	// actual keys of the destination objects.
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: prefix(`DEBUG "sync s3://%v/v1/data/a.txt s3://%v/data/a.txt": object size matches (source: size=9 `, bucket, dstbucket),
+		1: equals(`cp s3://%v/readme.md s3://%v/readme.md`, bucket, dstbucket),
+		2: equals(`cp s3://%v/v1/data/b.txt s3://%v/data/b.txt`, bucket, dstbucket),
+		3: equals(`rm s3://%v/data/stale.txt`, dstbucket),
+	}, sortInput(true))
+
+	assert.Assert(t, ensureS3Object(s3client, dstbucket, "data/a.txt", "D: file a"))
+	assert.Assert(t, ensureS3Object(s3client, dstbucket, "data/b.txt", "S: file b"))
+	assert.Assert(t, ensureS3Object(s3client, dstbucket, "readme.md", "S: readme"))
+
+	err := ensureS3Object(s3client, dstbucket, "data/stale.txt", "D: stale file")
+	assertError(t, err, errS3NoSuchKey)
+
+	err = ensureS3Object(s3client, dstbucket, "v1/data/b.txt", "S: file b")
+	assertError(t, err, errS3NoSuchKey)
+}
+
+// sync --compare-key-strip-prefix v1/ s3://bucket/* s3://destbucket/
+func TestSyncS3BucketToS3BucketWithCompareKeyStripPrefixCollision(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	dstbucket := s3BucketFromTestNameWithPrefix(t, "dst")
+	createBucket(t, s3client, bucket)
+	createBucket(t, s3client, dstbucket)
+
+	putFile(t, s3client, bucket, "readme.md", "S: readme")
+	putFile(t, s3client, bucket, "v1/readme.md", "S: v1 readme")
+
+	src := fmt.Sprintf("s3://%v/*", bucket)
+	dst := fmt.Sprintf("s3://%v/", dstbucket)
+
+	cmd := s5cmd("sync", "--compare-key-strip-prefix", "v1/", src, dst)
+	result := icmd.RunCmd(cmd)
+
+	// like the other listing errors, colliding objects are reported but they
+	// don't fail the sync of the others.
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: contains(`has the same key as s3://%v/`, bucket),
+	})
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: prefix(`cp s3://%v/`, bucket),
+	})
+}
+
 // sync --delete s3://bucket/* .
 func TestSyncS3BucketToLocalWithDelete(t *testing.T) {
 	t.Parallel()
@@ -1563,6 +1746,49 @@ func TestSyncLocalFilesWithNoFollowSymlinksToS3Bucket(t *testing.T) {
 	assertLines(t, result.Stdout(), map[int]compareFunc{})
 }
 
+// sync --on-broken-symlink upload-as-link dir/ s3://bucket/
+func TestSyncLocalFilesWithBrokenSymlinksToS3Bucket(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	workdir := fs.NewDir(t, "somedir", fs.WithFile("file.txt", "content"))
+	defer workdir.Remove()
+
+	assert.NilError(t, os.Symlink("missing.txt", workdir.Join("link")))
+
+	src := fmt.Sprintf("%v/", filepath.ToSlash(workdir.Path()))
+	dst := fmt.Sprintf("s3://%v/", bucket)
+
+	// broken symbolic links are skipped by default
+	cmd := s5cmd("sync", src, dst)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %vfile.txt %vfile.txt`, src, dst),
+	})
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`WARNING skipping broken symbolic link %vlink -> missing.txt`, src),
+	})
+
+	cmd = s5cmd("sync", "--on-broken-symlink", "upload-as-link", src, dst)
+	result = icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %vlink %vlink`, src, dst),
+	})
+
+	assert.Assert(t, ensureS3Object(s3client, bucket, "link", "missing.txt",
+		ensureMetadata("S5cmd-Symlink-Target", "missing.txt")))
+}
+
 // sync --exclude pattern s3://bucket/* s3://anotherbucket/prefix/
 func TestSyncS3ObjectsIntoAnotherBucketWithExcludeFilters(t *testing.T) {
 	t.Parallel()
@@ -1645,6 +1871,147 @@ func TestSyncS3ObjectsIntoAnotherBucketWithExcludeFilters(t *testing.T) {
 	}
 }
 
+// sync --delete --include "*.parquet" dir/ s3://bucket/prefix/
+func TestSyncLocalFolderToS3BucketWithIncludeFilters(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	timeSource := newFixedTimeSource(now)
+	s3client, s5cmd := setup(t, withTimeSource(timeSource))
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	const content = "this is a file content"
+
+	timestamp := fs.WithTimestamps(now.Add(-time.Minute), now.Add(-time.Minute))
+	workdir := fs.NewDir(t, "somedir",
+		fs.WithFile("a.parquet", content, timestamp),
+		fs.WithFile("b.csv", content, timestamp),
+		fs.WithDir("nested",
+			fs.WithFile("c.parquet", content, timestamp),
+			fs.WithFile("d.txt", content, timestamp),
+		),
+	)
+	defer workdir.Remove()
+
+	putFile(t, s3client, bucket, "prefix/stale.parquet", content)
+	putFile(t, s3client, bucket, "prefix/unrelated.csv", content)
+
+	src := filepath.ToSlash(fmt.Sprintf("%v/", workdir.Path()))
+	dst := fmt.Sprintf("s3://%v/prefix/", bucket)
+
+	cmd := s5cmd("sync", "--delete", "--include", "*.parquet", src, dst)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %va.parquet %va.parquet`, src, dst),
+		1: equals(`cp %vnested/c.parquet %vnested/c.parquet`, src, dst),
+		2: equals(`rm %vstale.parquet`, dst),
+	}, sortInput(true), strictLineCheck(true))
+
+	for _, key := range []string{"prefix/a.parquet", "prefix/nested/c.parquet", "prefix/unrelated.csv"} {
+		assert.Assert(t, ensureS3Object(s3client, bucket, key, content))
+	}
+	for _, key := range []string{"prefix/stale.parquet", "prefix/b.csv", "prefix/nested/d.txt"} {
+		err := ensureS3Object(s3client, bucket, key, content)
+		assertError(t, err, errS3NoSuchKey)
+	}
+}
+
+// sync --delete --storage-class-filter !STANDARD s3://bucket/* s3://destbucket/
+func TestSyncS3BucketToS3BucketWithStorageClassFilter(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	srcbucket := s3BucketFromTestName(t)
+	dstbucket := "copy-" + srcbucket
+	createBucket(t, s3client, srcbucket)
+	createBucket(t, s3client, dstbucket)
+
+	const content = "this is a file content"
+	putFile(t, s3client, srcbucket, "a.txt", content)
+	putFile(t, s3client, dstbucket, "stale.txt", content)
+
+	src := fmt.Sprintf("s3://%v/*", srcbucket)
+	dst := fmt.Sprintf("s3://%v/", dstbucket)
+
+	// the objects in destination which are not in the filtered storage
+	// classes are not deleted.
+	cmd := s5cmd("sync", "--delete", "--storage-class-filter", "!STANDARD", src, dst)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+	assertLines(t, result.Stdout(), map[int]compareFunc{}, strictLineCheck(true))
+
+	assert.Assert(t, ensureS3Object(s3client, dstbucket, "stale.txt", content))
+	err := ensureS3Object(s3client, dstbucket, "a.txt", content)
+	assertError(t, err, errS3NoSuchKey)
+
+	cmd = s5cmd("sync", "--delete", "--storage-class-filter", "STANDARD", src, dst)
+	result = icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp s3://%v/a.txt s3://%v/a.txt`, srcbucket, dstbucket),
+		1: equals(`rm s3://%v/stale.txt`, dstbucket),
+	}, sortInput(true), strictLineCheck(true))
+
+	assert.Assert(t, ensureS3Object(s3client, dstbucket, "a.txt", content))
+	err = ensureS3Object(s3client, dstbucket, "stale.txt", content)
+	assertError(t, err, errS3NoSuchKey)
+}
+
+// sync --delete --exclude "logs/*" dir/ s3://bucket/prefix/
+func TestSyncLocalFolderToS3BucketWithDeleteAndExcludeFilters(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	timeSource := newFixedTimeSource(now)
+	s3client, s5cmd := setup(t, withTimeSource(timeSource))
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	const content = "this is a file content"
+
+	timestamp := fs.WithTimestamps(now.Add(-time.Minute), now.Add(-time.Minute))
+	workdir := fs.NewDir(t, "somedir",
+		fs.WithFile("a.txt", content, timestamp),
+		fs.WithDir("logs", fs.WithFile("local.log", content, timestamp)),
+	)
+	defer workdir.Remove()
+
+	putFile(t, s3client, bucket, "prefix/stale.txt", content)
+	putFile(t, s3client, bucket, "prefix/logs/remote.log", content)
+	putFile(t, s3client, bucket, "prefix/logs/nested/remote.log", content)
+
+	src := filepath.ToSlash(fmt.Sprintf("%v/", workdir.Path()))
+	dst := fmt.Sprintf("s3://%v/prefix/", bucket)
+
+	cmd := s5cmd("sync", "--delete", "--exclude", "logs/*", src, dst)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %va.txt %va.txt`, src, dst),
+		1: equals(`rm %vstale.txt`, dst),
+	}, sortInput(true), strictLineCheck(true))
+
+	// the excluded objects only in destination are not deleted.
+	for _, key := range []string{"prefix/a.txt", "prefix/logs/remote.log", "prefix/logs/nested/remote.log"} {
+		assert.Assert(t, ensureS3Object(s3client, bucket, key, content))
+	}
+	for _, key := range []string{"prefix/stale.txt", "prefix/logs/local.log"} {
+		err := ensureS3Object(s3client, bucket, key, content)
+		assertError(t, err, errS3NoSuchKey)
+	}
+}
+
 // sync --exclude "*.gz" dir s3://bucket/
 // sync --exclude "*.gz" dir/ s3://bucket/
 // sync --exclude "*.gz" dir/* s3://bucket/
@@ -1777,9 +2144,12 @@ func TestIssue435(t *testing.T) {
 
 	assertLines(t, result.Stderr(), map[int]compareFunc{})
 
-	expected := make(map[int]compareFunc)
+	// the objects are deleted in the batches of the default delete batch size.
+	expected := map[int]compareFunc{
+		0: equals("DEBUG deleting %v objects in destination with 10 rm commands of at most 1000 objects", filecount),
+	}
 	for i := 0; i < filecount; i++ {
-		expected[i] = contains("rm s3://%v/file_%06d", bucket, i)
+		expected[i+1] = contains("rm s3://%v/file_%06d", bucket, i)
 	}
 
 	assertLines(t, result.Stdout(), expected, sortInput(true))
@@ -1793,3 +2163,3180 @@ func TestIssue435(t *testing.T) {
 		assertError(t, err, errS3NoSuchKey)
 	}
 }
+
+func TestSyncWithVerifyBucketFailsForNonexistentSourceBucket(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+
+	workdir := fs.NewDir(t, t.Name())
+	defer workdir.Remove()
+
+	srcpath := fmt.Sprintf("s3://%v/*", bucket)
+	dstpath := filepath.ToSlash(workdir.Path()) + "/"
+
+	cmd := s5cmd("sync", "--verify-bucket", srcpath, dstpath)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "sync --verify-bucket=true %v %v": source bucket %q does not exist`, srcpath, dstpath, bucket),
+	}, strictLineCheck(true))
+}
+
+// sync --prefer-source folder/ s3://bucket (source older, same sizes)
+func TestSyncLocalFolderToS3BucketPreferSource(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	timeSource := newFixedTimeSource(now)
+	s3client, s5cmd := setup(t, withTimeSource(timeSource))
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	// local files are 1 minute older than the remotes
+	timestamp := fs.WithTimestamps(
+		now.Add(-time.Minute), // access time
+		now.Add(-time.Minute), // mod time
+	)
+
+	workdir := fs.NewDir(t, "somedir",
+		fs.WithFile("main.py", "S: this is a python file", timestamp),
+		fs.WithFile("testfile.txt", "S: this is a test file", timestamp),
+	)
+	defer workdir.Remove()
+
+	putFile(t, s3client, bucket, "main.py", "D: this is a python file")
+	putFile(t, s3client, bucket, "testfile.txt", "D: this is a test file")
+
+	src := filepath.ToSlash(fmt.Sprintf("%v/", workdir.Path()))
+	dst := fmt.Sprintf("s3://%v/", bucket)
+
+	cmd := s5cmd("sync", "--prefer-source", src, dst)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %vmain.py %vmain.py`, src, dst),
+		1: equals(`cp %vtestfile.txt %vtestfile.txt`, src, dst),
+	}, sortInput(true))
+
+	assert.Assert(t, ensureS3Object(s3client, bucket, "main.py", "S: this is a python file"))
+	assert.Assert(t, ensureS3Object(s3client, bucket, "testfile.txt", "S: this is a test file"))
+}
+
+// sync --skew-tolerance 2s folder/ s3://bucket (source newer within tolerance)
+func TestSyncLocalFolderToS3BucketWithSkewTolerance(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	timeSource := newFixedTimeSource(now)
+	s3client, s5cmd := setup(t, withTimeSource(timeSource))
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	// local file is 1 second newer than the remote
+	timestamp := fs.WithTimestamps(
+		now.Add(time.Second), // access time
+		now.Add(time.Second), // mod time
+	)
+
+	workdir := fs.NewDir(t, "somedir",
+		fs.WithFile("main.py", "S: this is a python file", timestamp),
+	)
+	defer workdir.Remove()
+
+	putFile(t, s3client, bucket, "main.py", "D: this is a python file")
+
+	src := filepath.ToSlash(fmt.Sprintf("%v/", workdir.Path()))
+	dst := fmt.Sprintf("s3://%v/", bucket)
+
+	cmd := s5cmd("--log", "debug", "sync", "--skew-tolerance", "2s", src, dst)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: prefix(`DEBUG "sync %vmain.py %vmain.py": object is newer or same age and object size matches (source: size=`, src, dst),
+	}, strictLineCheck(true))
+
+	assert.Assert(t, ensureS3Object(s3client, bucket, "main.py", "D: this is a python file"))
+}
+
+// sync --assume-fs-precision 2s s3://bucket/* folder/ (source newer within precision)
+func TestSyncS3BucketToLocalWithAssumedFSPrecision(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	timeSource := newFixedTimeSource(now)
+	s3client, s5cmd := setup(t, withTimeSource(timeSource))
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	// local file is 1 second older than the remote, as it would be if it was
+	// stored in 2 seconds precision.
+	timestamp := fs.WithTimestamps(
+		now.Add(-time.Second), // access time
+		now.Add(-time.Second), // mod time
+	)
+
+	workdir := fs.NewDir(t, "somedir",
+		fs.WithFile("main.py", "D: this is a python file", timestamp),
+	)
+	defer workdir.Remove()
+
+	putFile(t, s3client, bucket, "main.py", "S: this is a python file")
+
+	src := fmt.Sprintf("s3://%v/", bucket)
+	dst := filepath.ToSlash(fmt.Sprintf("%v/", workdir.Path()))
+
+	cmd := s5cmd("--log", "debug", "sync", "--assume-fs-precision", "2s", src+"*", dst)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: prefix(`DEBUG "sync %vmain.py %vmain.py": object is newer or same age and object size matches (source: size=`, src, dst),
+	}, strictLineCheck(true))
+
+	expected := fs.Expected(t, fs.WithFile("main.py", "D: this is a python file"))
+	assert.Assert(t, fs.Equal(workdir.Path(), expected))
+}
+
+// sync --delete --assume-case-insensitive s3://bucket/* folder/
+func TestSyncS3BucketToLocalWithAssumedCaseInsensitive(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	timeSource := newFixedTimeSource(now)
+	s3client, s5cmd := setup(t, withTimeSource(timeSource))
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	// local files are newer than the remote ones.
+	timestamp := fs.WithTimestamps(
+		now.Add(time.Minute),
+		now.Add(time.Minute),
+	)
+
+	workdir := fs.NewDir(t, "somedir",
+		fs.WithFile("readme.md", "D: this is a readme file", timestamp),
+		fs.WithDir("docs",
+			fs.WithFile("guide.txt", "D: this is a guide", timestamp),
+			timestamp,
+		),
+	)
+	defer workdir.Remove()
+
+	putFile(t, s3client, bucket, "README.md", "S: this is a readme file")
+	putFile(t, s3client, bucket, "Docs/Guide.txt", "S: this is a guide")
+	putFile(t, s3client, bucket, "new.txt", "S: this is a new file")
+
+	src := fmt.Sprintf("s3://%v/", bucket)
+	dst := filepath.ToSlash(fmt.Sprintf("%v/", workdir.Path()))
+
+	cmd := s5cmd("sync", "--delete", "--assume-case-insensitive", src+"*", dst)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	// the files which only differ from the objects in case are neither
+	// copied nor deleted.
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %vnew.txt %vnew.txt`, src, dst),
+	}, strictLineCheck(true))
+
+	expected := fs.Expected(t,
+		fs.WithFile("readme.md", "D: this is a readme file"),
+		fs.WithFile("new.txt", "S: this is a new file"),
+		fs.WithDir("docs",
+			fs.WithFile("guide.txt", "D: this is a guide"),
+		),
+	)
+	assert.Assert(t, fs.Equal(workdir.Path(), expected))
+}
+
+// sync --assume-case-insensitive s3://bucket/* folder/ (objects differing in case)
+func TestSyncS3BucketToLocalWithAssumedCaseInsensitiveCollision(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	putFile(t, s3client, bucket, "A.txt", "upper")
+	putFile(t, s3client, bucket, "a.txt", "lower")
+
+	workdir := fs.NewDir(t, "somedir")
+	defer workdir.Remove()
+
+	src := fmt.Sprintf("s3://%v/", bucket)
+	dst := filepath.ToSlash(fmt.Sprintf("%v/", workdir.Path()))
+
+	cmd := s5cmd("sync", "--assume-case-insensitive", src+"*", dst)
+	result := icmd.RunCmd(cmd)
+
+	// like the keys colliding after their prefix is stripped, only the first
+	// one of the objects is synced and the others are reported.
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %vA.txt %vA.txt`, src, dst),
+	}, strictLineCheck(true))
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "sync --assume-case-insensitive=true %v* %v": object %va.txt has the same key as %vA.txt on the case-insensitive destination`, src, dst, src, src),
+	}, strictLineCheck(true))
+
+	expected := fs.Expected(t, fs.WithFile("A.txt", "upper"))
+	assert.Assert(t, fs.Equal(workdir.Path(), expected))
+}
+
+func TestSyncAssumedFilesystemValidation(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd := setup(t)
+
+	testcases := []struct {
+		args     []string
+		expected string
+	}{
+		{
+			args:     []string{"--assume-case-insensitive", "dir/", "s3://bucket/"},
+			expected: `ERROR "sync --assume-case-insensitive=true dir/ s3://bucket/": "assume-case-insensitive" flag requires local destination`,
+		},
+		{
+			args:     []string{"--assume-fs-precision", "2s", "dir/", "s3://bucket/"},
+			expected: `ERROR "sync --assume-fs-precision=2s dir/ s3://bucket/": "assume-fs-precision" flag requires local destination`,
+		},
+		{
+			args:     []string{"--assume-fs-precision", "-1s", "s3://bucket/*", "dir/"},
+			expected: `ERROR "sync --assume-fs-precision=-1s s3://bucket/* dir/": "assume-fs-precision" flag cannot be negative`,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.expected, func(t *testing.T) {
+			cmd := s5cmd(append([]string{"sync"}, tc.args...)...)
+			result := icmd.RunCmd(cmd)
+
+			result.Assert(t, icmd.Expected{ExitCode: 1})
+
+			assertLines(t, result.Stderr(), map[int]compareFunc{
+				0: equals(tc.expected),
+			}, strictLineCheck(true))
+		})
+	}
+}
+
+func TestSyncPreferSourceWithSizeOnly(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd := setup(t)
+
+	cmd := s5cmd("sync", "--prefer-source", "--size-only", "folder/", "s3://bucket/")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "sync --size-only=true --prefer-source=true folder/ s3://bucket/": it is not allowed to combine "size-only" and "prefer-source" flags`),
+	}, strictLineCheck(true))
+}
+
+// --dry-run sync --diff --delete s3://bucket/* s3://destbucket/
+func TestSyncS3BucketToS3BucketDryRunDiff(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	timeSource := newFixedTimeSource(now)
+	s3client, s5cmd := setup(t, withTimeSource(timeSource))
+
+	bucket := s3BucketFromTestName(t)
+	dstbucket := s3BucketFromTestNameWithPrefix(t, "dst")
+
+	createBucket(t, s3client, bucket)
+	createBucket(t, s3client, dstbucket)
+
+	destS3Content := map[string]string{
+		"changed.txt":   "old content",
+		"same.txt":      "same content",
+		"obsolete.txt":  "obsolete content",
+		"a/changed.txt": "D: content",
+	}
+
+	sourceS3Content := map[string]string{
+		"changed.txt":   "new and longer content",
+		"same.txt":      "same content",
+		"new.txt":       "new content",
+		"a/changed.txt": "S: content",
+	}
+
+	timeSource.Advance(-time.Minute)
+	for filename, content := range destS3Content {
+		putFile(t, s3client, dstbucket, filename, content)
+	}
+
+	timeSource.Advance(time.Minute)
+	for filename, content := range sourceS3Content {
+		if filename == "same.txt" {
+			continue
+		}
+		putFile(t, s3client, bucket, filename, content)
+	}
+
+	// make the same object older in source, so that it is not synced.
+	timeSource.Advance(-2 * time.Minute)
+	putFile(t, s3client, bucket, "same.txt", sourceS3Content["same.txt"])
+
+	src := fmt.Sprintf("s3://%v/*", bucket)
+	dst := fmt.Sprintf("s3://%v/", dstbucket)
+
+	cmd := s5cmd("--dry-run", "sync", "--diff", "--delete", src, dst)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`M %va/changed.txt (source is newer by 1m0s)`, dst),
+		1: equals(`M %vchanged.txt (size 11 -> 22, source is newer by 1m0s)`, dst),
+		2: equals(`+ %vnew.txt (only in source)`, dst),
+		3: equals(`- %vobsolete.txt (only in destination)`, dst),
+		4: equals(`1 new, 1 deleted, 2 modified`),
+	}, strictLineCheck(true))
+
+	// nothing is changed in destination.
+	for key, content := range destS3Content {
+		assert.Assert(t, ensureS3Object(s3client, dstbucket, key, content))
+	}
+}
+
+// sync --dry-run --delete s3://bucket/* s3://destbucket/
+func TestSyncS3BucketToS3BucketPrintPlan(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	timeSource := newFixedTimeSource(now)
+	s3client, s5cmd := setup(t, withTimeSource(timeSource))
+
+	bucket := s3BucketFromTestName(t)
+	dstbucket := s3BucketFromTestNameWithPrefix(t, "dst")
+
+	createBucket(t, s3client, bucket)
+	createBucket(t, s3client, dstbucket)
+
+	destS3Content := map[string]string{
+		"changed.txt":  "old content",
+		"newer.txt":    "D: content",
+		"same.txt":     "same content",
+		"obsolete.txt": "obsolete content",
+	}
+
+	timeSource.Advance(-time.Minute)
+	for filename, content := range destS3Content {
+		putFile(t, s3client, dstbucket, filename, content)
+	}
+
+	timeSource.Advance(time.Minute)
+	putFile(t, s3client, bucket, "changed.txt", "new and longer content")
+	putFile(t, s3client, bucket, "newer.txt", "S: content")
+	putFile(t, s3client, bucket, "new.txt", "new content")
+
+	// make the same object older in source, so that it is not synced.
+	timeSource.Advance(-2 * time.Minute)
+	putFile(t, s3client, bucket, "same.txt", "same content")
+
+	src := fmt.Sprintf("s3://%v/", bucket)
+	dst := fmt.Sprintf("s3://%v/", dstbucket)
+
+	cmd := s5cmd("sync", "--dry-run", "--delete", src+"*", dst)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp --raw=true "%vchanged.txt" "%vchanged.txt"`, src, dst),
+		1: equals(`cp --raw=true "%vnew.txt" "%vnew.txt"`, src, dst),
+		2: equals(`cp --raw=true "%vnewer.txt" "%vnewer.txt"`, src, dst),
+		3: equals(`rm --raw=true "%vobsolete.txt"`, dst),
+	}, strictLineCheck(true), sortInput(true))
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`dry-run: 1 objects to upload, 2 to update, 1 to delete, 43 bytes to transfer`),
+	}, strictLineCheck(true))
+
+	// nothing is changed in destination.
+	for key, content := range destS3Content {
+		assert.Assert(t, ensureS3Object(s3client, dstbucket, key, content))
+	}
+	err := ensureS3Object(s3client, dstbucket, "new.txt", "")
+	assertError(t, err, errS3NoSuchKey)
+
+	cmd = s5cmd("--json", "sync", "--dry-run", "--delete", src+"*", dst)
+	result = icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`{"v":2,"operation":"sync-plan","action":"cp","source":"%[1]vchanged.txt","destination":"%[2]vchanged.txt","reason":"size-differs"}`, src, dst),
+		1: equals(`{"v":2,"operation":"sync-plan","action":"cp","source":"%[1]vnew.txt","destination":"%[2]vnew.txt","reason":"only-in-source"}`, src, dst),
+		2: equals(`{"v":2,"operation":"sync-plan","action":"cp","source":"%[1]vnewer.txt","destination":"%[2]vnewer.txt","reason":"newer"}`, src, dst),
+		3: equals(`{"v":2,"operation":"sync-plan","action":"rm","destination":"%vobsolete.txt","reason":"only-in-destination"}`, dst),
+	}, strictLineCheck(true), sortInput(true))
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`{"v":2,"operation":"sync-plan-summary","upload":1,"update":2,"delete":1,"bytes":43}`),
+	}, strictLineCheck(true))
+}
+
+// --json --dry-run sync --delete dir/ s3://bucket/
+func TestSyncDryRunSummaryWithoutChanges(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	timeSource := newFixedTimeSource(now)
+	s3client, s5cmd := setup(t, withTimeSource(timeSource))
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	timestamp := fs.WithTimestamps(now.Add(-time.Minute), now.Add(-time.Minute))
+	workdir := fs.NewDir(t, "somedir",
+		fs.WithFile("same.txt", "same content", timestamp),
+	)
+	defer workdir.Remove()
+
+	putFile(t, s3client, bucket, "same.txt", "same content")
+
+	src := filepath.ToSlash(fmt.Sprintf("%v/", workdir.Path()))
+	dst := fmt.Sprintf("s3://%v/", bucket)
+
+	cmd := s5cmd("--json", "--dry-run", "sync", "--delete", src, dst)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{}, strictLineCheck(true))
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`{"v":2,"operation":"sync-plan-summary","upload":0,"update":0,"delete":0,"bytes":0}`),
+	}, strictLineCheck(true))
+}
+
+// sync --dry-run dir/ s3://bucket/
+func TestSyncLocalFolderToS3BucketPrintPlan(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	putFile(t, s3client, bucket, "obsolete.txt", "obsolete content")
+
+	workdir := fs.NewDir(t, "somedir", fs.WithDir("a", fs.WithFile("file.txt", "content")))
+	defer workdir.Remove()
+
+	src := fmt.Sprintf("%v/", workdir.Path())
+	src = filepath.ToSlash(src)
+	dst := fmt.Sprintf("s3://%v/", bucket)
+
+	// the objects only in destination are not planned to be deleted without
+	// the delete flag.
+	cmd := s5cmd("sync", "--dry-run", src, dst)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp --preserve-mtime=true --raw=true --skip-vanished=true "%va/file.txt" "%va/file.txt"`, src, dst),
+	}, strictLineCheck(true))
+
+	err := ensureS3Object(s3client, bucket, "a/file.txt", "")
+	assertError(t, err, errS3NoSuchKey)
+}
+
+// sync --dry-run --diff --max-compare-memory 1KB dir/ s3://bucket/
+func TestSyncWithMaxCompareMemory(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	// the objects are more than the smallest chunk, so that they are spilled
+	// to multiple temporary files with the limit.
+	const (
+		dirs  = 25
+		files = 100
+	)
+	var dirOps []fs.PathOp
+	for d := dirs - 1; d >= 0; d-- {
+		var fileOps []fs.PathOp
+		for f := files - 1; f >= 0; f-- {
+			fileOps = append(fileOps, fs.WithFile(fmt.Sprintf("file-%03d.txt", f), "content"))
+		}
+		dirOps = append(dirOps, fs.WithDir(fmt.Sprintf("dir-%02d", d), fileOps...))
+	}
+	workdir := fs.NewDir(t, "somedir", dirOps...)
+	defer workdir.Remove()
+
+	src := fmt.Sprintf("%v/", workdir.Path())
+	dst := fmt.Sprintf("s3://%v/", bucket)
+
+	cmd := s5cmd("--dry-run", "sync", "--diff", "--max-compare-memory", "1KB", src, dst)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	expected := map[int]compareFunc{}
+	for d := 0; d < dirs; d++ {
+		for f := 0; f < files; f++ {
+			expected[d*files+f] = equals(`+ %vdir-%02d/file-%03d.txt (only in source)`, dst, d, f)
+		}
+	}
+	expected[dirs*files] = equals(`%v new, 0 deleted, 0 modified`, dirs*files)
+
+	assertLines(t, result.Stdout(), expected, strictLineCheck(true), sortInput(false))
+}
+
+func TestSyncMaxCompareMemoryValidation(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd := setup(t)
+
+	testcases := []struct {
+		value    string
+		expected string
+	}{
+		{
+			value:    "lots",
+			expected: `ERROR "sync --max-compare-memory=lots dir/ s3://bucket/": invalid max-compare-memory: `,
+		},
+		{
+			value:    "0",
+			expected: `ERROR "sync --max-compare-memory=0 dir/ s3://bucket/": "max-compare-memory" flag must be greater than zero`,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.value, func(t *testing.T) {
+			cmd := s5cmd("sync", "--max-compare-memory", tc.value, "dir/", "s3://bucket/")
+			result := icmd.RunCmd(cmd)
+
+			result.Assert(t, icmd.Expected{ExitCode: 1})
+
+			assertLines(t, result.Stderr(), map[int]compareFunc{
+				0: prefix(tc.expected),
+			})
+		})
+	}
+}
+
+// sync --stream --delete s3://bucket/* s3://destbucket/
+func TestSyncS3BucketToS3BucketWithStream(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	srcbucket := s3BucketFromTestName(t)
+	dstbucket := "copy-" + s3BucketFromTestName(t)
+	createBucket(t, s3client, srcbucket)
+	createBucket(t, s3client, dstbucket)
+
+	// "a.txt" is listed before "a/b.txt", unlike the walk of a directory.
+	putFile(t, s3client, srcbucket, "a.txt", "content of a")
+	putFile(t, s3client, srcbucket, "a/b.txt", "content of b")
+	putFile(t, s3client, srcbucket, "c.txt", "content of c")
+
+	putFile(t, s3client, dstbucket, "a.txt", "content of a")
+	putFile(t, s3client, dstbucket, "a/old.txt", "old content")
+	putFile(t, s3client, dstbucket, "d.txt", "content of d")
+
+	src := fmt.Sprintf("s3://%v/*", srcbucket)
+	dst := fmt.Sprintf("s3://%v/", dstbucket)
+
+	cmd := s5cmd("sync", "--stream", "--delete", src, dst)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp s3://%v/a/b.txt %va/b.txt`, srcbucket, dst),
+		1: equals(`cp s3://%v/c.txt %vc.txt`, srcbucket, dst),
+		2: equals(`rm %va/old.txt`, dst),
+		3: equals(`rm %vd.txt`, dst),
+	}, sortInput(true), strictLineCheck(true))
+
+	assert.Assert(t, ensureS3Object(s3client, dstbucket, "a/b.txt", "content of b"))
+	assert.Assert(t, ensureS3Object(s3client, dstbucket, "c.txt", "content of c"))
+	err := ensureS3Object(s3client, dstbucket, "d.txt", "content of d")
+	assertError(t, err, errS3NoSuchKey)
+}
+
+// sync --stream dir/ s3://bucket/
+func TestSyncLocalFolderToS3BucketWithStream(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	putFile(t, s3client, bucket, "a.txt", "content of a")
+	putFile(t, s3client, bucket, "a/b.txt", "content of b")
+
+	// the files are sorted, since they are not walked in the order of the
+	// keys of the bucket.
+	workdir := fs.NewDir(t, "somedir",
+		fs.WithFile("a.txt", "content of a"),
+		fs.WithDir("a", fs.WithFile("b.txt", "content of b")),
+		fs.WithFile("c.txt", "content of c"),
+	)
+	defer workdir.Remove()
+
+	src := filepath.ToSlash(workdir.Path()) + "/"
+	dst := fmt.Sprintf("s3://%v/", bucket)
+
+	cmd := s5cmd("sync", "--stream", "--size-only", src, dst)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %vc.txt %vc.txt`, src, dst),
+	}, strictLineCheck(true))
+}
+
+func TestSyncStreamWithDeleteBatchInterleave(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd := setup(t)
+
+	cmd := s5cmd("sync", "--stream", "--delete", "--delete-batch-interleave", "10", "s3://bucket/*", "s3://destbucket/")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "sync --delete=true --delete-batch-interleave=10 --stream=true s3://bucket/* s3://destbucket/": it is not allowed to combine "stream" and "delete-batch-interleave" flags`),
+	}, strictLineCheck(true))
+}
+
+// sync --two-way dir/ s3://bucket/
+func TestSyncLocalFolderToS3BucketTwoWay(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	timeSource := newFixedTimeSource(now)
+	s3client, s5cmd := setup(t, withTimeSource(timeSource))
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	older := fs.WithTimestamps(now.Add(-time.Hour), now.Add(-time.Hour))
+	newer := fs.WithTimestamps(now.Add(time.Hour), now.Add(time.Hour))
+
+	workdir := fs.NewDir(t, "somedir",
+		fs.WithFile("same.txt", "same content", older),
+		fs.WithFile("local-newer.txt", "changed locally", newer),
+		fs.WithFile("remote-newer.txt", "old", older),
+		fs.WithFile("only-local.txt", "only in local", older),
+	)
+	defer workdir.Remove()
+
+	putFile(t, s3client, bucket, "same.txt", "same content")
+	putFile(t, s3client, bucket, "local-newer.txt", "old")
+	putFile(t, s3client, bucket, "remote-newer.txt", "changed remotely")
+	putFile(t, s3client, bucket, "dir/only-remote.txt", "only in remote")
+
+	src := filepath.ToSlash(workdir.Path()) + "/"
+	dst := fmt.Sprintf("s3://%v/", bucket)
+
+	cmd := s5cmd("sync", "--two-way", src, dst)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %vlocal-newer.txt %vlocal-newer.txt`, src, dst),
+		1: equals(`cp %vonly-local.txt %vonly-local.txt`, src, dst),
+		2: equals(`cp %vdir/only-remote.txt %vdir/only-remote.txt`, dst, src),
+		3: equals(`cp %vremote-newer.txt %vremote-newer.txt`, dst, src),
+		4: equals(`cp %vsame.txt %vsame.txt`, dst, src),
+	}, sortInput(true), strictLineCheck(true))
+
+	assert.Assert(t, ensureS3Object(s3client, bucket, "local-newer.txt", "changed locally"))
+	assert.Assert(t, ensureS3Object(s3client, bucket, "only-local.txt", "only in local"))
+	assert.Assert(t, fs.Equal(workdir.Path(), fs.Expected(t,
+		fs.WithFile("same.txt", "same content"),
+		fs.WithFile("local-newer.txt", "changed locally"),
+		fs.WithFile("remote-newer.txt", "changed remotely"),
+		fs.WithFile("only-local.txt", "only in local"),
+		fs.WithDir("dir", fs.WithFile("only-remote.txt", "only in remote")),
+	)))
+}
+
+func TestSyncTwoWayValidation(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd := setup(t)
+
+	testcases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "delete",
+			args:     []string{"sync", "--two-way", "--delete", "dir/", "s3://bucket/"},
+			expected: `ERROR "sync --delete=true --two-way=true dir/ s3://bucket/": it is not allowed to combine "two-way" and "delete" flags`,
+		},
+		{
+			name:     "source without trailing slash",
+			args:     []string{"sync", "--two-way", "dir", "s3://bucket/"},
+			expected: `ERROR "sync --two-way=true dir s3://bucket/": "two-way" flag requires the source to be a directory with a trailing slash, or a prefix followed by "/*"`,
+		},
+		{
+			name:     "remote source with filter",
+			args:     []string{"sync", "--two-way", "s3://bucket/*.txt", "dir/"},
+			expected: `ERROR "sync --two-way=true s3://bucket/*.txt dir/": "two-way" flag requires the source to be a directory with a trailing slash, or a prefix followed by "/*"`,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			result := icmd.RunCmd(s5cmd(tc.args...))
+
+			result.Assert(t, icmd.Expected{ExitCode: 1})
+
+			assertLines(t, result.Stderr(), map[int]compareFunc{
+				0: equals(tc.expected),
+			}, strictLineCheck(true))
+		})
+	}
+}
+
+func TestSyncDiffWithoutDryRun(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd := setup(t)
+
+	cmd := s5cmd("sync", "--diff", "dir/", "s3://bucket/")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "sync --diff=true dir/ s3://bucket/": "diff" flag requires "dry-run" flag`),
+	})
+}
+
+// sync s3://bucket/* dir/ (dir/file.txt is a directory)
+func TestSyncS3BucketToLocalTypeConflict(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	putFile(t, s3client, bucket, "file.txt", "content")
+	putFile(t, s3client, bucket, "new.txt", "new")
+
+	workdir := fs.NewDir(t, t.Name(), fs.WithDir("file.txt", fs.WithFile("existing.txt", "existing")))
+	defer workdir.Remove()
+
+	src := fmt.Sprintf("s3://%v/*", bucket)
+	dst := filepath.ToSlash(workdir.Path()) + "/"
+
+	// the conflicting directory is skipped by default.
+	cmd := s5cmd("sync", src, dst)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp s3://%v/new.txt %vnew.txt`, bucket, dst),
+		1: equals(`skip s3://%v/file.txt %vfile.txt: type conflict: %vfile.txt is a directory`, bucket, dst, dst),
+	}, sortInput(true))
+
+	expected := fs.Expected(t,
+		fs.WithDir("file.txt", fs.WithFile("existing.txt", "existing")),
+		fs.WithFile("new.txt", "new"),
+	)
+	assert.Assert(t, fs.Equal(workdir.Path(), expected))
+
+	cmd = s5cmd("sync", "--on-type-conflict", "replace", "--force", src, dst)
+	result = icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	expected = fs.Expected(t,
+		fs.WithFile("file.txt", "content"),
+		fs.WithFile("new.txt", "new"),
+	)
+	assert.Assert(t, fs.Equal(workdir.Path(), expected))
+}
+
+// sync --delete --partition-by-prefix s3://bucket/* s3://destbucket/
+func TestSyncS3BucketToS3BucketPartitionByPrefix(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	dstbucket := s3BucketFromTestNameWithPrefix(t, "dst")
+
+	createBucket(t, s3client, bucket)
+	createBucket(t, s3client, dstbucket)
+
+	sourceS3Content := map[string]string{
+		"top.txt":   "top",
+		"a/1.txt":   "a1",
+		"a/2.txt":   "a2",
+		"b/c/1.txt": "bc1",
+	}
+	destS3Content := map[string]string{
+		"obsolete.txt":   "obsolete",
+		"a/obsolete.txt": "obsolete",
+	}
+
+	for filename, content := range sourceS3Content {
+		putFile(t, s3client, bucket, filename, content)
+	}
+	for filename, content := range destS3Content {
+		putFile(t, s3client, dstbucket, filename, content)
+	}
+
+	src := fmt.Sprintf("s3://%v/", bucket)
+	dst := fmt.Sprintf("s3://%v/", dstbucket)
+
+	cmd := s5cmd("sync", "--delete", "--partition-by-prefix", "--partition-concurrency", "1", src+"*", dst)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`3 partitions: 3 succeeded, 0 failed, 0 skipped, 0 errors`),
+		1: equals(`cp %va/1.txt %va/1.txt`, src, dst),
+		2: equals(`cp %va/2.txt %va/2.txt`, src, dst),
+		3: equals(`cp %vb/c/1.txt %vb/c/1.txt`, src, dst),
+		4: equals(`cp %vtop.txt %vtop.txt`, src, dst),
+		5: equals(`partition %v %v: succeeded`, src, dst),
+		6: equals(`partition %va/* %va/: succeeded`, src, dst),
+		7: equals(`partition %vb/* %vb/: succeeded`, src, dst),
+		8: equals(`rm %va/obsolete.txt`, dst),
+		9: equals(`rm %vobsolete.txt`, dst),
+	}, sortInput(true))
+
+	for key, content := range sourceS3Content {
+		assert.Assert(t, ensureS3Object(s3client, dstbucket, key, content))
+	}
+	for key := range destS3Content {
+		err := ensureS3Object(s3client, dstbucket, key, "obsolete")
+		assertError(t, err, errS3NoSuchKey)
+	}
+}
+
+// sync --partition-by-prefix --depth 2 s3://bucket/data/* dir/
+func TestSyncS3PrefixToLocalPartitionByPrefixWithDepth(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	s3Content := map[string]string{
+		"data/a/1.txt":   "a1",
+		"data/b/2.txt":   "b2",
+		"data/b/c/3.txt": "bc3",
+		"other/4.txt":    "other",
+	}
+	for filename, content := range s3Content {
+		putFile(t, s3client, bucket, filename, content)
+	}
+
+	workdir := fs.NewDir(t, t.Name())
+	defer workdir.Remove()
+
+	src := fmt.Sprintf("s3://%v/data/", bucket)
+	dst := filepath.ToSlash(workdir.Path()) + "/"
+
+	cmd := s5cmd("--json", "sync", "--partition-by-prefix", "--depth", "2", src+"*", dst)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: contains(`"source":"%va/1.txt"`, src),
+		1: contains(`"source":"%vb/2.txt"`, src),
+		2: contains(`"source":"%vb/c/3.txt"`, src),
+		3: equals(`{"v":2,"operation":"partition","source":"%va/","destination":"%va/","status":"succeeded","errors":0}`, src, dst),
+		4: equals(`{"v":2,"operation":"partition","source":"%vb/","destination":"%vb/","status":"succeeded","errors":0}`, src, dst),
+		5: equals(`{"v":2,"operation":"partition","source":"%vb/c/*","destination":"%vb/c/","status":"succeeded","errors":0}`, src, dst),
+		6: equals(`{"v":2,"operation":"partition-summary","partitions":3,"succeeded":3,"failed":0,"skipped":0,"errors":0}`),
+	}, sortInput(true))
+
+	expected := fs.Expected(t,
+		fs.WithDir("a", fs.WithFile("1.txt", "a1")),
+		fs.WithDir("b",
+			fs.WithFile("2.txt", "b2"),
+			fs.WithDir("c", fs.WithFile("3.txt", "bc3")),
+		),
+	)
+	assert.Assert(t, fs.Equal(workdir.Path(), expected))
+}
+
+// sync --partition-by-prefix --on-type-conflict fail s3://bucket/* dir/ (dir/a is a file)
+func TestSyncS3BucketToLocalPartitionFailure(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name              string
+		continueOnFailure bool
+	}{
+		{name: "stop on partition failure"},
+		{name: "continue on partition failure", continueOnFailure: true},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			s3client, s5cmd := setup(t)
+
+			bucket := s3BucketFromTestName(t)
+			createBucket(t, s3client, bucket)
+
+			putFile(t, s3client, bucket, "a/1.txt", "a1")
+			putFile(t, s3client, bucket, "b/2.txt", "b2")
+
+			workdir := fs.NewDir(t, "partition", fs.WithFile("a", "conflict"))
+			defer workdir.Remove()
+
+			src := fmt.Sprintf("s3://%v/", bucket)
+			dst := filepath.ToSlash(workdir.Path()) + "/"
+
+			args := []string{"sync", "--partition-by-prefix", "--partition-concurrency", "1", "--on-type-conflict", "fail"}
+			if tc.continueOnFailure {
+				args = append(args, "--continue-on-partition-failure")
+			}
+			cmd := s5cmd(append(args, src+"*", dst)...)
+			result := icmd.RunCmd(cmd)
+
+			assertLines(t, result.Stderr(), map[int]compareFunc{
+				0: contains(`%va/1.txt`, dst),
+			})
+
+			if !tc.continueOnFailure {
+				result.Assert(t, icmd.Expected{ExitCode: 1})
+
+				assertLines(t, result.Stdout(), map[int]compareFunc{
+					0: equals(`partition %va/* %va/: failed with 1 errors`, src, dst),
+					1: equals(`partition %vb/* %vb/: skipped`, src, dst),
+					2: equals(`2 partitions: 0 succeeded, 1 failed, 1 skipped, 1 errors`),
+				}, strictLineCheck(true))
+
+				assert.Assert(t, fs.Equal(workdir.Path(), fs.Expected(t, fs.WithFile("a", "conflict"))))
+				return
+			}
+
+			result.Assert(t, icmd.Success)
+
+			assertLines(t, result.Stdout(), map[int]compareFunc{
+				0: equals(`partition %va/* %va/: failed with 1 errors`, src, dst),
+				1: equals(`cp %vb/2.txt %vb/2.txt`, src, dst),
+				2: equals(`partition %vb/* %vb/: succeeded`, src, dst),
+				3: equals(`2 partitions: 1 succeeded, 1 failed, 0 skipped, 1 errors`),
+			}, strictLineCheck(true))
+
+			expected := fs.Expected(t,
+				fs.WithFile("a", "conflict"),
+				fs.WithDir("b", fs.WithFile("2.txt", "b2")),
+			)
+			assert.Assert(t, fs.Equal(workdir.Path(), expected))
+		})
+	}
+}
+
+func TestSyncPartitionByPrefixWithInvalidSource(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd := setup(t)
+
+	cmd := s5cmd("sync", "--partition-by-prefix", "s3://bucket/prefix*", "dir/")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "sync --partition-by-prefix=true s3://bucket/prefix* dir/": source of "partition-by-prefix" flag must be a bucket or a prefix followed by "/*", e.g. "s3://bucket/prefix/*"`),
+	})
+}
+
+// sync --sync-strategy checksum folder/ s3://bucket/
+func TestSyncLocalFolderToS3BucketChecksumStrategy(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	timeSource := newFixedTimeSource(now)
+	s3client, s5cmd := setup(t, withTimeSource(timeSource))
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	timestamp := fs.WithTimestamps(now.Add(-2*time.Minute), now.Add(-2*time.Minute))
+	workdir := fs.NewDir(t, "somedir",
+		fs.WithFile("changed.txt", "S: this is a test file", timestamp),
+		fs.WithFile("same.txt", "this is the same file", timestamp),
+	)
+	defer workdir.Remove()
+
+	// the remote object is newer and has the same size, but its content is
+	// different.
+	timeSource.Advance(-time.Minute)
+	putFile(t, s3client, bucket, "changed.txt", "D: this is a test file")
+
+	// the remote object is older, but its content is the same.
+	timeSource.Advance(-2 * time.Minute)
+	putFile(t, s3client, bucket, "same.txt", "this is the same file")
+
+	src := filepath.ToSlash(fmt.Sprintf("%v/", workdir.Path()))
+	dst := fmt.Sprintf("s3://%v/", bucket)
+
+	cmd := s5cmd("sync", "--sync-strategy", "checksum", "--show-skips", src, dst)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %vchanged.txt %vchanged.txt`, src, dst),
+		1: prefix(`skip %vsame.txt %vsame.txt: object checksum matches`, src, dst),
+	}, sortInput(true), strictLineCheck(true))
+
+	assert.Assert(t, ensureS3Object(s3client, bucket, "changed.txt", "S: this is a test file"))
+}
+
+// sync --checksum folder/ s3://bucket/
+func TestSyncLocalFolderToS3BucketChecksumFlag(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	timeSource := newFixedTimeSource(now)
+	s3client, s5cmd := setup(t, withTimeSource(timeSource))
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	timestamp := fs.WithTimestamps(now.Add(-2*time.Minute), now.Add(-2*time.Minute))
+	workdir := fs.NewDir(t, "somedir",
+		fs.WithFile("changed.txt", "S: this is a test file", timestamp),
+		fs.WithFile("same.txt", "this is the same file", timestamp),
+	)
+	defer workdir.Remove()
+
+	// the remote object is newer and has the same size, but its content is
+	// different.
+	timeSource.Advance(-time.Minute)
+	putFile(t, s3client, bucket, "changed.txt", "D: this is a test file")
+
+	// the remote object is older, but its content is the same.
+	timeSource.Advance(-2 * time.Minute)
+	putFile(t, s3client, bucket, "same.txt", "this is the same file")
+
+	src := filepath.ToSlash(fmt.Sprintf("%v/", workdir.Path()))
+	dst := fmt.Sprintf("s3://%v/", bucket)
+
+	cmd := s5cmd("sync", "--checksum", "--show-skips", src, dst)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %vchanged.txt %vchanged.txt`, src, dst),
+		1: prefix(`skip %vsame.txt %vsame.txt: object checksum matches`, src, dst),
+	}, sortInput(true), strictLineCheck(true))
+
+	assert.Assert(t, ensureS3Object(s3client, bucket, "changed.txt", "S: this is a test file"))
+}
+
+// sync --sync-strategy checksum --sse aws:kms folder/ s3://bucket/
+func TestSyncLocalFolderToS3BucketChecksumStrategyWithKMSEncryption(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	timeSource := newFixedTimeSource(now)
+	s3client, s5cmd := setup(t, withTimeSource(timeSource))
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	timestamp := fs.WithTimestamps(now.Add(-2*time.Minute), now.Add(-2*time.Minute))
+	workdir := fs.NewDir(t, "somedir",
+		fs.WithFile("newer.txt", "S: this is a test file", timestamp),
+		fs.WithFile("older.txt", "this is the same file", timestamp),
+	)
+	defer workdir.Remove()
+
+	// the ETags of the objects encrypted with KMS keys are not the checksums
+	// of their contents, so the objects are compared by their sizes and
+	// modification times instead.
+	timeSource.Advance(-time.Minute)
+	putFile(t, s3client, bucket, "newer.txt", "D: this is a test file")
+
+	timeSource.Advance(-2 * time.Minute)
+	putFile(t, s3client, bucket, "older.txt", "this is the same file")
+
+	src := filepath.ToSlash(fmt.Sprintf("%v/", workdir.Path()))
+	dst := fmt.Sprintf("s3://%v/", bucket)
+
+	cmd := s5cmd("sync", "--sync-strategy", "checksum", "--sse", "aws:kms", "--show-skips", src, dst)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %volder.txt %volder.txt`, src, dst),
+		1: prefix(`skip %vnewer.txt %vnewer.txt: object is newer or same age and object size matches`, src, dst),
+	}, sortInput(true), strictLineCheck(true))
+
+	assert.Assert(t, ensureS3Object(s3client, bucket, "newer.txt", "D: this is a test file"))
+}
+
+// sync --sse aws:kms --match-encryption folder/ s3://bucket/
+func TestSyncLocalFolderToS3BucketWithMatchEncryption(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	now := time.Now()
+	timestamp := fs.WithTimestamps(now.Add(-time.Hour), now.Add(-time.Hour))
+	workdir := fs.NewDir(t, "somedir",
+		fs.WithFile("file.txt", "this is a test file", timestamp),
+	)
+	defer workdir.Remove()
+
+	putFile(t, s3client, bucket, "file.txt", "this is a test file")
+
+	src := filepath.ToSlash(fmt.Sprintf("%v/", workdir.Path()))
+	dst := fmt.Sprintf("s3://%v/", bucket)
+
+	// the object is unchanged, but it is not encrypted with KMS keys.
+	cmd := s5cmd("sync", "--sse", "aws:kms", "--match-encryption", src, dst)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %vfile.txt %vfile.txt`, src, dst),
+	}, strictLineCheck(true))
+
+	// the object is skipped without --match-encryption.
+	cmd = s5cmd("sync", "--sse", "aws:kms", src, dst)
+	result = icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+	assertLines(t, result.Stdout(), map[int]compareFunc{}, strictLineCheck(true))
+}
+
+// sync --delete --delete-batch-size 1 folder/ s3://bucket/
+func TestSyncLocalFolderToS3BucketWithDeleteBatchSize(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	workdir := fs.NewDir(t, "somedir", fs.WithFile("a.txt", "content"))
+	defer workdir.Remove()
+
+	putFile(t, s3client, bucket, "b.txt", "content")
+	putFile(t, s3client, bucket, "c.txt", "content")
+
+	src := filepath.ToSlash(fmt.Sprintf("%v/", workdir.Path()))
+	dst := fmt.Sprintf("s3://%v/", bucket)
+
+	cmd := s5cmd("sync", "--delete", "--delete-batch-size", "1", src, dst)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %va.txt %va.txt`, src, dst),
+		1: equals(`rm %vb.txt`, dst),
+		2: equals(`rm %vc.txt`, dst),
+	}, sortInput(true), strictLineCheck(true))
+
+	assert.Assert(t, ensureS3Object(s3client, bucket, "a.txt", "content"))
+	for _, filename := range []string{"b.txt", "c.txt"} {
+		err := ensureS3Object(s3client, bucket, filename, "content")
+		assertError(t, err, errS3NoSuchKey)
+	}
+}
+
+func TestSyncDeleteBatchSizeWithoutDelete(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd := setup(t)
+
+	cmd := s5cmd("sync", "--delete-batch-size", "10", "folder/", "s3://bucket/")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "sync --delete-batch-size=10 folder/ s3://bucket/": "delete-batch-size" flag requires "delete" flag`),
+	}, strictLineCheck(true))
+}
+
+// --log debug sync --dry-run --delete --delete-batch-size 2 folder/ s3://bucket/
+func TestSyncDryRunDeletesInBatches(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	workdir := fs.NewDir(t, "somedir", fs.WithFile("a.txt", "content"))
+	defer workdir.Remove()
+
+	putFile(t, s3client, bucket, "a.txt", "content")
+	putFile(t, s3client, bucket, "b.txt", "content")
+	putFile(t, s3client, bucket, "c.txt", "content")
+	putFile(t, s3client, bucket, "d.txt", "content")
+
+	src := filepath.ToSlash(fmt.Sprintf("%v/", workdir.Path()))
+	dst := fmt.Sprintf("s3://%v/", bucket)
+
+	cmd := s5cmd("--log", "debug", "sync", "--dry-run", "--size-only", "--delete", "--delete-batch-size", "2", src, dst)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: prefix(`DEBUG "sync %va.txt %va.txt": object size matches`, src, dst),
+		1: equals(`DEBUG deleting 3 objects in destination with 2 rm commands of at most 2 objects`),
+		2: equals(`rm --delete-batch-size=2 --raw=true "%[1]vb.txt" "%[1]vc.txt"`, dst),
+		3: equals(`rm --delete-batch-size=2 --raw=true "%vd.txt"`, dst),
+	}, strictLineCheck(true), sortInput(true))
+}
+
+// sync --delete --keep-sentinels .keep folder/ s3://bucket/
+func TestSyncLocalFolderToS3BucketWithKeepSentinels(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	workdir := fs.NewDir(t, "somedir", fs.WithFile("a.txt", "content"))
+	defer workdir.Remove()
+
+	putFile(t, s3client, bucket, ".keep", "keep")
+	putFile(t, s3client, bucket, "b.txt", "content")
+	putFile(t, s3client, bucket, "dir/.keep", "keep")
+	putFile(t, s3client, bucket, "dir/nested/.keep", "keep")
+	putFile(t, s3client, bucket, "dir/c.txt", "content")
+
+	src := filepath.ToSlash(fmt.Sprintf("%v/", workdir.Path()))
+	dst := fmt.Sprintf("s3://%v/", bucket)
+
+	cmd := s5cmd("sync", "--delete", "--keep-sentinels", ".keep", src, dst)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %va.txt %va.txt`, src, dst),
+		1: equals(`kept 3 sentinel objects matching [.keep]`),
+		2: equals(`rm %vb.txt`, dst),
+		3: equals(`rm %vdir/c.txt`, dst),
+	}, sortInput(true), strictLineCheck(true))
+
+	// the sentinels are kept at any depth.
+	for _, key := range []string{".keep", "dir/.keep", "dir/nested/.keep"} {
+		assert.Assert(t, ensureS3Object(s3client, bucket, key, "keep"))
+	}
+	for _, key := range []string{"b.txt", "dir/c.txt"} {
+		err := ensureS3Object(s3client, bucket, key, "content")
+		assertError(t, err, errS3NoSuchKey)
+	}
+}
+
+func TestSyncKeepSentinelsWithoutDelete(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd := setup(t)
+
+	cmd := s5cmd("sync", "--keep-sentinels", ".keep", "folder/", "s3://bucket/")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "sync --keep-sentinels=.keep folder/ s3://bucket/": "keep-sentinels" flag requires "delete" flag`),
+	}, strictLineCheck(true))
+}
+
+// sync --delete --max-delete 1 folder/ s3://bucket/
+func TestSyncLocalFolderToS3BucketWithMaxDelete(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	workdir := fs.NewDir(t, "somedir", fs.WithFile("a.txt", "content"))
+	defer workdir.Remove()
+
+	putFile(t, s3client, bucket, "b.txt", "content")
+	putFile(t, s3client, bucket, "c.txt", "content")
+
+	src := filepath.ToSlash(fmt.Sprintf("%v/", workdir.Path()))
+	dst := fmt.Sprintf("s3://%v/", bucket)
+
+	cmd := s5cmd("sync", "--delete", "--max-delete", "1", src, dst)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{}, strictLineCheck(true))
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "sync --delete=true --max-delete=1 %v %v": refusing to delete 2 objects in destination, which is more than the 1 allowed by "max-delete" flag; nothing is copied or deleted`, src, dst),
+	}, strictLineCheck(true))
+
+	// nothing is copied or deleted.
+	for _, key := range []string{"b.txt", "c.txt"} {
+		assert.Assert(t, ensureS3Object(s3client, bucket, key, "content"))
+	}
+	err := ensureS3Object(s3client, bucket, "a.txt", "content")
+	assertError(t, err, errS3NoSuchKey)
+
+	// the deletes within the limit are run.
+	cmd = s5cmd("sync", "--delete", "--max-delete", "2", src, dst)
+	result = icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %va.txt %va.txt`, src, dst),
+		1: equals(`rm %vb.txt`, dst),
+		2: equals(`rm %vc.txt`, dst),
+	}, sortInput(true), strictLineCheck(true))
+}
+
+// sync --delete --max-delete-percent 50 folder/ s3://bucket/
+func TestSyncLocalFolderToS3BucketWithMaxDeletePercent(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	workdir := fs.NewDir(t, "somedir", fs.WithFile("a.txt", "content"))
+	defer workdir.Remove()
+
+	putFile(t, s3client, bucket, "a.txt", "content")
+	putFile(t, s3client, bucket, "b.txt", "content")
+	putFile(t, s3client, bucket, "c.txt", "content")
+
+	src := filepath.ToSlash(fmt.Sprintf("%v/", workdir.Path()))
+	dst := fmt.Sprintf("s3://%v/", bucket)
+
+	cmd := s5cmd("sync", "--delete", "--max-delete-percent", "50", src, dst)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{}, strictLineCheck(true))
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "sync --delete=true --max-delete-percent=50 %v %v": refusing to delete 2 of 3 objects in destination (66.7%%), which is more than the 50%% allowed by "max-delete-percent" flag; nothing is copied or deleted`, src, dst),
+	}, strictLineCheck(true))
+
+	for _, key := range []string{"b.txt", "c.txt"} {
+		assert.Assert(t, ensureS3Object(s3client, bucket, key, "content"))
+	}
+
+	// no object is to be deleted with 0.
+	cmd = s5cmd("sync", "--delete", "--max-delete", "0", src, dst)
+	result = icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "sync --delete=true --max-delete=0 %v %v": refusing to delete 2 objects in destination, which is more than the 0 allowed by "max-delete" flag; nothing is copied or deleted`, src, dst),
+	}, strictLineCheck(true))
+
+	cmd = s5cmd("sync", "--delete", "--max-delete-percent", "70", src, dst)
+	result = icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`rm %vb.txt`, dst),
+		1: equals(`rm %vc.txt`, dst),
+	}, sortInput(true), strictLineCheck(true))
+}
+
+func TestSyncMaxDeleteValidation(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "without delete",
+			args:     []string{"--max-delete", "10"},
+			expected: `ERROR "sync --max-delete=10 folder/ s3://bucket/": "max-delete" flag requires "delete" flag`,
+		},
+		{
+			name:     "negative",
+			args:     []string{"--delete", "--max-delete", "-2"},
+			expected: `ERROR "sync --delete=true --max-delete=-2 folder/ s3://bucket/": "max-delete" flag must not be negative, except -1 which disables the limit`,
+		},
+		{
+			name:     "with partitions",
+			args:     []string{"--delete", "--max-delete", "10", "--partition-by-prefix"},
+			expected: `ERROR "sync --delete=true --max-delete=10 --partition-by-prefix=true folder/ s3://bucket/": it is not allowed to combine "max-delete" and "partition-by-prefix" flags`,
+		},
+		{
+			name:     "percentage without delete",
+			args:     []string{"--max-delete-percent", "10"},
+			expected: `ERROR "sync --max-delete-percent=10 folder/ s3://bucket/": "max-delete-percent" flag requires "delete" flag`,
+		},
+		{
+			name:     "percentage above 100",
+			args:     []string{"--delete", "--max-delete-percent", "150"},
+			expected: `ERROR "sync --delete=true --max-delete-percent=150 folder/ s3://bucket/": "max-delete-percent" flag must be between 0 and 100, or -1 which disables the limit`,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, s5cmd := setup(t)
+
+			args := append(append([]string{"sync"}, tc.args...), "folder/", "s3://bucket/")
+			result := icmd.RunCmd(s5cmd(args...))
+
+			result.Assert(t, icmd.Expected{ExitCode: 1})
+
+			assertLines(t, result.Stderr(), map[int]compareFunc{
+				0: equals(tc.expected),
+			}, strictLineCheck(true))
+		})
+	}
+}
+
+// sync --delete --delete-scope "*.a.csv" folder/ s3://bucket/merged/
+func TestSyncLocalFolderToS3BucketWithDeleteScope(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	workdir := fs.NewDir(t, "somedir", fs.WithFile("1.a.csv", "content"))
+	defer workdir.Remove()
+
+	// the objects of the other sources are not deleted.
+	putFile(t, s3client, bucket, "merged/2.a.csv", "content")
+	putFile(t, s3client, bucket, "merged/1.b.csv", "content")
+	putFile(t, s3client, bucket, "merged/dir/3.a.csv", "content")
+	putFile(t, s3client, bucket, "merged/dir/2.b.csv", "content")
+
+	src := filepath.ToSlash(fmt.Sprintf("%v/", workdir.Path()))
+	dst := fmt.Sprintf("s3://%v/merged/", bucket)
+
+	cmd := s5cmd("sync", "--delete", "--delete-scope", "*.a.csv", src, dst)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %v1.a.csv %v1.a.csv`, src, dst),
+		1: equals(`kept 2 objects out of delete scope [*.a.csv]`),
+		2: equals(`rm %v2.a.csv`, dst),
+		3: equals(`rm %vdir/3.a.csv`, dst),
+	}, sortInput(true), strictLineCheck(true))
+
+	for _, key := range []string{"merged/1.a.csv", "merged/1.b.csv", "merged/dir/2.b.csv"} {
+		assert.Assert(t, ensureS3Object(s3client, bucket, key, "content"))
+	}
+	for _, key := range []string{"merged/2.a.csv", "merged/dir/3.a.csv"} {
+		err := ensureS3Object(s3client, bucket, key, "content")
+		assertError(t, err, errS3NoSuchKey)
+	}
+}
+
+func TestSyncDeleteScopeWithoutDelete(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd := setup(t)
+
+	cmd := s5cmd("sync", "--delete-scope", "*.a.csv", "folder/", "s3://bucket/")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "sync --delete-scope=*.a.csv folder/ s3://bucket/": "delete-scope" flag requires "delete" flag`),
+	}, strictLineCheck(true))
+}
+
+// sync --delete --exclude "*.log" --delete-excluded folder/ s3://bucket/
+func TestSyncLocalFolderToS3BucketWithDeleteExcluded(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	workdir := fs.NewDir(t, "somedir",
+		fs.WithFile("a.txt", "content"),
+		fs.WithFile("b.log", "content"),
+	)
+	defer workdir.Remove()
+
+	// the excluded objects are deleted whether or not they are in source.
+	putFile(t, s3client, bucket, "b.log", "content")
+	putFile(t, s3client, bucket, "dir/c.log", "content")
+	putFile(t, s3client, bucket, "d.txt", "content")
+
+	src := filepath.ToSlash(fmt.Sprintf("%v/", workdir.Path()))
+	dst := fmt.Sprintf("s3://%v/", bucket)
+
+	cmd := s5cmd("sync", "--delete", "--exclude", "*.log", "--delete-excluded", src, dst)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %va.txt %va.txt`, src, dst),
+		1: equals(`rm %vb.log`, dst),
+		2: equals(`rm %vd.txt`, dst),
+		3: equals(`rm %vdir/c.log`, dst),
+	}, sortInput(true), strictLineCheck(true))
+
+	assert.Assert(t, ensureS3Object(s3client, bucket, "a.txt", "content"))
+	for _, key := range []string{"b.log", "dir/c.log", "d.txt"} {
+		err := ensureS3Object(s3client, bucket, key, "content")
+		assertError(t, err, errS3NoSuchKey)
+	}
+}
+
+func TestSyncDeleteExcludedValidation(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "without delete",
+			args:     []string{"sync", "--exclude", "*.log", "--delete-excluded", "folder/", "s3://bucket/"},
+			expected: `ERROR "sync --delete-excluded=true --exclude=*.log folder/ s3://bucket/": "delete-excluded" flag requires "delete" flag`,
+		},
+		{
+			name:     "without exclude",
+			args:     []string{"sync", "--delete", "--delete-excluded", "folder/", "s3://bucket/"},
+			expected: `ERROR "sync --delete=true --delete-excluded=true folder/ s3://bucket/": "delete-excluded" flag requires "exclude" or "include" flag`,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, s5cmd := setup(t)
+
+			result := icmd.RunCmd(s5cmd(tc.args...))
+			result.Assert(t, icmd.Expected{ExitCode: 1})
+
+			assertLines(t, result.Stderr(), map[int]compareFunc{
+				0: equals(tc.expected),
+			}, strictLineCheck(true))
+		})
+	}
+}
+
+// sync --delete --lock s3://bucket/.s5cmd.lock folder/ s3://bucket/
+func TestSyncLocalFolderToS3BucketWithLock(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	workdir := fs.NewDir(t, "somedir", fs.WithFile("a.txt", "content"))
+	defer workdir.Remove()
+
+	putFile(t, s3client, bucket, "b.txt", "content")
+
+	src := filepath.ToSlash(fmt.Sprintf("%v/", workdir.Path()))
+	dst := fmt.Sprintf("s3://%v/", bucket)
+	lock := fmt.Sprintf("s3://%v/.s5cmd.lock", bucket)
+
+	// the lock object in destination is not deleted while the sync holds it.
+	cmd := s5cmd("sync", "--delete", "--lock", lock, src, dst)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %va.txt %va.txt`, src, dst),
+		1: equals(`rm %vb.txt`, dst),
+	}, sortInput(true), strictLineCheck(true))
+	assertLines(t, result.Stderr(), map[int]compareFunc{}, strictLineCheck(true))
+
+	assert.Assert(t, ensureS3Object(s3client, bucket, "a.txt", "content"))
+
+	// the lock is released once the sync is completed.
+	err := ensureS3Object(s3client, bucket, ".s5cmd.lock", "")
+	assertError(t, err, errS3NoSuchKey)
+}
+
+// sync --lock s3://bucket/.s5cmd.lock folder/ s3://bucket/
+func TestSyncLocalFolderToS3BucketWithHeldLock(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	workdir := fs.NewDir(t, "somedir", fs.WithFile("a.txt", "content"))
+	defer workdir.Remove()
+
+	expires := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	held := fmt.Sprintf(`{"token":"other","owner":"host:1","acquired":"%v","expires":"%v"}`,
+		expires.Add(-time.Hour).Format(time.RFC3339), expires.Format(time.RFC3339))
+	putFile(t, s3client, bucket, ".s5cmd.lock", held)
+
+	src := filepath.ToSlash(fmt.Sprintf("%v/", workdir.Path()))
+	dst := fmt.Sprintf("s3://%v/", bucket)
+	lock := fmt.Sprintf("s3://%v/.s5cmd.lock", bucket)
+
+	testcases := []struct {
+		name string
+		args []string
+	}{
+		{
+			name: "fail fast",
+			args: []string{"sync", "--lock", lock, src, dst},
+		},
+		{
+			name: "wait",
+			args: []string{"sync", "--lock", lock, "--lock-wait", "1s", src, dst},
+		},
+	}
+
+	for _, tc := range testcases {
+		result := icmd.RunCmd(s5cmd(tc.args...))
+		result.Assert(t, icmd.Expected{ExitCode: 1})
+
+		assertLines(t, result.Stdout(), map[int]compareFunc{}, strictLineCheck(true))
+		assertLines(t, result.Stderr(), map[int]compareFunc{
+			0: contains(`lock %v is held by "host:1" until %v`, lock, expires.Format(time.RFC3339)),
+		}, strictLineCheck(true))
+	}
+
+	// nothing is synced and the lock of the other sync is kept.
+	err := ensureS3Object(s3client, bucket, "a.txt", "content")
+	assertError(t, err, errS3NoSuchKey)
+	assert.Assert(t, ensureS3Object(s3client, bucket, ".s5cmd.lock", held))
+}
+
+// sync --lock s3://bucket/.s5cmd.lock folder/ s3://bucket/
+func TestSyncLocalFolderToS3BucketWithExpiredLock(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	workdir := fs.NewDir(t, "somedir", fs.WithFile("a.txt", "content"))
+	defer workdir.Remove()
+
+	// the lock of a sync which crashed is taken over once it expires.
+	expired := time.Now().Add(-time.Minute).UTC()
+	putFile(t, s3client, bucket, ".s5cmd.lock", fmt.Sprintf(`{"token":"other","owner":"host:1","acquired":"%v","expires":"%v"}`,
+		expired.Add(-time.Hour).Format(time.RFC3339), expired.Format(time.RFC3339)))
+
+	src := filepath.ToSlash(fmt.Sprintf("%v/", workdir.Path()))
+	dst := fmt.Sprintf("s3://%v/", bucket)
+
+	cmd := s5cmd("sync", "--lock", fmt.Sprintf("s3://%v/.s5cmd.lock", bucket), src, dst)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %va.txt %va.txt`, src, dst),
+	}, strictLineCheck(true))
+
+	assert.Assert(t, ensureS3Object(s3client, bucket, "a.txt", "content"))
+	err := ensureS3Object(s3client, bucket, ".s5cmd.lock", "")
+	assertError(t, err, errS3NoSuchKey)
+}
+
+func TestSyncLockValidation(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "ttl without lock",
+			args:     []string{"sync", "--lock-ttl", "2h", "folder/", "s3://bucket/"},
+			expected: `ERROR "sync --lock-ttl=2h0m0s folder/ s3://bucket/": "lock-ttl" flag requires "lock" flag`,
+		},
+		{
+			name:     "local lock",
+			args:     []string{"sync", "--lock", "dir/.s5cmd.lock", "folder/", "s3://bucket/"},
+			expected: `ERROR "sync --lock=dir/.s5cmd.lock folder/ s3://bucket/": "lock" flag must be the URL of a remote object, e.g. s3://bucket/prefix/.s5cmd.lock`,
+		},
+		{
+			name:     "prefix lock",
+			args:     []string{"sync", "--lock", "s3://bucket/prefix/", "folder/", "s3://bucket/"},
+			expected: `ERROR "sync --lock=s3://bucket/prefix/ folder/ s3://bucket/": "lock" flag must be the URL of a remote object, e.g. s3://bucket/prefix/.s5cmd.lock`,
+		},
+		{
+			name:     "zero ttl",
+			args:     []string{"sync", "--lock", "s3://bucket/.s5cmd.lock", "--lock-ttl", "0s", "folder/", "s3://bucket/"},
+			expected: `ERROR "sync --lock=s3://bucket/.s5cmd.lock --lock-ttl=0s folder/ s3://bucket/": "lock-ttl" flag must be greater than zero`,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, s5cmd := setup(t)
+
+			result := icmd.RunCmd(s5cmd(tc.args...))
+			result.Assert(t, icmd.Expected{ExitCode: 1})
+
+			assertLines(t, result.Stderr(), map[int]compareFunc{
+				0: equals(tc.expected),
+			}, strictLineCheck(true))
+		})
+	}
+}
+
+// sync --dir-mtime-shortcut state folder/ s3://bucket/
+func TestSyncLocalFolderToS3BucketWithDirMtimeShortcut(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	workdir := fs.NewDir(t, "somedir",
+		fs.WithFile("a.txt", "content"),
+		fs.WithDir("dir",
+			fs.WithFile("b.txt", "content"),
+		),
+	)
+	defer workdir.Remove()
+
+	statefile := filepath.Join(t.TempDir(), "state")
+	src := filepath.ToSlash(fmt.Sprintf("%v/", workdir.Path()))
+	dst := fmt.Sprintf("s3://%v/", bucket)
+
+	cmd := s5cmd("sync", "--dir-mtime-shortcut", statefile, src, dst)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %va.txt %va.txt`, src, dst),
+		1: equals(`cp %vdir/b.txt %vdir/b.txt`, src, dst),
+	}, sortInput(true), strictLineCheck(true))
+
+	// the file is written in place, which doesn't change the modification
+	// time of its directory, so the change is not seen with the shortcut.
+	err := os.WriteFile(workdir.Join("dir", "b.txt"), []byte("updated content"), 0644)
+	assert.NilError(t, err)
+
+	cmd = s5cmd("sync", "--dir-mtime-shortcut", statefile, src, dst)
+	result = icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+	assertLines(t, result.Stdout(), map[int]compareFunc{}, strictLineCheck(true))
+
+	// the full walk sees the change and refreshes the state.
+	cmd = s5cmd("sync", "--dir-mtime-shortcut", statefile, "--no-shortcut", src, dst)
+	result = icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %vdir/b.txt %vdir/b.txt`, src, dst),
+	}, strictLineCheck(true))
+
+	assert.Assert(t, ensureS3Object(s3client, bucket, "a.txt", "content"))
+	assert.Assert(t, ensureS3Object(s3client, bucket, "dir/b.txt", "updated content"))
+}
+
+func TestSyncDirMtimeShortcutValidation(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "remote source",
+			args:     []string{"sync", "--dir-mtime-shortcut", "state", "s3://bucket/*", "folder/"},
+			expected: `ERROR "sync --dir-mtime-shortcut=state s3://bucket/* folder/": "dir-mtime-shortcut" flag can only be used with a local source directory`,
+		},
+		{
+			name:     "no-shortcut without dir-mtime-shortcut",
+			args:     []string{"sync", "--no-shortcut", "folder/", "s3://bucket/"},
+			expected: `ERROR "sync --no-shortcut=true folder/ s3://bucket/": "no-shortcut" flag requires "dir-mtime-shortcut" flag`,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, s5cmd := setup(t)
+
+			result := icmd.RunCmd(s5cmd(tc.args...))
+			result.Assert(t, icmd.Expected{ExitCode: 1})
+
+			assertLines(t, result.Stderr(), map[int]compareFunc{
+				0: equals(tc.expected),
+			}, strictLineCheck(true))
+		})
+	}
+}
+
+// --dry-run sync --validate-keys folder/ s3://bucket/
+func TestSyncLocalFolderToS3BucketWithValidateKeys(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	workdir := fs.NewDir(t, "somedir",
+		fs.WithFile("valid.txt", "content"),
+		fs.WithFile("c:report.txt", "content"),
+		fs.WithDir("notes ",
+			fs.WithFile("todo.txt", "content"),
+		),
+	)
+	defer workdir.Remove()
+
+	src := fmt.Sprintf("%v/", workdir.Path())
+	src = filepath.ToSlash(src)
+	dst := fmt.Sprintf("s3://%v/", bucket)
+
+	cmd := s5cmd("--dry-run", "sync", "--validate-keys", src, dst)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	// the commands are planned even if their keys are invalid.
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %vc:report.txt %vc:report.txt`, src, dst),
+		1: equals(`cp %vnotes /todo.txt %vnotes /todo.txt`, src, dst),
+		2: equals(`cp %vvalid.txt %vvalid.txt`, src, dst),
+	}, sortInput(true))
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "sync %vc:report.txt %vc:report.txt": invalid destination key: key contains disallowed character ':'`, src, dst),
+		1: equals(`ERROR "sync %vnotes /todo.txt %vnotes /todo.txt": invalid destination key: "notes " has leading or trailing spaces`, src, dst),
+		2: equals(`ERROR 2 errors: Other: 2 (first: %vc:report.txt)`, src),
+		3: equals(`dry-run: 3 objects to upload, 0 to update, 0 to delete, 21 bytes to transfer`),
+	}, sortInput(true))
+
+	// the objects are not uploaded with --dry-run.
+	err := ensureS3Object(s3client, bucket, "valid.txt", "content")
+	assertError(t, err, errS3NoSuchKey)
+}
+
+func TestSyncValidateKeysValidation(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "without dry-run",
+			args:     []string{"sync", "--validate-keys", "folder/", "s3://bucket/"},
+			expected: `ERROR "sync --validate-keys=true folder/ s3://bucket/": "validate-keys" flag requires "dry-run" flag`,
+		},
+		{
+			name:     "local destination",
+			args:     []string{"--dry-run", "sync", "--validate-keys", "s3://bucket/*", "folder/"},
+			expected: `ERROR "sync --validate-keys=true s3://bucket/* folder/": "validate-keys" flag requires remote destination`,
+		},
+		{
+			name:     "max-key-length without validate-keys",
+			args:     []string{"sync", "--max-key-length", "255", "folder/", "s3://bucket/"},
+			expected: `ERROR "sync --max-key-length=255 folder/ s3://bucket/": "max-key-length" flag requires "validate-keys" flag`,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, s5cmd := setup(t)
+
+			result := icmd.RunCmd(s5cmd(tc.args...))
+			result.Assert(t, icmd.Expected{ExitCode: 1})
+
+			assertLines(t, result.Stderr(), map[int]compareFunc{
+				0: equals(tc.expected),
+			}, strictLineCheck(true))
+		})
+	}
+}
+
+// sync --sync-strategy always --compare-exec "cmp -s {src} {dst}" --compare-exec-input file dir/ s3://bucket/
+func TestSyncLocalFolderToS3BucketWithCompareExec(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("cmp is not available on windows")
+	}
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	putFile(t, s3client, bucket, "a.txt", "content of a")
+	putFile(t, s3client, bucket, "b.txt", "content of b")
+
+	workdir := fs.NewDir(t, "somedir",
+		fs.WithFile("a.txt", "content of a"),
+		fs.WithFile("b.txt", "changed content of b"),
+	)
+	defer workdir.Remove()
+
+	src := filepath.ToSlash(workdir.Path()) + "/"
+	dst := fmt.Sprintf("s3://%v/", bucket)
+
+	cmd := s5cmd("sync", "--sync-strategy", "always", "--compare-exec", "cmp -s {src} {dst}", "--compare-exec-input", "file", src, dst)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %vb.txt %vb.txt`, src, dst),
+		1: equals(`sync: 2 objects compared with compare command, 1 equivalent, 1 different, 0 failed`),
+	}, sortInput(true))
+
+	assert.Assert(t, ensureS3Object(s3client, bucket, "b.txt", "changed content of b"))
+}
+
+// --temp-dir tmp/ --temp-max 16 sync --compare-exec "cmp -s {src} {dst}" --compare-exec-input file dir/ s3://bucket/
+func TestSyncCompareExecWithTempMax(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("cmp is not available on windows")
+	}
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	putFile(t, s3client, bucket, "a.txt", "content of a")
+	putFile(t, s3client, bucket, "large.txt", "content of the large file")
+
+	workdir := fs.NewDir(t, "somedir",
+		fs.WithFile("a.txt", "content of a"),
+		fs.WithFile("large.txt", "changed content of the large file"),
+	)
+	defer workdir.Remove()
+	tempdir := fs.NewDir(t, "tempdir")
+	defer tempdir.Remove()
+
+	src := filepath.ToSlash(workdir.Path()) + "/"
+	dst := fmt.Sprintf("s3://%v/", bucket)
+
+	// the objects are compared one by one, so that the temporary file of the
+	// other object is removed before the large one is compared.
+	cmd := s5cmd("--temp-dir", tempdir.Path(), "--temp-max", "16", "sync", "--sync-strategy", "always", "--compare-exec", "cmp -s {src} {dst}", "--compare-exec-input", "file", "--compare-exec-concurrency", "1", src, dst)
+	result := icmd.RunCmd(cmd)
+
+	// the failures of the compare command are reported, and the objects are
+	// synced by default.
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %vlarge.txt %vlarge.txt`, src, dst),
+		1: equals(`sync: 2 objects compared with compare command, 1 equivalent, 0 different, 1 failed`),
+	}, strictLineCheck(true))
+
+	// the object which doesn't fit into the temporary files fails before it
+	// is downloaded.
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "sync %vlarge.txt %vlarge.txt": compare command failed: spool exhausted: 25 is required, 16 of 16 is available`, src, dst),
+	}, strictLineCheck(true))
+
+	assert.Assert(t, ensureS3Object(s3client, bucket, "large.txt", "changed content of the large file"))
+
+	// the temporary files of the run are removed with their directory.
+	entries, err := os.ReadDir(tempdir.Path())
+	assert.NilError(t, err)
+	assert.Equal(t, len(entries), 0)
+}
+
+// sync --compare-exec /nonexistent/compare --compare-exec-on-error skip dir/ s3://bucket/
+func TestSyncCompareExecOnErrorSkip(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	putFile(t, s3client, bucket, "a.txt", "content of a")
+
+	workdir := fs.NewDir(t, "somedir",
+		fs.WithFile("a.txt", "changed content of a"),
+	)
+	defer workdir.Remove()
+
+	src := filepath.ToSlash(workdir.Path()) + "/"
+	dst := fmt.Sprintf("s3://%v/", bucket)
+
+	cmd := s5cmd("sync", "--sync-strategy", "always", "--compare-exec", "/nonexistent/compare {src} {dst}", "--compare-exec-on-error", "skip", src, dst)
+	result := icmd.RunCmd(cmd)
+
+	// the failures of the compare command are reported, but they don't fail
+	// the sync.
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`sync: 1 objects compared with compare command, 0 equivalent, 0 different, 1 failed`),
+	}, strictLineCheck(true))
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: prefix(`ERROR "sync %va.txt %va.txt": compare command failed:`, src, dst),
+	}, strictLineCheck(true))
+
+	// the object is not synced.
+	assert.Assert(t, ensureS3Object(s3client, bucket, "a.txt", "content of a"))
+}
+
+func TestSyncCompareExecValidation(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "compare-exec-input without compare-exec",
+			args:     []string{"sync", "--compare-exec-input", "file", "folder/", "s3://bucket/"},
+			expected: `ERROR "sync --compare-exec-input=file folder/ s3://bucket/": "compare-exec-input" flag requires "compare-exec" flag`,
+		},
+		{
+			name:     "zero concurrency",
+			args:     []string{"sync", "--compare-exec", "cmp", "--compare-exec-concurrency", "0", "folder/", "s3://bucket/"},
+			expected: `ERROR "sync --compare-exec=cmp --compare-exec-concurrency=0 folder/ s3://bucket/": "compare-exec-concurrency" flag must be greater than zero`,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, s5cmd := setup(t)
+
+			result := icmd.RunCmd(s5cmd(tc.args...))
+			result.Assert(t, icmd.Expected{ExitCode: 1})
+
+			assertLines(t, result.Stderr(), map[int]compareFunc{
+				0: equals(tc.expected),
+			}, strictLineCheck(true))
+		})
+	}
+}
+
+func TestSyncMatchEncryptionValidation(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "without sse",
+			args:     []string{"--match-encryption", "folder/", "s3://bucket/"},
+			expected: `ERROR "sync --match-encryption=true folder/ s3://bucket/": "match-encryption" flag requires "sse" flag`,
+		},
+		{
+			name:     "local destination",
+			args:     []string{"--sse", "aws:kms", "--match-encryption", "s3://bucket/*", "folder/"},
+			expected: `ERROR "sync --match-encryption=true --sse=aws:kms s3://bucket/* folder/": "match-encryption" flag can only be used with remote destination`,
+		},
+		{
+			name:     "key alias",
+			args:     []string{"--sse", "aws:kms", "--sse-kms-key-id", "alias/key", "--match-encryption", "folder/", "s3://bucket/"},
+			expected: `ERROR "sync --match-encryption=true --sse=aws:kms --sse-kms-key-id=alias/key folder/ s3://bucket/": "match-encryption" flag requires the id or the ARN of the key, not an alias`,
+		},
+	}
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, s5cmd := setup(t)
+
+			cmd := s5cmd(append([]string{"sync"}, tc.args...)...)
+			result := icmd.RunCmd(cmd)
+
+			result.Assert(t, icmd.Expected{ExitCode: 1})
+
+			assertLines(t, result.Stderr(), map[int]compareFunc{
+				0: equals(tc.expected),
+			}, strictLineCheck(true))
+		})
+	}
+}
+
+// sync --sync-strategy etag s3://bucket/* s3://destbucket/
+func TestSyncS3BucketToS3BucketEtagStrategy(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	timeSource := newFixedTimeSource(now)
+	s3client, s5cmd := setup(t, withTimeSource(timeSource))
+
+	bucket := s3BucketFromTestName(t)
+	dstbucket := s3BucketFromTestNameWithPrefix(t, "dst")
+
+	createBucket(t, s3client, bucket)
+	createBucket(t, s3client, dstbucket)
+
+	// the destination objects are newer, and have the same sizes.
+	putFile(t, s3client, dstbucket, "changed.txt", "D: content")
+	putFile(t, s3client, dstbucket, "same.txt", "same content")
+
+	timeSource.Advance(-time.Minute)
+	putFile(t, s3client, bucket, "changed.txt", "S: content")
+	putFile(t, s3client, bucket, "same.txt", "same content")
+
+	src := fmt.Sprintf("s3://%v/", bucket)
+	dst := fmt.Sprintf("s3://%v/", dstbucket)
+
+	cmd := s5cmd("sync", "--sync-strategy", "etag", src+"*", dst)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %vchanged.txt %vchanged.txt`, src, dst),
+	}, strictLineCheck(true))
+
+	assert.Assert(t, ensureS3Object(s3client, dstbucket, "changed.txt", "S: content"))
+}
+
+// sync --sync-strategy always s3://bucket/* s3://destbucket/
+func TestSyncS3BucketToS3BucketAlwaysStrategy(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	dstbucket := s3BucketFromTestNameWithPrefix(t, "dst")
+
+	createBucket(t, s3client, bucket)
+	createBucket(t, s3client, dstbucket)
+
+	putFile(t, s3client, bucket, "same.txt", "same content")
+	putFile(t, s3client, dstbucket, "same.txt", "same content")
+
+	src := fmt.Sprintf("s3://%v/", bucket)
+	dst := fmt.Sprintf("s3://%v/", dstbucket)
+
+	cmd := s5cmd("sync", "--sync-strategy", "always", src+"*", dst)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %vsame.txt %vsame.txt`, src, dst),
+	}, strictLineCheck(true))
+}
+
+func TestSyncInvalidSyncStrategyFlags(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "etag strategy with local source",
+			args:     []string{"sync", "--sync-strategy", "etag", "dir/", "s3://bucket/"},
+			expected: `ERROR "sync --sync-strategy=etag dir/ s3://bucket/": "etag" sync strategy requires remote source and destination`,
+		},
+		{
+			name:     "size only with another strategy",
+			args:     []string{"sync", "--size-only", "--sync-strategy", "checksum", "dir/", "s3://bucket/"},
+			expected: `ERROR "sync --sync-strategy=checksum --size-only=true dir/ s3://bucket/": it is not allowed to combine "size-only" and "sync-strategy" flags`,
+		},
+		{
+			name:     "checksum with size only",
+			args:     []string{"sync", "--checksum", "--size-only", "dir/", "s3://bucket/"},
+			expected: `ERROR "sync --size-only=true --checksum=true dir/ s3://bucket/": it is not allowed to combine "checksum" and "size-only" flags`,
+		},
+		{
+			name:     "checksum with another strategy",
+			args:     []string{"sync", "--checksum", "--sync-strategy", "etag", "s3://bucket/", "s3://bucket2/"},
+			expected: `ERROR "sync --sync-strategy=etag --checksum=true s3://bucket/ s3://bucket2/": it is not allowed to combine "checksum" and "sync-strategy" flags`,
+		},
+		{
+			name:     "prefer source with size strategy",
+			args:     []string{"sync", "--prefer-source", "--sync-strategy", "size", "dir/", "s3://bucket/"},
+			expected: `ERROR "sync --sync-strategy=size --prefer-source=true dir/ s3://bucket/": "prefer-source" flag requires "size-mtime" or "checksum" sync strategy`,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, s5cmd := setup(t)
+
+			result := icmd.RunCmd(s5cmd(tc.args...))
+
+			result.Assert(t, icmd.Expected{ExitCode: 1})
+
+			assertLines(t, result.Stderr(), map[int]compareFunc{
+				0: equals(tc.expected),
+			})
+		})
+	}
+}
+
+// sync --delete --delete-batch-interleave 2 folder/ s3://bucket/
+func TestSyncLocalToS3BucketWithDeleteBatchInterleave(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	timestamp := fs.WithTimestamps(now.Add(-time.Minute), now.Add(-time.Minute))
+	workdir := fs.NewDir(t, "somedir",
+		fs.WithFile("a.txt", "S: a", timestamp),
+		fs.WithFile("c.txt", "S: c", timestamp),
+		fs.WithFile("e.txt", "S: e", timestamp),
+	)
+	defer workdir.Remove()
+
+	S3Content := map[string]string{
+		"b.txt": "D: b",
+		"d.txt": "D: d",
+		"f.txt": "D: f",
+	}
+	for filename, content := range S3Content {
+		putFile(t, s3client, bucket, filename, content)
+	}
+
+	src := filepath.ToSlash(workdir.Path()) + "/"
+	dst := fmt.Sprintf("s3://%v/", bucket)
+
+	cmd := s5cmd("sync", "--delete", "--delete-batch-interleave", "2", src, dst)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	lines := strings.Split(strings.TrimSpace(result.Stdout()), "\n")
+	index := func(line string) int {
+		for i, l := range lines {
+			if l == line {
+				return i
+			}
+		}
+		t.Fatalf("line %q not found in output:\n%v", line, result.Stdout())
+		return -1
+	}
+
+	// the objects only in destination are deleted after the files before
+	// them are uploaded.
+	copyA := index(fmt.Sprintf("cp %va.txt %va.txt", src, dst))
+	copyC := index(fmt.Sprintf("cp %vc.txt %vc.txt", src, dst))
+	copyE := index(fmt.Sprintf("cp %ve.txt %ve.txt", src, dst))
+	removeB := index(fmt.Sprintf("rm %vb.txt", dst))
+	removeD := index(fmt.Sprintf("rm %vd.txt", dst))
+	removeF := index(fmt.Sprintf("rm %vf.txt", dst))
+
+	assert.Assert(t, copyA < removeB, result.Stdout())
+	assert.Assert(t, copyC < removeD, result.Stdout())
+	assert.Assert(t, copyE < removeF, result.Stdout())
+	assert.Equal(t, 6, len(lines), result.Stdout())
+
+	for _, key := range []string{"a.txt", "c.txt", "e.txt"} {
+		assert.Assert(t, ensureS3Object(s3client, bucket, key, "S: "+key[:1]))
+	}
+	for key, content := range S3Content {
+		err := ensureS3Object(s3client, bucket, key, content)
+		assertError(t, err, errS3NoSuchKey)
+	}
+}
+
+// sync --delete --delete-batch-interleave 1 s3://bucket/* folder/
+func TestSyncS3BucketToLocalWithDeleteBatchInterleave(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	putFile(t, s3client, bucket, "b.txt", "S: b")
+	putFile(t, s3client, bucket, "d.txt", "S: d")
+
+	workdir := fs.NewDir(t, "somedir",
+		fs.WithFile("a.txt", "D: a"),
+		fs.WithFile("c.txt", "D: c"),
+	)
+	defer workdir.Remove()
+
+	src := fmt.Sprintf("s3://%v/*", bucket)
+	dst := filepath.ToSlash(workdir.Path()) + "/"
+
+	cmd := s5cmd("sync", "--delete", "--delete-batch-interleave", "1", src, dst)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp s3://%v/b.txt %vb.txt`, bucket, dst),
+		1: equals(`cp s3://%v/d.txt %vd.txt`, bucket, dst),
+		2: equals(`rm %va.txt`, dst),
+		3: equals(`rm %vc.txt`, dst),
+	}, sortInput(true))
+
+	expected := fs.Expected(t,
+		fs.WithFile("b.txt", "S: b"),
+		fs.WithFile("d.txt", "S: d"),
+	)
+	assert.Assert(t, fs.Equal(workdir.Path(), expected))
+}
+
+func TestSyncDeleteBatchInterleaveValidation(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "without delete",
+			args:     []string{"sync", "--delete-batch-interleave", "2", "dir/", "s3://bucket/"},
+			expected: `ERROR "sync --delete-batch-interleave=2 dir/ s3://bucket/": "delete-batch-interleave" flag requires "delete" flag`,
+		},
+		{
+			name:     "zero batch size",
+			args:     []string{"sync", "--delete", "--delete-batch-interleave", "0", "dir/", "s3://bucket/"},
+			expected: `ERROR "sync --delete=true --delete-batch-interleave=0 dir/ s3://bucket/": delete-batch-interleave must be greater than zero`,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, s5cmd := setup(t)
+
+			result := icmd.RunCmd(s5cmd(tc.args...))
+
+			result.Assert(t, icmd.Expected{ExitCode: 1})
+
+			assertLines(t, result.Stderr(), map[int]compareFunc{
+				0: equals(tc.expected),
+			})
+		})
+	}
+}
+
+// --stats-csv stats.csv sync --pin-versions s3://bucket/* dir/
+func TestSyncS3BucketToLocalWithPinVersions(t *testing.T) {
+	t.Parallel()
+
+	bucket := s3BucketFromTestName(t)
+
+	// versioninng is only supported with in memory backend!
+	s3client, s5cmd := setup(t, withS3Backend("mem"))
+
+	createBucket(t, s3client, bucket)
+	setBucketVersioning(t, s3client, bucket, "Enabled")
+
+	putFile(t, s3client, bucket, "a.txt", "first content of a")
+	putFile(t, s3client, bucket, "a.txt", "second content of a")
+	putFile(t, s3client, bucket, "b.txt", "content of b")
+	putFile(t, s3client, bucket, "c.txt", "content of c")
+	deleteObject(t, s3client, bucket, "c.txt")
+
+	versions := latestVersions(t, s3client, bucket)
+
+	workdir := fs.NewDir(t, t.Name(), fs.WithFile("b.txt", "old content of b"))
+	defer workdir.Remove()
+
+	statsfile := filepath.Join(t.TempDir(), "stats.csv")
+	bucketPath := fmt.Sprintf("s3://%v", bucket)
+	src := fmt.Sprintf("%v/*", bucketPath)
+	dst := filepath.ToSlash(workdir.Path()) + "/"
+
+	cmd := s5cmd("--stats-csv", statsfile, "sync", "--pin-versions", "--size-only", src, dst)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %v/a.txt %va.txt`, bucketPath, dst),
+		1: equals(`cp %v/b.txt %vb.txt`, bucketPath, dst),
+	}, sortInput(true))
+
+	expected := fs.Expected(t,
+		fs.WithFile("a.txt", "second content of a"),
+		fs.WithFile("b.txt", "content of b"),
+	)
+	assert.Assert(t, fs.Equal(workdir.Path(), expected))
+
+	// the report documents the copied version of each object.
+	content, err := os.ReadFile(statsfile)
+	assert.NilError(t, err)
+
+	assertLines(t, string(content), map[int]compareFunc{
+		0: match(fmt.Sprintf(`^cp,%v/a.txt,19,[0-9.]+,[0-9]+,0,succeeded,%v$`, bucketPath, regexp.QuoteMeta(versions["a.txt"]))),
+		1: match(fmt.Sprintf(`^cp,%v/b.txt,12,[0-9.]+,[0-9]+,0,succeeded,%v$`, bucketPath, regexp.QuoteMeta(versions["b.txt"]))),
+		2: equals("operation,key,bytes,duration,throughput,retries,status,version_id"),
+	}, sortInput(true))
+}
+
+func TestSyncPinVersionsWithLocalSource(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd := setup(t)
+
+	workdir := fs.NewDir(t, t.Name(), fs.WithFile("a.txt", "content"))
+	defer workdir.Remove()
+
+	src := filepath.ToSlash(workdir.Path()) + "/"
+
+	cmd := s5cmd("sync", "--pin-versions", src, "s3://bucket/")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "sync --pin-versions=true %v s3://bucket/": "pin-versions" flag can only be used with remote source`, src),
+	})
+}
+
+// sync --website-redirect /index.html --system-header Content-Type=text/plain folder/ s3://bucket/
+func TestSyncLocalFolderToS3BucketWithWebsiteRedirect(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	workdir := fs.NewDir(t, "somedir",
+		fs.WithFile("old.html", "moved"),
+		fs.WithDir("docs", fs.WithFile("old.html", "moved")),
+	)
+	defer workdir.Remove()
+
+	src := filepath.ToSlash(fmt.Sprintf("%v/", workdir.Path()))
+	dst := fmt.Sprintf("s3://%v/", bucket)
+
+	cmd := s5cmd("sync", "--website-redirect", "/index.html", "--system-header", "Content-Type=text/plain", src, dst)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	for _, key := range []string{"old.html", "docs/old.html"} {
+		assert.Assert(t, ensureS3Object(s3client, bucket, key, "moved", ensureContentType("text/plain"), ensureWebsiteRedirect("/index.html")))
+	}
+}
+
+// sync --delete --dest-date-prefix 2006/01/02 folder/ s3://bucket/logs/
+func TestSyncLocalFolderToS3BucketWithDestDatePrefix(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	day := time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC)
+	workdir := fs.NewDir(t, "somedir",
+		fs.WithFile("a.log", "content", fs.WithTimestamps(day, day)),
+		fs.WithDir("app",
+			fs.WithFile("b.log", "content", fs.WithTimestamps(day.AddDate(0, 0, 1), day.AddDate(0, 0, 1))),
+		),
+	)
+	defer workdir.Remove()
+
+	putFile(t, s3client, bucket, "logs/2024/01/01/old.log", "content")
+
+	src := filepath.ToSlash(fmt.Sprintf("%v/", workdir.Path()))
+	dst := fmt.Sprintf("s3://%v/logs/", bucket)
+
+	result := icmd.RunCmd(s5cmd("sync", "--delete", "--dest-date-prefix", "2006/01/02", src, dst))
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %va.log %v2024/01/02/a.log`, src, dst),
+		1: equals(`cp %vapp/b.log %v2024/01/03/app/b.log`, src, dst),
+		2: equals(`rm %v2024/01/01/old.log`, dst),
+	}, sortInput(true), strictLineCheck(true))
+
+	assert.Assert(t, ensureS3Object(s3client, bucket, "logs/2024/01/02/a.log", "content"))
+	assert.Assert(t, ensureS3Object(s3client, bucket, "logs/2024/01/03/app/b.log", "content"))
+
+	// the objects under the date prefixes are neither copied nor deleted
+	// again.
+	result = icmd.RunCmd(s5cmd("sync", "--delete", "--size-only", "--dest-date-prefix", "2006/01/02", src, dst))
+	result.Assert(t, icmd.Success)
+	assert.Equal(t, result.Stdout(), "")
+
+	assert.Assert(t, ensureS3Object(s3client, bucket, "logs/2024/01/02/a.log", "content"))
+	assert.Assert(t, ensureS3Object(s3client, bucket, "logs/2024/01/03/app/b.log", "content"))
+}
+
+// sync --transform-exec "tr a-z A-Z" folder/ s3://bucket/
+func TestSyncLocalFolderToS3BucketWithTransformExec(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("transform command requires tr")
+	}
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	past := time.Now().Add(-time.Hour)
+	workdir := fs.NewDir(t, "somedir",
+		fs.WithFile("a.txt", "content", fs.WithTimestamps(past, past)),
+	)
+	defer workdir.Remove()
+
+	src := filepath.ToSlash(fmt.Sprintf("%v/", workdir.Path()))
+	dst := fmt.Sprintf("s3://%v/", bucket)
+
+	result := icmd.RunCmd(s5cmd("sync", "--transform-exec", "tr a-z A-Z", src, dst))
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %va.txt %va.txt`, src, dst),
+	}, strictLineCheck(true))
+	assert.Assert(t, ensureS3Object(s3client, bucket, "a.txt", "CONTENT"))
+
+	// the transformed objects are compared by their modification times
+	// only, since their contents differ from the ones of their sources.
+	result = icmd.RunCmd(s5cmd("sync", "--transform-exec", "tr a-z A-Z", src, dst))
+	result.Assert(t, icmd.Success)
+	assert.Equal(t, result.Stdout(), "")
+}
+
+// sync --delete --exit-code-on-change folder/ s3://bucket/
+func TestSyncExitCodeOnChange(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	past := time.Now().Add(-time.Hour)
+	workdir := fs.NewDir(t, "somedir",
+		fs.WithFile("a.txt", "content", fs.WithTimestamps(past, past)),
+	)
+	defer workdir.Remove()
+
+	putFile(t, s3client, bucket, "b.txt", "content")
+
+	src := filepath.ToSlash(fmt.Sprintf("%v/", workdir.Path()))
+	dst := fmt.Sprintf("s3://%v/", bucket)
+
+	result := icmd.RunCmd(s5cmd("sync", "--delete", "--exit-code-on-change", src, dst))
+	result.Assert(t, icmd.Expected{ExitCode: 10})
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`changes made: copied 1 objects, deleted 1 objects`),
+		1: equals(`cp %va.txt %va.txt`, src, dst),
+		2: equals(`rm %vb.txt`, dst),
+	}, sortInput(true))
+	assertLines(t, result.Stderr(), map[int]compareFunc{})
+
+	// nothing is changed once the destination is in sync.
+	result = icmd.RunCmd(s5cmd("--json", "sync", "--delete", "--exit-code-on-change", src, dst))
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: contains(`"operation":"sync-changes","changes_made":false,"copied":0,"deleted":0}`),
+	}, strictLineCheck(true))
+}
+
+// sync --exit-code-on-change s3://bucket/* folder/
+func TestSyncExitCodeOnChangeWithFailures(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	putFile(t, s3client, bucket, "a.txt", "content")
+	putFile(t, s3client, bucket, "b.txt", "content")
+
+	// b.txt can't be downloaded since it exists as a directory.
+	workdir := fs.NewDir(t, "somedir",
+		fs.WithDir("b.txt", fs.WithFile("file", "content")),
+	)
+	defer workdir.Remove()
+
+	dst := filepath.ToSlash(fmt.Sprintf("%v/", workdir.Path()))
+
+	result := icmd.RunCmd(s5cmd("sync", "--on-type-conflict", "fail", "--exit-code-on-change", "s3://"+bucket+"/*", dst))
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`changes made: copied 1 objects, deleted 0 objects`),
+		1: equals(`cp s3://%v/a.txt %va.txt`, bucket, dst),
+	}, sortInput(true))
+}
+
+func TestSyncExitCodeOnChangeInRunMode(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd := setup(t)
+
+	file := fs.NewFile(t, "list", fs.WithContent("sync --exit-code-on-change dir/ s3://bucket/\n"))
+	defer file.Remove()
+
+	result := icmd.RunCmd(s5cmd("run", file.Path()))
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "%v:1: sync --exit-code-on-change=true dir/ s3://bucket/": "exit-code-on-change" flag is not permitted in run-mode`, file.Path()),
+	})
+}
+
+// sync --delete --exit-code folder/ s3://bucket/
+func TestSyncExitCode(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	past := time.Now().Add(-time.Hour)
+	workdir := fs.NewDir(t, "somedir",
+		fs.WithFile("a.txt", "content", fs.WithTimestamps(past, past)),
+	)
+	defer workdir.Remove()
+
+	putFile(t, s3client, bucket, "b.txt", "content")
+
+	src := filepath.ToSlash(fmt.Sprintf("%v/", workdir.Path()))
+	dst := fmt.Sprintf("s3://%v/", bucket)
+
+	// the changes are planned, but not made with --dry-run.
+	result := icmd.RunCmd(s5cmd("--dry-run", "sync", "--delete", "--exit-code", src, dst))
+	result.Assert(t, icmd.Expected{ExitCode: 2})
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %va.txt %va.txt`, src, dst),
+		1: equals(`rm %vb.txt`, dst),
+	}, sortInput(true), strictLineCheck(true))
+
+	assert.Assert(t, ensureS3Object(s3client, bucket, "b.txt", "content"))
+
+	result = icmd.RunCmd(s5cmd("sync", "--delete", "--exit-code", src, dst))
+	result.Assert(t, icmd.Expected{ExitCode: 2})
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %va.txt %va.txt`, src, dst),
+		1: equals(`rm %vb.txt`, dst),
+	}, sortInput(true), strictLineCheck(true))
+	assertLines(t, result.Stderr(), map[int]compareFunc{})
+
+	// nothing is planned once the destination is in sync.
+	result = icmd.RunCmd(s5cmd("sync", "--delete", "--exit-code", src, dst))
+	result.Assert(t, icmd.Success)
+	assertLines(t, result.Stdout(), map[int]compareFunc{}, strictLineCheck(true))
+}
+
+func TestSyncExitCodeValidation(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd := setup(t)
+
+	result := icmd.RunCmd(s5cmd("sync", "--exit-code", "--exit-code-on-change", "dir/", "s3://bucket/"))
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "sync --exit-code-on-change=true --exit-code=true dir/ s3://bucket/": it is not allowed to combine "exit-code" and "exit-code-on-change" flags`),
+	}, strictLineCheck(true))
+
+	file := fs.NewFile(t, "list", fs.WithContent("sync --exit-code dir/ s3://bucket/\n"))
+	defer file.Remove()
+
+	result = icmd.RunCmd(s5cmd("run", file.Path()))
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "%v:1: sync --exit-code=true dir/ s3://bucket/": "exit-code" flag is not permitted in run-mode`, file.Path()),
+	})
+}
+
+// sync --delete --summary folder/ s3://bucket/
+func TestSyncLocalFolderToS3BucketWithSummary(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	past := time.Now().Add(-time.Hour)
+	workdir := fs.NewDir(t, "somedir",
+		fs.WithFile("a.txt", "content a", fs.WithTimestamps(past, past)),
+		fs.WithFile("c.txt", "content", fs.WithTimestamps(past, past)),
+	)
+	defer workdir.Remove()
+
+	// c.txt is up-to-date and b.txt is only in destination.
+	putFile(t, s3client, bucket, "b.txt", "content")
+	putFile(t, s3client, bucket, "c.txt", "content")
+
+	src := filepath.ToSlash(fmt.Sprintf("%v/", workdir.Path()))
+	dst := fmt.Sprintf("s3://%v/", bucket)
+
+	result := icmd.RunCmd(s5cmd("sync", "--delete", "--summary", src, dst))
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %va.txt %va.txt`, src, dst),
+		1: equals(`rm %vb.txt`, dst),
+	}, sortInput(true), strictLineCheck(true))
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`summary: 1 objects copied, 9 bytes transferred, 1 deleted, 1 skipped, 0 failed`),
+	}, strictLineCheck(true))
+
+	// nothing is copied or deleted once the destination is in sync.
+	result = icmd.RunCmd(s5cmd("--json", "sync", "--delete", "--summary", src, dst))
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{}, strictLineCheck(true))
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`{"v":2,"operation":"sync-summary","copied":0,"bytes":0,"deleted":0,"skipped":2,"failed":0}`),
+	}, strictLineCheck(true))
+}
+
+// --dry-run sync --summary folder/ s3://bucket/
+func TestSyncDryRunWithSummary(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	workdir := fs.NewDir(t, "somedir", fs.WithFile("a.txt", "content"))
+	defer workdir.Remove()
+
+	src := filepath.ToSlash(fmt.Sprintf("%v/", workdir.Path()))
+	dst := fmt.Sprintf("s3://%v/", bucket)
+
+	// the summary of the plan is printed instead.
+	result := icmd.RunCmd(s5cmd("--dry-run", "sync", "--summary", src, dst))
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`dry-run: 1 objects to upload, 0 to update, 0 to delete, 7 bytes to transfer`),
+	}, strictLineCheck(true))
+}
+
+func TestSyncLocalFolderToS3BucketWithLayoutMismatch(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	workdir := fs.NewDir(t, "somedir", fs.WithFile("a.txt", "content"), fs.WithFile("b.txt", "content"))
+	defer workdir.Remove()
+
+	// the folder was synced without a trailing slash before.
+	name := filepath.Base(workdir.Path())
+	putFile(t, s3client, bucket, name+"/a.txt", "content")
+	putFile(t, s3client, bucket, name+"/b.txt", "content")
+
+	folder := filepath.ToSlash(workdir.Path())
+	src := folder + "/"
+	dst := fmt.Sprintf("s3://%v/", bucket)
+
+	// the objects only in destination are not deleted.
+	cmd := s5cmd("sync", "--delete", src, dst)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{}, strictLineCheck(true))
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "sync --delete=true %v %v": %v has the files of %q under "%v/", as synced from %q, but they are synced into it; use %q to sync into the same layout; refusing to delete the objects in destination with "delete" flag unless "layout-confirmed" flag is given`, src, dst, dst, src, name, folder, folder),
+	}, strictLineCheck(true))
+
+	for _, key := range []string{name + "/a.txt", name + "/b.txt"} {
+		assert.Assert(t, ensureS3Object(s3client, bucket, key, "content"))
+	}
+	err := ensureS3Object(s3client, bucket, "a.txt", "content")
+	assertError(t, err, errS3NoSuchKey)
+
+	// a warning is printed without --delete.
+	cmd = s5cmd("sync", src, dst)
+	result = icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %va.txt %va.txt`, src, dst),
+		1: equals(`cp %vb.txt %vb.txt`, src, dst),
+	}, sortInput(true), strictLineCheck(true))
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`WARNING %v has the files of %q under "%v/", as synced from %q, but they are synced into it; use %q to sync into the same layout, or "layout-confirmed" flag if it is intended`, dst, src, name, folder, folder),
+	}, strictLineCheck(true))
+
+	// the layout is confirmed, the objects synced without the trailing slash
+	// are deleted.
+	cmd = s5cmd("sync", "--delete", "--layout-confirmed", src, dst)
+	result = icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`rm %v%v/a.txt`, dst, name),
+		1: equals(`rm %v%v/b.txt`, dst, name),
+	}, sortInput(true), strictLineCheck(true))
+	assertLines(t, result.Stderr(), map[int]compareFunc{}, strictLineCheck(true))
+}
+
+func TestSyncLocalFolderToS3BucketWithContentsOnly(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	workdir := fs.NewDir(t, "somedir", fs.WithFile("a.txt", "content"), fs.WithFile("b.txt", "content"))
+	defer workdir.Remove()
+
+	// the contents of the folder were synced before.
+	putFile(t, s3client, bucket, "a.txt", "content")
+
+	name := filepath.Base(workdir.Path())
+	src := filepath.ToSlash(workdir.Path())
+	dst := fmt.Sprintf("s3://%v/", bucket)
+
+	cmd := s5cmd("sync", "--delete", src, dst)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{}, strictLineCheck(true))
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "sync --delete=true %v %v": %v has the files of %q in itself, as synced from "%v/", but they are synced under "%v/"; use "%v/" or "contents-only" flag to sync into the same layout; refusing to delete the objects in destination with "delete" flag unless "layout-confirmed" flag is given`, src, dst, dst, src, src, name, src),
+	}, strictLineCheck(true))
+
+	// the contents of the folder are synced into the bucket.
+	cmd = s5cmd("sync", "--delete", "--contents-only", src, dst)
+	result = icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %v/b.txt %vb.txt`, src, dst),
+	}, strictLineCheck(true))
+	assertLines(t, result.Stderr(), map[int]compareFunc{}, strictLineCheck(true))
+
+	for _, key := range []string{"a.txt", "b.txt"} {
+		assert.Assert(t, ensureS3Object(s3client, bucket, key, "content"))
+	}
+}
+
+// sync --preserve-metadata s3://bucket/* s3://destbucket/
+func TestSyncS3BucketToS3BucketWithPreserveMetadata(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	dstbucket := s3BucketFromTestNameWithPrefix(t, "dst")
+
+	createBucket(t, s3client, bucket)
+	createBucket(t, s3client, dstbucket)
+
+	const content = "id,name\n1,alice\n"
+
+	workdir := fs.NewDir(t, "somedir", fs.WithFile("users.csv", content))
+	defer workdir.Remove()
+
+	file := filepath.ToSlash(workdir.Join("users.csv"))
+	result := icmd.RunCmd(s5cmd("cp", "--content-type", "text/csv", "--metadata", "owner=alice", file, fmt.Sprintf("s3://%v/", bucket)))
+	result.Assert(t, icmd.Success)
+
+	assert.Assert(t, ensureS3Object(s3client, bucket, "users.csv", content,
+		ensureContentType("text/csv"), ensureMetadata("Owner", "alice")))
+
+	// the destination object has the same content, without the metadata.
+	putFile(t, s3client, dstbucket, "users.csv", content)
+
+	src := fmt.Sprintf("s3://%v/*", bucket)
+	dst := fmt.Sprintf("s3://%v/", dstbucket)
+
+	// the metadata is not compared by default.
+	result = icmd.RunCmd(s5cmd("sync", src, dst))
+
+	result.Assert(t, icmd.Success)
+	assertLines(t, result.Stdout(), map[int]compareFunc{}, strictLineCheck(true))
+
+	// the object is copied on the server side along with its metadata.
+	result = icmd.RunCmd(s5cmd("sync", "--preserve-metadata", src, dst))
+
+	result.Assert(t, icmd.Success)
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp s3://%v/users.csv %vusers.csv`, bucket, dst),
+	}, strictLineCheck(true))
+
+	// the metadata is given to the transformed objects, which are uploaded
+	// rather than copied.
+	upper := fmt.Sprintf("s3://%v/upper/", dstbucket)
+	result = icmd.RunCmd(s5cmd("sync", "--preserve-metadata", "--transform-exec", "tr a-z A-Z", src, upper))
+
+	result.Assert(t, icmd.Success)
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp s3://%v/users.csv %vusers.csv`, bucket, upper),
+	}, strictLineCheck(true))
+
+	assert.Assert(t, ensureS3Object(s3client, dstbucket, "upper/users.csv", strings.ToUpper(content),
+		ensureContentType("text/csv"), ensureMetadata("Owner", "alice")))
+
+	// the metadata survives the round trip, so nothing is synced.
+	result = icmd.RunCmd(s5cmd("sync", "--preserve-metadata", "--transform-exec", "tr a-z A-Z", src, upper))
+
+	result.Assert(t, icmd.Success)
+	assertLines(t, result.Stdout(), map[int]compareFunc{}, strictLineCheck(true))
+}
+
+func TestSyncPreserveMetadataWithLocalSource(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd := setup(t)
+
+	result := icmd.RunCmd(s5cmd("sync", "--preserve-metadata", "folder/", "s3://bucket/"))
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "sync --preserve-metadata=true folder/ s3://bucket/": "preserve-metadata" flag can only be used with remote source and destination`),
+	}, strictLineCheck(true))
+}
+
+// sync folder/ s3://bucket/ && sync s3://bucket/* folder2/
+func TestSyncLocalFolderToS3BucketAndBackPreservesMtime(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	timeSource := newFixedTimeSource(now)
+	s3client, s5cmd := setup(t, withTimeSource(timeSource))
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	modTime := now.Add(-24 * time.Hour)
+	timestamp := fs.WithTimestamps(modTime, modTime)
+	workdir := fs.NewDir(t, "somedir",
+		fs.WithFile("a.txt", "content of a", timestamp),
+		fs.WithDir("dir", fs.WithFile("b.txt", "content of b", timestamp)),
+	)
+	defer workdir.Remove()
+
+	outdir := fs.NewDir(t, "out")
+	defer outdir.Remove()
+
+	src := filepath.ToSlash(workdir.Path()) + "/"
+	out := filepath.ToSlash(outdir.Path()) + "/"
+	bucketpath := fmt.Sprintf("s3://%v/", bucket)
+
+	result := icmd.RunCmd(s5cmd("sync", src, bucketpath))
+	result.Assert(t, icmd.Success)
+
+	assert.Assert(t, ensureS3Object(s3client, bucket, "a.txt", "content of a",
+		ensureMetadata("S5cmd-Mtime", modTime.Format(time.RFC3339Nano))))
+
+	result = icmd.RunCmd(s5cmd("sync", bucketpath+"*", out))
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %va.txt %va.txt`, bucketpath, out),
+		1: equals(`cp %vdir/b.txt %vdir/b.txt`, bucketpath, out),
+	}, sortInput(true), strictLineCheck(true))
+
+	for _, name := range []string{"a.txt", "dir/b.txt"} {
+		st, err := os.Stat(outdir.Join(name))
+		assert.NilError(t, err)
+		assert.Assert(t, st.ModTime().Equal(modTime), "%v: expected %v, got %v", name, modTime, st.ModTime())
+	}
+
+	// the files are synced neither back nor again, although they are older
+	// than the objects.
+	for _, args := range [][]string{
+		{"sync", bucketpath + "*", out},
+		{"sync", out, bucketpath},
+	} {
+		result = icmd.RunCmd(s5cmd(args...))
+		result.Assert(t, icmd.Success)
+		assertLines(t, result.Stdout(), map[int]compareFunc{}, strictLineCheck(true))
+	}
+}
+
+// sync dir/ s3://bucket/ while the directories are renamed away and back.
+func TestSyncLocalFolderToS3BucketWhileDirectoriesAreRenamed(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("directories are walked by their file descriptors only on linux")
+	}
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	const (
+		dirCount  = 20
+		fileCount = 20
+	)
+
+	var ops []fs.PathOp
+	for i := 0; i < dirCount; i++ {
+		dir := fmt.Sprintf("d%v", i)
+		var files []fs.PathOp
+		for j := 0; j < fileCount; j++ {
+			file := fmt.Sprintf("f%v", j)
+			files = append(files, fs.WithFile(file, dir+"/"+file))
+		}
+		ops = append(ops, fs.WithDir(dir, files...))
+	}
+
+	workdir := fs.NewDir(t, bucket, ops...)
+	defer workdir.Remove()
+
+	done := make(chan struct{})
+	renamed := make(chan struct{})
+	go func() {
+		defer close(renamed)
+		for i := 0; ; i++ {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			dir := workdir.Join(fmt.Sprintf("d%v", i%dirCount))
+			if err := os.Rename(dir, dir+".moved"); err != nil {
+				continue
+			}
+			time.Sleep(time.Millisecond)
+			os.Rename(dir+".moved", dir)
+		}
+	}()
+
+	src := filepath.ToSlash(workdir.Path()) + "/"
+	dst := fmt.Sprintf("s3://%v/", bucket)
+
+	cmd := s5cmd("sync", src, dst)
+	result := icmd.RunCmd(cmd)
+
+	close(done)
+	<-renamed
+
+	result.Assert(t, icmd.Success)
+	assert.Assert(t, !strings.Contains(result.Stderr(), "ERROR"), result.Stderr())
+
+	// every uploaded object has the content of the file it's keyed by, no
+	// matter which name its directory had when it's walked.
+	cmd = s5cmd("ls", dst+"*")
+	result = icmd.RunCmd(cmd)
+	result.Assert(t, icmd.Success)
+
+	for _, line := range strings.Split(strings.TrimSpace(result.Stdout()), "\n") {
+		fields := strings.Fields(line)
+		key := fields[len(fields)-1]
+		content := strings.Replace(key, ".moved/", "/", 1)
+		assert.NilError(t, ensureS3Object(s3client, bucket, key, content))
+	}
+}
@@ -298,6 +298,13 @@ func s5cmd(workdir, endpoint string) func(args ...string) icmd.Cmd {
 				fmt.Sprintf("AWS_SECRET_ACCESS_KEY=%v", secret),
 			}...,
 		)
+
+		// the binary built with the race detector sleeps for a second before
+		// it exits successfully, which adds up to minutes over the commands
+		// of the tests. The races are reported as they are detected anyway.
+		if os.Getenv("GORACE") == "" {
+			env = append(env, "GORACE=atexit_sleep_ms=0")
+		}
 		cmd.Env = env
 		cmd.Dir = workdir
 		return cmd
@@ -508,12 +515,34 @@ func setBucketVersioning(t *testing.T, s3client *s3.S3, bucket string, versionin
 	}
 }
 
+// latestVersions returns the version IDs of the latest versions of the
+// objects in the bucket by their keys.
+func latestVersions(t *testing.T, s3client *s3.S3, bucket string) map[string]string {
+	t.Helper()
+	output, err := s3client.ListObjectVersions(&s3.ListObjectVersionsInput{
+		Bucket: aws.String(bucket),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	versions := map[string]string{}
+	for _, v := range output.Versions {
+		if aws.BoolValue(v.IsLatest) {
+			versions[aws.StringValue(v.Key)] = aws.StringValue(v.VersionId)
+		}
+	}
+	return versions
+}
+
 var errS3NoSuchKey = fmt.Errorf("s3: no such key")
 
 type ensureOpts struct {
 	contentType        *string
 	contentDisposition *string
+	websiteRedirect    *string
 	storageClass       *string
+	metadata           map[string]string
 }
 
 type ensureOption func(*ensureOpts)
@@ -530,12 +559,27 @@ func ensureContentDisposition(contentDisposition string) ensureOption {
 	}
 }
 
+func ensureWebsiteRedirect(location string) ensureOption {
+	return func(opts *ensureOpts) {
+		opts.websiteRedirect = &location
+	}
+}
+
 func ensureStorageClass(expected string) ensureOption {
 	return func(opts *ensureOpts) {
 		opts.storageClass = &expected
 	}
 }
 
+func ensureMetadata(key, value string) ensureOption {
+	return func(opts *ensureOpts) {
+		if opts.metadata == nil {
+			opts.metadata = map[string]string{}
+		}
+		opts.metadata[key] = value
+	}
+}
+
 func ensureS3Object(
 	client *s3.S3,
 	bucket string,
@@ -587,15 +631,62 @@ func ensureS3Object(
 
 	}
 
+	if opts.websiteRedirect != nil {
+		if diff := cmp.Diff(opts.websiteRedirect, output.WebsiteRedirectLocation); diff != "" {
+			return fmt.Errorf("website-redirect-location of %v/%v: (-want +got):\n%v", bucket, key, diff)
+		}
+	}
+
 	if opts.storageClass != nil {
 		if diff := cmp.Diff(opts.storageClass, output.StorageClass); diff != "" {
 			return fmt.Errorf("storage-class of %v/%v: (-want +got):\n%v", bucket, key, diff)
 		}
 	}
 
+	for metadataKey, expected := range opts.metadata {
+		if diff := cmp.Diff(expected, aws.StringValue(output.Metadata[metadataKey])); diff != "" {
+			return fmt.Errorf("metadata %q of %v/%v: (-want +got):\n%v", metadataKey, bucket, key, diff)
+		}
+	}
+
 	return nil
 }
 
+func deleteObject(t *testing.T, client *s3.S3, bucket string, key string) {
+	t.Helper()
+
+	_, err := client.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// countVersions returns the number of versions, including the delete markers,
+// of each key in the bucket.
+func countVersions(t *testing.T, client *s3.S3, bucket string) map[string]int {
+	t.Helper()
+
+	counts := map[string]int{}
+	err := client.ListObjectVersionsPages(&s3.ListObjectVersionsInput{
+		Bucket: aws.String(bucket),
+	}, func(p *s3.ListObjectVersionsOutput, lastPage bool) bool {
+		for _, v := range p.Versions {
+			counts[aws.StringValue(v.Key)]++
+		}
+		for _, d := range p.DeleteMarkers {
+			counts[aws.StringValue(d.Key)]++
+		}
+		return !lastPage
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return counts
+}
+
 func putFile(t *testing.T, client *s3.S3, bucket string, filename string, content string) {
 	t.Helper()
 
@@ -609,6 +700,33 @@ func putFile(t *testing.T, client *s3.S3, bucket string, filename string, conten
 	}
 }
 
+// createMultipartUpload starts a multipart upload of the key and uploads the
+// given parts to it without completing it.
+func createMultipartUpload(t *testing.T, client *s3.S3, bucket string, key string, parts ...string) {
+	t.Helper()
+
+	upload, err := client.CreateMultipartUpload(&s3.CreateMultipartUploadInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, part := range parts {
+		_, err := client.UploadPart(&s3.UploadPartInput{
+			Body:       strings.NewReader(part),
+			Bucket:     aws.String(bucket),
+			Key:        aws.String(key),
+			PartNumber: aws.Int64(int64(i + 1)),
+			UploadId:   upload.UploadId,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
 func replaceMatchWithSpace(input string, match ...string) string {
 	for _, m := range match {
 		if m == "" {
@@ -22,10 +22,16 @@ type Error struct {
 	Dst *url.URL
 	// The underlying error if any
 	Err error
+	// Position is the position of the command in the input of the run or
+	// sync command which runs it, e.g. "commands.txt:3", if any.
+	Position string
 }
 
 // FullCommand returns the command string that occurred at.
 func (e *Error) FullCommand() string {
+	if e.Position != "" {
+		return fmt.Sprintf("%v: %v %v %v", e.Position, e.Op, e.Src, e.Dst)
+	}
 	return fmt.Sprintf("%v %v %v", e.Op, e.Src, e.Dst)
 }
 
@@ -39,6 +45,40 @@ func (e *Error) Unwrap() error {
 	return e.Err
 }
 
+// ExitError is an error which sets the exit code of s5cmd to Code instead of
+// the default one. It deliberately does not implement cli.ExitCoder, which
+// makes the cli package exit even if the command is run by the run command.
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+// Error implements the error interface.
+func (e *ExitError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("exit code %d", e.Code)
+	}
+	return e.Err.Error()
+}
+
+// Unwrap unwraps the error.
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// ExitCode returns the exit code of s5cmd for the given error returned from a
+// command: 0 if there is no error, the code of an ExitError, and 1 otherwise.
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.Code
+	}
+	return 1
+}
+
 // IsCancelation reports whether if given error is a cancelation error.
 func IsCancelation(err error) bool {
 	if err == nil {
@@ -79,13 +119,52 @@ var (
 
 	// ErrObjectIsNewerAndSizesMatch indicates the specified object is newer or same age and sizes of objects match.
 	ErrObjectIsNewerAndSizesMatch = fmt.Errorf("%v and %v", ErrObjectIsNewer, ErrObjectSizesMatch)
+
+	// ErrObjectIsSameAge indicates a specified object has the same modification time.
+	ErrObjectIsSameAge = fmt.Errorf("object is same age")
+
+	// ErrObjectIsSameAgeAndSizesMatch indicates the specified object has the same modification time and sizes of objects match.
+	ErrObjectIsSameAgeAndSizesMatch = fmt.Errorf("%v and %v", ErrObjectIsSameAge, ErrObjectSizesMatch)
+
+	// ErrObjectChecksumsMatch indicates the checksums of the contents of
+	// objects match.
+	ErrObjectChecksumsMatch = fmt.Errorf("object checksum matches")
+
+	// ErrObjectEtagsMatch indicates the ETags of objects match.
+	ErrObjectEtagsMatch = fmt.Errorf("object etag matches")
+
+	// ErrObjectsEquivalent indicates the compare command finds the objects
+	// equivalent.
+	ErrObjectsEquivalent = fmt.Errorf("compare command finds objects equivalent")
+
+	// ErrObjectTimeout indicates an object operation did not complete within
+	// its per-object deadline.
+	ErrObjectTimeout = fmt.Errorf("object operation timed out")
+
+	// ErrTypeConflict indicates a file can't be written since its path, or
+	// the path of one of its parent directories, exists with another type.
+	ErrTypeConflict = fmt.Errorf("type conflict")
 )
 
+// IsTimeout reports whether the given error is caused by an exceeded
+// per-object deadline.
+func IsTimeout(err error) bool {
+	return errors.Is(err, ErrObjectTimeout)
+}
+
+// IsTypeConflict reports whether the given error is caused by a type conflict
+// on the destination.
+func IsTypeConflict(err error) bool {
+	return errors.Is(err, ErrTypeConflict)
+}
+
 // IsWarning checks if given error is either ErrObjectExists,
 // ErrObjectIsNewer or ErrObjectSizesMatch.
 func IsWarning(err error) bool {
 	switch err {
-	case ErrObjectExists, ErrObjectIsNewer, ErrObjectSizesMatch, ErrObjectIsNewerAndSizesMatch:
+	case ErrObjectExists, ErrObjectIsNewer, ErrObjectSizesMatch, ErrObjectIsNewerAndSizesMatch,
+		ErrObjectIsSameAge, ErrObjectIsSameAgeAndSizesMatch, ErrObjectChecksumsMatch, ErrObjectEtagsMatch,
+		ErrObjectsEquivalent:
 		return true
 	}
 
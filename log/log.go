@@ -3,12 +3,29 @@ package log
 import (
 	"fmt"
 	"os"
+	"strings"
+)
+
+const (
+	// OutputVersion1 is the schema of JSON records without a version field.
+	OutputVersion1 = 1
+	// OutputVersion2 is the schema of JSON records with a "v" field holding
+	// the schema version.
+	OutputVersion2 = 2
+
+	// LatestOutputVersion is the default schema version of JSON records.
+	LatestOutputVersion = OutputVersion2
 )
 
 // output is an internal container for messages to be logged.
 type output struct {
 	std     *os.File
 	message string
+	// terminator is written after the message.
+	terminator string
+	// flushed is closed once the messages before it are written, instead of
+	// writing a message.
+	flushed chan struct{}
 }
 
 // outputCh is used to synchronize writes to standard output. Multi-line
@@ -22,6 +39,12 @@ func Init(level string, json bool) {
 	global = New(level, json)
 }
 
+// SetOutputVersion sets the schema version of the JSON records printed by the
+// global logger.
+func SetOutputVersion(version int) {
+	global.outputVersion = version
+}
+
 // Trace prints message in trace mode.
 func Trace(msg Message) {
 	global.printf(LevelTrace, msg, os.Stdout)
@@ -43,11 +66,33 @@ func Stat(msg Message) {
 	global.printfHelper(LevelInfo, msg, os.Stdout)
 }
 
+// Summary prints summary message to standard error regardless of the log
+// level, so that it is not mixed with the output of the command on standard
+// output.
+func Summary(msg Message) {
+	global.printfHelper(LevelInfo, msg, os.Stderr)
+}
+
+// Warning prints message in warning mode.
+func Warning(msg Message) {
+	global.printf(LevelWarning, msg, os.Stderr)
+}
+
 // Error prints message in error mode.
 func Error(msg Message) {
 	global.printf(LevelError, msg, os.Stderr)
 }
 
+// Flush waits until the messages logged so far are written.
+func Flush() {
+	if global == nil {
+		return
+	}
+	flushed := make(chan struct{})
+	outputCh <- output{flushed: flushed}
+	<-flushed
+}
+
 // Close closes logger and its channel.
 func Close() {
 	if global != nil {
@@ -58,18 +103,20 @@ func Close() {
 
 // Logger is a structure for logging messages.
 type Logger struct {
-	donech chan struct{}
-	json   bool
-	level  LogLevel
+	donech        chan struct{}
+	json          bool
+	level         LogLevel
+	outputVersion int
 }
 
 // New creates new logger.
 func New(level string, json bool) *Logger {
 	logLevel := LevelFromString(level)
 	logger := &Logger{
-		donech: make(chan struct{}),
-		json:   json,
-		level:  logLevel,
+		donech:        make(chan struct{}),
+		json:          json,
+		level:         logLevel,
+		outputVersion: LatestOutputVersion,
 	}
 	go logger.out()
 	return logger
@@ -86,15 +133,49 @@ func (l *Logger) printf(level LogLevel, message Message, std *os.File) {
 func (l *Logger) printfHelper(level LogLevel, message Message, std *os.File) {
 	if l.json {
 		outputCh <- output{
-			message: message.JSON(),
-			std:     std,
+			message:    VersionedJSON(message, l.outputVersion),
+			std:        std,
+			terminator: "\n",
 		}
 	} else {
 		outputCh <- output{
-			message: fmt.Sprintf("%v%v", level, message.String()),
-			std:     std,
+			message:    fmt.Sprintf("%v%v", level, message.String()),
+			std:        std,
+			terminator: terminator(message),
+		}
+	}
+}
+
+// terminator returns the terminator of the text representation of the
+// message, which is a newline unless the message is a TerminatedMessage.
+func terminator(message Message) string {
+	if m, ok := message.(TerminatedMessage); ok {
+		return m.Terminator()
+	}
+	return "\n"
+}
+
+// VersionedJSON returns the JSON representation of the message in the given
+// schema version. Each record of the message is prefixed with the schema
+// version, except for the first version which has no version field.
+func VersionedJSON(message Message, version int) string {
+	records := message.JSON()
+	if version <= OutputVersion1 {
+		return records
+	}
+
+	field := fmt.Sprintf(`"v":%d`, version)
+
+	lines := strings.Split(records, "\n")
+	for i, line := range lines {
+		switch {
+		case line == "{}":
+			lines[i] = "{" + field + "}"
+		case strings.HasPrefix(line, "{"):
+			lines[i] = "{" + field + "," + line[1:]
 		}
 	}
+	return strings.Join(lines, "\n")
 }
 
 // out listens for outputCh and logs messages.
@@ -102,7 +183,11 @@ func (l *Logger) out() {
 	defer close(l.donech)
 
 	for output := range outputCh {
-		_, _ = fmt.Fprintln(output.std, output.message)
+		if output.flushed != nil {
+			close(output.flushed)
+			continue
+		}
+		_, _ = fmt.Fprint(output.std, output.message+output.terminator)
 	}
 }
 
@@ -113,6 +198,7 @@ const (
 	LevelTrace LogLevel = iota
 	LevelDebug
 	LevelInfo
+	LevelWarning
 	LevelError
 )
 
@@ -121,6 +207,8 @@ func (l LogLevel) String() string {
 	switch l {
 	case LevelInfo:
 		return ""
+	case LevelWarning:
+		return "WARNING "
 	case LevelError:
 		return "ERROR "
 	case LevelDebug:
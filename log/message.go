@@ -13,6 +13,14 @@ type Message interface {
 	JSON() string
 }
 
+// TerminatedMessage is a message whose text representation is terminated with
+// a string other than a newline, e.g. NUL for the keys which may contain
+// newlines. The JSON representation is always terminated with a newline.
+type TerminatedMessage interface {
+	Message
+	Terminator() string
+}
+
 // InfoMessage is a generic message structure for successful operations.
 type InfoMessage struct {
 	Operation   string   `json:"operation"`
@@ -20,6 +28,9 @@ type InfoMessage struct {
 	Source      *url.URL `json:"source,omitempty"`
 	Destination *url.URL `json:"destination,omitempty"`
 	Object      Message  `json:"object,omitempty"`
+	// URL is the URL of the uploaded object printed with --print-urls, if
+	// any.
+	URL string `json:"url,omitempty"`
 
 	// the VersionID field exist only for JSON Marshall, it must not be used for
 	// any other purpose.
@@ -29,6 +40,10 @@ type InfoMessage struct {
 // String is the string representation of InfoMessage.
 func (i InfoMessage) String() string {
 	if i.Source != nil && i.Destination != nil {
+		if i.URL != "" {
+			// the URL is printed on its own line to be read by the scripts.
+			return fmt.Sprintf("%v %v %v\n%v", i.Operation, i.Source, i.Destination, i.URL)
+		}
 		return fmt.Sprintf("%v %v %v", i.Operation, i.Source, i.Destination)
 	}
 	if i.Source != nil && i.Source.VersionID != "" {
@@ -42,7 +57,7 @@ func (i InfoMessage) String() string {
 
 // JSON is the JSON representation of InfoMessage.
 func (i InfoMessage) JSON() string {
-	if i.Destination == nil && i.Source != nil {
+	if i.Source != nil {
 		i.VersionID = i.Source.VersionID
 	}
 	i.Success = true
@@ -69,6 +84,23 @@ func (e ErrorMessage) JSON() string {
 	return strutil.JSON(e)
 }
 
+// WarningMessage is a generic message structure for the problems which do not
+// fail the operations.
+type WarningMessage struct {
+	Operation string `json:"operation,omitempty"`
+	Warning   string `json:"warning"`
+}
+
+// String is the string representation of WarningMessage.
+func (w WarningMessage) String() string {
+	return w.Warning
+}
+
+// JSON is the JSON representation of WarningMessage.
+func (w WarningMessage) JSON() string {
+	return strutil.JSON(w)
+}
+
 // DebugMessage is a generic message structure for unsuccessful operations.
 type DebugMessage struct {
 	Operation string `json:"operation,omitempty"`
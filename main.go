@@ -7,6 +7,7 @@ import (
 	"syscall"
 
 	"github.com/peak/s5cmd/v2/command"
+	errorpkg "github.com/peak/s5cmd/v2/error"
 )
 
 func main() {
@@ -14,6 +15,6 @@ func main() {
 	defer cancel()
 
 	if err := command.Main(ctx, os.Args); err != nil {
-		os.Exit(1)
+		os.Exit(errorpkg.ExitCode(err))
 	}
 }
@@ -21,3 +21,6 @@ func Close() {
 
 // Run runs global ParallelManager.
 func Run(task Task, waiter *Waiter) { global.Run(task, waiter) }
+
+// WorkerCount returns the number of workers of global ParallelManager.
+func WorkerCount() int { return cap(global.semaphore) }
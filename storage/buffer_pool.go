@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"sync"
+
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+// DefaultBufferPoolSize is the default size of the buffers used to move
+// object data between the network and local files.
+const DefaultBufferPoolSize = 64 * 1024
+
+// Every object transfer creates its own downloader and uploader, so the
+// buffer providers are shared process-wide, one pair for each buffer size.
+// Buffers are kept in a sync.Pool and reused across transfers instead of
+// being allocated for each object part, which reduces the allocation rate and
+// GC pressure when transferring many small objects.
+var bufferPools = struct {
+	sync.Mutex
+	pools map[int64]*bufferPool
+}{pools: map[int64]*bufferPool{}}
+
+type bufferPool struct {
+	download s3manager.WriterReadFromProvider
+	upload   s3manager.ReadSeekerWriteToProvider
+}
+
+// transferBufferPool returns the shared buffer providers of the given size.
+func transferBufferPool(size int64) *bufferPool {
+	bufferPools.Lock()
+	defer bufferPools.Unlock()
+
+	pool, ok := bufferPools.pools[size]
+	if !ok {
+		pool = &bufferPool{
+			download: s3manager.NewPooledBufferedWriterReadFromProvider(int(size)),
+			upload:   s3manager.NewBufferedReadSeekerWriteToPool(int(size)),
+		}
+		bufferPools.pools[size] = pool
+	}
+	return pool
+}
+
+// withDownloadBufferPool sets the pooled buffer provider of the given size to
+// the downloader. The default provider of the SDK is kept if size is 0.
+func withDownloadBufferPool(size int64) func(*s3manager.Downloader) {
+	return func(d *s3manager.Downloader) {
+		if size > 0 {
+			d.BufferProvider = transferBufferPool(size).download
+		}
+	}
+}
+
+// withUploadBufferPool sets the pooled buffer provider of the given size to
+// the uploader. The default provider of the SDK is kept if size is 0.
+func withUploadBufferPool(size int64) func(*s3manager.Uploader) {
+	return func(u *s3manager.Uploader) {
+		if size > 0 {
+			u.BufferProvider = transferBufferPool(size).upload
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/awstesting/unit"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+func newMockGetObjectAPI(content []byte) *s3.S3 {
+	mockAPI := s3.New(unit.Session)
+
+	mockAPI.Handlers.Unmarshal.Clear()
+	mockAPI.Handlers.UnmarshalMeta.Clear()
+	mockAPI.Handlers.UnmarshalError.Clear()
+	mockAPI.Handlers.Send.Clear()
+
+	mockAPI.Handlers.Send.PushBack(func(r *request.Request) {
+		// hide io.WriterTo of the reader like an actual response body does.
+		body := struct{ io.Reader }{bytes.NewReader(content)}
+		r.HTTPResponse = &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(body),
+		}
+
+		output := r.Data.(*s3.GetObjectOutput)
+		output.Body = r.HTTPResponse.Body
+		output.ContentLength = aws.Int64(int64(len(content)))
+		output.ContentRange = aws.String(fmt.Sprintf("bytes 0-%d/%d", len(content)-1, len(content)))
+	})
+
+	return mockAPI
+}
+
+func TestNewS3StorageBufferPool(t *testing.T) {
+	globalSessionCache.clear()
+
+	testcases := []struct {
+		name string
+		size int64
+	}{
+		{name: "default_size", size: DefaultBufferPoolSize},
+		{name: "custom_size", size: 1024 * 1024},
+		{name: "disabled", size: 0},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			opts := Options{
+				BufferPoolSize: tc.size,
+				// avoid fetching the region of the bucket.
+				region: "us-east-1",
+			}
+			client, err := newS3Storage(context.Background(), opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			downloader := client.downloader.(*s3manager.Downloader)
+			uploader := client.uploader.(*s3manager.Uploader)
+
+			if tc.size == 0 {
+				if _, ok := downloader.BufferProvider.(*s3manager.PooledBufferedReadFromProvider); ok {
+					t.Errorf("expected the downloader not to use the pooled buffer provider")
+				}
+				if _, ok := uploader.BufferProvider.(*s3manager.BufferedReadSeekerWriteToPool); ok {
+					t.Errorf("expected the uploader not to use the pooled buffer provider")
+				}
+				return
+			}
+
+			pool := transferBufferPool(tc.size)
+			if downloader.BufferProvider != pool.download {
+				t.Errorf("expected the downloader to use the pooled buffer provider of %v bytes", tc.size)
+			}
+			if uploader.BufferProvider != pool.upload {
+				t.Errorf("expected the uploader to use the pooled buffer provider of %v bytes", tc.size)
+			}
+		})
+	}
+}
+
+func TestS3GetWithBufferPool(t *testing.T) {
+	content := bytes.Repeat([]byte("s5cmd"), 100_000)
+
+	u, err := url.New("s3://bucket/key")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	mockS3 := &S3{
+		downloader: s3manager.NewDownloaderWithClient(newMockGetObjectAPI(content), withDownloadBufferPool(DefaultBufferPoolSize)),
+	}
+
+	// download twice to make sure the reused buffers do not leak data.
+	for i := 0; i < 2; i++ {
+		buf := aws.NewWriteAtBuffer(nil)
+		n, err := mockS3.Get(context.Background(), u, buf, 1, int64(len(content)))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if n != int64(len(content)) || !bytes.Equal(buf.Bytes(), content) {
+			t.Fatalf("downloaded content does not match, got %d bytes", n)
+		}
+	}
+}
+
+func BenchmarkS3GetSmallObjects(b *testing.B) {
+	content := bytes.Repeat([]byte("x"), 256*1024)
+
+	u, err := url.New("s3://bucket/key")
+	if err != nil {
+		b.Fatalf("unexpected error: %v", err)
+	}
+
+	benchmarks := []struct {
+		name    string
+		options []func(*s3manager.Downloader)
+	}{
+		{name: "without-buffer-pool", options: []func(*s3manager.Downloader){
+			func(d *s3manager.Downloader) { d.BufferProvider = nil },
+		}},
+		{name: "with-buffer-pool", options: []func(*s3manager.Downloader){withDownloadBufferPool(DefaultBufferPoolSize)}},
+	}
+
+	for _, bm := range benchmarks {
+		bm := bm
+		b.Run(bm.name, func(b *testing.B) {
+			mockAPI := newMockGetObjectAPI(content)
+			dst := aws.NewWriteAtBuffer(make([]byte, len(content)))
+
+			b.ReportAllocs()
+			b.SetBytes(int64(len(content)))
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				// a new downloader is created for each object, as it is done
+				// for each transfer.
+				mockS3 := &S3{
+					downloader: s3manager.NewDownloaderWithClient(mockAPI, bm.options...),
+				}
+				if _, err := mockS3.Get(context.Background(), u, dst, 1, int64(len(content))); err != nil {
+					b.Fatalf("unexpected error: %v", err)
+				}
+			}
+		})
+	}
+}
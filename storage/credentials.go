@@ -0,0 +1,209 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+
+	"github.com/peak/s5cmd/v2/log"
+)
+
+const (
+	// credentialsExpiryWindow is the duration before their expiration that
+	// the credentials are refreshed, so that the requests signed right
+	// before the expiration don't fail.
+	credentialsExpiryWindow = 5 * time.Minute
+
+	// credentialsRefreshTimeout is the duration that a failing refresh of the
+	// credentials is retried for.
+	credentialsRefreshTimeout = 5 * time.Minute
+
+	credentialsRefreshMinDelay = time.Second
+	credentialsRefreshMaxDelay = 30 * time.Second
+)
+
+// envPrefixProviderName is the name of the credentials provider which reads
+// the prefixed environment variables.
+const envPrefixProviderName = "EnvPrefixProvider"
+
+// envPrefixProvider is a credentials.Provider which reads the credentials
+// from the environment variables with the given prefix, e.g.
+// TENANT1_AWS_ACCESS_KEY_ID, so that multiple sets of credentials can coexist
+// in the environment of a process. Unlike the SDK, it doesn't fall back to
+// the other providers if the variables are not set.
+type envPrefixProvider struct {
+	prefix    string
+	retrieved bool
+}
+
+func newEnvPrefixCredentials(prefix string) *credentials.Credentials {
+	return credentials.NewCredentials(&envPrefixProvider{prefix: prefix})
+}
+
+// Retrieve implements credentials.Provider interface.
+func (p *envPrefixProvider) Retrieve() (credentials.Value, error) {
+	p.retrieved = false
+
+	id := p.lookup("AWS_ACCESS_KEY_ID", "AWS_ACCESS_KEY")
+	secret := p.lookup("AWS_SECRET_ACCESS_KEY", "AWS_SECRET_KEY")
+	if id == "" {
+		return credentials.Value{ProviderName: envPrefixProviderName},
+			fmt.Errorf("%vAWS_ACCESS_KEY_ID or %vAWS_ACCESS_KEY not found in environment", p.prefix, p.prefix)
+	}
+	if secret == "" {
+		return credentials.Value{ProviderName: envPrefixProviderName},
+			fmt.Errorf("%vAWS_SECRET_ACCESS_KEY or %vAWS_SECRET_KEY not found in environment", p.prefix, p.prefix)
+	}
+
+	p.retrieved = true
+	return credentials.Value{
+		AccessKeyID:     id,
+		SecretAccessKey: secret,
+		SessionToken:    os.Getenv(p.prefix + "AWS_SESSION_TOKEN"),
+		ProviderName:    envPrefixProviderName,
+	}, nil
+}
+
+// IsExpired implements credentials.Provider interface.
+func (p *envPrefixProvider) IsExpired() bool {
+	return !p.retrieved
+}
+
+// lookup returns the value of the first prefixed environment variable which
+// is set.
+func (p *envPrefixProvider) lookup(names ...string) string {
+	for _, name := range names {
+		if value := os.Getenv(p.prefix + name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// credentialPatterns match the access key IDs and the session tokens in the
+// requests dumped by the SDK, both in the headers and in the query strings.
+var credentialPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)(Credential=)[^/%,&\s]+`),
+	regexp.MustCompile(`(?i)(X-Amz-Security-Token[:=] ?)[^&\s]+`),
+}
+
+// redactCredentials replaces the credentials in the given log message.
+func redactCredentials(msg string) string {
+	for _, pattern := range credentialPatterns {
+		msg = pattern.ReplaceAllString(msg, "${1}[redacted]")
+	}
+	return msg
+}
+
+// refreshingProvider is a credentials.Provider which refreshes the given
+// credentials before they expire. Once the credentials are retrieved, the
+// failed refreshes are retried until credentialsRefreshTimeout rather than
+// failed. The requests wait for the credentials while they are refreshed, so
+// the dispatch of the requests pauses instead of failing all of them.
+type refreshingProvider struct {
+	creds *credentials.Credentials
+
+	expiryWindow   time.Duration
+	refreshTimeout time.Duration
+	minDelay       time.Duration
+	maxDelay       time.Duration
+
+	// retrieved reports whether the credentials are retrieved at least once.
+	retrieved bool
+	// expiration is the time the credentials are refreshed at. It is zero if
+	// the credentials don't expire.
+	expiration time.Time
+}
+
+func newRefreshingCredentials(creds *credentials.Credentials) *credentials.Credentials {
+	return credentials.NewCredentials(&refreshingProvider{
+		creds:          creds,
+		expiryWindow:   credentialsExpiryWindow,
+		refreshTimeout: credentialsRefreshTimeout,
+		minDelay:       credentialsRefreshMinDelay,
+		maxDelay:       credentialsRefreshMaxDelay,
+	})
+}
+
+// Retrieve implements credentials.Provider interface.
+func (p *refreshingProvider) Retrieve() (credentials.Value, error) {
+	return p.RetrieveWithContext(aws.BackgroundContext())
+}
+
+// RetrieveWithContext implements credentials.ProviderWithContext interface.
+func (p *refreshingProvider) RetrieveWithContext(ctx credentials.Context) (credentials.Value, error) {
+	if p.retrieved {
+		// the wrapped credentials may not be expired yet if they are
+		// refreshed within the expiry window.
+		p.creds.Expire()
+	}
+
+	value, err := p.creds.GetWithContext(ctx)
+	if err != nil && p.retrieved {
+		value, err = p.retry(ctx, err)
+	}
+	if err != nil {
+		return value, err
+	}
+
+	p.retrieved = true
+	p.expiration = time.Time{}
+	if expiresAt, err := p.creds.ExpiresAt(); err == nil && !expiresAt.IsZero() {
+		// refresh the short-lived credentials halfway through their
+		// lifetime at the latest, not to refresh them on every request.
+		window := p.expiryWindow
+		if remaining := time.Until(expiresAt); window > remaining/2 {
+			window = remaining / 2
+		}
+		p.expiration = expiresAt.Add(-window)
+	}
+	return value, nil
+}
+
+// retry retries the failed refresh of the credentials with an exponential
+// backoff until the refresh timeout.
+func (p *refreshingProvider) retry(ctx credentials.Context, err error) (credentials.Value, error) {
+	deadline := time.Now().Add(p.refreshTimeout)
+	delay := p.minDelay
+	for {
+		if time.Now().Add(delay).After(deadline) {
+			return credentials.Value{}, fmt.Errorf("failed to refresh credentials: %w", err)
+		}
+
+		log.Warning(log.WarningMessage{
+			Warning: fmt.Sprintf("failed to refresh credentials, retrying in %v: %v", delay, err),
+		})
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return credentials.Value{}, ctx.Err()
+		}
+
+		var value credentials.Value
+		value, err = p.creds.GetWithContext(ctx)
+		if err == nil {
+			return value, nil
+		}
+
+		delay *= 2
+		if delay > p.maxDelay {
+			delay = p.maxDelay
+		}
+	}
+}
+
+// IsExpired implements credentials.Provider interface.
+func (p *refreshingProvider) IsExpired() bool {
+	if !p.retrieved {
+		return true
+	}
+	if p.expiration.IsZero() {
+		return p.creds.IsExpired()
+	}
+	return !time.Now().Before(p.expiration)
+}
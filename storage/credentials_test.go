@@ -0,0 +1,242 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"gotest.tools/v3/assert"
+
+	"github.com/peak/s5cmd/v2/log"
+)
+
+// fakeProvider returns a new access key on each retrieval, after failing the
+// given number of times.
+type fakeProvider struct {
+	credentials.Expiry
+
+	lifetime time.Duration
+	failures int
+	calls    int
+}
+
+func (p *fakeProvider) Retrieve() (credentials.Value, error) {
+	p.calls++
+	if p.failures > 0 {
+		p.failures--
+		return credentials.Value{}, fmt.Errorf("broker is unavailable")
+	}
+
+	p.SetExpiration(time.Now().Add(p.lifetime), 0)
+	return credentials.Value{
+		AccessKeyID:     fmt.Sprintf("key%v", p.calls),
+		SecretAccessKey: "secret",
+	}, nil
+}
+
+func newTestRefreshingCredentials(p *fakeProvider, refreshTimeout time.Duration) *credentials.Credentials {
+	return credentials.NewCredentials(&refreshingProvider{
+		creds:          credentials.NewCredentials(p),
+		expiryWindow:   time.Hour,
+		refreshTimeout: refreshTimeout,
+		minDelay:       time.Millisecond,
+		maxDelay:       5 * time.Millisecond,
+	})
+}
+
+func TestRefreshingProviderRefreshesBeforeExpiry(t *testing.T) {
+	t.Parallel()
+
+	p := &fakeProvider{lifetime: 200 * time.Millisecond}
+	creds := newTestRefreshingCredentials(p, time.Second)
+
+	value, err := creds.Get()
+	assert.NilError(t, err)
+	assert.Equal(t, "key1", value.AccessKeyID)
+
+	value, err = creds.Get()
+	assert.NilError(t, err)
+	assert.Equal(t, "key1", value.AccessKeyID)
+
+	// the credentials are refreshed halfway through their lifetime, since
+	// the expiry window is longer than their lifetime.
+	time.Sleep(120 * time.Millisecond)
+
+	value, err = creds.Get()
+	assert.NilError(t, err)
+	assert.Equal(t, "key2", value.AccessKeyID)
+}
+
+func TestRefreshingProviderRetriesFailedRefresh(t *testing.T) {
+	log.Init("error", false)
+
+	p := &fakeProvider{lifetime: 20 * time.Millisecond}
+	creds := newTestRefreshingCredentials(p, time.Second)
+
+	_, err := creds.Get()
+	assert.NilError(t, err)
+
+	p.failures = 3
+	time.Sleep(30 * time.Millisecond)
+
+	value, err := creds.Get()
+	assert.NilError(t, err)
+	assert.Equal(t, "key5", value.AccessKeyID)
+}
+
+func TestRefreshingProviderFailsRefreshAfterTimeout(t *testing.T) {
+	log.Init("error", false)
+
+	p := &fakeProvider{lifetime: 20 * time.Millisecond}
+	creds := newTestRefreshingCredentials(p, 20*time.Millisecond)
+
+	_, err := creds.Get()
+	assert.NilError(t, err)
+
+	p.failures = 1000
+	time.Sleep(30 * time.Millisecond)
+
+	_, err = creds.Get()
+	assert.ErrorContains(t, err, "failed to refresh credentials")
+}
+
+func TestRefreshingProviderDoesNotRetryFirstRetrieval(t *testing.T) {
+	t.Parallel()
+
+	p := &fakeProvider{lifetime: time.Hour, failures: 1}
+	creds := newTestRefreshingCredentials(p, time.Second)
+
+	_, err := creds.Get()
+	assert.ErrorContains(t, err, "broker is unavailable")
+	assert.Equal(t, 1, p.calls)
+}
+
+const testCredentialProcessOutput = `{"Version": 1, "AccessKeyId": "process_key_id", "SecretAccessKey": "process_access_key"}`
+
+func TestNewSessionWithCredentialsCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the command is run by a POSIX shell")
+	}
+
+	globalSessionCache.clear()
+	sess, err := globalSessionCache.newSession(context.Background(), Options{
+		CredentialsCommand: fmt.Sprintf("echo '%v'", testCredentialProcessOutput),
+		LogLevel:           log.LevelError,
+	})
+	assert.NilError(t, err)
+
+	value, err := sess.Config.Credentials.Get()
+	assert.NilError(t, err)
+	assert.Equal(t, "process_key_id", value.AccessKeyID)
+	assert.Equal(t, "process_access_key", value.SecretAccessKey)
+}
+
+func TestNewSessionWithCredentialProcessProfile(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the command is run by a POSIX shell")
+	}
+
+	dir := t.TempDir()
+
+	command := filepath.Join(dir, "credentials.sh")
+	script := fmt.Sprintf("#!/bin/sh\necho '%v'\n", testCredentialProcessOutput)
+	assert.NilError(t, os.WriteFile(command, []byte(script), 0o755))
+
+	credentialFile := filepath.Join(dir, "credentials")
+	profiles := fmt.Sprintf(`[default]
+aws_access_key_id = default_profile_key_id
+aws_secret_access_key = default_profile_access_key
+
+[process]
+credential_process = %v
+`, command)
+	assert.NilError(t, os.WriteFile(credentialFile, []byte(profiles), 0o600))
+
+	globalSessionCache.clear()
+	sess, err := globalSessionCache.newSession(context.Background(), Options{
+		Profile:        "process",
+		CredentialFile: credentialFile,
+		LogLevel:       log.LevelError,
+	})
+	assert.NilError(t, err)
+
+	value, err := sess.Config.Credentials.Get()
+	assert.NilError(t, err)
+	assert.Equal(t, "process_key_id", value.AccessKeyID)
+	assert.Equal(t, "process_access_key", value.SecretAccessKey)
+}
+
+func TestEnvPrefixProvider(t *testing.T) {
+	t.Setenv("TENANT1_AWS_ACCESS_KEY_ID", "tenant1-key")
+	t.Setenv("TENANT1_AWS_SECRET_ACCESS_KEY", "tenant1-secret")
+	t.Setenv("TENANT1_AWS_SESSION_TOKEN", "tenant1-token")
+	t.Setenv("TENANT2_AWS_ACCESS_KEY", "tenant2-key")
+	t.Setenv("TENANT2_AWS_SECRET_KEY", "tenant2-secret")
+	t.Setenv("TENANT3_AWS_ACCESS_KEY_ID", "tenant3-key")
+
+	value, err := newEnvPrefixCredentials("TENANT1_").Get()
+	assert.NilError(t, err)
+	assert.Equal(t, "tenant1-key", value.AccessKeyID)
+	assert.Equal(t, "tenant1-secret", value.SecretAccessKey)
+	assert.Equal(t, "tenant1-token", value.SessionToken)
+	assert.Equal(t, envPrefixProviderName, value.ProviderName)
+
+	value, err = newEnvPrefixCredentials("TENANT2_").Get()
+	assert.NilError(t, err)
+	assert.Equal(t, "tenant2-key", value.AccessKeyID)
+	assert.Equal(t, "tenant2-secret", value.SecretAccessKey)
+	assert.Equal(t, "", value.SessionToken)
+
+	_, err = newEnvPrefixCredentials("TENANT3_").Get()
+	assert.ErrorContains(t, err, "TENANT3_AWS_SECRET_ACCESS_KEY or TENANT3_AWS_SECRET_KEY not found")
+
+	// the credentials without the prefix are not used.
+	t.Setenv("AWS_ACCESS_KEY_ID", "key")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	_, err = newEnvPrefixCredentials("TENANT4_").Get()
+	assert.ErrorContains(t, err, "TENANT4_AWS_ACCESS_KEY_ID or TENANT4_AWS_ACCESS_KEY not found")
+}
+
+func TestRedactCredentials(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		msg      string
+		expected string
+	}{
+		{
+			name:     "authorization header",
+			msg:      "Authorization: AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20240301/us-east-1/s3/aws4_request, SignedHeaders=host",
+			expected: "Authorization: AWS4-HMAC-SHA256 Credential=[redacted]/20240301/us-east-1/s3/aws4_request, SignedHeaders=host",
+		},
+		{
+			name:     "security token header",
+			msg:      "X-Amz-Security-Token: FwoGZXIvYXdzEJr//////////wEaDH\r\nUser-Agent: aws-sdk-go",
+			expected: "X-Amz-Security-Token: [redacted]\r\nUser-Agent: aws-sdk-go",
+		},
+		{
+			name:     "query string",
+			msg:      "GET /key?X-Amz-Credential=AKIDEXAMPLE%2F20240301%2Fus-east-1&X-Amz-Security-Token=FwoGZX%2F HTTP/1.1",
+			expected: "GET /key?X-Amz-Credential=[redacted]%2F20240301%2Fus-east-1&X-Amz-Security-Token=[redacted] HTTP/1.1",
+		},
+		{
+			name:     "no credentials",
+			msg:      "DEBUG: Request s3/ListObjectsV2 Details:",
+			expected: "DEBUG: Request s3/ListObjectsV2 Details:",
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.expected, redactCredentials(tc.msg))
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package storage
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	IPVersionAuto = "auto"
+	IPVersion4    = "4"
+	IPVersion6    = "6"
+)
+
+// Resolver looks up the addresses of the hosts. It is implemented by
+// *net.Resolver.
+type Resolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// Dialer dials the connections of the storage clients, constraining the
+// address family of the endpoints and pinning the addresses of the given
+// endpoints. Its HTTP transports are shared by all storage clients, so that
+// they share their connections as the clients using the default transport
+// do.
+type Dialer struct {
+	ipVersion string
+	// pinned is the addresses of the endpoints given as "host:port".
+	pinned   map[string]string
+	resolver Resolver
+	dialer   *net.Dialer
+
+	once     sync.Once
+	secure   *http.Client
+	insecure *http.Client
+}
+
+// NewDialer returns the dialer constraining the address family of the
+// endpoints to the given IP version, and pinning the endpoints to the
+// addresses given in curl's "host:port:addr" format. It returns nil if there
+// is nothing to change about the default dialer.
+func NewDialer(ipVersion string, resolve []string) (*Dialer, error) {
+	if ipVersion == "" {
+		ipVersion = IPVersionAuto
+	}
+	switch ipVersion {
+	case IPVersionAuto, IPVersion4, IPVersion6:
+	default:
+		return nil, fmt.Errorf("invalid ip version %q, it must be one of %q, %q or %q", ipVersion, IPVersionAuto, IPVersion4, IPVersion6)
+	}
+
+	pinned := map[string]string{}
+	for _, entry := range resolve {
+		endpoint, addr, err := parseResolveEntry(entry)
+		if err != nil {
+			return nil, err
+		}
+		if ipVersion != IPVersionAuto && ipVersionOf(net.ParseIP(addr)) != ipVersion {
+			return nil, fmt.Errorf("invalid resolve entry %q: %v is not an IPv%v address", entry, addr, ipVersion)
+		}
+		pinned[endpoint] = addr
+	}
+
+	if ipVersion == IPVersionAuto && len(pinned) == 0 {
+		return nil, nil
+	}
+
+	return &Dialer{
+		ipVersion: ipVersion,
+		pinned:    pinned,
+		resolver:  net.DefaultResolver,
+		// the timeouts of the default transport.
+		dialer: &net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		},
+	}, nil
+}
+
+// parseResolveEntry parses a "host:port:addr" entry, whose address may be an
+// IPv6 address in brackets, e.g. "s3.amazonaws.com:443:[2600:1f18::1]". It
+// returns the endpoint as "host:port", and the address.
+func parseResolveEntry(entry string) (string, string, error) {
+	invalid := func(reason string) error {
+		return fmt.Errorf("invalid resolve entry %q: %v, it must be in host:port:addr format", entry, reason)
+	}
+
+	host, rest, _ := strings.Cut(entry, ":")
+	if host == "" {
+		return "", "", invalid("missing host")
+	}
+	port, addr, ok := strings.Cut(rest, ":")
+	if port == "" {
+		return "", "", invalid("missing port")
+	}
+	if !ok {
+		return "", "", invalid("missing address")
+	}
+	if _, err := net.LookupPort("tcp", port); err != nil {
+		return "", "", invalid("invalid port")
+	}
+
+	addr = strings.TrimSuffix(strings.TrimPrefix(addr, "["), "]")
+	if net.ParseIP(addr) == nil {
+		return "", "", invalid("invalid address")
+	}
+	return net.JoinHostPort(strings.ToLower(host), port), addr, nil
+}
+
+// ipVersionOf returns the IP version of the address.
+func ipVersionOf(ip net.IP) string {
+	if ip.To4() != nil {
+		return IPVersion4
+	}
+	return IPVersion6
+}
+
+// network returns the network of the connections of the IP version.
+func (d *Dialer) network(network string) string {
+	switch d.ipVersion {
+	case IPVersion4:
+		return network + "4"
+	case IPVersion6:
+		return network + "6"
+	}
+	return network
+}
+
+// DialContext connects to the address on the named network. The pinned
+// endpoints are connected to their addresses. The addresses of the others are
+// looked up and filtered by the IP version, and connected in order until a
+// connection succeeds.
+func (d *Dialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+
+	if addr, ok := d.pinned[net.JoinHostPort(strings.ToLower(host), port)]; ok {
+		return d.dialer.DialContext(ctx, d.network(network), net.JoinHostPort(addr, port))
+	}
+
+	if net.ParseIP(host) != nil {
+		return d.dialer.DialContext(ctx, d.network(network), address)
+	}
+
+	addrs, err := d.resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	// the error of the first address is returned as the default dialer
+	// does.
+	var firstErr error
+	for _, addr := range addrs {
+		if d.ipVersion != IPVersionAuto && ipVersionOf(addr.IP) != d.ipVersion {
+			continue
+		}
+		conn, err := d.dialer.DialContext(ctx, d.network(network), net.JoinHostPort(addr.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	if firstErr == nil {
+		return nil, &net.DNSError{
+			Err:        fmt.Sprintf("no IPv%v address", d.ipVersion),
+			Name:       host,
+			IsNotFound: true,
+		}
+	}
+	return nil, firstErr
+}
+
+// client returns the HTTP client whose connections are dialed by d, which
+// skips the verification of the certificates if insecure is set.
+func (d *Dialer) client(insecure bool) *http.Client {
+	d.once.Do(func() {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.DialContext = d.DialContext
+
+		insecureTransport := transport.Clone()
+		insecureTransport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+
+		d.secure = &http.Client{Transport: transport}
+		d.insecure = &http.Client{Transport: insecureTransport}
+	})
+	if insecure {
+		return d.insecure
+	}
+	return d.secure
+}
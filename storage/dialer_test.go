@@ -0,0 +1,250 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+// fakeResolver resolves the hosts to the given addresses, and records the
+// hosts it is asked for.
+type fakeResolver struct {
+	addrs  map[string][]string
+	lookup []string
+}
+
+func (r *fakeResolver) LookupIPAddr(_ context.Context, host string) ([]net.IPAddr, error) {
+	r.lookup = append(r.lookup, host)
+
+	addrs, ok := r.addrs[host]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+
+	var result []net.IPAddr
+	for _, addr := range addrs {
+		result = append(result, net.IPAddr{IP: net.ParseIP(addr)})
+	}
+	return result, nil
+}
+
+// listen returns a listener on the IPv4 loopback address which accepts and
+// closes the connections.
+func listen(t *testing.T) (net.Listener, string) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp4", "127.0.0.1:0")
+	assert.NilError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	_, port, err := net.SplitHostPort(listener.Addr().String())
+	assert.NilError(t, err)
+	return listener, port
+}
+
+func TestNewDialer(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name        string
+		ipVersion   string
+		resolve     []string
+		expectNil   bool
+		expectedErr string
+		pinned      map[string]string
+	}{
+		{
+			name:      "default",
+			ipVersion: IPVersionAuto,
+			expectNil: true,
+		},
+		{
+			name:      "ipv6",
+			ipVersion: IPVersion6,
+			pinned:    map[string]string{},
+		},
+		{
+			name:      "resolve",
+			ipVersion: IPVersionAuto,
+			resolve:   []string{"S3.amazonaws.com:443:52.216.0.1", "bucket.s3.amazonaws.com:443:[2600:1f18::1]"},
+			pinned: map[string]string{
+				"s3.amazonaws.com:443":        "52.216.0.1",
+				"bucket.s3.amazonaws.com:443": "2600:1f18::1",
+			},
+		},
+		{
+			name:        "invalid ip version",
+			ipVersion:   "5",
+			expectedErr: `invalid ip version "5", it must be one of "auto", "4" or "6"`,
+		},
+		{
+			name:        "missing port",
+			ipVersion:   IPVersionAuto,
+			resolve:     []string{"s3.amazonaws.com"},
+			expectedErr: `invalid resolve entry "s3.amazonaws.com": missing port, it must be in host:port:addr format`,
+		},
+		{
+			name:        "missing address",
+			ipVersion:   IPVersionAuto,
+			resolve:     []string{"s3.amazonaws.com:443"},
+			expectedErr: `invalid resolve entry "s3.amazonaws.com:443": missing address, it must be in host:port:addr format`,
+		},
+		{
+			name:        "invalid port",
+			ipVersion:   IPVersionAuto,
+			resolve:     []string{"s3.amazonaws.com:https-port:52.216.0.1"},
+			expectedErr: `invalid resolve entry "s3.amazonaws.com:https-port:52.216.0.1": invalid port, it must be in host:port:addr format`,
+		},
+		{
+			name:        "invalid address",
+			ipVersion:   IPVersionAuto,
+			resolve:     []string{"s3.amazonaws.com:443:s3.example.com"},
+			expectedErr: `invalid resolve entry "s3.amazonaws.com:443:s3.example.com": invalid address, it must be in host:port:addr format`,
+		},
+		{
+			name:        "address of another ip version",
+			ipVersion:   IPVersion6,
+			resolve:     []string{"s3.amazonaws.com:443:52.216.0.1"},
+			expectedErr: `invalid resolve entry "s3.amazonaws.com:443:52.216.0.1": 52.216.0.1 is not an IPv6 address`,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			dialer, err := NewDialer(tc.ipVersion, tc.resolve)
+			if tc.expectedErr != "" {
+				assert.Error(t, err, tc.expectedErr)
+				return
+			}
+			assert.NilError(t, err)
+
+			if tc.expectNil {
+				assert.Assert(t, dialer == nil)
+				return
+			}
+			assert.Equal(t, dialer.ipVersion, tc.ipVersion)
+			assert.DeepEqual(t, dialer.pinned, tc.pinned)
+		})
+	}
+}
+
+func TestDialerIPVersion(t *testing.T) {
+	t.Parallel()
+
+	_, port := listen(t)
+
+	testcases := []struct {
+		name        string
+		ipVersion   string
+		addrs       []string
+		expectedErr bool
+	}{
+		{
+			name:      "ipv4 skips ipv6 addresses",
+			ipVersion: IPVersion4,
+			// the IPv6 address is not listened on, it would fail if it
+			// was dialed.
+			addrs: []string{"2001:db8::1", "127.0.0.1"},
+		},
+		{
+			name:        "ipv6 without ipv6 addresses",
+			ipVersion:   IPVersion6,
+			addrs:       []string{"127.0.0.1"},
+			expectedErr: true,
+		},
+		{
+			name:      "auto falls back to the next address",
+			ipVersion: IPVersionAuto,
+			// nothing listens on the port of the first address.
+			addrs: []string{"127.0.0.2", "127.0.0.1"},
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			// an endpoint is pinned, so that the dialer is created
+			// regardless of the ip version.
+			pinned := "pinned.example.com:1:127.0.0.1"
+			if tc.ipVersion == IPVersion6 {
+				pinned = "pinned.example.com:1:[::1]"
+			}
+			dialer, err := NewDialer(tc.ipVersion, []string{pinned})
+			assert.NilError(t, err)
+			dialer.resolver = &fakeResolver{
+				addrs: map[string][]string{"s3.example.com": tc.addrs},
+			}
+			// the addresses which are not listened on may not refuse the
+			// connections on some platforms.
+			dialer.dialer.Timeout = time.Second
+
+			conn, err := dialer.DialContext(context.Background(), "tcp", net.JoinHostPort("s3.example.com", port))
+			if tc.expectedErr {
+				var dnsErr *net.DNSError
+				assert.Assert(t, errors.As(err, &dnsErr), "expected a DNS error, got %v", err)
+				return
+			}
+			assert.NilError(t, err)
+			defer conn.Close()
+
+			assert.Equal(t, conn.RemoteAddr().String(), net.JoinHostPort("127.0.0.1", port))
+		})
+	}
+}
+
+func TestDialerResolve(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Host)
+	}))
+	defer server.Close()
+
+	_, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	assert.NilError(t, err)
+
+	dialer, err := NewDialer(IPVersionAuto, []string{"bucket.s3.example.com:" + port + ":127.0.0.1"})
+	assert.NilError(t, err)
+
+	resolver := &fakeResolver{}
+	dialer.resolver = resolver
+
+	resp, err := dialer.client(false).Get("http://bucket.s3.example.com:" + port + "/key")
+	assert.NilError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	assert.NilError(t, err)
+
+	// the request is sent to the pinned address with the host of the
+	// endpoint, without looking up the host.
+	assert.Equal(t, string(body), "bucket.s3.example.com:"+port)
+	assert.Equal(t, len(resolver.lookup), 0)
+
+	// the endpoints which are not pinned are looked up.
+	_, err = dialer.client(false).Get("http://other.s3.example.com:" + port + "/key")
+	assert.ErrorContains(t, err, "no such host")
+	assert.DeepEqual(t, resolver.lookup, []string{"other.s3.example.com"})
+}
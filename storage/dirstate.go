@@ -0,0 +1,306 @@
+package storage
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+// DirState is the state of the directories of the local walks, which is kept
+// between the runs to skip the stat calls of the files in the directories
+// which are not changed since the previous run.
+//
+// A directory is assumed to be unchanged if its modification time and the
+// number of its entries are the same as in the previous run. Then, its files
+// are listed with their sizes and modification times in the previous run,
+// without stat calls. Its subdirectories and symbolic links are still checked.
+// This is a heuristic: the modification time of a directory changes when its
+// entries are added, removed or renamed, but not when a file is written in
+// place, so such changes are not seen.
+type DirState struct {
+	path string
+	// full is set to walk all directories, recording their state without
+	// using the previous one.
+	full bool
+
+	mu       sync.Mutex
+	previous map[string]*dirRecord
+	current  map[string]*dirRecord
+	// unchanged is the number of the directories whose files are listed from
+	// the previous state.
+	unchanged int64
+}
+
+// dirRecord is the state of a directory.
+type dirRecord struct {
+	Path    string `json:"path"`
+	ModTime int64  `json:"mtime"`
+	Entries int    `json:"entries"`
+	// Files are the regular files in the directory.
+	Files []fileRecord `json:"files,omitempty"`
+	// Dirs are the names of the subdirectories.
+	Dirs []string `json:"dirs,omitempty"`
+	// Links are the names of the symbolic links, which are checked on each
+	// run since their targets may change independently of the directory.
+	Links []string `json:"links,omitempty"`
+}
+
+type fileRecord struct {
+	Name    string      `json:"name"`
+	Size    int64       `json:"size"`
+	ModTime int64       `json:"mtime"`
+	Mode    os.FileMode `json:"mode"`
+}
+
+// OpenDirState reads the state of the previous run from the file at path, if
+// it exists. If full is set, the previous state is not used, but the state of
+// the walk is still recorded.
+func OpenDirState(path string, full bool) (*DirState, error) {
+	state := &DirState{
+		path:     path,
+		full:     full,
+		previous: map[string]*dirRecord{},
+		current:  map[string]*dirRecord{},
+	}
+	if full {
+		return state, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return state, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	decoder := json.NewDecoder(bufio.NewReader(file))
+	for {
+		var record dirRecord
+		if err := decoder.Decode(&record); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("invalid directory state %q: %w", path, err)
+		}
+		state.previous[record.Path] = &record
+	}
+	return state, nil
+}
+
+// Save writes the state of the directories walked in this run to the file.
+// The file is replaced at once, so that the previous state is kept if the
+// state can't be written.
+func (s *DirState) Save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	paths := make([]string, 0, len(s.current))
+	for path := range s.current {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	w := bufio.NewWriter(file)
+	encoder := json.NewEncoder(w)
+	for _, path := range paths {
+		if err := encoder.Encode(s.current[path]); err != nil {
+			file.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), s.path)
+}
+
+// Unchanged returns the number of the directories whose files are listed from
+// the previous state.
+func (s *DirState) Unchanged() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.unchanged
+}
+
+// lookup returns the previous state of the directory at path if its
+// modification time and the number of its entries are not changed.
+func (s *DirState) lookup(path string, modTime time.Time, entries int) (*dirRecord, bool) {
+	if s.full {
+		return nil, false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, ok := s.previous[path]
+	if !ok || record.ModTime != modTime.UnixNano() || record.Entries != entries {
+		return nil, false
+	}
+	s.unchanged++
+	return record, true
+}
+
+// record records the state of the directory in this run.
+func (s *DirState) record(record *dirRecord) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.current[record.Path] = record
+}
+
+// walkDirState walks the directory at dir like walkDir, using the previous
+// state of the directories to skip the stat calls of the files in the
+// unchanged ones.
+func walkDirState(ctx context.Context, fs *Filesystem, src *url.URL, dir string, followSymlinks bool, fn func(o *Object)) error {
+	st, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+	names, err := readDirNames(dir)
+	if err != nil {
+		return err
+	}
+
+	newURL := func(name string) (*url.URL, error) {
+		fileurl, err := url.New(filepath.Join(dir, name), url.WithRaw(true))
+		if err != nil {
+			return nil, err
+		}
+		fileurl.SetRelative(src)
+		return fileurl, nil
+	}
+
+	walkLink := func(name string) error {
+		fileurl, err := newURL(name)
+		if err != nil {
+			return err
+		}
+		// skip if symlink and --no-follow-symlink
+		if !followSymlinks {
+			return nil
+		}
+		obj, err := fs.Stat(ctx, fileurl)
+		if err != nil {
+			if fs.handleBrokenSymlink(fileurl, fn) {
+				return nil
+			}
+			return err
+		}
+		if obj.Type.IsDir() {
+			return walkDirState(ctx, fs, src, fileurl.Absolute(), followSymlinks, fn)
+		}
+		fn(obj)
+		return nil
+	}
+
+	if record, ok := fs.dirState.lookup(dir, st.ModTime(), len(names)); ok {
+		for _, file := range record.Files {
+			fileurl, err := newURL(file.Name)
+			if err != nil {
+				return err
+			}
+			modTime := time.Unix(0, file.ModTime)
+			fn(&Object{
+				URL:     fileurl,
+				Type:    ObjectType{file.Mode},
+				Size:    file.Size,
+				ModTime: &modTime,
+			})
+		}
+		for _, name := range record.Dirs {
+			if err := walkDirState(ctx, fs, src, filepath.Join(dir, name), followSymlinks, fn); err != nil {
+				return err
+			}
+		}
+		for _, name := range record.Links {
+			if err := walkLink(name); err != nil {
+				return err
+			}
+		}
+		fs.dirState.record(record)
+		return nil
+	}
+
+	record := &dirRecord{
+		Path:    dir,
+		ModTime: st.ModTime().UnixNano(),
+		Entries: len(names),
+	}
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		lst, err := os.Lstat(path)
+		if err != nil {
+			return err
+		}
+
+		switch mode := lst.Mode(); {
+		case mode&os.ModeSymlink != 0:
+			record.Links = append(record.Links, name)
+			if err := walkLink(name); err != nil {
+				return err
+			}
+		case mode.IsDir():
+			record.Dirs = append(record.Dirs, name)
+			if err := walkDirState(ctx, fs, src, path, followSymlinks, fn); err != nil {
+				return err
+			}
+		default:
+			fileurl, err := newURL(name)
+			if err != nil {
+				return err
+			}
+			modTime := lst.ModTime()
+			record.Files = append(record.Files, fileRecord{
+				Name:    name,
+				Size:    lst.Size(),
+				ModTime: modTime.UnixNano(),
+				Mode:    mode,
+			})
+			fn(&Object{
+				URL:     fileurl,
+				Type:    ObjectType{mode},
+				Size:    lst.Size(),
+				ModTime: &modTime,
+			})
+		}
+	}
+	fs.dirState.record(record)
+	return nil
+}
+
+// readDirNames returns the names of the entries of the directory, sorted.
+func readDirNames(dir string) ([]string, error) {
+	f, err := os.Open(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	names, err := f.Readdirnames(-1)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	return names, nil
+}
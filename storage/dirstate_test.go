@@ -0,0 +1,134 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/peak/s5cmd/v2/log"
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+func TestFilesystemWalkWithDirState(t *testing.T) {
+	log.Init("debug", false)
+
+	root := t.TempDir()
+	for path, content := range map[string]string{
+		"a.txt":         "a",
+		"dir/b.txt":     "bb",
+		"dir/sub/c.txt": "ccc",
+	} {
+		path = filepath.Join(root, path)
+		assert.NilError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		assert.NilError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+	assert.NilError(t, os.Symlink(filepath.Join(root, "a.txt"), filepath.Join(root, "dir", "link.txt")))
+
+	statePath := filepath.Join(t.TempDir(), "state")
+	src, err := url.New(root + "/")
+	assert.NilError(t, err)
+
+	// walk lists the relative paths and the sizes of the files with a new
+	// state read from the state file, and saves the state.
+	walk := func(full bool) (map[string]int64, int64) {
+		t.Helper()
+
+		state, err := OpenDirState(statePath, full)
+		assert.NilError(t, err)
+
+		fs := NewLocalClient(Options{DirState: state})
+		files := map[string]int64{}
+		for obj := range fs.List(context.Background(), src, true) {
+			assert.NilError(t, obj.Err)
+			files[filepath.ToSlash(obj.URL.Relative())] = obj.Size
+		}
+		assert.NilError(t, state.Save())
+		return files, state.Unchanged()
+	}
+
+	expected := map[string]int64{
+		"a.txt":         1,
+		"dir/b.txt":     2,
+		"dir/link.txt":  1,
+		"dir/sub/c.txt": 3,
+	}
+
+	// the files are listed as they are without the state.
+	files := map[string]int64{}
+	for obj := range NewLocalClient(Options{}).List(context.Background(), src, true) {
+		assert.NilError(t, obj.Err)
+		files[filepath.ToSlash(obj.URL.Relative())] = obj.Size
+	}
+	assert.DeepEqual(t, expected, files)
+
+	files, unchanged := walk(false)
+	assert.DeepEqual(t, expected, files)
+	assert.Equal(t, int64(0), unchanged)
+
+	// all of the directories are listed from the state.
+	files, unchanged = walk(false)
+	assert.DeepEqual(t, expected, files)
+	assert.Equal(t, int64(3), unchanged)
+
+	// the files written in place are not seen, since the modification time of
+	// their directory doesn't change. the targets of the links are checked on
+	// each walk.
+	assert.NilError(t, os.WriteFile(filepath.Join(root, "a.txt"), []byte("aaaa"), 0o644))
+	files, unchanged = walk(false)
+	assert.Equal(t, int64(1), files["a.txt"])
+	assert.Equal(t, int64(4), files["dir/link.txt"])
+	assert.Equal(t, int64(3), unchanged)
+
+	// the new files change the modification time of their directory.
+	assert.NilError(t, os.WriteFile(filepath.Join(root, "dir", "sub", "d.txt"), []byte("dddd"), 0o644))
+	files, unchanged = walk(false)
+	assert.Equal(t, int64(4), files["dir/sub/d.txt"])
+	assert.Equal(t, int64(2), unchanged)
+
+	// the full walk doesn't use the state, but records it.
+	files, unchanged = walk(true)
+	assert.Equal(t, int64(4), files["a.txt"])
+	assert.Equal(t, int64(0), unchanged)
+
+	files, unchanged = walk(false)
+	assert.Equal(t, int64(4), files["a.txt"])
+	assert.Equal(t, int64(3), unchanged)
+}
+
+func TestFilesystemWalkWithDirStateNoFollowSymlinks(t *testing.T) {
+	log.Init("debug", false)
+
+	root := t.TempDir()
+	assert.NilError(t, os.WriteFile(filepath.Join(root, "a.txt"), []byte("a"), 0o644))
+	assert.NilError(t, os.Symlink(filepath.Join(root, "a.txt"), filepath.Join(root, "link.txt")))
+
+	state, err := OpenDirState(filepath.Join(t.TempDir(), "state"), false)
+	assert.NilError(t, err)
+
+	src, err := url.New(root + "/")
+	assert.NilError(t, err)
+
+	var files []string
+	for obj := range NewLocalClient(Options{DirState: state}).List(context.Background(), src, false) {
+		assert.NilError(t, obj.Err)
+		files = append(files, obj.URL.Relative())
+	}
+	sort.Strings(files)
+	assert.DeepEqual(t, []string{"a.txt"}, files)
+}
+
+func TestOpenDirStateInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state")
+	assert.NilError(t, os.WriteFile(path, []byte("not json"), 0o644))
+
+	_, err := OpenDirState(path, false)
+	assert.ErrorContains(t, err, "invalid directory state")
+
+	// the previous state is not read for a full walk.
+	_, err = OpenDirState(path, true)
+	assert.NilError(t, err)
+}
@@ -0,0 +1,61 @@
+package storage
+
+import (
+	"crypto/md5"
+	"fmt"
+	"io"
+
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+// UploadPartCount returns the number of parts which an object of the given
+// size is uploaded in by Put with the given part size, or 1 if it is uploaded
+// with a single request.
+func UploadPartCount(size, partSize int64) int {
+	partSize = adjustUploadPartSize(size, partSize)
+	if size <= partSize {
+		return 1
+	}
+	return int((size + partSize - 1) / partSize)
+}
+
+// MultipartEtag returns the ETag of the object which the content of r, of the
+// given size, is uploaded to with a multipart upload with the given part size:
+// the MD5 checksum of the MD5 checksums of its parts followed by
+// "-<number of parts>". The content is streamed, so only the checksum of a part
+// is kept in memory at a time.
+func MultipartEtag(r io.Reader, size, partSize int64) (string, error) {
+	if partSize <= 0 {
+		return "", fmt.Errorf("invalid part size %d", partSize)
+	}
+	partSize = adjustUploadPartSize(size, partSize)
+
+	sums := md5.New()
+	parts := 0
+	for remaining := size; parts == 0 || remaining > 0; parts++ {
+		n := partSize
+		if remaining < n {
+			n = remaining
+		}
+		h := md5.New()
+		if _, err := io.CopyN(h, r, n); err != nil {
+			return "", err
+		}
+		sums.Write(h.Sum(nil))
+		remaining -= n
+	}
+	return fmt.Sprintf("%x-%d", sums.Sum(nil), parts), nil
+}
+
+// adjustUploadPartSize returns the part size which an object of the given size
+// is uploaded with. The uploader increases the part size of the objects which
+// would have more than the maximum number of parts.
+func adjustUploadPartSize(size, partSize int64) int64 {
+	if partSize < MinPartSize {
+		partSize = MinPartSize
+	}
+	if size/partSize >= s3manager.MaxUploadParts {
+		partSize = size/s3manager.MaxUploadParts + 1
+	}
+	return partSize
+}
@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/md5"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"gotest.tools/v3/assert"
+)
+
+func TestUploadPartCount(t *testing.T) {
+	t.Parallel()
+
+	const M = MinPartSize
+
+	tests := []struct {
+		name     string
+		size     int64
+		partSize int64
+		expected int
+	}{
+		{name: "empty", size: 0, partSize: M, expected: 1},
+		{name: "single part", size: M, partSize: M, expected: 1},
+		{name: "two parts", size: M + 1, partSize: M, expected: 2},
+		{name: "exact parts", size: 3 * M, partSize: M, expected: 3},
+		{name: "part size below minimum", size: 2 * M, partSize: 1024, expected: 2},
+		{name: "too many parts", size: 2 * s3manager.MaxUploadParts * M, partSize: M, expected: s3manager.MaxUploadParts},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, UploadPartCount(tc.size, tc.partSize), tc.expected)
+		})
+	}
+}
+
+func TestMultipartEtag(t *testing.T) {
+	t.Parallel()
+
+	content := bytes.Repeat([]byte("0123456789abcdef"), (2*MinPartSize+1024)/16)
+	partSize := int64(MinPartSize)
+
+	etagOf := func(parts ...[]byte) string {
+		var sums []byte
+		for _, part := range parts {
+			sum := md5.Sum(part)
+			sums = append(sums, sum[:]...)
+		}
+		return fmt.Sprintf("%x-%d", md5.Sum(sums), len(parts))
+	}
+
+	etag, err := MultipartEtag(bytes.NewReader(content), int64(len(content)), partSize)
+	assert.NilError(t, err)
+	assert.Equal(t, etag, etagOf(content[:partSize], content[partSize:2*partSize], content[2*partSize:]))
+
+	// a single part.
+	small := content[:1024]
+	etag, err = MultipartEtag(bytes.NewReader(small), int64(len(small)), partSize)
+	assert.NilError(t, err)
+	assert.Equal(t, etag, etagOf(small))
+
+	// the content is shorter than its size, e.g. the file is truncated.
+	_, err = MultipartEtag(bytes.NewReader(content[:partSize]), int64(len(content)), partSize)
+	assert.Assert(t, errors.Is(err, io.EOF), "unexpected error: %v", err)
+
+	_, err = MultipartEtag(bytes.NewReader(content), int64(len(content)), 0)
+	assert.ErrorContains(t, err, "invalid part size")
+}
@@ -2,24 +2,66 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"syscall"
+	"time"
 
 	"github.com/karrick/godirwalk"
 	"github.com/termie/go-shutil"
 
+	"github.com/peak/s5cmd/v2/log"
 	"github.com/peak/s5cmd/v2/storage/url"
 )
 
+const (
+	// BrokenSymlinkSkip skips the symbolic links whose targets don't exist
+	// with a warning.
+	BrokenSymlinkSkip = "skip"
+	// BrokenSymlinkError reports the symbolic links whose targets don't exist
+	// as errors.
+	BrokenSymlinkError = "error"
+	// BrokenSymlinkUploadAsLink lists the symbolic links whose targets don't
+	// exist as they are, so that they are uploaded as objects holding their
+	// targets.
+	BrokenSymlinkUploadAsLink = "upload-as-link"
+)
+
+// ErrBrokenSymlink indicates a symbolic link whose target doesn't exist.
+var ErrBrokenSymlink = errors.New("broken symbolic link")
+
 // Filesystem is the Storage implementation of a local filesystem.
 type Filesystem struct {
 	dryRun bool
+
+	// onBrokenSymlink is the policy for the symbolic links whose targets
+	// don't exist. It is one of the BrokenSymlink constants, the empty value
+	// is BrokenSymlinkSkip.
+	onBrokenSymlink string
+
+	// readLimiter limits the files read at the same time and the rate of
+	// opening them. It is nil if the reads are not limited or tracked.
+	readLimiter *readLimiter
+
+	// dirState is the state of the directories of the previous walks, which
+	// is used to skip the files in the unchanged directories. It is nil if
+	// the directories are always walked.
+	dirState *DirState
 }
 
 // Stat returns the Object structure describing object.
 func (f *Filesystem) Stat(ctx context.Context, url *url.URL) (*Object, error) {
 	st, err := os.Stat(url.Absolute())
+	if err != nil && f.onBrokenSymlink == BrokenSymlinkUploadAsLink && isNotExistOrNotDir(err) {
+		// a broken symbolic link is described by itself, since the link is
+		// uploaded rather than its target.
+		if lst, lerr := os.Lstat(url.Absolute()); lerr == nil && lst.Mode()&os.ModeSymlink != 0 {
+			st, err = lst, nil
+		}
+	}
 	if err != nil {
 		if os.IsNotExist(err) {
 			return nil, &ErrGivenObjectNotFound{ObjectAbsPath: url.Absolute()}
@@ -37,6 +79,12 @@ func (f *Filesystem) Stat(ctx context.Context, url *url.URL) (*Object, error) {
 	}, nil
 }
 
+// StatMany stats the given files, running at most concurrency of them at
+// once.
+func (f *Filesystem) StatMany(ctx context.Context, urls []*url.URL, concurrency int) []StatResult {
+	return statMany(ctx, urls, concurrency, f.Stat)
+}
+
 // List returns the objects and directories reside in given src.
 func (f *Filesystem) List(ctx context.Context, src *url.URL, followSymlinks bool) <-chan *Object {
 	if src.IsWildcard() {
@@ -72,7 +120,7 @@ func (f *Filesystem) expandGlob(ctx context.Context, src *url.URL, followSymlink
 	go func() {
 		defer close(ch)
 
-		matchedFiles, err := filepath.Glob(src.Absolute())
+		matchedFiles, err := src.Glob()
 		if err != nil {
 			sendError(ctx, err, ch)
 			return
@@ -86,7 +134,7 @@ func (f *Filesystem) expandGlob(ctx context.Context, src *url.URL, followSymlink
 		for _, filename := range matchedFiles {
 			filename := filename
 
-			fileurl, err := url.New(filename)
+			fileurl, err := url.New(filename, url.WithRaw(true))
 			if err != nil {
 				sendError(ctx, err, ch)
 				return
@@ -96,6 +144,9 @@ func (f *Filesystem) expandGlob(ctx context.Context, src *url.URL, followSymlink
 
 			obj, err := f.Stat(ctx, fileurl)
 			if err != nil {
+				if f.handleBrokenSymlink(fileurl, func(obj *Object) { sendObject(ctx, obj, ch) }) {
+					continue
+				}
 				sendError(ctx, err, ch)
 				return
 			}
@@ -118,14 +169,27 @@ func walkDir(ctx context.Context, fs *Filesystem, src *url.URL, followSymlinks b
 	if !ShouldProcessURL(src, followSymlinks) {
 		return
 	}
-	err := godirwalk.Walk(src.Absolute(), &godirwalk.Options{
+	if fs.dirState != nil {
+		if err := walkDirState(ctx, fs, src, src.Absolute(), followSymlinks, fn); err != nil {
+			fn(&Object{Err: err})
+		}
+		return
+	}
+	if err := walkDirAt(ctx, fs, src, followSymlinks, fn); err != nil {
+		fn(&Object{Err: err})
+	}
+}
+
+// walkDirPath walks the directory of src by the paths of its entries.
+func walkDirPath(ctx context.Context, fs *Filesystem, src *url.URL, followSymlinks bool, fn func(o *Object)) error {
+	return godirwalk.Walk(src.Absolute(), &godirwalk.Options{
 		Callback: func(pathname string, dirent *godirwalk.Dirent) error {
 			// we're interested in files
 			if dirent.IsDir() {
 				return nil
 			}
 
-			fileurl, err := url.New(pathname)
+			fileurl, err := url.New(pathname, url.WithRaw(true))
 			if err != nil {
 				return err
 			}
@@ -139,18 +203,59 @@ func walkDir(ctx context.Context, fs *Filesystem, src *url.URL, followSymlinks b
 
 			obj, err := fs.Stat(ctx, fileurl)
 			if err != nil {
+				if fs.handleBrokenSymlink(fileurl, fn) {
+					return nil
+				}
 				return err
 			}
 
 			fn(obj)
 			return nil
 		},
+		ErrorCallback: func(pathname string, err error) godirwalk.ErrorAction {
+			// the broken symbolic links are already handled by the callback,
+			// the walk fails to follow them afterwards.
+			if _, ok := fs.BrokenSymlinkTarget(pathname); ok {
+				return godirwalk.SkipNode
+			}
+			return godirwalk.Halt
+		},
 		FollowSymbolicLinks: followSymlinks,
 	})
+}
+
+// handleBrokenSymlink applies the broken symbolic link policy if the given
+// URL is a symbolic link whose target doesn't exist. It reports whether the
+// URL is a broken symbolic link.
+func (f *Filesystem) handleBrokenSymlink(url *url.URL, fn func(o *Object)) bool {
+	target, ok := f.BrokenSymlinkTarget(url.Absolute())
+	if !ok {
+		return false
+	}
+
+	if f.onBrokenSymlink == BrokenSymlinkError {
+		fn(&Object{URL: url, Err: fmt.Errorf("%w %v -> %v", ErrBrokenSymlink, url, target)})
+		return true
+	}
+
+	log.Warning(log.WarningMessage{
+		Warning: fmt.Sprintf("skipping %v %v -> %v", ErrBrokenSymlink, url, target),
+	})
+	return true
+}
+
+// BrokenSymlinkTarget returns the target of the symbolic link at the given
+// path if the target doesn't exist. Otherwise, it returns false.
+func (f *Filesystem) BrokenSymlinkTarget(path string) (string, bool) {
+	if _, err := os.Stat(path); err == nil || !isNotExistOrNotDir(err) {
+		return "", false
+	}
+
+	target, err := os.Readlink(path)
 	if err != nil {
-		obj := &Object{Err: err}
-		fn(obj)
+		return "", false
 	}
+	return target, true
 }
 
 func (f *Filesystem) walkDir(ctx context.Context, src *url.URL, followSymlinks bool) <-chan *Object {
@@ -174,7 +279,14 @@ func (f *Filesystem) Copy(ctx context.Context, src, dst *url.URL, _ Metadata) er
 	if err := os.MkdirAll(dst.Dir(), os.ModePerm); err != nil {
 		return err
 	}
-	_, err := shutil.Copy(src.Absolute(), dst.Absolute(), true)
+
+	release, err := f.AcquireRead(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	_, err = shutil.Copy(src.Absolute(), dst.Absolute(), true)
 	return err
 }
 
@@ -205,6 +317,61 @@ func (f *Filesystem) MultiDelete(ctx context.Context, urlch <-chan *url.URL) <-c
 	return resultch
 }
 
+// RemoveAll removes the given path and its children, if any.
+func (f *Filesystem) RemoveAll(path string) error {
+	if f.dryRun {
+		return nil
+	}
+	return os.RemoveAll(path)
+}
+
+// TypeConflict is an existing entry which prevents writing a file to a path.
+type TypeConflict struct {
+	// Path is the path of the conflicting entry.
+	Path string
+	// IsDir reports whether the entry is a directory at the path of the
+	// file. Otherwise, it is a non-directory entry at the path of one of the
+	// parent directories of the file.
+	IsDir bool
+}
+
+// FindTypeConflict returns the entry which prevents writing a file to the
+// given path, or nil if the file can be written.
+func (f *Filesystem) FindTypeConflict(path string) (*TypeConflict, error) {
+	st, err := os.Lstat(path)
+	if err == nil {
+		if st.IsDir() {
+			return &TypeConflict{Path: path, IsDir: true}, nil
+		}
+		return nil, nil
+	}
+	if !isNotExistOrNotDir(err) {
+		return nil, err
+	}
+
+	// the nearest existing parent must be a directory, or a symbolic link to
+	// a directory.
+	for dir := filepath.Dir(path); ; dir = filepath.Dir(dir) {
+		st, err := os.Stat(dir)
+		if err == nil {
+			if st.IsDir() {
+				return nil, nil
+			}
+			return &TypeConflict{Path: dir}, nil
+		}
+		if !isNotExistOrNotDir(err) {
+			return nil, err
+		}
+		if filepath.Dir(dir) == dir {
+			return nil, nil
+		}
+	}
+}
+
+func isNotExistOrNotDir(err error) bool {
+	return errors.Is(err, fs.ErrNotExist) || errors.Is(err, syscall.ENOTDIR)
+}
+
 // MkdirAll calls os.MkdirAll.
 func (f *Filesystem) MkdirAll(path string) error {
 	if f.dryRun {
@@ -222,14 +389,32 @@ func (f *Filesystem) Create(path string) (*os.File, error) {
 	return os.Create(path)
 }
 
-// Open opens the given source.
-func (f *Filesystem) Open(path string) (*os.File, error) {
+// Open opens the given source for reading. It waits until the file can be
+// read with respect to the read limits, and returns the function releasing
+// the read, which must be called once the file is read.
+func (f *Filesystem) Open(ctx context.Context, path string) (*os.File, func(), error) {
+	release, err := f.AcquireRead(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	file, err := os.OpenFile(path, os.O_RDONLY, 0644)
 	if err != nil {
-		return nil, err
+		release()
+		return nil, nil, err
 	}
 
-	return file, nil
+	return file, release, nil
+}
+
+// AcquireRead waits until a file, or another local input such as the
+// standard input, can be read with respect to the read limits. It returns the
+// function releasing the read.
+func (f *Filesystem) AcquireRead(ctx context.Context) (func(), error) {
+	if f.readLimiter == nil {
+		return func() {}, nil
+	}
+	return f.readLimiter.acquire(ctx)
 }
 
 // CreateTemp creates a new temporary file
@@ -247,6 +432,87 @@ func (f *Filesystem) CreateTemp(dir, pattern string) (*os.File, error) {
 	return file, err
 }
 
+// Preallocate reserves disk space for the given file up to size bytes, so
+// that a full disk is reported before writing any data and the file is laid
+// out contiguously. It is a no-op on platforms and filesystems which don't
+// support preallocation.
+func (f *Filesystem) Preallocate(file *os.File, size int64) error {
+	if f.dryRun || size <= 0 {
+		return nil
+	}
+	return preallocate(file, size)
+}
+
+// ErrWindowsAttributesNotSupported indicates the Windows file attributes and
+// alternate data streams can not be read or written on the platform.
+var ErrWindowsAttributesNotSupported = fmt.Errorf("windows file attributes are not supported on this platform")
+
+// WindowsAttributes returns the read-only, hidden, system and archive
+// attributes of the file at the given path as a bitmask.
+func (f *Filesystem) WindowsAttributes(path string) (uint32, error) {
+	return windowsAttributes(path)
+}
+
+// SetWindowsAttributes sets the read-only, hidden, system and archive
+// attributes of the file at the given path to the ones of the bitmask, and
+// keeps its other attributes.
+func (f *Filesystem) SetWindowsAttributes(path string, attrs uint32) error {
+	if f.dryRun {
+		return nil
+	}
+	return setWindowsAttributes(path, attrs)
+}
+
+// Chtimes sets the modification time of the file at the given path. Its
+// access time is set to the same time.
+func (f *Filesystem) Chtimes(path string, modTime time.Time) error {
+	if f.dryRun {
+		return nil
+	}
+	return os.Chtimes(path, modTime, modTime)
+}
+
+// AlternateDataStreams returns the names of the alternate data streams of the
+// file at the given path, which are not part of its content.
+func (f *Filesystem) AlternateDataStreams(path string) ([]string, error) {
+	return alternateDataStreams(path)
+}
+
+// ErrDiskSpaceNotSupported indicates the free disk space can not be queried
+// on the platform.
+var ErrDiskSpaceNotSupported = fmt.Errorf("querying free disk space is not supported on this platform")
+
+// DiskSpace returns the number of bytes available to unprivileged users and the
+// ID of the device of the filesystem that the given path is on. If the path
+// doesn't exist yet, the nearest existing parent directory is used.
+func (f *Filesystem) DiskSpace(path string) (free int64, device uint64, err error) {
+	path, err = filepath.Abs(path)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for {
+		free, device, err = diskSpace(path)
+		if !os.IsNotExist(err) {
+			return free, device, err
+		}
+
+		parent := filepath.Dir(path)
+		if parent == path {
+			return free, device, err
+		}
+		path = parent
+	}
+}
+
+// Truncate changes the size of the given file.
+func (f *Filesystem) Truncate(file *os.File, size int64) error {
+	if f.dryRun {
+		return nil
+	}
+	return file.Truncate(size)
+}
+
 // Rename a file
 func (f *Filesystem) Rename(file *os.File, newpath string) error {
 	if f.dryRun {
@@ -256,6 +522,36 @@ func (f *Filesystem) Rename(file *os.File, newpath string) error {
 	return os.Rename(file.Name(), newpath)
 }
 
+// Link creates newpath as a hard link to oldpath, replacing newpath if it
+// exists. The link is created under a temporary name in the directory of
+// newpath and then renamed, so an existing newpath is replaced atomically.
+func (f *Filesystem) Link(oldpath, newpath string) error {
+	if f.dryRun {
+		return nil
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(newpath), filepath.Base(newpath))
+	if err != nil {
+		return err
+	}
+	tmp.Close()
+
+	// the temporary file only reserves a unique name for the link.
+	if err := os.Remove(tmp.Name()); err != nil {
+		return err
+	}
+
+	if err := os.Link(oldpath, tmp.Name()); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), newpath); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
 func sendObject(ctx context.Context, obj *Object, ch chan *Object) {
 	select {
 	case <-ctx.Done():
@@ -0,0 +1,8 @@
+//go:build !linux && !darwin && !freebsd
+// +build !linux,!darwin,!freebsd
+
+package storage
+
+func diskSpace(path string) (int64, uint64, error) {
+	return 0, 0, ErrDiskSpaceNotSupported
+}
@@ -0,0 +1,23 @@
+//go:build linux || darwin || freebsd
+// +build linux darwin freebsd
+
+package storage
+
+import (
+	"syscall"
+)
+
+func diskSpace(path string) (int64, uint64, error) {
+	var st syscall.Stat_t
+	if err := syscall.Stat(path, &st); err != nil {
+		return 0, 0, err
+	}
+
+	var fs syscall.Statfs_t
+	if err := syscall.Statfs(path, &fs); err != nil {
+		return 0, 0, err
+	}
+
+	// the types of the fields differ between platforms.
+	return int64(fs.Bavail) * int64(fs.Bsize), uint64(st.Dev), nil
+}
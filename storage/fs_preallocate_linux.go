@@ -0,0 +1,19 @@
+//go:build linux
+// +build linux
+
+package storage
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+func preallocate(file *os.File, size int64) error {
+	err := syscall.Fallocate(int(file.Fd()), 0, 0, size)
+	// fall back to regular writes if the filesystem doesn't support it.
+	if errors.Is(err, syscall.EOPNOTSUPP) || errors.Is(err, syscall.ENOSYS) {
+		return nil
+	}
+	return err
+}
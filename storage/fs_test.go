@@ -1,6 +1,19 @@
 package storage
 
-import "testing"
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/peak/s5cmd/v2/log"
+	"github.com/peak/s5cmd/v2/storage/url"
+)
 
 func TestFilesystemImplementsStorageInterface(t *testing.T) {
 	var i interface{} = new(Filesystem)
@@ -8,3 +21,216 @@ func TestFilesystemImplementsStorageInterface(t *testing.T) {
 		t.Errorf("expected %t to implement Storage interface", i)
 	}
 }
+
+func TestFilesystemPreallocate(t *testing.T) {
+	t.Parallel()
+
+	fs := NewLocalClient(Options{})
+
+	file, err := fs.CreateTemp(t.TempDir(), "preallocate")
+	assert.NilError(t, err)
+	defer file.Close()
+
+	const size = 1 << 20
+	assert.NilError(t, fs.Preallocate(file, size))
+
+	_, err = file.WriteString("content")
+	assert.NilError(t, err)
+	assert.NilError(t, fs.Truncate(file, int64(len("content"))))
+
+	st, err := os.Stat(file.Name())
+	assert.NilError(t, err)
+	assert.Equal(t, int64(len("content")), st.Size())
+
+	content, err := os.ReadFile(filepath.Clean(file.Name()))
+	assert.NilError(t, err)
+	assert.Equal(t, "content", string(content))
+}
+
+func TestFilesystemChtimes(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "file")
+	assert.NilError(t, os.WriteFile(path, []byte("content"), 0o644))
+
+	modTime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	dryRun := NewLocalClient(Options{DryRun: true})
+	assert.NilError(t, dryRun.Chtimes(path, modTime))
+	st, err := os.Stat(path)
+	assert.NilError(t, err)
+	assert.Assert(t, !st.ModTime().Equal(modTime))
+
+	fs := NewLocalClient(Options{})
+	assert.NilError(t, fs.Chtimes(path, modTime))
+	st, err = os.Stat(path)
+	assert.NilError(t, err)
+	assert.Assert(t, st.ModTime().Equal(modTime))
+}
+
+func TestFilesystemDiskSpace(t *testing.T) {
+	t.Parallel()
+
+	fs := NewLocalClient(Options{})
+	dir := t.TempDir()
+
+	free, device, err := fs.DiskSpace(dir)
+	if err == ErrDiskSpaceNotSupported {
+		t.Skip(err)
+	}
+	assert.NilError(t, err)
+	assert.Assert(t, free > 0)
+
+	// a path that doesn't exist yet is on the filesystem of its nearest
+	// existing parent.
+	missingFree, missingDevice, err := fs.DiskSpace(filepath.Join(dir, "missing", "file"))
+	assert.NilError(t, err)
+	assert.Assert(t, missingFree > 0)
+	assert.Equal(t, device, missingDevice)
+}
+
+func TestFilesystemFindTypeConflict(t *testing.T) {
+	t.Parallel()
+
+	fs := NewLocalClient(Options{})
+	dir := t.TempDir()
+	assert.NilError(t, os.MkdirAll(filepath.Join(dir, "dir"), 0o755))
+	assert.NilError(t, os.WriteFile(filepath.Join(dir, "file"), nil, 0o644))
+
+	testcases := []struct {
+		name     string
+		path     string
+		expected *TypeConflict
+	}{
+		{
+			name: "missing file",
+			path: "missing",
+		},
+		{
+			name: "missing file in missing directory",
+			path: filepath.Join("dir", "missing", "file"),
+		},
+		{
+			name: "existing file",
+			path: "file",
+		},
+		{
+			name:     "directory",
+			path:     "dir",
+			expected: &TypeConflict{Path: "dir", IsDir: true},
+		},
+		{
+			name:     "file as parent directory",
+			path:     filepath.Join("file", "child"),
+			expected: &TypeConflict{Path: "file"},
+		},
+		{
+			name:     "file as ancestor directory",
+			path:     filepath.Join("file", "a", "b"),
+			expected: &TypeConflict{Path: "file"},
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			conflict, err := fs.FindTypeConflict(filepath.Join(dir, tc.path))
+			assert.NilError(t, err)
+
+			if tc.expected == nil {
+				assert.Assert(t, conflict == nil, "unexpected conflict %+v", conflict)
+				return
+			}
+			tc.expected.Path = filepath.Join(dir, tc.expected.Path)
+			assert.DeepEqual(t, tc.expected, conflict)
+		})
+	}
+}
+
+func TestFilesystemListBrokenSymlinks(t *testing.T) {
+	log.Init("error", false)
+
+	dir := t.TempDir()
+	assert.NilError(t, os.WriteFile(filepath.Join(dir, "file.txt"), []byte("content"), 0o644))
+	assert.NilError(t, os.Symlink("missing.txt", filepath.Join(dir, "broken")))
+	assert.NilError(t, os.Symlink(filepath.Join("file.txt", "child"), filepath.Join(dir, "not-dir")))
+
+	testcases := []struct {
+		name           string
+		policy         string
+		expectedKeys   []string
+		expectedErrors int
+	}{
+		{
+			name:         "default policy skips",
+			expectedKeys: []string{"file.txt"},
+		},
+		{
+			name:         "skip",
+			policy:       BrokenSymlinkSkip,
+			expectedKeys: []string{"file.txt"},
+		},
+		{
+			name:           "error",
+			policy:         BrokenSymlinkError,
+			expectedKeys:   []string{"file.txt"},
+			expectedErrors: 2,
+		},
+		{
+			name:         "upload as link",
+			policy:       BrokenSymlinkUploadAsLink,
+			expectedKeys: []string{"broken", "file.txt", "not-dir"},
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		for _, pattern := range []string{dir, filepath.Join(dir, "*")} {
+			pattern := pattern
+			t.Run(tc.name+" "+filepath.Base(pattern), func(t *testing.T) {
+				fs := NewLocalClient(Options{OnBrokenSymlink: tc.policy})
+
+				src, err := url.New(pattern)
+				assert.NilError(t, err)
+
+				var keys []string
+				var errs int
+				for obj := range fs.List(context.Background(), src, true) {
+					if obj.Err != nil {
+						assert.Assert(t, errors.Is(obj.Err, ErrBrokenSymlink), "unexpected error %v", obj.Err)
+						errs++
+						continue
+					}
+					name := filepath.Base(obj.URL.Absolute())
+					keys = append(keys, name)
+					if name != "file.txt" {
+						assert.Assert(t, obj.Type.IsSymlink())
+					}
+				}
+				sort.Strings(keys)
+
+				assert.DeepEqual(t, tc.expectedKeys, keys)
+				assert.Equal(t, tc.expectedErrors, errs)
+			})
+		}
+	}
+}
+
+func TestFilesystemBrokenSymlinkTarget(t *testing.T) {
+	t.Parallel()
+
+	fs := NewLocalClient(Options{})
+	dir := t.TempDir()
+	assert.NilError(t, os.WriteFile(filepath.Join(dir, "file.txt"), nil, 0o644))
+	assert.NilError(t, os.Symlink("file.txt", filepath.Join(dir, "link")))
+	assert.NilError(t, os.Symlink("missing.txt", filepath.Join(dir, "broken")))
+
+	target, ok := fs.BrokenSymlinkTarget(filepath.Join(dir, "broken"))
+	assert.Assert(t, ok)
+	assert.Equal(t, "missing.txt", target)
+
+	for _, name := range []string{"file.txt", "link", "missing"} {
+		_, ok := fs.BrokenSymlinkTarget(filepath.Join(dir, name))
+		assert.Assert(t, !ok, "%v is not a broken symbolic link", name)
+	}
+}
@@ -0,0 +1,224 @@
+//go:build linux
+// +build linux
+
+package storage
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/peak/s5cmd/v2/log"
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+// walkDirAt walks the directory of src holding the file descriptors of the
+// directories, and stats and opens the entries relative to the directories
+// they are listed in rather than by their paths. So a directory renamed
+// during the walk is not followed into its new place, and the entries which
+// are removed or renamed after they are listed are skipped as vanished rather
+// than listed under stale paths.
+func walkDirAt(ctx context.Context, fs *Filesystem, src *url.URL, followSymlinks bool, fn func(o *Object)) error {
+	root := src.Absolute()
+	fd, err := unix.Open(root, unix.O_RDONLY|unix.O_DIRECTORY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return &os.PathError{Op: "open", Path: root, Err: err}
+	}
+
+	w := &dirWalker{
+		fs:             fs,
+		src:            src,
+		followSymlinks: followSymlinks,
+		fn:             fn,
+	}
+	return w.walk(fd, root)
+}
+
+// dirWalker walks the directories by their file descriptors.
+type dirWalker struct {
+	fs             *Filesystem
+	src            *url.URL
+	followSymlinks bool
+	fn             func(o *Object)
+}
+
+// walk walks the directory of fd at path in the order of the names of its
+// entries, and closes fd.
+func (w *dirWalker) walk(fd int, path string) error {
+	dir := os.NewFile(uintptr(fd), path)
+	defer dir.Close()
+
+	names, err := dir.Readdirnames(-1)
+	if err != nil {
+		return err
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := w.visit(fd, path, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// visit lists the entry of the directory of dirfd with the given name, and
+// walks it if it's a directory.
+func (w *dirWalker) visit(dirfd int, dirpath, name string) error {
+	path := filepath.Join(dirpath, name)
+
+	var lst unix.Stat_t
+	if err := unix.Fstatat(dirfd, name, &lst, unix.AT_SYMLINK_NOFOLLOW); err != nil {
+		if isVanishedEntry(err) {
+			w.vanished(path)
+			return nil
+		}
+		return &os.PathError{Op: "lstat", Path: path, Err: err}
+	}
+
+	switch lst.Mode & unix.S_IFMT {
+	case unix.S_IFDIR:
+		return w.walkSubdir(dirfd, path, name, &lst, unix.O_NOFOLLOW)
+	case unix.S_IFLNK:
+		// the symbolic links are not listed with --no-follow-symlinks.
+		if !w.followSymlinks {
+			return nil
+		}
+		return w.visitSymlink(dirfd, path, name, &lst)
+	}
+
+	fileurl, err := w.url(path)
+	if err != nil {
+		return err
+	}
+	w.fn(statObject(fileurl, &lst))
+	return nil
+}
+
+// visitSymlink lists the target of the symbolic link of the directory of
+// dirfd with the given name, and walks it if it's a directory. The link is
+// listed as the walks by path list it, i.e. a link to a directory is listed
+// before the entries of the directory.
+func (w *dirWalker) visitSymlink(dirfd int, path, name string, lst *unix.Stat_t) error {
+	fileurl, err := w.url(path)
+	if err != nil {
+		return err
+	}
+
+	var st unix.Stat_t
+	if err := unix.Fstatat(dirfd, name, &st, 0); err != nil {
+		if !isVanishedEntry(err) {
+			return &os.PathError{Op: "stat", Path: path, Err: err}
+		}
+		// the link itself is uploaded if its target doesn't exist.
+		if w.fs.onBrokenSymlink == BrokenSymlinkUploadAsLink {
+			w.fn(statObject(fileurl, lst))
+			return nil
+		}
+		if !w.fs.handleBrokenSymlink(fileurl, w.fn) {
+			w.vanished(path)
+		}
+		return nil
+	}
+
+	w.fn(statObject(fileurl, &st))
+	if st.Mode&unix.S_IFMT != unix.S_IFDIR {
+		return nil
+	}
+	return w.walkSubdir(dirfd, path, name, &st, 0)
+}
+
+// walkSubdir opens the subdirectory of dirfd with the given name and walks
+// it. The subdirectory is skipped as vanished if it's not the one statted as
+// st anymore, e.g. it's replaced by another directory after it's listed.
+func (w *dirWalker) walkSubdir(dirfd int, path, name string, st *unix.Stat_t, flags int) error {
+	fd, err := unix.Openat(dirfd, name, unix.O_RDONLY|unix.O_DIRECTORY|unix.O_CLOEXEC|flags, 0)
+	if err != nil {
+		// the directory replaced by a symbolic link fails with ELOOP.
+		if isVanishedEntry(err) || err == unix.ELOOP {
+			w.vanished(path)
+			return nil
+		}
+		return &os.PathError{Op: "openat", Path: path, Err: err}
+	}
+
+	var opened unix.Stat_t
+	if err := unix.Fstat(fd, &opened); err != nil {
+		unix.Close(fd)
+		return &os.PathError{Op: "fstat", Path: path, Err: err}
+	}
+	if opened.Dev != st.Dev || opened.Ino != st.Ino {
+		unix.Close(fd)
+		w.vanished(path)
+		return nil
+	}
+	return w.walk(fd, path)
+}
+
+func (w *dirWalker) url(path string) (*url.URL, error) {
+	fileurl, err := url.New(path, url.WithRaw(true))
+	if err != nil {
+		return nil, err
+	}
+	fileurl.SetRelative(w.src)
+	return fileurl, nil
+}
+
+// vanished reports the entry at path which is removed or renamed after it's
+// listed.
+func (w *dirWalker) vanished(path string) {
+	log.Debug(log.DebugMessage{
+		Err: fmt.Sprintf("skipping %v: it's removed or renamed after it's listed", path),
+	})
+}
+
+// isVanishedEntry reports whether the error of an entry is caused by its
+// removal or rename after it's listed.
+func isVanishedEntry(err error) bool {
+	return err == unix.ENOENT || err == unix.ENOTDIR
+}
+
+// statObject returns the object of url described by st.
+func statObject(url *url.URL, st *unix.Stat_t) *Object {
+	mod := time.Unix(int64(st.Mtim.Sec), int64(st.Mtim.Nsec))
+	return &Object{
+		URL:     url,
+		Type:    ObjectType{fileMode(st)},
+		Size:    st.Size,
+		ModTime: &mod,
+	}
+}
+
+// fileMode returns the mode of the file described by st as os.Stat returns.
+func fileMode(st *unix.Stat_t) os.FileMode {
+	mode := os.FileMode(st.Mode & 0o777)
+	switch st.Mode & unix.S_IFMT {
+	case unix.S_IFDIR:
+		mode |= os.ModeDir
+	case unix.S_IFLNK:
+		mode |= os.ModeSymlink
+	case unix.S_IFIFO:
+		mode |= os.ModeNamedPipe
+	case unix.S_IFSOCK:
+		mode |= os.ModeSocket
+	case unix.S_IFCHR:
+		mode |= os.ModeDevice | os.ModeCharDevice
+	case unix.S_IFBLK:
+		mode |= os.ModeDevice
+	}
+	if st.Mode&unix.S_ISUID != 0 {
+		mode |= os.ModeSetuid
+	}
+	if st.Mode&unix.S_ISGID != 0 {
+		mode |= os.ModeSetgid
+	}
+	if st.Mode&unix.S_ISVTX != 0 {
+		mode |= os.ModeSticky
+	}
+	return mode
+}
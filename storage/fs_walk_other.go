@@ -0,0 +1,16 @@
+//go:build !linux
+// +build !linux
+
+package storage
+
+import (
+	"context"
+
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+// walkDirAt walks the directory of src by the paths of its entries, since
+// the entries can't be opened relative to their directories on the platform.
+func walkDirAt(ctx context.Context, fs *Filesystem, src *url.URL, followSymlinks bool, fn func(o *Object)) error {
+	return walkDirPath(ctx, fs, src, followSymlinks, fn)
+}
@@ -0,0 +1,16 @@
+//go:build !windows
+// +build !windows
+
+package storage
+
+func windowsAttributes(path string) (uint32, error) {
+	return 0, ErrWindowsAttributesNotSupported
+}
+
+func setWindowsAttributes(path string, attrs uint32) error {
+	return ErrWindowsAttributesNotSupported
+}
+
+func alternateDataStreams(path string) ([]string, error) {
+	return nil, ErrWindowsAttributesNotSupported
+}
@@ -0,0 +1,95 @@
+//go:build windows
+// +build windows
+
+package storage
+
+import (
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// windowsAttributesMask is the basic attributes of the files which are kept
+// in the object metadata.
+const windowsAttributesMask = windows.FILE_ATTRIBUTE_READONLY |
+	windows.FILE_ATTRIBUTE_HIDDEN |
+	windows.FILE_ATTRIBUTE_SYSTEM |
+	windows.FILE_ATTRIBUTE_ARCHIVE
+
+var (
+	modkernel32          = windows.NewLazySystemDLL("kernel32.dll")
+	procFindFirstStreamW = modkernel32.NewProc("FindFirstStreamW")
+	procFindNextStreamW  = modkernel32.NewProc("FindNextStreamW")
+)
+
+// win32FindStreamData is WIN32_FIND_STREAM_DATA.
+type win32FindStreamData struct {
+	StreamSize int64
+	StreamName [windows.MAX_PATH + 36]uint16
+}
+
+func windowsAttributes(path string) (uint32, error) {
+	p, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, err
+	}
+	attrs, err := windows.GetFileAttributes(p)
+	if err != nil {
+		return 0, err
+	}
+	return attrs & windowsAttributesMask, nil
+}
+
+func setWindowsAttributes(path string, attrs uint32) error {
+	p, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return err
+	}
+	current, err := windows.GetFileAttributes(p)
+	if err != nil {
+		return err
+	}
+	attrs = current&^windowsAttributesMask | attrs&windowsAttributesMask
+	if attrs == 0 {
+		attrs = windows.FILE_ATTRIBUTE_NORMAL
+	}
+	return windows.SetFileAttributes(p, attrs)
+}
+
+func alternateDataStreams(path string) ([]string, error) {
+	p, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var data win32FindStreamData
+	// FindStreamInfoStandard is 0.
+	handle, _, err := procFindFirstStreamW.Call(uintptr(unsafe.Pointer(p)), 0, uintptr(unsafe.Pointer(&data)), 0)
+	if windows.Handle(handle) == windows.InvalidHandle {
+		// the directories and the files on the filesystems other than
+		// NTFS have no streams.
+		if err == windows.ERROR_HANDLE_EOF || err == windows.ERROR_INVALID_PARAMETER {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer windows.FindClose(windows.Handle(handle))
+
+	var streams []string
+	for {
+		// the unnamed stream "::$DATA" is the content of the file.
+		name := windows.UTF16ToString(data.StreamName[:])
+		if name != "::$DATA" {
+			streams = append(streams, strings.TrimSuffix(strings.TrimPrefix(name, ":"), ":$DATA"))
+		}
+
+		ok, _, err := procFindNextStreamW.Call(handle, uintptr(unsafe.Pointer(&data)))
+		if ok == 0 {
+			if err == windows.ERROR_HANDLE_EOF {
+				return streams, nil
+			}
+			return streams, err
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/aws/request"
+)
+
+// RequestHook is called before a request is sent to S3 with the name of its
+// operation, e.g. "PutObject", and its input, e.g. *s3.PutObjectInput. The
+// returned context, if not nil, replaces the context of the request, so that
+// the values added to it are passed to the response hooks.
+type RequestHook func(ctx context.Context, operation string, input interface{}) context.Context
+
+// ResponseHook is called after a request to S3 is completed, including its
+// retries, with the name of its operation, its output, e.g.
+// *s3.PutObjectOutput, and its error, if any.
+type ResponseHook func(ctx context.Context, operation string, output interface{}, err error)
+
+// Hooks are the functions which are called for each request made by the S3
+// clients which are created with them, see Options.Hooks. The hooks are called
+// concurrently by the requests of all workers, so they must be safe for
+// concurrent use. They must be registered before any clients are created.
+type Hooks struct {
+	request  []RequestHook
+	response []ResponseHook
+	handlers []func(*request.Handlers)
+}
+
+// NewHooks creates an empty set of hooks.
+func NewHooks() *Hooks {
+	return &Hooks{}
+}
+
+// OnRequest registers the hook to be called before each request. The hooks
+// are called in the order they are registered.
+func (h *Hooks) OnRequest(hook RequestHook) *Hooks {
+	h.request = append(h.request, hook)
+	return h
+}
+
+// OnResponse registers the hook to be called after each request. The hooks
+// are called in the order they are registered.
+func (h *Hooks) OnResponse(hook ResponseHook) *Hooks {
+	h.response = append(h.response, hook)
+	return h
+}
+
+// UseHandlers registers a function to add SDK handlers to the sessions, for
+// the cases which the hooks don't cover, e.g. adding headers to the HTTP
+// requests.
+func (h *Hooks) UseHandlers(fn func(*request.Handlers)) *Hooks {
+	h.handlers = append(h.handlers, fn)
+	return h
+}
+
+// register adds the hooks to the handlers of a session. It is a no-op if h is
+// nil.
+func (h *Hooks) register(handlers *request.Handlers) {
+	if h == nil {
+		return
+	}
+
+	// the hooks are copied so that the sessions don't share the slices
+	// with the later registrations.
+	requestHooks := append([]RequestHook(nil), h.request...)
+	responseHooks := append([]ResponseHook(nil), h.response...)
+
+	if len(requestHooks) > 0 {
+		// validation is run once for each request, unlike the handlers
+		// which are run for each retry.
+		handlers.Validate.PushFrontNamed(request.NamedHandler{
+			Name: "s5cmd.RequestHooks",
+			Fn: func(r *request.Request) {
+				ctx := r.Context()
+				for _, hook := range requestHooks {
+					if hookCtx := hook(ctx, r.Operation.Name, r.Params); hookCtx != nil {
+						ctx = hookCtx
+					}
+				}
+				r.SetContext(ctx)
+			},
+		})
+	}
+
+	if len(responseHooks) > 0 {
+		handlers.Complete.PushBackNamed(request.NamedHandler{
+			Name: "s5cmd.ResponseHooks",
+			Fn: func(r *request.Request) {
+				for _, hook := range responseHooks {
+					hook(r.Context(), r.Operation.Name, r.Data, r.Error)
+				}
+			},
+		})
+	}
+
+	for _, fn := range h.handlers {
+		fn(handlers)
+	}
+}
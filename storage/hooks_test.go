@@ -0,0 +1,106 @@
+package storage
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/awstesting/unit"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"gotest.tools/v3/assert"
+
+	"github.com/peak/s5cmd/v2/log"
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+type hookKey struct{}
+
+func TestS3Hooks(t *testing.T) {
+	log.Init("debug", false)
+
+	u, err := url.New("s3://bucket/key")
+	assert.NilError(t, err)
+
+	var (
+		mu        sync.Mutex
+		requests  []string
+		responses []string
+		errs      []error
+		headers   []string
+	)
+
+	hooks := NewHooks().
+		OnRequest(func(ctx context.Context, operation string, input interface{}) context.Context {
+			_, ok := input.(*s3.HeadObjectInput)
+			assert.Assert(t, ok)
+
+			mu.Lock()
+			defer mu.Unlock()
+			requests = append(requests, operation)
+			return context.WithValue(ctx, hookKey{}, "correlation-id")
+		}).
+		OnResponse(func(ctx context.Context, operation string, output interface{}, err error) {
+			_, ok := output.(*s3.HeadObjectOutput)
+			assert.Assert(t, ok)
+			// the values of the request hooks are passed to the response
+			// hooks.
+			assert.Equal(t, ctx.Value(hookKey{}), "correlation-id")
+
+			mu.Lock()
+			defer mu.Unlock()
+			responses = append(responses, operation)
+			errs = append(errs, err)
+		}).
+		UseHandlers(func(handlers *request.Handlers) {
+			handlers.Build.PushBack(func(r *request.Request) {
+				r.HTTPRequest.Header.Set("X-Gateway", "s5cmd")
+			})
+		})
+
+	sess := unit.Session.Copy()
+	sess.Config.Retryer = newCustomRetryer(2)
+	hooks.register(&sess.Handlers)
+
+	mockAPI := s3.New(sess)
+	mockS3 := &S3{api: mockAPI}
+
+	mockAPI.Handlers.Send.Clear()
+	mockAPI.Handlers.Unmarshal.Clear()
+	mockAPI.Handlers.UnmarshalMeta.Clear()
+	mockAPI.Handlers.ValidateResponse.Clear()
+	mockAPI.Handlers.Send.PushBack(func(r *request.Request) {
+		mu.Lock()
+		headers = append(headers, r.HTTPRequest.Header.Get("X-Gateway"))
+		mu.Unlock()
+
+		r.Error = awserr.New("InternalError", "internal error", nil)
+		r.HTTPResponse = &http.Response{}
+	})
+
+	_, err = mockS3.Stat(context.Background(), u)
+	assert.ErrorContains(t, err, "internal error")
+
+	// the hooks are called once for each request, regardless of its retries.
+	assert.DeepEqual(t, requests, []string{"HeadObject"})
+	assert.DeepEqual(t, responses, []string{"HeadObject"})
+	assert.Equal(t, len(errs), 1)
+	assert.ErrorContains(t, errs[0], "internal error")
+	// the handlers are run for each retry.
+	assert.DeepEqual(t, headers, []string{"s5cmd", "s5cmd", "s5cmd"})
+}
+
+func TestS3HooksNil(t *testing.T) {
+	var hooks *Hooks
+
+	sess := unit.Session.Copy()
+	validate, complete := sess.Handlers.Validate.Len(), sess.Handlers.Complete.Len()
+
+	hooks.register(&sess.Handlers)
+	NewHooks().register(&sess.Handlers)
+
+	assert.Equal(t, sess.Handlers.Validate.Len(), validate)
+	assert.Equal(t, sess.Handlers.Complete.Len(), complete)
+}
@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"io"
+	"net/http"
+	"sync"
+)
+
+// hostSemaphores holds the semaphores limiting the in-flight requests to each
+// host. They are shared by all sessions of the process, so that the requests
+// to the buckets on the same host are limited together.
+var hostSemaphores = &hostSemaphoreRegistry{
+	semaphores: map[hostSemaphoreKey]chan struct{}{},
+}
+
+type hostSemaphoreKey struct {
+	host  string
+	limit int
+}
+
+type hostSemaphoreRegistry struct {
+	mu         sync.Mutex
+	semaphores map[hostSemaphoreKey]chan struct{}
+}
+
+func (r *hostSemaphoreRegistry) get(host string, limit int) chan struct{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := hostSemaphoreKey{host: host, limit: limit}
+	sem, ok := r.semaphores[key]
+	if !ok {
+		sem = make(chan struct{}, limit)
+		r.semaphores[key] = sem
+	}
+	return sem
+}
+
+// hostLimitedTransport is an http.RoundTripper which limits the number of
+// in-flight requests to each host. A request is in flight until its response
+// body is read to the end or closed, since the body of a GetObject response
+// keeps the connection busy while the object is downloaded.
+type hostLimitedTransport struct {
+	base  http.RoundTripper
+	limit int
+}
+
+func newHostLimitedTransport(base http.RoundTripper, limit int) *hostLimitedTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &hostLimitedTransport{base: base, limit: limit}
+}
+
+// RoundTrip waits until the number of in-flight requests to the host of the
+// request drops below the limit, and then executes the request.
+func (t *hostLimitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	sem := hostSemaphores.get(req.URL.Host, t.limit)
+
+	select {
+	case sem <- struct{}{}:
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	}
+
+	var once sync.Once
+	release := func() {
+		once.Do(func() { <-sem })
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || resp.Body == nil {
+		release()
+		return resp, err
+	}
+
+	resp.Body = &releasingBody{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+// releasingBody releases the slot of its request once it is read to the end
+// or closed.
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releasingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err == io.EOF {
+		b.release()
+	}
+	return n, err
+}
+
+func (b *releasingBody) Close() error {
+	defer b.release()
+	return b.ReadCloser.Close()
+}
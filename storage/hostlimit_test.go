@@ -0,0 +1,105 @@
+package storage
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+// blockingTransport holds the requests until unblock is closed, and records
+// the maximum number of requests in progress for each host.
+type blockingTransport struct {
+	unblock chan struct{}
+
+	mu       sync.Mutex
+	inflight map[string]int
+	max      map[string]int
+}
+
+func (t *blockingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := req.URL.Host
+
+	t.mu.Lock()
+	t.inflight[host]++
+	if t.inflight[host] > t.max[host] {
+		t.max[host] = t.inflight[host]
+	}
+	t.mu.Unlock()
+
+	<-t.unblock
+
+	t.mu.Lock()
+	t.inflight[host]--
+	t.mu.Unlock()
+
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       io.NopCloser(strings.NewReader("content")),
+	}, nil
+}
+
+func TestHostLimitedTransport(t *testing.T) {
+	base := &blockingTransport{
+		unblock:  make(chan struct{}),
+		inflight: map[string]int{},
+		max:      map[string]int{},
+	}
+	transport := newHostLimitedTransport(base, 2)
+
+	var (
+		wg   sync.WaitGroup
+		done int32
+	)
+	for _, host := range []string{"limit-a.example.com", "limit-b.example.com"} {
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func(host string) {
+				defer wg.Done()
+				req, _ := http.NewRequest(http.MethodGet, "http://"+host+"/bucket/key", nil)
+				resp, err := transport.RoundTrip(req)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				_, _ = io.Copy(io.Discard, resp.Body)
+				resp.Body.Close()
+				atomic.AddInt32(&done, 1)
+			}(host)
+		}
+	}
+
+	// let the requests pile up before they are released.
+	time.Sleep(50 * time.Millisecond)
+	close(base.unblock)
+	wg.Wait()
+
+	assert.Equal(t, int32(10), done)
+	assert.Equal(t, 2, base.max["limit-a.example.com"])
+	assert.Equal(t, 2, base.max["limit-b.example.com"])
+}
+
+func TestHostLimitedTransportReleasesOnBodyClose(t *testing.T) {
+	base := &blockingTransport{
+		unblock:  make(chan struct{}),
+		inflight: map[string]int{},
+		max:      map[string]int{},
+	}
+	close(base.unblock)
+	transport := newHostLimitedTransport(base, 1)
+
+	for i := 0; i < 3; i++ {
+		req, _ := http.NewRequest(http.MethodGet, "http://release.example.com/bucket/key", nil)
+		resp, err := transport.RoundTrip(req)
+		assert.NilError(t, err)
+
+		// the body is not read, closing it releases the slot of the request.
+		assert.NilError(t, resp.Body.Close())
+		assert.NilError(t, resp.Body.Close())
+	}
+}
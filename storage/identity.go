@@ -0,0 +1,38 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+// stsDefaultRegion is the region of the STS requests if no region is
+// configured. STS is a global service, so any region works.
+const stsDefaultRegion = "us-east-1"
+
+// CallerIdentity returns the ARN of the identity whose credentials sign the
+// requests made with opts, e.g. the ARN of the assumed role, using STS
+// GetCallerIdentity. It returns an empty string if the requests are not
+// signed or a custom endpoint is used, since STS is not available for them.
+func CallerIdentity(ctx context.Context, opts Options) (string, error) {
+	if opts.NoSignRequest || opts.Endpoint != "" {
+		return "", nil
+	}
+
+	sess, err := globalSessionCache.newSession(ctx, opts)
+	if err != nil {
+		return "", err
+	}
+
+	cfg := aws.NewConfig()
+	if aws.StringValue(sess.Config.Region) == "" {
+		cfg = cfg.WithRegion(stsDefaultRegion)
+	}
+
+	output, err := sts.New(sess, cfg).GetCallerIdentityWithContext(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return "", err
+	}
+	return aws.StringValue(output.Arn), nil
+}
@@ -0,0 +1,592 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+// MemoryOp is the name of an operation on Memory storage. It is used to count
+// the calls and to target injected faults.
+type MemoryOp string
+
+const (
+	MemoryStat   MemoryOp = "stat"
+	MemoryList   MemoryOp = "list"
+	MemoryDelete MemoryOp = "delete"
+	MemoryCopy   MemoryOp = "copy"
+	MemoryPut    MemoryOp = "put"
+	MemoryRead   MemoryOp = "read"
+)
+
+// Fault is called before every operation on Memory storage with the
+// operation, the number of calls of the operation so far including this one
+// and the URL of the operation. If it returns an error, the operation fails
+// with it.
+type Fault func(ctx context.Context, op MemoryOp, n int, u *url.URL) error
+
+// FailOnCall returns a Fault which fails the nth call of the given operation
+// with err. Calls are counted from 1.
+func FailOnCall(op MemoryOp, n int, err error) Fault {
+	return func(_ context.Context, gotOp MemoryOp, gotN int, _ *url.URL) error {
+		if gotOp == op && gotN == n {
+			return err
+		}
+		return nil
+	}
+}
+
+// Throttle returns a Fault which delays every call of the given operation by
+// delay, simulating a slow or rate limited storage. The operation fails if
+// the context is done while waiting.
+func Throttle(op MemoryOp, delay time.Duration) Fault {
+	return func(ctx context.Context, gotOp MemoryOp, _ int, _ *url.URL) error {
+		if gotOp != op {
+			return nil
+		}
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+			return nil
+		}
+	}
+}
+
+var (
+	defaultMemoryMu sync.Mutex
+	defaultMemory   = NewMemory()
+)
+
+// DefaultMemory returns the Memory storage that is used for "mem://" URLs.
+func DefaultMemory() *Memory {
+	defaultMemoryMu.Lock()
+	defer defaultMemoryMu.Unlock()
+	return defaultMemory
+}
+
+// SetDefaultMemory replaces the Memory storage that is used for "mem://"
+// URLs and returns the previous one.
+func SetDefaultMemory(m *Memory) *Memory {
+	defaultMemoryMu.Lock()
+	defer defaultMemoryMu.Unlock()
+	prev := defaultMemory
+	defaultMemory = m
+	return prev
+}
+
+// NewMemoryClient returns a client of the default Memory storage which
+// respects the given options.
+func NewMemoryClient(opts Options) *Memory {
+	m := DefaultMemory()
+	return &Memory{store: m.store, dryRun: opts.DryRun}
+}
+
+// Memory is an in-memory Storage implementation. Objects are kept in a map
+// along with their sizes, modification times, ETags and metadata. Buckets can
+// be versioned, and faults can be injected to the operations.
+//
+// It is meant to be used to test the code orchestrating storage operations
+// without an S3 compatible server. URLs with the "mem://" scheme refer to the
+// Memory storage returned by DefaultMemory.
+type Memory struct {
+	store  *memoryStore
+	dryRun bool
+}
+
+type memoryStore struct {
+	sync.Mutex
+	buckets   map[string]*memoryBucket
+	faults    []Fault
+	calls     map[MemoryOp]int
+	versionID int
+	now       func() time.Time
+}
+
+type memoryBucket struct {
+	versioned bool
+	// objects holds the versions of each key, the latest version is the last.
+	objects map[string][]*memoryObject
+}
+
+type memoryObject struct {
+	data         []byte
+	modTime      time.Time
+	etag         string
+	versionID    string
+	metadata     Metadata
+	deleteMarker bool
+}
+
+// NewMemory creates an empty Memory storage.
+func NewMemory() *Memory {
+	return &Memory{
+		store: &memoryStore{
+			buckets: map[string]*memoryBucket{},
+			calls:   map[MemoryOp]int{},
+			now:     time.Now,
+		},
+	}
+}
+
+// MakeBucket creates a bucket with the given name.
+func (m *Memory) MakeBucket(name string) error {
+	m.store.Lock()
+	defer m.store.Unlock()
+
+	if _, ok := m.store.buckets[name]; ok {
+		return fmt.Errorf("bucket %q already exists", name)
+	}
+	m.store.buckets[name] = &memoryBucket{objects: map[string][]*memoryObject{}}
+	return nil
+}
+
+// SetVersioning enables or disables versioning of the given bucket.
+func (m *Memory) SetVersioning(bucket string, enabled bool) error {
+	m.store.Lock()
+	defer m.store.Unlock()
+
+	b, err := m.store.bucket(bucket)
+	if err != nil {
+		return err
+	}
+	b.versioned = enabled
+	return nil
+}
+
+// SetTimeSource sets the function used to determine modification times of
+// the objects.
+func (m *Memory) SetTimeSource(now func() time.Time) {
+	m.store.Lock()
+	defer m.store.Unlock()
+	m.store.now = now
+}
+
+// InjectFault adds a fault to be checked before every operation.
+func (m *Memory) InjectFault(f Fault) {
+	m.store.Lock()
+	defer m.store.Unlock()
+	m.store.faults = append(m.store.faults, f)
+}
+
+// Calls returns the number of calls of the given operation.
+func (m *Memory) Calls(op MemoryOp) int {
+	m.store.Lock()
+	defer m.store.Unlock()
+	return m.store.calls[op]
+}
+
+// call counts the call of op and runs the injected faults.
+func (m *Memory) call(ctx context.Context, op MemoryOp, u *url.URL) error {
+	m.store.Lock()
+	m.store.calls[op]++
+	n := m.store.calls[op]
+	faults := append([]Fault(nil), m.store.faults...)
+	m.store.Unlock()
+
+	for _, f := range faults {
+		if err := f(ctx, op, n, u); err != nil {
+			return err
+		}
+	}
+	return ctx.Err()
+}
+
+func (s *memoryStore) bucket(name string) (*memoryBucket, error) {
+	b, ok := s.buckets[name]
+	if !ok {
+		return nil, fmt.Errorf("bucket %q does not exist", name)
+	}
+	return b, nil
+}
+
+// lookup returns the version of the object at u, or the latest version if u
+// has no version ID. It returns nil if there is no such object.
+func (s *memoryStore) lookup(u *url.URL) (*memoryObject, error) {
+	b, err := s.bucket(u.Bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := b.objects[u.Path]
+	if u.VersionID == "" {
+		if len(versions) == 0 || versions[len(versions)-1].deleteMarker {
+			return nil, nil
+		}
+		return versions[len(versions)-1], nil
+	}
+
+	for _, v := range versions {
+		if v.versionID == u.VersionID && !v.deleteMarker {
+			return v, nil
+		}
+	}
+	return nil, nil
+}
+
+// add stores obj as the latest version of the key in the bucket.
+func (s *memoryStore) add(bucket, key string, obj *memoryObject) error {
+	b, err := s.bucket(bucket)
+	if err != nil {
+		return err
+	}
+
+	obj.modTime = s.now().UTC()
+	if !b.versioned {
+		b.objects[key] = []*memoryObject{obj}
+		return nil
+	}
+
+	s.versionID++
+	obj.versionID = fmt.Sprintf("%020d", s.versionID)
+	b.objects[key] = append(b.objects[key], obj)
+	return nil
+}
+
+func (s *memoryStore) toObject(u *url.URL, key string, obj *memoryObject) *Object {
+	newurl := u.Clone()
+	newurl.Path = key
+	newurl.VersionID = obj.versionID
+
+	var objtype os.FileMode
+	if strings.HasSuffix(key, "/") {
+		objtype = os.ModeDir
+	}
+
+	mod := obj.modTime
+	return &Object{
+		URL:          newurl,
+		Etag:         obj.etag,
+		ModTime:      &mod,
+		Type:         ObjectType{objtype},
+		Size:         int64(len(obj.data)),
+		StorageClass: StorageClass(obj.metadata.StorageClass()),
+	}
+}
+
+// Put stores the content read from reader at the given URL.
+func (m *Memory) Put(ctx context.Context, reader io.Reader, to *url.URL, metadata Metadata) error {
+	if err := m.call(ctx, MemoryPut, to); err != nil {
+		return err
+	}
+
+	if m.dryRun {
+		return nil
+	}
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+
+	sum := md5.Sum(data)
+
+	m.store.Lock()
+	defer m.store.Unlock()
+
+	return m.store.add(to.Bucket, to.Path, &memoryObject{
+		data:     data,
+		etag:     hex.EncodeToString(sum[:]),
+		metadata: copyMetadata(metadata),
+	})
+}
+
+// Read returns a reader of the content of the object at the given URL.
+func (m *Memory) Read(ctx context.Context, src *url.URL) (io.ReadCloser, error) {
+	if err := m.call(ctx, MemoryRead, src); err != nil {
+		return nil, err
+	}
+
+	m.store.Lock()
+	defer m.store.Unlock()
+
+	obj, err := m.store.lookup(src)
+	if err != nil {
+		return nil, err
+	}
+	if obj == nil {
+		return nil, &ErrGivenObjectNotFound{ObjectAbsPath: src.Absolute()}
+	}
+	return io.NopCloser(bytes.NewReader(obj.data)), nil
+}
+
+// Metadata returns the metadata of the object at the given URL.
+func (m *Memory) Metadata(ctx context.Context, src *url.URL) (Metadata, error) {
+	m.store.Lock()
+	defer m.store.Unlock()
+
+	obj, err := m.store.lookup(src)
+	if err != nil {
+		return nil, err
+	}
+	if obj == nil {
+		return nil, &ErrGivenObjectNotFound{ObjectAbsPath: src.Absolute()}
+	}
+	return copyMetadata(obj.metadata), nil
+}
+
+// Stat returns the Object structure describing the object at the given URL.
+func (m *Memory) Stat(ctx context.Context, src *url.URL) (*Object, error) {
+	if err := m.call(ctx, MemoryStat, src); err != nil {
+		return nil, err
+	}
+
+	m.store.Lock()
+	defer m.store.Unlock()
+
+	obj, err := m.store.lookup(src)
+	if err != nil {
+		return nil, err
+	}
+	if obj == nil {
+		return nil, &ErrGivenObjectNotFound{ObjectAbsPath: src.Absolute()}
+	}
+
+	o := m.store.toObject(src, src.Path, obj)
+	o.URL = src
+	o.ContentType = obj.metadata.ContentType()
+	o.UserMetadata = obj.metadata.UserMetadata()
+	return o, nil
+}
+
+// StatMany stats the given objects, running at most concurrency of them at
+// once. Each of them is counted as a call of Stat.
+func (m *Memory) StatMany(ctx context.Context, urls []*url.URL, concurrency int) []StatResult {
+	return statMany(ctx, urls, concurrency, m.Stat)
+}
+
+// List lists the objects and prefixes matching with the given URL, with the
+// same semantics of S3 listing. The objects are listed in lexicographical
+// order of their keys.
+func (m *Memory) List(ctx context.Context, src *url.URL, _ bool) <-chan *Object {
+	objCh := make(chan *Object)
+
+	go func() {
+		defer close(objCh)
+
+		if err := m.call(ctx, MemoryList, src); err != nil {
+			objCh <- &Object{Err: err}
+			return
+		}
+
+		objects, err := m.list(src)
+		if err != nil {
+			objCh <- &Object{Err: err}
+			return
+		}
+
+		if len(objects) == 0 {
+			objCh <- &Object{Err: ErrNoObjectFound}
+			return
+		}
+
+		for _, obj := range objects {
+			select {
+			case <-ctx.Done():
+				objCh <- &Object{Err: ctx.Err()}
+				return
+			case objCh <- obj:
+			}
+		}
+	}()
+
+	return objCh
+}
+
+func (m *Memory) list(src *url.URL) ([]*Object, error) {
+	m.store.Lock()
+	defer m.store.Unlock()
+
+	b, err := m.store.bucket(src.Bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(b.objects))
+	for key := range b.objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var (
+		objects  []*Object
+		prefixes = map[string]struct{}{}
+		versions = src.VersionID != "" || src.AllVersions
+	)
+
+	for _, key := range keys {
+		if !strings.HasPrefix(key, src.Prefix) {
+			continue
+		}
+
+		if src.Delimiter != "" {
+			rest := strings.TrimPrefix(key, src.Prefix)
+			if i := strings.Index(rest, src.Delimiter); i >= 0 {
+				prefix := src.Prefix + rest[:i+len(src.Delimiter)]
+				if _, ok := prefixes[prefix]; ok || !src.Match(prefix) {
+					continue
+				}
+				prefixes[prefix] = struct{}{}
+
+				newurl := src.Clone()
+				newurl.Path = prefix
+				objects = append(objects, &Object{
+					URL:  newurl,
+					Type: ObjectType{os.ModeDir},
+				})
+				continue
+			}
+		}
+
+		if !src.Match(key) {
+			continue
+		}
+
+		keyVersions := b.objects[key]
+		if !versions {
+			latest := keyVersions[len(keyVersions)-1]
+			if latest.deleteMarker {
+				continue
+			}
+			objects = append(objects, m.store.toObject(src, key, latest))
+			continue
+		}
+
+		for i, v := range keyVersions {
+			if src.VersionID != "" && src.VersionID != v.versionID {
+				continue
+			}
+			obj := m.store.toObject(src, key, v)
+			obj.IsLatest = i == len(keyVersions)-1
+			obj.DeleteMarker = v.deleteMarker
+			objects = append(objects, obj)
+		}
+	}
+
+	return objects, nil
+}
+
+// Delete deletes the object at the given URL. In versioned buckets, a delete
+// marker is added unless a version ID is given.
+func (m *Memory) Delete(ctx context.Context, src *url.URL) error {
+	if err := m.call(ctx, MemoryDelete, src); err != nil {
+		return err
+	}
+
+	if m.dryRun {
+		return nil
+	}
+
+	m.store.Lock()
+	defer m.store.Unlock()
+
+	b, err := m.store.bucket(src.Bucket)
+	if err != nil {
+		return err
+	}
+
+	versions, ok := b.objects[src.Path]
+	if !ok {
+		return nil
+	}
+
+	switch {
+	case src.VersionID != "":
+		remaining := versions[:0]
+		for _, v := range versions {
+			if v.versionID != src.VersionID {
+				remaining = append(remaining, v)
+			}
+		}
+		versions = remaining
+	case b.versioned:
+		m.store.versionID++
+		versions = append(versions, &memoryObject{
+			modTime:      m.store.now().UTC(),
+			versionID:    fmt.Sprintf("%020d", m.store.versionID),
+			deleteMarker: true,
+		})
+	default:
+		versions = nil
+	}
+
+	if len(versions) == 0 {
+		delete(b.objects, src.Path)
+		return nil
+	}
+	b.objects[src.Path] = versions
+	return nil
+}
+
+// MultiDelete deletes all objects at the URLs read from the given channel.
+// Each deleted object, or the error encountered while deleting it, is sent to
+// the returned channel.
+func (m *Memory) MultiDelete(ctx context.Context, urls <-chan *url.URL) <-chan *Object {
+	resultch := make(chan *Object)
+
+	go func() {
+		defer close(resultch)
+		for u := range urls {
+			err := m.Delete(ctx, u)
+			resultch <- &Object{URL: u, Err: err}
+		}
+	}()
+
+	return resultch
+}
+
+// Copy copies the object at src to dst. The metadata of the source object is
+// kept unless it is overridden by the given metadata.
+func (m *Memory) Copy(ctx context.Context, src, dst *url.URL, metadata Metadata) error {
+	if err := m.call(ctx, MemoryCopy, src); err != nil {
+		return err
+	}
+
+	if m.dryRun {
+		return nil
+	}
+
+	m.store.Lock()
+	defer m.store.Unlock()
+
+	obj, err := m.store.lookup(src)
+	if err != nil {
+		return err
+	}
+	if obj == nil {
+		return &ErrGivenObjectNotFound{ObjectAbsPath: src.Absolute()}
+	}
+
+	newMetadata := copyMetadata(obj.metadata)
+	for k, v := range metadata {
+		if v != "" {
+			newMetadata[k] = v
+		}
+	}
+
+	return m.store.add(dst.Bucket, dst.Path, &memoryObject{
+		data:     obj.data,
+		etag:     obj.etag,
+		metadata: newMetadata,
+	})
+}
+
+func copyMetadata(metadata Metadata) Metadata {
+	m := NewMetadata()
+	for k, v := range metadata {
+		m[k] = v
+	}
+	return m
+}
@@ -0,0 +1,205 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+func TestMemoryImplementsStorageInterface(t *testing.T) {
+	var i interface{} = new(Memory)
+	if _, ok := i.(Storage); !ok {
+		t.Errorf("expected %t to implement Storage interface", i)
+	}
+}
+
+func mustURL(t *testing.T, s string) *url.URL {
+	t.Helper()
+	u, err := url.New(s)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return u
+}
+
+func newTestMemory(t *testing.T, keys ...string) *Memory {
+	t.Helper()
+
+	m := NewMemory()
+	assert.NilError(t, m.MakeBucket("bucket"))
+	for _, key := range keys {
+		err := m.Put(context.Background(), strings.NewReader("content of "+key), mustURL(t, "mem://bucket/"+key), NewMetadata())
+		assert.NilError(t, err)
+	}
+	return m
+}
+
+func listKeys(t *testing.T, m *Memory, src string) ([]string, error) {
+	t.Helper()
+
+	var (
+		keys []string
+		err  error
+	)
+	for obj := range m.List(context.Background(), mustURL(t, src), false) {
+		if obj.Err != nil {
+			err = obj.Err
+			continue
+		}
+		keys = append(keys, obj.URL.Absolute())
+	}
+	sort.Strings(keys)
+	return keys, err
+}
+
+func TestMemoryList(t *testing.T) {
+	m := newTestMemory(t, "a.txt", "b.gz", "dir/c.txt", "dir/sub/d.txt")
+
+	testcases := []struct {
+		src      string
+		expected []string
+		err      error
+	}{
+		{
+			src:      "mem://bucket/*",
+			expected: []string{"mem://bucket/a.txt", "mem://bucket/b.gz", "mem://bucket/dir/c.txt", "mem://bucket/dir/sub/d.txt"},
+		},
+		{
+			src:      "mem://bucket/*.txt",
+			expected: []string{"mem://bucket/a.txt", "mem://bucket/dir/c.txt", "mem://bucket/dir/sub/d.txt"},
+		},
+		{
+			src:      "mem://bucket/dir/",
+			expected: []string{"mem://bucket/dir/c.txt", "mem://bucket/dir/sub/"},
+		},
+		{
+			src:      "mem://bucket/a.txt",
+			expected: []string{"mem://bucket/a.txt"},
+		},
+		{
+			src: "mem://bucket/nosuchkey",
+			err: ErrNoObjectFound,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.src, func(t *testing.T) {
+			keys, err := listKeys(t, m, tc.src)
+			assert.Equal(t, err, tc.err)
+			assert.DeepEqual(t, keys, tc.expected)
+		})
+	}
+}
+
+func TestMemoryStatCopyDelete(t *testing.T) {
+	ctx := context.Background()
+	m := newTestMemory(t, "key")
+
+	src := mustURL(t, "mem://bucket/key")
+	obj, err := m.Stat(ctx, src)
+	assert.NilError(t, err)
+	assert.Equal(t, obj.Size, int64(len("content of key")))
+	assert.Equal(t, obj.Etag, "efd6f18956c777b156ab8a2e0b44820f")
+
+	dst := mustURL(t, "mem://bucket/copy")
+	err = m.Copy(ctx, src, dst, NewMetadata().SetContentType("text/plain"))
+	assert.NilError(t, err)
+
+	metadata, err := m.Metadata(ctx, dst)
+	assert.NilError(t, err)
+	assert.Equal(t, metadata.ContentType(), "text/plain")
+
+	rc, err := m.Read(ctx, dst)
+	assert.NilError(t, err)
+	content, _ := io.ReadAll(rc)
+	assert.Equal(t, string(content), "content of key")
+
+	urlch := make(chan *url.URL, 2)
+	urlch <- src
+	urlch <- dst
+	close(urlch)
+	for obj := range m.MultiDelete(ctx, urlch) {
+		assert.NilError(t, obj.Err)
+	}
+
+	var notFound *ErrGivenObjectNotFound
+	_, err = m.Stat(ctx, src)
+	assert.Assert(t, errors.As(err, &notFound))
+	assert.Equal(t, m.Calls(MemoryDelete), 2)
+}
+
+func TestMemoryVersioning(t *testing.T) {
+	ctx := context.Background()
+	m := newTestMemory(t)
+	assert.NilError(t, m.SetVersioning("bucket", true))
+
+	u := mustURL(t, "mem://bucket/key")
+	for _, content := range []string{"v1", "v2"} {
+		assert.NilError(t, m.Put(ctx, strings.NewReader(content), u, NewMetadata()))
+	}
+	assert.NilError(t, m.Delete(ctx, u))
+
+	// the object is deleted but its versions are kept.
+	_, err := listKeys(t, m, "mem://bucket/*")
+	assert.Equal(t, err, ErrNoObjectFound)
+
+	allVersions, err := url.New("mem://bucket/key", url.WithAllVersions(true))
+	assert.NilError(t, err)
+
+	var versions []*Object
+	for obj := range m.List(ctx, allVersions, false) {
+		assert.NilError(t, obj.Err)
+		versions = append(versions, obj)
+	}
+	assert.Equal(t, len(versions), 3)
+
+	first := u.Clone()
+	first.VersionID = versions[0].URL.VersionID
+	rc, err := m.Read(ctx, first)
+	assert.NilError(t, err)
+	content, _ := io.ReadAll(rc)
+	assert.Equal(t, string(content), "v1")
+}
+
+func TestMemoryFaults(t *testing.T) {
+	errInjected := errors.New("injected error")
+
+	m := newTestMemory(t, "key")
+	m.InjectFault(FailOnCall(MemoryStat, 2, errInjected))
+	m.InjectFault(Throttle(MemoryList, time.Minute))
+
+	u := mustURL(t, "mem://bucket/key")
+	for i, want := range []error{nil, errInjected, nil} {
+		_, err := m.Stat(context.Background(), u)
+		assert.Equal(t, err, want, "call %d", i+1)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	for obj := range m.List(ctx, u, false) {
+		assert.Equal(t, obj.Err, context.DeadlineExceeded)
+	}
+}
+
+func TestMemoryDryRun(t *testing.T) {
+	ctx := context.Background()
+	prev := SetDefaultMemory(newTestMemory(t, "key"))
+	defer SetDefaultMemory(prev)
+
+	u := mustURL(t, "mem://bucket/key")
+	client, err := NewClient(ctx, u, Options{DryRun: true})
+	assert.NilError(t, err)
+	assert.NilError(t, client.Delete(ctx, u))
+
+	_, err = DefaultMemory().Stat(ctx, u)
+	assert.NilError(t, err)
+}
@@ -0,0 +1,347 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+// MergeSegment is a byte range of an object which is concatenated into a
+// merged object.
+type MergeSegment struct {
+	Object *Object
+	Offset int64
+	Length int64
+}
+
+// MergePart is a part of the multipart upload of a merged object. The parts
+// of a single segment are copied on the server side, the others are read from
+// their objects and uploaded.
+type MergePart struct {
+	Number   int64
+	Segments []MergeSegment
+}
+
+// Size returns the number of bytes of the part.
+func (p MergePart) Size() int64 {
+	var size int64
+	for _, segment := range p.Segments {
+		size += segment.Length
+	}
+	return size
+}
+
+// IsCopy reports whether the part is copied with UploadPartCopy rather than
+// uploaded.
+func (p MergePart) IsCopy() bool {
+	return len(p.Segments) == 1
+}
+
+// PlanMerge returns the parts of the multipart upload which concatenates the
+// given objects in order. Every part but the last one must be at least
+// MinPartSize bytes, so the adjacent objects smaller than that are coalesced
+// into the parts which are uploaded, filled up with the beginnings of the
+// following objects if needed. The rest of the objects are copied on the server
+// side, in parts of at most 5GiB.
+func PlanMerge(objects []*Object) ([]MergePart, error) {
+	var (
+		parts       []MergePart
+		pending     []MergeSegment
+		pendingSize int64
+	)
+
+	add := func(segments ...MergeSegment) {
+		parts = append(parts, MergePart{
+			Number:   int64(len(parts) + 1),
+			Segments: segments,
+		})
+	}
+
+	for _, object := range objects {
+		offset, remaining := int64(0), object.Size
+
+		if pendingSize > 0 && remaining > 0 {
+			// the pending part is filled up with the beginning of the object
+			// if the rest of it is large enough to be a part on its own.
+			length := remaining
+			if need := MinPartSize - pendingSize; remaining-need >= MinPartSize {
+				length = need
+			}
+			pending = append(pending, MergeSegment{Object: object, Offset: offset, Length: length})
+			pendingSize += length
+			offset, remaining = offset+length, remaining-length
+
+			if pendingSize >= MinPartSize {
+				add(pending...)
+				pending, pendingSize = nil, 0
+			}
+		}
+
+		if remaining == 0 {
+			continue
+		}
+
+		if remaining < MinPartSize {
+			pending = append(pending, MergeSegment{Object: object, Offset: offset, Length: remaining})
+			pendingSize += remaining
+			continue
+		}
+
+		// every copied part must be at most 5GiB, so the larger objects are
+		// split into equal parts.
+		count := (remaining + maxCopyPartSize - 1) / maxCopyPartSize
+		partSize := (remaining + count - 1) / count
+		for end := offset + remaining; offset < end; offset += partSize {
+			length := partSize
+			if offset+length > end {
+				length = end - offset
+			}
+			add(MergeSegment{Object: object, Offset: offset, Length: length})
+		}
+	}
+
+	if len(pending) > 0 {
+		add(pending...)
+	}
+
+	if len(parts) == 0 {
+		return nil, fmt.Errorf("the objects to merge are empty")
+	}
+	if len(parts) > s3manager.MaxUploadParts {
+		return nil, fmt.Errorf("the merged object would have %d parts, more than the maximum of %d parts",
+			len(parts), s3manager.MaxUploadParts)
+	}
+	return parts, nil
+}
+
+// Merge concatenates the objects of the given parts into the object at "to",
+// with the parts planned by PlanMerge. The parts are copied or uploaded by
+// concurrency workers. The merge fails if any of the objects is modified after
+// it is listed, and the multipart upload is aborted if it fails.
+func (s *S3) Merge(
+	ctx context.Context,
+	parts []MergePart,
+	to *url.URL,
+	metadata Metadata,
+	concurrency int,
+) error {
+	if s.dryRun {
+		return nil
+	}
+
+	input, err := s.createMultipartUploadInput(to, metadata)
+	if err != nil {
+		return err
+	}
+
+	output, err := s.api.CreateMultipartUploadWithContext(ctx, input)
+	if err != nil {
+		return err
+	}
+	uploadID := output.UploadId
+
+	completed, err := s.mergeParts(ctx, uploadID, parts, to, concurrency)
+	if err == nil {
+		_, err = s.api.CompleteMultipartUploadWithContext(ctx, &s3.CompleteMultipartUploadInput{
+			Bucket:          aws.String(to.Bucket),
+			Key:             aws.String(to.Path),
+			UploadId:        uploadID,
+			MultipartUpload: &s3.CompletedMultipartUpload{Parts: completed},
+			RequestPayer:    s.RequestPayer(),
+		})
+	}
+
+	if err != nil {
+		s.abortUpload(to, aws.StringValue(uploadID))
+		return err
+	}
+
+	recordUpload(true)
+	return nil
+}
+
+// mergeParts copies or uploads the given parts to the multipart upload by
+// concurrency workers, and returns the completed parts in order.
+func (s *S3) mergeParts(
+	ctx context.Context,
+	uploadID *string,
+	parts []MergePart,
+	to *url.URL,
+	concurrency int,
+) ([]*s3.CompletedPart, error) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	completed := make([]*s3.CompletedPart, len(parts))
+	indexes := make(chan int)
+
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		mergeErr error
+	)
+	fail := func(err error) {
+		errOnce.Do(func() {
+			mergeErr = err
+			cancel()
+		})
+	}
+
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				var (
+					etag *string
+					err  error
+				)
+				if parts[i].IsCopy() {
+					etag, err = s.copyMergePart(ctx, uploadID, parts[i], to)
+				} else {
+					etag, err = s.uploadMergePart(ctx, uploadID, parts[i], to)
+				}
+				if err != nil {
+					fail(err)
+					continue
+				}
+
+				completed[i] = &s3.CompletedPart{
+					ETag:       etag,
+					PartNumber: aws.Int64(parts[i].Number),
+				}
+			}
+		}()
+	}
+
+send:
+	for i := range parts {
+		select {
+		case indexes <- i:
+		case <-ctx.Done():
+			break send
+		}
+	}
+	close(indexes)
+	wg.Wait()
+
+	if mergeErr != nil {
+		return nil, mergeErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return completed, nil
+}
+
+// copyMergePart copies the single segment of the part with UploadPartCopy and
+// returns the ETag of the part.
+func (s *S3) copyMergePart(ctx context.Context, uploadID *string, part MergePart, to *url.URL) (*string, error) {
+	segment := part.Segments[0]
+	from := segment.Object.URL
+
+	copySource := from.EscapedPath()
+	if from.VersionID != "" {
+		copySource += "?versionId=" + from.VersionID
+	}
+
+	input := &s3.UploadPartCopyInput{
+		Bucket:          aws.String(to.Bucket),
+		Key:             aws.String(to.Path),
+		UploadId:        uploadID,
+		PartNumber:      aws.Int64(part.Number),
+		CopySource:      aws.String(copySource),
+		CopySourceRange: aws.String(fmt.Sprintf("bytes=%d-%d", segment.Offset, segment.Offset+segment.Length-1)),
+		RequestPayer:    s.RequestPayer(),
+	}
+	if etag := segment.Object.Etag; etag != "" {
+		input.CopySourceIfMatch = aws.String(strconv.Quote(etag))
+	}
+
+	release, err := acquirePartCopy(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	output, err := s.api.UploadPartCopyWithContext(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	return output.CopyPartResult.ETag, nil
+}
+
+// uploadMergePart reads the segments of the part with ranged requests and
+// uploads them as the part. It returns the ETag of the part.
+func (s *S3) uploadMergePart(ctx context.Context, uploadID *string, part MergePart, to *url.URL) (*string, error) {
+	var buf bytes.Buffer
+	buf.Grow(int(part.Size()))
+
+	for _, segment := range part.Segments {
+		if err := s.readMergeSegment(ctx, &buf, segment); err != nil {
+			return nil, err
+		}
+	}
+
+	output, err := s.api.UploadPartWithContext(ctx, &s3.UploadPartInput{
+		Bucket:        aws.String(to.Bucket),
+		Key:           aws.String(to.Path),
+		UploadId:      uploadID,
+		PartNumber:    aws.Int64(part.Number),
+		Body:          bytes.NewReader(buf.Bytes()),
+		ContentLength: aws.Int64(int64(buf.Len())),
+		RequestPayer:  s.RequestPayer(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return output.ETag, nil
+}
+
+// readMergeSegment writes the byte range of the segment to w. The range is
+// requested even for the whole objects, so that the objects with gzip content
+// encoding are not decompressed by the HTTP transport.
+func (s *S3) readMergeSegment(ctx context.Context, w io.Writer, segment MergeSegment) error {
+	src := segment.Object.URL
+
+	input := &s3.GetObjectInput{
+		Bucket:       aws.String(src.Bucket),
+		Key:          aws.String(src.Path),
+		Range:        aws.String(fmt.Sprintf("bytes=%d-%d", segment.Offset, segment.Offset+segment.Length-1)),
+		RequestPayer: s.RequestPayer(),
+	}
+	if src.VersionID != "" {
+		input.SetVersionId(src.VersionID)
+	}
+	if etag := segment.Object.Etag; etag != "" {
+		input.IfMatch = aws.String(strconv.Quote(etag))
+	}
+
+	resp, err := s.api.GetObjectWithContext(ctx, input)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	n, err := io.Copy(w, resp.Body)
+	if err != nil {
+		return err
+	}
+	if n != segment.Length {
+		return fmt.Errorf("read %d bytes of %v, expected %d bytes", n, src, segment.Length)
+	}
+	return nil
+}
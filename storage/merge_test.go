@@ -0,0 +1,282 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/awstesting/unit"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"gotest.tools/v3/assert"
+
+	"github.com/peak/s5cmd/v2/log"
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+func TestPlanMerge(t *testing.T) {
+	t.Parallel()
+
+	const (
+		M = MinPartSize
+		K = 1024
+	)
+
+	// segment is the index of an object, and the offset and the length of
+	// its range.
+	type segment [3]int64
+
+	repeat := func(size int64, n int) []int64 {
+		sizes := make([]int64, n)
+		for i := range sizes {
+			sizes[i] = size
+		}
+		return sizes
+	}
+
+	tests := []struct {
+		name     string
+		sizes    []int64
+		expected [][]segment
+		err      string
+	}{
+		{
+			name:  "large objects are copied",
+			sizes: []int64{2 * M, 3 * M},
+			expected: [][]segment{
+				{{0, 0, 2 * M}},
+				{{1, 0, 3 * M}},
+			},
+		},
+		{
+			name:  "small objects are coalesced",
+			sizes: []int64{K, 2 * K, 3 * K},
+			expected: [][]segment{
+				{{0, 0, K}, {1, 0, 2 * K}, {2, 0, 3 * K}},
+			},
+		},
+		{
+			name:  "small object is filled up with the beginning of the next object",
+			sizes: []int64{M / 2, 3 * M},
+			expected: [][]segment{
+				{{0, 0, M / 2}, {1, 0, M / 2}},
+				{{1, M / 2, 3*M - M/2}},
+			},
+		},
+		{
+			name:  "next object is coalesced if its rest would be too small",
+			sizes: []int64{M / 2, M + M/5},
+			expected: [][]segment{
+				{{0, 0, M / 2}, {1, 0, M + M/5}},
+			},
+		},
+		{
+			name:  "small object between large objects",
+			sizes: []int64{2 * M, K, 2 * M},
+			expected: [][]segment{
+				{{0, 0, 2 * M}},
+				{{1, 0, K}, {2, 0, M - K}},
+				{{2, M - K, M + K}},
+			},
+		},
+		{
+			name:  "last small object is copied",
+			sizes: []int64{2 * M, K},
+			expected: [][]segment{
+				{{0, 0, 2 * M}},
+				{{1, 0, K}},
+			},
+		},
+		{
+			name:  "empty objects are skipped",
+			sizes: []int64{0, K, 0, 2 * K},
+			expected: [][]segment{
+				{{1, 0, K}, {3, 0, 2 * K}},
+			},
+		},
+		{
+			name:  "objects larger than 5GiB are split",
+			sizes: []int64{maxCopyPartSize + 2},
+			expected: [][]segment{
+				{{0, 0, maxCopyPartSize/2 + 1}},
+				{{0, maxCopyPartSize/2 + 1, maxCopyPartSize/2 + 1}},
+			},
+		},
+		{
+			name:  "empty objects",
+			sizes: []int64{0, 0},
+			err:   "the objects to merge are empty",
+		},
+		{
+			name:  "too many parts",
+			sizes: repeat(M, s3manager.MaxUploadParts+1),
+			err:   "more than the maximum of 10000 parts",
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			objects := make([]*Object, len(tc.sizes))
+			for i, size := range tc.sizes {
+				u, err := url.New(fmt.Sprintf("s3://bucket/part-%05d", i))
+				assert.NilError(t, err)
+				objects[i] = &Object{URL: u, Size: size}
+			}
+
+			parts, err := PlanMerge(objects)
+			if tc.err != "" {
+				assert.ErrorContains(t, err, tc.err)
+				return
+			}
+			assert.NilError(t, err)
+
+			got := make([][]segment, 0, len(parts))
+			for i, part := range parts {
+				assert.Equal(t, part.Number, int64(i+1))
+
+				var segments []segment
+				for _, s := range part.Segments {
+					index := int64(-1)
+					for j, object := range objects {
+						if object == s.Object {
+							index = int64(j)
+						}
+					}
+					segments = append(segments, segment{index, s.Offset, s.Length})
+				}
+				got = append(got, segments)
+
+				// all parts but the last one must be at least the minimum
+				// part size.
+				if i < len(parts)-1 {
+					assert.Assert(t, part.Size() >= M, "part %d is %d bytes", part.Number, part.Size())
+				}
+			}
+			assert.DeepEqual(t, got, tc.expected)
+		})
+	}
+}
+
+func TestS3Merge(t *testing.T) {
+	log.Init("debug", false)
+
+	to, err := url.New("s3://bucket/merged")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	contents := map[string][]byte{
+		"small": bytes.Repeat([]byte("a"), MinPartSize/2),
+		"large": bytes.Repeat([]byte("b"), 2*MinPartSize),
+	}
+	var objects []*Object
+	for _, key := range []string{"small", "large"} {
+		u, err := url.New("s3://source/" + key)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		objects = append(objects, &Object{URL: u, Etag: key + "-etag", Size: int64(len(contents[key]))})
+	}
+
+	parts, err := PlanMerge(objects)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var (
+		mu         sync.Mutex
+		reads      []string
+		uploaded   [][]byte
+		copyRanges []string
+		completed  []*s3.CompletedPart
+		aborted    bool
+	)
+
+	mockAPI := s3.New(unit.Session)
+	mockAPI.Handlers.Unmarshal.Clear()
+	mockAPI.Handlers.UnmarshalMeta.Clear()
+	mockAPI.Handlers.UnmarshalError.Clear()
+	mockAPI.Handlers.Send.Clear()
+	mockAPI.Handlers.Send.PushBack(func(r *request.Request) {
+		r.Handlers.Unmarshal.Clear()
+		r.HTTPResponse = &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader("")),
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch params := r.Params.(type) {
+		case *s3.CreateMultipartUploadInput:
+			assert.Equal(t, aws.StringValue(params.ContentType), "application/octet-stream")
+			*r.Data.(*s3.CreateMultipartUploadOutput) = s3.CreateMultipartUploadOutput{UploadId: aws.String("upload-id")}
+		case *s3.GetObjectInput:
+			key := aws.StringValue(params.Key)
+			assert.Equal(t, aws.StringValue(params.IfMatch), fmt.Sprintf("%q", key+"-etag"))
+			reads = append(reads, key+" "+aws.StringValue(params.Range))
+
+			var start, end int
+			fmt.Sscanf(aws.StringValue(params.Range), "bytes=%d-%d", &start, &end)
+			*r.Data.(*s3.GetObjectOutput) = s3.GetObjectOutput{
+				Body: io.NopCloser(bytes.NewReader(contents[key][start : end+1])),
+			}
+		case *s3.UploadPartInput:
+			assert.Equal(t, aws.Int64Value(params.PartNumber), int64(1))
+			body, err := io.ReadAll(params.Body)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			uploaded = append(uploaded, body)
+			*r.Data.(*s3.UploadPartOutput) = s3.UploadPartOutput{ETag: aws.String("upload-etag")}
+		case *s3.UploadPartCopyInput:
+			assert.Equal(t, aws.Int64Value(params.PartNumber), int64(2))
+			assert.Equal(t, aws.StringValue(params.CopySource), "source/large")
+			assert.Equal(t, aws.StringValue(params.CopySourceIfMatch), `"large-etag"`)
+			copyRanges = append(copyRanges, aws.StringValue(params.CopySourceRange))
+			*r.Data.(*s3.UploadPartCopyOutput) = s3.UploadPartCopyOutput{
+				CopyPartResult: &s3.CopyPartResult{ETag: aws.String("copy-etag")},
+			}
+		case *s3.CompleteMultipartUploadInput:
+			completed = params.MultipartUpload.Parts
+			*r.Data.(*s3.CompleteMultipartUploadOutput) = s3.CompleteMultipartUploadOutput{}
+		case *s3.AbortMultipartUploadInput:
+			aborted = true
+			*r.Data.(*s3.AbortMultipartUploadOutput) = s3.AbortMultipartUploadOutput{}
+		default:
+			t.Errorf("unexpected request %T", params)
+		}
+	})
+
+	mockS3 := &S3{api: mockAPI}
+
+	err = mockS3.Merge(context.Background(), parts, to, NewMetadata(), 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	half := MinPartSize / 2
+	assert.DeepEqual(t, reads, []string{
+		fmt.Sprintf("small bytes=0-%d", half-1),
+		fmt.Sprintf("large bytes=0-%d", half-1),
+	})
+	expected := append(append([]byte{}, contents["small"]...), contents["large"][:half]...)
+	assert.Equal(t, len(uploaded), 1)
+	assert.Assert(t, bytes.Equal(uploaded[0], expected), "unexpected content of the uploaded part")
+	assert.DeepEqual(t, copyRanges, []string{fmt.Sprintf("bytes=%d-%d", half, 2*MinPartSize-1)})
+	assert.DeepEqual(t, completed, []*s3.CompletedPart{
+		{ETag: aws.String("upload-etag"), PartNumber: aws.Int64(1)},
+		{ETag: aws.String("copy-etag"), PartNumber: aws.Int64(2)},
+	})
+	assert.Assert(t, !aborted)
+}
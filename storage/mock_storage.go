@@ -105,3 +105,17 @@ func (mr *MockStorageMockRecorder) Stat(ctx, src interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Stat", reflect.TypeOf((*MockStorage)(nil).Stat), ctx, src)
 }
+
+// StatMany mocks base method.
+func (m *MockStorage) StatMany(ctx context.Context, urls []*url.URL, concurrency int) []StatResult {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StatMany", ctx, urls, concurrency)
+	ret0, _ := ret[0].([]StatResult)
+	return ret0
+}
+
+// StatMany indicates an expected call of StatMany.
+func (mr *MockStorageMockRecorder) StatMany(ctx, urls, concurrency interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StatMany", reflect.TypeOf((*MockStorage)(nil).StatMany), ctx, urls, concurrency)
+}
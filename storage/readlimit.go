@@ -0,0 +1,255 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/peak/s5cmd/v2/strutil"
+)
+
+// readLimiters holds the limiters of the local file reads. They are shared by
+// all local clients of the process, so that the files read by all workers are
+// limited together.
+var readLimiters = &readLimiterRegistry{
+	limiters: map[readLimiterKey]*readLimiter{},
+}
+
+type readLimiterKey struct {
+	concurrency int
+	iops        int
+}
+
+type readLimiterRegistry struct {
+	mu       sync.Mutex
+	limiters map[readLimiterKey]*readLimiter
+}
+
+func (r *readLimiterRegistry) get(concurrency, iops int) *readLimiter {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := readLimiterKey{concurrency: concurrency, iops: iops}
+	limiter, ok := r.limiters[key]
+	if !ok {
+		limiter = newReadLimiter(concurrency, iops)
+		r.limiters[key] = limiter
+	}
+	return limiter
+}
+
+// ReadStats is the statistics of the local file reads.
+type ReadStats struct {
+	// Files is the number of files read.
+	Files int64
+	// PeakConcurrency is the maximum number of files read at the same time.
+	PeakConcurrency int
+	// AverageConcurrency is the average number of files read at the same
+	// time, while at least one file is read.
+	AverageConcurrency float64
+	// Wait is the total duration that the reads waited for the limits.
+	Wait time.Duration
+}
+
+// String is the string representation of ReadStats.
+func (s ReadStats) String() string {
+	return fmt.Sprintf(
+		"read %v files, %.2f at a time on average, %v at most, waited %v for read limits",
+		s.Files, s.AverageConcurrency, s.PeakConcurrency, s.Wait.Round(time.Millisecond),
+	)
+}
+
+// JSON is the JSON representation of ReadStats.
+func (s ReadStats) JSON() string {
+	return strutil.JSON(struct {
+		Operation          string  `json:"operation"`
+		Files              int64   `json:"files"`
+		PeakConcurrency    int     `json:"peak_concurrency"`
+		AverageConcurrency float64 `json:"average_concurrency"`
+		Wait               string  `json:"wait"`
+	}{
+		Operation:          "read",
+		Files:              s.Files,
+		PeakConcurrency:    s.PeakConcurrency,
+		AverageConcurrency: math.Round(s.AverageConcurrency*100) / 100,
+		Wait:               s.Wait.Round(time.Millisecond).String(),
+	})
+}
+
+// ReadStatistics returns the statistics of the local file reads. It reports
+// false if no files are read.
+func ReadStatistics() (ReadStats, bool) {
+	readLimiters.mu.Lock()
+	defer readLimiters.mu.Unlock()
+
+	var (
+		stats ReadStats
+		busy  time.Duration
+		area  float64
+	)
+	for _, limiter := range readLimiters.limiters {
+		limiter.mu.Lock()
+		stats.Files += limiter.files
+		stats.Wait += limiter.wait
+		if limiter.peak > stats.PeakConcurrency {
+			stats.PeakConcurrency = limiter.peak
+		}
+		busy += limiter.busy
+		area += limiter.area
+		limiter.mu.Unlock()
+	}
+
+	if stats.Files == 0 {
+		return stats, false
+	}
+	if busy > 0 {
+		stats.AverageConcurrency = area / busy.Seconds()
+	}
+	return stats, true
+}
+
+// readLimiter limits the number of files read at the same time, and the rate
+// of opening files with a token bucket. A zero limit is unlimited.
+type readLimiter struct {
+	slots  chan struct{}
+	bucket *tokenBucket
+
+	mu     sync.Mutex
+	active int
+	// changed is the last time the number of active reads changed.
+	changed time.Time
+	files   int64
+	peak    int
+	wait    time.Duration
+	// busy is the total duration of at least one active read, and area is
+	// the integral of the number of active reads over time in seconds.
+	busy time.Duration
+	area float64
+}
+
+func newReadLimiter(concurrency, iops int) *readLimiter {
+	l := &readLimiter{}
+	if concurrency > 0 {
+		l.slots = make(chan struct{}, concurrency)
+	}
+	if iops > 0 {
+		l.bucket = newTokenBucket(iops)
+	}
+	return l
+}
+
+// acquire waits until a file can be read with respect to the limits, and
+// returns the function releasing the read.
+func (l *readLimiter) acquire(ctx context.Context) (func(), error) {
+	start := time.Now()
+
+	if l.slots != nil {
+		select {
+		case l.slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	if l.bucket != nil {
+		if err := l.bucket.wait(ctx); err != nil {
+			if l.slots != nil {
+				<-l.slots
+			}
+			return nil, err
+		}
+	}
+
+	l.mu.Lock()
+	l.wait += time.Since(start)
+	l.files++
+	l.update(1)
+	if l.active > l.peak {
+		l.peak = l.active
+	}
+	l.mu.Unlock()
+
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			l.mu.Lock()
+			l.update(-1)
+			l.mu.Unlock()
+
+			if l.slots != nil {
+				<-l.slots
+			}
+		})
+	}
+	return release, nil
+}
+
+// update changes the number of active reads by delta. It must be called with
+// the lock held.
+func (l *readLimiter) update(delta int) {
+	now := time.Now()
+	if l.active > 0 {
+		elapsed := now.Sub(l.changed)
+		l.busy += elapsed
+		l.area += float64(l.active) * elapsed.Seconds()
+	}
+	l.active += delta
+	l.changed = now
+}
+
+// tokenBucket is a token bucket which is filled with rate tokens per second,
+// up to rate tokens.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate int) *tokenBucket {
+	return &tokenBucket{
+		rate:   float64(rate),
+		tokens: float64(rate),
+		last:   time.Now(),
+	}
+}
+
+// wait takes a token from the bucket, waiting until the token is filled if
+// the bucket is empty.
+func (b *tokenBucket) wait(ctx context.Context) error {
+	b.mu.Lock()
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.rate {
+		b.tokens = b.rate
+	}
+	b.last = now
+
+	// the token is reserved even if it is not filled yet, so that the
+	// waiting reads take the tokens in order.
+	b.tokens--
+	var delay time.Duration
+	if b.tokens < 0 {
+		delay = time.Duration(-b.tokens / b.rate * float64(time.Second))
+	}
+	b.mu.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		b.mu.Lock()
+		b.tokens++
+		b.mu.Unlock()
+		return ctx.Err()
+	}
+}
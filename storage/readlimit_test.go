@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestReadLimiterLimitsConcurrency(t *testing.T) {
+	t.Parallel()
+
+	limiter := newReadLimiter(2, 0)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			release, err := limiter.acquire(context.Background())
+			assert.NilError(t, err)
+			defer release()
+
+			time.Sleep(20 * time.Millisecond)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int64(6), limiter.files)
+	assert.Equal(t, 2, limiter.peak)
+	assert.Equal(t, 0, limiter.active)
+	// the reads are done in 3 rounds of 2 files.
+	assert.Assert(t, limiter.area/limiter.busy.Seconds() > 1.5)
+}
+
+func TestReadLimiterLimitsRate(t *testing.T) {
+	t.Parallel()
+
+	limiter := newReadLimiter(0, 50)
+
+	start := time.Now()
+	for i := 0; i < 60; i++ {
+		release, err := limiter.acquire(context.Background())
+		assert.NilError(t, err)
+		release()
+	}
+
+	// the bucket is full at first, the remaining 10 opens wait for 200ms.
+	elapsed := time.Since(start)
+	assert.Assert(t, elapsed >= 180*time.Millisecond, "elapsed: %v", elapsed)
+	assert.Assert(t, limiter.wait >= 180*time.Millisecond, "wait: %v", limiter.wait)
+}
+
+func TestReadLimiterCancel(t *testing.T) {
+	t.Parallel()
+
+	limiter := newReadLimiter(1, 0)
+
+	release, err := limiter.acquire(context.Background())
+	assert.NilError(t, err)
+	defer release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err = limiter.acquire(ctx)
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Equal(t, int64(1), limiter.files)
+}
+
+func TestReadStatsMessage(t *testing.T) {
+	t.Parallel()
+
+	stats := ReadStats{
+		Files:              12,
+		PeakConcurrency:    4,
+		AverageConcurrency: 3.456,
+		Wait:               1500 * time.Millisecond,
+	}
+
+	assert.Equal(t, "read 12 files, 3.46 at a time on average, 4 at most, waited 1.5s for read limits", stats.String())
+	assert.Equal(t,
+		`{"operation":"read","files":12,"peak_concurrency":4,"average_concurrency":3.46,"wait":"1.5s"}`,
+		stats.JSON(),
+	)
+}
@@ -0,0 +1,39 @@
+package storage
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+)
+
+type retryCounterKey struct{}
+
+// WithRetryCounter returns a context which counts the retries of the S3
+// requests made with it. The count is read with RetryCount.
+func WithRetryCounter(ctx context.Context) context.Context {
+	return context.WithValue(ctx, retryCounterKey{}, new(int64))
+}
+
+// RetryCount returns the number of the retried S3 requests made with the
+// given context. It returns 0 if the context doesn't count the retries.
+func RetryCount(ctx context.Context) int64 {
+	counter, ok := ctx.Value(retryCounterKey{}).(*int64)
+	if !ok {
+		return 0
+	}
+	return atomic.LoadInt64(counter)
+}
+
+// countRetry is an AfterRetry handler which counts the retry of the request
+// in the counter of its context, if any. It runs after the SDK's handler,
+// which clears the error of the request if it is going to be retried.
+func countRetry(r *request.Request) {
+	if r.Error != nil || !aws.BoolValue(r.Retryable) {
+		return
+	}
+	if counter, ok := r.Context().Value(retryCounterKey{}).(*int64); ok {
+		atomic.AddInt64(counter, 1)
+	}
+}
@@ -1,9 +1,12 @@
 package storage
 
 import (
+	"bytes"
 	"context"
+	"crypto/md5"
 	"crypto/rand"
 	"crypto/tls"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -13,6 +16,7 @@ import (
 	"net/http"
 	urlpkg "net/url"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -22,6 +26,8 @@ import (
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/client"
 	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/processcreds"
+	"github.com/aws/aws-sdk-go/aws/defaults"
 	"github.com/aws/aws-sdk-go/aws/endpoints"
 	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
@@ -37,9 +43,9 @@ import (
 var sentinelURL = urlpkg.URL{}
 
 const (
-	// deleteObjectsMax is the max allowed objects to be deleted on single HTTP
+	// DeleteObjectsMax is the max allowed objects to be deleted on single HTTP
 	// request.
-	deleteObjectsMax = 1000
+	DeleteObjectsMax = 1000
 
 	// Amazon Accelerated Transfer endpoint
 	transferAccelEndpoint = "s3-accelerate.amazonaws.com"
@@ -47,8 +53,41 @@ const (
 	// Google Cloud Storage endpoint
 	gcsEndpoint = "storage.googleapis.com"
 
+	// AddressingStyleAuto uses the path-style addressing for the endpoints
+	// which don't support virtual-host-style and for the bucket names with
+	// dots over HTTPS, and virtual-host-style otherwise.
+	AddressingStyleAuto = "auto"
+	// AddressingStylePath always uses the path-style addressing.
+	AddressingStylePath = "path"
+	// AddressingStyleVirtual always uses the virtual-host-style addressing.
+	AddressingStyleVirtual = "virtual"
+
 	// the key of the object metadata which is used to handle retry decision on NoSuchUpload error
 	metadataKeyRetryID = "s5cmd-upload-retry-id"
+
+	// the key of the object metadata which holds the target of an uploaded
+	// symbolic link
+	metadataKeySymlinkTarget = "s5cmd-symlink-target"
+
+	// the key of the object metadata which holds the Windows file attributes
+	// of an uploaded file.
+	metadataKeyWindowsAttributes = "s5cmd-windows-attributes"
+
+	// the key of the object metadata which holds the modification time of an
+	// uploaded file.
+	metadataKeyModTime = "s5cmd-mtime"
+
+	// MinPartSize is the minimum size of a multipart upload part, except for
+	// the last one. Smaller objects can not be appended to.
+	MinPartSize = 5 * 1024 * 1024
+
+	// maxCopyPartSize is the maximum size of a part copied with UploadPartCopy.
+	maxCopyPartSize = 5 * 1024 * 1024 * 1024
+
+	// MaxCopyObjectSize is the size of the largest object which can be copied
+	// with a single CopyObject request. The larger objects are copied with
+	// MultipartCopy.
+	MaxCopyObjectSize = maxCopyPartSize
 )
 
 // Re-used AWS sessions dramatically improve performance.
@@ -56,17 +95,32 @@ var globalSessionCache = &SessionCache{
 	sessions: map[Options]*session.Session{},
 }
 
+// globalRegionCache and globalClientCache are only used if
+// Options.EndpointResolverCache is set.
+var (
+	globalRegionCache = &regionCache{
+		regions: map[regionCacheKey]string{},
+	}
+	globalClientCache = &clientCache{
+		clients: map[Options]*S3{},
+	}
+)
+
 // S3 is a storage type which interacts with S3API, DownloaderAPI and
 // UploaderAPI.
 type S3 struct {
 	api                    s3iface.S3API
+	credentials            *credentials.Credentials
+	region                 string
 	downloader             s3manageriface.DownloaderAPI
 	uploader               s3manageriface.UploaderAPI
 	endpointURL            urlpkg.URL
 	dryRun                 bool
 	useListObjectsV1       bool
+	fetchOwner             bool
 	noSuchUploadRetryCount int
 	requestPayer           string
+	deleteBatchSize        int
 }
 
 func (s *S3) RequestPayer() *string {
@@ -103,16 +157,112 @@ func newS3Storage(ctx context.Context, opts Options) (*S3, error) {
 
 	return &S3{
 		api:                    s3.New(awsSession),
-		downloader:             s3manager.NewDownloader(awsSession),
-		uploader:               s3manager.NewUploader(awsSession),
+		credentials:            awsSession.Config.Credentials,
+		region:                 aws.StringValue(awsSession.Config.Region),
+		downloader:             s3manager.NewDownloader(awsSession, withDownloadBufferPool(opts.BufferPoolSize)),
+		uploader:               s3manager.NewUploader(awsSession, withUploadBufferPool(opts.BufferPoolSize)),
 		endpointURL:            endpointURL,
 		dryRun:                 opts.DryRun,
 		useListObjectsV1:       opts.UseListObjectsV1,
+		fetchOwner:             opts.FetchOwner,
 		requestPayer:           opts.RequestPayer,
 		noSuchUploadRetryCount: opts.NoSuchUploadRetryCount,
+		deleteBatchSize:        opts.DeleteBatchSize,
 	}, nil
 }
 
+// Presign returns a URL which the object at url can be downloaded from without
+// credentials until it expires.
+func (s *S3) Presign(ctx context.Context, url *url.URL, expires time.Duration) (string, error) {
+	input := &s3.GetObjectInput{
+		Bucket:       aws.String(url.Bucket),
+		Key:          aws.String(url.Path),
+		RequestPayer: s.RequestPayer(),
+	}
+	if url.VersionID != "" {
+		input.SetVersionId(url.VersionID)
+	}
+
+	req, _ := s.api.GetObjectRequest(input)
+	req.SetContext(ctx)
+	return req.Presign(expires)
+}
+
+// ObjectURL returns the unsigned URL of the object at url. It is built the
+// same way as the URLs of the requests of the client, with respect to its
+// endpoint, region and addressing style.
+func (s *S3) ObjectURL(url *url.URL) (string, error) {
+	req, _ := s.api.GetObjectRequest(&s3.GetObjectInput{
+		Bucket: aws.String(url.Bucket),
+		Key:    aws.String(url.Path),
+	})
+	if err := req.Build(); err != nil {
+		return "", err
+	}
+	return req.HTTPRequest.URL.String(), nil
+}
+
+// ErrPreconditionFailed is returned by PutConditional if the object is not
+// written since the condition of the write doesn't hold.
+var ErrPreconditionFailed = errors.New("precondition failed")
+
+// ReadAll returns the contents and the ETag of the object at url, which is
+// expected to be small enough to be kept in memory.
+func (s *S3) ReadAll(ctx context.Context, url *url.URL) ([]byte, string, error) {
+	resp, err := s.api.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket:       aws.String(url.Bucket),
+		Key:          aws.String(url.Path),
+		RequestPayer: s.RequestPayer(),
+	})
+	if err != nil {
+		if errHasCode(err, s3.ErrCodeNoSuchKey) || errHasCode(err, "NotFound") {
+			return nil, "", &ErrGivenObjectNotFound{ObjectAbsPath: url.Absolute()}
+		}
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	return content, strings.Trim(aws.StringValue(resp.ETag), `"`), nil
+}
+
+// PutConditional writes content to the object at url with a single request,
+// only if the object doesn't exist if etag is empty, or only if its ETag is
+// etag otherwise. It returns the ETag of the written object, or
+// ErrPreconditionFailed if the condition doesn't hold. The endpoints which
+// don't support the conditional writes may ignore the condition and write the
+// object anyway.
+func (s *S3) PutConditional(ctx context.Context, url *url.URL, content []byte, etag string) (string, error) {
+	if s.dryRun {
+		return "", nil
+	}
+
+	header := map[string]string{"If-None-Match": "*"}
+	if etag != "" {
+		header = map[string]string{"If-Match": strconv.Quote(etag)}
+	}
+
+	output, err := s.api.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Bucket:       aws.String(url.Bucket),
+		Key:          aws.String(url.Path),
+		Body:         bytes.NewReader(content),
+		ContentType:  aws.String("application/json"),
+		RequestPayer: s.RequestPayer(),
+	}, request.WithSetRequestHeaders(header))
+	if err != nil {
+		// the concurrent conditional writes of the same object are
+		// rejected with a conflict instead.
+		if ResponseStatusCode(err) == http.StatusPreconditionFailed || errHasCode(err, "ConditionalRequestConflict") {
+			return "", ErrPreconditionFailed
+		}
+		return "", err
+	}
+	return strings.Trim(aws.StringValue(output.ETag), `"`), nil
+}
+
 // Stat retrieves metadata from S3 object without returning the object itself.
 func (s *S3) Stat(ctx context.Context, url *url.URL) (*Object, error) {
 	input := &s3.HeadObjectInput{
@@ -136,10 +286,26 @@ func (s *S3) Stat(ctx context.Context, url *url.URL) (*Object, error) {
 	mod := aws.TimeValue(output.LastModified)
 
 	obj := &Object{
-		URL:     url,
-		Etag:    strings.Trim(etag, `"`),
-		ModTime: &mod,
-		Size:    aws.Int64Value(output.ContentLength),
+		URL:              url,
+		Etag:             strings.Trim(etag, `"`),
+		ModTime:          &mod,
+		Size:             aws.Int64Value(output.ContentLength),
+		EncryptionMethod: aws.StringValue(output.ServerSideEncryption),
+		EncryptionKeyID:  aws.StringValue(output.SSEKMSKeyId),
+		ContentType:      aws.StringValue(output.ContentType),
+		UserMetadata:     userMetadata(output.Metadata),
+	}
+
+	if attrs, ok := output.Metadata[metadataKeyWindowsAttributes]; ok {
+		obj.WindowsAttributes = aws.StringValue(attrs)
+	}
+
+	// the modification times which can't be parsed are ignored, as if the
+	// object is not uploaded with its modification time.
+	if value, ok := output.Metadata[metadataKeyModTime]; ok {
+		if modTime, err := time.Parse(time.RFC3339Nano, aws.StringValue(value)); err == nil {
+			obj.PreservedModTime = &modTime
+		}
 	}
 
 	if s.noSuchUploadRetryCount > 0 {
@@ -151,9 +317,36 @@ func (s *S3) Stat(ctx context.Context, url *url.URL) (*Object, error) {
 	return obj, nil
 }
 
+// userMetadata returns the user-defined metadata of an object by their names
+// in lower case, without the ones s5cmd sets for itself.
+func userMetadata(headers map[string]*string) map[string]string {
+	var metadata map[string]string
+	for name, value := range headers {
+		name = strings.ToLower(name)
+		switch name {
+		case metadataKeyRetryID, metadataKeySymlinkTarget, metadataKeyWindowsAttributes, metadataKeyModTime:
+			continue
+		}
+		if metadata == nil {
+			metadata = map[string]string{}
+		}
+		metadata[name] = aws.StringValue(value)
+	}
+	return metadata
+}
+
+// StatMany retrieves the metadata of the given objects with HeadObject
+// requests, running at most concurrency of them at once. The requests are
+// limited by the number of concurrent requests per host as the other ones.
+func (s *S3) StatMany(ctx context.Context, urls []*url.URL, concurrency int) []StatResult {
+	return statMany(ctx, urls, concurrency, s.Stat)
+}
+
 // List is a non-blocking S3 list operation which paginates and filters S3
 // keys. If no object found or an error is encountered during this period,
-// it sends these errors to object channel.
+// it sends these errors to object channel. The pages are fetched as the
+// objects are received from the channel, at most one page ahead, and the
+// listing stops as soon as ctx is canceled.
 func (s *S3) List(ctx context.Context, url *url.URL, _ bool) <-chan *Object {
 	if url.VersionID != "" || url.AllVersions {
 		return s.listObjectVersions(ctx, url)
@@ -165,6 +358,72 @@ func (s *S3) List(ctx context.Context, url *url.URL, _ bool) <-chan *Object {
 	return s.listObjectsV2(ctx, url)
 }
 
+// listPages sends the objects of the pages which are fetched by paginate to the
+// returned channel. paginate calls emit with the objects of each page, and
+// stops if it returns false. It returns whether any object is found, which
+// includes the objects which are filtered out.
+//
+// The pages are handed over without a buffer, so the next page is fetched
+// while the objects of the current one are received, and the page after it is
+// not fetched until the consumer reaches the next page. Once ctx is canceled,
+// neither the objects nor the pages are waited to be received, and the
+// channel is closed with the cancellation error.
+func listPages(ctx context.Context, paginate func(emit func(page []*Object) bool) (bool, error)) <-chan *Object {
+	objCh := make(chan *Object)
+	pages := make(chan []*Object)
+
+	var (
+		found   bool
+		err     error
+		stopped bool
+	)
+	go func() {
+		defer close(pages)
+		found, err = paginate(func(page []*Object) bool {
+			select {
+			case pages <- page:
+				return true
+			case <-ctx.Done():
+				stopped = true
+				return false
+			}
+		})
+	}()
+
+	go func() {
+		defer close(objCh)
+
+		canceled := false
+		for page := range pages {
+			for _, obj := range page {
+				if canceled {
+					break
+				}
+				select {
+				case objCh <- obj:
+				case <-ctx.Done():
+					canceled = true
+				}
+			}
+		}
+
+		// found, err and stopped are set once the pages are closed.
+		if err == nil && (canceled || stopped) {
+			err = ctx.Err()
+		}
+		if err != nil {
+			objCh <- &Object{Err: err}
+			return
+		}
+
+		if !found {
+			objCh <- &Object{Err: ErrNoObjectFound}
+		}
+	}()
+
+	return objCh
+}
+
 func (s *S3) listObjectVersions(ctx context.Context, url *url.URL) <-chan *Object {
 	listInput := s3.ListObjectVersionsInput{
 		Bucket: aws.String(url.Bucket),
@@ -175,16 +434,14 @@ func (s *S3) listObjectVersions(ctx context.Context, url *url.URL) <-chan *Objec
 		listInput.SetDelimiter(url.Delimiter)
 	}
 
-	objCh := make(chan *Object)
-
-	go func() {
-		defer close(objCh)
+	return listPages(ctx, func(emit func(page []*Object) bool) (bool, error) {
 		objectFound := false
 
 		var now time.Time
 
 		err := s.api.ListObjectVersionsPagesWithContext(ctx, &listInput,
 			func(p *s3.ListObjectVersionsOutput, lastPage bool) bool {
+				page := make([]*Object, 0, len(p.CommonPrefixes)+len(p.Versions)+len(p.DeleteMarkers))
 				for _, c := range p.CommonPrefixes {
 					prefix := aws.StringValue(c.Prefix)
 					if !url.Match(prefix) {
@@ -193,10 +450,10 @@ func (s *S3) listObjectVersions(ctx context.Context, url *url.URL) <-chan *Objec
 
 					newurl := url.Clone()
 					newurl.Path = prefix
-					objCh <- &Object{
+					page = append(page, &Object{
 						URL:  newurl,
 						Type: ObjectType{os.ModeDir},
-					}
+					})
 
 					objectFound = true
 				}
@@ -206,6 +463,8 @@ func (s *S3) listObjectVersions(ctx context.Context, url *url.URL) <-chan *Objec
 					now = time.Now().UTC()
 				}
 
+				versions := make([]*Object, 0, len(p.Versions)+len(p.DeleteMarkers))
+
 				// iterate over all versions of the objects (except the delete markers)
 				for _, v := range p.Versions {
 					key := aws.StringValue(v.Key)
@@ -232,14 +491,16 @@ func (s *S3) listObjectVersions(ctx context.Context, url *url.URL) <-chan *Objec
 					newurl.VersionID = aws.StringValue(v.VersionId)
 					etag := aws.StringValue(v.ETag)
 
-					objCh <- &Object{
+					versions = append(versions, &Object{
 						URL:          newurl,
 						Etag:         strings.Trim(etag, `"`),
 						ModTime:      &mod,
 						Type:         ObjectType{objtype},
 						Size:         aws.Int64Value(v.Size),
 						StorageClass: StorageClass(aws.StringValue(v.StorageClass)),
-					}
+						Owner:        s.owner(v.Owner),
+						IsLatest:     aws.BoolValue(v.IsLatest),
+					})
 
 					objectFound = true
 				}
@@ -269,30 +530,35 @@ func (s *S3) listObjectVersions(ctx context.Context, url *url.URL) <-chan *Objec
 					newurl.Path = aws.StringValue(d.Key)
 					newurl.VersionID = aws.StringValue(d.VersionId)
 
-					objCh <- &Object{
-						URL:     newurl,
-						ModTime: &mod,
-						Type:    ObjectType{objtype},
-						Size:    0,
-					}
+					versions = append(versions, &Object{
+						URL:          newurl,
+						ModTime:      &mod,
+						Type:         ObjectType{objtype},
+						Size:         0,
+						IsLatest:     aws.BoolValue(d.IsLatest),
+						DeleteMarker: true,
+					})
 
 					objectFound = true
 				}
 
-				return !lastPage
-			})
-
-		if err != nil {
-			objCh <- &Object{Err: err}
-			return
-		}
+				// versions and delete markers are returned in separate lists,
+				// merge them back by key.
+				sortVersions(versions)
+				page = append(page, versions...)
 
-		if !objectFound {
-			objCh <- &Object{Err: ErrNoObjectFound}
-		}
-	}()
+				return emit(page) && !lastPage
+			})
+		return objectFound, err
+	})
+}
 
-	return objCh
+// sortVersions sorts the versions of a listing page by key, so that the
+// versions and the delete markers of a key are sent adjacently.
+func sortVersions(versions []*Object) {
+	sort.SliceStable(versions, func(i, j int) bool {
+		return versions[i].URL.Path < versions[j].URL.Path
+	})
 }
 
 func (s *S3) listObjectsV2(ctx context.Context, url *url.URL) <-chan *Object {
@@ -306,15 +572,17 @@ func (s *S3) listObjectsV2(ctx context.Context, url *url.URL) <-chan *Object {
 		listInput.SetDelimiter(url.Delimiter)
 	}
 
-	objCh := make(chan *Object)
+	if s.fetchOwner {
+		listInput.SetFetchOwner(true)
+	}
 
-	go func() {
-		defer close(objCh)
+	return listPages(ctx, func(emit func(page []*Object) bool) (bool, error) {
 		objectFound := false
 
 		var now time.Time
 
 		err := s.api.ListObjectsV2PagesWithContext(ctx, &listInput, func(p *s3.ListObjectsV2Output, lastPage bool) bool {
+			page := make([]*Object, 0, len(p.CommonPrefixes)+len(p.Contents))
 			for _, c := range p.CommonPrefixes {
 				prefix := aws.StringValue(c.Prefix)
 				if !url.Match(prefix) {
@@ -323,10 +591,10 @@ func (s *S3) listObjectsV2(ctx context.Context, url *url.URL) <-chan *Object {
 
 				newurl := url.Clone()
 				newurl.Path = prefix
-				objCh <- &Object{
+				page = append(page, &Object{
 					URL:  newurl,
 					Type: ObjectType{os.ModeDir},
-				}
+				})
 
 				objectFound = true
 			}
@@ -357,32 +625,35 @@ func (s *S3) listObjectsV2(ctx context.Context, url *url.URL) <-chan *Object {
 				newurl.Path = aws.StringValue(c.Key)
 				etag := aws.StringValue(c.ETag)
 
-				objCh <- &Object{
+				page = append(page, &Object{
 					URL:          newurl,
 					Etag:         strings.Trim(etag, `"`),
 					ModTime:      &mod,
 					Type:         ObjectType{objtype},
 					Size:         aws.Int64Value(c.Size),
 					StorageClass: StorageClass(aws.StringValue(c.StorageClass)),
-				}
+					Owner:        s.owner(c.Owner),
+				})
 
 				objectFound = true
 			}
 
-			return !lastPage
+			return emit(page) && !lastPage
 		})
+		return objectFound, err
+	})
+}
 
-		if err != nil {
-			objCh <- &Object{Err: err}
-			return
-		}
-
-		if !objectFound {
-			objCh <- &Object{Err: ErrNoObjectFound}
-		}
-	}()
-
-	return objCh
+// owner converts the owner information returned in listings. It returns nil
+// unless owner information is requested.
+func (s *S3) owner(o *s3.Owner) *Owner {
+	if !s.fetchOwner || o == nil {
+		return nil
+	}
+	return &Owner{
+		ID:          aws.StringValue(o.ID),
+		DisplayName: aws.StringValue(o.DisplayName),
+	}
 }
 
 // listObjects is used for cloud services that does not support S3
@@ -398,15 +669,13 @@ func (s *S3) listObjects(ctx context.Context, url *url.URL) <-chan *Object {
 		listInput.SetDelimiter(url.Delimiter)
 	}
 
-	objCh := make(chan *Object)
-
-	go func() {
-		defer close(objCh)
+	return listPages(ctx, func(emit func(page []*Object) bool) (bool, error) {
 		objectFound := false
 
 		var now time.Time
 
 		err := s.api.ListObjectsPagesWithContext(ctx, &listInput, func(p *s3.ListObjectsOutput, lastPage bool) bool {
+			page := make([]*Object, 0, len(p.CommonPrefixes)+len(p.Contents))
 			for _, c := range p.CommonPrefixes {
 				prefix := aws.StringValue(c.Prefix)
 				if !url.Match(prefix) {
@@ -415,10 +684,10 @@ func (s *S3) listObjects(ctx context.Context, url *url.URL) <-chan *Object {
 
 				newurl := url.Clone()
 				newurl.Path = prefix
-				objCh <- &Object{
+				page = append(page, &Object{
 					URL:  newurl,
 					Type: ObjectType{os.ModeDir},
-				}
+				})
 
 				objectFound = true
 			}
@@ -449,32 +718,107 @@ func (s *S3) listObjects(ctx context.Context, url *url.URL) <-chan *Object {
 				newurl.Path = aws.StringValue(c.Key)
 				etag := aws.StringValue(c.ETag)
 
-				objCh <- &Object{
+				page = append(page, &Object{
 					URL:          newurl,
 					Etag:         strings.Trim(etag, `"`),
 					ModTime:      &mod,
 					Type:         ObjectType{objtype},
 					Size:         aws.Int64Value(c.Size),
 					StorageClass: StorageClass(aws.StringValue(c.StorageClass)),
-				}
+					Owner:        s.owner(c.Owner),
+				})
 
 				objectFound = true
 			}
 
-			return !lastPage
+			return emit(page) && !lastPage
 		})
+		return objectFound, err
+	})
+}
 
-		if err != nil {
-			objCh <- &Object{Err: err}
-			return
-		}
+// MultipartUpload is a multipart upload which is not completed or aborted yet.
+type MultipartUpload struct {
+	URL          *url.URL
+	ID           string
+	Initiated    time.Time
+	StorageClass StorageClass
+	Err          error
+}
 
-		if !objectFound {
-			objCh <- &Object{Err: ErrNoObjectFound}
+// ListMultipartUploads lists the multipart uploads in progress whose keys
+// match url, in the same way as the objects are listed with List. The last
+// upload has Err set if the listing fails.
+func (s *S3) ListMultipartUploads(ctx context.Context, url *url.URL) <-chan *MultipartUpload {
+	input := s3.ListMultipartUploadsInput{
+		Bucket: aws.String(url.Bucket),
+		Prefix: aws.String(url.Prefix),
+	}
+	if url.Delimiter != "" {
+		input.SetDelimiter(url.Delimiter)
+	}
+
+	uploadch := make(chan *MultipartUpload)
+	go func() {
+		defer close(uploadch)
+
+		err := s.api.ListMultipartUploadsPagesWithContext(ctx, &input, func(p *s3.ListMultipartUploadsOutput, lastPage bool) bool {
+			for _, u := range p.Uploads {
+				key := aws.StringValue(u.Key)
+				if !url.Match(key) {
+					continue
+				}
+
+				newurl := url.Clone()
+				newurl.Path = key
+				upload := &MultipartUpload{
+					URL:          newurl,
+					ID:           aws.StringValue(u.UploadId),
+					Initiated:    aws.TimeValue(u.Initiated).UTC(),
+					StorageClass: StorageClass(aws.StringValue(u.StorageClass)),
+				}
+				select {
+				case uploadch <- upload:
+				case <-ctx.Done():
+					return false
+				}
+			}
+			return !lastPage
+		})
+		if err == nil {
+			err = ctx.Err()
+		}
+		if err != nil {
+			uploadch <- &MultipartUpload{Err: err}
 		}
 	}()
+	return uploadch
+}
 
-	return objCh
+// UploadedParts returns the number and the total size of the parts uploaded
+// to the given multipart upload so far, which are billed until the upload is
+// completed or aborted. It returns zero if the upload no longer exists.
+func (s *S3) UploadedParts(ctx context.Context, upload *MultipartUpload) (int64, int64, error) {
+	input := &s3.ListPartsInput{
+		Bucket:       aws.String(upload.URL.Bucket),
+		Key:          aws.String(upload.URL.Path),
+		UploadId:     aws.String(upload.ID),
+		RequestPayer: s.RequestPayer(),
+	}
+
+	var count, size int64
+	err := s.api.ListPartsPagesWithContext(ctx, input, func(p *s3.ListPartsOutput, lastPage bool) bool {
+		for _, part := range p.Parts {
+			count++
+			size += aws.Int64Value(part.Size)
+		}
+		return !lastPage
+	})
+	// the upload is completed or aborted after it's listed.
+	if errHasCode(err, s3.ErrCodeNoSuchUpload) {
+		return 0, 0, nil
+	}
+	return count, size, err
 }
 
 // Copy is a single-object copy operation which copies objects to S3
@@ -535,44 +879,85 @@ func (s *S3) Copy(ctx context.Context, from, to *url.URL, metadata Metadata) err
 		input.Expires = aws.Time(t)
 	}
 
+	// CopyObject copies the metadata of the source as it is, unless it's
+	// replaced as a whole. The metadata of the source is read to keep the
+	// headers which are not overridden.
+	if metadata.ContentLanguage() != "" || metadata.WebsiteRedirect() != "" || len(metadata.UserMetadata()) > 0 {
+		if err := s.replaceCopyMetadata(ctx, from, input, metadata); err != nil {
+			return err
+		}
+	}
+
 	_, err := s.api.CopyObject(input)
+	if err == nil {
+		recordUpload(false)
+	}
 	return err
 }
 
-// Read fetches the remote object and returns its contents as an io.ReadCloser.
-func (s *S3) Read(ctx context.Context, src *url.URL) (io.ReadCloser, error) {
-	input := &s3.GetObjectInput{
-		Bucket:       aws.String(src.Bucket),
-		Key:          aws.String(src.Path),
-		RequestPayer: s.RequestPayer(),
-	}
-	if src.VersionID != "" {
-		input.SetVersionId(src.VersionID)
+// replaceCopyMetadata sets the copy input to replace the metadata of the
+// source with the given metadata, keeping the headers of the source which are
+// not given.
+func (s *S3) replaceCopyMetadata(ctx context.Context, from *url.URL, input *s3.CopyObjectInput, metadata Metadata) error {
+	source, err := s.headCopySource(ctx, from)
+	if err != nil {
+		return err
 	}
 
-	resp, err := s.api.GetObjectWithContext(ctx, input)
+	input.MetadataDirective = aws.String(s3.MetadataDirectiveReplace)
+	input.ContentType = source.ContentType
+	input.ContentEncoding = source.ContentEncoding
+	input.ContentDisposition = source.ContentDisposition
+	input.ContentLanguage = source.ContentLanguage
+	input.WebsiteRedirectLocation = source.WebsiteRedirectLocation
+	input.Metadata = withUserMetadata(source.Metadata, metadata)
+	if input.CacheControl == nil {
+		input.CacheControl = source.CacheControl
+	}
+	if input.Expires == nil && source.Expires != nil {
+		if t, err := http.ParseTime(aws.StringValue(source.Expires)); err == nil {
+			input.Expires = aws.Time(t)
+		}
+	}
 
-	if err != nil {
-		return nil, err
+	if contentType := metadata.ContentType(); contentType != "" {
+		input.ContentType = aws.String(contentType)
 	}
-	return resp.Body, nil
+	if contentEncoding := metadata.ContentEncoding(); contentEncoding != "" {
+		input.ContentEncoding = aws.String(contentEncoding)
+	}
+	if contentDisposition := metadata.ContentDisposition(); contentDisposition != "" {
+		input.ContentDisposition = aws.String(contentDisposition)
+	}
+	if contentLanguage := metadata.ContentLanguage(); contentLanguage != "" {
+		input.ContentLanguage = aws.String(contentLanguage)
+	}
+	if websiteRedirect := metadata.WebsiteRedirect(); websiteRedirect != "" {
+		input.WebsiteRedirectLocation = aws.String(websiteRedirect)
+	}
+	return nil
 }
 
-// Get is a multipart download operation which downloads S3 objects into any
-// destination that implements io.WriterAt interface.
-// Makes a single 'GetObject' call if 'concurrency' is 1 and ignores 'partSize'.
-func (s *S3) Get(
-	ctx context.Context,
-	from *url.URL,
-	to io.WriterAt,
-	concurrency int,
-	partSize int64,
-) (int64, error) {
-	if s.dryRun {
-		return 0, nil
+// withUserMetadata returns the user-defined metadata of the source of a copy
+// along with the given ones, which override the ones of the source.
+func withUserMetadata(source map[string]*string, metadata Metadata) map[string]*string {
+	userMetadata := metadata.UserMetadata()
+	if len(userMetadata) == 0 {
+		return source
+	}
+	merged := make(map[string]*string, len(source)+len(userMetadata))
+	for name, value := range source {
+		merged[strings.ToLower(name)] = value
 	}
+	for name, value := range userMetadata {
+		merged[name] = aws.String(value)
+	}
+	return merged
+}
 
-	input := &s3.GetObjectInput{
+// headCopySource returns the headers of the source object of a copy.
+func (s *S3) headCopySource(ctx context.Context, from *url.URL) (*s3.HeadObjectOutput, error) {
+	input := &s3.HeadObjectInput{
 		Bucket:       aws.String(from.Bucket),
 		Key:          aws.String(from.Path),
 		RequestPayer: s.RequestPayer(),
@@ -580,27 +965,360 @@ func (s *S3) Get(
 	if from.VersionID != "" {
 		input.VersionId = aws.String(from.VersionID)
 	}
-
-	return s.downloader.DownloadWithContext(ctx, to, input, func(u *s3manager.Downloader) {
-		u.PartSize = partSize
-		u.Concurrency = concurrency
-	})
+	return s.api.HeadObjectWithContext(ctx, input)
 }
 
-type SelectQuery struct {
-	ExpressionType  string
-	Expression      string
-	CompressionType string
+// partCopySlots limits the number of the UploadPartCopy requests which run at
+// the same time across all multipart copies, so that a few huge copies don't
+// take all connections from the other transfers. It's nil, i.e. unlimited,
+// unless LimitPartCopies is called.
+var partCopySlots chan struct{}
+
+// LimitPartCopies limits the number of the UploadPartCopy requests of all
+// multipart copies which run at the same time to n. It must be called before
+// any copies are started.
+func LimitPartCopies(n int) {
+	partCopySlots = make(chan struct{}, n)
 }
 
-func (s *S3) Select(ctx context.Context, url *url.URL, query *SelectQuery, resultCh chan<- json.RawMessage) error {
+// MultipartCopy copies the object at from to "to" on the server side with
+// UploadPartCopy requests, up to concurrency of which run at the same time.
+// It's used for the objects larger than MaxCopyObjectSize, which can't be
+// copied with CopyObject. The parts are partSize bytes, unless the object
+// would have more parts than allowed. Unlike CopyObject, a multipart upload
+// doesn't copy the metadata of the source, so it's read from the source
+// object, unless it's given.
+func (s *S3) MultipartCopy(
+	ctx context.Context,
+	from *url.URL,
+	to *url.URL,
+	metadata Metadata,
+	concurrency int,
+	partSize int64,
+) error {
 	if s.dryRun {
 		return nil
 	}
 
-	input := &s3.SelectObjectContentInput{
-		Bucket:         aws.String(url.Bucket),
-		Key:            aws.String(url.Path),
+	source, err := s.headCopySource(ctx, from)
+	if err != nil {
+		return err
+	}
+
+	size := aws.Int64Value(source.ContentLength)
+	partSize, err = copyPartSize(size, partSize)
+	if err != nil {
+		return err
+	}
+
+	input := &s3.CreateMultipartUploadInput{
+		Bucket:                  aws.String(to.Bucket),
+		Key:                     aws.String(to.Path),
+		ContentType:             source.ContentType,
+		ContentEncoding:         source.ContentEncoding,
+		ContentDisposition:      source.ContentDisposition,
+		ContentLanguage:         source.ContentLanguage,
+		CacheControl:            source.CacheControl,
+		WebsiteRedirectLocation: source.WebsiteRedirectLocation,
+		Metadata:                withUserMetadata(source.Metadata, metadata),
+		RequestPayer:            s.RequestPayer(),
+	}
+
+	if contentType := metadata.ContentType(); contentType != "" {
+		input.ContentType = aws.String(contentType)
+	}
+	if contentEncoding := metadata.ContentEncoding(); contentEncoding != "" {
+		input.ContentEncoding = aws.String(contentEncoding)
+	}
+	if contentDisposition := metadata.ContentDisposition(); contentDisposition != "" {
+		input.ContentDisposition = aws.String(contentDisposition)
+	}
+	if contentLanguage := metadata.ContentLanguage(); contentLanguage != "" {
+		input.ContentLanguage = aws.String(contentLanguage)
+	}
+	if cacheControl := metadata.CacheControl(); cacheControl != "" {
+		input.CacheControl = aws.String(cacheControl)
+	}
+	if websiteRedirect := metadata.WebsiteRedirect(); websiteRedirect != "" {
+		input.WebsiteRedirectLocation = aws.String(websiteRedirect)
+	}
+
+	storageClass := metadata.StorageClass()
+	if storageClass != "" {
+		input.StorageClass = aws.String(storageClass)
+	}
+	acl := metadata.ACL()
+	if acl != "" {
+		input.ACL = aws.String(acl)
+	}
+
+	expires := metadata.Expires()
+	if expires != "" {
+		t, err := time.Parse(time.RFC3339, expires)
+		if err != nil {
+			return err
+		}
+		input.Expires = aws.Time(t)
+	}
+
+	sseEncryption := metadata.SSE()
+	if sseEncryption != "" {
+		input.ServerSideEncryption = aws.String(sseEncryption)
+		sseKmsKeyID := metadata.SSEKeyID()
+		if sseKmsKeyID != "" {
+			input.SSEKMSKeyId = aws.String(sseKmsKeyID)
+		}
+	}
+
+	output, err := s.api.CreateMultipartUploadWithContext(ctx, input)
+	if err != nil {
+		return err
+	}
+	uploadID := output.UploadId
+
+	completed, err := s.copyParts(ctx, uploadID, from, to, aws.StringValue(source.ETag), size, partSize, concurrency)
+	if err == nil {
+		_, err = s.api.CompleteMultipartUploadWithContext(ctx, &s3.CompleteMultipartUploadInput{
+			Bucket:          aws.String(to.Bucket),
+			Key:             aws.String(to.Path),
+			UploadId:        uploadID,
+			MultipartUpload: &s3.CompletedMultipartUpload{Parts: completed},
+			RequestPayer:    s.RequestPayer(),
+		})
+	}
+
+	if err != nil {
+		s.abortUpload(to, aws.StringValue(uploadID))
+		return err
+	}
+
+	recordUpload(true)
+	return nil
+}
+
+// copyParts copies the object at from in parts of partSize bytes to the given
+// multipart upload. The parts are copied by concurrency workers, and the
+// copy fails if the source object is modified after its ETag is read.
+func (s *S3) copyParts(
+	ctx context.Context,
+	uploadID *string,
+	from *url.URL,
+	to *url.URL,
+	etag string,
+	size int64,
+	partSize int64,
+	concurrency int,
+) ([]*s3.CompletedPart, error) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	copySource := from.EscapedPath()
+	if from.VersionID != "" {
+		copySource += "?versionId=" + from.VersionID
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	parts := (size + partSize - 1) / partSize
+	completed := make([]*s3.CompletedPart, parts)
+	partNumbers := make(chan int64)
+
+	var (
+		wg      sync.WaitGroup
+		errOnce sync.Once
+		copyErr error
+	)
+	fail := func(err error) {
+		errOnce.Do(func() {
+			copyErr = err
+			cancel()
+		})
+	}
+
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for partNumber := range partNumbers {
+				offset := (partNumber - 1) * partSize
+				end := offset + partSize
+				if end > size {
+					end = size
+				}
+
+				release, err := acquirePartCopy(ctx)
+				if err != nil {
+					fail(err)
+					continue
+				}
+				output, err := s.api.UploadPartCopyWithContext(ctx, &s3.UploadPartCopyInput{
+					Bucket:            aws.String(to.Bucket),
+					Key:               aws.String(to.Path),
+					UploadId:          uploadID,
+					PartNumber:        aws.Int64(partNumber),
+					CopySource:        aws.String(copySource),
+					CopySourceIfMatch: aws.String(etag),
+					CopySourceRange:   aws.String(fmt.Sprintf("bytes=%d-%d", offset, end-1)),
+					RequestPayer:      s.RequestPayer(),
+				})
+				release()
+				if err != nil {
+					fail(err)
+					continue
+				}
+
+				completed[partNumber-1] = &s3.CompletedPart{
+					ETag:       output.CopyPartResult.ETag,
+					PartNumber: aws.Int64(partNumber),
+				}
+			}
+		}()
+	}
+
+send:
+	for partNumber := int64(1); partNumber <= parts; partNumber++ {
+		select {
+		case partNumbers <- partNumber:
+		case <-ctx.Done():
+			break send
+		}
+	}
+	close(partNumbers)
+	wg.Wait()
+
+	if copyErr != nil {
+		return nil, copyErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return completed, nil
+}
+
+// acquirePartCopy waits for a slot to make an UploadPartCopy request, see
+// LimitPartCopies. It returns the function to release the slot.
+func acquirePartCopy(ctx context.Context) (func(), error) {
+	slots := partCopySlots
+	if slots == nil {
+		return func() {}, nil
+	}
+	select {
+	case slots <- struct{}{}:
+		return func() { <-slots }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// copyPartSize returns the size of the parts which an object of the given size
+// is copied in. The parts are at least MinPartSize bytes, and larger than
+// partSize if the object would have more than the maximum number of parts.
+func copyPartSize(size, partSize int64) (int64, error) {
+	if partSize < MinPartSize {
+		partSize = MinPartSize
+	}
+	if (size+partSize-1)/partSize > s3manager.MaxUploadParts {
+		partSize = (size + s3manager.MaxUploadParts - 1) / s3manager.MaxUploadParts
+	}
+	if partSize > maxCopyPartSize {
+		return 0, fmt.Errorf("object of %d bytes can't be copied in %d parts of at most %d bytes",
+			size, s3manager.MaxUploadParts, int64(maxCopyPartSize))
+	}
+	return partSize, nil
+}
+
+// Read fetches the remote object and returns its contents as an io.ReadCloser.
+func (s *S3) Read(ctx context.Context, src *url.URL) (io.ReadCloser, error) {
+	input := &s3.GetObjectInput{
+		Bucket:       aws.String(src.Bucket),
+		Key:          aws.String(src.Path),
+		RequestPayer: s.RequestPayer(),
+	}
+	if src.VersionID != "" {
+		input.SetVersionId(src.VersionID)
+	}
+
+	resp, err := s.api.GetObjectWithContext(ctx, input)
+
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// ReadRange fetches the given byte range of the remote object, up to its end
+// if length is negative, and returns its contents as an io.ReadCloser. Unlike
+// Read, the contents of the objects with gzip content encoding are returned
+// as they are stored, since the ranged requests are not decompressed by the
+// HTTP transport.
+func (s *S3) ReadRange(ctx context.Context, src *url.URL, offset, length int64) (io.ReadCloser, error) {
+	byteRange := fmt.Sprintf("bytes=%d-", offset)
+	if length >= 0 {
+		byteRange = fmt.Sprintf("bytes=%d-%d", offset, offset+length-1)
+	}
+
+	input := &s3.GetObjectInput{
+		Bucket:       aws.String(src.Bucket),
+		Key:          aws.String(src.Path),
+		Range:        aws.String(byteRange),
+		RequestPayer: s.RequestPayer(),
+	}
+	if src.VersionID != "" {
+		input.SetVersionId(src.VersionID)
+	}
+
+	resp, err := s.api.GetObjectWithContext(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Get is a multipart download operation which downloads S3 objects into any
+// destination that implements io.WriterAt interface.
+// Makes a single 'GetObject' call if 'concurrency' is 1 and ignores 'partSize'.
+func (s *S3) Get(
+	ctx context.Context,
+	from *url.URL,
+	to io.WriterAt,
+	concurrency int,
+	partSize int64,
+) (int64, error) {
+	if s.dryRun {
+		return 0, nil
+	}
+
+	input := &s3.GetObjectInput{
+		Bucket:       aws.String(from.Bucket),
+		Key:          aws.String(from.Path),
+		RequestPayer: s.RequestPayer(),
+	}
+	if from.VersionID != "" {
+		input.VersionId = aws.String(from.VersionID)
+	}
+
+	return s.downloader.DownloadWithContext(ctx, to, input, func(u *s3manager.Downloader) {
+		u.PartSize = partSize
+		u.Concurrency = concurrency
+	})
+}
+
+type SelectQuery struct {
+	ExpressionType  string
+	Expression      string
+	CompressionType string
+}
+
+func (s *S3) Select(ctx context.Context, url *url.URL, query *SelectQuery, resultCh chan<- json.RawMessage) error {
+	if s.dryRun {
+		return nil
+	}
+
+	input := &s3.SelectObjectContentInput{
+		Bucket:         aws.String(url.Bucket),
+		Key:            aws.String(url.Path),
 		ExpressionType: aws.String(query.ExpressionType),
 		Expression:     aws.String(query.Expression),
 		InputSerialization: &s3.InputSerialization{
@@ -679,12 +1397,277 @@ func (s *S3) Put(
 		contentType = "application/octet-stream"
 	}
 
-	input := &s3manager.UploadInput{
+	input := &s3manager.UploadInput{
+		Bucket:       aws.String(to.Bucket),
+		Key:          aws.String(to.Path),
+		Body:         reader,
+		ContentType:  aws.String(contentType),
+		Metadata:     make(map[string]*string),
+		RequestPayer: s.RequestPayer(),
+	}
+
+	storageClass := metadata.StorageClass()
+	if storageClass != "" {
+		input.StorageClass = aws.String(storageClass)
+	}
+	acl := metadata.ACL()
+	if acl != "" {
+		input.ACL = aws.String(acl)
+	}
+
+	cacheControl := metadata.CacheControl()
+	if cacheControl != "" {
+		input.CacheControl = aws.String(cacheControl)
+	}
+
+	expires := metadata.Expires()
+	if expires != "" {
+		t, err := time.Parse(time.RFC3339, expires)
+		if err != nil {
+			return err
+		}
+		input.Expires = aws.Time(t)
+	}
+
+	sseEncryption := metadata.SSE()
+	if sseEncryption != "" {
+		input.ServerSideEncryption = aws.String(sseEncryption)
+		sseKmsKeyID := metadata.SSEKeyID()
+		if sseKmsKeyID != "" {
+			input.SSEKMSKeyId = aws.String(sseKmsKeyID)
+		}
+	}
+
+	contentEncoding := metadata.ContentEncoding()
+	if contentEncoding != "" {
+		input.ContentEncoding = aws.String(contentEncoding)
+	}
+
+	contentDisposition := metadata.ContentDisposition()
+	if contentDisposition != "" {
+		input.ContentDisposition = aws.String(contentDisposition)
+	}
+
+	contentLanguage := metadata.ContentLanguage()
+	if contentLanguage != "" {
+		input.ContentLanguage = aws.String(contentLanguage)
+	}
+
+	websiteRedirect := metadata.WebsiteRedirect()
+	if websiteRedirect != "" {
+		input.WebsiteRedirectLocation = aws.String(websiteRedirect)
+	}
+
+	for name, value := range metadata.UserMetadata() {
+		input.Metadata[name] = aws.String(value)
+	}
+
+	if symlinkTarget := metadata.SymlinkTarget(); symlinkTarget != "" {
+		input.Metadata[metadataKeySymlinkTarget] = aws.String(symlinkTarget)
+	}
+
+	if attrs := metadata.WindowsAttributes(); attrs != "" {
+		input.Metadata[metadataKeyWindowsAttributes] = aws.String(attrs)
+	}
+
+	if modTime := metadata.ModTime(); modTime != "" {
+		input.Metadata[metadataKeyModTime] = aws.String(modTime)
+	}
+
+	// add retry ID to the object metadata
+	if s.noSuchUploadRetryCount > 0 {
+		input.Metadata[metadataKeyRetryID] = generateRetryID()
+	}
+
+	uploaderOptsFn := func(u *s3manager.Uploader) {
+		u.PartSize = partSize
+		u.Concurrency = concurrency
+	}
+	output, err := s.uploader.UploadWithContext(ctx, input, uploaderOptsFn)
+
+	if errHasCode(err, s3.ErrCodeNoSuchUpload) && s.noSuchUploadRetryCount > 0 {
+		err = s.retryOnNoSuchUpload(ctx, to, input, err, uploaderOptsFn)
+		if err == nil {
+			recordUpload(true)
+		}
+		return err
+	}
+
+	if err != nil && ctx.Err() != nil {
+		s.abortMultipartUpload(to, err)
+	}
+
+	if err == nil {
+		recordUpload(output.UploadID != "")
+	}
+	return err
+}
+
+// abortMultipartUpload aborts the multipart upload which failed with the given
+// error because its context is done. The uploader tries to abort failed
+// uploads by itself, but it uses the same context which is no longer usable.
+func (s *S3) abortMultipartUpload(to *url.URL, err error) {
+	var multiUploadErr s3manager.MultiUploadFailure
+	if !errors.As(err, &multiUploadErr) || multiUploadErr.UploadID() == "" {
+		return
+	}
+
+	s.abortUpload(to, multiUploadErr.UploadID())
+}
+
+func (s *S3) retryOnNoSuchUpload(ctx aws.Context, to *url.URL, input *s3manager.UploadInput,
+	err error, uploaderOpts ...func(*s3manager.Uploader)) error {
+
+	var expectedRetryID string
+	if ID, ok := input.Metadata[metadataKeyRetryID]; ok {
+		expectedRetryID = *ID
+	}
+
+	attempts := 0
+	for ; errHasCode(err, s3.ErrCodeNoSuchUpload) && attempts < s.noSuchUploadRetryCount; attempts++ {
+		// check if object exists and has the retry ID we provided, if it does
+		// then it means that one of previous uploads was succesfull despite the received error.
+		obj, sErr := s.Stat(ctx, to)
+		if sErr == nil && obj.retryID == expectedRetryID {
+			err = nil
+			break
+		}
+
+		msg := log.DebugMessage{Err: fmt.Sprintf("Retrying to upload %v upon error: %q", to, err.Error())}
+		log.Debug(msg)
+
+		_, err = s.uploader.UploadWithContext(ctx, input, uploaderOpts...)
+	}
+
+	if errHasCode(err, s3.ErrCodeNoSuchUpload) && s.noSuchUploadRetryCount > 0 {
+		err = awserr.New(s3.ErrCodeNoSuchUpload, fmt.Sprintf(
+			"RetryOnNoSuchUpload: %v attempts to retry resulted in %v", attempts,
+			s3.ErrCodeNoSuchUpload), err)
+	}
+	return err
+}
+
+// HasPrefix reports whether the content of the remote object obj is the same
+// as the first obj.Size bytes of r. The ETag of the object is compared against
+// the MD5 digest of r, calculated part by part for objects uploaded in
+// multiple parts. Objects whose ETag is not an MD5 digest, such as the ones
+// encrypted with SSE-KMS, never match.
+func (s *S3) HasPrefix(ctx context.Context, obj *Object, r io.ReaderAt) (bool, error) {
+	etag, parts := obj.Etag, 0
+	if i := strings.LastIndex(etag, "-"); i >= 0 {
+		n, err := strconv.Atoi(etag[i+1:])
+		if err != nil || n <= 0 {
+			return false, nil
+		}
+		parts = n
+	}
+
+	if parts == 0 {
+		sum, err := md5Sum(io.NewSectionReader(r, 0, obj.Size))
+		if err != nil {
+			return false, err
+		}
+		return hex.EncodeToString(sum) == etag, nil
+	}
+
+	// the ETag of a multipart upload is the MD5 digest of the concatenated
+	// MD5 digests of its parts, suffixed by the number of parts.
+	digests := md5.New()
+	var offset int64
+	for partNumber := 1; partNumber <= parts; partNumber++ {
+		output, err := s.api.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+			Bucket:       aws.String(obj.URL.Bucket),
+			Key:          aws.String(obj.URL.Path),
+			PartNumber:   aws.Int64(int64(partNumber)),
+			IfMatch:      aws.String(strconv.Quote(etag)),
+			RequestPayer: s.RequestPayer(),
+		})
+		if err != nil {
+			return false, err
+		}
+
+		size := aws.Int64Value(output.ContentLength)
+		sum, err := md5Sum(io.NewSectionReader(r, offset, size))
+		if err != nil {
+			return false, err
+		}
+		digests.Write(sum)
+		offset += size
+	}
+
+	if offset != obj.Size {
+		return false, nil
+	}
+	return fmt.Sprintf("%x-%d", digests.Sum(nil), parts) == etag, nil
+}
+
+// Append uploads the first size bytes of r to the existing object prefix,
+// whose content must be the same as the beginning of r. The content of prefix
+// is copied on the server side with UploadPartCopy and only the remaining
+// bytes of r are uploaded. The copy fails if the object is modified after its
+// ETag is read.
+func (s *S3) Append(
+	ctx context.Context,
+	r io.ReaderAt,
+	size int64,
+	prefix *Object,
+	metadata Metadata,
+	partSize int64,
+) error {
+	if s.dryRun {
+		return nil
+	}
+
+	if prefix.Size < MinPartSize {
+		return fmt.Errorf("object %v is smaller than the minimum part size", prefix.URL)
+	}
+	if size <= prefix.Size {
+		return fmt.Errorf("nothing to append to object %v", prefix.URL)
+	}
+
+	to := prefix.URL
+
+	input, err := s.createMultipartUploadInput(to, metadata)
+	if err != nil {
+		return err
+	}
+
+	output, err := s.api.CreateMultipartUploadWithContext(ctx, input)
+	if err != nil {
+		return err
+	}
+	uploadID := output.UploadId
+
+	completed, err := s.appendParts(ctx, uploadID, r, size, prefix, partSize)
+	if err == nil {
+		_, err = s.api.CompleteMultipartUploadWithContext(ctx, &s3.CompleteMultipartUploadInput{
+			Bucket:          aws.String(to.Bucket),
+			Key:             aws.String(to.Path),
+			UploadId:        uploadID,
+			MultipartUpload: &s3.CompletedMultipartUpload{Parts: completed},
+			RequestPayer:    s.RequestPayer(),
+		})
+	}
+
+	if err != nil {
+		s.abortUpload(to, aws.StringValue(uploadID))
+	}
+
+	return err
+}
+
+// createMultipartUploadInput returns the input of the multipart upload to the
+// object at "to" with the given metadata.
+func (s *S3) createMultipartUploadInput(to *url.URL, metadata Metadata) (*s3.CreateMultipartUploadInput, error) {
+	contentType := metadata.ContentType()
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	input := &s3.CreateMultipartUploadInput{
 		Bucket:       aws.String(to.Bucket),
 		Key:          aws.String(to.Path),
-		Body:         reader,
 		ContentType:  aws.String(contentType),
-		Metadata:     make(map[string]*string),
 		RequestPayer: s.RequestPayer(),
 	}
 
@@ -706,7 +1689,7 @@ func (s *S3) Put(
 	if expires != "" {
 		t, err := time.Parse(time.RFC3339, expires)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		input.Expires = aws.Time(t)
 	}
@@ -730,59 +1713,133 @@ func (s *S3) Put(
 		input.ContentDisposition = aws.String(contentDisposition)
 	}
 
-	// add retry ID to the object metadata
-	if s.noSuchUploadRetryCount > 0 {
-		input.Metadata[metadataKeyRetryID] = generateRetryID()
+	contentLanguage := metadata.ContentLanguage()
+	if contentLanguage != "" {
+		input.ContentLanguage = aws.String(contentLanguage)
 	}
 
-	uploaderOptsFn := func(u *s3manager.Uploader) {
-		u.PartSize = partSize
-		u.Concurrency = concurrency
+	websiteRedirect := metadata.WebsiteRedirect()
+	if websiteRedirect != "" {
+		input.WebsiteRedirectLocation = aws.String(websiteRedirect)
 	}
-	_, err := s.uploader.UploadWithContext(ctx, input, uploaderOptsFn)
 
-	if errHasCode(err, s3.ErrCodeNoSuchUpload) && s.noSuchUploadRetryCount > 0 {
-		return s.retryOnNoSuchUpload(ctx, to, input, err, uploaderOptsFn)
-	}
+	return input, nil
+}
 
-	return err
+// abortUpload aborts the given multipart upload to the object at "to", so that
+// its parts are not kept.
+func (s *S3) abortUpload(to *url.URL, uploadID string) {
+	// use a fresh context, the given one may be the reason of the failure.
+	_, err := s.api.AbortMultipartUploadWithContext(context.Background(), &s3.AbortMultipartUploadInput{
+		Bucket:       aws.String(to.Bucket),
+		Key:          aws.String(to.Path),
+		UploadId:     aws.String(uploadID),
+		RequestPayer: s.RequestPayer(),
+	})
+	if err != nil {
+		msg := log.DebugMessage{Err: fmt.Sprintf("failed to abort multipart upload of %v: %v", to, err)}
+		log.Debug(msg)
+	}
 }
 
-func (s *S3) retryOnNoSuchUpload(ctx aws.Context, to *url.URL, input *s3manager.UploadInput,
-	err error, uploaderOpts ...func(*s3manager.Uploader)) error {
+// appendParts copies the content of prefix and uploads the rest of r as the
+// parts of the given multipart upload.
+func (s *S3) appendParts(
+	ctx context.Context,
+	uploadID *string,
+	r io.ReaderAt,
+	size int64,
+	prefix *Object,
+	partSize int64,
+) ([]*s3.CompletedPart, error) {
+	to := prefix.URL
 
-	var expectedRetryID string
-	if ID, ok := input.Metadata[metadataKeyRetryID]; ok {
-		expectedRetryID = *ID
+	// every part but the last one must be at least MinPartSize bytes, so the
+	// prefix is split into equal parts instead of maxCopyPartSize chunks.
+	copyParts := (prefix.Size + maxCopyPartSize - 1) / maxCopyPartSize
+	copyPartSize := (prefix.Size + copyParts - 1) / copyParts
+
+	tail := size - prefix.Size
+	if partSize < MinPartSize {
+		partSize = MinPartSize
+	}
+	if maxParts := int64(s3manager.MaxUploadParts) - copyParts; (tail+partSize-1)/partSize > maxParts {
+		partSize = (tail + maxParts - 1) / maxParts
 	}
 
-	attempts := 0
-	for ; errHasCode(err, s3.ErrCodeNoSuchUpload) && attempts < s.noSuchUploadRetryCount; attempts++ {
-		// check if object exists and has the retry ID we provided, if it does
-		// then it means that one of previous uploads was succesfull despite the received error.
-		obj, sErr := s.Stat(ctx, to)
-		if sErr == nil && obj.retryID == expectedRetryID {
-			err = nil
-			break
+	var (
+		completed  []*s3.CompletedPart
+		partNumber int64
+	)
+
+	for offset := int64(0); offset < prefix.Size; offset += copyPartSize {
+		end := offset + copyPartSize
+		if end > prefix.Size {
+			end = prefix.Size
+		}
+		partNumber++
+
+		output, err := s.api.UploadPartCopyWithContext(ctx, &s3.UploadPartCopyInput{
+			Bucket:            aws.String(to.Bucket),
+			Key:               aws.String(to.Path),
+			UploadId:          uploadID,
+			PartNumber:        aws.Int64(partNumber),
+			CopySource:        aws.String(to.EscapedPath()),
+			CopySourceIfMatch: aws.String(strconv.Quote(prefix.Etag)),
+			CopySourceRange:   aws.String(fmt.Sprintf("bytes=%d-%d", offset, end-1)),
+			RequestPayer:      s.RequestPayer(),
+		})
+		if err != nil {
+			return nil, err
 		}
 
-		msg := log.DebugMessage{Err: fmt.Sprintf("Retrying to upload %v upon error: %q", to, err.Error())}
-		log.Debug(msg)
+		completed = append(completed, &s3.CompletedPart{
+			ETag:       output.CopyPartResult.ETag,
+			PartNumber: aws.Int64(partNumber),
+		})
+	}
 
-		_, err = s.uploader.UploadWithContext(ctx, input, uploaderOpts...)
+	for offset := prefix.Size; offset < size; offset += partSize {
+		length := partSize
+		if offset+length > size {
+			length = size - offset
+		}
+		partNumber++
+
+		output, err := s.api.UploadPartWithContext(ctx, &s3.UploadPartInput{
+			Bucket:        aws.String(to.Bucket),
+			Key:           aws.String(to.Path),
+			UploadId:      uploadID,
+			PartNumber:    aws.Int64(partNumber),
+			Body:          io.NewSectionReader(r, offset, length),
+			ContentLength: aws.Int64(length),
+			RequestPayer:  s.RequestPayer(),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		completed = append(completed, &s3.CompletedPart{
+			ETag:       output.ETag,
+			PartNumber: aws.Int64(partNumber),
+		})
 	}
 
-	if errHasCode(err, s3.ErrCodeNoSuchUpload) && s.noSuchUploadRetryCount > 0 {
-		err = awserr.New(s3.ErrCodeNoSuchUpload, fmt.Sprintf(
-			"RetryOnNoSuchUpload: %v attempts to retry resulted in %v", attempts,
-			s3.ErrCodeNoSuchUpload), err)
+	return completed, nil
+}
+
+func md5Sum(r io.Reader) ([]byte, error) {
+	h := md5.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return nil, err
 	}
-	return err
+	return h.Sum(nil), nil
 }
 
 // chunk is an object identifier container which is used on MultiDelete
 // operations. Since DeleteObjects API allows deleting objects up to 1000,
-// splitting keys into multiple chunks is required.
+// splitting keys into multiple chunks is required. The chunks can be smaller
+// if the delete batch size is set.
 type chunk struct {
 	Bucket string
 	Keys   []*s3.ObjectIdentifier
@@ -793,7 +1850,10 @@ type chunk struct {
 func (s *S3) calculateChunks(ch <-chan *url.URL) <-chan chunk {
 	chunkch := make(chan chunk)
 
-	chunkSize := deleteObjectsMax
+	chunkSize := DeleteObjectsMax
+	if s.deleteBatchSize > 0 && s.deleteBatchSize < DeleteObjectsMax {
+		chunkSize = s.deleteBatchSize
+	}
 	// delete each object individually if using gcs.
 	if IsGoogleEndpoint(s.endpointURL) {
 		chunkSize = 1
@@ -919,7 +1979,7 @@ func (s *S3) doDelete(ctx context.Context, chunk chunk, resultch chan *Object) {
 // MultiDelete is a asynchronous removal operation for multiple objects.
 // It reads given url channel, creates multiple chunks and run these
 // chunks in parallel. Each chunk may have at most 1000 objects since DeleteObjects
-// API has a limitation.
+// API has a limitation, or the delete batch size if it is smaller.
 // See: https://docs.aws.amazon.com/AmazonS3/latest/API/API_DeleteObjects.html.
 func (s *S3) MultiDelete(ctx context.Context, urlch <-chan *url.URL) <-chan *Object {
 	resultch := make(chan *Object)
@@ -972,6 +2032,67 @@ func (s *S3) ListBuckets(ctx context.Context, prefix string) ([]Bucket, error) {
 	return buckets, nil
 }
 
+// Region returns the region that the requests of the client are sent to.
+func (s *S3) Region() string {
+	return s.region
+}
+
+// ResolveCredentials retrieves the credentials of the client and returns the
+// name of the provider that resolved them. Anonymous clients have no provider.
+func (s *S3) ResolveCredentials(ctx context.Context) (string, error) {
+	if s.credentials == nil || s.credentials == credentials.AnonymousCredentials {
+		return "", nil
+	}
+
+	value, err := s.credentials.GetWithContext(ctx)
+	if err != nil {
+		return "", err
+	}
+	return value.ProviderName, nil
+}
+
+// BucketRegion returns the region of the bucket with the given name.
+func (s *S3) BucketRegion(ctx context.Context, name string) (string, error) {
+	return s3manager.GetBucketRegionWithClient(ctx, s.api, name)
+}
+
+// HeadBucket checks whether the bucket with the given name exists and is
+// accessible.
+func (s *S3) HeadBucket(ctx context.Context, name string) error {
+	_, err := s.api.HeadBucketWithContext(ctx, &s3.HeadBucketInput{
+		Bucket: aws.String(name),
+	})
+	return err
+}
+
+// HasObjectWithPrefix reports whether the bucket has any object whose key
+// starts with the given prefix. At most one key is listed.
+func (s *S3) HasObjectWithPrefix(ctx context.Context, bucket, prefix string) (bool, error) {
+	if s.useListObjectsV1 {
+		output, err := s.api.ListObjectsWithContext(ctx, &s3.ListObjectsInput{
+			Bucket:       aws.String(bucket),
+			Prefix:       aws.String(prefix),
+			MaxKeys:      aws.Int64(1),
+			RequestPayer: s.RequestPayer(),
+		})
+		if err != nil {
+			return false, err
+		}
+		return len(output.Contents) > 0, nil
+	}
+
+	output, err := s.api.ListObjectsV2WithContext(ctx, &s3.ListObjectsV2Input{
+		Bucket:       aws.String(bucket),
+		Prefix:       aws.String(prefix),
+		MaxKeys:      aws.Int64(1),
+		RequestPayer: s.RequestPayer(),
+	})
+	if err != nil {
+		return false, err
+	}
+	return len(output.Contents) > 0, nil
+}
+
 // MakeBucket creates an S3 bucket with the given name.
 func (s *S3) MakeBucket(ctx context.Context, name string) error {
 	if s.dryRun {
@@ -1024,11 +2145,166 @@ func (s *S3) GetBucketVersioning(ctx context.Context, bucket string) (string, er
 
 }
 
+// ObjectLock is the object lock configuration of a bucket.
+type ObjectLock struct {
+	Enabled bool `json:"enabled"`
+	// Mode, Days and Years are the default retention of the objects, if any.
+	Mode  string `json:"mode,omitempty"`
+	Days  int64  `json:"days,omitempty"`
+	Years int64  `json:"years,omitempty"`
+}
+
+// BucketEncryption is the default encryption configuration of a bucket.
+type BucketEncryption struct {
+	Algorithm        string `json:"algorithm,omitempty"`
+	KMSKeyID         string `json:"kms_key_id,omitempty"`
+	BucketKeyEnabled bool   `json:"bucket_key_enabled,omitempty"`
+}
+
+// PublicAccessBlock is the public access block configuration of a bucket.
+type PublicAccessBlock struct {
+	BlockPublicAcls       bool `json:"block_public_acls"`
+	IgnorePublicAcls      bool `json:"ignore_public_acls"`
+	BlockPublicPolicy     bool `json:"block_public_policy"`
+	RestrictPublicBuckets bool `json:"restrict_public_buckets"`
+}
+
+// GetObjectLockConfiguration returns the object lock configuration of the
+// bucket. Object lock is reported as disabled if the bucket has no object
+// lock configuration.
+func (s *S3) GetObjectLockConfiguration(ctx context.Context, bucket string) (ObjectLock, error) {
+	output, err := s.api.GetObjectLockConfigurationWithContext(ctx, &s3.GetObjectLockConfigurationInput{
+		Bucket: aws.String(bucket),
+	})
+	if err != nil {
+		if errHasCode(err, "ObjectLockConfigurationNotFoundError") {
+			return ObjectLock{}, nil
+		}
+		return ObjectLock{}, err
+	}
+
+	config := output.ObjectLockConfiguration
+	if config == nil {
+		return ObjectLock{}, nil
+	}
+
+	lock := ObjectLock{
+		Enabled: aws.StringValue(config.ObjectLockEnabled) == s3.ObjectLockEnabledEnabled,
+	}
+	if config.Rule != nil && config.Rule.DefaultRetention != nil {
+		retention := config.Rule.DefaultRetention
+		lock.Mode = aws.StringValue(retention.Mode)
+		lock.Days = aws.Int64Value(retention.Days)
+		lock.Years = aws.Int64Value(retention.Years)
+	}
+	return lock, nil
+}
+
+// GetBucketEncryption returns the default encryption configuration of the
+// bucket. The algorithm is empty if the bucket has no default encryption.
+func (s *S3) GetBucketEncryption(ctx context.Context, bucket string) (BucketEncryption, error) {
+	output, err := s.api.GetBucketEncryptionWithContext(ctx, &s3.GetBucketEncryptionInput{
+		Bucket: aws.String(bucket),
+	})
+	if err != nil {
+		if errHasCode(err, "ServerSideEncryptionConfigurationNotFoundError") {
+			return BucketEncryption{}, nil
+		}
+		return BucketEncryption{}, err
+	}
+
+	config := output.ServerSideEncryptionConfiguration
+	if config == nil {
+		return BucketEncryption{}, nil
+	}
+
+	for _, rule := range config.Rules {
+		if rule.ApplyServerSideEncryptionByDefault == nil {
+			continue
+		}
+		return BucketEncryption{
+			Algorithm:        aws.StringValue(rule.ApplyServerSideEncryptionByDefault.SSEAlgorithm),
+			KMSKeyID:         aws.StringValue(rule.ApplyServerSideEncryptionByDefault.KMSMasterKeyID),
+			BucketKeyEnabled: aws.BoolValue(rule.BucketKeyEnabled),
+		}, nil
+	}
+	return BucketEncryption{}, nil
+}
+
+// GetPublicAccessBlock returns the public access block configuration of the
+// bucket, or nil if the bucket has no public access block configuration.
+func (s *S3) GetPublicAccessBlock(ctx context.Context, bucket string) (*PublicAccessBlock, error) {
+	output, err := s.api.GetPublicAccessBlockWithContext(ctx, &s3.GetPublicAccessBlockInput{
+		Bucket: aws.String(bucket),
+	})
+	if err != nil {
+		if errHasCode(err, "NoSuchPublicAccessBlockConfiguration") {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	config := output.PublicAccessBlockConfiguration
+	if config == nil {
+		return nil, nil
+	}
+
+	return &PublicAccessBlock{
+		BlockPublicAcls:       aws.BoolValue(config.BlockPublicAcls),
+		IgnorePublicAcls:      aws.BoolValue(config.IgnorePublicAcls),
+		BlockPublicPolicy:     aws.BoolValue(config.BlockPublicPolicy),
+		RestrictPublicBuckets: aws.BoolValue(config.RestrictPublicBuckets),
+	}, nil
+}
+
+// BucketLifecycleRuleCount returns the number of lifecycle rules of the
+// bucket.
+func (s *S3) BucketLifecycleRuleCount(ctx context.Context, bucket string) (int, error) {
+	output, err := s.api.GetBucketLifecycleConfigurationWithContext(ctx, &s3.GetBucketLifecycleConfigurationInput{
+		Bucket: aws.String(bucket),
+	})
+	if err != nil {
+		if errHasCode(err, "NoSuchLifecycleConfiguration") {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return len(output.Rules), nil
+}
+
+// BucketCORSRuleCount returns the number of CORS rules of the bucket.
+func (s *S3) BucketCORSRuleCount(ctx context.Context, bucket string) (int, error) {
+	output, err := s.api.GetBucketCorsWithContext(ctx, &s3.GetBucketCorsInput{
+		Bucket: aws.String(bucket),
+	})
+	if err != nil {
+		if errHasCode(err, "NoSuchCORSConfiguration") {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return len(output.CORSRules), nil
+}
+
+// HasBucketPolicy reports whether the bucket has a bucket policy.
+func (s *S3) HasBucketPolicy(ctx context.Context, bucket string) (bool, error) {
+	output, err := s.api.GetBucketPolicyWithContext(ctx, &s3.GetBucketPolicyInput{
+		Bucket: aws.String(bucket),
+	})
+	if err != nil {
+		if errHasCode(err, "NoSuchBucketPolicy") {
+			return false, nil
+		}
+		return false, err
+	}
+	return aws.StringValue(output.Policy) != "", nil
+}
+
 type sdkLogger struct{}
 
 func (l sdkLogger) Log(args ...interface{}) {
 	msg := log.TraceMessage{
-		Message: fmt.Sprint(args...),
+		Message: redactCredentials(fmt.Sprint(args...)),
 	}
 	log.Trace(msg)
 }
@@ -1055,10 +2331,10 @@ func (sc *SessionCache) newSession(ctx context.Context, opts Options) (*session.
 	if opts.NoSignRequest {
 		// do not sign requests when making service API calls
 		awsCfg = awsCfg.WithCredentials(credentials.AnonymousCredentials)
-	} else if opts.CredentialFile != "" || opts.Profile != "" {
-		awsCfg = awsCfg.WithCredentials(
-			credentials.NewSharedCredentials(opts.CredentialFile, opts.Profile),
-		)
+	} else if opts.CredentialsCommand != "" {
+		awsCfg = awsCfg.WithCredentials(processcreds.NewCredentials(opts.CredentialsCommand))
+	} else if opts.EnvPrefix != "" {
+		awsCfg = awsCfg.WithCredentials(newEnvPrefixCredentials(opts.EnvPrefix))
 	}
 
 	endpointURL, err := parseEndpoint(opts.Endpoint)
@@ -1066,9 +2342,7 @@ func (sc *SessionCache) newSession(ctx context.Context, opts Options) (*session.
 		return nil, err
 	}
 
-	// use virtual-host-style if the endpoint is known to support it,
-	// otherwise use the path-style approach.
-	isVirtualHostStyle := isVirtualHostStyle(endpointURL)
+	forcePathStyle := usePathStyle(endpointURL, opts.bucket, opts.AddressingStyle)
 
 	useAccelerate := supportsTransferAcceleration(endpointURL)
 	// AWS SDK handles transfer acceleration automatically. Setting the
@@ -1079,12 +2353,15 @@ func (sc *SessionCache) newSession(ctx context.Context, opts Options) (*session.
 	}
 
 	var httpClient *http.Client
-	if opts.NoVerifySSL {
+	switch {
+	case opts.Dialer != nil:
+		httpClient = opts.Dialer.client(opts.NoVerifySSL)
+	case opts.NoVerifySSL:
 		httpClient = insecureHTTPClient
 	}
 	awsCfg = awsCfg.
 		WithEndpoint(endpointURL.String()).
-		WithS3ForcePathStyle(!isVirtualHostStyle).
+		WithS3ForcePathStyle(forcePathStyle).
 		WithS3UseAccelerate(useAccelerate).
 		WithHTTPClient(httpClient).
 		// TODO WithLowerCaseHeaderMaps and WithDisableRestProtocolURICleaning options
@@ -1113,26 +2390,60 @@ func (sc *SessionCache) newSession(ctx context.Context, opts Options) (*session.
 		}
 	}
 
-	sess, err := session.NewSessionWithOptions(
-		session.Options{
-			Config:            *awsCfg,
-			SharedConfigState: useSharedConfig,
-		},
-	)
+	sessOpts := session.Options{
+		Config:            *awsCfg,
+		SharedConfigState: useSharedConfig,
+	}
+	if !opts.NoSignRequest && opts.CredentialsCommand == "" && opts.EnvPrefix == "" &&
+		(opts.CredentialFile != "" || opts.Profile != "") {
+		profile, sharedConfigFiles := sharedConfigProfile(opts, useSharedConfig)
+		if hasSharedConfigProfile(sharedConfigFiles, profile) {
+			// the credentials of the profile are resolved by the SDK, so
+			// that the profiles with credential_process, role or SSO
+			// settings work as well as the ones with static keys.
+			sessOpts.Profile, sessOpts.SharedConfigFiles = profile, sharedConfigFiles
+		} else {
+			// the SDK falls back to the credentials of the instance if the
+			// profile doesn't exist, fail the requests instead.
+			sessOpts.Config.Credentials = credentials.NewSharedCredentials(opts.CredentialFile, opts.Profile)
+		}
+	}
+
+	sess, err := session.NewSessionWithOptions(sessOpts)
 	if err != nil {
 		return nil, err
 	}
 
+	if !opts.NoSignRequest && sess.Config.Credentials != nil {
+		sess.Config.Credentials = newRefreshingCredentials(sess.Config.Credentials)
+	}
+	sess.Handlers.AfterRetry.PushBack(countRetry)
+	opts.Hooks.register(&sess.Handlers)
+
+	// the transport is wrapped once the session is created, since the SDK
+	// can only load a custom CA bundle into an *http.Transport.
+	if opts.MaxRequestsPerHost > 0 {
+		client := http.Client{}
+		if sess.Config.HTTPClient != nil {
+			client = *sess.Config.HTTPClient
+		}
+		client.Transport = newHostLimitedTransport(client.Transport, opts.MaxRequestsPerHost)
+		sess.Config.HTTPClient = &client
+	}
+
 	// get region of the bucket and create session accordingly. if the region
 	// is not provided, it means we want region-independent session
 	// for operations such as listing buckets, making a new bucket etc.
 	// only get bucket region when it is not specified.
 	if opts.region != "" {
 		sess.Config.Region = aws.String(opts.region)
+	} else if region, ok := globalRegionCache.get(opts); ok {
+		sess.Config.Region = aws.String(region)
 	} else {
 		if err := setSessionRegion(ctx, sess, opts.bucket); err != nil {
 			return nil, err
 		}
+		globalRegionCache.set(opts, aws.StringValue(sess.Config.Region))
 	}
 
 	sc.sessions[opts] = sess
@@ -1140,12 +2451,152 @@ func (sc *SessionCache) newSession(ctx context.Context, opts Options) (*session.
 	return sess, nil
 }
 
+// sharedConfigProfile returns the profile given by the flags and the shared
+// config files to load it from.
+func sharedConfigProfile(opts Options, sharedConfig session.SharedConfigState) (string, []string) {
+	profile := opts.Profile
+	if profile == "" {
+		profile = os.Getenv("AWS_PROFILE")
+	}
+	if profile == "" {
+		profile = "default"
+	}
+
+	credentialFile := opts.CredentialFile
+	if credentialFile == "" {
+		credentialFile = os.Getenv("AWS_SHARED_CREDENTIALS_FILE")
+	}
+	if credentialFile == "" {
+		credentialFile = defaults.SharedCredentialsFilename()
+	}
+
+	// the later files override the earlier ones.
+	files := []string{credentialFile}
+	if sharedConfig != session.SharedConfigDisable {
+		configFile := os.Getenv("AWS_CONFIG_FILE")
+		if configFile == "" {
+			configFile = defaults.SharedConfigFilename()
+		}
+		files = []string{configFile, credentialFile}
+	}
+	return profile, files
+}
+
+// hasSharedConfigProfile reports whether any of the shared config files has a
+// section for the given profile.
+func hasSharedConfigProfile(files []string, profile string) bool {
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		for _, line := range strings.Split(string(content), "\n") {
+			line = strings.TrimSpace(line)
+			if !strings.HasPrefix(line, "[") || !strings.HasSuffix(line, "]") {
+				continue
+			}
+			section := strings.TrimSpace(line[1 : len(line)-1])
+			if section == profile || section == "profile "+profile {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func (sc *SessionCache) clear() {
 	sc.Lock()
 	defer sc.Unlock()
 	sc.sessions = map[Options]*session.Session{}
 }
 
+// regionCacheKey identifies a bucket. The profile and the credentials file are
+// part of the key since a profile may pin the region of its sessions.
+type regionCacheKey struct {
+	endpoint       string
+	profile        string
+	credentialFile string
+	bucket         string
+}
+
+// regionCache holds the resolved regions of buckets, so that sessions created
+// with different options for the same bucket don't fetch its region again.
+type regionCache struct {
+	sync.Mutex
+	regions map[regionCacheKey]string
+}
+
+func newRegionCacheKey(opts Options) (regionCacheKey, bool) {
+	if !opts.EndpointResolverCache || opts.bucket == "" {
+		return regionCacheKey{}, false
+	}
+	return regionCacheKey{
+		endpoint:       opts.Endpoint,
+		profile:        opts.Profile,
+		credentialFile: opts.CredentialFile,
+		bucket:         opts.bucket,
+	}, true
+}
+
+func (rc *regionCache) get(opts Options) (string, bool) {
+	key, ok := newRegionCacheKey(opts)
+	if !ok {
+		return "", false
+	}
+
+	rc.Lock()
+	defer rc.Unlock()
+	region, ok := rc.regions[key]
+	return region, ok
+}
+
+func (rc *regionCache) set(opts Options, region string) {
+	key, ok := newRegionCacheKey(opts)
+	if !ok || region == "" {
+		return
+	}
+
+	rc.Lock()
+	defer rc.Unlock()
+	rc.regions[key] = region
+}
+
+func (rc *regionCache) clear() {
+	rc.Lock()
+	defer rc.Unlock()
+	rc.regions = map[regionCacheKey]string{}
+}
+
+// clientCache holds S3 clients according to their options, so that workers
+// reuse the clients instead of building new ones for each object.
+type clientCache struct {
+	sync.Mutex
+	clients map[Options]*S3
+}
+
+func (cc *clientCache) newClient(ctx context.Context, opts Options) (*S3, error) {
+	cc.Lock()
+	defer cc.Unlock()
+
+	if client, ok := cc.clients[opts]; ok {
+		return client, nil
+	}
+
+	client, err := newS3Storage(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	cc.clients[opts] = client
+	return client, nil
+}
+
+func (cc *clientCache) clear() {
+	cc.Lock()
+	defer cc.Unlock()
+	cc.clients = map[Options]*S3{}
+}
+
 func setSessionRegion(ctx context.Context, sess *session.Session, bucket string) error {
 	region := aws.StringValue(sess.Config.Region)
 
@@ -1235,6 +2686,29 @@ func IsGoogleEndpoint(endpoint urlpkg.URL) bool {
 	return endpoint.Hostname() == gcsEndpoint
 }
 
+// usePathStyle reports whether the requests to the bucket should use the
+// path-style addressing with respect to the given addressing style. In auto
+// mode, virtual-host-style is used if the endpoint is known to support it,
+// except for the bucket names with dots over HTTPS, since the wildcard
+// certificates of the virtual hosts don't match them. Transfer acceleration
+// requires virtual-host-style.
+func usePathStyle(endpoint urlpkg.URL, bucket, style string) bool {
+	switch style {
+	case AddressingStylePath:
+		return true
+	case AddressingStyleVirtual:
+		return false
+	}
+
+	if !isVirtualHostStyle(endpoint) {
+		return true
+	}
+	if supportsTransferAcceleration(endpoint) {
+		return false
+	}
+	return strings.Contains(bucket, ".") && endpoint.Scheme != "http"
+}
+
 // isVirtualHostStyle reports whether the given endpoint supports S3 virtual
 // host style bucket name resolving. If a custom S3 API compatible endpoint is
 // given, resolve the bucketname from the URL path.
@@ -1263,6 +2737,59 @@ func errHasCode(err error, code string) bool {
 
 }
 
+// ErrorCode returns the error code of the S3 API error the given error is
+// caused by, e.g. "AccessDenied", or the code of the error which a multipart
+// upload failed with. It returns an empty string if the error is not an S3 API
+// error.
+func ErrorCode(err error) string {
+	var multiUploadErr s3manager.MultiUploadFailure
+	if errors.As(err, &multiUploadErr) {
+		if code := ErrorCode(multiUploadErr.OrigErr()); code != "" {
+			return code
+		}
+	}
+
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) {
+		return awsErr.Code()
+	}
+	return ""
+}
+
+// ResponseStatusCode returns the HTTP status code of the response which the
+// given error is caused by. It returns 0 if no response is received, e.g. the
+// endpoint is not reachable.
+func ResponseStatusCode(err error) int {
+	var reqErr awserr.RequestFailure
+	if errors.As(err, &reqErr) {
+		return reqErr.StatusCode()
+	}
+	return 0
+}
+
+// IsNoSuchBucketError reports whether given error is caused by a nonexistent
+// bucket. HEAD requests have no response body, so their error code is derived
+// from the status code.
+func IsNoSuchBucketError(err error) bool {
+	return errHasCode(err, s3.ErrCodeNoSuchBucket) || errHasCode(err, "NotFound")
+}
+
+// IsAccessDeniedError reports whether given error is caused by a lack of
+// permissions.
+func IsAccessDeniedError(err error) bool {
+	return errHasCode(err, "AccessDenied") || errHasCode(err, "Forbidden")
+}
+
+// IsVersionNotFoundError reports whether the given error is caused by a
+// version of an object which doesn't exist, e.g. because it is deleted.
+func IsVersionNotFoundError(err error) bool {
+	var objNotFound *ErrGivenObjectNotFound
+	if errors.As(err, &objNotFound) {
+		return true
+	}
+	return errHasCode(err, "NoSuchVersion") || errHasCode(err, "NoSuchKey") || errHasCode(err, "NotFound")
+}
+
 // IsCancelationError reports whether given error is a storage related
 // cancelation error.
 func IsCancelationError(err error) bool {
@@ -3,6 +3,8 @@ package storage
 import (
 	"bytes"
 	"context"
+	"crypto/md5"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
@@ -12,7 +14,9 @@ import (
 	urlpkg "net/url"
 	"os"
 	"reflect"
+	"sort"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -44,6 +48,8 @@ func TestNewSessionPathStyle(t *testing.T) {
 	testcases := []struct {
 		name            string
 		endpoint        urlpkg.URL
+		bucket          string
+		style           string
 		expectPathStyle bool
 	}{
 		{
@@ -76,13 +82,57 @@ func TestNewSessionPathStyle(t *testing.T) {
 			endpoint:        urlpkg.URL{Scheme: "https", Host: "example.com"},
 			expectPathStyle: true,
 		},
+		{
+			name:            "expect_path_style_for_bucket_with_dots",
+			endpoint:        urlpkg.URL{},
+			bucket:          "bucket.with.dots",
+			expectPathStyle: true,
+		},
+		{
+			name:            "expect_path_style_for_bucket_with_dots_on_google_cloud_storage",
+			endpoint:        urlpkg.URL{Scheme: "https", Host: gcsEndpoint},
+			bucket:          "bucket.with.dots",
+			expectPathStyle: true,
+		},
+		{
+			name:            "expect_virtual_host_style_for_bucket_without_dots",
+			endpoint:        urlpkg.URL{},
+			bucket:          "bucket",
+			expectPathStyle: false,
+		},
+		{
+			name:            "expect_virtual_host_style_for_bucket_with_dots_when_forced",
+			endpoint:        urlpkg.URL{},
+			bucket:          "bucket.with.dots",
+			style:           AddressingStyleVirtual,
+			expectPathStyle: false,
+		},
+		{
+			name:            "expect_path_style_for_bucket_without_dots_when_forced",
+			endpoint:        urlpkg.URL{},
+			bucket:          "bucket",
+			style:           AddressingStylePath,
+			expectPathStyle: true,
+		},
+		{
+			name:            "expect_virtual_host_style_for_custom_endpoint_when_forced",
+			endpoint:        urlpkg.URL{Scheme: "https", Host: "example.com"},
+			style:           AddressingStyleVirtual,
+			expectPathStyle: false,
+		},
 	}
 
 	for _, tc := range testcases {
 		tc := tc
 		t.Run(tc.name, func(t *testing.T) {
 
-			opts := Options{Endpoint: tc.endpoint.String()}
+			opts := Options{
+				Endpoint:        tc.endpoint.String(),
+				AddressingStyle: tc.style,
+				bucket:          tc.bucket,
+				// avoid fetching the region of the bucket.
+				region: "us-east-1",
+			}
 			sess, err := globalSessionCache.newSession(context.Background(), opts)
 			if err != nil {
 				t.Fatal(err)
@@ -96,6 +146,52 @@ func TestNewSessionPathStyle(t *testing.T) {
 	}
 }
 
+func TestNewSessionPathStylePerBucket(t *testing.T) {
+	globalSessionCache.clear()
+
+	// the buckets of a single command, e.g. the source and destination of a
+	// sync, use their own addressing styles.
+	testcases := []struct {
+		bucket       string
+		expectedHost string
+		expectedPath string
+	}{
+		{
+			bucket:       "source.with.dots",
+			expectedHost: "s3.amazonaws.com",
+			expectedPath: "/source.with.dots/key",
+		},
+		{
+			bucket:       "destination",
+			expectedHost: "destination.s3.amazonaws.com",
+			expectedPath: "/key",
+		},
+	}
+
+	for _, tc := range testcases {
+		opts := Options{bucket: tc.bucket, region: "us-east-1"}
+		sess, err := globalSessionCache.newSession(context.Background(), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req, _ := s3.New(sess).GetObjectRequest(&s3.GetObjectInput{
+			Bucket: aws.String(tc.bucket),
+			Key:    aws.String("key"),
+		})
+		if err := req.Build(); err != nil {
+			t.Fatal(err)
+		}
+
+		if req.HTTPRequest.URL.Host != tc.expectedHost {
+			t.Errorf("%v: expected host: %v, got: %v", tc.bucket, tc.expectedHost, req.HTTPRequest.URL.Host)
+		}
+		if req.HTTPRequest.URL.Path != tc.expectedPath {
+			t.Errorf("%v: expected path: %v, got: %v", tc.bucket, tc.expectedPath, req.HTTPRequest.URL.Path)
+		}
+	}
+}
+
 func TestNewSessionWithRegionSetViaEnv(t *testing.T) {
 	globalSessionCache.clear()
 
@@ -392,6 +488,169 @@ func TestS3ListContextCancelled(t *testing.T) {
 	}
 }
 
+func TestS3ListBackPressure(t *testing.T) {
+	const pageSize = 10
+
+	u, err := url.New("s3://bucket/*")
+	assert.NilError(t, err)
+
+	mockAPI := s3.New(unit.Session)
+	mockS3 := &S3{api: mockAPI}
+
+	var calls int64
+	mockAPI.Handlers.Send.Clear()
+	mockAPI.Handlers.Unmarshal.Clear()
+	mockAPI.Handlers.UnmarshalMeta.Clear()
+	mockAPI.Handlers.ValidateResponse.Clear()
+	mockAPI.Handlers.Send.PushBack(func(r *request.Request) {
+		atomic.AddInt64(&calls, 1)
+	})
+	mockAPI.Handlers.Unmarshal.PushBack(func(r *request.Request) {
+		// the listing never ends.
+		output := &s3.ListObjectsV2Output{
+			IsTruncated:           aws.Bool(true),
+			NextContinuationToken: aws.String("token"),
+		}
+		for i := 0; i < pageSize; i++ {
+			output.Contents = append(output.Contents, &s3.Object{
+				Key:          aws.String(fmt.Sprintf("key-%d", i)),
+				LastModified: aws.Time(time.Now().Add(-time.Hour)),
+			})
+		}
+		r.Data = output
+	})
+
+	// waitCalls waits for the listing to settle and returns the number of
+	// the pages fetched.
+	waitCalls := func() int64 {
+		time.Sleep(100 * time.Millisecond)
+		return atomic.LoadInt64(&calls)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	objCh := mockS3.List(ctx, u, false)
+
+	// the next page is fetched while the first one is received, but not the
+	// one after it.
+	<-objCh
+	assert.Equal(t, waitCalls(), int64(2))
+
+	for i := 1; i < pageSize+1; i++ {
+		<-objCh
+	}
+	assert.Equal(t, waitCalls(), int64(3))
+
+	// no more pages are fetched once the listing is canceled, and the
+	// channel is closed with the cancellation error.
+	cancel()
+
+	var last *Object
+	for obj := range objCh {
+		last = obj
+	}
+	assert.Assert(t, last != nil)
+	assert.Assert(t, errors.Is(last.Err, context.Canceled))
+	assert.Assert(t, waitCalls() <= 3)
+}
+
+func TestS3ListMultipartUploads(t *testing.T) {
+	u, err := url.New("s3://bucket/dir/*.bin")
+	assert.NilError(t, err)
+
+	mockAPI := s3.New(unit.Session)
+	mockS3 := &S3{api: mockAPI}
+
+	mockAPI.Handlers.Send.Clear()
+	mockAPI.Handlers.Unmarshal.Clear()
+	mockAPI.Handlers.UnmarshalMeta.Clear()
+	mockAPI.Handlers.ValidateResponse.Clear()
+	mockAPI.Handlers.Unmarshal.PushBack(func(r *request.Request) {
+		switch input := r.Params.(type) {
+		case *s3.ListMultipartUploadsInput:
+			assert.Equal(t, aws.StringValue(input.Prefix), "dir/")
+			r.Data = &s3.ListMultipartUploadsOutput{
+				Uploads: []*s3.MultipartUpload{
+					{Key: aws.String("dir/a.bin"), UploadId: aws.String("a")},
+					{Key: aws.String("dir/b.txt"), UploadId: aws.String("b")},
+					{Key: aws.String("dir/c.bin"), UploadId: aws.String("c")},
+				},
+			}
+		case *s3.ListPartsInput:
+			// the upload "c" is completed after it's listed.
+			if aws.StringValue(input.UploadId) == "c" {
+				r.Error = awserr.New(s3.ErrCodeNoSuchUpload, "not found", nil)
+				return
+			}
+			r.Data = &s3.ListPartsOutput{
+				Parts: []*s3.Part{
+					{PartNumber: aws.Int64(1), Size: aws.Int64(100)},
+					{PartNumber: aws.Int64(2), Size: aws.Int64(50)},
+				},
+			}
+		}
+	})
+
+	var uploads []*MultipartUpload
+	for upload := range mockS3.ListMultipartUploads(context.Background(), u) {
+		assert.NilError(t, upload.Err)
+		uploads = append(uploads, upload)
+	}
+	assert.Equal(t, len(uploads), 2)
+	assert.Equal(t, uploads[0].URL.String(), "s3://bucket/dir/a.bin")
+	assert.Equal(t, uploads[1].URL.String(), "s3://bucket/dir/c.bin")
+
+	count, size, err := mockS3.UploadedParts(context.Background(), uploads[0])
+	assert.NilError(t, err)
+	assert.Equal(t, count, int64(2))
+	assert.Equal(t, size, int64(150))
+
+	count, size, err = mockS3.UploadedParts(context.Background(), uploads[1])
+	assert.NilError(t, err)
+	assert.Equal(t, count, int64(0))
+	assert.Equal(t, size, int64(0))
+}
+
+func TestS3HasObjectWithPrefix(t *testing.T) {
+	for _, useListObjectsV1 := range []bool{false, true} {
+		useListObjectsV1 := useListObjectsV1
+		t.Run(fmt.Sprintf("useListObjectsV1=%v", useListObjectsV1), func(t *testing.T) {
+			mockAPI := s3.New(unit.Session)
+			mockS3 := &S3{api: mockAPI, useListObjectsV1: useListObjectsV1}
+
+			mockAPI.Handlers.Send.Clear()
+			mockAPI.Handlers.Unmarshal.Clear()
+			mockAPI.Handlers.UnmarshalMeta.Clear()
+			mockAPI.Handlers.ValidateResponse.Clear()
+			mockAPI.Handlers.Unmarshal.PushBack(func(r *request.Request) {
+				switch input := r.Params.(type) {
+				case *s3.ListObjectsV2Input:
+					assert.Assert(t, !useListObjectsV1)
+					assert.Equal(t, aws.Int64Value(input.MaxKeys), int64(1))
+					if aws.StringValue(input.Prefix) == "logs/2024-" {
+						r.Data.(*s3.ListObjectsV2Output).Contents = []*s3.Object{{Key: aws.String("logs/2024-01-01.gz")}}
+					}
+				case *s3.ListObjectsInput:
+					assert.Assert(t, useListObjectsV1)
+					assert.Equal(t, aws.Int64Value(input.MaxKeys), int64(1))
+					if aws.StringValue(input.Prefix) == "logs/2024-" {
+						r.Data.(*s3.ListObjectsOutput).Contents = []*s3.Object{{Key: aws.String("logs/2024-01-01.gz")}}
+					}
+				}
+			})
+
+			exists, err := mockS3.HasObjectWithPrefix(context.Background(), "bucket", "logs/2024-")
+			assert.NilError(t, err)
+			assert.Assert(t, exists)
+
+			exists, err = mockS3.HasObjectWithPrefix(context.Background(), "bucket", "logs/2025-")
+			assert.NilError(t, err)
+			assert.Assert(t, !exists)
+		})
+	}
+}
+
 func TestS3Retry(t *testing.T) {
 	log.Init("debug", false)
 
@@ -575,6 +834,69 @@ func TestS3Retry(t *testing.T) {
 	}
 }
 
+func TestS3RetryCount(t *testing.T) {
+	log.Init("debug", false)
+
+	testcases := []struct {
+		name     string
+		err      error
+		expected int64
+	}{
+		{
+			name:     "retryable error",
+			err:      awserr.New("InternalError", "internal error", nil),
+			expected: 2,
+		},
+		{
+			name:     "non-retryable error",
+			err:      awserr.New("ExpiredToken", "expired token", nil),
+			expected: 0,
+		},
+		{
+			name:     "no error",
+			expected: 0,
+		},
+	}
+
+	url, err := url.New("s3://bucket/key")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			sess := unit.Session.Copy()
+			sess.Config.Retryer = newCustomRetryer(2)
+			sess.Handlers.AfterRetry.PushBack(countRetry)
+
+			mockAPI := s3.New(sess)
+			mockS3 := &S3{
+				api: mockAPI,
+			}
+
+			mockAPI.Handlers.Send.Clear()
+			mockAPI.Handlers.Unmarshal.Clear()
+			mockAPI.Handlers.UnmarshalMeta.Clear()
+			mockAPI.Handlers.ValidateResponse.Clear()
+			mockAPI.Handlers.Send.PushBack(func(r *request.Request) {
+				r.Error = tc.err
+				r.HTTPResponse = &http.Response{}
+			})
+
+			ctx := WithRetryCounter(context.Background())
+			_, _ = mockS3.Stat(ctx, url)
+
+			if got := RetryCount(ctx); got != tc.expected {
+				t.Errorf("expected %v retries, got %v", tc.expected, got)
+			}
+			if got := RetryCount(context.Background()); got != 0 {
+				t.Errorf("expected no retries without counter, got %v", got)
+			}
+		})
+	}
+}
+
 func TestS3RetryOnNoSuchUpload(t *testing.T) {
 	log.Init("debug", false)
 
@@ -849,6 +1171,58 @@ func TestS3PutEncryptionRequest(t *testing.T) {
 	}
 }
 
+func TestS3DeleteBatchSize(t *testing.T) {
+	testcases := []struct {
+		name            string
+		deleteBatchSize int
+		objects         int
+		expected        []int
+	}{
+		{
+			name:     "default",
+			objects:  1500,
+			expected: []int{1000, 500},
+		},
+		{
+			name:            "smaller batches",
+			deleteBatchSize: 2,
+			objects:         5,
+			expected:        []int{2, 2, 1},
+		},
+		{
+			name:            "capped",
+			deleteBatchSize: 5000,
+			objects:         1001,
+			expected:        []int{1000, 1},
+		},
+	}
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			mockS3 := &S3{deleteBatchSize: tc.deleteBatchSize}
+
+			urlch := make(chan *url.URL)
+			go func() {
+				defer close(urlch)
+				for i := 0; i < tc.objects; i++ {
+					u, err := url.New(fmt.Sprintf("s3://bucket/key%d", i))
+					if err != nil {
+						t.Error(err)
+						return
+					}
+					urlch <- u
+				}
+			}()
+
+			var batches []int
+			for chunk := range mockS3.calculateChunks(urlch) {
+				batches = append(batches, len(chunk.Keys))
+			}
+			assert.DeepEqual(t, tc.expected, batches)
+		})
+	}
+}
+
 func TestS3listObjectsV2(t *testing.T) {
 	const (
 		numObjectsToReturn = 10100
@@ -929,56 +1303,578 @@ func TestS3listObjectsV2(t *testing.T) {
 	assert.Equal(t, len(mapReturnObjNameToModtime), 0)
 }
 
-func TestSessionCreateAndCachingWithDifferentBuckets(t *testing.T) {
-	log.Init("error", false)
+func TestS3listObjectsV2FetchOwner(t *testing.T) {
+	u, err := url.New("s3://bucket/key")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
 	testcases := []struct {
-		bucket         string
-		alreadyCreated bool // sessions should not be created again if they already have been created before
+		name       string
+		fetchOwner bool
+		expected   *Owner
 	}{
-		{bucket: "bucket"},
-		{bucket: "bucket", alreadyCreated: true},
-		{bucket: "test-bucket"},
+		{
+			name:       "fetch owner",
+			fetchOwner: true,
+			expected:   &Owner{ID: "owner-id", DisplayName: "owner-name"},
+		},
+		{
+			name:       "do not fetch owner",
+			fetchOwner: false,
+			expected:   nil,
+		},
 	}
 
-	sess := map[string]*session.Session{}
-
 	for _, tc := range testcases {
-		awsSess, err := globalSessionCache.newSession(context.Background(), Options{
-			bucket: tc.bucket,
-		})
-		if err != nil {
-			t.Error(err)
-		}
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			mockAPI := s3.New(unit.Session)
 
-		if tc.alreadyCreated {
-			_, ok := sess[tc.bucket]
-			assert.Check(t, ok, "session should not have been created again")
-		} else {
-			sess[tc.bucket] = awsSess
-		}
+			mockAPI.Handlers.Unmarshal.Clear()
+			mockAPI.Handlers.UnmarshalMeta.Clear()
+			mockAPI.Handlers.UnmarshalError.Clear()
+			mockAPI.Handlers.Send.Clear()
+
+			var gotFetchOwner bool
+			mockAPI.Handlers.Send.PushBack(func(r *request.Request) {
+				gotFetchOwner = aws.BoolValue(r.Params.(*s3.ListObjectsV2Input).FetchOwner)
+
+				r.HTTPResponse = &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       io.NopCloser(strings.NewReader("")),
+				}
+
+				r.Data = &s3.ListObjectsV2Output{
+					Contents: []*s3.Object{
+						{
+							Key:          aws.String("key"),
+							LastModified: aws.Time(time.Now().Add(-time.Minute)),
+							Owner: &s3.Owner{
+								ID:          aws.String("owner-id"),
+								DisplayName: aws.String("owner-name"),
+							},
+						},
+					},
+				}
+			})
+
+			mockS3 := &S3{
+				api:        mockAPI,
+				fetchOwner: tc.fetchOwner,
+			}
+
+			var objects []*Object
+			for obj := range mockS3.listObjectsV2(context.Background(), u) {
+				objects = append(objects, obj)
+			}
+
+			assert.Equal(t, gotFetchOwner, tc.fetchOwner)
+			assert.Equal(t, len(objects), 1)
+			assert.DeepEqual(t, objects[0].Owner, tc.expected)
+		})
 	}
 }
 
-func TestSessionRegionDetection(t *testing.T) {
-	bucketRegion := "sa-east-1"
+func TestS3HasPrefix(t *testing.T) {
+	u, err := url.New("s3://bucket/key")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	content := []byte("old content|new content")
+	prefix := content[:11]
+
+	md5Hex := func(b []byte) string {
+		sum := md5.Sum(b)
+		return hex.EncodeToString(sum[:])
+	}
+
+	part1, part2 := md5.Sum(prefix[:4]), md5.Sum(prefix[4:])
+	multipartEtag := fmt.Sprintf("%s-2", md5Hex(append(part1[:], part2[:]...)))
 
 	testcases := []struct {
-		name           string
-		bucket         string
-		optsRegion     string
-		envRegion      string
-		expectedRegion string
+		name      string
+		etag      string
+		partSizes []int64
+		expected  bool
 	}{
 		{
-			name:           "RegionWithSourceRegionParameter",
-			bucket:         "bucket",
-			optsRegion:     "ap-east-1",
-			envRegion:      "ca-central-1",
-			expectedRegion: "ap-east-1",
+			name:     "single part prefix",
+			etag:     md5Hex(prefix),
+			expected: true,
 		},
 		{
-			name:           "RegionWithEnvironmentVariable",
-			bucket:         "bucket",
+			name:     "changed single part prefix",
+			etag:     md5Hex([]byte("old-content")),
+			expected: false,
+		},
+		{
+			name:      "multipart prefix",
+			etag:      multipartEtag,
+			partSizes: []int64{4, 7},
+			expected:  true,
+		},
+		{
+			name:      "multipart prefix with different part sizes",
+			etag:      multipartEtag,
+			partSizes: []int64{5, 6},
+			expected:  false,
+		},
+		{
+			name:     "etag is not an md5 digest",
+			etag:     "not-an-md5-digest",
+			expected: false,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			mockAPI := s3.New(unit.Session)
+
+			mockAPI.Handlers.Unmarshal.Clear()
+			mockAPI.Handlers.UnmarshalMeta.Clear()
+			mockAPI.Handlers.UnmarshalError.Clear()
+			mockAPI.Handlers.Send.Clear()
+
+			mockAPI.Handlers.Send.PushBack(func(r *request.Request) {
+				params := r.Params.(*s3.HeadObjectInput)
+				partNumber := aws.Int64Value(params.PartNumber)
+
+				r.HTTPResponse = &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       io.NopCloser(strings.NewReader("")),
+				}
+				*r.Data.(*s3.HeadObjectOutput) = s3.HeadObjectOutput{
+					ContentLength: aws.Int64(tc.partSizes[partNumber-1]),
+				}
+			})
+
+			mockS3 := &S3{api: mockAPI}
+
+			obj := &Object{URL: u, Etag: tc.etag, Size: int64(len(prefix))}
+			got, err := mockS3.HasPrefix(context.Background(), obj, bytes.NewReader(content))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			assert.Equal(t, got, tc.expected)
+		})
+	}
+}
+
+func TestS3Append(t *testing.T) {
+	log.Init("debug", false)
+
+	u, err := url.New("s3://bucket/key")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	prefixSize := int64(MinPartSize + 1)
+	content := make([]byte, prefixSize+100)
+	for i := range content {
+		content[i] = byte(i)
+	}
+
+	mockAPI := s3.New(unit.Session)
+
+	mockAPI.Handlers.Unmarshal.Clear()
+	mockAPI.Handlers.UnmarshalMeta.Clear()
+	mockAPI.Handlers.UnmarshalError.Clear()
+	mockAPI.Handlers.Send.Clear()
+
+	var (
+		copyRanges []string
+		uploaded   [][]byte
+		completed  []*s3.CompletedPart
+		aborted    bool
+	)
+	mockAPI.Handlers.Send.PushBack(func(r *request.Request) {
+		// copy and complete requests check the body of successful responses
+		// for errors.
+		r.Handlers.Unmarshal.Clear()
+		r.HTTPResponse = &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader("")),
+		}
+
+		switch params := r.Params.(type) {
+		case *s3.CreateMultipartUploadInput:
+			assert.Equal(t, aws.StringValue(params.ContentType), "text/plain")
+			*r.Data.(*s3.CreateMultipartUploadOutput) = s3.CreateMultipartUploadOutput{UploadId: aws.String("upload-id")}
+		case *s3.UploadPartCopyInput:
+			assert.Equal(t, aws.StringValue(params.CopySource), "bucket/key")
+			assert.Equal(t, aws.StringValue(params.CopySourceIfMatch), `"prefix-etag"`)
+			copyRanges = append(copyRanges, aws.StringValue(params.CopySourceRange))
+			*r.Data.(*s3.UploadPartCopyOutput) = s3.UploadPartCopyOutput{
+				CopyPartResult: &s3.CopyPartResult{ETag: aws.String("copy-etag")},
+			}
+		case *s3.UploadPartInput:
+			body, err := io.ReadAll(params.Body)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			uploaded = append(uploaded, body)
+			*r.Data.(*s3.UploadPartOutput) = s3.UploadPartOutput{ETag: aws.String("upload-etag")}
+		case *s3.CompleteMultipartUploadInput:
+			completed = params.MultipartUpload.Parts
+			*r.Data.(*s3.CompleteMultipartUploadOutput) = s3.CompleteMultipartUploadOutput{}
+		case *s3.AbortMultipartUploadInput:
+			aborted = true
+			*r.Data.(*s3.AbortMultipartUploadOutput) = s3.AbortMultipartUploadOutput{}
+		default:
+			t.Errorf("unexpected request %T", params)
+		}
+	})
+
+	mockS3 := &S3{api: mockAPI}
+
+	prefix := &Object{URL: u, Etag: "prefix-etag", Size: prefixSize}
+	metadata := NewMetadata().SetContentType("text/plain")
+
+	err = mockS3.Append(context.Background(), bytes.NewReader(content), int64(len(content)), prefix, metadata, MinPartSize)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	assert.DeepEqual(t, copyRanges, []string{fmt.Sprintf("bytes=0-%d", prefixSize-1)})
+	assert.DeepEqual(t, uploaded, [][]byte{content[prefixSize:]})
+	assert.DeepEqual(t, completed, []*s3.CompletedPart{
+		{ETag: aws.String("copy-etag"), PartNumber: aws.Int64(1)},
+		{ETag: aws.String("upload-etag"), PartNumber: aws.Int64(2)},
+	})
+	assert.Assert(t, !aborted)
+
+	// objects smaller than the minimum part size can not be appended to.
+	small := &Object{URL: u, Etag: "prefix-etag", Size: MinPartSize - 1}
+	err = mockS3.Append(context.Background(), bytes.NewReader(content), int64(len(content)), small, metadata, MinPartSize)
+	assert.ErrorContains(t, err, "smaller than the minimum part size")
+}
+
+func TestS3MultipartCopy(t *testing.T) {
+	log.Init("debug", false)
+
+	from, err := url.New("s3://bucket/key", url.WithVersion("v1"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	to, err := url.New("s3://target-bucket/key")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	const (
+		size        = 3*MinPartSize + 10
+		concurrency = 2
+	)
+
+	newMockS3 := func(failPart int64) (*S3, *multipartCopyRecorder) {
+		recorder := &multipartCopyRecorder{}
+
+		mockAPI := s3.New(unit.Session)
+		mockAPI.Handlers.Unmarshal.Clear()
+		mockAPI.Handlers.UnmarshalMeta.Clear()
+		mockAPI.Handlers.UnmarshalError.Clear()
+		mockAPI.Handlers.Send.Clear()
+		mockAPI.Handlers.Send.PushBack(func(r *request.Request) {
+			r.Handlers.Unmarshal.Clear()
+			r.HTTPResponse = &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(strings.NewReader("")),
+			}
+
+			switch params := r.Params.(type) {
+			case *s3.HeadObjectInput:
+				assert.Equal(t, aws.StringValue(params.VersionId), "v1")
+				*r.Data.(*s3.HeadObjectOutput) = s3.HeadObjectOutput{
+					ContentLength: aws.Int64(size),
+					ContentType:   aws.String("text/plain"),
+					ETag:          aws.String(`"source-etag"`),
+					Metadata:      map[string]*string{"owner": aws.String("team")},
+				}
+			case *s3.CreateMultipartUploadInput:
+				// the metadata of the source is kept.
+				assert.Equal(t, aws.StringValue(params.ContentType), "text/plain")
+				assert.Equal(t, aws.StringValue(params.Metadata["owner"]), "team")
+				assert.Equal(t, aws.StringValue(params.StorageClass), "STANDARD_IA")
+				*r.Data.(*s3.CreateMultipartUploadOutput) = s3.CreateMultipartUploadOutput{UploadId: aws.String("upload-id")}
+			case *s3.UploadPartCopyInput:
+				assert.Equal(t, aws.StringValue(params.CopySource), "bucket/key?versionId=v1")
+				assert.Equal(t, aws.StringValue(params.CopySourceIfMatch), `"source-etag"`)
+
+				inflight := atomic.AddInt64(&recorder.inflight, 1)
+				defer atomic.AddInt64(&recorder.inflight, -1)
+				recorder.add(aws.StringValue(params.CopySourceRange), inflight)
+				time.Sleep(10 * time.Millisecond)
+
+				if aws.Int64Value(params.PartNumber) == failPart {
+					r.Error = errors.New("part copy failed")
+					return
+				}
+				*r.Data.(*s3.UploadPartCopyOutput) = s3.UploadPartCopyOutput{
+					CopyPartResult: &s3.CopyPartResult{ETag: aws.String(fmt.Sprintf("etag-%d", aws.Int64Value(params.PartNumber)))},
+				}
+			case *s3.CompleteMultipartUploadInput:
+				recorder.completed = params.MultipartUpload.Parts
+				*r.Data.(*s3.CompleteMultipartUploadOutput) = s3.CompleteMultipartUploadOutput{}
+			case *s3.AbortMultipartUploadInput:
+				recorder.aborted = true
+				*r.Data.(*s3.AbortMultipartUploadOutput) = s3.AbortMultipartUploadOutput{}
+			default:
+				t.Errorf("unexpected request %T", params)
+			}
+		})
+		return &S3{api: mockAPI}, recorder
+	}
+
+	metadata := NewMetadata().SetStorageClass("STANDARD_IA")
+
+	mockS3, recorder := newMockS3(0)
+	err = mockS3.MultipartCopy(context.Background(), from, to, metadata, concurrency, MinPartSize)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expectedRanges := []string{
+		fmt.Sprintf("bytes=%d-%d", 0, MinPartSize-1),
+		fmt.Sprintf("bytes=%d-%d", MinPartSize, 2*MinPartSize-1),
+		fmt.Sprintf("bytes=%d-%d", 2*MinPartSize, 3*MinPartSize-1),
+		fmt.Sprintf("bytes=%d-%d", 3*MinPartSize, size-1),
+	}
+	// the parts are copied concurrently, so the order of the ranges is not
+	// known.
+	sort.Strings(expectedRanges)
+	sort.Strings(recorder.ranges)
+	assert.DeepEqual(t, recorder.ranges, expectedRanges)
+	assert.Assert(t, recorder.maxInflight <= concurrency)
+	assert.DeepEqual(t, recorder.completed, []*s3.CompletedPart{
+		{ETag: aws.String("etag-1"), PartNumber: aws.Int64(1)},
+		{ETag: aws.String("etag-2"), PartNumber: aws.Int64(2)},
+		{ETag: aws.String("etag-3"), PartNumber: aws.Int64(3)},
+		{ETag: aws.String("etag-4"), PartNumber: aws.Int64(4)},
+	})
+	assert.Assert(t, !recorder.aborted)
+
+	// the upload is aborted if a part can't be copied.
+	mockS3, recorder = newMockS3(2)
+	err = mockS3.MultipartCopy(context.Background(), from, to, metadata, concurrency, MinPartSize)
+	assert.ErrorContains(t, err, "part copy failed")
+	assert.Assert(t, recorder.completed == nil)
+	assert.Assert(t, recorder.aborted)
+}
+
+// multipartCopyRecorder records the requests of a multipart copy.
+type multipartCopyRecorder struct {
+	mu          sync.Mutex
+	ranges      []string
+	inflight    int64
+	maxInflight int64
+	completed   []*s3.CompletedPart
+	aborted     bool
+}
+
+func (r *multipartCopyRecorder) add(copyRange string, inflight int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.ranges = append(r.ranges, copyRange)
+	if inflight > r.maxInflight {
+		r.maxInflight = inflight
+	}
+}
+
+func TestS3CopyReplaceMetadata(t *testing.T) {
+	from, err := url.New("s3://bucket/key")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	to, err := url.New("s3://target-bucket/key")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	testcases := []struct {
+		name     string
+		metadata Metadata
+		// the expected copy input, the metadata is copied by S3 if the
+		// directive is empty.
+		directive       string
+		contentType     string
+		contentLanguage string
+		websiteRedirect string
+	}{
+		{
+			name:     "metadata is copied as it is without overridden headers",
+			metadata: NewMetadata().SetContentType("text/html"),
+		},
+		{
+			name:            "source headers are kept along with content language",
+			metadata:        NewMetadata().SetContentLanguage("de"),
+			directive:       s3.MetadataDirectiveReplace,
+			contentType:     "text/plain",
+			contentLanguage: "de",
+			websiteRedirect: "/index.html",
+		},
+		{
+			name:            "source headers are overridden along with website redirect",
+			metadata:        NewMetadata().SetSystemHeader("x-amz-website-redirect-location", "/new.html").SetContentType("text/html"),
+			directive:       s3.MetadataDirectiveReplace,
+			contentType:     "text/html",
+			contentLanguage: "en",
+			websiteRedirect: "/new.html",
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			var input *s3.CopyObjectInput
+
+			mockAPI := s3.New(unit.Session)
+			mockAPI.Handlers.Unmarshal.Clear()
+			mockAPI.Handlers.UnmarshalMeta.Clear()
+			mockAPI.Handlers.UnmarshalError.Clear()
+			mockAPI.Handlers.Send.Clear()
+			mockAPI.Handlers.Send.PushBack(func(r *request.Request) {
+				r.Handlers.Unmarshal.Clear()
+				r.HTTPResponse = &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       io.NopCloser(strings.NewReader("")),
+				}
+
+				switch params := r.Params.(type) {
+				case *s3.HeadObjectInput:
+					*r.Data.(*s3.HeadObjectOutput) = s3.HeadObjectOutput{
+						ContentType:             aws.String("text/plain"),
+						ContentLanguage:         aws.String("en"),
+						WebsiteRedirectLocation: aws.String("/index.html"),
+						Metadata:                map[string]*string{"owner": aws.String("team")},
+					}
+				case *s3.CopyObjectInput:
+					input = params
+				default:
+					t.Errorf("unexpected request %T", params)
+				}
+			})
+
+			mockS3 := &S3{api: mockAPI}
+			if err := mockS3.Copy(context.Background(), from, to, tc.metadata); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			assert.Equal(t, aws.StringValue(input.MetadataDirective), tc.directive)
+			if tc.directive == "" {
+				return
+			}
+			assert.Equal(t, aws.StringValue(input.ContentType), tc.contentType)
+			assert.Equal(t, aws.StringValue(input.ContentLanguage), tc.contentLanguage)
+			assert.Equal(t, aws.StringValue(input.WebsiteRedirectLocation), tc.websiteRedirect)
+			assert.Equal(t, aws.StringValue(input.Metadata["owner"]), "team")
+		})
+	}
+}
+
+func TestCopyPartSize(t *testing.T) {
+	const gib = 1024 * 1024 * 1024
+
+	testcases := []struct {
+		name        string
+		size        int64
+		partSize    int64
+		expected    int64
+		expectedErr bool
+	}{
+		{
+			name:     "given part size",
+			size:     6 * gib,
+			partSize: 50 * 1024 * 1024,
+			expected: 50 * 1024 * 1024,
+		},
+		{
+			name:     "minimum part size",
+			size:     6 * gib,
+			partSize: 1024,
+			expected: MinPartSize,
+		},
+		{
+			name:     "maximum number of parts",
+			size:     5 * 1024 * gib,
+			partSize: 50 * 1024 * 1024,
+			expected: (5*1024*gib + s3manager.MaxUploadParts - 1) / s3manager.MaxUploadParts,
+		},
+		{
+			name:        "too large",
+			size:        s3manager.MaxUploadParts*maxCopyPartSize + 1,
+			partSize:    50 * 1024 * 1024,
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := copyPartSize(tc.size, tc.partSize)
+			if tc.expectedErr {
+				assert.Assert(t, err != nil)
+				return
+			}
+			assert.NilError(t, err)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
+
+func TestSessionCreateAndCachingWithDifferentBuckets(t *testing.T) {
+	log.Init("error", false)
+	testcases := []struct {
+		bucket         string
+		alreadyCreated bool // sessions should not be created again if they already have been created before
+	}{
+		{bucket: "bucket"},
+		{bucket: "bucket", alreadyCreated: true},
+		{bucket: "test-bucket"},
+	}
+
+	sess := map[string]*session.Session{}
+
+	for _, tc := range testcases {
+		awsSess, err := globalSessionCache.newSession(context.Background(), Options{
+			bucket: tc.bucket,
+		})
+		if err != nil {
+			t.Error(err)
+		}
+
+		if tc.alreadyCreated {
+			_, ok := sess[tc.bucket]
+			assert.Check(t, ok, "session should not have been created again")
+		} else {
+			sess[tc.bucket] = awsSess
+		}
+	}
+}
+
+func TestSessionRegionDetection(t *testing.T) {
+	bucketRegion := "sa-east-1"
+
+	testcases := []struct {
+		name           string
+		bucket         string
+		optsRegion     string
+		envRegion      string
+		expectedRegion string
+	}{
+		{
+			name:           "RegionWithSourceRegionParameter",
+			bucket:         "bucket",
+			optsRegion:     "ap-east-1",
+			envRegion:      "ca-central-1",
+			expectedRegion: "ap-east-1",
+		},
+		{
+			name:           "RegionWithEnvironmentVariable",
+			bucket:         "bucket",
 			optsRegion:     "",
 			envRegion:      "ca-central-1",
 			expectedRegion: "ca-central-1",
@@ -1050,6 +1946,94 @@ func TestSessionRegionDetection(t *testing.T) {
 	}
 }
 
+func TestSessionRegionCache(t *testing.T) {
+	// ignore local profile loading
+	os.Setenv("AWS_SDK_LOAD_CONFIG", "0")
+
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("X-Amz-Bucket-Region", "sa-east-1")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	testcases := []struct {
+		name             string
+		resolverCache    bool
+		expectedRequests int32
+	}{
+		{
+			name:             "without cache",
+			resolverCache:    false,
+			expectedRequests: 2,
+		},
+		{
+			name:             "with cache",
+			resolverCache:    true,
+			expectedRequests: 1,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			globalSessionCache.clear()
+			globalRegionCache.clear()
+			atomic.StoreInt32(&requests, 0)
+
+			// sessions of the options below are not shared, but the region
+			// of the bucket is.
+			for _, maxRetries := range []int{1, 2} {
+				sess, err := globalSessionCache.newSession(context.Background(), Options{
+					Endpoint:              server.URL,
+					NoSignRequest:         true,
+					MaxRetries:            maxRetries,
+					EndpointResolverCache: tc.resolverCache,
+					bucket:                "bucket",
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+				assert.Equal(t, aws.StringValue(sess.Config.Region), "sa-east-1")
+			}
+
+			assert.Equal(t, atomic.LoadInt32(&requests), tc.expectedRequests)
+		})
+	}
+}
+
+func TestNewRemoteClientWithEndpointResolverCache(t *testing.T) {
+	globalClientCache.clear()
+
+	u, err := url.New("s3://bucket/key")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts := Options{
+		NoSignRequest:         true,
+		EndpointResolverCache: true,
+	}
+	opts.SetRegion("us-west-2")
+
+	first, err := NewRemoteClient(context.Background(), u, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := NewRemoteClient(context.Background(), u, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Assert(t, first == second, "client should have been reused")
+
+	opts.EndpointResolverCache = false
+	third, err := NewRemoteClient(context.Background(), u, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Assert(t, first != third, "client should not have been reused")
+}
+
 func TestSessionAutoRegionValidateCredentials(t *testing.T) {
 	awsSess := unit.Session
 	awsSess.Handlers.Unmarshal.Clear()
@@ -1289,3 +2273,322 @@ func (e tempError) Error() string { return e.err.Error() }
 func (e tempError) Temporary() bool { return e.temp }
 
 func (e *tempError) Unwrap() error { return e.err }
+
+func TestBucketErrorClassification(t *testing.T) {
+	testcases := []struct {
+		err          error
+		noSuchBucket bool
+		accessDenied bool
+	}{
+		{err: awserr.New(s3.ErrCodeNoSuchBucket, "no such bucket", nil), noSuchBucket: true},
+		{err: awserr.New("NotFound", "Not Found", nil), noSuchBucket: true},
+		{err: awserr.New("AccessDenied", "access denied", nil), accessDenied: true},
+		{err: awserr.New("Forbidden", "Forbidden", nil), accessDenied: true},
+		{err: fmt.Errorf("wrapped: %w", awserr.New("Forbidden", "Forbidden", nil)), accessDenied: true},
+		{err: awserr.New("InternalError", "internal error", nil)},
+		{err: nil},
+	}
+
+	for _, tc := range testcases {
+		if got := IsNoSuchBucketError(tc.err); got != tc.noSuchBucket {
+			t.Errorf("IsNoSuchBucketError(%v) = %v, want %v", tc.err, got, tc.noSuchBucket)
+		}
+		if got := IsAccessDeniedError(tc.err); got != tc.accessDenied {
+			t.Errorf("IsAccessDeniedError(%v) = %v, want %v", tc.err, got, tc.accessDenied)
+		}
+	}
+}
+
+func TestS3BucketConfiguration(t *testing.T) {
+	mockAPI := s3.New(unit.Session)
+
+	mockAPI.Handlers.Unmarshal.Clear()
+	mockAPI.Handlers.UnmarshalMeta.Clear()
+	mockAPI.Handlers.UnmarshalError.Clear()
+	mockAPI.Handlers.ValidateResponse.Clear()
+	mockAPI.Handlers.Send.Clear()
+
+	configured := true
+	mockAPI.Handlers.Send.PushBack(func(r *request.Request) {
+		r.HTTPResponse = &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader("")),
+		}
+
+		switch r.Params.(type) {
+		case *s3.GetObjectLockConfigurationInput:
+			if !configured {
+				r.Error = awserr.New("ObjectLockConfigurationNotFoundError", "not found", nil)
+				return
+			}
+			*r.Data.(*s3.GetObjectLockConfigurationOutput) = s3.GetObjectLockConfigurationOutput{
+				ObjectLockConfiguration: &s3.ObjectLockConfiguration{
+					ObjectLockEnabled: aws.String(s3.ObjectLockEnabledEnabled),
+					Rule: &s3.ObjectLockRule{
+						DefaultRetention: &s3.DefaultRetention{
+							Mode: aws.String(s3.ObjectLockRetentionModeGovernance),
+							Days: aws.Int64(30),
+						},
+					},
+				},
+			}
+		case *s3.GetBucketEncryptionInput:
+			if !configured {
+				r.Error = awserr.New("ServerSideEncryptionConfigurationNotFoundError", "not found", nil)
+				return
+			}
+			*r.Data.(*s3.GetBucketEncryptionOutput) = s3.GetBucketEncryptionOutput{
+				ServerSideEncryptionConfiguration: &s3.ServerSideEncryptionConfiguration{
+					Rules: []*s3.ServerSideEncryptionRule{
+						{
+							ApplyServerSideEncryptionByDefault: &s3.ServerSideEncryptionByDefault{
+								SSEAlgorithm:   aws.String(s3.ServerSideEncryptionAwsKms),
+								KMSMasterKeyID: aws.String("key-id"),
+							},
+							BucketKeyEnabled: aws.Bool(true),
+						},
+					},
+				},
+			}
+		case *s3.GetPublicAccessBlockInput:
+			if !configured {
+				r.Error = awserr.New("NoSuchPublicAccessBlockConfiguration", "not found", nil)
+				return
+			}
+			*r.Data.(*s3.GetPublicAccessBlockOutput) = s3.GetPublicAccessBlockOutput{
+				PublicAccessBlockConfiguration: &s3.PublicAccessBlockConfiguration{
+					BlockPublicAcls:   aws.Bool(true),
+					BlockPublicPolicy: aws.Bool(true),
+				},
+			}
+		case *s3.GetBucketLifecycleConfigurationInput:
+			if !configured {
+				r.Error = awserr.New("NoSuchLifecycleConfiguration", "not found", nil)
+				return
+			}
+			*r.Data.(*s3.GetBucketLifecycleConfigurationOutput) = s3.GetBucketLifecycleConfigurationOutput{
+				Rules: []*s3.LifecycleRule{{}, {}},
+			}
+		case *s3.GetBucketCorsInput:
+			if !configured {
+				r.Error = awserr.New("NoSuchCORSConfiguration", "not found", nil)
+				return
+			}
+			*r.Data.(*s3.GetBucketCorsOutput) = s3.GetBucketCorsOutput{
+				CORSRules: []*s3.CORSRule{{}},
+			}
+		case *s3.GetBucketPolicyInput:
+			if !configured {
+				r.Error = awserr.New("NoSuchBucketPolicy", "not found", nil)
+				return
+			}
+			*r.Data.(*s3.GetBucketPolicyOutput) = s3.GetBucketPolicyOutput{
+				Policy: aws.String(`{"Version":"2012-10-17"}`),
+			}
+		default:
+			t.Errorf("unexpected request %T", r.Params)
+		}
+	})
+
+	mockS3 := &S3{api: mockAPI}
+	ctx := context.Background()
+
+	lock, err := mockS3.GetObjectLockConfiguration(ctx, "bucket")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, lock, ObjectLock{Enabled: true, Mode: "GOVERNANCE", Days: 30})
+
+	encryption, err := mockS3.GetBucketEncryption(ctx, "bucket")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, encryption, BucketEncryption{Algorithm: "aws:kms", KMSKeyID: "key-id", BucketKeyEnabled: true})
+
+	block, err := mockS3.GetPublicAccessBlock(ctx, "bucket")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, block, &PublicAccessBlock{BlockPublicAcls: true, BlockPublicPolicy: true})
+
+	lifecycleRules, err := mockS3.BucketLifecycleRuleCount(ctx, "bucket")
+	assert.NilError(t, err)
+	assert.Equal(t, lifecycleRules, 2)
+
+	corsRules, err := mockS3.BucketCORSRuleCount(ctx, "bucket")
+	assert.NilError(t, err)
+	assert.Equal(t, corsRules, 1)
+
+	policy, err := mockS3.HasBucketPolicy(ctx, "bucket")
+	assert.NilError(t, err)
+	assert.Assert(t, policy)
+
+	// the missing configurations are not errors.
+	configured = false
+
+	lock, err = mockS3.GetObjectLockConfiguration(ctx, "bucket")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, lock, ObjectLock{})
+
+	encryption, err = mockS3.GetBucketEncryption(ctx, "bucket")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, encryption, BucketEncryption{})
+
+	block, err = mockS3.GetPublicAccessBlock(ctx, "bucket")
+	assert.NilError(t, err)
+	assert.Assert(t, block == nil)
+
+	lifecycleRules, err = mockS3.BucketLifecycleRuleCount(ctx, "bucket")
+	assert.NilError(t, err)
+	assert.Equal(t, lifecycleRules, 0)
+
+	corsRules, err = mockS3.BucketCORSRuleCount(ctx, "bucket")
+	assert.NilError(t, err)
+	assert.Equal(t, corsRules, 0)
+
+	policy, err = mockS3.HasBucketPolicy(ctx, "bucket")
+	assert.NilError(t, err)
+	assert.Assert(t, !policy)
+}
+
+func TestS3Presign(t *testing.T) {
+	u, err := url.New("s3://bucket/dir/key.parquet")
+	assert.NilError(t, err)
+	u.VersionID = "v1"
+
+	mockS3 := &S3{api: s3.New(unit.Session)}
+
+	presigned, err := mockS3.Presign(context.Background(), u, 15*time.Minute)
+	assert.NilError(t, err)
+
+	parsed, err := urlpkg.Parse(presigned)
+	assert.NilError(t, err)
+	assert.Assert(t, strings.HasSuffix(parsed.Path, "/dir/key.parquet"))
+
+	query := parsed.Query()
+	assert.Equal(t, query.Get("X-Amz-Expires"), "900")
+	assert.Equal(t, query.Get("versionId"), "v1")
+	assert.Assert(t, query.Get("X-Amz-Signature") != "")
+}
+
+func TestS3ObjectURL(t *testing.T) {
+	testcases := []struct {
+		name     string
+		endpoint string
+		style    string
+		key      string
+		expected string
+	}{
+		{
+			name:     "aws",
+			key:      "dir/key.parquet",
+			expected: "https://bucket.s3.amazonaws.com/dir/key.parquet",
+		},
+		{
+			name:     "aws with path style",
+			style:    AddressingStylePath,
+			key:      "dir/key.parquet",
+			expected: "https://s3.amazonaws.com/bucket/dir/key.parquet",
+		},
+		{
+			name:     "custom endpoint",
+			endpoint: "http://127.0.0.1:9000",
+			key:      "dir/key.parquet",
+			expected: "http://127.0.0.1:9000/bucket/dir/key.parquet",
+		},
+		{
+			name:     "escaped key",
+			key:      "dir/a b+c.txt",
+			expected: "https://bucket.s3.amazonaws.com/dir/a%20b%2Bc.txt",
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			opts := Options{
+				Endpoint:        tc.endpoint,
+				AddressingStyle: tc.style,
+				bucket:          "bucket",
+				region:          "us-east-1",
+			}
+			sess, err := globalSessionCache.newSession(context.Background(), opts)
+			assert.NilError(t, err)
+
+			u, err := url.New("s3://bucket/" + tc.key)
+			assert.NilError(t, err)
+
+			mockS3 := &S3{api: s3.New(sess)}
+			got, err := mockS3.ObjectURL(u)
+			assert.NilError(t, err)
+			assert.Equal(t, got, tc.expected)
+		})
+	}
+}
+
+func TestS3PutConditional(t *testing.T) {
+	testcases := []struct {
+		name           string
+		etag           string
+		statusCode     int
+		code           string
+		expectedHeader string
+		expectedValue  string
+		expectedErr    error
+	}{
+		{
+			name:           "if none match",
+			expectedHeader: "If-None-Match",
+			expectedValue:  "*",
+		},
+		{
+			name:           "if match",
+			etag:           "abc",
+			expectedHeader: "If-Match",
+			expectedValue:  `"abc"`,
+		},
+		{
+			name:           "precondition failed",
+			statusCode:     http.StatusPreconditionFailed,
+			code:           "PreconditionFailed",
+			expectedHeader: "If-None-Match",
+			expectedValue:  "*",
+			expectedErr:    ErrPreconditionFailed,
+		},
+		{
+			name:           "conflict",
+			etag:           "abc",
+			statusCode:     http.StatusConflict,
+			code:           "ConditionalRequestConflict",
+			expectedHeader: "If-Match",
+			expectedValue:  `"abc"`,
+			expectedErr:    ErrPreconditionFailed,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			mockAPI := s3.New(unit.Session)
+			mockS3 := &S3{api: mockAPI}
+
+			mockAPI.Handlers.Send.Clear()
+			mockAPI.Handlers.Unmarshal.Clear()
+			mockAPI.Handlers.UnmarshalMeta.Clear()
+			mockAPI.Handlers.ValidateResponse.Clear()
+			mockAPI.Handlers.Send.PushBack(func(r *request.Request) {
+				assert.Equal(t, r.HTTPRequest.Header.Get(tc.expectedHeader), tc.expectedValue)
+				if tc.statusCode != 0 {
+					r.Error = awserr.NewRequestFailure(awserr.New(tc.code, tc.code, nil), tc.statusCode, "")
+					return
+				}
+				r.Data.(*s3.PutObjectOutput).ETag = aws.String(`"def"`)
+			})
+
+			u, err := url.New("s3://bucket/.s5cmd.lock")
+			assert.NilError(t, err)
+
+			etag, err := mockS3.PutConditional(context.Background(), u, []byte("{}"), tc.etag)
+			if tc.expectedErr != nil {
+				assert.Assert(t, errors.Is(err, tc.expectedErr))
+				return
+			}
+			assert.NilError(t, err)
+			assert.Equal(t, etag, "def")
+		})
+	}
+}
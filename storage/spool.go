@@ -0,0 +1,176 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/peak/s5cmd/v2/strutil"
+)
+
+// ErrSpoolExhausted is returned when a temporary file would exceed the size
+// limit of the spool.
+var ErrSpoolExhausted = errors.New("spool exhausted")
+
+// Spool allocates the temporary files of a run in a directory of its own, and
+// keeps the total size of the files which are open within a limit. The
+// directory is created on the first use, and it is removed with the files
+// left in it by Cleanup.
+type Spool struct {
+	parent string
+	limit  int64
+
+	mu      sync.Mutex
+	dir     string
+	used    int64
+	cleaned bool
+}
+
+// NewSpool creates a spool in parent directory, or in the default directory
+// for temporary files if it is empty. A limit of 0 disables the size limit.
+func NewSpool(parent string, limit int64) *Spool {
+	return &Spool{parent: parent, limit: limit}
+}
+
+// Dir returns the directory of the spool, creating it if it doesn't exist.
+// The files created by others, e.g. the sorters of sync, are placed in it to
+// be removed by Cleanup, but they are not counted against the limit.
+func (s *Spool) Dir() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dirLocked()
+}
+
+func (s *Spool) dirLocked() (string, error) {
+	if s.cleaned {
+		return "", fmt.Errorf("spool is cleaned up")
+	}
+	if s.dir != "" {
+		return s.dir, nil
+	}
+	dir, err := os.MkdirTemp(s.parent, "s5cmd-*")
+	if err != nil {
+		return "", err
+	}
+	s.dir = dir
+	return dir, nil
+}
+
+// Create creates a temporary file in the spool. The size is the expected size
+// of the file, which is reserved up front so that the file fails early if it
+// doesn't fit into the spool. The file can be written beyond it as long as
+// the spool has room for it. A size of 0 reserves nothing.
+func (s *Spool) Create(pattern string, size int64) (*SpoolFile, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.reserveLocked(size); err != nil {
+		return nil, err
+	}
+	dir, err := s.dirLocked()
+	if err != nil {
+		s.used -= size
+		return nil, err
+	}
+	file, err := os.CreateTemp(dir, pattern)
+	if err != nil {
+		s.used -= size
+		return nil, err
+	}
+	return &SpoolFile{File: file, spool: s, reserved: size}, nil
+}
+
+func (s *Spool) reserveLocked(size int64) error {
+	if s.limit > 0 && s.used+size > s.limit {
+		return fmt.Errorf(
+			"%w: %v is required, %v of %v is available",
+			ErrSpoolExhausted,
+			strutil.HumanizeBytes(size),
+			strutil.HumanizeBytes(s.limit-s.used),
+			strutil.HumanizeBytes(s.limit),
+		)
+	}
+	s.used += size
+	return nil
+}
+
+// Used returns the size reserved by the open files of the spool.
+func (s *Spool) Used() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.used
+}
+
+// Cleanup removes the directory of the spool with the files in it. The spool
+// can't be used afterwards.
+func (s *Spool) Cleanup() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.cleaned = true
+	if s.dir == "" {
+		return nil
+	}
+	return os.RemoveAll(s.dir)
+}
+
+// SpoolFile is a temporary file of a spool. Its writes fail with
+// ErrSpoolExhausted once they don't fit into the spool.
+type SpoolFile struct {
+	*os.File
+
+	spool    *Spool
+	reserved int64
+	written  int64
+	once     sync.Once
+}
+
+// Write writes p to the file, reserving the room for the bytes written beyond
+// the reserved size.
+func (f *SpoolFile) Write(p []byte) (int, error) {
+	if err := f.grow(f.written + int64(len(p))); err != nil {
+		return 0, err
+	}
+	n, err := f.File.Write(p)
+	f.written += int64(n)
+	return n, err
+}
+
+// WriteAt writes p to the file at offset off, reserving the room for the bytes
+// written beyond the reserved size. It is used by the concurrent downloads.
+func (f *SpoolFile) WriteAt(p []byte, off int64) (int, error) {
+	if err := f.grow(off + int64(len(p))); err != nil {
+		return 0, err
+	}
+	return f.File.WriteAt(p, off)
+}
+
+// grow reserves the room for the file to be size bytes.
+func (f *SpoolFile) grow(size int64) error {
+	f.spool.mu.Lock()
+	defer f.spool.mu.Unlock()
+
+	if size <= f.reserved {
+		return nil
+	}
+	if err := f.spool.reserveLocked(size - f.reserved); err != nil {
+		return err
+	}
+	f.reserved = size
+	return nil
+}
+
+// Remove closes and removes the file, and releases its room in the spool.
+func (f *SpoolFile) Remove() error {
+	var err error
+	f.once.Do(func() {
+		f.File.Close()
+		err = os.Remove(f.File.Name())
+
+		f.spool.mu.Lock()
+		f.spool.used -= f.reserved
+		f.spool.mu.Unlock()
+	})
+	return err
+}
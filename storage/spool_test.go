@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestSpool(t *testing.T) {
+	t.Parallel()
+
+	parent := t.TempDir()
+	spool := NewSpool(parent, 10)
+
+	// the directory is created on the first use.
+	entries, err := os.ReadDir(parent)
+	assert.NilError(t, err)
+	assert.Equal(t, len(entries), 0)
+
+	a, err := spool.Create("a-*", 6)
+	assert.NilError(t, err)
+	assert.Equal(t, filepath.Dir(filepath.Dir(a.Name())), parent)
+	assert.Equal(t, spool.Used(), int64(6))
+
+	// the expected size doesn't fit into the room left.
+	_, err = spool.Create("b-*", 5)
+	assert.Assert(t, errors.Is(err, ErrSpoolExhausted), "error: %v", err)
+	assert.Equal(t, spool.Used(), int64(6))
+
+	b, err := spool.Create("b-*", 0)
+	assert.NilError(t, err)
+	_, err = b.Write([]byte("abc"))
+	assert.NilError(t, err)
+	assert.Equal(t, spool.Used(), int64(9))
+
+	// the writes beyond the reserved size are counted, and the ones which
+	// don't fit fail before they are written.
+	_, err = a.WriteAt([]byte("01234567"), 0)
+	assert.Assert(t, errors.Is(err, ErrSpoolExhausted), "error: %v", err)
+	n, err := a.WriteAt([]byte("012345"), 0)
+	assert.NilError(t, err)
+	assert.Equal(t, n, 6)
+
+	assert.NilError(t, b.Remove())
+	assert.Equal(t, spool.Used(), int64(6))
+	_, err = a.WriteAt([]byte("01234567"), 0)
+	assert.NilError(t, err)
+	assert.Equal(t, spool.Used(), int64(8))
+
+	// the files left in the spool are removed with its directory.
+	dir, err := spool.Dir()
+	assert.NilError(t, err)
+	assert.NilError(t, spool.Cleanup())
+	_, err = os.Stat(dir)
+	assert.Assert(t, os.IsNotExist(err))
+
+	_, err = spool.Create("c-*", 0)
+	assert.ErrorContains(t, err, "spool is cleaned up")
+}
+
+func TestSpoolUnlimited(t *testing.T) {
+	t.Parallel()
+
+	spool := NewSpool(t.TempDir(), 0)
+	file, err := spool.Create("a-*", 1<<40)
+	assert.NilError(t, err)
+	_, err = file.Write([]byte("content"))
+	assert.NilError(t, err)
+	assert.NilError(t, file.Remove())
+	assert.Equal(t, spool.Used(), int64(0))
+	assert.NilError(t, spool.Cleanup())
+}
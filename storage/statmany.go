@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+// StatResult is the result of the Stat of a URL by StatMany.
+type StatResult struct {
+	URL *url.URL
+	// Object is the object of URL. It is nil if the object is not found or
+	// its Stat fails.
+	Object *Object
+	// NotFound reports whether the object is not found. Err is nil in that
+	// case.
+	NotFound bool
+	// Err is the error of the Stat, other than the object not being found.
+	Err error
+}
+
+// statMany stats the given urls with stat, running at most concurrency of
+// them at once. The results are in the order of urls. The urls which are not
+// stated yet when ctx is canceled get the error of ctx.
+func statMany(
+	ctx context.Context,
+	urls []*url.URL,
+	concurrency int,
+	stat func(context.Context, *url.URL) (*Object, error),
+) []StatResult {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	if concurrency > len(urls) {
+		concurrency = len(urls)
+	}
+
+	results := make([]StatResult, len(urls))
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i] = statResult(ctx, urls[i], stat)
+			}
+		}()
+	}
+
+	for i := range urls {
+		if ctx.Err() == nil {
+			select {
+			case indexes <- i:
+				continue
+			case <-ctx.Done():
+			}
+		}
+		results[i] = StatResult{URL: urls[i], Err: ctx.Err()}
+	}
+	close(indexes)
+	wg.Wait()
+
+	return results
+}
+
+func statResult(
+	ctx context.Context,
+	u *url.URL,
+	stat func(context.Context, *url.URL) (*Object, error),
+) StatResult {
+	obj, err := stat(ctx, u)
+	var notFound *ErrGivenObjectNotFound
+	switch {
+	case errors.As(err, &notFound):
+		return StatResult{URL: u, NotFound: true}
+	case err != nil:
+		return StatResult{URL: u, Err: err}
+	}
+	return StatResult{URL: u, Object: obj}
+}
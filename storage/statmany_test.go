@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+func TestStatMany(t *testing.T) {
+	t.Parallel()
+
+	errInjected := errors.New("injected error")
+
+	m := newTestMemory(t, "a", "b", "d")
+	m.InjectFault(func(_ context.Context, op MemoryOp, _ int, u *url.URL) error {
+		if op == MemoryStat && u.Path == "d" {
+			return errInjected
+		}
+		return nil
+	})
+
+	var urls []*url.URL
+	for _, key := range []string{"b", "c", "a", "d"} {
+		urls = append(urls, mustURL(t, "mem://bucket/"+key))
+	}
+
+	results := m.StatMany(context.Background(), urls, 2)
+	assert.Equal(t, len(results), len(urls))
+	for i, result := range results {
+		assert.Equal(t, result.URL, urls[i])
+	}
+
+	assert.NilError(t, results[0].Err)
+	assert.Equal(t, results[0].Object.Size, int64(len("content of b")))
+	assert.Assert(t, !results[0].NotFound)
+
+	assert.NilError(t, results[1].Err)
+	assert.Assert(t, results[1].NotFound)
+	assert.Assert(t, results[1].Object == nil)
+
+	assert.NilError(t, results[2].Err)
+	assert.Equal(t, results[2].Object.URL.Path, "a")
+
+	assert.Equal(t, results[3].Err, errInjected)
+	assert.Assert(t, !results[3].NotFound)
+	assert.Assert(t, results[3].Object == nil)
+
+	assert.Equal(t, m.Calls(MemoryStat), len(urls))
+}
+
+func TestStatManyConcurrency(t *testing.T) {
+	t.Parallel()
+
+	const concurrency = 3
+
+	var urls []*url.URL
+	for i := 0; i < 20; i++ {
+		urls = append(urls, mustURL(t, fmt.Sprintf("s3://bucket/key%d", i)))
+	}
+
+	var inflight, peak int64
+	stat := func(_ context.Context, u *url.URL) (*Object, error) {
+		n := atomic.AddInt64(&inflight, 1)
+		defer atomic.AddInt64(&inflight, -1)
+		for {
+			p := atomic.LoadInt64(&peak)
+			if n <= p || atomic.CompareAndSwapInt64(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		return &Object{URL: u}, nil
+	}
+
+	results := statMany(context.Background(), urls, concurrency, stat)
+	for i, result := range results {
+		assert.NilError(t, result.Err)
+		assert.Equal(t, result.Object.URL, urls[i])
+	}
+	assert.Assert(t, atomic.LoadInt64(&peak) <= concurrency, "peak concurrency: %d", peak)
+}
+
+func TestStatManyCanceled(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	urls := []*url.URL{mustURL(t, "s3://bucket/a"), mustURL(t, "s3://bucket/b")}
+	results := statMany(ctx, urls, 1, func(context.Context, *url.URL) (*Object, error) {
+		t.Fatal("stat is called after cancelation")
+		return nil, nil
+	})
+	for i, result := range results {
+		assert.Equal(t, result.URL, urls[i])
+		assert.Equal(t, result.Err, context.Canceled)
+	}
+
+	// no urls.
+	assert.Equal(t, len(statMany(context.Background(), nil, 4, nil)), 0)
+}
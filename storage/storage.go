@@ -9,7 +9,11 @@ import (
 	"encoding/gob"
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/lanrat/extsort"
@@ -40,6 +44,11 @@ type Storage interface {
 	// found, ErrGivenObjectNotFound is returned.
 	Stat(ctx context.Context, src *url.URL) (*Object, error)
 
+	// StatMany stats the given urls, running at most concurrency of the
+	// stats at once. The results are in the order of urls. The objects which
+	// are not found are reported with NotFound rather than an error.
+	StatMany(ctx context.Context, urls []*url.URL, concurrency int) []StatResult
+
 	// List the objects and directories/prefixes in the src.
 	List(ctx context.Context, src *url.URL, followSymlinks bool) <-chan *Object
 
@@ -56,10 +65,19 @@ type Storage interface {
 }
 
 func NewLocalClient(opts Options) *Filesystem {
-	return &Filesystem{dryRun: opts.DryRun}
+	return &Filesystem{
+		dryRun:          opts.DryRun,
+		onBrokenSymlink: opts.OnBrokenSymlink,
+		readLimiter:     readLimiters.get(opts.ReadConcurrency, opts.ReadIOPS),
+		dirState:        opts.DirState,
+	}
 }
 
 func NewRemoteClient(ctx context.Context, url *url.URL, opts Options) (*S3, error) {
+	if url.IsMemory() {
+		return nil, fmt.Errorf("%v: operation is not supported by the in-memory storage", url)
+	}
+
 	newOpts := Options{
 		MaxRetries:             opts.MaxRetries,
 		NoSuchUploadRetryCount: opts.NoSuchUploadRetryCount,
@@ -68,17 +86,33 @@ func NewRemoteClient(ctx context.Context, url *url.URL, opts Options) (*S3, erro
 		DryRun:                 opts.DryRun,
 		NoSignRequest:          opts.NoSignRequest,
 		UseListObjectsV1:       opts.UseListObjectsV1,
+		FetchOwner:             opts.FetchOwner,
 		RequestPayer:           opts.RequestPayer,
 		Profile:                opts.Profile,
 		CredentialFile:         opts.CredentialFile,
+		CredentialsCommand:     opts.CredentialsCommand,
+		EnvPrefix:              opts.EnvPrefix,
 		LogLevel:               opts.LogLevel,
+		EndpointResolverCache:  opts.EndpointResolverCache,
+		MaxRequestsPerHost:     opts.MaxRequestsPerHost,
+		AddressingStyle:        opts.AddressingStyle,
+		DeleteBatchSize:        opts.DeleteBatchSize,
+		BufferPoolSize:         opts.BufferPoolSize,
+		Hooks:                  opts.Hooks,
+		Dialer:                 opts.Dialer,
 		bucket:                 url.Bucket,
 		region:                 opts.region,
 	}
+	if newOpts.EndpointResolverCache {
+		return globalClientCache.newClient(ctx, newOpts)
+	}
 	return newS3Storage(ctx, newOpts)
 }
 
 func NewClient(ctx context.Context, url *url.URL, opts Options) (Storage, error) {
+	if url.IsMemory() {
+		return NewMemoryClient(opts), nil
+	}
 	if url.IsRemote() {
 		return NewRemoteClient(ctx, url, opts)
 	}
@@ -94,10 +128,24 @@ type Options struct {
 	DryRun                 bool
 	NoSignRequest          bool
 	UseListObjectsV1       bool
+	FetchOwner             bool
 	LogLevel               log.LogLevel
 	RequestPayer           string
 	Profile                string
 	CredentialFile         string
+	CredentialsCommand     string
+	EnvPrefix              string
+	EndpointResolverCache  bool
+	MaxRequestsPerHost     int
+	OnBrokenSymlink        string
+	ReadConcurrency        int
+	ReadIOPS               int
+	AddressingStyle        string
+	DeleteBatchSize        int
+	BufferPoolSize         int64
+	Hooks                  *Hooks
+	Dialer                 *Dialer
+	DirState               *DirState
 	bucket                 string
 	region                 string
 }
@@ -114,9 +162,34 @@ type Object struct {
 	Type         ObjectType   `json:"type,omitempty"`
 	Size         int64        `json:"size,omitempty"`
 	StorageClass StorageClass `json:"storage_class,omitempty"`
+	Owner        *Owner       `json:"owner,omitempty"`
 	Err          error        `json:"error,omitempty"`
 	retryID      string
 
+	// IsLatest and DeleteMarker are only set for the objects of a versions
+	// listing.
+	IsLatest     bool `json:"-"`
+	DeleteMarker bool `json:"-"`
+
+	// EncryptionMethod and EncryptionKeyID are only set by Stat of the
+	// remote objects.
+	EncryptionMethod string `json:"-"`
+	EncryptionKeyID  string `json:"-"`
+
+	// WindowsAttributes is only set by Stat of the remote objects uploaded
+	// with their Windows file attributes.
+	WindowsAttributes string `json:"-"`
+
+	// PreservedModTime is only set by Stat of the remote objects uploaded
+	// with the modification times of their files.
+	PreservedModTime *time.Time `json:"-"`
+
+	// ContentType and UserMetadata are only set by Stat of the remote
+	// objects. The names of the user-defined metadata are in lower case, and
+	// the ones s5cmd sets for itself are not included.
+	ContentType  string            `json:"-"`
+	UserMetadata map[string]string `json:"-"`
+
 	// the VersionID field exist only for JSON Marshall, it must not be used for
 	// any other purpose. URL.VersionID must be used instead.
 	VersionID string `json:"version_id,omitempty"`
@@ -135,6 +208,40 @@ func (o *Object) JSON() string {
 	return strutil.JSON(o)
 }
 
+// MultipartEtagParts returns the number of parts of the object if its ETag is
+// the one of a multipart upload, which is the MD5 checksum of the checksums of
+// its parts followed by "-<number of parts>" rather than the MD5 checksum of
+// its content.
+func (o *Object) MultipartEtagParts() (int, bool) {
+	checksum, parts, ok := strings.Cut(o.Etag, "-")
+	if !ok || checksum == "" {
+		return 0, false
+	}
+	n, err := strconv.Atoi(parts)
+	if err != nil || n < 1 {
+		return 0, false
+	}
+	return n, true
+}
+
+// Owner is the account that owns an object.
+type Owner struct {
+	ID          string `json:"id,omitempty"`
+	DisplayName string `json:"display_name,omitempty"`
+}
+
+// String returns the display name of the owner if it is known, and its ID
+// otherwise.
+func (o *Owner) String() string {
+	if o == nil {
+		return ""
+	}
+	if o.DisplayName != "" {
+		return o.DisplayName
+	}
+	return o.ID
+}
+
 // ObjectType is the type of Object.
 type ObjectType struct {
 	mode os.FileMode
@@ -302,6 +409,125 @@ func (m Metadata) SetContentEncoding(contentEncoding string) Metadata {
 	return m
 }
 
+func (m Metadata) ContentLanguage() string {
+	return m["ContentLanguage"]
+}
+
+func (m Metadata) SetContentLanguage(contentLanguage string) Metadata {
+	m["ContentLanguage"] = contentLanguage
+	return m
+}
+
+func (m Metadata) WebsiteRedirect() string {
+	return m["WebsiteRedirect"]
+}
+
+func (m Metadata) SetWebsiteRedirect(location string) Metadata {
+	m["WebsiteRedirect"] = location
+	return m
+}
+
+// systemHeaders are the setters of the system-defined object metadata which
+// can be given by their HTTP header names, keyed by their canonical names.
+var systemHeaders = map[string]func(Metadata, string) Metadata{
+	"Cache-Control":                               Metadata.SetCacheControl,
+	"Content-Disposition":                         Metadata.SetContentDisposition,
+	"Content-Encoding":                            Metadata.SetContentEncoding,
+	"Content-Language":                            Metadata.SetContentLanguage,
+	"Content-Type":                                Metadata.SetContentType,
+	"Expires":                                     Metadata.SetExpires,
+	"X-Amz-Server-Side-Encryption":                Metadata.SetSSE,
+	"X-Amz-Storage-Class":                         Metadata.SetStorageClass,
+	"X-Amz-Website-Redirect-Location":             Metadata.SetWebsiteRedirect,
+	"X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id": Metadata.SetSSEKeyID,
+}
+
+// SystemHeaders returns the canonical names of the HTTP headers which can be
+// set with SetSystemHeader, in sorted order.
+func SystemHeaders() []string {
+	names := make([]string, 0, len(systemHeaders))
+	for name := range systemHeaders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// IsSystemHeader reports whether the HTTP header with the given name can be
+// set with SetSystemHeader. The name is case insensitive.
+func IsSystemHeader(name string) bool {
+	_, ok := systemHeaders[http.CanonicalHeaderKey(name)]
+	return ok
+}
+
+// SetSystemHeader sets the system-defined object metadata given by its HTTP
+// header name, e.g. "Content-Language". The value of Expires is in RFC3339
+// format as with SetExpires. The headers which are not system-defined metadata
+// are ignored.
+func (m Metadata) SetSystemHeader(name, value string) Metadata {
+	if set, ok := systemHeaders[http.CanonicalHeaderKey(name)]; ok {
+		set(m, value)
+	}
+	return m
+}
+
+func (m Metadata) SymlinkTarget() string {
+	return m["SymlinkTarget"]
+}
+
+func (m Metadata) SetSymlinkTarget(target string) Metadata {
+	m["SymlinkTarget"] = target
+	return m
+}
+
+func (m Metadata) WindowsAttributes() string {
+	return m["WindowsAttributes"]
+}
+
+func (m Metadata) SetWindowsAttributes(attrs string) Metadata {
+	m["WindowsAttributes"] = attrs
+	return m
+}
+
+func (m Metadata) ModTime() string {
+	return m["ModTime"]
+}
+
+// SetModTime sets the modification time of the uploaded file, which is kept
+// in the object metadata with nanosecond precision.
+func (m Metadata) SetModTime(modTime time.Time) Metadata {
+	m["ModTime"] = modTime.UTC().Format(time.RFC3339Nano)
+	return m
+}
+
+// userMetadataPrefix is the prefix of the keys of the user-defined metadata,
+// which are kept along with the system-defined ones.
+const userMetadataPrefix = "UserMetadata:"
+
+// UserMetadata returns the user-defined metadata by their names in lower case,
+// or nil if there are none.
+func (m Metadata) UserMetadata() map[string]string {
+	var metadata map[string]string
+	for key, value := range m {
+		if !strings.HasPrefix(key, userMetadataPrefix) {
+			continue
+		}
+		if metadata == nil {
+			metadata = map[string]string{}
+		}
+		metadata[strings.TrimPrefix(key, userMetadataPrefix)] = value
+	}
+	return metadata
+}
+
+// SetUserMetadata sets the user-defined metadata with the given name, which is
+// stored in "x-amz-meta-<name>" header of the remote objects. The names are
+// case insensitive.
+func (m Metadata) SetUserMetadata(name, value string) Metadata {
+	m[userMetadataPrefix+strings.ToLower(name)] = value
+	return m
+}
+
 func (o Object) ToBytes() []byte {
 	buf := bytes.NewBuffer(make([]byte, 0, 200))
 	enc := gob.NewEncoder(buf)
@@ -309,6 +535,7 @@ func (o Object) ToBytes() []byte {
 	enc.Encode(o.ModTime.Format(time.RFC3339Nano))
 	enc.Encode(o.Type.mode)
 	enc.Encode(o.Size)
+	enc.Encode(o.Etag)
 
 	return buf.Bytes()
 }
@@ -327,6 +554,7 @@ func FromBytes(data []byte) extsort.SortType {
 	o.ModTime = &tmp
 	dec.Decode(&o.Type.mode)
 	dec.Decode(&o.Size)
+	dec.Decode(&o.Etag)
 	return o
 }
 
@@ -0,0 +1,37 @@
+package storage
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestObjectMultipartEtagParts(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		etag      string
+		parts     int
+		multipart bool
+	}{
+		{etag: "9a0364b9e99bb480dd25e1f0284c8555"},
+		{etag: ""},
+		{etag: "9a0364b9e99bb480dd25e1f0284c8555-2", parts: 2, multipart: true},
+		{etag: "9a0364b9e99bb480dd25e1f0284c8555-10000", parts: 10000, multipart: true},
+		{etag: "9a0364b9e99bb480dd25e1f0284c8555-"},
+		{etag: "9a0364b9e99bb480dd25e1f0284c8555-0"},
+		{etag: "9a0364b9e99bb480dd25e1f0284c8555-x"},
+		{etag: "-2"},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.etag, func(t *testing.T) {
+			t.Parallel()
+
+			parts, multipart := (&Object{Etag: tc.etag}).MultipartEtagParts()
+			assert.Equal(t, parts, tc.parts)
+			assert.Equal(t, multipart, tc.multipart)
+		})
+	}
+}
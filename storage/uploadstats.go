@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/peak/s5cmd/v2/strutil"
+)
+
+// uploads counts the objects uploaded and copied by all remote clients of the
+// process, by the number of requests their contents are sent with.
+var uploads struct {
+	singlePart int64
+	multipart  int64
+}
+
+// recordUpload counts an object uploaded or copied with a single request, or
+// in parts.
+func recordUpload(multipart bool) {
+	if multipart {
+		atomic.AddInt64(&uploads.multipart, 1)
+		return
+	}
+	atomic.AddInt64(&uploads.singlePart, 1)
+}
+
+// UploadStats is the statistics of the objects uploaded and copied.
+type UploadStats struct {
+	// SinglePart is the number of objects sent with a single request.
+	SinglePart int64
+	// Multipart is the number of objects sent in parts.
+	Multipart int64
+}
+
+// String is the string representation of UploadStats.
+func (s UploadStats) String() string {
+	return fmt.Sprintf(
+		"uploaded %v objects with a single request and %v objects in parts",
+		s.SinglePart, s.Multipart,
+	)
+}
+
+// JSON is the JSON representation of UploadStats.
+func (s UploadStats) JSON() string {
+	return strutil.JSON(struct {
+		Operation  string `json:"operation"`
+		SinglePart int64  `json:"single_part"`
+		Multipart  int64  `json:"multipart"`
+	}{
+		Operation:  "upload",
+		SinglePart: s.SinglePart,
+		Multipart:  s.Multipart,
+	})
+}
+
+// UploadStatistics returns the statistics of the objects uploaded and copied.
+// It reports false if no objects are uploaded or copied.
+func UploadStatistics() (UploadStats, bool) {
+	stats := UploadStats{
+		SinglePart: atomic.LoadInt64(&uploads.singlePart),
+		Multipart:  atomic.LoadInt64(&uploads.multipart),
+	}
+	return stats, stats.SinglePart+stats.Multipart > 0
+}
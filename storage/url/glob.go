@@ -0,0 +1,293 @@
+package url
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Wildcard patterns support the following syntax, both on local and remote
+// URLs:
+//
+//	*       matches any sequence of characters
+//	?       matches any single character
+//	[abc]   matches any of the characters in the brackets
+//	[a-z]   matches any character in the range
+//	[!a-z]  matches any character not in the range, [^a-z] is the same
+//	\c      matches the metacharacter c literally, e.g. \* or \[
+//
+// A "[" without a matching "]" is a literal character. Backslash escaping is
+// not available on local paths on Windows, where backslash is the path
+// separator.
+//
+// The "*" wildcard matches across the "/" separators of remote keys. The
+// local paths are matched segment by segment, and the matching directories
+// are walked recursively.
+
+// globIndex returns the index of the first wildcard metacharacter in the
+// pattern, or -1 if the pattern has no wildcards.
+func globIndex(pattern string, escape bool) int {
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			if escape && i+1 < len(pattern) && isGlobMeta(pattern[i+1]) {
+				i++
+			}
+		case '*', '?':
+			return i
+		case '[':
+			if _, ok := globClassEnd(pattern, i, escape); ok {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// globUnescape removes the backslashes escaping the metacharacters of the
+// literal string s.
+func globUnescape(s string, escape bool) string {
+	if !escape || !strings.Contains(s, `\`) {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && isGlobMeta(s[i+1]) {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// globToRegexp translates the wildcard pattern to an equivalent, unanchored
+// regular expression.
+func globToRegexp(pattern string, escape bool) string {
+	var b strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case c == '\\' && escape && i+1 < len(pattern) && isGlobMeta(pattern[i+1]):
+			i++
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		case c == '*':
+			b.WriteString(".*")
+		case c == '?':
+			b.WriteString(".")
+		case c == '[':
+			end, ok := globClassEnd(pattern, i, escape)
+			if !ok {
+				b.WriteString(`\[`)
+				continue
+			}
+			b.WriteString(globClassToRegexp(pattern[i+1:end], escape))
+			i = end
+		default:
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	return b.String()
+}
+
+// globClassEnd returns the index of the "]" closing the character class
+// starting at pattern[start].
+func globClassEnd(pattern string, start int, escape bool) (int, bool) {
+	i := start + 1
+	if i < len(pattern) && (pattern[i] == '!' || pattern[i] == '^') {
+		i++
+	}
+	// a "]" right after the opening bracket is a literal character.
+	if i < len(pattern) && pattern[i] == ']' {
+		i++
+	}
+
+	for ; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			if escape && i+1 < len(pattern) {
+				i++
+			}
+		case ']':
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// globClassToRegexp translates the content of a character class, without the
+// brackets, to a regular expression character class.
+func globClassToRegexp(class string, escape bool) string {
+	var b strings.Builder
+	b.WriteByte('[')
+	if class != "" && (class[0] == '!' || class[0] == '^') {
+		b.WriteByte('^')
+		class = class[1:]
+	}
+
+	for i := 0; i < len(class); i++ {
+		c := class[i]
+		if c == '\\' && escape && i+1 < len(class) {
+			// an escaped character is always literal.
+			i++
+			c = class[i]
+			if c < utf8.RuneSelf && !isAlphanumeric(c) {
+				b.WriteByte('\\')
+			}
+			b.WriteByte(c)
+			continue
+		}
+
+		switch c {
+		case '-':
+			// a "-" at the start or at the end of the class is literal,
+			// otherwise it denotes a range.
+			if i == 0 || i == len(class)-1 {
+				b.WriteString(`\-`)
+			} else {
+				b.WriteByte('-')
+			}
+		case '\\', ']', '[', '^':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	b.WriteByte(']')
+	return b.String()
+}
+
+func isAlphanumeric(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9'
+}
+
+func isGlobMeta(c byte) bool {
+	switch c {
+	case '*', '?', '[', ']', '\\':
+		return true
+	}
+	return false
+}
+
+// EscapeGlob escapes the metacharacters of s with backslash, so that s is
+// matched literally when it is used in the path of a remote URL.
+func EscapeGlob(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if isGlobMeta(s[i]) {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// hasGlobCharacter reports whether the string contains any wildcards.
+func hasGlobCharacter(s string) bool {
+	return globIndex(s, true) >= 0
+}
+
+// escapeEnabled reports whether the metacharacters can be escaped with
+// backslash in the path of the URL.
+func (u *URL) escapeEnabled() bool {
+	return u.IsRemote() || filepath.Separator != '\\'
+}
+
+// Glob returns the names of the local files and directories matching the
+// wildcard pattern of the URL, in lexical order. It is the equivalent of
+// filepath.Glob with the wildcard syntax of the URLs. Like filepath.Glob, the
+// I/O errors such as reading a directory without permission are ignored.
+func (u *URL) Glob() ([]string, error) {
+	// the path of a URL without wildcards is already unescaped.
+	if !u.IsWildcard() {
+		name := filepath.FromSlash(u.Path)
+		if _, err := os.Lstat(name); err != nil {
+			return nil, nil
+		}
+		return []string{name}, nil
+	}
+
+	escape := u.escapeEnabled()
+	pattern := filepath.ToSlash(u.Path)
+	loc := globIndex(pattern, escape)
+
+	// the directory to start matching from is the literal part of the pattern
+	// up to the last separator before the first wildcard.
+	root := ""
+	if i := strings.LastIndex(pattern[:loc], "/"); i >= 0 {
+		root = globUnescape(pattern[:i+1], escape)
+		pattern = pattern[i+1:]
+	}
+
+	matches := []string{root}
+	for _, segment := range strings.Split(pattern, "/") {
+		if segment == "" {
+			continue
+		}
+
+		var next []string
+		if globIndex(segment, escape) < 0 {
+			name := globUnescape(segment, escape)
+			for _, dir := range matches {
+				if _, err := os.Lstat(filepath.FromSlash(path.Join(dir, name))); err == nil {
+					next = append(next, joinGlobMatch(dir, name))
+				}
+			}
+		} else {
+			re, err := regexp.Compile("^(?s)" + globToRegexp(segment, escape) + "$")
+			if err != nil {
+				return nil, err
+			}
+			for _, dir := range matches {
+				next = append(next, globDir(dir, re)...)
+			}
+		}
+
+		matches = next
+		if len(matches) == 0 {
+			return nil, nil
+		}
+	}
+
+	for i, match := range matches {
+		matches[i] = filepath.FromSlash(match)
+	}
+	return matches, nil
+}
+
+// globDir returns the entries of the directory whose names match re.
+func globDir(dir string, re *regexp.Regexp) []string {
+	name := dir
+	if name == "" {
+		name = "."
+	}
+
+	entries, err := os.ReadDir(filepath.FromSlash(name))
+	if err != nil {
+		return nil
+	}
+
+	var matches []string
+	for _, entry := range entries {
+		if re.MatchString(entry.Name()) {
+			matches = append(matches, joinGlobMatch(dir, entry.Name()))
+		}
+	}
+	sort.Strings(matches)
+	return matches
+}
+
+// joinGlobMatch joins the name to the matched directory. Like
+// filepath.Glob, the joined path is cleaned.
+func joinGlobMatch(dir, name string) string {
+	if dir == "" {
+		return name
+	}
+	return path.Join(dir, name)
+}
@@ -0,0 +1,448 @@
+package url
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWildcardMatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		match   []string
+		noMatch []string
+	}{
+		{
+			name:    "star",
+			pattern: "a/*.txt",
+			match:   []string{"a/b.txt", "a/.txt", "a/b/c.txt"},
+			noMatch: []string{"a/b.txt.gz", "b/a.txt"},
+		},
+		{
+			name:    "question_mark",
+			pattern: "a/?.txt",
+			match:   []string{"a/b.txt", "a/?.txt"},
+			noMatch: []string{"a/.txt", "a/bc.txt"},
+		},
+		{
+			name:    "character_class",
+			pattern: "log[abc].txt",
+			match:   []string{"loga.txt", "logc.txt"},
+			noMatch: []string{"logd.txt", "log[abc].txt", "logab.txt"},
+		},
+		{
+			name:    "character_range",
+			pattern: "log[0-9][0-9].txt",
+			match:   []string{"log00.txt", "log42.txt"},
+			noMatch: []string{"log4.txt", "loga2.txt"},
+		},
+		{
+			name:    "multiple_ranges",
+			pattern: "[a-cx-z]",
+			match:   []string{"a", "b", "y"},
+			noMatch: []string{"d", "w", "-"},
+		},
+		{
+			name:    "negated_class_with_exclamation_mark",
+			pattern: "log[!0-9].txt",
+			match:   []string{"loga.txt", "log-.txt", "log!.txt"},
+			noMatch: []string{"log1.txt", "log12.txt"},
+		},
+		{
+			name:    "negated_class_with_caret",
+			pattern: "log[^0-9].txt",
+			match:   []string{"loga.txt", "log^.txt"},
+			noMatch: []string{"log1.txt", "log12.txt"},
+		},
+		{
+			name:    "closing_bracket_first_in_class",
+			pattern: "[]a]",
+			match:   []string{"]", "a"},
+			noMatch: []string{"b", "[]a]"},
+		},
+		{
+			name:    "closing_bracket_first_in_negated_class",
+			pattern: "[!]a]",
+			match:   []string{"b"},
+			noMatch: []string{"]", "a"},
+		},
+		{
+			name:    "dash_at_the_start_of_class",
+			pattern: "[-a]",
+			match:   []string{"-", "a"},
+			noMatch: []string{"b"},
+		},
+		{
+			name:    "dash_at_the_end_of_class",
+			pattern: "[a-]",
+			match:   []string{"-", "a"},
+			noMatch: []string{"b"},
+		},
+		{
+			name:    "regexp_metacharacters_in_class",
+			pattern: "[.+$|(]",
+			match:   []string{".", "+", "$", "|", "("},
+			noMatch: []string{"a"},
+		},
+		{
+			name:    "backslash_and_caret_in_class",
+			pattern: `[a^\\]`,
+			match:   []string{"a", "^", `\`},
+			noMatch: []string{"b"},
+		},
+		{
+			name:    "escaped_closing_bracket_in_class",
+			pattern: `[a\]]`,
+			match:   []string{"a", "]"},
+			noMatch: []string{`\`, "a]"},
+		},
+		{
+			name:    "escaped_dash_in_class",
+			pattern: `[a\-z]`,
+			match:   []string{"a", "-", "z"},
+			noMatch: []string{"b"},
+		},
+		{
+			name:    "escaped_star",
+			pattern: `a\**`,
+			match:   []string{"a*", "a*b"},
+			noMatch: []string{"ab", `a\b`},
+		},
+		{
+			name:    "escaped_question_mark",
+			pattern: `a\?*`,
+			match:   []string{"a?", "a?b"},
+			noMatch: []string{"ab"},
+		},
+		{
+			name:    "escaped_brackets",
+			pattern: `\[a-z\]*`,
+			match:   []string{"[a-z]", "[a-z].txt"},
+			noMatch: []string{"a", "b.txt"},
+		},
+		{
+			name:    "escaped_backslash",
+			pattern: `a\\*`,
+			match:   []string{`a\`, `a\b`},
+			noMatch: []string{"ab"},
+		},
+		{
+			name:    "backslash_before_non_metacharacter",
+			pattern: `a\b*`,
+			match:   []string{`a\b`, `a\bc`},
+			noMatch: []string{"ab"},
+		},
+		{
+			name:    "unclosed_bracket",
+			pattern: "a[b*",
+			match:   []string{"a[b", "a[bc"},
+			noMatch: []string{"ab"},
+		},
+		{
+			name:    "regexp_metacharacters_are_literal",
+			pattern: "a.b+(c)|d$*",
+			match:   []string{"a.b+(c)|d$", "a.b+(c)|d$e"},
+			noMatch: []string{"axb+(c)|d$", "abbc"},
+		},
+		{
+			name:    "newline_in_key",
+			pattern: "a*b",
+			match:   []string{"a\nb"},
+		},
+		{
+			name:    "unicode",
+			pattern: "ç?[ğü]",
+			match:   []string{"çaü", "çşğ"},
+			noMatch: []string{"çaa"},
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			u, err := New("s3://bucket/" + tc.pattern)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !u.IsWildcard() {
+				t.Fatalf("expected %q to be a wildcard", tc.pattern)
+			}
+
+			for _, key := range tc.match {
+				if !u.Match(key) {
+					t.Errorf("expected %q to match %q", tc.pattern, key)
+				}
+			}
+			for _, key := range tc.noMatch {
+				if u.Match(key) {
+					t.Errorf("expected %q not to match %q", tc.pattern, key)
+				}
+			}
+		})
+	}
+}
+
+func TestWildcardPrefix(t *testing.T) {
+	tests := []struct {
+		name       string
+		path       string
+		wantPath   string
+		wantPrefix string
+		wantFilter string
+		isWildcard bool
+	}{
+		{
+			name:       "no_wildcard",
+			path:       "a/b/c.txt",
+			wantPath:   "a/b/c.txt",
+			wantPrefix: "a/b/c.txt",
+		},
+		{
+			name:       "star",
+			path:       "a/b*/c",
+			wantPath:   "a/b*/c",
+			wantPrefix: "a/b",
+			wantFilter: "*/c",
+			isWildcard: true,
+		},
+		{
+			name:       "question_mark",
+			path:       "a/b?",
+			wantPath:   "a/b?",
+			wantPrefix: "a/b",
+			wantFilter: "?",
+			isWildcard: true,
+		},
+		{
+			name:       "character_class",
+			path:       "a/log[0-9].txt",
+			wantPath:   "a/log[0-9].txt",
+			wantPrefix: "a/log",
+			wantFilter: "[0-9].txt",
+			isWildcard: true,
+		},
+		{
+			name:       "escaped_metacharacters_in_prefix",
+			path:       `a/\*b\[c\]/*`,
+			wantPath:   `a/\*b\[c\]/*`,
+			wantPrefix: "a/*b[c]/",
+			wantFilter: "*",
+			isWildcard: true,
+		},
+		{
+			name:       "escaped_metacharacters_without_wildcard",
+			path:       `a/\*b\?\[c\]`,
+			wantPath:   "a/*b?[c]",
+			wantPrefix: "a/*b?[c]",
+		},
+		{
+			name:       "escaped_backslash",
+			path:       `a\\b`,
+			wantPath:   `a\b`,
+			wantPrefix: `a\b`,
+		},
+		{
+			name:       "backslash_before_non_metacharacter",
+			path:       `a\b`,
+			wantPath:   `a\b`,
+			wantPrefix: `a\b`,
+		},
+		{
+			name:       "unclosed_bracket",
+			path:       "a/b[c",
+			wantPath:   "a/b[c",
+			wantPrefix: "a/b[c",
+		},
+		{
+			name:       "closing_bracket_only",
+			path:       "a/b]c",
+			wantPath:   "a/b]c",
+			wantPrefix: "a/b]c",
+		},
+		{
+			name:       "unclosed_bracket_before_wildcard",
+			path:       "a/b[c/*",
+			wantPath:   "a/b[c/*",
+			wantPrefix: "a/b[c/",
+			wantFilter: "*",
+			isWildcard: true,
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			u, err := New("s3://bucket/" + tc.path)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if u.Path != tc.wantPath {
+				t.Errorf("path = %q, want %q", u.Path, tc.wantPath)
+			}
+			if u.Prefix != tc.wantPrefix {
+				t.Errorf("prefix = %q, want %q", u.Prefix, tc.wantPrefix)
+			}
+			if u.filter != tc.wantFilter {
+				t.Errorf("filter = %q, want %q", u.filter, tc.wantFilter)
+			}
+			if got := u.IsWildcard(); got != tc.isWildcard {
+				t.Errorf("IsWildcard() = %v, want %v", got, tc.isWildcard)
+			}
+
+			raw, err := New("s3://bucket/"+tc.path, WithRaw(true))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if raw.Path != tc.path || raw.IsWildcard() {
+				t.Errorf("raw url: path = %q, IsWildcard() = %v", raw.Path, raw.IsWildcard())
+			}
+		})
+	}
+}
+
+func TestEscapeGlob(t *testing.T) {
+	tests := []string{
+		"a/b/c.txt",
+		"a/*b?/",
+		"a/log[0-9]/",
+		`a\b`,
+		"",
+	}
+	for _, key := range tests {
+		key := key
+		t.Run(key, func(t *testing.T) {
+			u, err := New("s3://bucket/" + EscapeGlob(key) + "*")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if u.Prefix != key {
+				t.Errorf("prefix = %q, want %q", u.Prefix, key)
+			}
+			if u.filter != "*" {
+				t.Errorf("filter = %q, want %q", u.filter, "*")
+			}
+		})
+	}
+}
+
+func TestURLGlob(t *testing.T) {
+	if filepath.Separator == '\\' {
+		t.Skip("backslash escaping is not available on Windows")
+	}
+
+	dir := t.TempDir()
+	files := []string{
+		"a/log1.txt",
+		"a/log2.txt",
+		"a/loga.txt",
+		"a/*star.txt",
+		"a/[1].txt",
+		"a/b/log3.txt",
+		"c/log4.txt",
+		"d/log5.txt",
+		".hidden/log6.txt",
+	}
+	for _, file := range files {
+		name := filepath.Join(dir, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		pattern string
+		want    []string
+	}{
+		{
+			name:    "star",
+			pattern: "a/*.txt",
+			want:    []string{"a/*star.txt", "a/[1].txt", "a/log1.txt", "a/log2.txt", "a/loga.txt"},
+		},
+		{
+			name:    "star_matches_directories",
+			pattern: "a/*",
+			want:    []string{"a/*star.txt", "a/[1].txt", "a/b", "a/log1.txt", "a/log2.txt", "a/loga.txt"},
+		},
+		{
+			name:    "question_mark",
+			pattern: "a/log?.txt",
+			want:    []string{"a/log1.txt", "a/log2.txt", "a/loga.txt"},
+		},
+		{
+			name:    "character_range",
+			pattern: "a/log[0-9].txt",
+			want:    []string{"a/log1.txt", "a/log2.txt"},
+		},
+		{
+			name:    "negated_character_class",
+			pattern: "a/log[!0-9].txt",
+			want:    []string{"a/loga.txt"},
+		},
+		{
+			name:    "wildcard_in_directory",
+			pattern: "[ac]/log*.txt",
+			want:    []string{"a/log1.txt", "a/log2.txt", "a/loga.txt", "c/log4.txt"},
+		},
+		{
+			name:    "wildcard_in_multiple_segments",
+			pattern: "*/*/log?.txt",
+			want:    []string{"a/b/log3.txt"},
+		},
+		{
+			name:    "star_matches_hidden_files",
+			pattern: "*/log6.txt",
+			want:    []string{".hidden/log6.txt"},
+		},
+		{
+			name:    "escaped_star",
+			pattern: `a/\**.txt`,
+			want:    []string{"a/*star.txt"},
+		},
+		{
+			name:    "escaped_brackets",
+			pattern: `a/\[1\]*`,
+			want:    []string{"a/[1].txt"},
+		},
+		{
+			name:    "escaped_literal_path",
+			pattern: `a/\[1\].txt`,
+			want:    []string{"a/[1].txt"},
+		},
+		{
+			name:    "no_match",
+			pattern: "a/log[3-9].txt",
+		},
+		{
+			name:    "missing_directory",
+			pattern: "x/*.txt",
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			u, err := New(filepath.Join(dir, tc.pattern))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			got, err := u.Glob()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var want []string
+			for _, name := range tc.want {
+				want = append(want, filepath.Join(dir, filepath.FromSlash(name)))
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("(-want +got):\n%v", diff)
+			}
+		})
+	}
+}
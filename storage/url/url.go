@@ -18,11 +18,13 @@ import (
 )
 
 const (
-	globCharacters string = "?*"
-
 	// s3Scheme is the schema used on s3 URLs
 	s3Scheme string = "s3://"
 
+	// memScheme is the scheme used on URLs of the in-memory storage. They
+	// share the semantics of s3 URLs.
+	memScheme string = "mem"
+
 	// s3Separator is the path separator for s3 URLs
 	s3Separator string = "/"
 
@@ -53,6 +55,7 @@ type URL struct {
 	filter       string
 	filterRegex  *regexp.Regexp
 	raw          bool
+	wildcard     bool
 }
 
 type Option func(u *URL)
@@ -99,7 +102,7 @@ func New(s string, opts ...Option) (*URL, error) {
 		return url, nil
 	}
 
-	if scheme != "s3" {
+	if scheme != "s3" && scheme != memScheme {
 		return nil, fmt.Errorf("s3 url should start with %q", s3Scheme)
 	}
 
@@ -121,7 +124,7 @@ func New(s string, opts ...Option) (*URL, error) {
 
 	url := &URL{
 		Type:   remoteObject,
-		Scheme: "s3",
+		Scheme: scheme,
 		Bucket: bucket,
 		Path:   key,
 	}
@@ -141,6 +144,11 @@ func (u *URL) IsRemote() bool {
 	return u.Type == remoteObject
 }
 
+// IsMemory reports whether the object is stored on the in-memory storage.
+func (u *URL) IsMemory() bool {
+	return u.IsRemote() && u.Scheme == memScheme
+}
+
 // IsPrefix reports whether the remote object is an S3 prefix, and does not
 // look like an object.
 func (u *URL) IsPrefix() bool {
@@ -245,6 +253,10 @@ func (u *URL) remoteURL() string {
 //	regex: ^a/b/test./c/.*?\\.tsv$
 //	delimiter: ""
 //
+// The escaped metacharacters in the prefix are unescaped, since the prefix is
+// a literal key prefix. If there are no wildcards, the path itself is
+// unescaped and denotes a literal key. See glob.go for the wildcard syntax.
+//
 // It prepares delimiter, prefix and regex for regular strings.
 // These are used in S3 listing operations.
 // See: https://docs.aws.amazon.com/AmazonS3/latest/dev/ListingKeysHierarchy.html
@@ -261,17 +273,20 @@ func (u *URL) setPrefixAndFilter() error {
 		return nil
 	}
 
-	if loc := strings.IndexAny(u.Path, globCharacters); loc < 0 {
+	escape := u.escapeEnabled()
+	if loc := globIndex(u.Path, escape); loc < 0 {
+		u.Path = globUnescape(u.Path, escape)
 		u.Delimiter = s3Separator
 		u.Prefix = u.Path
 	} else {
-		u.Prefix = u.Path[:loc]
+		u.Prefix = globUnescape(u.Path[:loc], escape)
 		u.filter = u.Path[loc:]
+		u.wildcard = true
 	}
 
 	filterRegex := matchAllRe
 	if u.filter != "" {
-		filterRegex = strutil.WildCardToRegexp(u.filter)
+		filterRegex = globToRegexp(u.filter, escape)
 	}
 	filterRegex = regexp.QuoteMeta(u.Prefix) + filterRegex
 	filterRegex = strutil.MatchFromStartToEnd(filterRegex)
@@ -300,19 +315,19 @@ func (u *URL) Clone() *URL {
 		filter:       u.filter,
 		filterRegex:  u.filterRegex,
 		raw:          u.raw,
+		wildcard:     u.wildcard,
 	}
 }
 
 // SetRelative explicitly sets the relative path of u against given base value.
-// If the base path contains `globCharacters` then, the relative path is
-// determined with respect to the parent directory of the so called wildcarded
-// object.
+// If the base path contains wildcards then, the relative path is determined
+// with respect to the parent directory of the so called wildcarded object.
 func (u *URL) SetRelative(base *URL) {
 	basePath := base.Absolute()
 	if base.IsWildcard() {
-		// When the basePath includes a wildcard character (globCharacters)
-		// replace basePath with its substring up to the
-		// index of the first instance of a wildcard character.
+		// When the basePath includes a wildcard character replace
+		// basePath with its unescaped substring up to the index of the
+		// first instance of a wildcard character.
 		//
 		// If we don't handle this, the filepath.Dir()
 		// will assume those wildcards as a part of the name.
@@ -323,20 +338,52 @@ func (u *URL) SetRelative(base *URL) {
 		// e.g. base.Absolute(): "/a/*/n"
 		//      u.Absolute()   : "/a/b/n"
 		//
-		// if we don't trim substring from globCharacters on
+		// if we don't trim substring from the wildcard on
 		// filepath.Dir() will give: "/a/*"
 		// consequently the
 		// filepath.Rel() will give: "../b/c" rather than "b/c"
 		// since "b" and "*" are not the same.
-		loc := strings.IndexAny(basePath, globCharacters)
+		escape := base.escapeEnabled()
+		loc := globIndex(basePath, escape)
 		if loc >= 0 {
-			basePath = basePath[:loc]
+			basePath = globUnescape(basePath[:loc], escape)
 		}
 	}
 	baseDir := filepath.Dir(basePath)
 	u.relativePath, _ = filepath.Rel(baseDir, u.Absolute())
 }
 
+// TrimRelativePrefix removes the given slash separated prefix from the
+// relative path of u. It reports whether the relative path had the prefix.
+func (u *URL) TrimRelativePrefix(prefix string) bool {
+	rel := filepath.ToSlash(u.Relative())
+	if prefix == "" || len(rel) <= len(prefix) || !strings.HasPrefix(rel, prefix) {
+		return false
+	}
+
+	rel = rel[len(prefix):]
+	if !u.IsRemote() {
+		rel = filepath.FromSlash(rel)
+	}
+	u.relativePath = rel
+	return true
+}
+
+// PrependRelativePrefix adds the given slash separated prefix to the relative
+// path of u.
+func (u *URL) PrependRelativePrefix(prefix string) {
+	prefix = strings.Trim(prefix, "/")
+	if prefix == "" {
+		return
+	}
+
+	rel := prefix + "/" + filepath.ToSlash(u.Relative())
+	if !u.IsRemote() {
+		rel = filepath.FromSlash(rel)
+	}
+	u.relativePath = rel
+}
+
 // Match reports whether if given key matches with the object.
 func (u *URL) Match(key string) bool {
 	if u.filterRegex == nil {
@@ -375,6 +422,7 @@ func (u URL) ToBytes() []byte {
 	enc.Encode(u.Absolute())
 	enc.Encode(u.relativePath)
 	enc.Encode(u.raw)
+	enc.Encode(u.VersionID)
 	return buf.Bytes()
 }
 
@@ -382,21 +430,22 @@ func FromBytes(data []byte) extsort.SortType {
 	buf := bytes.NewBuffer(data)
 	dec := gob.NewDecoder(buf)
 	var (
-		abs, rel string
-		raw      bool
+		abs, rel, version string
+		raw               bool
 	)
 	dec.Decode(&abs)
 	dec.Decode(&rel)
 	dec.Decode(&raw)
+	dec.Decode(&version)
 
-	url, _ := New(abs, WithRaw(raw))
+	url, _ := New(abs, WithRaw(raw), WithVersion(version))
 	url.relativePath = rel
 	return url
 }
 
-// IsWildcard reports whether if a string contains any wildcard chars.
+// IsWildcard reports whether the path of the URL is a wildcard pattern.
 func (u *URL) IsWildcard() bool {
-	return !u.raw && hasGlobCharacter(u.Path)
+	return !u.raw && u.wildcard
 }
 
 // parseBatch parses keys for wildcard operations.
@@ -449,11 +498,6 @@ func parseNonBatch(prefix string, key string) string {
 	return trimmedKey
 }
 
-// hasGlobCharacter reports whether if a string contains any wildcard chars.
-func hasGlobCharacter(s string) bool {
-	return strings.ContainsAny(s, globCharacters)
-}
-
 func (u *URL) EscapedPath() string {
 	sourceKey := strings.TrimPrefix(u.String(), "s3://")
 	sourceKeyElements := strings.Split(sourceKey, "/")
@@ -98,6 +98,23 @@ func TestNew(t *testing.T) {
 			},
 			wantFilterRe: regexp.MustCompile(strutil.AddNewLineFlag(`^key/a/./test/.*$`)).String(),
 		},
+		{
+			name:   "memory_url",
+			object: "mem://bucket/key",
+			want: &URL{
+				Scheme:    "mem",
+				Bucket:    "bucket",
+				Path:      "key",
+				Prefix:    "key",
+				Delimiter: "/",
+			},
+			wantFilterRe: regexp.MustCompile(strutil.AddNewLineFlag(`^key.*$`)).String(),
+		},
+		{
+			name:    "error_if_scheme_is_unknown",
+			object:  "gs://bucket/key",
+			wantErr: true,
+		},
 	}
 	for _, tc := range tests {
 		tc := tc
@@ -229,6 +246,7 @@ func TestURLSetPrefixAndFilter(t *testing.T) {
 				Delimiter:   "",
 				filter:      "*/de/*/test",
 				filterRegex: regexp.MustCompile(strutil.AddNewLineFlag("^a/b_c/.*/de/.*/test$")),
+				wildcard:    true,
 			},
 		},
 		{
@@ -601,17 +619,153 @@ func TestURLSetRelative(t *testing.T) {
 	}
 }
 
+func TestURLTrimRelativePrefix(t *testing.T) {
+	sep := string(filepath.Separator)
+	tests := []struct {
+		name    string
+		base    string
+		target  string
+		prefix  string
+		trimmed bool
+		expect  string
+	}{
+		{
+			name:    "s3_object_with_prefix",
+			base:    "s3://bucket/*",
+			target:  "s3://bucket/v1/data/object",
+			prefix:  "v1/",
+			trimmed: true,
+			expect:  "data/object",
+		},
+		{
+			name:    "s3_object_without_prefix",
+			base:    "s3://bucket/*",
+			target:  "s3://bucket/data/object",
+			prefix:  "v1/",
+			trimmed: false,
+			expect:  "data/object",
+		},
+		{
+			name:    "s3_object_same_as_prefix",
+			base:    "s3://bucket/*",
+			target:  "s3://bucket/v1/",
+			prefix:  "v1/",
+			trimmed: false,
+			expect:  "v1",
+		},
+		{
+			name:    "local_file_with_prefix",
+			base:    sep + "parent" + sep + "*",
+			target:  sep + "parent" + sep + "v1" + sep + "data" + sep + "file",
+			prefix:  "v1/",
+			trimmed: true,
+			expect:  "data" + sep + "file",
+		},
+		{
+			name:    "empty_prefix",
+			base:    "s3://bucket/*",
+			target:  "s3://bucket/v1/data/object",
+			prefix:  "",
+			trimmed: false,
+			expect:  "v1/data/object",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			baseURL, err := New(tt.base)
+			if err != nil {
+				t.Fatalf("URL cannot be instantiated: \nPath: %v, Error: %v", tt.base, err)
+			}
+			targURL, err := New(tt.target)
+			if err != nil {
+				t.Fatalf("URL cannot be instantiated:\nPath: %v, Error: %v", tt.target, err)
+			}
+
+			targURL.SetRelative(baseURL)
+
+			if trimmed := targURL.TrimRelativePrefix(tt.prefix); trimmed != tt.trimmed {
+				t.Errorf("expected trimmed to be %v, got %v", tt.trimmed, trimmed)
+			}
+
+			if diff := cmp.Diff(tt.expect, targURL.Relative()); diff != "" {
+				t.Errorf("TrimRelativePrefix() did not produce expected path (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestURLPrependRelativePrefix(t *testing.T) {
+	sep := string(filepath.Separator)
+	tests := []struct {
+		name   string
+		base   string
+		target string
+		prefix string
+		expect string
+	}{
+		{
+			name:   "s3_object",
+			base:   "s3://bucket/*",
+			target: "s3://bucket/data/object",
+			prefix: "2024/01/02",
+			expect: "2024/01/02/data/object",
+		},
+		{
+			name:   "local_file",
+			base:   sep + "parent" + sep + "*",
+			target: sep + "parent" + sep + "data" + sep + "file",
+			prefix: "/2024/01/02/",
+			expect: "2024" + sep + "01" + sep + "02" + sep + "data" + sep + "file",
+		},
+		{
+			name:   "empty_prefix",
+			base:   "s3://bucket/*",
+			target: "s3://bucket/data/object",
+			prefix: "",
+			expect: "data/object",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			baseURL, err := New(tt.base)
+			if err != nil {
+				t.Fatalf("URL cannot be instantiated: \nPath: %v, Error: %v", tt.base, err)
+			}
+			targURL, err := New(tt.target)
+			if err != nil {
+				t.Fatalf("URL cannot be instantiated:\nPath: %v, Error: %v", tt.target, err)
+			}
+
+			targURL.SetRelative(baseURL)
+			targURL.PrependRelativePrefix(tt.prefix)
+
+			if diff := cmp.Diff(tt.expect, targURL.Relative()); diff != "" {
+				t.Errorf("PrependRelativePrefix() did not produce expected path (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
 func TestToFromBytes(t *testing.T) {
 	testcases := []struct {
 		name     string
 		key      string
 		relative string
+		version  string
 	}{
 		{
 			name:     "plain remote",
 			key:      "s3://bucket/file",
 			relative: "file",
 		},
+		{
+			name:     "versioned remote",
+			key:      "s3://bucket/file",
+			relative: "file",
+			version:  "3/L4kqtJlcpXroDTDmJ+rmSpXd3dIbrHY+MTRCxf3vjVBH40Nr8X8gdRQBpUMLUo",
+		},
 		{
 			name:     "space char remote",
 			key:      "s3://bucket/s ace/file",
@@ -626,7 +780,7 @@ func TestToFromBytes(t *testing.T) {
 
 	for _, tc := range testcases {
 		t.Run(tc.name, func(t *testing.T) {
-			url, err := New(tc.key)
+			url, err := New(tc.key, WithVersion(tc.version))
 			if err != nil {
 				t.Errorf("URL cannot be instantiated: \nPath: %v, Error: %v", tc.key, err)
 			}
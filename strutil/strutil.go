@@ -3,9 +3,11 @@ package strutil
 import (
 	"encoding/json"
 	"fmt"
+	"math"
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 )
 
 var humanDivisors = [...]struct {
@@ -37,6 +39,30 @@ func HumanizeBytes(b int64) string {
 	return fmt.Sprintf("%.1f%s", float64(b)/float64(div), suffix)
 }
 
+// ParseBytes parses a byte-size such as "400GB", "1.5T" or "512MiB". The
+// units are powers of 1024, as in HumanizeBytes. A number without a unit is
+// the number of bytes.
+func ParseBytes(s string) (int64, error) {
+	str := strings.ToUpper(strings.TrimSpace(s))
+	str = strings.TrimSuffix(str, "B")
+	str = strings.TrimSuffix(str, "I")
+
+	mult := int64(1)
+	for _, f := range humanDivisors {
+		if strings.HasSuffix(str, f.suffix) {
+			str = strings.TrimSuffix(str, f.suffix)
+			mult = f.div
+			break
+		}
+	}
+
+	n, err := strconv.ParseFloat(strings.TrimSpace(str), 64)
+	if err != nil || n < 0 || n*float64(mult) > float64(math.MaxInt64) {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return int64(n * float64(mult)), nil
+}
+
 // JSON is a helper function for creating JSON-encoded strings.
 func JSON(v interface{}) string {
 	bytes, _ := json.Marshal(v)
@@ -71,3 +97,28 @@ func WildCardToRegexp(pattern string) string {
 func MatchFromStartToEnd(pattern string) string {
 	return "^" + pattern + "$"
 }
+
+// ParseDuration parses a duration string like time.ParseDuration, but also
+// accepts a leading number of days with the "d" unit, such as "90d" or "1d12h".
+func ParseDuration(s string) (time.Duration, error) {
+	days, rest, ok := strings.Cut(s, "d")
+	if !ok {
+		return time.ParseDuration(s)
+	}
+
+	n, err := strconv.ParseUint(days, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+
+	d := time.Duration(n) * 24 * time.Hour
+	if rest == "" {
+		return d, nil
+	}
+
+	r, err := time.ParseDuration(rest)
+	if err != nil || r < 0 {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return d + r, nil
+}
@@ -1,6 +1,9 @@
 package strutil
 
-import "testing"
+import (
+	"testing"
+	"time"
+)
 
 func TestCapitalizeFirstLetter(t *testing.T) {
 	tests := []struct {
@@ -74,3 +77,68 @@ func Test_WildCardToRegexp(t *testing.T) {
 		})
 	}
 }
+
+func TestParseDuration(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		input   string
+		wanted  time.Duration
+		wantErr bool
+	}{
+		{input: "36h", wanted: 36 * time.Hour},
+		{input: "90d", wanted: 90 * 24 * time.Hour},
+		{input: "1d12h", wanted: 36 * time.Hour},
+		{input: "0d", wanted: 0},
+		{input: "d", wantErr: true},
+		{input: "-1d", wantErr: true},
+		{input: "1d-1h", wantErr: true},
+		{input: "1.5d", wantErr: true},
+		{input: "ten days", wantErr: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseDuration(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseDuration() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.wanted {
+				t.Errorf("ParseDuration() = %v, want %v", got, tt.wanted)
+			}
+		})
+	}
+}
+
+func TestParseBytes(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		input   string
+		wanted  int64
+		wantErr bool
+	}{
+		{input: "1024", wanted: 1024},
+		{input: "10B", wanted: 10},
+		{input: "4K", wanted: 4 << 10},
+		{input: "512MiB", wanted: 512 << 20},
+		{input: "400GB", wanted: 400 << 30},
+		{input: "400gb", wanted: 400 << 30},
+		{input: "1.5T", wanted: 3 << 39},
+		{input: "", wantErr: true},
+		{input: "GB", wantErr: true},
+		{input: "-1G", wantErr: true},
+		{input: "ten GB", wantErr: true},
+		{input: "10PB", wantErr: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseBytes(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseBytes() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.wanted {
+				t.Errorf("ParseBytes() = %v, want %v", got, tt.wanted)
+			}
+		})
+	}
+}